@@ -2,15 +2,131 @@ package authorization
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 
 	"github.com/casbin/casbin/v2"
-	"github.com/savannahghi/converterandformatter"
+	"github.com/casbin/casbin/v2/model"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/authorization/permission"
 	"github.com/savannahghi/profileutils"
 )
 
+// PolicyPathEnvVarName is the environment variable that points to a Casbin
+// CSV policy file. When it is not set, the default policy is used.
+const PolicyPathEnvVarName = "AUTHORIZATION_POLICY_PATH"
+
+// AdminsEnvVarName is the environment variable that lists, separated by
+// commas, the emails and phone numbers of the admins in the default policy
+const AdminsEnvVarName = "AUTHORIZATION_ADMINS"
+
+// AdminRole is granted every permission in the default policy
+const AdminRole = "admin"
+
+// accessModel is the Casbin model described in this package's README.
+// Subjects are granted permissions directly or through roles.
+const accessModel = `
+[request_definition]
+r = user_id, feature, action
+
+[policy_definition]
+p = user_id, feature, action
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.user_id, p.user_id) && r.feature == p.feature && r.action == p.action
+`
+
+var (
+	enforcerOnce sync.Once
+	enforcer     *casbin.Enforcer
+	enforcerErr  error
+)
+
+// getEnforcer returns the enforcer that checks permissions, loading the
+// policy on first use
+func getEnforcer() (*casbin.Enforcer, error) {
+	enforcerOnce.Do(func() {
+		enforcer, enforcerErr = newEnforcer(
+			os.Getenv(PolicyPathEnvVarName),
+			parseAdmins(os.Getenv(AdminsEnvVarName)),
+		)
+	})
+	return enforcer, enforcerErr
+}
+
+// parseAdmins splits a comma separated list of admins
+func parseAdmins(value string) []string {
+	admins := []string{}
+	for _, admin := range strings.Split(value, ",") {
+		if admin = strings.TrimSpace(admin); admin != "" {
+			admins = append(admins, admin)
+		}
+	}
+	return admins
+}
+
+// newEnforcer creates an enforcer that uses the policy file at the supplied
+// path, or the default policy when no path is supplied. The default policy
+// only grants permissions to the supplied admins, identified by their email
+// or phone number, and denies everyone else.
+func newEnforcer(policyPath string, admins []string) (*casbin.Enforcer, error) {
+	m, err := model.NewModelFromString(accessModel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization model: %w", err)
+	}
+
+	if policyPath != "" {
+		e, err := casbin.NewEnforcer(m, fileadapter.NewAdapter(policyPath))
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to load authorization policy from %s: %w", policyPath, err)
+		}
+		return e, nil
+	}
+
+	e, err := casbin.NewEnforcer(m)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create authorization enforcer: %w", err)
+	}
+	policies := [][]string{}
+	for _, p := range permission.All {
+		policies = append(policies, []string{AdminRole, p.Resource, p.Action})
+	}
+	if _, err := e.AddPolicies(policies); err != nil {
+		return nil, fmt.Errorf("unable to add default policies: %w", err)
+	}
+	if len(admins) == 0 {
+		return e, nil
+	}
+	roles := [][]string{}
+	for _, admin := range admins {
+		roles = append(roles, []string{phoneSubject(admin), AdminRole})
+	}
+	if _, err := e.AddGroupingPolicies(roles); err != nil {
+		return nil, fmt.Errorf("unable to add default roles: %w", err)
+	}
+	return e, nil
+}
+
+// phoneSubject is the policy subject for a phone number i.e the normalized
+// phone number without the leading `+`
+func phoneSubject(phone string) string {
+	return strings.TrimPrefix(phone, "+")
+}
+
 // CheckPemissions is used to check whether the permissions of a subject are set
 func CheckPemissions(subject string, input profileutils.PermissionInput) (bool, error) {
-	enforcer := &casbin.Enforcer{}
+	enforcer, err := getEnforcer()
+	if err != nil {
+		return false, fmt.Errorf("unable to check permissions %w", err)
+	}
 	ok, err := enforcer.Enforce(subject, input.Resource, input.Action)
 	if err != nil {
 		return false, fmt.Errorf("unable to check permissions %w", err)
@@ -35,18 +151,24 @@ func CheckAuthorization(subject string, permission profileutils.PermissionInput)
 	return true, nil
 }
 
-// IsAuthorized checks if the subject identified by their email has permission to access the
-// specified resource
-// currently only known internal anonymous users and external API Integrations emails are checked, internal and default logged in users
-// have access by default.
-// for subjects identified by their phone number normalize the phone and omit the first (+) character
+// IsAuthorized checks if the subject identified by their email or phone
+// number has permission to access the specified resource.
+//
+// The email is checked first, then the phone number. For subjects identified
+// by their phone number, normalize the phone and omit the first (+)
+// character in the policy.
 func IsAuthorized(user *profileutils.UserInfo, permission profileutils.PermissionInput) (bool, error) {
-	if user.PhoneNumber != "" && converterandformatter.StringSliceContains(profileutils.AuthorizedPhones, user.PhoneNumber) {
-		return CheckAuthorization(user.PhoneNumber[1:], permission)
+	if user == nil {
+		return false, nil
 	}
-	if user.Email != "" && converterandformatter.StringSliceContains(profileutils.AuthorizedEmails, user.Email) {
-		return CheckAuthorization(user.Email, permission)
-
+	if user.Email != "" {
+		ok, err := CheckAuthorization(user.Email, permission)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	if user.PhoneNumber != "" {
+		return CheckAuthorization(phoneSubject(user.PhoneNumber), permission)
 	}
 	return false, nil
 }
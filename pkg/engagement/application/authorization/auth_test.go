@@ -1,8 +1,11 @@
 package authorization
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/authorization/permission"
 	"github.com/savannahghi/profileutils"
 )
 
@@ -151,3 +154,143 @@ func TestIsAuthorized(t *testing.T) {
 		})
 	}
 }
+
+func TestIsAuthorized_DefaultPolicy(t *testing.T) {
+	for _, user := range []*profileutils.UserInfo{
+		{Email: profileutils.AuthorizedEmails[0]},
+		{PhoneNumber: profileutils.AuthorizedPhones[0]},
+		{Email: "stranger@example.com", PhoneNumber: "+254711111111"},
+	} {
+		ok, err := IsAuthorized(user, permission.PublishItem)
+		if err != nil || ok {
+			t.Errorf("expected %v to be unauthorized without admins, got %v, %v", user, ok, err)
+		}
+	}
+}
+
+func TestNewEnforcer_DefaultPolicy(t *testing.T) {
+	admins := parseAdmins(" admin@example.com, +254722222222 ,,")
+	if len(admins) != 2 {
+		t.Fatalf("expected 2 admins, got %v", admins)
+	}
+
+	e, err := newEnforcer("", admins)
+	if err != nil {
+		t.Fatalf("unable to create enforcer: %v", err)
+	}
+	tests := []struct {
+		name    string
+		subject string
+		want    bool
+	}{
+		{
+			name:    "happy case: admin email",
+			subject: "admin@example.com",
+			want:    true,
+		},
+		{
+			name:    "happy case: admin phone",
+			subject: phoneSubject("+254722222222"),
+			want:    true,
+		},
+		{
+			name:    "sad case: known internal users are not admins",
+			subject: profileutils.AuthorizedEmails[0],
+			want:    false,
+		},
+		{
+			name:    "sad case: unknown user",
+			subject: "stranger@example.com",
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Enforce(
+				tt.subject,
+				permission.PublishItem.Resource,
+				permission.PublishItem.Action,
+			)
+			if err != nil {
+				t.Fatalf("unable to enforce: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Enforce() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	e, err = newEnforcer("", nil)
+	if err != nil {
+		t.Fatalf("unable to create enforcer: %v", err)
+	}
+	for _, p := range permission.All {
+		got, err := e.Enforce(AdminRole, p.Resource, p.Action)
+		if err != nil || !got {
+			t.Errorf("expected the admin role to hold %v, got %v, %v", p, got, err)
+		}
+	}
+}
+
+func TestNewEnforcer_PolicyFile(t *testing.T) {
+	policy := `p, publisher, publish_item, create
+g, editor@example.com, publisher
+p, 254722222222, delete_item, delete
+`
+	path := filepath.Join(t.TempDir(), "policy.csv")
+	if err := ioutil.WriteFile(path, []byte(policy), 0600); err != nil {
+		t.Fatalf("unable to write policy: %v", err)
+	}
+
+	e, err := newEnforcer(path, []string{"admin@example.com"})
+	if err != nil {
+		t.Fatalf("unable to create enforcer: %v", err)
+	}
+	tests := []struct {
+		name    string
+		subject string
+		input   profileutils.PermissionInput
+		want    bool
+	}{
+		{
+			name:    "happy case: permission through a role",
+			subject: "editor@example.com",
+			input:   permission.PublishItem,
+			want:    true,
+		},
+		{
+			name:    "happy case: direct permission",
+			subject: "254722222222",
+			input:   permission.DeleteItem,
+			want:    true,
+		},
+		{
+			name:    "sad case: permission not granted",
+			subject: "editor@example.com",
+			input:   permission.DeleteItem,
+			want:    false,
+		},
+		{
+			name:    "sad case: default admins are not in the policy file",
+			subject: "admin@example.com",
+			input:   permission.PublishItem,
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Enforce(tt.subject, tt.input.Resource, tt.input.Action)
+			if err != nil {
+				t.Fatalf("unable to enforce: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Enforce() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	_, err = newEnforcer(filepath.Join(t.TempDir(), "missing.csv"), nil)
+	if err == nil {
+		t.Errorf("expected an error for a missing policy file")
+	}
+}
@@ -135,3 +135,82 @@ var SendMessage = profileutils.PermissionInput{
 	Resource: "send_message",
 	Action:   "create",
 }
+
+// PublishNudge describes create permissions on a nudge
+var PublishNudge = profileutils.PermissionInput{
+	Resource: "publish_nudge",
+	Action:   "create",
+}
+
+// DeleteNudge describes delete permissions on a nudge
+var DeleteNudge = profileutils.PermissionInput{
+	Resource: "delete_nudge",
+	Action:   "delete",
+}
+
+// PublishAction describes create permissions on an action
+var PublishAction = profileutils.PermissionInput{
+	Resource: "publish_action",
+	Action:   "create",
+}
+
+// DeleteAction describes delete permissions on an action
+var DeleteAction = profileutils.PermissionInput{
+	Resource: "delete_action",
+	Action:   "delete",
+}
+
+// ModerateMessages describes permissions to review reported messages and
+// hide or restore them
+var ModerateMessages = profileutils.PermissionInput{
+	Resource: "moderate_messages",
+	Action:   "update",
+}
+
+// ViewNotificationReports describes permissions to view reports on how
+// push notifications are received
+var ViewNotificationReports = profileutils.PermissionInput{
+	Resource: "view_notification_reports",
+	Action:   "view",
+}
+
+// SendToTopic describes permissions to send a push notification to the
+// devices subscribed to an FCM topic
+var SendToTopic = profileutils.PermissionInput{
+	Resource: "send_to_topic",
+	Action:   "create",
+}
+
+// All lists every permission. The default authorization policy grants them
+// to admins.
+var All = []profileutils.PermissionInput{
+	FeedView,
+	ThinFeedView,
+	FeedItemView,
+	NudgeView,
+	ActionView,
+	PublishItem,
+	DeleteItem,
+	ResolveItem,
+	UnresolveItem,
+	PinItem,
+	UnpinItem,
+	HideItem,
+	ShowItem,
+	GetLabel,
+	CreateLabel,
+	UnreadPersistentItems,
+	UpdateUnreadPersistentItems,
+	PostMessage,
+	DeleteMessage,
+	ProcessEvent,
+	ItemUpdate,
+	SendMessage,
+	PublishNudge,
+	DeleteNudge,
+	PublishAction,
+	DeleteAction,
+	ModerateMessages,
+	ViewNotificationReports,
+	SendToTopic,
+}
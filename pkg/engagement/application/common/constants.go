@@ -11,19 +11,26 @@ const (
 	ItemShowTopic       = "items.show"
 	ItemPinTopic        = "items.pin"
 	ItemUnpinTopic      = "items.unpin"
+	ItemExpireTopic     = "items.expire"
+	ItemDueSoonTopic    = "items.due_soon"
 	NudgePublishTopic   = "nudges.publish"
 	NudgeDeleteTopic    = "nudges.delete"
 	NudgeResolveTopic   = "nudges.resolve"
 	NudgeUnresolveTopic = "nudges.unresolve"
 	NudgeHideTopic      = "nudges.hide"
 	NudgeShowTopic      = "nudges.show"
+	NudgeExpireTopic    = "nudges.expire"
+	NudgeDueSoonTopic   = "nudges.due_soon"
+	FeedBulkUpdateTopic = "feed.bulk_update"
 	ActionPublishTopic  = "actions.publish"
 	ActionDeleteTopic   = "actions.delete"
 	MessagePostTopic    = "message.post"
 	MessageDeleteTopic  = "message.delete"
+	MessageUpdateTopic  = "message.update"
 	IncomingEventTopic  = "incoming.event"
 	FcmPublishTopic     = "fcm.send_notification"
 	SentEmailTopic      = "mails.inbox"
+	DigestDueTopic      = "digests.due"
 
 	// DefaultLabel is the label used for welcome content
 	DefaultLabel = "WELCOME"
@@ -3,7 +3,10 @@ package dto
 import (
 	"time"
 
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 	"github.com/savannahghi/enumutils"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/firebasetools"
 )
 
 // SendSMSPayload is used to serialise an SMS sent through the AIT service REST API
@@ -12,6 +15,17 @@ type SendSMSPayload struct {
 	Message string             `json:"message"`
 	Sender  enumutils.SenderID `json:"sender"`
 	Segment *string            `json:"segment"`
+
+	// transactional messages e.g one time PINs are sent regardless of the
+	// recipients' notification preferences. Messages are screened unless
+	// this is true.
+	Transactional *bool `json:"transactional,omitempty"`
+}
+
+// IsTransactional reports whether an SMS is sent regardless of the
+// recipients' notification preferences
+func (p SendSMSPayload) IsTransactional() bool {
+	return p.Transactional != nil && *p.Transactional
 }
 
 // EMailMessage holds data required to send emails
@@ -19,6 +33,25 @@ type EMailMessage struct {
 	Subject string   `json:"subject,omitempty"`
 	Text    string   `json:"text,omitempty"`
 	To      []string `json:"to,omitempty"`
+
+	// transactional emails e.g verification codes are sent regardless of
+	// the recipients' notification preferences. Emails are screened unless
+	// this is true.
+	Transactional *bool `json:"transactional,omitempty"`
+}
+
+// IsTransactional reports whether an email is sent regardless of the
+// recipients' notification preferences
+func (m EMailMessage) IsTransactional() bool {
+	return m.Transactional != nil && *m.Transactional
+}
+
+// DigestDue is published for each feed whose digest is due. The digest is
+// built and sent by the handler of the message.
+type DigestDue struct {
+	UID       string                 `json:"uid"`
+	Flavour   feedlib.Flavour        `json:"flavour"`
+	Frequency domain.DigestFrequency `json:"frequency"`
 }
 
 // FeedbackInput is reason a user gave a certain NPS score
@@ -50,6 +83,147 @@ type UIDPayload struct {
 	UID *string `json:"uid"`
 }
 
+// PhoneNumberPayload is used to look up a phone number on the profile service
+type PhoneNumberPayload struct {
+	PhoneNumber *string `json:"phoneNumber"`
+}
+
+// MergeAnonymousFeedInput identifies the anonymous feed that is merged into
+// the feed of a user that has signed up
+type MergeAnonymousFeedInput struct {
+	AnonymousUID string          `json:"anonymousUID"`
+	UID          string          `json:"uid"`
+	Flavour      feedlib.Flavour `json:"flavour"`
+}
+
+// BulkUpdateInput is an operation that is applied to many items and nudges
+// in a feed
+type BulkUpdateInput struct {
+	Operation domain.BulkOperation `json:"operation"`
+	ItemIDs   []string             `json:"itemIDs"`
+	NudgeIDs  []string             `json:"nudgeIDs"`
+}
+
+// EditMessageInput is the new text of an edited message. The editor is the
+// feed's owner unless an `editedByUID` is supplied.
+type EditMessageInput struct {
+	Text        string `json:"text"`
+	EditedByUID string `json:"editedByUID"`
+}
+
+// MessageReactionInput adds or removes an emoji reaction to a message. The
+// reactor is the feed's owner unless a `reactorUID` is supplied.
+type MessageReactionInput struct {
+	Emoji      string `json:"emoji"`
+	ReactorUID string `json:"reactorUID"`
+	Reacted    bool   `json:"reacted"`
+}
+
+// MessageAttachmentInput lists the uploads that are attached to a message
+type MessageAttachmentInput struct {
+	UploadIDs []string `json:"uploadIDs"`
+}
+
+// MessageReportInput is a complaint about a message. The reporter is the
+// feed's owner unless a `reportedByUID` is supplied.
+type MessageReportInput struct {
+	ReportedByUID string `json:"reportedByUID"`
+	Reason        string `json:"reason"`
+}
+
+// ModerationDecisionInput is a moderator's decision on a reported message
+type ModerationDecisionInput struct {
+	Action       domain.ModerationAction `json:"action"`
+	ModeratorUID string                  `json:"moderatorUID"`
+	Reason       string                  `json:"reason"`
+}
+
+// TopicSubscriptionInput is used to subscribe a user's devices to an FCM
+// topic, or to unsubscribe them from it
+type TopicSubscriptionInput struct {
+	UID   string `json:"uid"`
+	Topic string `json:"topic"`
+}
+
+// TopicMessagePayload is an FCM message for the devices subscribed to a
+// topic, or to a condition expression that combines topics e.g
+// `'county-nairobi' in topics && 'programme-mch' in topics`. Only one of
+// the topic and the condition is set.
+type TopicMessagePayload struct {
+	Topic        string                                         `json:"topic"`
+	Condition    string                                         `json:"condition"`
+	Data         map[string]string                              `json:"data"`
+	Notification *firebasetools.FirebaseSimpleNotificationInput `json:"notification"`
+	Android      *firebasetools.FirebaseAndroidConfigInput      `json:"android"`
+	Ios          *firebasetools.FirebaseAPNSConfigInput         `json:"ios"`
+	Web          *firebasetools.FirebaseWebpushConfigInput      `json:"web"`
+}
+
+// APNSPayloadInput sets the `aps` dictionary of a notification to iOS
+// devices
+type APNSPayloadInput struct {
+	Badge    *int    `json:"badge,omitempty"`
+	Sound    *string `json:"sound,omitempty"`
+	Category *string `json:"category,omitempty"`
+	ThreadID *string `json:"threadID,omitempty"`
+
+	// lets a notification service extension modify the notification before
+	// it is shown
+	MutableContent bool `json:"mutableContent,omitempty"`
+
+	// wakes the app up in the background. Without a notification, the
+	// message is a silent background push.
+	ContentAvailable bool `json:"contentAvailable,omitempty"`
+}
+
+// AndroidOptionsInput sets the Android options that
+// `firebasetools.FirebaseAndroidConfigInput` does not carry
+type AndroidOptionsInput struct {
+	// how long, in seconds, FCM keeps the message for an offline device
+	TTL *int `json:"ttl,omitempty"`
+
+	ChannelID   *string `json:"channelID,omitempty"`
+	ClickAction *string `json:"clickAction,omitempty"`
+	Icon        *string `json:"icon,omitempty"`
+
+	// in the #RRGGBB format
+	Color *string `json:"color,omitempty"`
+}
+
+// NotificationOptions are the platform options of a push notification
+type NotificationOptions struct {
+	APNSPayload    *APNSPayloadInput    `json:"apnsPayload,omitempty"`
+	AndroidOptions *AndroidOptionsInput `json:"androidOptions,omitempty"`
+
+	// the users that the registration tokens belong to. Notifications that
+	// are not transactional only reach the tokens of the users that accept
+	// them.
+	UIDs []string `json:"uids,omitempty"`
+
+	// transactional notifications are sent regardless of the recipients'
+	// notification preferences. Notifications are screened unless this is
+	// true.
+	Transactional *bool `json:"transactional,omitempty"`
+
+	// the feed sender that the notification was sent for e.g ITEM_PUBLISHED.
+	// `Push` sets it so that open rates can be reported by sender.
+	SenderType string `json:"senderType,omitempty"`
+}
+
+// IsTransactional reports whether a notification is sent regardless of the
+// recipients' notification preferences
+func (o NotificationOptions) IsTransactional() bool {
+	return o.Transactional != nil && *o.Transactional
+}
+
+// NotificationPayload is a `firebasetools.SendNotificationPayload` along
+// with its platform options. Its JSON adds to that of the firebasetools
+// payload, so either can be sent over the FCM Pub/Sub topic.
+type NotificationPayload struct {
+	firebasetools.SendNotificationPayload
+	NotificationOptions
+}
+
 // OutgoingEmailsLog contains the content of the sent email message sent via MailGun
 type OutgoingEmailsLog struct {
 	UUID    string   `json:"uuid" firestore:"uuid"`
@@ -83,4 +257,6 @@ type TemporaryPIN struct {
 	FirstName   string `json:"firstName,omitempty"`
 	PIN         string `json:"pin,omitempty"`
 	Channel     int    `json:"channel,omitempty"`
+	// Language is the recipient's language e.g `sw`. It is optional.
+	Language string `json:"language,omitempty"`
 }
@@ -3,6 +3,7 @@ package dto
 import (
 	"time"
 
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 	"github.com/savannahghi/feedlib"
 	"github.com/savannahghi/firebasetools"
 )
@@ -167,6 +168,8 @@ type SMSPayload struct {
 type SavedNotification struct {
 	ID                string                      `json:"id,omitempty"`
 	RegistrationToken string                      `json:"registrationToken,omitempty"`
+	Topic             string                      `json:"topic,omitempty"`
+	Condition         string                      `json:"condition,omitempty"`
 	MessageID         string                      `json:"messageID,omitempty"`
 	Timestamp         time.Time                   `json:"timestamp,omitempty"`
 	Data              map[string]interface{}      `json:"data,omitempty"`
@@ -174,11 +177,57 @@ type SavedNotification struct {
 	AndroidConfig     *FirebaseAndroidConfig      `json:"androidConfig,omitempty"`
 	WebpushConfig     *FirebaseWebpushConfig      `json:"webpushConfig,omitempty"`
 	APNSConfig        *FirebaseAPNSConfig         `json:"apnsConfig,omitempty"`
+
+	// the feed sender that the notification was sent for e.g ITEM_PUBLISHED
+	SenderType string `json:"senderType,omitempty"`
+
+	// how far the notification got, as reported by FCM and acknowledged by
+	// the app
+	Status      domain.NotificationStatus `json:"status,omitempty"`
+	DeliveredAt *time.Time                `json:"deliveredAt,omitempty"`
+	DismissedAt *time.Time                `json:"dismissedAt,omitempty"`
+	OpenedAt    *time.Time                `json:"openedAt,omitempty"`
 }
 
 // IsEntity ...
 func (u SavedNotification) IsEntity() {}
 
+// Acknowledge records that the app received, dismissed or opened the
+// notification. It returns false when the acknowledgement changes nothing
+// e.g because it was already recorded.
+func (u *SavedNotification) Acknowledge(
+	status domain.NotificationStatus,
+	at time.Time,
+) bool {
+	var stamp **time.Time
+	switch status {
+	case domain.NotificationStatusDelivered:
+		stamp = &u.DeliveredAt
+	case domain.NotificationStatusDismissed:
+		stamp = &u.DismissedAt
+	case domain.NotificationStatusOpened:
+		stamp = &u.OpenedAt
+	default:
+		return false
+	}
+
+	changed := false
+	if *stamp == nil {
+		*stamp = &at
+		changed = true
+	}
+	if u.DeliveredAt == nil {
+		// a notification can't be dismissed or opened without reaching the app
+		u.DeliveredAt = &at
+		changed = true
+	}
+	if status.Supersedes(u.Status) {
+		u.Status = status
+		changed = true
+	}
+	return changed
+}
+
 // FirebaseSimpleNotification is used to serialize simple FCM notification.
 // It is a mirror of Firebase messaging.Notification
 type FirebaseSimpleNotification struct {
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 	"github.com/savannahghi/firebasetools"
 	"github.com/stretchr/testify/assert"
 )
@@ -407,6 +408,30 @@ func TestSavedNotification_IsEntity(t *testing.T) {
 	}
 }
 
+func TestSavedNotification_Acknowledge(t *testing.T) {
+	sent := time.Now()
+	opened := sent.Add(time.Minute)
+	later := opened.Add(time.Minute)
+	n := dto.SavedNotification{Status: domain.NotificationStatusSent}
+
+	assert.False(t, n.Acknowledge(domain.NotificationStatusSent, sent))
+
+	assert.True(t, n.Acknowledge(domain.NotificationStatusOpened, opened))
+	assert.Equal(t, domain.NotificationStatusOpened, n.Status)
+	assert.Equal(t, &opened, n.OpenedAt)
+	assert.Equal(t, &opened, n.DeliveredAt, "an opened notification was delivered")
+
+	assert.False(t, n.Acknowledge(domain.NotificationStatusDelivered, later))
+	assert.Equal(t, &opened, n.DeliveredAt)
+
+	assert.True(t, n.Acknowledge(domain.NotificationStatusDismissed, later))
+	assert.Equal(t, domain.NotificationStatusOpened, n.Status, "a dismissal does not undo an open")
+	assert.Equal(t, &later, n.DismissedAt)
+
+	assert.False(t, n.Acknowledge(domain.NotificationStatusOpened, later))
+	assert.Equal(t, &opened, n.OpenedAt)
+}
+
 func TestNewOKResp(t *testing.T) {
 	type args struct {
 		rawResponse interface{}
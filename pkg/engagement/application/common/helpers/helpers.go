@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/savannahghi/feedlib"
@@ -18,8 +19,42 @@ const (
 	ServiceName = "engagement"
 	// TopicVersion ...
 	TopicVersion = "v1"
+
+	// ConcurrentRequests is the number of requests, e.g profile lookups, that
+	// are made to another service at the same time
+	ConcurrentRequests = 10
 )
 
+// ForEachConcurrently calls fn with each index below count, with at most
+// limit calls running at the same time. It waits for every call to return
+// and returns the first error.
+func ForEachConcurrently(count int, limit int, fn func(i int) error) error {
+	if limit < 1 {
+		limit = 1
+	}
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	slots := make(chan struct{}, limit)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			if err := fn(i); err != nil {
+				once.Do(func() { firstErr = err })
+			}
+		}(i)
+	}
+	wg.Wait()
+	return firstErr
+}
+
 // AddPubSubNamespace creates a namespaced topic name
 func AddPubSubNamespace(topicName string) string {
 	environment := serverutils.GetRunningEnvironment()
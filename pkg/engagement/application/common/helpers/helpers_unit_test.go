@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
 	"testing"
 	"time"
 
@@ -295,3 +296,44 @@ func Test_StripMailGunIDSpecialCharacters(t *testing.T) {
 		})
 	}
 }
+
+func Test_ForEachConcurrently(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		running int
+		most    int
+	)
+	squares := make([]int, 20)
+	err := helpers.ForEachConcurrently(len(squares), 3, func(i int) error {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+		squares[i] = i * i
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.LessOrEqual(t, most, 3)
+	for i, square := range squares {
+		assert.Equal(t, i*i, square)
+	}
+
+	calls := 0
+	err = helpers.ForEachConcurrently(5, 1, func(i int) error {
+		calls++
+		if i == 2 {
+			return fmt.Errorf("failed at %d", i)
+		}
+		return nil
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 5, calls, "every call is made")
+}
@@ -0,0 +1,239 @@
+// Package i18n holds the translation bundles that ship with the engagement
+// service and the rules used to pick a language for a user or request.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// supported languages
+const (
+	// English is the language that content is authored in by default
+	English = "en"
+
+	// Swahili is used for users who prefer it on their profile or request it
+	Swahili = "sw"
+
+	// DefaultLanguage is used when no supported language can be resolved
+	DefaultLanguage = English
+)
+
+// message keys, as used in the translation bundles
+const (
+	OTPMessage             = "otp_message"
+	OTPEmailSubject        = "otp_email_subject"
+	PhoneVerificationCode  = "phone_verification_code"
+	EmailVerificationCode  = "email_verification_code"
+	TemporaryPINSMS        = "temporary_pin_sms"
+	TemporaryPINWhatsApp   = "temporary_pin_whatsapp"
+	InboxNotificationTitle = "inbox_notification_title"
+	InboxNotificationBody  = "inbox_notification_body"
+	ExpiryReminderTitle    = "expiry_reminder_title"
+	ExpiryReminderBody     = "expiry_reminder_body"
+	MessagePostedTitle     = "message_posted_title"
+	MessageMentionTitle    = "message_mention_title"
+	MessagePostedBody      = "message_posted_body"
+	DigestTitle            = "digest_title"
+	DigestSummary          = "digest_summary"
+	DigestItemsHeading     = "digest_items_heading"
+	DigestNudgesHeading    = "digest_nudges_heading"
+)
+
+const acceptLanguageHeader = "Accept-Language"
+
+type contextKey string
+
+const languageContextKey = contextKey("language")
+
+//go:embed bundles/*.json
+var bundleFiles embed.FS
+
+// bundles maps a language to its message templates
+var bundles = loadBundles()
+
+func loadBundles() map[string]map[string]string {
+	files, err := bundleFiles.ReadDir("bundles")
+	if err != nil {
+		log.Panicf("unable to read translation bundles: %s", err)
+	}
+
+	loaded := map[string]map[string]string{}
+	for _, f := range files {
+		data, err := bundleFiles.ReadFile(path.Join("bundles", f.Name()))
+		if err != nil {
+			log.Panicf("unable to read translation bundle %s: %s", f.Name(), err)
+		}
+
+		messages := map[string]string{}
+		err = json.Unmarshal(data, &messages)
+		if err != nil {
+			log.Panicf("invalid translation bundle %s: %s", f.Name(), err)
+		}
+		loaded[strings.TrimSuffix(f.Name(), path.Ext(f.Name()))] = messages
+	}
+
+	if _, ok := loaded[DefaultLanguage]; !ok {
+		log.Panicf("no translation bundle for the default language %s", DefaultLanguage)
+	}
+	return loaded
+}
+
+// SupportedLanguages returns the languages that have a translation bundle
+func SupportedLanguages() []string {
+	languages := []string{}
+	for language := range bundles {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// IsSupported reports whether the supplied language tag resolves to a
+// language that has a translation bundle
+func IsSupported(tag string) bool {
+	_, ok := bundles[normalize(tag)]
+	return ok
+}
+
+// normalize reduces a BCP 47 tag such as `sw-KE` or `EN_us` to its lowercase
+// primary language subtag
+func normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// Resolve returns the first supported language among the candidates, which
+// are checked in order of preference. Regional variants fall back to their
+// primary language e.g `sw-KE` resolves to `sw`. When none of the candidates
+// is supported, the default language is returned.
+func Resolve(candidates ...string) string {
+	for _, candidate := range candidates {
+		if IsSupported(candidate) {
+			return normalize(candidate)
+		}
+	}
+	return DefaultLanguage
+}
+
+// ParseAcceptLanguage returns the language tags in an `Accept-Language`
+// header value, ordered from the most to the least preferred
+func ParseAcceptLanguage(header string) []string {
+	type weightedTag struct {
+		tag    string
+		weight float64
+	}
+
+	weighted := []weightedTag{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		weight := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err == nil {
+				weight = q
+			}
+		}
+		if weight <= 0 {
+			continue
+		}
+		weighted = append(weighted, weightedTag{tag: tag, weight: weight})
+	}
+
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].weight > weighted[j].weight
+	})
+
+	tags := []string{}
+	for _, w := range weighted {
+		tags = append(tags, w.tag)
+	}
+	return tags
+}
+
+// WithLanguage returns a copy of the context that carries an explicitly
+// requested language. Unsupported languages are ignored so that later
+// resolution steps (e.g the user's profile) still get a chance.
+func WithLanguage(ctx context.Context, tag string) context.Context {
+	if !IsSupported(tag) {
+		return ctx
+	}
+	return context.WithValue(ctx, languageContextKey, normalize(tag))
+}
+
+// LanguageFromContext returns the language that was explicitly requested for
+// the current request, if any
+func LanguageFromContext(ctx context.Context) (string, bool) {
+	language, ok := ctx.Value(languageContextKey).(string)
+	return language, ok && language != ""
+}
+
+// ContextLanguage returns the language requested in the context or the
+// default language
+func ContextLanguage(ctx context.Context) string {
+	if language, ok := LanguageFromContext(ctx); ok {
+		return language
+	}
+	return DefaultLanguage
+}
+
+// AcceptLanguageMiddleware records the most preferred supported language in
+// the request's `Accept-Language` header on the request context
+func AcceptLanguageMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tags := ParseAcceptLanguage(r.Header.Get(acceptLanguageHeader))
+			for _, tag := range tags {
+				if IsSupported(tag) {
+					r = r.WithContext(WithLanguage(r.Context(), tag))
+					break
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Translate formats the message with the supplied key in the requested
+// language. Messages that are missing from the language's bundle fall back to
+// the default language. An unknown key is returned as is.
+func Translate(language string, key string, args ...interface{}) string {
+	template, ok := bundles[normalize(language)][key]
+	if !ok {
+		template, ok = bundles[DefaultLanguage][key]
+	}
+	if !ok {
+		return key
+	}
+
+	if len(args) == 0 {
+		return template
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// TranslateContext translates a message into the language requested in the
+// context
+func TranslateContext(ctx context.Context, key string, args ...interface{}) string {
+	return Translate(ContextLanguage(ctx), key, args...)
+}
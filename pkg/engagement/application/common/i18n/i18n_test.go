@@ -0,0 +1,149 @@
+package i18n_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/i18n"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundlesHaveTheSameKeys(t *testing.T) {
+	keys := []string{
+		i18n.OTPMessage,
+		i18n.OTPEmailSubject,
+		i18n.PhoneVerificationCode,
+		i18n.EmailVerificationCode,
+		i18n.TemporaryPINSMS,
+		i18n.TemporaryPINWhatsApp,
+		i18n.InboxNotificationTitle,
+		i18n.InboxNotificationBody,
+		i18n.ExpiryReminderTitle,
+		i18n.ExpiryReminderBody,
+		i18n.MessagePostedTitle,
+		i18n.MessageMentionTitle,
+		i18n.MessagePostedBody,
+		i18n.DigestTitle,
+		i18n.DigestSummary,
+		i18n.DigestItemsHeading,
+		i18n.DigestNudgesHeading,
+	}
+	for _, language := range i18n.SupportedLanguages() {
+		for _, key := range keys {
+			assert.NotEqual(t, key, i18n.Translate(language, key), "%s is missing %s", language, key)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		want       string
+	}{
+		{
+			name:       "happy case: exact match",
+			candidates: []string{"sw"},
+			want:       i18n.Swahili,
+		},
+		{
+			name:       "happy case: regional variant falls back to the primary language",
+			candidates: []string{"SW-ke"},
+			want:       i18n.Swahili,
+		},
+		{
+			name:       "happy case: first supported candidate wins",
+			candidates: []string{"fr", "", "en-GB", "sw"},
+			want:       i18n.English,
+		},
+		{
+			name:       "sad case: nothing supported",
+			candidates: []string{"fr", "de"},
+			want:       i18n.DefaultLanguage,
+		},
+		{
+			name: "sad case: no candidates",
+			want: i18n.DefaultLanguage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, i18n.Resolve(tt.candidates...))
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []string
+	}{
+		{
+			name:   "happy case: ordered by weight",
+			header: "en;q=0.5, sw-KE, fr;q=0.8",
+			want:   []string{"sw-KE", "fr", "en"},
+		},
+		{
+			name:   "happy case: wildcard and zero weights are dropped",
+			header: "*, de;q=0, sw;q=0.1",
+			want:   []string{"sw"},
+		},
+		{
+			name:   "sad case: empty header",
+			header: "",
+			want:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, i18n.ParseAcceptLanguage(tt.header))
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	assert.Equal(
+		t,
+		"Nambari yako ya uthibitisho wa simu ni 1234",
+		i18n.Translate("sw-KE", i18n.PhoneVerificationCode, "1234"),
+	)
+	assert.Equal(
+		t,
+		"Your phone number verification code is 1234",
+		i18n.Translate("fr", i18n.PhoneVerificationCode, "1234"),
+	)
+	assert.Equal(t, "unknown_key", i18n.Translate(i18n.Swahili, "unknown_key"))
+}
+
+func TestWithLanguage(t *testing.T) {
+	ctx := context.Background()
+	_, ok := i18n.LanguageFromContext(ctx)
+	assert.False(t, ok)
+	assert.Equal(t, i18n.DefaultLanguage, i18n.ContextLanguage(ctx))
+
+	unsupported := i18n.WithLanguage(ctx, "fr")
+	_, ok = i18n.LanguageFromContext(unsupported)
+	assert.False(t, ok)
+
+	swahili := i18n.WithLanguage(ctx, "sw-TZ")
+	language, ok := i18n.LanguageFromContext(swahili)
+	assert.True(t, ok)
+	assert.Equal(t, i18n.Swahili, language)
+}
+
+func TestAcceptLanguageMiddleware(t *testing.T) {
+	var got string
+	handler := i18n.AcceptLanguageMiddleware()(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = i18n.ContextLanguage(r.Context())
+		}),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "fr-FR, sw;q=0.9, en;q=0.8")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, i18n.Swahili, got)
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"github.com/savannahghi/feedlib"
+)
+
+// ActionInvocation is a request, from a client, to run the server-side
+// handler of one of the actions in a user's feed
+type ActionInvocation struct {
+	UID     string                 `json:"uid"`
+	Flavour feedlib.Flavour        `json:"flavour"`
+	Action  feedlib.Action         `json:"action"`
+	Payload map[string]interface{} `json:"payload"`
+}
+
+// ActionResult is the outcome of running an action's server-side handler
+type ActionResult struct {
+	ActionID string `json:"actionID"`
+	Name     string `json:"name"`
+
+	// a human readable description of what the handler did
+	Message string `json:"message"`
+
+	// any data that the client needs in order to carry on with the action
+	Data map[string]interface{} `json:"data"`
+}
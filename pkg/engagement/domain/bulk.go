@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// BulkOperation is a change that is applied to many feed elements at once
+type BulkOperation string
+
+// known bulk operations
+const (
+	BulkOperationResolve   BulkOperation = "RESOLVE"
+	BulkOperationUnresolve BulkOperation = "UNRESOLVE"
+	BulkOperationPin       BulkOperation = "PIN"
+	BulkOperationUnpin     BulkOperation = "UNPIN"
+	BulkOperationHide      BulkOperation = "HIDE"
+	BulkOperationShow      BulkOperation = "SHOW"
+	BulkOperationDelete    BulkOperation = "DELETE"
+)
+
+// AllBulkOperation is the set of known bulk operations
+var AllBulkOperation = []BulkOperation{
+	BulkOperationResolve,
+	BulkOperationUnresolve,
+	BulkOperationPin,
+	BulkOperationUnpin,
+	BulkOperationHide,
+	BulkOperationShow,
+	BulkOperationDelete,
+}
+
+// IsValid returns true if a bulk operation is valid
+func (e BulkOperation) IsValid() bool {
+	switch e {
+	case BulkOperationResolve,
+		BulkOperationUnresolve,
+		BulkOperationPin,
+		BulkOperationUnpin,
+		BulkOperationHide,
+		BulkOperationShow,
+		BulkOperationDelete:
+		return true
+	}
+	return false
+}
+
+// AppliesTo returns true if the operation can be applied to the element type.
+// Only items can be pinned and unpinned.
+func (e BulkOperation) AppliesTo(elementType ElementType) bool {
+	switch elementType {
+	case ElementTypeItem:
+		return e.IsValid()
+	case ElementTypeNudge:
+		return e.IsValid() && e != BulkOperationPin && e != BulkOperationUnpin
+	}
+	return false
+}
+
+func (e BulkOperation) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL translates the input value given into a bulk operation
+func (e *BulkOperation) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = BulkOperation(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid BulkOperation", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the bulk operation to the supplied writer
+func (e BulkOperation) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// BulkElementResult is the outcome of a bulk operation on one element
+type BulkElementResult struct {
+	ElementType ElementType `json:"elementType"`
+	ElementID   string      `json:"elementID"`
+	Succeeded   bool        `json:"succeeded"`
+	Error       string      `json:"error,omitempty"`
+}
+
+// BulkResult is the outcome of a bulk operation on a feed's elements
+type BulkResult struct {
+	UID       string              `json:"uid"`
+	Flavour   feedlib.Flavour     `json:"flavour"`
+	Operation BulkOperation       `json:"operation"`
+	Results   []BulkElementResult `json:"results"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+}
+
+// Add records the outcome of the operation on an element
+func (b *BulkResult) Add(elementType ElementType, elementID string, err error) {
+	result := BulkElementResult{
+		ElementType: elementType,
+		ElementID:   elementID,
+		Succeeded:   err == nil,
+	}
+	if err != nil {
+		result.Error = err.Error()
+		b.Failed++
+	} else {
+		b.Succeeded++
+	}
+	b.Results = append(b.Results, result)
+}
+
+// ValidateAndUnmarshal checks that the input data is a valid bulk result and
+// unmarshals it if it is
+func (b *BulkResult) ValidateAndUnmarshal(data []byte) error {
+	err := json.Unmarshal(data, b)
+	if err != nil {
+		return fmt.Errorf("invalid bulk result JSON: %w", err)
+	}
+	if !b.Operation.IsValid() {
+		return fmt.Errorf("invalid bulk operation: %s", b.Operation)
+	}
+	return nil
+}
+
+// ValidateAndMarshal checks that a bulk result is valid then marshals it to
+// JSON so that it can be published
+func (b *BulkResult) ValidateAndMarshal() ([]byte, error) {
+	if !b.Operation.IsValid() {
+		return nil, fmt.Errorf("invalid bulk operation: %s", b.Operation)
+	}
+	return json.Marshal(b)
+}
@@ -0,0 +1,167 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Checklist breaks a feed item down into ordered steps e.g the steps of
+// "complete onboarding".
+//
+// A step unlocks once every step before it is done. The item can't be worked
+// on until the items that it depends on have been resolved, and it is resolved
+// automatically when its last step is done.
+type Checklist struct {
+	ItemID string          `json:"itemID" firestore:"itemID"`
+	Steps  []ChecklistStep `json:"steps" firestore:"steps"`
+
+	// the IDs of the items that should be resolved before this item
+	DependsOn []string `json:"dependsOn" firestore:"dependsOn"`
+}
+
+// ChecklistStep is one step of a checklist
+type ChecklistStep struct {
+	ID          string `json:"id" firestore:"id"`
+	Title       string `json:"title" firestore:"title"`
+	Description string `json:"description" firestore:"description"`
+
+	// an event that completes the step when it is processed for the feed e.g
+	// `PROFILE_COMPLETED`. Steps without an event are completed explicitly.
+	EventName string `json:"eventName,omitempty" firestore:"eventName,omitempty"`
+
+	Done        bool       `json:"done" firestore:"done"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" firestore:"completedAt,omitempty"`
+}
+
+// ItemProgress reports how far along a feed item's checklist is
+type ItemProgress struct {
+	ItemID         string `json:"itemID"`
+	CompletedSteps int    `json:"completedSteps"`
+	TotalSteps     int    `json:"totalSteps"`
+
+	// the first step that is yet to be done, if any
+	NextStepID string `json:"nextStepID,omitempty"`
+
+	// the items that should be resolved before this item can be worked on
+	BlockedBy []string `json:"blockedBy"`
+}
+
+// Validate checks that a checklist has steps with unique IDs and titles and
+// that it does not depend on its own item
+func (c Checklist) Validate() error {
+	if strings.TrimSpace(c.ItemID) == "" {
+		return fmt.Errorf("a checklist should belong to an item")
+	}
+	if len(c.Steps) == 0 {
+		return fmt.Errorf("a checklist should have at least one step")
+	}
+
+	ids := map[string]bool{}
+	for _, step := range c.Steps {
+		if strings.TrimSpace(step.ID) == "" {
+			return fmt.Errorf("every checklist step should have an ID")
+		}
+		if strings.TrimSpace(step.Title) == "" {
+			return fmt.Errorf("checklist step `%s` should have a title", step.ID)
+		}
+		if ids[step.ID] {
+			return fmt.Errorf("duplicate checklist step `%s`", step.ID)
+		}
+		ids[step.ID] = true
+	}
+
+	for _, itemID := range c.DependsOn {
+		if itemID == c.ItemID {
+			return fmt.Errorf("an item can't depend on itself")
+		}
+	}
+	return nil
+}
+
+// CompletedSteps returns the number of steps that are done
+func (c Checklist) CompletedSteps() int {
+	completed := 0
+	for _, step := range c.Steps {
+		if step.Done {
+			completed++
+		}
+	}
+	return completed
+}
+
+// Done is true when every step of the checklist is done
+func (c Checklist) Done() bool {
+	return len(c.Steps) > 0 && c.CompletedSteps() == len(c.Steps)
+}
+
+// NextStep returns the first step that is yet to be done. It is nil when the
+// checklist is done.
+func (c Checklist) NextStep() *ChecklistStep {
+	for i := range c.Steps {
+		if !c.Steps[i].Done {
+			return &c.Steps[i]
+		}
+	}
+	return nil
+}
+
+// CompleteStep marks a step as done. Steps are done in order, so completing a
+// step before the steps above it is an error. Completing a step that is
+// already done does nothing and returns false.
+func (c *Checklist) CompleteStep(stepID string, at time.Time) (bool, error) {
+	for i := range c.Steps {
+		step := &c.Steps[i]
+		if step.ID != stepID {
+			if !step.Done {
+				return false, fmt.Errorf(
+					"step `%s` should be done before step `%s`", step.ID, stepID)
+			}
+			continue
+		}
+		if step.Done {
+			return false, nil
+		}
+		step.Done = true
+		step.CompletedAt = &at
+		return true, nil
+	}
+	return false, fmt.Errorf("no checklist step `%s` found", stepID)
+}
+
+// CarryOver keeps the completion state of the steps that an earlier version
+// of the checklist had in common with this one
+func (c *Checklist) CarryOver(previous *Checklist) {
+	if previous == nil {
+		return
+	}
+	done := map[string]*time.Time{}
+	for _, step := range previous.Steps {
+		if step.Done {
+			done[step.ID] = step.CompletedAt
+		}
+	}
+	for i := range c.Steps {
+		if completedAt, ok := done[c.Steps[i].ID]; ok {
+			c.Steps[i].Done = true
+			c.Steps[i].CompletedAt = completedAt
+		}
+	}
+}
+
+// Progress summarizes the checklist for the feed
+func (c Checklist) Progress(blockedBy []string) ItemProgress {
+	progress := ItemProgress{
+		ItemID:         c.ItemID,
+		CompletedSteps: c.CompletedSteps(),
+		TotalSteps:     len(c.Steps),
+		BlockedBy:      blockedBy,
+	}
+	if progress.BlockedBy == nil {
+		progress.BlockedBy = []string{}
+	}
+	if next := c.NextStep(); next != nil {
+		progress.NextStepID = next.ID
+	}
+	return progress
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// DeliveryStatus is the outcome of sending a notification over one channel
+type DeliveryStatus string
+
+// known delivery statuses
+const (
+	// the notification was handed to the channel's provider for every
+	// recipient
+	DeliveryStatusSent DeliveryStatus = "SENT"
+
+	// the notification reached some of the recipients but not all of them
+	DeliveryStatusPartial DeliveryStatus = "PARTIAL"
+
+	// the notification could not be sent to any recipient
+	DeliveryStatusFailed DeliveryStatus = "FAILED"
+
+	// the notification was not sent e.g because the recipients have no
+	// address for the channel
+	DeliveryStatusSkipped DeliveryStatus = "SKIPPED"
+
+	// the notification is held back until the recipients' quiet hours end
+	DeliveryStatusDeferred DeliveryStatus = "DEFERRED"
+
+	// the recipients do not want the notification
+	DeliveryStatusSuppressed DeliveryStatus = "SUPPRESSED"
+)
+
+// AllDeliveryStatus is the set of known delivery statuses
+var AllDeliveryStatus = []DeliveryStatus{
+	DeliveryStatusSent,
+	DeliveryStatusPartial,
+	DeliveryStatusFailed,
+	DeliveryStatusSkipped,
+	DeliveryStatusDeferred,
+	DeliveryStatusSuppressed,
+}
+
+// IsValid returns true if a delivery status is valid
+func (e DeliveryStatus) IsValid() bool {
+	switch e {
+	case DeliveryStatusSent,
+		DeliveryStatusPartial,
+		DeliveryStatusFailed,
+		DeliveryStatusSkipped,
+		DeliveryStatusDeferred,
+		DeliveryStatusSuppressed:
+		return true
+	}
+	return false
+}
+
+func (e DeliveryStatus) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL translates the input value given into a delivery status
+func (e *DeliveryStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DeliveryStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DeliveryStatus", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the delivery status to the supplied writer
+func (e DeliveryStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// ChannelDelivery records the outcome of sending a feed element's
+// notification over one of its notification channels
+type ChannelDelivery struct {
+	ID          string          `json:"id" firestore:"id"`
+	ElementType ElementType     `json:"elementType" firestore:"elementType"`
+	ElementID   string          `json:"elementID" firestore:"elementID"`
+	Sender      string          `json:"sender" firestore:"sender"`
+	Channel     feedlib.Channel `json:"channel" firestore:"channel"`
+	Status      DeliveryStatus  `json:"status" firestore:"status"`
+
+	// the number of addresses (device tokens, email addresses or phone
+	// numbers) that the notification was sent, or failed to be sent, to
+	Sent   int `json:"sent" firestore:"sent"`
+	Failed int `json:"failed" firestore:"failed"`
+
+	// the number of recipients that the notification was held back for, or
+	// not sent to, because of their notification preferences
+	Deferred   int `json:"deferred" firestore:"deferred"`
+	Suppressed int `json:"suppressed" firestore:"suppressed"`
+
+	// the last error, if any
+	Error string `json:"error,omitempty" firestore:"error,omitempty"`
+
+	// identifies the Pub/Sub message and recipients that the notification
+	// was sent for, so that a redelivery of the message does not send it
+	// again
+	DeliveryKey string `json:"deliveryKey,omitempty" firestore:"deliveryKey,omitempty"`
+
+	Timestamp time.Time `json:"timestamp" firestore:"timestamp"`
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// DigestFrequency is how often a user gets a summary of their unread items
+// and pending nudges
+type DigestFrequency string
+
+// known digest frequencies
+const (
+	DigestFrequencyOff    DigestFrequency = "OFF"
+	DigestFrequencyDaily  DigestFrequency = "DAILY"
+	DigestFrequencyWeekly DigestFrequency = "WEEKLY"
+)
+
+// AllDigestFrequency is the set of known digest frequencies
+var AllDigestFrequency = []DigestFrequency{
+	DigestFrequencyOff,
+	DigestFrequencyDaily,
+	DigestFrequencyWeekly,
+}
+
+// IsValid returns true if a digest frequency is valid
+func (e DigestFrequency) IsValid() bool {
+	switch e {
+	case DigestFrequencyOff, DigestFrequencyDaily, DigestFrequencyWeekly:
+		return true
+	}
+	return false
+}
+
+func (e DigestFrequency) String() string {
+	return string(e)
+}
+
+// Period returns the time between two digests. It is zero when digests are
+// off.
+func (e DigestFrequency) Period() time.Duration {
+	switch e {
+	case DigestFrequencyDaily:
+		return 24 * time.Hour
+	case DigestFrequencyWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// UnmarshalGQL translates the input value given into a digest frequency
+func (e *DigestFrequency) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DigestFrequency(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DigestFrequency", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the digest frequency to the supplied writer
+func (e DigestFrequency) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// DigestSettings is how a user gets the digest of one of their feeds
+type DigestSettings struct {
+	UID       string          `json:"uid" firestore:"uid"`
+	Flavour   feedlib.Flavour `json:"flavour" firestore:"flavour"`
+	Frequency DigestFrequency `json:"frequency" firestore:"frequency"`
+
+	// the digest is sent by EMAIL or SMS
+	Channel feedlib.Channel `json:"channel" firestore:"channel"`
+
+	// the end of the period that the last digest covered. Only items and
+	// nudges that are newer go into the next digest.
+	LastDigestAt time.Time `json:"lastDigestAt,omitempty" firestore:"lastDigestAt,omitempty"`
+
+	// nudges do not carry a timestamp, so the pending nudges that the last
+	// digest listed are kept to tell the new ones apart
+	DigestedNudgeIDs []string `json:"digestedNudgeIDs,omitempty" firestore:"digestedNudgeIDs,omitempty"`
+
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// Validate ensures that the digest settings can be applied
+func (d DigestSettings) Validate() error {
+	if !d.Flavour.IsValid() {
+		return fmt.Errorf("%s is not a valid flavour", d.Flavour)
+	}
+	if !d.Frequency.IsValid() {
+		return fmt.Errorf("%s is not a valid digest frequency", d.Frequency)
+	}
+	if d.Channel != feedlib.ChannelEmail && d.Channel != feedlib.ChannelSms {
+		return fmt.Errorf("digests can't be sent over %s", d.Channel)
+	}
+	return nil
+}
+
+// DigestReport summarizes a run of the digest scheduler
+type DigestReport struct {
+	// feeds whose digest was due and has been queued to be built and sent
+	Queued int `json:"queued"`
+
+	// digests that could not be queued. They are retried on the next run.
+	Errors []string `json:"errors"`
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// ExpiringElement is a feed item or nudge whose expiry falls before a cutoff,
+// together with the feed that it belongs to.
+//
+// Only one of `Item` or `Nudge` is set, as indicated by the element type.
+type ExpiringElement struct {
+	UID         string          `json:"uid"`
+	Flavour     feedlib.Flavour `json:"flavour"`
+	ElementType ElementType     `json:"elementType"`
+	Item        *feedlib.Item   `json:"item,omitempty"`
+	Nudge       *feedlib.Nudge  `json:"nudge,omitempty"`
+
+	// the position of the element in the scan that found it
+	Cursor ExpiryCursor `json:"-"`
+}
+
+// ExpiryCursor is the position of an element in a scan of expiring elements.
+// Elements are scanned in order of their expiry, and then of the path of
+// their document.
+type ExpiryCursor struct {
+	Expiry time.Time
+	Path   string
+}
+
+// ID returns the ID of the expiring item or nudge
+func (e ExpiringElement) ID() string {
+	if e.Item != nil {
+		return e.Item.ID
+	}
+	if e.Nudge != nil {
+		return e.Nudge.ID
+	}
+	return ""
+}
+
+// Expiry returns the expiry of the expiring item or nudge
+func (e ExpiringElement) Expiry() time.Time {
+	if e.Item != nil {
+		return e.Item.Expiry
+	}
+	if e.Nudge != nil {
+		return e.Nudge.Expiry
+	}
+	return time.Time{}
+}
+
+// ExpiryReport summarizes a run of the expiry processor
+type ExpiryReport struct {
+	ArchivedItems  int `json:"archivedItems"`
+	ArchivedNudges int `json:"archivedNudges"`
+
+	// "due soon" reminders that were queued for sending
+	ItemReminders  int `json:"itemReminders"`
+	NudgeReminders int `json:"nudgeReminders"`
+
+	// elements that could not be processed. They are retried on the next run.
+	Errors []string `json:"errors"`
+
+	// true when the run stopped before it got to every expiring element. The
+	// rest are processed on the next run.
+	HasMore bool `json:"hasMore"`
+}
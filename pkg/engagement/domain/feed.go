@@ -37,6 +37,9 @@ type Feed struct {
 
 	// indicates whether the user is Anonymous or not
 	IsAnonymous *bool `json:"isAnonymous" firestore:"isAnonymous"`
+
+	// how far along the items that have checklists are (computed, not stored)
+	Progress []ItemProgress `json:"progress,omitempty" firestore:"-"`
 }
 
 // GetID return the feed ID
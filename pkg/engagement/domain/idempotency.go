@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"time"
+)
+
+// IdempotencyRecord remembers a request that was made with an idempotency
+// key so that repeats of the request are answered with the first response
+// instead of being carried out again.
+type IdempotencyRecord struct {
+	// what the key applies to e.g an endpoint
+	Scope string `json:"scope" firestore:"scope"`
+	Key   string `json:"key" firestore:"key"`
+
+	// a hash of the request. Reusing a key for a different request is an error.
+	Fingerprint string `json:"fingerprint" firestore:"fingerprint"`
+
+	// the response, set once the first request completes
+	Completed bool                `json:"completed" firestore:"completed"`
+	Response  *IdempotentResponse `json:"response,omitempty" firestore:"response,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt" firestore:"expiresAt"`
+}
+
+// IdempotentResponse is a stored response that is replayed for repeated
+// requests
+type IdempotentResponse struct {
+	StatusCode  int    `json:"statusCode" firestore:"statusCode"`
+	ContentType string `json:"contentType" firestore:"contentType"`
+	Body        []byte `json:"body" firestore:"body"`
+}
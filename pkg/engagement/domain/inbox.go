@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// InboxCountPush is a pending push of a feed's unread inbox count. Inbox
+// changes that happen before it is due are collapsed into it.
+type InboxCountPush struct {
+	UID     string          `json:"uid" firestore:"uid"`
+	Flavour feedlib.Flavour `json:"flavour" firestore:"flavour"`
+
+	// the number of inbox changes collapsed into the push
+	Changes int `json:"changes" firestore:"changes"`
+
+	// when the push goes out i.e the end of the window opened by the first
+	// change
+	DueAt time.Time `json:"dueAt" firestore:"dueAt"`
+
+	// the time of the latest change
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// InboxCountPushReport summarizes a run of the inbox count push processor
+type InboxCountPushReport struct {
+	// feeds whose count was pushed, and how many of those also got a tray
+	// notification
+	Pushed   int `json:"pushed"`
+	Notified int `json:"notified"`
+
+	// the inbox changes that the pushes stood in for
+	Changes int `json:"changes"`
+
+	// pushes that failed. They are retried on the next run.
+	Errors []string `json:"errors"`
+}
@@ -0,0 +1,13 @@
+package domain
+
+// LabelCount holds the number of items in a feed that carry a label
+type LabelCount struct {
+	Label string `json:"label"`
+
+	// every item with the label, whatever its status or visibility
+	Items int `json:"items"`
+
+	// persistent items with the label that are still pending, visible and
+	// not expired i.e what counts towards the inbox's unread count
+	Unread int `json:"unread"`
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// FeedMerge records that the feed of an anonymous user was merged into the
+// feed of the user that they signed up as.
+//
+// It is saved against the anonymous feed so that a merge is only done once
+// and so that the anonymous feed is not used again.
+type FeedMerge struct {
+	AnonymousUID string          `json:"anonymousUID" firestore:"anonymousUID"`
+	UID          string          `json:"uid" firestore:"uid"`
+	Flavour      feedlib.Flavour `json:"flavour" firestore:"flavour"`
+	MergedAt     time.Time       `json:"mergedAt" firestore:"mergedAt"`
+
+	// the number of elements whose state was carried over to the permanent
+	// feed, either by updating a matching element or by copying the element
+	Items    int `json:"items" firestore:"items"`
+	Nudges   int `json:"nudges" firestore:"nudges"`
+	Actions  int `json:"actions" firestore:"actions"`
+	Messages int `json:"messages" firestore:"messages"`
+	Labels   int `json:"labels" firestore:"labels"`
+	Events   int `json:"events" firestore:"events"`
+}
@@ -0,0 +1,202 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// ModerationStatus is the state of a reported message's review
+type ModerationStatus string
+
+// known moderation statuses
+const (
+	// the message is waiting to be reviewed
+	ModerationStatusPending ModerationStatus = "PENDING"
+
+	// the message was hidden from the item's conversation
+	ModerationStatusHidden ModerationStatus = "HIDDEN"
+
+	// the message was reviewed and left (or put back) in the conversation
+	ModerationStatusDismissed ModerationStatus = "DISMISSED"
+)
+
+// AllModerationStatus is the set of known moderation statuses
+var AllModerationStatus = []ModerationStatus{
+	ModerationStatusPending,
+	ModerationStatusHidden,
+	ModerationStatusDismissed,
+}
+
+// IsValid returns true if a moderation status is valid
+func (e ModerationStatus) IsValid() bool {
+	switch e {
+	case ModerationStatusPending,
+		ModerationStatusHidden,
+		ModerationStatusDismissed:
+		return true
+	}
+	return false
+}
+
+func (e ModerationStatus) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL translates the input value given into a moderation status
+func (e *ModerationStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ModerationStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ModerationStatus", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the moderation status to the supplied writer
+func (e ModerationStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// ModerationAction is something that was done to moderate a message, either
+// by a moderator or by the automatic filter
+type ModerationAction string
+
+// known moderation actions
+const (
+	ModerationActionHide    ModerationAction = "HIDE"
+	ModerationActionRestore ModerationAction = "RESTORE"
+	ModerationActionDismiss ModerationAction = "DISMISS"
+
+	// a message was rejected by the filter because of a blocked term
+	ModerationActionBlock ModerationAction = "BLOCK"
+
+	// personal information was removed from a message by the filter
+	ModerationActionRedact ModerationAction = "REDACT"
+)
+
+// AllModerationAction is the set of known moderation actions
+var AllModerationAction = []ModerationAction{
+	ModerationActionHide,
+	ModerationActionRestore,
+	ModerationActionDismiss,
+	ModerationActionBlock,
+	ModerationActionRedact,
+}
+
+// IsValid returns true if a moderation action is valid
+func (e ModerationAction) IsValid() bool {
+	switch e {
+	case ModerationActionHide,
+		ModerationActionRestore,
+		ModerationActionDismiss,
+		ModerationActionBlock,
+		ModerationActionRedact:
+		return true
+	}
+	return false
+}
+
+// IsDecision returns true for the actions that a moderator takes on a
+// reported message
+func (e ModerationAction) IsDecision() bool {
+	switch e {
+	case ModerationActionHide,
+		ModerationActionRestore,
+		ModerationActionDismiss:
+		return true
+	}
+	return false
+}
+
+// Status returns the status that a reported message is left in by a
+// moderator's decision
+func (e ModerationAction) Status() ModerationStatus {
+	if e == ModerationActionHide {
+		return ModerationStatusHidden
+	}
+	return ModerationStatusDismissed
+}
+
+func (e ModerationAction) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL translates the input value given into a moderation action
+func (e *ModerationAction) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ModerationAction(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ModerationAction", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the moderation action to the supplied writer
+func (e ModerationAction) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// MessageReport is a user's complaint about a message
+type MessageReport struct {
+	ReportedByUID string    `json:"reportedByUID" firestore:"reportedByUID"`
+	Reason        string    `json:"reason" firestore:"reason"`
+	ReportedAt    time.Time `json:"reportedAt" firestore:"reportedAt"`
+}
+
+// ModerationCase gathers the reports about a message for staff to review.
+//
+// There is one case per message. Later reports are added to it.
+type ModerationCase struct {
+	ID        string          `json:"id" firestore:"id"`
+	UID       string          `json:"uid" firestore:"uid"`
+	Flavour   feedlib.Flavour `json:"flavour" firestore:"flavour"`
+	ItemID    string          `json:"itemID" firestore:"itemID"`
+	MessageID string          `json:"messageID" firestore:"messageID"`
+
+	// the message as it was when it was first reported
+	Text        string `json:"text" firestore:"text"`
+	PostedByUID string `json:"postedByUID" firestore:"postedByUID"`
+
+	Status  ModerationStatus `json:"status" firestore:"status"`
+	Reports []MessageReport  `json:"reports" firestore:"reports"`
+
+	// the last decision on the case, if any
+	DecidedByUID string     `json:"decidedByUID,omitempty" firestore:"decidedByUID,omitempty"`
+	DecidedAt    *time.Time `json:"decidedAt,omitempty" firestore:"decidedAt,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// ModerationAuditEntry records a moderation decision, or an action taken by
+// the automatic filter, for later review
+type ModerationAuditEntry struct {
+	ID string `json:"id" firestore:"id"`
+
+	// the case that was decided. It is blank for the filter's actions.
+	CaseID string `json:"caseID,omitempty" firestore:"caseID,omitempty"`
+
+	UID       string          `json:"uid" firestore:"uid"`
+	Flavour   feedlib.Flavour `json:"flavour" firestore:"flavour"`
+	ItemID    string          `json:"itemID" firestore:"itemID"`
+	MessageID string          `json:"messageID,omitempty" firestore:"messageID,omitempty"`
+
+	Action ModerationAction `json:"action" firestore:"action"`
+
+	// the moderator, or the author of a filtered message
+	ActorUID  string    `json:"actorUID" firestore:"actorUID"`
+	Reason    string    `json:"reason" firestore:"reason"`
+	Timestamp time.Time `json:"timestamp" firestore:"timestamp"`
+}
@@ -0,0 +1,279 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	// the time zone database is embedded so that users' time zones resolve
+	// even where the host has no zoneinfo files
+	_ "time/tzdata"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// NotificationCategory groups notifications so that users can turn off the
+// kinds that they do not want
+type NotificationCategory string
+
+// known notification categories
+const (
+	// feed items that are published to the user
+	NotificationCategoryItems NotificationCategory = "ITEMS"
+
+	// nudges that are published to, or resolved for, the user
+	NotificationCategoryNudges NotificationCategory = "NUDGES"
+
+	// messages posted in the conversations that the user takes part in
+	NotificationCategoryMessages NotificationCategory = "MESSAGES"
+
+	// reminders about items and nudges that expire soon
+	NotificationCategoryReminders NotificationCategory = "REMINDERS"
+
+	// SMS, emails and push notifications sent directly by other services
+	NotificationCategoryGeneral NotificationCategory = "GENERAL"
+
+	// daily or weekly summaries of unread items and pending nudges
+	NotificationCategoryDigests NotificationCategory = "DIGESTS"
+)
+
+// AllNotificationCategory is the set of known notification categories
+var AllNotificationCategory = []NotificationCategory{
+	NotificationCategoryItems,
+	NotificationCategoryNudges,
+	NotificationCategoryMessages,
+	NotificationCategoryReminders,
+	NotificationCategoryGeneral,
+	NotificationCategoryDigests,
+}
+
+// IsValid returns true if a notification category is valid
+func (e NotificationCategory) IsValid() bool {
+	switch e {
+	case NotificationCategoryItems,
+		NotificationCategoryNudges,
+		NotificationCategoryMessages,
+		NotificationCategoryReminders,
+		NotificationCategoryGeneral,
+		NotificationCategoryDigests:
+		return true
+	}
+	return false
+}
+
+func (e NotificationCategory) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL translates the input value given into a notification category
+func (e *NotificationCategory) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NotificationCategory(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NotificationCategory", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the notification category to the supplied writer
+func (e NotificationCategory) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// QuietHours is a daily period in which a user does not want to be
+// notified. Notifications that fall in it are held back until it ends.
+//
+// The period may run past midnight e.g from 22:00 to 07:00.
+type QuietHours struct {
+	// the start and end of the period as 24 hour `HH:MM` times
+	Start string `json:"start" firestore:"start"`
+	End   string `json:"end" firestore:"end"`
+
+	// the IANA name of the user's time zone e.g `Africa/Nairobi`
+	Timezone string `json:"timezone" firestore:"timezone"`
+}
+
+// Validate ensures that the quiet hours can be applied
+func (q QuietHours) Validate() error {
+	start, err := parseClockTime(q.Start)
+	if err != nil {
+		return fmt.Errorf("invalid start of quiet hours: %w", err)
+	}
+	end, err := parseClockTime(q.End)
+	if err != nil {
+		return fmt.Errorf("invalid end of quiet hours: %w", err)
+	}
+	if start == end {
+		return fmt.Errorf("quiet hours should not start and end at the same time")
+	}
+	if q.Timezone == "" {
+		return fmt.Errorf("the time zone of the quiet hours is required")
+	}
+	if _, err := time.LoadLocation(q.Timezone); err != nil {
+		return fmt.Errorf("%s is not a valid time zone", q.Timezone)
+	}
+	return nil
+}
+
+// Until reports whether a time falls in the quiet hours and, when it does,
+// the time at which they end
+func (q QuietHours) Until(t time.Time) (time.Time, bool) {
+	if q.Validate() != nil {
+		return time.Time{}, false
+	}
+	location, _ := time.LoadLocation(q.Timezone)
+	start, _ := parseClockTime(q.Start)
+	end, _ := parseClockTime(q.End)
+
+	local := t.In(location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	now := local.Sub(midnight)
+
+	switch {
+	case start < end && now >= start && now < end:
+		return midnight.Add(end), true
+	case start > end && now >= start:
+		// the period runs on to the next day
+		return midnight.AddDate(0, 0, 1).Add(end), true
+	case start > end && now < end:
+		return midnight.Add(end), true
+	}
+	return time.Time{}, false
+}
+
+// parseClockTime parses an `HH:MM` time into the time since midnight
+func parseClockTime(clock string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a HH:MM time", clock)
+	}
+	return time.Duration(parsed.Hour())*time.Hour +
+		time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// NotificationPreferences are the notifications that a user is willing to
+// receive. Everything is allowed until the user says otherwise.
+type NotificationPreferences struct {
+	UID string `json:"uid" firestore:"uid"`
+
+	// the user does not want any notifications that are not transactional
+	OptedOut bool `json:"optedOut" firestore:"optedOut"`
+
+	DisabledChannels   []feedlib.Channel      `json:"disabledChannels" firestore:"disabledChannels"`
+	DisabledCategories []NotificationCategory `json:"disabledCategories" firestore:"disabledCategories"`
+
+	QuietHours *QuietHours `json:"quietHours,omitempty" firestore:"quietHours,omitempty"`
+
+	// the language that the user's content and notifications are shown in
+	// e.g `sw`. The default language is used when it is not set.
+	Language string `json:"language,omitempty" firestore:"language,omitempty"`
+
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// Validate ensures that the preferences can be applied
+func (p NotificationPreferences) Validate() error {
+	for _, channel := range p.DisabledChannels {
+		if !channel.IsValid() {
+			return fmt.Errorf("%s is not a valid channel", channel)
+		}
+	}
+	for _, category := range p.DisabledCategories {
+		if !category.IsValid() {
+			return fmt.Errorf("%s is not a valid notification category", category)
+		}
+	}
+	if p.QuietHours != nil {
+		return p.QuietHours.Validate()
+	}
+	return nil
+}
+
+// Allows reports whether the user accepts notifications of a category over a
+// channel, quiet hours aside
+func (p NotificationPreferences) Allows(
+	channel feedlib.Channel,
+	category NotificationCategory,
+) bool {
+	if p.OptedOut {
+		return false
+	}
+	for _, disabled := range p.DisabledChannels {
+		if disabled == channel {
+			return false
+		}
+	}
+	for _, disabled := range p.DisabledCategories {
+		if disabled == category {
+			return false
+		}
+	}
+	return true
+}
+
+// DeferUntil returns the time that a notification sent at `t` should be held
+// back until. It is nil when the notification can go out straight away.
+func (p NotificationPreferences) DeferUntil(t time.Time) *time.Time {
+	if p.QuietHours == nil {
+		return nil
+	}
+	end, quiet := p.QuietHours.Until(t)
+	if !quiet {
+		return nil
+	}
+	return &end
+}
+
+// DeferredNotification is a notification that was held back because it fell
+// in the recipient's quiet hours
+type DeferredNotification struct {
+	ID           string               `json:"id" firestore:"id"`
+	RecipientUID string               `json:"recipientUID" firestore:"recipientUID"`
+	Channel      feedlib.Channel      `json:"channel" firestore:"channel"`
+	Category     NotificationCategory `json:"category" firestore:"category"`
+	Sender       string               `json:"sender" firestore:"sender"`
+
+	// the addresses that the notification was sent to. When there are none,
+	// the recipient's addresses for the channel are looked up when it goes
+	// out.
+	Addresses []string `json:"addresses,omitempty" firestore:"addresses,omitempty"`
+
+	Title    string `json:"title,omitempty" firestore:"title,omitempty"`
+	Body     string `json:"body" firestore:"body"`
+	HTML     string `json:"html,omitempty" firestore:"html,omitempty"`
+	ImageURL string `json:"imageURL,omitempty" firestore:"imageURL,omitempty"`
+	LinkURL  string `json:"linkURL,omitempty" firestore:"linkURL,omitempty"`
+
+	// data that is sent along with a push notification
+	Data map[string]interface{} `json:"data,omitempty" firestore:"data,omitempty"`
+
+	// the feed element that the notification is about, if any, and the
+	// notification envelope that is pushed with it
+	UID         string          `json:"uid,omitempty" firestore:"uid,omitempty"`
+	Flavour     feedlib.Flavour `json:"flavour,omitempty" firestore:"flavour,omitempty"`
+	ElementType ElementType     `json:"elementType,omitempty" firestore:"elementType,omitempty"`
+	ElementID   string          `json:"elementID,omitempty" firestore:"elementID,omitempty"`
+	Envelope    []byte          `json:"envelope,omitempty" firestore:"envelope,omitempty"`
+
+	DeliverAt time.Time `json:"deliverAt" firestore:"deliverAt"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+}
+
+// DeferralReport summarizes a run of the deferred notification processor
+type DeferralReport struct {
+	Delivered int `json:"delivered"`
+
+	// notifications that the recipient no longer wants
+	Suppressed int `json:"suppressed"`
+
+	// notifications that fell in the recipient's (changed) quiet hours again
+	Deferred int `json:"deferred"`
+
+	// notifications that could not be sent. They are retried on the next run.
+	Errors []string `json:"errors"`
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"time"
+)
+
+// ProcessedMessage remembers a Pub/Sub message that was handled, or is being
+// handled, so that redeliveries of the message are not processed again
+type ProcessedMessage struct {
+	// the Pub/Sub message ID, which is unique within a topic
+	ID string `json:"id" firestore:"id"`
+
+	// the name of the topic e.g `items.publish`
+	Topic string `json:"topic" firestore:"topic"`
+
+	// set once the message has been handled. A message that is still being
+	// handled when its lease runs out can be claimed by a redelivery, in case
+	// the instance that was handling it went away.
+	Completed      bool      `json:"completed" firestore:"completed"`
+	LeaseExpiresAt time.Time `json:"leaseExpiresAt" firestore:"leaseExpiresAt"`
+
+	// the side effects of handling the message that are done e.g a silent
+	// push to a user's devices. A redelivery of a message that failed part
+	// way skips them.
+	Steps []string `json:"steps,omitempty" firestore:"steps,omitempty"`
+
+	ReceivedAt  time.Time  `json:"receivedAt" firestore:"receivedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" firestore:"completedAt,omitempty"`
+	ExpiresAt   time.Time  `json:"expiresAt" firestore:"expiresAt"`
+}
+
+// Claimable returns true if a redelivery of the message can be processed at
+// the given time
+func (m ProcessedMessage) Claimable(now time.Time) bool {
+	if !m.ExpiresAt.After(now) {
+		return true
+	}
+	return !m.Completed && !m.LeaseExpiresAt.After(now)
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"time"
+)
+
+// FCMRetry is an FCM message that is waiting to be sent again to the
+// registration tokens that it failed to reach
+type FCMRetry struct {
+	ID string `json:"id" firestore:"id"`
+
+	// the tokens that have not received the message yet
+	Tokens []string `json:"tokens" firestore:"tokens"`
+
+	// the JSON serialized notification payload, as it came in over Pub/Sub
+	Payload []byte `json:"payload" firestore:"payload"`
+
+	// the sends made so far, including the first one
+	Attempts int `json:"attempts" firestore:"attempts"`
+
+	LastError     string    `json:"lastError" firestore:"lastError"`
+	NextAttemptAt time.Time `json:"nextAttemptAt" firestore:"nextAttemptAt"`
+	CreatedAt     time.Time `json:"createdAt" firestore:"createdAt"`
+}
+
+// FCMDeadLetter is an FCM message that was given up on after it kept failing.
+// It is kept for inspection and can be replayed.
+type FCMDeadLetter struct {
+	ID        string    `json:"id" firestore:"id"`
+	Tokens    []string  `json:"tokens" firestore:"tokens"`
+	Payload   []byte    `json:"payload" firestore:"payload"`
+	Attempts  int       `json:"attempts" firestore:"attempts"`
+	LastError string    `json:"lastError" firestore:"lastError"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+	DeadAt    time.Time `json:"deadAt" firestore:"deadAt"`
+}
+
+// FCMRetryReport summarizes a run of the FCM retry processor, or the replay
+// of a dead letter
+type FCMRetryReport struct {
+	// messages that need no more attempts, because they reached all their
+	// tokens or none of the tokens is valid any more
+	Completed int `json:"completed"`
+
+	// messages that are waiting for another attempt, and those that were
+	// given up on
+	Rescheduled  int `json:"rescheduled"`
+	DeadLettered int `json:"deadLettered"`
+
+	// retries that could not be processed. They are picked up on the next
+	// run.
+	Errors []string `json:"errors"`
+}
@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/savannahghi/feedlib"
+)
+
+// ElementType is the kind of feed element that a change applies to
+type ElementType string
+
+// known feed element types
+const (
+	ElementTypeItem   ElementType = "ITEM"
+	ElementTypeNudge  ElementType = "NUDGE"
+	ElementTypeAction ElementType = "ACTION"
+)
+
+// AllElementType is the set of known element types
+var AllElementType = []ElementType{
+	ElementTypeItem,
+	ElementTypeNudge,
+	ElementTypeAction,
+}
+
+// IsValid returns true if an element type is valid
+func (e ElementType) IsValid() bool {
+	switch e {
+	case ElementTypeItem, ElementTypeNudge, ElementTypeAction:
+		return true
+	}
+	return false
+}
+
+func (e ElementType) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL translates the input value given into an element type
+func (e *ElementType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ElementType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ElementType", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the element type to the supplied writer
+func (e ElementType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// ChangeType records how a feed element was changed
+type ChangeType string
+
+// known change types
+const (
+	ChangeTypeCreated ChangeType = "CREATED"
+	ChangeTypeUpdated ChangeType = "UPDATED"
+	ChangeTypeDeleted ChangeType = "DELETED"
+)
+
+// FeedChangeRetention is how long change log entries are kept. Firestore
+// deletes the entries once their `expiresAt` time passes. Clients that have
+// not synced for longer have to sync the whole feed again.
+const FeedChangeRetention = 30 * 24 * time.Hour
+
+// FeedChange is an entry in a feed's change log. An entry is written every
+// time an element is created, updated or deleted so that clients can sync
+// only what changed since they last synced.
+type FeedChange struct {
+	ID          string      `json:"id" firestore:"id"`
+	ElementID   string      `json:"elementID" firestore:"elementID"`
+	ElementType ElementType `json:"elementType" firestore:"elementType"`
+	ChangeType  ChangeType  `json:"changeType" firestore:"changeType"`
+	Timestamp   time.Time   `json:"timestamp" firestore:"timestamp"`
+	ExpiresAt   time.Time   `json:"expiresAt" firestore:"expiresAt"`
+}
+
+// SyncCursor is the position in a feed's change log up to which a client has
+// synced. Several changes can share a timestamp, so the ID of the last change
+// read breaks ties. A cursor without a change ID starts at its timestamp.
+type SyncCursor struct {
+	Timestamp time.Time
+	ChangeID  string
+}
+
+// Tombstone marks a feed element that has been deleted since a client
+// last synced
+type Tombstone struct {
+	ElementID   string      `json:"elementID"`
+	ElementType ElementType `json:"elementType"`
+	DeletedAt   time.Time   `json:"deletedAt"`
+}
+
+// FeedChanges holds the feed elements that changed after a sync token.
+//
+// Created and updated elements are returned in full. Deleted elements are
+// returned as tombstones.
+type FeedChanges struct {
+	UID string `json:"uid"`
+
+	Flavour feedlib.Flavour `json:"flavour"`
+
+	Actions []feedlib.Action `json:"actions"`
+
+	Nudges []feedlib.Nudge `json:"nudges"`
+
+	Items []feedlib.Item `json:"items"`
+
+	Tombstones []Tombstone `json:"tombstones"`
+
+	// an opaque token that should be sent back on the next sync
+	SyncToken string `json:"syncToken"`
+
+	// true when there are more changes than could be returned at once. The
+	// client should sync again with the new sync token straight away.
+	HasMore bool `json:"hasMore"`
+
+	// the latest change that was read, used to issue the sync token
+	LastChange SyncCursor `json:"-"`
+}
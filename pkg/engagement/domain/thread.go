@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"time"
+)
+
+// ThreadMessage is a message in an item's conversation, together with its
+// edit history, reactions, attachments and replies.
+//
+// It is stored in the same document as the `feedlib.Message` that it extends,
+// so its message fields use the same names.
+type ThreadMessage struct {
+	ID             string    `json:"id" firestore:"id"`
+	SequenceNumber int       `json:"sequenceNumber" firestore:"sequenceNumber"`
+	Text           string    `json:"text" firestore:"text"`
+	ReplyTo        string    `json:"replyTo" firestore:"replyTo"`
+	PostedByUID    string    `json:"postedByUID" firestore:"postedByUID"`
+	PostedByName   string    `json:"postedByName" firestore:"postedByName"`
+	Timestamp      time.Time `json:"timestamp" firestore:"timestamp"`
+
+	// when the message was last edited, if it was
+	EditedAt *time.Time `json:"editedAt,omitempty" firestore:"editedAt,omitempty"`
+
+	// the earlier versions of the message's text, oldest first
+	Edits []MessageEdit `json:"edits" firestore:"edits,omitempty"`
+
+	// the users that reacted with each emoji
+	ReactionsByEmoji map[string][]string `json:"-" firestore:"reactions,omitempty"`
+
+	Reactions   []MessageReaction   `json:"reactions" firestore:"-"`
+	Attachments []MessageAttachment `json:"attachments" firestore:"attachments,omitempty"`
+
+	// the messages that reply to this one, oldest first
+	Replies []*ThreadMessage `json:"replies" firestore:"-"`
+
+	// hidden messages were taken out of the conversation by a moderator
+	Hidden bool `json:"-" firestore:"hidden,omitempty"`
+}
+
+// MessageEdit is an earlier version of an edited message's text
+type MessageEdit struct {
+	Text        string    `json:"text" firestore:"text"`
+	EditedByUID string    `json:"editedByUID" firestore:"editedByUID"`
+	EditedAt    time.Time `json:"editedAt" firestore:"editedAt"`
+}
+
+// MessageReaction lists the users that reacted to a message with an emoji
+type MessageReaction struct {
+	Emoji string   `json:"emoji"`
+	UIDs  []string `json:"uids"`
+}
+
+// MessageAttachment is an upload that was attached to a message
+type MessageAttachment struct {
+	UploadID    string `json:"uploadID" firestore:"uploadID"`
+	Title       string `json:"title" firestore:"title"`
+	ContentType string `json:"contentType" firestore:"contentType"`
+	URL         string `json:"url" firestore:"url"`
+	Size        int    `json:"size" firestore:"size"`
+}
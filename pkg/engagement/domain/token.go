@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"time"
+)
+
+// TokenErrorCode classifies why FCM could not deliver to a registration token
+type TokenErrorCode string
+
+// token error codes
+const (
+	// the app was uninstalled or the token expired. The token will never
+	// work again.
+	TokenErrorUnregistered TokenErrorCode = "UNREGISTERED"
+
+	// FCM rejected the token as malformed. The same code is used for invalid
+	// messages, so a token is only given up on after repeated failures.
+	TokenErrorInvalidArgument TokenErrorCode = "INVALID_ARGUMENT"
+
+	// any other failure e.g an unavailable server or an exceeded quota. These
+	// do not say anything about the token.
+	TokenErrorOther TokenErrorCode = "OTHER"
+)
+
+// IsValid checks that the token error code is known
+func (c TokenErrorCode) IsValid() bool {
+	switch c {
+	case TokenErrorUnregistered, TokenErrorInvalidArgument, TokenErrorOther:
+		return true
+	}
+	return false
+}
+
+func (c TokenErrorCode) String() string {
+	return string(c)
+}
+
+// TokenHealth is the delivery record of an FCM registration token. Only
+// tokens that have failed at least once have one.
+type TokenHealth struct {
+	Token string `json:"token" firestore:"token"`
+
+	// all the failures recorded against the token, and those since the last
+	// successful delivery
+	Failures            int `json:"failures" firestore:"failures"`
+	ConsecutiveFailures int `json:"consecutiveFailures" firestore:"consecutiveFailures"`
+
+	LastErrorCode TokenErrorCode `json:"lastErrorCode" firestore:"lastErrorCode"`
+	LastError     string         `json:"lastError" firestore:"lastError"`
+	LastFailureAt time.Time      `json:"lastFailureAt" firestore:"lastFailureAt"`
+	LastSuccessAt *time.Time     `json:"lastSuccessAt,omitempty" firestore:"lastSuccessAt"`
+
+	// invalid tokens are no longer sent to
+	Invalid       bool       `json:"invalid" firestore:"invalid"`
+	InvalidatedAt *time.Time `json:"invalidatedAt,omitempty" firestore:"invalidatedAt"`
+}
+
+// TokenHealthReport summarizes the health of a user's registration tokens
+type TokenHealthReport struct {
+	UID string `json:"uid"`
+
+	// tokens with no recorded failures since their last delivery, tokens
+	// that are failing but still sent to and tokens that are no longer sent to
+	Healthy int `json:"healthy"`
+	Failing int `json:"failing"`
+	Invalid int `json:"invalid"`
+
+	// the delivery record of every token that the user has, in the order
+	// given by the profile service. Healthy tokens with no failures on record
+	// have only their token set.
+	Tokens []TokenHealth `json:"tokens"`
+}
@@ -0,0 +1,15 @@
+package domain
+
+// TopicSubscriptionResult reports on subscribing a user's devices to an FCM
+// topic, or unsubscribing them from it
+type TopicSubscriptionResult struct {
+	UID   string `json:"uid"`
+	Topic string `json:"topic"`
+
+	// the registration tokens that were changed, and those that were not
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+
+	// why the failed tokens were not changed
+	Errors []string `json:"errors"`
+}
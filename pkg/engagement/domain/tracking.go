@@ -0,0 +1,151 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// NotificationSenderOther is the sender type of push notifications that were
+// not sent on behalf of a feed sender e.g those sent directly over the API
+const NotificationSenderOther = "OTHER"
+
+// NotificationStatus is how far a push notification got on its way to the
+// user
+type NotificationStatus string
+
+// known notification statuses
+const (
+	// FCM accepted the notification
+	NotificationStatusSent NotificationStatus = "SENT"
+
+	// FCM did not accept the notification
+	NotificationStatusFailed NotificationStatus = "FAILED"
+
+	// the app acknowledged that it received the notification
+	NotificationStatusDelivered NotificationStatus = "DELIVERED"
+
+	// the user dismissed the notification without opening it
+	NotificationStatusDismissed NotificationStatus = "DISMISSED"
+
+	// the user opened the notification
+	NotificationStatusOpened NotificationStatus = "OPENED"
+)
+
+// AllNotificationStatus is the set of known notification statuses
+var AllNotificationStatus = []NotificationStatus{
+	NotificationStatusSent,
+	NotificationStatusFailed,
+	NotificationStatusDelivered,
+	NotificationStatusDismissed,
+	NotificationStatusOpened,
+}
+
+// IsValid returns true if a notification status is valid
+func (e NotificationStatus) IsValid() bool {
+	switch e {
+	case NotificationStatusSent,
+		NotificationStatusFailed,
+		NotificationStatusDelivered,
+		NotificationStatusDismissed,
+		NotificationStatusOpened:
+		return true
+	}
+	return false
+}
+
+// IsAcknowledgement returns true for the statuses that apps report
+func (e NotificationStatus) IsAcknowledgement() bool {
+	switch e {
+	case NotificationStatusDelivered,
+		NotificationStatusDismissed,
+		NotificationStatusOpened:
+		return true
+	}
+	return false
+}
+
+// rank orders the statuses by how far along they are. Opening a notification
+// is the strongest signal, so it outranks dismissing it.
+func (e NotificationStatus) rank() int {
+	switch e {
+	case NotificationStatusDelivered:
+		return 1
+	case NotificationStatusDismissed:
+		return 2
+	case NotificationStatusOpened:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Supersedes returns true if a notification with the other status moves on
+// to this one. Statuses never go back e.g a late delivery acknowledgement
+// does not undo an open.
+func (e NotificationStatus) Supersedes(other NotificationStatus) bool {
+	return e.rank() > other.rank()
+}
+
+func (e NotificationStatus) String() string {
+	return string(e)
+}
+
+// UnmarshalGQL translates the input value given into a notification status
+func (e *NotificationStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NotificationStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NotificationStatus", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes the notification status to the supplied writer
+func (e NotificationStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// NotificationOpenRate summarizes how the push notifications of one sender
+// type fared
+type NotificationOpenRate struct {
+	SenderType string `json:"senderType"`
+
+	// the notifications that FCM accepted, and those of them that apps
+	// acknowledged, that were dismissed and that were opened. A notification
+	// is only counted under its latest status, apart from delivery which
+	// every later status implies.
+	Sent      int `json:"sent"`
+	Delivered int `json:"delivered"`
+	Dismissed int `json:"dismissed"`
+	Opened    int `json:"opened"`
+
+	// the delivered and opened notifications as fractions of those sent
+	DeliveryRate float64 `json:"deliveryRate"`
+	OpenRate     float64 `json:"openRate"`
+}
+
+// Count adds a notification with the given status to the summary.
+// Notifications saved before statuses were tracked have none and count as
+// sent.
+func (r *NotificationOpenRate) Count(status NotificationStatus) {
+	if status == NotificationStatusFailed {
+		return
+	}
+	r.Sent++
+	if status.rank() >= NotificationStatusDelivered.rank() {
+		r.Delivered++
+	}
+	switch status {
+	case NotificationStatusDismissed:
+		r.Dismissed++
+	case NotificationStatusOpened:
+		r.Opened++
+	}
+	r.DeliveryRate = float64(r.Delivered) / float64(r.Sent)
+	r.OpenRate = float64(r.Opened) / float64(r.Sent)
+}
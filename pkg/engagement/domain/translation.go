@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"github.com/savannahghi/feedlib"
+)
+
+// LocalizedContent is the user facing text of a feed item or nudge in one
+// language.
+//
+// Empty fields are not translated and the element's original text is used
+// in their place.
+type LocalizedContent struct {
+	// nudge title
+	Title string `json:"title,omitempty" firestore:"title,omitempty"`
+
+	// item or nudge body text
+	Text string `json:"text,omitempty" firestore:"text,omitempty"`
+
+	// item tagline, also used as the push notification title
+	Tagline string `json:"tagline,omitempty" firestore:"tagline,omitempty"`
+
+	// item summary, also used as the push notification body
+	Summary string `json:"summary,omitempty" firestore:"summary,omitempty"`
+
+	// nudge notification messages
+	NotificationBody *feedlib.NotificationBody `json:"notificationBody,omitempty" firestore:"notificationBody,omitempty"`
+}
+
+// ElementTranslations holds the per-language variants of a feed element's
+// text. The variants are keyed by language e.g `sw`.
+type ElementTranslations struct {
+	ElementID string                      `json:"elementID" firestore:"elementID"`
+	Variants  map[string]LocalizedContent `json:"variants" firestore:"variants"`
+}
+
+// Variant returns the content for the supplied language, if it exists
+func (t ElementTranslations) Variant(language string) (LocalizedContent, bool) {
+	content, ok := t.Variants[language]
+	return content, ok
+}
+
+// LocalizeItem replaces the item's text with the translated text
+func (c LocalizedContent) LocalizeItem(item *feedlib.Item) {
+	if item == nil {
+		return
+	}
+	if c.Text != "" {
+		item.Text = c.Text
+	}
+	if c.Tagline != "" {
+		item.Tagline = c.Tagline
+	}
+	if c.Summary != "" {
+		item.Summary = c.Summary
+	}
+}
+
+// LocalizeNudge replaces the nudge's text with the translated text
+func (c LocalizedContent) LocalizeNudge(nudge *feedlib.Nudge) {
+	if nudge == nil {
+		return
+	}
+	if c.Title != "" {
+		nudge.Title = c.Title
+	}
+	if c.Text != "" {
+		nudge.Text = c.Text
+	}
+	if c.NotificationBody == nil {
+		return
+	}
+
+	body := *c.NotificationBody
+	if body.PublishMessage != "" {
+		nudge.NotificationBody.PublishMessage = body.PublishMessage
+	}
+	if body.DeleteMessage != "" {
+		nudge.NotificationBody.DeleteMessage = body.DeleteMessage
+	}
+	if body.ResolveMessage != "" {
+		nudge.NotificationBody.ResolveMessage = body.ResolveMessage
+	}
+	if body.UnresolveMessage != "" {
+		nudge.NotificationBody.UnresolveMessage = body.UnresolveMessage
+	}
+	if body.ShowMessage != "" {
+		nudge.NotificationBody.ShowMessage = body.ShowMessage
+	}
+	if body.HideMessage != "" {
+		nudge.NotificationBody.HideMessage = body.HideMessage
+	}
+}
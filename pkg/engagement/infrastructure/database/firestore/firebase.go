@@ -2,12 +2,17 @@ package fb
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/savannahghi/converterandformatter"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
@@ -29,14 +34,22 @@ import (
 var tracer = otel.Tracer("github.com/savannahghi/engagementcore/pkg/engagement/services/database")
 
 const (
-	feedCollectionName           = "feed"
-	elementsGroupName            = "elements"
-	actionsSubcollectionName     = "actions"
-	nudgesSubcollectionName      = "nudges"
-	itemsSubcollectionName       = "items"
-	messagesSubcollectionName    = "messages"
-	incomingEventsCollectionName = "incoming_events"
-	outgoingEventsCollectionName = "outgoing_events"
+	feedCollectionName               = "feed"
+	elementsGroupName                = "elements"
+	actionsSubcollectionName         = "actions"
+	nudgesSubcollectionName          = "nudges"
+	itemsSubcollectionName           = "items"
+	messagesSubcollectionName        = "messages"
+	translationsSubcollectionName    = "translations"
+	changesSubcollectionName         = "changes"
+	archivedItemsSubcollectionName   = "archived_items"
+	archivedNudgesSubcollectionName  = "archived_nudges"
+	expiryRemindersSubcollectionName = "expiry_reminders"
+	threadMutesSubcollectionName     = "thread_mutes"
+	checklistsSubcollectionName      = "checklists"
+	deliveriesSubcollectionName      = "deliveries"
+	incomingEventsCollectionName     = "incoming_events"
+	outgoingEventsCollectionName     = "outgoing_events"
 
 	// NPSResponseCollectionName firestore collection name where nps responses are stored
 	NPSResponseCollectionName = "nps_response"
@@ -50,8 +63,34 @@ const (
 
 	notificationCollectionName = "notifications"
 
+	idempotencyKeysCollectionName = "idempotency_keys"
+
+	moderationCasesCollectionName = "moderation_cases"
+
+	moderationAuditCollectionName = "moderation_audit"
+
+	notificationPreferencesCollectionName = "notification_preferences"
+
+	deferredNotificationsCollectionName = "deferred_notifications"
+
+	digestSettingsCollectionName = "digest_settings"
+
+	inboxCountPushesCollectionName = "inbox_count_pushes"
+
+	tokenHealthCollectionName = "fcm_token_health"
+
+	fcmRetriesCollectionName = "fcm_retries"
+
+	fcmDeadLettersCollectionName = "fcm_dead_letters"
+
+	processedMessagesCollectionName = "processed_pubsub_messages"
+
+	uploadOwnersCollectionName = "upload_owners"
+
 	labelsDocID            = "item_labels"
+	labelCountsDocID       = "item_label_counts"
 	unreadInboxCountsDocID = "unread_inbox_counts"
+	feedMergeDocID         = "feed_merge"
 
 	itemsLimit = 1000
 
@@ -302,6 +341,52 @@ func (fr Repository) GetFeedItem(
 	return item, nil
 }
 
+// GetFeedItemStatuses retrieves the statuses of several feed items at once.
+// Items that do not exist are left out.
+func (fr Repository) GetFeedItemStatuses(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemIDs []string,
+) (map[string]feedlib.Status, error) {
+	ctx, span := tracer.Start(ctx, "GetFeedItemStatuses")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	statuses := map[string]feedlib.Status{}
+	if len(itemIDs) == 0 {
+		return statuses, nil
+	}
+	coll := fr.getItemsCollection(uid, flavour)
+	refs := []*firestore.DocumentRef{}
+	for _, itemID := range itemIDs {
+		refs = append(refs, coll.Doc(itemID))
+	}
+	docs, err := fr.firestoreClient.GetAll(ctx, refs)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get items: %w", err)
+	}
+	for _, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+		item := feedlib.Item{}
+		err := doc.DataTo(&item)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal item from firebase doc: %w", err)
+		}
+		statuses[doc.Ref.ID] = item.Status
+	}
+	return statuses, nil
+}
+
 // SaveFeedItem validates and saves a feed item.
 // It's expected to have an ID and sequence number already.
 // One suggestion is to use UUIDs for IDs and UTC Unix Epoch seconds for
@@ -338,6 +423,15 @@ func (fr Repository) SaveFeedItem(
 		item.SequenceNumber,
 		coll,
 		true,
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeItem,
+			elementID:   item.ID,
+			changeType:  domain.ChangeTypeCreated,
+			countLabels: true,
+			item:        item,
+		},
 	); err != nil {
 		helpers.RecordSpanError(span, err)
 		return nil, fmt.Errorf("unable to save item: %w", err)
@@ -388,6 +482,15 @@ func (fr Repository) UpdateFeedItem(
 		item.SequenceNumber,
 		coll,
 		false, // not a new item, skip existing checks
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeItem,
+			elementID:   item.ID,
+			changeType:  domain.ChangeTypeUpdated,
+			countLabels: true,
+			item:        item,
+		},
 	); err != nil {
 		helpers.RecordSpanError(span, err)
 		return nil, fmt.Errorf("unable to save item: %w", err)
@@ -420,10 +523,35 @@ func (fr Repository) DeleteFeedItem(
 			"repository precondition check failed: %w", err)
 	}
 
-	_, err := fr.getItemsCollection(uid, flavour).Doc(itemID).Delete(ctx)
+	err := fr.writeWithChange(
+		ctx,
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeItem,
+			elementID:   itemID,
+			changeType:  domain.ChangeTypeDeleted,
+			countLabels: true,
+		},
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			err := tx.Delete(fr.getItemsCollection(uid, flavour).Doc(itemID))
+			if err != nil {
+				return fmt.Errorf("can't delete item: %w", err)
+			}
+			err = tx.Delete(fr.getElementCollection(
+				uid,
+				flavour,
+				checklistsSubcollectionName,
+			).Doc(itemID))
+			if err != nil {
+				return fmt.Errorf("can't delete item checklist: %w", err)
+			}
+			return nil
+		},
+	)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
-		return fmt.Errorf("can't delete item: %w", err)
+		return err
 	}
 
 	return nil
@@ -511,6 +639,13 @@ func (fr Repository) SaveNudge(
 		nudge.SequenceNumber,
 		coll,
 		true, // a new nudge
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeNudge,
+			elementID:   nudge.ID,
+			changeType:  domain.ChangeTypeCreated,
+		},
 	); err != nil {
 		helpers.RecordSpanError(span, err)
 		return nil, fmt.Errorf("unable to save nudge: %w", err)
@@ -552,6 +687,13 @@ func (fr Repository) UpdateNudge(
 		nudge.SequenceNumber,
 		coll,
 		false, // not a new nudge, should not check for existence
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeNudge,
+			elementID:   nudge.ID,
+			changeType:  domain.ChangeTypeUpdated,
+		},
 	); err != nil {
 		helpers.RecordSpanError(span, err)
 		return nil, fmt.Errorf("unable to save nudge: %w", err)
@@ -575,7 +717,19 @@ func (fr Repository) DeleteNudge(
 			"repository precondition check failed: %w", err)
 	}
 
-	_, err := fr.getNudgesCollection(uid, flavour).Doc(nudgeID).Delete(ctx)
+	err := fr.writeWithChange(
+		ctx,
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeNudge,
+			elementID:   nudgeID,
+			changeType:  domain.ChangeTypeDeleted,
+		},
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			return tx.Delete(fr.getNudgesCollection(uid, flavour).Doc(nudgeID))
+		},
+	)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
 		return fmt.Errorf("can't delete nudge: %w", err)
@@ -657,6 +811,13 @@ func (fr Repository) SaveAction(
 		action.SequenceNumber,
 		coll,
 		true,
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeAction,
+			elementID:   action.ID,
+			changeType:  domain.ChangeTypeCreated,
+		},
 	); err != nil {
 		helpers.RecordSpanError(span, err)
 		return nil, fmt.Errorf("unable to save action: %w", err)
@@ -680,7 +841,19 @@ func (fr Repository) DeleteAction(
 			"repository precondition check failed: %w", err)
 	}
 
-	_, err := fr.getActionsCollection(uid, flavour).Doc(actionID).Delete(ctx)
+	err := fr.writeWithChange(
+		ctx,
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeAction,
+			elementID:   actionID,
+			changeType:  domain.ChangeTypeDeleted,
+		},
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			return tx.Delete(fr.getActionsCollection(uid, flavour).Doc(actionID))
+		},
+	)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
 		return fmt.Errorf("can't delete action: %w", err)
@@ -713,6 +886,14 @@ func (fr Repository) PostMessage(
 		message.SequenceNumber,
 		coll,
 		true,
+		// the item's conversation changed
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeItem,
+			elementID:   itemID,
+			changeType:  domain.ChangeTypeUpdated,
+		},
 	); err != nil {
 		helpers.RecordSpanError(span, err)
 		return nil, fmt.Errorf("unable to save message: %w", err)
@@ -745,6 +926,9 @@ func (fr Repository) GetMessages(
 		return nil, fmt.Errorf("unable to get messages: %w", err)
 	}
 	for _, msgDoc := range msgDocs {
+		if hidden, _ := msgDoc.Data()["hidden"].(bool); hidden {
+			continue // taken out of the conversation by a moderator
+		}
 		msg := &feedlib.Message{}
 		err := msgDoc.DataTo(msg)
 		if err != nil {
@@ -812,9 +996,21 @@ func (fr Repository) DeleteMessage(
 			"repository precondition check failed: %w", err)
 	}
 
-	_, err := fr.getMessagesCollection(uid, flavour, itemID).
-		Doc(messageID).
-		Delete(ctx)
+	ref := fr.getMessagesCollection(uid, flavour, itemID).Doc(messageID)
+	// the item's conversation changed
+	err := fr.writeWithChange(
+		ctx,
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeItem,
+			elementID:   itemID,
+			changeType:  domain.ChangeTypeUpdated,
+		},
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			return tx.Delete(ref)
+		},
+	)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
 		return fmt.Errorf("can't delete message: %w", err)
@@ -823,6 +1019,280 @@ func (fr Repository) DeleteMessage(
 	return nil
 }
 
+// GetThreadMessages retrieves the messages on an item together with their
+// edit history, reactions and attachments
+func (fr Repository) GetThreadMessages(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) ([]domain.ThreadMessage, error) {
+	ctx, span := tracer.Start(ctx, "GetThreadMessages")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	msgDocs, err := fr.getMessagesQuery(uid, flavour, itemID).Documents(ctx).GetAll()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get messages: %w", err)
+	}
+
+	messages := []domain.ThreadMessage{}
+	seenMessageIDs := []string{}
+	for _, msgDoc := range msgDocs {
+		msg, err := threadMessageFromDoc(msgDoc)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, err
+		}
+		if msg.Hidden {
+			continue
+		}
+		if !converterandformatter.StringSliceContains(seenMessageIDs, msg.ID) {
+			messages = append(messages, *msg)
+			seenMessageIDs = append(seenMessageIDs, msg.ID)
+		}
+	}
+	return messages, nil
+}
+
+// EditMessage replaces the text of a message. The replaced text is kept in the
+// message's edit history.
+func (fr Repository) EditMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	text string,
+	editedByUID string,
+) (*domain.ThreadMessage, error) {
+	ctx, span := tracer.Start(ctx, "EditMessage")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	ref := fr.getMessagesCollection(uid, flavour, itemID).Doc(messageID)
+	var edited *domain.ThreadMessage
+	// the item's conversation changed
+	err := fr.writeWithChange(
+		ctx,
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeItem,
+			elementID:   itemID,
+			changeType:  domain.ChangeTypeUpdated,
+		},
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			doc, err := tx.Get(ref)
+			if err != nil {
+				return fmt.Errorf("unable to get message: %w", err)
+			}
+			msg, err := threadMessageFromDoc(doc)
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			msg.Edits = append(msg.Edits, domain.MessageEdit{
+				Text:        msg.Text,
+				EditedByUID: editedByUID,
+				EditedAt:    now,
+			})
+			msg.Text = text
+			msg.EditedAt = &now
+			edited = msg
+			return tx.Update(ref, []firestore.Update{
+				{Path: "text", Value: msg.Text},
+				{Path: "editedAt", Value: now},
+				{Path: "edits", Value: msg.Edits},
+			})
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to edit message: %w", err)
+	}
+
+	return edited, nil
+}
+
+// ReactToMessage adds or removes a user's emoji reaction to a message
+func (fr Repository) ReactToMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	emoji string,
+	reactorUID string,
+	reacted bool,
+) error {
+	ctx, span := tracer.Start(ctx, "ReactToMessage")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	var reactors interface{} = firestore.ArrayRemove(reactorUID)
+	if reacted {
+		reactors = firestore.ArrayUnion(reactorUID)
+	}
+	ref := fr.getMessagesCollection(uid, flavour, itemID).Doc(messageID)
+	// the item's conversation changed
+	err := fr.writeWithChange(
+		ctx,
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeItem,
+			elementID:   itemID,
+			changeType:  domain.ChangeTypeUpdated,
+		},
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			return tx.Update(ref, []firestore.Update{
+				{FieldPath: firestore.FieldPath{"reactions", emoji}, Value: reactors},
+			})
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save reaction: %w", err)
+	}
+
+	return nil
+}
+
+// AttachToMessage adds attachments to a message
+func (fr Repository) AttachToMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	attachments []domain.MessageAttachment,
+) error {
+	ctx, span := tracer.Start(ctx, "AttachToMessage")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	toAdd := []interface{}{}
+	for _, attachment := range attachments {
+		toAdd = append(toAdd, attachment)
+	}
+	ref := fr.getMessagesCollection(uid, flavour, itemID).Doc(messageID)
+	// the item's conversation changed
+	err := fr.writeWithChange(
+		ctx,
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeItem,
+			elementID:   itemID,
+			changeType:  domain.ChangeTypeUpdated,
+		},
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			return tx.Update(ref, []firestore.Update{
+				{Path: "attachments", Value: firestore.ArrayUnion(toAdd...)},
+			})
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save attachments: %w", err)
+	}
+
+	return nil
+}
+
+func (fr Repository) getUploadOwnersCollectionName() string {
+	suffixed := firebasetools.SuffixCollection(uploadOwnersCollectionName)
+	return suffixed
+}
+
+// uploadOwner records the user that made an upload. Uploads are kept by the
+// uploads service, which does not know who made them.
+type uploadOwner struct {
+	UploadID string `firestore:"uploadID"`
+	UID      string `firestore:"uid"`
+}
+
+// SaveUploadOwner records the user that made an upload
+func (fr Repository) SaveUploadOwner(
+	ctx context.Context,
+	uploadID string,
+	uid string,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveUploadOwner")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getUploadOwnersCollectionName(),
+	).Doc(uploadID).Set(ctx, uploadOwner{UploadID: uploadID, UID: uid})
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save upload owner: %w", err)
+	}
+	return nil
+}
+
+// GetUploadOwner returns the user that made an upload, or a blank UID when
+// the upload's owner was not recorded
+func (fr Repository) GetUploadOwner(
+	ctx context.Context,
+	uploadID string,
+) (string, error) {
+	ctx, span := tracer.Start(ctx, "GetUploadOwner")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return "", fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	doc, err := fr.firestoreClient.Collection(
+		fr.getUploadOwnersCollectionName(),
+	).Doc(uploadID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return "", nil
+	}
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return "", fmt.Errorf("unable to get upload owner: %w", err)
+	}
+
+	owner := uploadOwner{}
+	err = doc.DataTo(&owner)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return "", fmt.Errorf(
+			"unable to unmarshal upload owner from firebase doc: %w", err)
+	}
+	return owner.UID, nil
+}
+
 // SaveIncomingEvent saves events that have been received from clients
 // before they are processed further
 func (fr Repository) SaveIncomingEvent(
@@ -951,6 +1421,20 @@ func (fr Repository) getItemsCollection(
 	return fr.getElementCollection(uid, flavour, itemsSubcollectionName)
 }
 
+func (fr Repository) getTranslationsCollection(
+	uid string,
+	flavour feedlib.Flavour,
+) *firestore.CollectionRef {
+	return fr.getElementCollection(uid, flavour, translationsSubcollectionName)
+}
+
+func (fr Repository) getChangesCollection(
+	uid string,
+	flavour feedlib.Flavour,
+) *firestore.CollectionRef {
+	return fr.getElementCollection(uid, flavour, changesSubcollectionName)
+}
+
 func (fr Repository) getMessagesCollection(
 	uid string,
 	flavour feedlib.Flavour,
@@ -993,18 +1477,23 @@ func (fr Repository) elementExists(
 	}
 	return len(docs) > 0, nil
 }
+
+// includesArchive reports whether an expiry filter asks for expired elements.
+// Expired items and nudges are moved to the feed's archive once the expiry
+// processor gets to them, so the archive has to be read as well.
+func includesArchive(expired *feedlib.BooleanFilter) bool {
+	return expired != nil && *expired != feedlib.BooleanFilterFalse
+}
+
 func (fr Repository) getItemsQuery(
-	uid string,
-	flavour feedlib.Flavour,
+	coll *firestore.CollectionRef,
 	persistent feedlib.BooleanFilter,
 	status *feedlib.Status,
 	visibility *feedlib.Visibility,
 	expired *feedlib.BooleanFilter,
 	filterParams *helpers.FilterParams,
 ) (*firestore.Query, error) {
-	itemsQuery := fr.getItemsCollection(
-		uid, flavour,
-	).Query.OrderBy(
+	itemsQuery := coll.Query.OrderBy(
 		"expiry", firestore.Desc,
 	).OrderBy(
 		"id", firestore.Desc,
@@ -1080,44 +1569,51 @@ func (fr Repository) GetItems(
 ) ([]feedlib.Item, error) {
 	ctx, span := tracer.Start(ctx, "GetItems")
 	defer span.End()
-	query, err := fr.getItemsQuery(
-		uid,
-		flavour,
-		persistent,
-		status,
-		visibility,
-		expired,
-		filterParams,
-	)
-	if err != nil {
-		helpers.RecordSpanError(span, err)
-		return nil, fmt.Errorf("unable to compose items query: %w", err)
+	colls := []*firestore.CollectionRef{fr.getItemsCollection(uid, flavour)}
+	if includesArchive(expired) {
+		colls = append(colls, fr.getElementCollection(
+			uid, flavour, archivedItemsSubcollectionName))
 	}
 
 	items := []feedlib.Item{}
 	seenItemIDs := []string{}
-	itemDocs, err := query.Documents(ctx).GetAll()
-	if err != nil {
-		helpers.RecordSpanError(span, err)
-		return nil, fmt.Errorf("unable to get items: %w", err)
-	}
-	for _, itemDoc := range itemDocs {
-		item := &feedlib.Item{}
-		err := itemDoc.DataTo(item)
+	for _, coll := range colls {
+		query, err := fr.getItemsQuery(
+			coll,
+			persistent,
+			status,
+			visibility,
+			expired,
+			filterParams,
+		)
 		if err != nil {
 			helpers.RecordSpanError(span, err)
-			return nil, fmt.Errorf(
-				"unable to unmarshal item from firebase doc: %w", err)
+			return nil, fmt.Errorf("unable to compose items query: %w", err)
+		}
+
+		itemDocs, err := query.Documents(ctx).GetAll()
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to get items: %w", err)
 		}
-		if !converterandformatter.StringSliceContains(seenItemIDs, item.ID) {
-			messages, err := fr.GetMessages(ctx, uid, flavour, item.ID)
+		for _, itemDoc := range itemDocs {
+			item := &feedlib.Item{}
+			err := itemDoc.DataTo(item)
 			if err != nil {
 				helpers.RecordSpanError(span, err)
-				return nil, fmt.Errorf("can't get feed item messages: %w", err)
+				return nil, fmt.Errorf(
+					"unable to unmarshal item from firebase doc: %w", err)
+			}
+			if !converterandformatter.StringSliceContains(seenItemIDs, item.ID) {
+				messages, err := fr.GetMessages(ctx, uid, flavour, item.ID)
+				if err != nil {
+					helpers.RecordSpanError(span, err)
+					return nil, fmt.Errorf("can't get feed item messages: %w", err)
+				}
+				item.Conversations = messages
+				items = append(items, *item)
+				seenItemIDs = append(seenItemIDs, item.ID)
 			}
-			item.Conversations = messages
-			items = append(items, *item)
-			seenItemIDs = append(seenItemIDs, item.ID)
 		}
 	}
 	return items, nil
@@ -1221,10 +1717,10 @@ func (fr Repository) SaveLabel(
 
 	if !converterandformatter.StringSliceContains(labels, label) {
 		labelDoc := fr.getUserCollection(uid, flavour).Doc(labelsDocID)
-		l := map[string][]string{
-			"labels": {label},
+		l := map[string]interface{}{
+			"labels": firestore.ArrayUnion(label),
 		}
-		_, err := labelDoc.Set(ctx, l)
+		_, err := labelDoc.Set(ctx, l, firestore.MergeAll)
 		if err != nil {
 			helpers.RecordSpanError(span, err)
 			return fmt.Errorf("can't save label: %w", err)
@@ -1234,27 +1730,215 @@ func (fr Repository) SaveLabel(
 	return nil
 }
 
-// UnreadPersistentItems fetches unread persistent items
-func (fr Repository) UnreadPersistentItems(
+// RemoveLabels removes labels from a feed's list of labels. Items that carry
+// the labels are not changed.
+func (fr Repository) RemoveLabels(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-) (int, error) {
-	ctx, span := tracer.Start(ctx, "UnreadPersistentItems")
+	labels []string,
+) error {
+	ctx, span := tracer.Start(ctx, "RemoveLabels")
 	defer span.End()
-	// unreadInboxCountsDocID
-	unreadDoc := fr.getUserCollection(uid, flavour).Doc(unreadInboxCountsDocID)
-	uDoc, err := unreadDoc.Get(ctx)
+	if len(labels) == 0 {
+		return nil
+	}
+
+	toRemove := []interface{}{}
+	for _, label := range labels {
+		toRemove = append(toRemove, label)
+	}
+	labelDoc := fr.getUserCollection(uid, flavour).Doc(labelsDocID)
+	_, err := labelDoc.Set(ctx, map[string]interface{}{
+		"labels": firestore.ArrayRemove(toRemove...),
+	}, firestore.MergeAll)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
-		if status.Code(err) != codes.NotFound {
-			return -1, fmt.Errorf(
-				"error fetching unread docs collection: %w",
-				err,
-			)
-		}
-		// create it if not found
-		defaultCount := map[string]int{
+		return fmt.Errorf("can't remove labels: %w", err)
+	}
+
+	return nil
+}
+
+// RelabelItems moves every item that carries a label to another label. It
+// returns the number of items that were moved.
+func (fr Repository) RelabelItems(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	label string,
+	newLabel string,
+) (int, error) {
+	ctx, span := tracer.Start(ctx, "RelabelItems")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return 0, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	query := fr.getItemsCollection(uid, flavour).Where("label", "==", label)
+	docs, err := fetchQueryDocs(ctx, query, false)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return 0, fmt.Errorf("unable to fetch labelled items: %w", err)
+	}
+
+	// relabelled items are updated like any other item update so that their
+	// sequence numbers move on and clients replace their copies
+	for _, doc := range docs {
+		item := &feedlib.Item{}
+		err := doc.DataTo(item)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return 0, fmt.Errorf(
+				"unable to unmarshal item from firebase doc: %w", err)
+		}
+		item.Label = newLabel
+		item.SequenceNumber++
+		_, err = fr.UpdateFeedItem(ctx, uid, flavour, item)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return 0, fmt.Errorf("unable to relabel item %s: %w", item.ID, err)
+		}
+	}
+
+	return len(docs), nil
+}
+
+// LabelCounts counts the items that carry each of a feed's labels. Labels
+// that are found on items but are missing from the feed's list of labels are
+// counted too.
+//
+// The counts are kept up to date as items are written. A feed's items are
+// only read the first time its labels are counted.
+func (fr Repository) LabelCounts(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) ([]domain.LabelCount, error) {
+	ctx, span := tracer.Start(ctx, "LabelCounts")
+	defer span.End()
+	labels, err := fr.Labels(ctx, uid, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to retrieve labels: %w", err)
+	}
+
+	countsDoc := fr.getUserCollection(uid, flavour).Doc(labelCountsDocID)
+	stored := labelCounts{}
+	err = fr.firestoreClient.RunTransaction(
+		ctx,
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			doc, err := tx.Get(countsDoc)
+			if err == nil {
+				return doc.DataTo(&stored)
+			}
+			if status.Code(err) != codes.NotFound {
+				return err
+			}
+
+			// item writes read the counts doc in their transactions so
+			// none can land between this scan and the save
+			stored = labelCounts{Items: map[string]int{}, Unread: map[string]int{}}
+			docs, err := tx.Documents(
+				fr.getItemsCollection(uid, flavour).Select(
+					"label",
+					"persistent",
+					"status",
+					"visibility",
+				),
+			).GetAll()
+			if err != nil {
+				return err
+			}
+			for _, doc := range docs {
+				item := feedlib.Item{}
+				if err := doc.DataTo(&item); err != nil {
+					return err
+				}
+				if item.Label == "" {
+					continue
+				}
+				stored.Items[item.Label]++
+				if countsAsUnread(&item) {
+					stored.Unread[item.Label]++
+				}
+			}
+			return tx.Set(countsDoc, stored)
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to count labels: %w", err)
+	}
+
+	counts := map[string]*domain.LabelCount{}
+	ordered := []string{}
+	add := func(label string) *domain.LabelCount {
+		count, ok := counts[label]
+		if !ok {
+			count = &domain.LabelCount{Label: label}
+			counts[label] = count
+			ordered = append(ordered, label)
+		}
+		return count
+	}
+	for _, label := range labels {
+		add(label)
+	}
+	// labels outside the feed's list are ordered by name so that the result
+	// is stable
+	extra := []string{}
+	for label, items := range stored.Items {
+		if _, ok := counts[label]; !ok && items > 0 {
+			extra = append(extra, label)
+		}
+	}
+	sort.Strings(extra)
+	for _, label := range extra {
+		add(label)
+	}
+	for _, label := range ordered {
+		counts[label].Items = stored.Items[label]
+		counts[label].Unread = stored.Unread[label]
+	}
+
+	result := []domain.LabelCount{}
+	for _, label := range ordered {
+		result = append(result, *counts[label])
+	}
+	return result, nil
+}
+
+// labelCounts is the stored count of a feed's items and unread items for
+// each label
+type labelCounts struct {
+	Items  map[string]int `firestore:"items"`
+	Unread map[string]int `firestore:"unread"`
+}
+
+// UnreadPersistentItems fetches unread persistent items
+func (fr Repository) UnreadPersistentItems(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (int, error) {
+	ctx, span := tracer.Start(ctx, "UnreadPersistentItems")
+	defer span.End()
+	// unreadInboxCountsDocID
+	unreadDoc := fr.getUserCollection(uid, flavour).Doc(unreadInboxCountsDocID)
+	uDoc, err := unreadDoc.Get(ctx)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		if status.Code(err) != codes.NotFound {
+			return -1, fmt.Errorf(
+				"error fetching unread docs collection: %w",
+				err,
+			)
+		}
+		// create it if not found
+		defaultCount := map[string]int{
 			"count": 0,
 		}
 		_, err := unreadDoc.Set(ctx, defaultCount)
@@ -1296,7 +1980,13 @@ func (fr Repository) UpdateUnreadPersistentItemsCount(
 	unreadDoc := fr.getUserCollection(uid, flavour).Doc(unreadInboxCountsDocID)
 
 	persistentItemsQ, err := fr.getItemsQuery(
-		uid, flavour, feedlib.BooleanFilterTrue, nil, nil, nil, nil)
+		fr.getItemsCollection(uid, flavour),
+		feedlib.BooleanFilterTrue,
+		nil,
+		nil,
+		nil,
+		nil,
+	)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
 		return fmt.Errorf("can't compose persistent items query: %w", err)
@@ -1332,15 +2022,12 @@ func (fr Repository) UpdateUnreadPersistentItemsCount(
 }
 
 func (fr Repository) getNudgesQuery(
-	uid string,
-	flavour feedlib.Flavour,
+	coll *firestore.CollectionRef,
 	status *feedlib.Status,
 	visibility *feedlib.Visibility,
 	expired *feedlib.BooleanFilter,
 ) *firestore.Query {
-	nudgesQuery := fr.getNudgesCollection(
-		uid, flavour,
-	).Query.OrderBy(
+	nudgesQuery := coll.Query.OrderBy(
 		"expiry", firestore.Desc,
 	).OrderBy(
 		"id", firestore.Desc,
@@ -1413,32 +2100,33 @@ func (fr Repository) GetNudges(
 ) ([]feedlib.Nudge, error) {
 	ctx, span := tracer.Start(ctx, "GetNudges")
 	defer span.End()
+	colls := []*firestore.CollectionRef{fr.getNudgesCollection(uid, flavour)}
+	if includesArchive(expired) {
+		colls = append(colls, fr.getElementCollection(
+			uid, flavour, archivedNudgesSubcollectionName))
+	}
+
 	nudges := []feedlib.Nudge{}
 	seenNudgeIDs := []string{}
-
-	query := fr.getNudgesQuery(
-		uid,
-		flavour,
-		status,
-		visibility,
-		expired,
-	)
-	nudgeDocs, err := query.Documents(ctx).GetAll()
-	if err != nil {
-		helpers.RecordSpanError(span, err)
-		return nil, fmt.Errorf("unable to get nudges: %w", err)
-	}
-	for _, nudgeDoc := range nudgeDocs {
-		nudge := &feedlib.Nudge{}
-		err := nudgeDoc.DataTo(nudge)
+	for _, coll := range colls {
+		query := fr.getNudgesQuery(coll, status, visibility, expired)
+		nudgeDocs, err := query.Documents(ctx).GetAll()
 		if err != nil {
 			helpers.RecordSpanError(span, err)
-			return nil, fmt.Errorf(
-				"unable to unmarshal nudge from firebase doc: %w", err)
+			return nil, fmt.Errorf("unable to get nudges: %w", err)
 		}
-		if !converterandformatter.StringSliceContains(seenNudgeIDs, nudge.ID) {
-			nudges = append(nudges, *nudge)
-			seenNudgeIDs = append(seenNudgeIDs, nudge.ID)
+		for _, nudgeDoc := range nudgeDocs {
+			nudge := &feedlib.Nudge{}
+			err := nudgeDoc.DataTo(nudge)
+			if err != nil {
+				helpers.RecordSpanError(span, err)
+				return nil, fmt.Errorf(
+					"unable to unmarshal nudge from firebase doc: %w", err)
+			}
+			if !converterandformatter.StringSliceContains(seenNudgeIDs, nudge.ID) {
+				nudges = append(nudges, *nudge)
+				seenNudgeIDs = append(seenNudgeIDs, nudge.ID)
+			}
 		}
 	}
 	return nudges, nil
@@ -1490,6 +2178,7 @@ func (fr Repository) saveElement(
 	sequenceNumber int,
 	coll *firestore.CollectionRef,
 	isNewElement bool,
+	change elementChange,
 ) error {
 	ctx, span := tracer.Start(ctx, "saveElement")
 	defer span.End()
@@ -1511,8 +2200,13 @@ func (fr Repository) saveElement(
 		}
 	}
 
-	doc := coll.Doc(id)
-	_, err := doc.Set(ctx, el)
+	err := fr.writeWithChange(
+		ctx,
+		change,
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			return tx.Set(coll.Doc(id), el)
+		},
+	)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
 		return fmt.Errorf("unable to save item: %w", err)
@@ -1521,6 +2215,185 @@ func (fr Repository) saveElement(
 	return nil
 }
 
+// elementChange identifies a write to a feed element for the feed's change
+// log
+type elementChange struct {
+	uid         string
+	flavour     feedlib.Flavour
+	elementType domain.ElementType
+	elementID   string
+	changeType  domain.ChangeType
+
+	// set for writes to an item itself, as opposed to its conversation, so
+	// that the feed's label counts follow the write. The item is the state
+	// after the write and is nil when the item is deleted.
+	countLabels bool
+	item        *feedlib.Item
+}
+
+// writeWithChange runs a write to a feed element in a transaction that also
+// appends an entry to the feed's change log, so that delta syncs see every
+// write that lands and only those
+func (fr Repository) writeWithChange(
+	ctx context.Context,
+	change elementChange,
+	write func(ctx context.Context, tx *firestore.Transaction) error,
+) error {
+	ctx, span := tracer.Start(ctx, "writeWithChange")
+	defer span.End()
+	err := fr.firestoreClient.RunTransaction(
+		ctx,
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			// transactions read before they write
+			var before *feedlib.Item
+			var counted bool
+			var err error
+			if change.countLabels {
+				before, err = fr.itemInTransaction(
+					tx,
+					fr.getItemsCollection(change.uid, change.flavour).Doc(change.elementID),
+				)
+				if err != nil {
+					return err
+				}
+				counted, err = fr.labelsCounted(tx, change.uid, change.flavour)
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := write(ctx, tx); err != nil {
+				return err
+			}
+
+			// feeds whose labels have not been counted yet are counted in
+			// full the first time their label counts are read
+			if counted {
+				err = fr.countLabels(tx, change.uid, change.flavour, before, change.item)
+				if err != nil {
+					return err
+				}
+			}
+
+			// a retried transaction gets a new entry
+			now := time.Now()
+			entry := domain.FeedChange{
+				ID:          uuid.New().String(),
+				ElementID:   change.elementID,
+				ElementType: change.elementType,
+				ChangeType:  change.changeType,
+				Timestamp:   now,
+				ExpiresAt:   now.Add(domain.FeedChangeRetention),
+			}
+			ref := fr.getChangesCollection(change.uid, change.flavour).Doc(entry.ID)
+			return tx.Create(ref, entry)
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to write feed change: %w", err)
+	}
+	return nil
+}
+
+// itemInTransaction reads an item within a transaction. A nil item is
+// returned when it does not exist.
+func (fr Repository) itemInTransaction(
+	tx *firestore.Transaction,
+	ref *firestore.DocumentRef,
+) (*feedlib.Item, error) {
+	doc, err := tx.Get(ref)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get item: %w", err)
+	}
+	item := &feedlib.Item{}
+	if err := doc.DataTo(item); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal item from firebase doc: %w", err)
+	}
+	return item, nil
+}
+
+// labelsCounted reports whether a feed's label counts have been set up
+func (fr Repository) labelsCounted(
+	tx *firestore.Transaction,
+	uid string,
+	flavour feedlib.Flavour,
+) (bool, error) {
+	_, err := tx.Get(fr.getUserCollection(uid, flavour).Doc(labelCountsDocID))
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to get label counts: %w", err)
+	}
+	return true, nil
+}
+
+// countsAsUnread reports whether an item adds to its label's unread count.
+// Expiry is left out since it passes without a write; expired items stop
+// counting when the expiry processor archives them.
+func countsAsUnread(item *feedlib.Item) bool {
+	return item.Persistent &&
+		item.Status == feedlib.StatusPending &&
+		item.Visibility == feedlib.VisibilityShow
+}
+
+// countLabels moves an item's contribution to the feed's label counts from
+// its state before a write to its state after the write
+func (fr Repository) countLabels(
+	tx *firestore.Transaction,
+	uid string,
+	flavour feedlib.Flavour,
+	before *feedlib.Item,
+	after *feedlib.Item,
+) error {
+	items := map[string]int{}
+	unread := map[string]int{}
+	if before != nil {
+		items[before.Label]--
+		if countsAsUnread(before) {
+			unread[before.Label]--
+		}
+	}
+	if after != nil {
+		items[after.Label]++
+		if countsAsUnread(after) {
+			unread[after.Label]++
+		}
+	}
+
+	increments := func(deltas map[string]int) map[string]interface{} {
+		fields := map[string]interface{}{}
+		for label, delta := range deltas {
+			// firestore field names can't be empty so unlabelled items
+			// are not counted
+			if label != "" && delta != 0 {
+				fields[label] = firestore.Increment(delta)
+			}
+		}
+		return fields
+	}
+	// an empty map would replace the stored counts when merged
+	data := map[string]interface{}{}
+	if fields := increments(items); len(fields) > 0 {
+		data["items"] = fields
+	}
+	if fields := increments(unread); len(fields) > 0 {
+		data["unread"] = fields
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return tx.Set(
+		fr.getUserCollection(uid, flavour).Doc(labelCountsDocID),
+		data,
+		firestore.MergeAll,
+	)
+}
+
 func validateElement(el feedlib.Element) error {
 	if el == nil {
 		return fmt.Errorf("failed validation: nil element")
@@ -1580,6 +2453,45 @@ func isPointer(i interface{}) bool {
 	return reflect.ValueOf(i).Type().Kind() == reflect.Ptr
 }
 
+// threadMessageFromDoc reads a message document, with its reactions listed by
+// emoji
+func threadMessageFromDoc(
+	doc *firestore.DocumentSnapshot,
+) (*domain.ThreadMessage, error) {
+	msg := &domain.ThreadMessage{}
+	err := doc.DataTo(msg)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to unmarshal message from firebase doc: %w", err)
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now() // backwards compat after schema change
+	}
+	if msg.Edits == nil {
+		msg.Edits = []domain.MessageEdit{}
+	}
+	if msg.Attachments == nil {
+		msg.Attachments = []domain.MessageAttachment{}
+	}
+
+	emojis := []string{}
+	for emoji, uids := range msg.ReactionsByEmoji {
+		if len(uids) > 0 {
+			emojis = append(emojis, emoji)
+		}
+	}
+	sort.Strings(emojis)
+	msg.Reactions = []domain.MessageReaction{}
+	for _, emoji := range emojis {
+		msg.Reactions = append(msg.Reactions, domain.MessageReaction{
+			Emoji: emoji,
+			UIDs:  msg.ReactionsByEmoji[emoji],
+		})
+	}
+	msg.Replies = []*domain.ThreadMessage{}
+	return msg, nil
+}
+
 // GetDefaultNudgeByTitle returns a default nudge given its title
 func (fr Repository) GetDefaultNudgeByTitle(
 	ctx context.Context,
@@ -1615,196 +2527,2571 @@ func (fr Repository) GetDefaultNudgeByTitle(
 	return nudge, nil
 }
 
-// SaveTwilioResponse saves the callback data
-func (fr Repository) SaveTwilioResponse(
+// SaveElementTranslations adds or replaces the language variants of a feed
+// element's text. Variants for languages that are not in the supplied
+// translations are left untouched.
+func (fr Repository) SaveElementTranslations(
 	ctx context.Context,
-	data dto.Message,
-) error {
-	ctx, span := tracer.Start(ctx, "SaveTwilioResponse")
+	uid string,
+	flavour feedlib.Flavour,
+	translations *domain.ElementTranslations,
+) (*domain.ElementTranslations, error) {
+	ctx, span := tracer.Start(ctx, "SaveElementTranslations")
 	defer span.End()
 	if err := fr.checkPreconditions(); err != nil {
 		helpers.RecordSpanError(span, err)
-		return fmt.Errorf("repository precondition check failed: %w", err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+	if translations == nil {
+		return nil, fmt.Errorf("nil element translations")
 	}
 
-	collectionName := fr.getTwilioCallbackCollectionName()
-	_, _, err := fr.firestoreClient.Collection(collectionName).Add(ctx, data)
+	doc := fr.getTranslationsCollection(uid, flavour).Doc(translations.ElementID)
+	variants := map[string]interface{}{}
+	for language, content := range translations.Variants {
+		variants[language] = content
+	}
+	_, err := doc.Set(ctx, map[string]interface{}{
+		"elementID": translations.ElementID,
+		"variants":  variants,
+	}, firestore.MergeAll)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
-		return fmt.Errorf("unable to save callback response")
+		return nil, fmt.Errorf("unable to save element translations: %w", err)
 	}
 
-	return nil
-}
-
-// SaveNotification saves a notification
-func (fr Repository) SaveNotification(
-	ctx context.Context,
-	firestoreClient *firestore.Client,
-	notification dto.SavedNotification,
-) error {
-	ctx, span := tracer.Start(ctx, "SaveNotification")
-	defer span.End()
-	collectionName := fr.getNotificationCollectionName()
-	_, _, err := firestoreClient.Collection(collectionName).
-		Add(ctx, notification)
+	snapshot, err := doc.Get(ctx)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
-		return fmt.Errorf("can't save notification: %w", err)
+		return nil, fmt.Errorf("unable to get saved element translations: %w", err)
 	}
-	return nil
+	saved := &domain.ElementTranslations{}
+	err = snapshot.DataTo(saved)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"unable to unmarshal element translations from firebase doc: %w", err)
+	}
+
+	return saved, nil
 }
 
-// RetrieveNotification retrieves a notification
-func (fr Repository) RetrieveNotification(
+// GetElementTranslations retrieves the translations of all the elements in a
+// user's feed, keyed by element ID
+func (fr Repository) GetElementTranslations(
 	ctx context.Context,
-	firestoreClient *firestore.Client,
-	registrationToken string,
-	newerThan time.Time,
-	limit int,
-) ([]*dto.SavedNotification, error) {
-	ctx, span := tracer.Start(ctx, "RetrieveNotification")
+	uid string,
+	flavour feedlib.Flavour,
+) (map[string]domain.ElementTranslations, error) {
+	ctx, span := tracer.Start(ctx, "GetElementTranslations")
 	defer span.End()
-	collectionName := fr.getNotificationCollectionName()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
 
-	docs, err := firestoreClient.Collection(
-		collectionName,
-	).Where(
-		"RegistrationToken", "==", registrationToken,
-	).Where(
-		"Timestamp", ">=", newerThan,
-	).Limit(limit).Documents(ctx).GetAll()
+	docs, err := fetchQueryDocs(
+		ctx,
+		fr.getTranslationsCollection(uid, flavour).Query,
+		false,
+	)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
-		return nil, fmt.Errorf("unable to retrieve notifications: %w", err)
+		return nil, fmt.Errorf("unable to fetch element translations: %w", err)
 	}
-	notifications := []*dto.SavedNotification{}
+
+	translations := map[string]domain.ElementTranslations{}
 	for _, doc := range docs {
-		var notification dto.SavedNotification
-		err = doc.DataTo(&notification)
+		t := domain.ElementTranslations{}
+		err = doc.DataTo(&t)
 		if err != nil {
 			helpers.RecordSpanError(span, err)
 			return nil, fmt.Errorf(
-				"error unmarshalling saved notification: %w",
-				err,
-			)
+				"unable to unmarshal element translations from firebase doc: %w", err)
 		}
-		notifications = append(notifications, &notification)
+		translations[t.ElementID] = t
 	}
-	return notifications, nil
+
+	return translations, nil
+}
+
+// GetFeedChanges retrieves the elements of a feed that changed after the
+// supplied position in its change log, oldest change first. Changes are
+// ordered by their timestamp and then their ID so that changes that share a
+// timestamp are not skipped between pages. At most `limit` changes are read;
+// when there are more, `HasMore` is set on the result.
+//
+// An element that changed several times is returned once, in its current
+// state. Elements that no longer exist are returned as tombstones.
+func (fr Repository) GetFeedChanges(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	after domain.SyncCursor,
+	limit int,
+) (*domain.FeedChanges, error) {
+	ctx, span := tracer.Start(ctx, "GetFeedChanges")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	query := fr.getChangesCollection(uid, flavour).
+		OrderBy("timestamp", firestore.Asc).
+		OrderBy(firestore.DocumentID, firestore.Asc)
+	if after.ChangeID != "" {
+		query = query.StartAfter(after.Timestamp, after.ChangeID)
+	} else {
+		query = query.Where("timestamp", ">=", after.Timestamp)
+	}
+	query = query.Limit(limit + 1)
+	docs, err := fetchQueryDocs(ctx, query, false)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to fetch feed changes: %w", err)
+	}
+
+	changes := &domain.FeedChanges{
+		UID:        uid,
+		Flavour:    flavour,
+		Actions:    []feedlib.Action{},
+		Nudges:     []feedlib.Nudge{},
+		Items:      []feedlib.Item{},
+		Tombstones: []domain.Tombstone{},
+		HasMore:    len(docs) > limit,
+		LastChange: after,
+	}
+	if changes.HasMore {
+		docs = docs[:limit]
+	}
+
+	// only the latest change to an element matters
+	latest := map[string]domain.FeedChange{}
+	order := []string{}
+	for _, doc := range docs {
+		change := domain.FeedChange{}
+		err = doc.DataTo(&change)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal feed change from firebase doc: %w", err)
+		}
+		key := fmt.Sprintf("%s|%s", change.ElementType, change.ElementID)
+		if _, seen := latest[key]; !seen {
+			order = append(order, key)
+		}
+		latest[key] = change
+		changes.LastChange = domain.SyncCursor{
+			Timestamp: change.Timestamp,
+			ChangeID:  doc.Ref.ID,
+		}
+	}
+
+	for _, key := range order {
+		change := latest[key]
+		tombstone := domain.Tombstone{
+			ElementID:   change.ElementID,
+			ElementType: change.ElementType,
+			DeletedAt:   change.Timestamp,
+		}
+		if change.ChangeType == domain.ChangeTypeDeleted {
+			changes.Tombstones = append(changes.Tombstones, tombstone)
+			continue
+		}
+
+		el, err := fr.findChangedElement(ctx, uid, flavour, change)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to get changed element: %w", err)
+		}
+		switch el := el.(type) {
+		case *feedlib.Item:
+			messages, err := fr.GetMessages(ctx, uid, flavour, el.ID)
+			if err != nil || messages == nil {
+				helpers.RecordSpanError(span, err)
+				// the thread may not have been initiated yet
+				el.Conversations = []feedlib.Message{}
+			} else {
+				el.Conversations = messages
+			}
+			changes.Items = append(changes.Items, *el)
+		case *feedlib.Nudge:
+			changes.Nudges = append(changes.Nudges, *el)
+		case *feedlib.Action:
+			changes.Actions = append(changes.Actions, *el)
+		default:
+			// deleted by a change that has not been read yet
+			changes.Tombstones = append(changes.Tombstones, tombstone)
+		}
+	}
+
+	return changes, nil
+}
+
+// findChangedElement gets the current state of the element that a change log
+// entry refers to. A nil element is returned when it no longer exists.
+func (fr Repository) findChangedElement(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	change domain.FeedChange,
+) (feedlib.Element, error) {
+	var coll *firestore.CollectionRef
+	var el feedlib.Element
+	switch change.ElementType {
+	case domain.ElementTypeItem:
+		coll, el = fr.getItemsCollection(uid, flavour), &feedlib.Item{}
+	case domain.ElementTypeNudge:
+		coll, el = fr.getNudgesCollection(uid, flavour), &feedlib.Nudge{}
+	case domain.ElementTypeAction:
+		coll, el = fr.getActionsCollection(uid, flavour), &feedlib.Action{}
+	default:
+		return nil, fmt.Errorf("unknown element type %s", change.ElementType)
+	}
+
+	query := orderAndLimitBySequence(coll.Where("id", "==", change.ElementID))
+	docs, err := fetchQueryDocs(ctx, query, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	return docToElement(docs[0], el)
+}
+
+// GetElementsExpiringBefore retrieves up to `limit` of the items or nudges,
+// across all feeds, whose expiry is before the supplied time, soonest expiry
+// first. The scan carries on after the `after` cursor when one is supplied.
+// Elements without an expiry are left out.
+func (fr Repository) GetElementsExpiringBefore(
+	ctx context.Context,
+	elementType domain.ElementType,
+	before time.Time,
+	after *domain.ExpiryCursor,
+	limit int,
+) ([]domain.ExpiringElement, error) {
+	ctx, span := tracer.Start(ctx, "GetElementsExpiringBefore")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	var groupName string
+	switch elementType {
+	case domain.ElementTypeItem:
+		groupName = itemsSubcollectionName
+	case domain.ElementTypeNudge:
+		groupName = nudgesSubcollectionName
+	default:
+		return nil, fmt.Errorf("%s elements do not expire", elementType)
+	}
+
+	// this collection group query needs a single field index exemption on
+	// `expiry` for the `items` and `nudges` collection groups
+	query := fr.firestoreClient.CollectionGroup(groupName).
+		Where("expiry", ">", time.Unix(0, 0)).
+		Where("expiry", "<", before).
+		OrderBy("expiry", firestore.Asc).
+		OrderBy(firestore.DocumentID, firestore.Asc)
+
+	expiring := []domain.ExpiringElement{}
+	for len(expiring) < limit {
+		requested := limit - len(expiring)
+		page := query.Limit(requested)
+		if after != nil {
+			page = page.StartAfter(after.Expiry, fr.firestoreClient.Doc(after.Path))
+		}
+		docs, err := fetchQueryDocs(ctx, page, false)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to fetch expiring elements: %w", err)
+		}
+
+		for _, doc := range docs {
+			el := domain.ExpiringElement{ElementType: elementType}
+			switch elementType {
+			case domain.ElementTypeItem:
+				el.Item = &feedlib.Item{}
+				err = doc.DataTo(el.Item)
+			case domain.ElementTypeNudge:
+				el.Nudge = &feedlib.Nudge{}
+				err = doc.DataTo(el.Nudge)
+			}
+			if err != nil {
+				helpers.RecordSpanError(span, err)
+				return nil, fmt.Errorf(
+					"unable to unmarshal %s from firebase doc: %w", elementType, err)
+			}
+			el.Cursor = domain.ExpiryCursor{
+				Expiry: el.Expiry(),
+				Path:   relativeDocPath(doc.Ref),
+			}
+			after = &el.Cursor
+
+			uid, flavour, ok := fr.elementOwner(doc.Ref)
+			if !ok {
+				// a collection with the same name outside this environment's
+				// feed
+				continue
+			}
+			el.UID, el.Flavour = uid, flavour
+			expiring = append(expiring, el)
+		}
+		if len(docs) < requested {
+			break // no more expiring elements
+		}
+	}
+
+	return expiring, nil
+}
+
+// relativeDocPath returns the path of a document relative to the root of the
+// database e.g `feed/{flavour}/{uid}/elements/items/{id}`
+func relativeDocPath(ref *firestore.DocumentRef) string {
+	const root = "/documents/"
+	if i := strings.Index(ref.Path, root); i >= 0 {
+		return ref.Path[i+len(root):]
+	}
+	return ref.Path
+}
+
+// elementOwner works out the UID and flavour of the feed that an element
+// belongs to from its path i.e `feed/{flavour}/{uid}/elements/{type}/{id}`
+func (fr Repository) elementOwner(
+	ref *firestore.DocumentRef,
+) (string, feedlib.Flavour, bool) {
+	if ref == nil || ref.Parent == nil {
+		return "", "", false
+	}
+	elementsDoc := ref.Parent.Parent
+	if elementsDoc == nil || elementsDoc.ID != elementsGroupName {
+		return "", "", false
+	}
+	userCollection := elementsDoc.Parent
+	flavourDoc := userCollection.Parent
+	if flavourDoc == nil || flavourDoc.Parent == nil {
+		return "", "", false
+	}
+	if flavourDoc.Parent.ID != fr.getFeedCollectionName() {
+		return "", "", false
+	}
+
+	flavour := feedlib.Flavour(flavourDoc.ID)
+	if !flavour.IsValid() {
+		return "", "", false
+	}
+	return userCollection.ID, flavour, true
+}
+
+// ArchiveFeedItem moves an item out of a user's feed and into the feed's
+// archive
+func (fr Repository) ArchiveFeedItem(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	item *feedlib.Item,
+) error {
+	ctx, span := tracer.Start(ctx, "ArchiveFeedItem")
+	defer span.End()
+	if item == nil {
+		return fmt.Errorf("nil item")
+	}
+
+	archive := fr.getElementCollection(uid, flavour, archivedItemsSubcollectionName)
+	_, err := archive.Doc(item.ID).Set(ctx, item)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to archive item: %w", err)
+	}
+
+	err = fr.DeleteFeedItem(ctx, uid, flavour, item.ID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to remove archived item from feed: %w", err)
+	}
+
+	return nil
+}
+
+// ArchiveNudge moves a nudge out of a user's feed and into the feed's archive
+func (fr Repository) ArchiveNudge(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	nudge *feedlib.Nudge,
+) error {
+	ctx, span := tracer.Start(ctx, "ArchiveNudge")
+	defer span.End()
+	if nudge == nil {
+		return fmt.Errorf("nil nudge")
+	}
+
+	archive := fr.getElementCollection(uid, flavour, archivedNudgesSubcollectionName)
+	_, err := archive.Doc(nudge.ID).Set(ctx, nudge)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to archive nudge: %w", err)
+	}
+
+	err = fr.DeleteNudge(ctx, uid, flavour, nudge.ID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to remove archived nudge from feed: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimExpiryReminder records that a "due soon" reminder is being sent for an
+// element's expiry. It returns false when a reminder for the same expiry has
+// already been claimed, so that each expiry is only reminded about once.
+func (fr Repository) ClaimExpiryReminder(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	elementType domain.ElementType,
+	elementID string,
+	expiry time.Time,
+) (bool, error) {
+	ctx, span := tracer.Start(ctx, "ClaimExpiryReminder")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return false, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	// a new expiry (e.g after an extension) gets its own reminder
+	docID := fmt.Sprintf("%s|%s|%d", elementType, elementID, expiry.Unix())
+	_, err := fr.getElementCollection(
+		uid,
+		flavour,
+		expiryRemindersSubcollectionName,
+	).Doc(docID).Create(ctx, map[string]interface{}{
+		"elementID":   elementID,
+		"elementType": elementType,
+		"expiry":      expiry,
+		"claimedAt":   time.Now(),
+	})
+	if status.Code(err) == codes.AlreadyExists {
+		return false, nil
+	}
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return false, fmt.Errorf("unable to claim expiry reminder: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetFeedElements retrieves every action, nudge and item in a feed,
+// regardless of their status, visibility or expiry. Items carry their
+// messages.
+func (fr Repository) GetFeedElements(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (*domain.Feed, error) {
+	ctx, span := tracer.Start(ctx, "GetFeedElements")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	actions, err := fr.GetActions(ctx, uid, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get actions: %w", err)
+	}
+
+	nudgeDocs, err := fetchQueryDocs(
+		ctx, fr.getNudgesCollection(uid, flavour).Query, false)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get nudges: %w", err)
+	}
+	nudges := []feedlib.Nudge{}
+	for _, doc := range nudgeDocs {
+		nudge := feedlib.Nudge{}
+		err := doc.DataTo(&nudge)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal nudge from firebase doc: %w", err)
+		}
+		nudges = append(nudges, nudge)
+	}
+
+	itemDocs, err := fetchQueryDocs(
+		ctx, fr.getItemsCollection(uid, flavour).Query, false)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get items: %w", err)
+	}
+	items := []feedlib.Item{}
+	for _, doc := range itemDocs {
+		item := feedlib.Item{}
+		err := doc.DataTo(&item)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal item from firebase doc: %w", err)
+		}
+		messages, err := fr.GetMessages(ctx, uid, flavour, item.ID)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("can't get feed item messages: %w", err)
+		}
+		item.Conversations = messages
+		items = append(items, item)
+	}
+
+	return &domain.Feed{
+		UID:     uid,
+		Flavour: flavour,
+		Actions: actions,
+		Nudges:  nudges,
+		Items:   items,
+	}, nil
+}
+
+// ReassignIncomingEvents moves the incoming events that were sent by one user
+// from a feed of the supplied flavour to another user. It returns the number
+// of events that were moved.
+func (fr Repository) ReassignIncomingEvents(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	fromUID string,
+	toUID string,
+) (int, error) {
+	ctx, span := tracer.Start(ctx, "ReassignIncomingEvents")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return 0, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	collectionName := firebasetools.SuffixCollection(incomingEventsCollectionName)
+	query := fr.firestoreClient.Collection(collectionName).
+		Where("context.userID", "==", fromUID).
+		Where("context.flavour", "==", flavour)
+	docs, err := fetchQueryDocs(ctx, query, false)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return 0, fmt.Errorf("unable to fetch incoming events: %w", err)
+	}
+
+	for _, doc := range docs {
+		_, err := doc.Ref.Update(ctx, []firestore.Update{
+			{Path: "context.userID", Value: toUID},
+		})
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return 0, fmt.Errorf("unable to reassign incoming event: %w", err)
+		}
+	}
+
+	return len(docs), nil
+}
+
+// SaveFeedMerge records that an anonymous feed was merged into a permanent
+// feed. The record is kept with the anonymous feed.
+func (fr Repository) SaveFeedMerge(
+	ctx context.Context,
+	merge *domain.FeedMerge,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveFeedMerge")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+	if merge == nil {
+		return fmt.Errorf("nil feed merge")
+	}
+
+	_, err := fr.getUserCollection(
+		merge.AnonymousUID,
+		merge.Flavour,
+	).Doc(feedMergeDocID).Set(ctx, merge)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save feed merge: %w", err)
+	}
+	return nil
+}
+
+// GetFeedMerge retrieves the record of an anonymous feed's merge into a
+// permanent feed. A nil merge is returned when the feed has not been merged.
+func (fr Repository) GetFeedMerge(
+	ctx context.Context,
+	anonymousUID string,
+	flavour feedlib.Flavour,
+) (*domain.FeedMerge, error) {
+	ctx, span := tracer.Start(ctx, "GetFeedMerge")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	doc, err := fr.getUserCollection(
+		anonymousUID,
+		flavour,
+	).Doc(feedMergeDocID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get feed merge: %w", err)
+	}
+
+	merge := &domain.FeedMerge{}
+	err = doc.DataTo(merge)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"unable to unmarshal feed merge from firebase doc: %w", err)
+	}
+	return merge, nil
+}
+
+// SetThreadMute mutes or unmutes the conversation on a feed item for one of
+// its participants
+func (fr Repository) SetThreadMute(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	participantUID string,
+	muted bool,
+) error {
+	ctx, span := tracer.Start(ctx, "SetThreadMute")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	var update interface{} = firestore.ArrayRemove(participantUID)
+	if muted {
+		update = firestore.ArrayUnion(participantUID)
+	}
+	_, err := fr.getElementCollection(
+		uid,
+		flavour,
+		threadMutesSubcollectionName,
+	).Doc(itemID).Set(ctx, map[string]interface{}{
+		"uids": update,
+	}, firestore.MergeAll)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save thread mute: %w", err)
+	}
+	return nil
+}
+
+// GetThreadMutes retrieves the UIDs of the participants that have muted the
+// conversation on a feed item
+func (fr Repository) GetThreadMutes(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "GetThreadMutes")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	doc, err := fr.getElementCollection(
+		uid,
+		flavour,
+		threadMutesSubcollectionName,
+	).Doc(itemID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return []string{}, nil
+	}
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get thread mutes: %w", err)
+	}
+
+	var mutes map[string][]string
+	err = doc.DataTo(&mutes)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"unable to unmarshal thread mutes from firebase doc: %w", err)
+	}
+	muted, ok := mutes["uids"]
+	if !ok {
+		return []string{}, nil
+	}
+	return muted, nil
+}
+
+// SaveChecklist saves the checklist of a feed item, replacing any earlier
+// version of it
+func (fr Repository) SaveChecklist(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	checklist domain.Checklist,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveChecklist")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.getElementCollection(
+		uid,
+		flavour,
+		checklistsSubcollectionName,
+	).Doc(checklist.ItemID).Set(ctx, checklist)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save checklist: %w", err)
+	}
+	return nil
+}
+
+// GetChecklist retrieves the checklist of a feed item. It is nil when the
+// item does not have a checklist.
+func (fr Repository) GetChecklist(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) (*domain.Checklist, error) {
+	ctx, span := tracer.Start(ctx, "GetChecklist")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	doc, err := fr.getElementCollection(
+		uid,
+		flavour,
+		checklistsSubcollectionName,
+	).Doc(itemID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get checklist: %w", err)
+	}
+
+	checklist := &domain.Checklist{}
+	err = doc.DataTo(checklist)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"unable to unmarshal checklist from firebase doc: %w", err)
+	}
+	return checklist, nil
+}
+
+// GetChecklists retrieves the checklists of every item in a feed
+func (fr Repository) GetChecklists(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) ([]domain.Checklist, error) {
+	ctx, span := tracer.Start(ctx, "GetChecklists")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	docs, err := fr.getElementCollection(
+		uid,
+		flavour,
+		checklistsSubcollectionName,
+	).Documents(ctx).GetAll()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get checklists: %w", err)
+	}
+
+	checklists := []domain.Checklist{}
+	for _, doc := range docs {
+		var checklist domain.Checklist
+		err = doc.DataTo(&checklist)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal checklist from firebase doc: %w", err)
+		}
+		checklists = append(checklists, checklist)
+	}
+	return checklists, nil
+}
+
+// SaveChannelDeliveries records the outcome of sending a feed element's
+// notification over its channels
+func (fr Repository) SaveChannelDeliveries(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	deliveries []domain.ChannelDelivery,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveChannelDeliveries")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	collection := fr.getElementCollection(
+		uid,
+		flavour,
+		deliveriesSubcollectionName,
+	)
+	batch := fr.firestoreClient.Batch()
+	for _, delivery := range deliveries {
+		batch.Set(collection.Doc(delivery.ID), delivery)
+	}
+	_, err := batch.Commit(ctx)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save channel deliveries: %w", err)
+	}
+	return nil
+}
+
+// GetChannelDeliveries retrieves the recorded notification deliveries of a
+// feed element, oldest first
+func (fr Repository) GetChannelDeliveries(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	elementID string,
+) ([]domain.ChannelDelivery, error) {
+	ctx, span := tracer.Start(ctx, "GetChannelDeliveries")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	docs, err := fr.getElementCollection(
+		uid,
+		flavour,
+		deliveriesSubcollectionName,
+	).Where("elementID", "==", elementID).Documents(ctx).GetAll()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get channel deliveries: %w", err)
+	}
+
+	deliveries := []domain.ChannelDelivery{}
+	for _, doc := range docs {
+		var delivery domain.ChannelDelivery
+		err = doc.DataTo(&delivery)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal channel delivery from firebase doc: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	// sorted here so that the query does not need a composite index
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].Timestamp.Before(deliveries[j].Timestamp)
+	})
+	return deliveries, nil
+}
+
+// SaveTwilioResponse saves the callback data
+func (fr Repository) SaveTwilioResponse(
+	ctx context.Context,
+	data dto.Message,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveTwilioResponse")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("repository precondition check failed: %w", err)
+	}
+
+	collectionName := fr.getTwilioCallbackCollectionName()
+	_, _, err := fr.firestoreClient.Collection(collectionName).Add(ctx, data)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save callback response")
+	}
+
+	return nil
+}
+
+// SaveNotification saves a notification
+func (fr Repository) SaveNotification(
+	ctx context.Context,
+	firestoreClient *firestore.Client,
+	notification dto.SavedNotification,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveNotification")
+	defer span.End()
+	collectionName := fr.getNotificationCollectionName()
+	_, _, err := firestoreClient.Collection(collectionName).
+		Add(ctx, notification)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't save notification: %w", err)
+	}
+	return nil
+}
+
+// RetrieveNotification retrieves a notification
+func (fr Repository) RetrieveNotification(
+	ctx context.Context,
+	firestoreClient *firestore.Client,
+	registrationToken string,
+	newerThan time.Time,
+	limit int,
+) ([]*dto.SavedNotification, error) {
+	ctx, span := tracer.Start(ctx, "RetrieveNotification")
+	defer span.End()
+	collectionName := fr.getNotificationCollectionName()
+
+	docs, err := firestoreClient.Collection(
+		collectionName,
+	).Where(
+		"RegistrationToken", "==", registrationToken,
+	).Where(
+		"Timestamp", ">=", newerThan,
+	).Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to retrieve notifications: %w", err)
+	}
+	notifications := []*dto.SavedNotification{}
+	for _, doc := range docs {
+		var notification dto.SavedNotification
+		err = doc.DataTo(&notification)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"error unmarshalling saved notification: %w",
+				err,
+			)
+		}
+		notifications = append(notifications, &notification)
+	}
+	return notifications, nil
+}
+
+// SaveNPSResponse  stores the nps responses
+func (fr Repository) SaveNPSResponse(
+	ctx context.Context,
+	response *dto.NPSResponse,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveNPSResponse")
+	defer span.End()
+	collection := fr.getNPSResponseCollectionName()
+	_, _, err := fr.firestoreClient.Collection(collection).Add(ctx, response)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't save nps response: %w", err)
+	}
+	return nil
+}
+
+// RecordSurveyFeedbackResponse stores the feedback responses
+func (fr Repository) RecordSurveyFeedbackResponse(
+	ctx context.Context,
+	response *domain.SurveyFeedbackResponse,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveSurveyFeedback")
+	collection := fr.getRecordSurveyFeedbackResponseCollectionName()
+	_, _, err := fr.firestoreClient.Collection(collection).Add(ctx, response)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't save survey feedback response: %w", err)
+	}
+	return nil
+}
+
+// SaveOutgoingEmails saves all the outgoing emails
+func (fr Repository) SaveOutgoingEmails(
+	ctx context.Context,
+	payload *dto.OutgoingEmailsLog,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveOutgoingEmails")
+	defer span.End()
+
+	collection := fr.getOutgoingEmailsCollectionName()
+	_, _, err := fr.firestoreClient.Collection(collection).Add(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("unable to save ougoing email logs")
+	}
+	return nil
+}
+
+// UpdateMailgunDeliveryStatus updates the status and delivery time of the sent email message
+func (fr Repository) UpdateMailgunDeliveryStatus(
+	ctx context.Context,
+	payload *dto.MailgunEvent,
+) (*dto.OutgoingEmailsLog, error) {
+	ctx, span := tracer.Start(ctx, "UpdateMailgunDeliveryStatus")
+	defer span.End()
+
+	collection := fr.getOutgoingEmailsCollectionName()
+
+	query := fr.firestoreClient.Collection(collection).
+		Where("messageID", "==", payload.MessageID)
+
+	docs, err := fetchQueryDocs(ctx, query, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var emailLogData dto.OutgoingEmailsLog
+	err = docs[0].DataTo(&emailLogData)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to marshal data from docs snapshot: %w",
+			err,
+		)
+	}
+
+	timeOfDelivery := helpers.EpochTimetoStandardTime(payload.DeliveredOn)
+
+	eventOutput := dto.MailgunEventOutput{
+		EventName:   payload.EventName,
+		DeliveredOn: timeOfDelivery,
+	}
+
+	emailLogData.Event = &eventOutput
+
+	doc := fr.firestoreClient.Collection(fr.getOutgoingEmailsCollectionName()).
+		Doc(docs[0].Ref.ID)
+	if _, err = doc.Set(ctx, emailLogData); err != nil {
+		logrus.Print("an error occurred while updating data: ", err)
+		return nil, fmt.Errorf("unable to update data")
+	}
+
+	return &emailLogData, nil
+}
+
+// SaveTwilioVideoCallbackStatus saves the callback data
+func (fr Repository) SaveTwilioVideoCallbackStatus(
+	ctx context.Context,
+	data dto.CallbackData,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveTwilioVideoCallbackStatus")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("repository precondition check failed: %w", err)
+	}
+
+	collectionName := fr.getTwilioVideoCallbackCollectionName()
+	_, _, err := fr.firestoreClient.Collection(collectionName).Add(ctx, data)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save callback response")
+	}
+
+	return nil
+}
+
+func (fr Repository) getIdempotencyKeysCollectionName() string {
+	suffixed := firebasetools.SuffixCollection(idempotencyKeysCollectionName)
+	return suffixed
+}
+
+// idempotencyKeyDocID is the ID of the document that records an idempotency
+// key. Keys are hashed since they are chosen by callers.
+func idempotencyKeyDocID(scope string, key string) string {
+	sum := sha256.Sum256([]byte(scope + "|" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ClaimIdempotencyKey records the first use of an idempotency key. When the
+// key has already been used, and has not expired, the existing record is
+// returned with false.
+//
+// Expired records are replaced. A Firestore TTL policy on `expiresAt` can be
+// used to remove them.
+func (fr Repository) ClaimIdempotencyKey(
+	ctx context.Context,
+	record domain.IdempotencyRecord,
+) (*domain.IdempotencyRecord, bool, error) {
+	ctx, span := tracer.Start(ctx, "ClaimIdempotencyKey")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, false, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	ref := fr.firestoreClient.Collection(
+		fr.getIdempotencyKeysCollectionName(),
+	).Doc(idempotencyKeyDocID(record.Scope, record.Key))
+
+	var existing *domain.IdempotencyRecord
+	err := fr.firestoreClient.RunTransaction(
+		ctx,
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			existing = nil
+			doc, err := tx.Get(ref)
+			if err != nil && status.Code(err) != codes.NotFound {
+				return fmt.Errorf("unable to read idempotency key: %w", err)
+			}
+			if err == nil {
+				found := &domain.IdempotencyRecord{}
+				if err := doc.DataTo(found); err != nil {
+					return fmt.Errorf("unable to unmarshal idempotency key: %w", err)
+				}
+				if found.ExpiresAt.After(time.Now()) {
+					existing = found
+					return nil
+				}
+			}
+			return tx.Set(ref, record)
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, false, fmt.Errorf("unable to claim idempotency key: %w", err)
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+	return &record, true, nil
+}
+
+// CompleteIdempotencyKey stores the response to the first request made with
+// an idempotency key
+func (fr Repository) CompleteIdempotencyKey(
+	ctx context.Context,
+	scope string,
+	key string,
+	response domain.IdempotentResponse,
+) error {
+	ctx, span := tracer.Start(ctx, "CompleteIdempotencyKey")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getIdempotencyKeysCollectionName(),
+	).Doc(idempotencyKeyDocID(scope, key)).Update(ctx, []firestore.Update{
+		{Path: "completed", Value: true},
+		{Path: "response", Value: response},
+	})
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets an idempotency key whose request failed so
+// that the request can be retried
+func (fr Repository) ReleaseIdempotencyKey(
+	ctx context.Context,
+	scope string,
+	key string,
+) error {
+	ctx, span := tracer.Start(ctx, "ReleaseIdempotencyKey")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getIdempotencyKeysCollectionName(),
+	).Doc(idempotencyKeyDocID(scope, key)).Delete(ctx)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// SetMessageHidden takes a message out of an item's conversation, or puts it
+// back. Hidden messages are kept for the moderation audit.
+func (fr Repository) SetMessageHidden(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	hidden bool,
+) error {
+	ctx, span := tracer.Start(ctx, "SetMessageHidden")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	ref := fr.getMessagesCollection(uid, flavour, itemID).Doc(messageID)
+	// the item's conversation changed
+	err := fr.writeWithChange(
+		ctx,
+		elementChange{
+			uid:         uid,
+			flavour:     flavour,
+			elementType: domain.ElementTypeItem,
+			elementID:   itemID,
+			changeType:  domain.ChangeTypeUpdated,
+		},
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			return tx.Update(ref, []firestore.Update{{Path: "hidden", Value: hidden}})
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to hide message: %w", err)
+	}
+	return nil
+}
+
+func (fr Repository) getModerationCasesCollectionName() string {
+	suffixed := firebasetools.SuffixCollection(moderationCasesCollectionName)
+	return suffixed
+}
+
+func (fr Repository) getModerationAuditCollectionName() string {
+	suffixed := firebasetools.SuffixCollection(moderationAuditCollectionName)
+	return suffixed
+}
+
+// moderationCaseDocID is the ID of the moderation case of a message. Every
+// report about a message goes to the same case.
+func moderationCaseDocID(
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+) string {
+	sum := sha256.Sum256([]byte(
+		fmt.Sprintf("%s|%s|%s|%s", uid, flavour, itemID, messageID)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ReportMessage adds a report to the moderation case of a message, opening
+// the case if the message has not been reported before. A dismissed case is
+// opened again by a report from a user that has not reported the message.
+func (fr Repository) ReportMessage(
+	ctx context.Context,
+	moderationCase domain.ModerationCase,
+	report domain.MessageReport,
+) (*domain.ModerationCase, error) {
+	ctx, span := tracer.Start(ctx, "ReportMessage")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	moderationCase.ID = moderationCaseDocID(
+		moderationCase.UID,
+		moderationCase.Flavour,
+		moderationCase.ItemID,
+		moderationCase.MessageID,
+	)
+	ref := fr.firestoreClient.Collection(
+		fr.getModerationCasesCollectionName(),
+	).Doc(moderationCase.ID)
+
+	var saved domain.ModerationCase
+	err := fr.firestoreClient.RunTransaction(
+		ctx,
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			saved = moderationCase
+			doc, err := tx.Get(ref)
+			if err != nil && status.Code(err) != codes.NotFound {
+				return fmt.Errorf("unable to read moderation case: %w", err)
+			}
+			if err == nil {
+				if err := doc.DataTo(&saved); err != nil {
+					return fmt.Errorf("unable to unmarshal moderation case: %w", err)
+				}
+			}
+
+			for _, existing := range saved.Reports {
+				if existing.ReportedByUID == report.ReportedByUID {
+					return nil // already reported by this user
+				}
+			}
+			saved.Reports = append(saved.Reports, report)
+			if saved.Status == domain.ModerationStatusDismissed {
+				saved.Status = domain.ModerationStatusPending
+			}
+			saved.UpdatedAt = report.ReportedAt
+			return tx.Set(ref, saved)
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to report message: %w", err)
+	}
+	return &saved, nil
+}
+
+// GetModerationCase retrieves a moderation case. It is nil when there is no
+// case with the ID.
+func (fr Repository) GetModerationCase(
+	ctx context.Context,
+	caseID string,
+) (*domain.ModerationCase, error) {
+	ctx, span := tracer.Start(ctx, "GetModerationCase")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	doc, err := fr.firestoreClient.Collection(
+		fr.getModerationCasesCollectionName(),
+	).Doc(caseID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get moderation case: %w", err)
+	}
+
+	moderationCase := &domain.ModerationCase{}
+	err = doc.DataTo(moderationCase)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"unable to unmarshal moderation case from firebase doc: %w", err)
+	}
+	return moderationCase, nil
+}
+
+// GetModerationCases retrieves the moderation cases with a status, the cases
+// that were reported first coming first
+func (fr Repository) GetModerationCases(
+	ctx context.Context,
+	moderationStatus domain.ModerationStatus,
+	limit int,
+) ([]domain.ModerationCase, error) {
+	ctx, span := tracer.Start(ctx, "GetModerationCases")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	docs, err := fr.firestoreClient.Collection(
+		fr.getModerationCasesCollectionName(),
+	).Where("status", "==", moderationStatus).Documents(ctx).GetAll()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get moderation cases: %w", err)
+	}
+
+	cases := []domain.ModerationCase{}
+	for _, doc := range docs {
+		var moderationCase domain.ModerationCase
+		err = doc.DataTo(&moderationCase)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal moderation case from firebase doc: %w", err)
+		}
+		cases = append(cases, moderationCase)
+	}
+	sort.SliceStable(cases, func(i, j int) bool {
+		return cases[i].CreatedAt.Before(cases[j].CreatedAt)
+	})
+	if limit > 0 && len(cases) > limit {
+		cases = cases[:limit]
+	}
+	return cases, nil
+}
+
+// UpdateModerationCase saves a decision on a moderation case
+func (fr Repository) UpdateModerationCase(
+	ctx context.Context,
+	moderationCase domain.ModerationCase,
+) error {
+	ctx, span := tracer.Start(ctx, "UpdateModerationCase")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getModerationCasesCollectionName(),
+	).Doc(moderationCase.ID).Set(ctx, moderationCase)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to update moderation case: %w", err)
+	}
+	return nil
+}
+
+// SaveModerationAuditEntry records a moderation decision, or an action taken
+// by the automatic filter
+func (fr Repository) SaveModerationAuditEntry(
+	ctx context.Context,
+	entry domain.ModerationAuditEntry,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveModerationAuditEntry")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getModerationAuditCollectionName(),
+	).Doc(entry.ID).Create(ctx, entry)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save moderation audit entry: %w", err)
+	}
+	return nil
+}
+
+// GetModerationAudit retrieves the decisions on a moderation case, oldest
+// first
+func (fr Repository) GetModerationAudit(
+	ctx context.Context,
+	caseID string,
+) ([]domain.ModerationAuditEntry, error) {
+	ctx, span := tracer.Start(ctx, "GetModerationAudit")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	docs, err := fr.firestoreClient.Collection(
+		fr.getModerationAuditCollectionName(),
+	).Where("caseID", "==", caseID).Documents(ctx).GetAll()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get moderation audit: %w", err)
+	}
+
+	entries := []domain.ModerationAuditEntry{}
+	for _, doc := range docs {
+		var entry domain.ModerationAuditEntry
+		err = doc.DataTo(&entry)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal moderation audit entry from firebase doc: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries, nil
+}
+
+func (fr Repository) getNotificationPreferencesCollectionName() string {
+	suffixed := firebasetools.SuffixCollection(notificationPreferencesCollectionName)
+	return suffixed
+}
+
+func (fr Repository) getDeferredNotificationsCollectionName() string {
+	suffixed := firebasetools.SuffixCollection(deferredNotificationsCollectionName)
+	return suffixed
+}
+
+// SaveNotificationPreferences saves a user's notification preferences,
+// replacing the earlier ones
+func (fr Repository) SaveNotificationPreferences(
+	ctx context.Context,
+	preferences domain.NotificationPreferences,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveNotificationPreferences")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getNotificationPreferencesCollectionName(),
+	).Doc(preferences.UID).Set(ctx, preferences)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save notification preferences: %w", err)
+	}
+	return nil
+}
+
+// GetNotificationPreferences retrieves a user's notification preferences. It
+// is nil when the user has not set any.
+func (fr Repository) GetNotificationPreferences(
+	ctx context.Context,
+	uid string,
+) (*domain.NotificationPreferences, error) {
+	ctx, span := tracer.Start(ctx, "GetNotificationPreferences")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	doc, err := fr.firestoreClient.Collection(
+		fr.getNotificationPreferencesCollectionName(),
+	).Doc(uid).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get notification preferences: %w", err)
+	}
+
+	preferences := &domain.NotificationPreferences{}
+	err = doc.DataTo(preferences)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"unable to unmarshal notification preferences from firebase doc: %w", err)
+	}
+	return preferences, nil
+}
+
+// SaveDeferredNotification holds a notification back for later delivery
+func (fr Repository) SaveDeferredNotification(
+	ctx context.Context,
+	notification domain.DeferredNotification,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveDeferredNotification")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getDeferredNotificationsCollectionName(),
+	).Doc(notification.ID).Set(ctx, notification)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save deferred notification: %w", err)
+	}
+	return nil
+}
+
+// GetDueDeferredNotifications retrieves the deferred notifications that are
+// due for delivery before a time, the earliest first
+func (fr Repository) GetDueDeferredNotifications(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]domain.DeferredNotification, error) {
+	ctx, span := tracer.Start(ctx, "GetDueDeferredNotifications")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	query := fr.firestoreClient.Collection(
+		fr.getDeferredNotificationsCollectionName(),
+	).Where("deliverAt", "<=", before).OrderBy("deliverAt", firestore.Asc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get deferred notifications: %w", err)
+	}
+
+	notifications := []domain.DeferredNotification{}
+	for _, doc := range docs {
+		var notification domain.DeferredNotification
+		err = doc.DataTo(&notification)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal deferred notification from firebase doc: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
 }
 
-// SaveNPSResponse  stores the nps responses
-func (fr Repository) SaveNPSResponse(
+// DeleteDeferredNotification removes a deferred notification
+func (fr Repository) DeleteDeferredNotification(
+	ctx context.Context,
+	id string,
+) error {
+	ctx, span := tracer.Start(ctx, "DeleteDeferredNotification")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getDeferredNotificationsCollectionName(),
+	).Doc(id).Delete(ctx)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to delete deferred notification: %w", err)
+	}
+	return nil
+}
+
+func (fr Repository) getDigestSettingsCollectionName() string {
+	suffixed := firebasetools.SuffixCollection(digestSettingsCollectionName)
+	return suffixed
+}
+
+// feedDocID identifies a document that belongs to one of a user's feeds
+func feedDocID(uid string, flavour feedlib.Flavour) string {
+	return fmt.Sprintf("%s_%s", uid, flavour)
+}
+
+// SaveDigestSettings saves a user's digest settings for one of their feeds,
+// replacing any that were there before
+func (fr Repository) SaveDigestSettings(
+	ctx context.Context,
+	settings domain.DigestSettings,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveDigestSettings")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getDigestSettingsCollectionName(),
+	).Doc(feedDocID(settings.UID, settings.Flavour)).Set(ctx, settings)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save digest settings: %w", err)
+	}
+	return nil
+}
+
+// GetDigestSettings retrieves a user's digest settings for one of their
+// feeds. They are nil when the user has not set any.
+func (fr Repository) GetDigestSettings(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (*domain.DigestSettings, error) {
+	ctx, span := tracer.Start(ctx, "GetDigestSettings")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	doc, err := fr.firestoreClient.Collection(
+		fr.getDigestSettingsCollectionName(),
+	).Doc(feedDocID(uid, flavour)).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get digest settings: %w", err)
+	}
+
+	settings := &domain.DigestSettings{}
+	err = doc.DataTo(settings)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"unable to unmarshal digest settings from firebase doc: %w", err)
+	}
+	return settings, nil
+}
+
+// ListDigestSettings retrieves a page of the digest settings of the feeds that
+// get digests at a frequency, starting after the supplied settings
+func (fr Repository) ListDigestSettings(
+	ctx context.Context,
+	frequency domain.DigestFrequency,
+	after *domain.DigestSettings,
+	limit int,
+) ([]domain.DigestSettings, error) {
+	ctx, span := tracer.Start(ctx, "ListDigestSettings")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	page := fr.firestoreClient.Collection(
+		fr.getDigestSettingsCollectionName(),
+	).Where("frequency", "==", frequency).OrderBy(firestore.DocumentID, firestore.Asc)
+	if after != nil {
+		page = page.StartAfter(feedDocID(after.UID, after.Flavour))
+	}
+	docs, err := page.Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to list digest settings: %w", err)
+	}
+
+	settings := []domain.DigestSettings{}
+	for _, doc := range docs {
+		var setting domain.DigestSettings
+		err = doc.DataTo(&setting)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal digest settings from firebase doc: %w", err)
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+func (fr Repository) getInboxCountPushesCollectionName() string {
+	suffixed := firebasetools.SuffixCollection(inboxCountPushesCollectionName)
+	return suffixed
+}
+
+// QueueInboxCountPush records a change to a feed's inbox. The first change
+// opens a push that is due at `dueAt`; later changes are collapsed into it
+// and do not move it.
+func (fr Repository) QueueInboxCountPush(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	dueAt time.Time,
+) error {
+	ctx, span := tracer.Start(ctx, "QueueInboxCountPush")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	ref := fr.firestoreClient.Collection(
+		fr.getInboxCountPushesCollectionName(),
+	).Doc(feedDocID(uid, flavour))
+	err := fr.firestoreClient.RunTransaction(
+		ctx,
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			push := domain.InboxCountPush{
+				UID:     uid,
+				Flavour: flavour,
+				DueAt:   dueAt,
+			}
+			doc, err := tx.Get(ref)
+			if err != nil && status.Code(err) != codes.NotFound {
+				return fmt.Errorf("unable to read inbox count push: %w", err)
+			}
+			if err == nil {
+				if err := doc.DataTo(&push); err != nil {
+					return fmt.Errorf("unable to unmarshal inbox count push: %w", err)
+				}
+			}
+			push.Changes++
+			push.UpdatedAt = time.Now()
+			return tx.Set(ref, push)
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to queue inbox count push: %w", err)
+	}
+	return nil
+}
+
+// GetDueInboxCountPushes retrieves the inbox count pushes that are due
+// before a time, the earliest first
+func (fr Repository) GetDueInboxCountPushes(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]domain.InboxCountPush, error) {
+	ctx, span := tracer.Start(ctx, "GetDueInboxCountPushes")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	query := fr.firestoreClient.Collection(
+		fr.getInboxCountPushesCollectionName(),
+	).Where("dueAt", "<=", before).OrderBy("dueAt", firestore.Asc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get inbox count pushes: %w", err)
+	}
+
+	pushes := []domain.InboxCountPush{}
+	for _, doc := range docs {
+		var push domain.InboxCountPush
+		err = doc.DataTo(&push)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal inbox count push from firebase doc: %w", err)
+		}
+		pushes = append(pushes, push)
+	}
+	return pushes, nil
+}
+
+// DeleteInboxCountPush removes an inbox count push once it has gone out. A
+// push that changed since it was read is kept, so that the later changes
+// are still pushed.
+func (fr Repository) DeleteInboxCountPush(
+	ctx context.Context,
+	push domain.InboxCountPush,
+) error {
+	ctx, span := tracer.Start(ctx, "DeleteInboxCountPush")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	ref := fr.firestoreClient.Collection(
+		fr.getInboxCountPushesCollectionName(),
+	).Doc(feedDocID(push.UID, push.Flavour))
+	err := fr.firestoreClient.RunTransaction(
+		ctx,
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			doc, err := tx.Get(ref)
+			if status.Code(err) == codes.NotFound {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("unable to read inbox count push: %w", err)
+			}
+			var current domain.InboxCountPush
+			if err := doc.DataTo(&current); err != nil {
+				return fmt.Errorf("unable to unmarshal inbox count push: %w", err)
+			}
+			if current.Changes != push.Changes {
+				return nil
+			}
+			return tx.Delete(ref)
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to delete inbox count push: %w", err)
+	}
+	return nil
+}
+
+func (fr Repository) getTokenHealthCollectionName() string {
+	suffixed := firebasetools.SuffixCollection(tokenHealthCollectionName)
+	return suffixed
+}
+
+// GetTokenHealth retrieves the delivery records of registration tokens,
+// keyed by token. Tokens that have never failed are left out.
+func (fr Repository) GetTokenHealth(
+	ctx context.Context,
+	tokens []string,
+) (map[string]domain.TokenHealth, error) {
+	ctx, span := tracer.Start(ctx, "GetTokenHealth")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	health := map[string]domain.TokenHealth{}
+	if len(tokens) == 0 {
+		return health, nil
+	}
+	collection := fr.firestoreClient.Collection(fr.getTokenHealthCollectionName())
+	refs := []*firestore.DocumentRef{}
+	for _, token := range tokens {
+		if token == "" {
+			continue
+		}
+		refs = append(refs, collection.Doc(token))
+	}
+	docs, err := fr.firestoreClient.GetAll(ctx, refs)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get token health: %w", err)
+	}
+	for _, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+		var record domain.TokenHealth
+		err = doc.DataTo(&record)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal token health from firebase doc: %w", err)
+		}
+		health[record.Token] = record
+	}
+	return health, nil
+}
+
+// SaveTokenHealth saves the delivery record of a registration token,
+// replacing the earlier one
+func (fr Repository) SaveTokenHealth(
+	ctx context.Context,
+	health domain.TokenHealth,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveTokenHealth")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+	if health.Token == "" {
+		return fmt.Errorf("can't save the health of a blank token")
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getTokenHealthCollectionName(),
+	).Doc(health.Token).Set(ctx, health)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save token health: %w", err)
+	}
+	return nil
+}
+
+func (fr Repository) getFCMRetriesCollectionName() string {
+	suffixed := firebasetools.SuffixCollection(fcmRetriesCollectionName)
+	return suffixed
+}
+
+func (fr Repository) getFCMDeadLettersCollectionName() string {
+	suffixed := firebasetools.SuffixCollection(fcmDeadLettersCollectionName)
+	return suffixed
+}
+
+// SaveFCMRetry schedules an FCM message to be sent again, replacing an
+// earlier schedule for it
+func (fr Repository) SaveFCMRetry(
+	ctx context.Context,
+	retry domain.FCMRetry,
+) error {
+	ctx, span := tracer.Start(ctx, "SaveFCMRetry")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getFCMRetriesCollectionName(),
+	).Doc(retry.ID).Set(ctx, retry)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save FCM retry: %w", err)
+	}
+	return nil
+}
+
+// GetDueFCMRetries retrieves the FCM retries whose next attempt is due
+// before the given time, the longest overdue first
+func (fr Repository) GetDueFCMRetries(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]domain.FCMRetry, error) {
+	ctx, span := tracer.Start(ctx, "GetDueFCMRetries")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	query := fr.firestoreClient.Collection(
+		fr.getFCMRetriesCollectionName(),
+	).Where("nextAttemptAt", "<=", before).OrderBy("nextAttemptAt", firestore.Asc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get FCM retries: %w", err)
+	}
+
+	retries := []domain.FCMRetry{}
+	for _, doc := range docs {
+		var retry domain.FCMRetry
+		err = doc.DataTo(&retry)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal FCM retry from firebase doc: %w", err)
+		}
+		retries = append(retries, retry)
+	}
+	return retries, nil
+}
+
+// DeleteFCMRetry removes an FCM retry
+func (fr Repository) DeleteFCMRetry(
+	ctx context.Context,
+	id string,
+) error {
+	ctx, span := tracer.Start(ctx, "DeleteFCMRetry")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getFCMRetriesCollectionName(),
+	).Doc(id).Delete(ctx)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to delete FCM retry: %w", err)
+	}
+	return nil
+}
+
+// SaveFCMDeadLetter keeps an FCM message that was given up on
+func (fr Repository) SaveFCMDeadLetter(
 	ctx context.Context,
-	response *dto.NPSResponse,
+	letter domain.FCMDeadLetter,
 ) error {
-	ctx, span := tracer.Start(ctx, "SaveNPSResponse")
+	ctx, span := tracer.Start(ctx, "SaveFCMDeadLetter")
 	defer span.End()
-	collection := fr.getNPSResponseCollectionName()
-	_, _, err := fr.firestoreClient.Collection(collection).Add(ctx, response)
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getFCMDeadLettersCollectionName(),
+	).Doc(letter.ID).Set(ctx, letter)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
-		return fmt.Errorf("can't save nps response: %w", err)
+		return fmt.Errorf("unable to save FCM dead letter: %w", err)
 	}
 	return nil
 }
 
-// RecordSurveyFeedbackResponse stores the feedback responses
-func (fr Repository) RecordSurveyFeedbackResponse(
+// ListFCMDeadLetters retrieves the FCM dead letters, the latest first
+func (fr Repository) ListFCMDeadLetters(
 	ctx context.Context,
-	response *domain.SurveyFeedbackResponse,
+	limit int,
+) ([]domain.FCMDeadLetter, error) {
+	ctx, span := tracer.Start(ctx, "ListFCMDeadLetters")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	query := fr.firestoreClient.Collection(
+		fr.getFCMDeadLettersCollectionName(),
+	).OrderBy("deadAt", firestore.Desc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to list FCM dead letters: %w", err)
+	}
+
+	letters := []domain.FCMDeadLetter{}
+	for _, doc := range docs {
+		var letter domain.FCMDeadLetter
+		err = doc.DataTo(&letter)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to unmarshal FCM dead letter from firebase doc: %w", err)
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
+
+// GetFCMDeadLetter retrieves an FCM dead letter. It is nil when there is no
+// dead letter with the ID.
+func (fr Repository) GetFCMDeadLetter(
+	ctx context.Context,
+	id string,
+) (*domain.FCMDeadLetter, error) {
+	ctx, span := tracer.Start(ctx, "GetFCMDeadLetter")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	doc, err := fr.firestoreClient.Collection(
+		fr.getFCMDeadLettersCollectionName(),
+	).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get FCM dead letter: %w", err)
+	}
+
+	letter := &domain.FCMDeadLetter{}
+	err = doc.DataTo(letter)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"unable to unmarshal FCM dead letter from firebase doc: %w", err)
+	}
+	return letter, nil
+}
+
+// DeleteFCMDeadLetter removes an FCM dead letter
+func (fr Repository) DeleteFCMDeadLetter(
+	ctx context.Context,
+	id string,
 ) error {
-	ctx, span := tracer.Start(ctx, "SaveSurveyFeedback")
-	collection := fr.getRecordSurveyFeedbackResponseCollectionName()
-	_, _, err := fr.firestoreClient.Collection(collection).Add(ctx, response)
+	ctx, span := tracer.Start(ctx, "DeleteFCMDeadLetter")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getFCMDeadLettersCollectionName(),
+	).Doc(id).Delete(ctx)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
-		return fmt.Errorf("can't save survey feedback response: %w", err)
+		return fmt.Errorf("unable to delete FCM dead letter: %w", err)
 	}
 	return nil
 }
 
-// SaveOutgoingEmails saves all the outgoing emails
-func (fr Repository) SaveOutgoingEmails(
+// notificationsByMessageID looks up the saved notifications with an FCM
+// message ID. A message sent to a topic has one, while each token of a
+// multicast gets its own message ID.
+func (fr Repository) notificationsByMessageID(
 	ctx context.Context,
-	payload *dto.OutgoingEmailsLog,
+	messageID string,
+) ([]*firestore.DocumentSnapshot, error) {
+	return fr.firestoreClient.Collection(
+		fr.getNotificationCollectionName(),
+	).Where("MessageID", "==", messageID).Documents(ctx).GetAll()
+}
+
+// GetNotificationsByMessageID retrieves the saved notifications with an FCM
+// message ID
+func (fr Repository) GetNotificationsByMessageID(
+	ctx context.Context,
+	messageID string,
+) ([]*dto.SavedNotification, error) {
+	ctx, span := tracer.Start(ctx, "GetNotificationsByMessageID")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
+
+	docs, err := fr.notificationsByMessageID(ctx, messageID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to retrieve notifications: %w", err)
+	}
+	notifications := []*dto.SavedNotification{}
+	for _, doc := range docs {
+		var notification dto.SavedNotification
+		err = doc.DataTo(&notification)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"error unmarshalling saved notification: %w", err)
+		}
+		notifications = append(notifications, &notification)
+	}
+	return notifications, nil
+}
+
+// UpdateNotificationStatus records an app's acknowledgement of the saved
+// notifications with an FCM message ID
+func (fr Repository) UpdateNotificationStatus(
+	ctx context.Context,
+	messageID string,
+	status domain.NotificationStatus,
+	at time.Time,
 ) error {
-	ctx, span := tracer.Start(ctx, "SaveOutgoingEmails")
+	ctx, span := tracer.Start(ctx, "UpdateNotificationStatus")
 	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
 
-	collection := fr.getOutgoingEmailsCollectionName()
-	_, _, err := fr.firestoreClient.Collection(collection).Add(ctx, payload)
+	docs, err := fr.notificationsByMessageID(ctx, messageID)
 	if err != nil {
-		return fmt.Errorf("unable to save ougoing email logs")
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to retrieve notifications: %w", err)
+	}
+	for _, doc := range docs {
+		var notification dto.SavedNotification
+		err = doc.DataTo(&notification)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return fmt.Errorf(
+				"error unmarshalling saved notification: %w", err)
+		}
+		if !notification.Acknowledge(status, at) {
+			continue
+		}
+		_, err = doc.Ref.Set(ctx, notification)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return fmt.Errorf("unable to update notification status: %w", err)
+		}
 	}
 	return nil
 }
 
-// UpdateMailgunDeliveryStatus updates the status and delivery time of the sent email message
-func (fr Repository) UpdateMailgunDeliveryStatus(
+// RetrieveNotificationsForTokens retrieves the latest notifications sent to
+// any of the registration tokens, the latest first
+func (fr Repository) RetrieveNotificationsForTokens(
 	ctx context.Context,
-	payload *dto.MailgunEvent,
-) (*dto.OutgoingEmailsLog, error) {
-	ctx, span := tracer.Start(ctx, "UpdateMailgunDeliveryStatus")
+	registrationTokens []string,
+	newerThan time.Time,
+	limit int,
+) ([]*dto.SavedNotification, error) {
+	ctx, span := tracer.Start(ctx, "RetrieveNotificationsForTokens")
 	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
+	}
 
-	collection := fr.getOutgoingEmailsCollectionName()
+	notifications := []*dto.SavedNotification{}
+	// Firestore allows at most 10 values in an `in` filter
+	for start := 0; start < len(registrationTokens); start += 10 {
+		end := start + 10
+		if end > len(registrationTokens) {
+			end = len(registrationTokens)
+		}
+		query := fr.firestoreClient.Collection(
+			fr.getNotificationCollectionName(),
+		).Where(
+			"RegistrationToken", "in", registrationTokens[start:end],
+		).Where(
+			"Timestamp", ">=", newerThan,
+		).OrderBy("Timestamp", firestore.Desc)
+		if limit > 0 {
+			query = query.Limit(limit)
+		}
+		docs, err := query.Documents(ctx).GetAll()
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to retrieve notifications: %w", err)
+		}
+		for _, doc := range docs {
+			var notification dto.SavedNotification
+			err = doc.DataTo(&notification)
+			if err != nil {
+				helpers.RecordSpanError(span, err)
+				return nil, fmt.Errorf(
+					"error unmarshalling saved notification: %w", err)
+			}
+			notifications = append(notifications, &notification)
+		}
+	}
 
-	query := fr.firestoreClient.Collection(collection).
-		Where("messageID", "==", payload.MessageID)
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].Timestamp.After(notifications[j].Timestamp)
+	})
+	if limit > 0 && len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
 
-	docs, err := fetchQueryDocs(ctx, query, true)
-	if err != nil {
-		return nil, err
+// NotificationOpenRates summarizes the notifications sent in a period by
+// sender type. Topic notifications are left out as they are not
+// acknowledged.
+func (fr Repository) NotificationOpenRates(
+	ctx context.Context,
+	since time.Time,
+	until time.Time,
+) ([]domain.NotificationOpenRate, error) {
+	ctx, span := tracer.Start(ctx, "NotificationOpenRates")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf(
+			"repository precondition check failed: %w", err)
 	}
 
-	var emailLogData dto.OutgoingEmailsLog
-	err = docs[0].DataTo(&emailLogData)
+	docs, err := fr.firestoreClient.Collection(
+		fr.getNotificationCollectionName(),
+	).Where(
+		"Timestamp", ">=", since,
+	).Where(
+		"Timestamp", "<", until,
+	).Select("SenderType", "Status", "Topic", "Condition").Documents(ctx).GetAll()
 	if err != nil {
-		return nil, fmt.Errorf(
-			"unable to marshal data from docs snapshot: %w",
-			err,
-		)
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to retrieve notifications: %w", err)
 	}
 
-	timeOfDelivery := helpers.EpochTimetoStandardTime(payload.DeliveredOn)
+	rates := map[string]*domain.NotificationOpenRate{}
+	for _, doc := range docs {
+		var notification dto.SavedNotification
+		err = doc.DataTo(&notification)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"error unmarshalling saved notification: %w", err)
+		}
+		if notification.Topic != "" || notification.Condition != "" {
+			continue
+		}
+		senderType := notification.SenderType
+		if senderType == "" {
+			senderType = domain.NotificationSenderOther
+		}
+		rate, ok := rates[senderType]
+		if !ok {
+			rate = &domain.NotificationOpenRate{SenderType: senderType}
+			rates[senderType] = rate
+		}
+		rate.Count(notification.Status)
+	}
 
-	eventOutput := dto.MailgunEventOutput{
-		EventName:   payload.EventName,
-		DeliveredOn: timeOfDelivery,
+	summary := []domain.NotificationOpenRate{}
+	for _, rate := range rates {
+		summary = append(summary, *rate)
 	}
+	sort.Slice(summary, func(i, j int) bool {
+		return summary[i].SenderType < summary[j].SenderType
+	})
+	return summary, nil
+}
 
-	emailLogData.Event = &eventOutput
+func (fr Repository) getProcessedMessagesCollectionName() string {
+	suffixed := firebasetools.SuffixCollection(processedMessagesCollectionName)
+	return suffixed
+}
 
-	doc := fr.firestoreClient.Collection(fr.getOutgoingEmailsCollectionName()).
-		Doc(docs[0].Ref.ID)
-	if _, err = doc.Set(ctx, emailLogData); err != nil {
-		logrus.Print("an error occurred while updating data: ", err)
-		return nil, fmt.Errorf("unable to update data")
+// processedMessageDocID is the ID of the document that records a Pub/Sub
+// message. Message IDs are only unique within a topic.
+func processedMessageDocID(topic string, messageID string) string {
+	sum := sha256.Sum256([]byte(topic + "|" + messageID))
+	return hex.EncodeToString(sum[:])
+}
+
+// ClaimPubSubMessage records that a Pub/Sub message is being processed. When
+// the message has already been processed, or is being processed by another
+// delivery, the existing record is returned with false.
+//
+// Expired records, and records whose lease has run out before the message
+// was processed, are replaced. The steps of a message whose processing
+// failed part way are carried over so that they are not repeated. A
+// Firestore TTL policy on `expiresAt` can be used to remove the records.
+func (fr Repository) ClaimPubSubMessage(
+	ctx context.Context,
+	message domain.ProcessedMessage,
+) (*domain.ProcessedMessage, bool, error) {
+	ctx, span := tracer.Start(ctx, "ClaimPubSubMessage")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, false, fmt.Errorf(
+			"repository precondition check failed: %w", err)
 	}
 
-	return &emailLogData, nil
+	ref := fr.firestoreClient.Collection(
+		fr.getProcessedMessagesCollectionName(),
+	).Doc(processedMessageDocID(message.Topic, message.ID))
+
+	var existing *domain.ProcessedMessage
+	err := fr.firestoreClient.RunTransaction(
+		ctx,
+		func(ctx context.Context, tx *firestore.Transaction) error {
+			existing = nil
+			doc, err := tx.Get(ref)
+			if err != nil && status.Code(err) != codes.NotFound {
+				return fmt.Errorf("unable to read processed message: %w", err)
+			}
+			if err == nil {
+				found := &domain.ProcessedMessage{}
+				if err := doc.DataTo(found); err != nil {
+					return fmt.Errorf("unable to unmarshal processed message: %w", err)
+				}
+				if !found.Claimable(message.ReceivedAt) {
+					existing = found
+					return nil
+				}
+				if found.ExpiresAt.After(message.ReceivedAt) {
+					message.Steps = found.Steps
+				}
+			}
+			return tx.Set(ref, message)
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, false, fmt.Errorf("unable to claim pub sub message: %w", err)
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+	return &message, true, nil
 }
 
-// SaveTwilioVideoCallbackStatus saves the callback data
-func (fr Repository) SaveTwilioVideoCallbackStatus(
+// CompletePubSubMessage records that a Pub/Sub message was processed
+func (fr Repository) CompletePubSubMessage(
 	ctx context.Context,
-	data dto.CallbackData,
+	topic string,
+	messageID string,
 ) error {
-	ctx, span := tracer.Start(ctx, "SaveTwilioVideoCallbackStatus")
+	ctx, span := tracer.Start(ctx, "CompletePubSubMessage")
 	defer span.End()
 	if err := fr.checkPreconditions(); err != nil {
 		helpers.RecordSpanError(span, err)
 		return fmt.Errorf("repository precondition check failed: %w", err)
 	}
 
-	collectionName := fr.getTwilioVideoCallbackCollectionName()
-	_, _, err := fr.firestoreClient.Collection(collectionName).Add(ctx, data)
+	_, err := fr.firestoreClient.Collection(
+		fr.getProcessedMessagesCollectionName(),
+	).Doc(processedMessageDocID(topic, messageID)).Update(ctx, []firestore.Update{
+		{Path: "completed", Value: true},
+		{Path: "completedAt", Value: time.Now()},
+	})
 	if err != nil {
 		helpers.RecordSpanError(span, err)
-		return fmt.Errorf("unable to save callback response")
+		return fmt.Errorf("unable to complete pub sub message: %w", err)
+	}
+	return nil
+}
+
+// CompletePubSubStep records that a side effect of processing a Pub/Sub
+// message is done
+func (fr Repository) CompletePubSubStep(
+	ctx context.Context,
+	topic string,
+	messageID string,
+	step string,
+) error {
+	ctx, span := tracer.Start(ctx, "CompletePubSubStep")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("repository precondition check failed: %w", err)
+	}
+
+	_, err := fr.firestoreClient.Collection(
+		fr.getProcessedMessagesCollectionName(),
+	).Doc(processedMessageDocID(topic, messageID)).Update(ctx, []firestore.Update{
+		{Path: "steps", Value: firestore.ArrayUnion(step)},
+	})
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to complete pub sub message step: %w", err)
+	}
+	return nil
+}
+
+// ReleasePubSubMessage ends the lease of a Pub/Sub message that failed to
+// process so that its redelivery is processed. The steps that were done are
+// kept.
+func (fr Repository) ReleasePubSubMessage(
+	ctx context.Context,
+	topic string,
+	messageID string,
+) error {
+	ctx, span := tracer.Start(ctx, "ReleasePubSubMessage")
+	defer span.End()
+	if err := fr.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("repository precondition check failed: %w", err)
 	}
 
+	_, err := fr.firestoreClient.Collection(
+		fr.getProcessedMessagesCollectionName(),
+	).Doc(processedMessageDocID(topic, messageID)).Update(ctx, []firestore.Update{
+		{Path: "leaseExpiresAt", Value: time.Now()},
+	})
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to release pub sub message: %w", err)
+	}
 	return nil
 }
@@ -2145,6 +2145,51 @@ func TestRepository_SaveLabel(t *testing.T) {
 	}
 }
 
+func TestRepository_SaveLabel_KeepsExistingLabels(t *testing.T) {
+	ctx := context.Background()
+	fr, err := db.NewFirebaseRepository(ctx)
+	if err != nil {
+		t.Errorf("can't initialize Firebase repository: %v", err)
+		return
+	}
+
+	uid := ksuid.New().String()
+	flavour := feedlib.FlavourConsumer
+	first := ksuid.New().String()
+	second := ksuid.New().String()
+
+	for _, label := range []string{first, second} {
+		err = fr.SaveLabel(ctx, uid, flavour, label)
+		if err != nil {
+			t.Errorf("can't save label %s: %v", label, err)
+			return
+		}
+	}
+
+	labels, err := fr.Labels(ctx, uid, flavour)
+	if err != nil {
+		t.Errorf("can't get labels: %v", err)
+		return
+	}
+	assert.Contains(t, labels, common.DefaultLabel)
+	assert.Contains(t, labels, first)
+	assert.Contains(t, labels, second)
+
+	err = fr.RemoveLabels(ctx, uid, flavour, []string{first})
+	if err != nil {
+		t.Errorf("can't remove label: %v", err)
+		return
+	}
+
+	labels, err = fr.Labels(ctx, uid, flavour)
+	if err != nil {
+		t.Errorf("can't get labels: %v", err)
+		return
+	}
+	assert.NotContains(t, labels, first)
+	assert.Contains(t, labels, second)
+}
+
 func TestRepository_UnreadPersistentItems(t *testing.T) {
 	ctx := context.Background()
 	fr, err := db.NewFirebaseRepository(ctx)
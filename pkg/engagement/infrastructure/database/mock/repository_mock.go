@@ -34,6 +34,13 @@ type FakeEngagementRepository struct {
 		itemID string,
 	) (*feedlib.Item, error)
 
+	GetFeedItemStatusesFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemIDs []string,
+	) (map[string]feedlib.Status, error)
+
 	// saving a new feed item
 	SaveFeedItemFn func(
 		ctx context.Context,
@@ -141,6 +148,54 @@ type FakeEngagementRepository struct {
 		messageID string,
 	) error
 
+	GetThreadMessagesFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+	) ([]domain.ThreadMessage, error)
+
+	EditMessageFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		text string,
+		editedByUID string,
+	) (*domain.ThreadMessage, error)
+
+	ReactToMessageFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		emoji string,
+		reactorUID string,
+		reacted bool,
+	) error
+
+	AttachToMessageFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		attachments []domain.MessageAttachment,
+	) error
+
+	SaveUploadOwnerFn func(
+		ctx context.Context,
+		uploadID string,
+		uid string,
+	) error
+
+	GetUploadOwnerFn func(
+		ctx context.Context,
+		uploadID string,
+	) (string, error)
+
 	// GetMessages retrieves a message
 	GetMessagesFn func(
 		ctx context.Context,
@@ -198,6 +253,27 @@ type FakeEngagementRepository struct {
 		label string,
 	) error
 
+	RemoveLabelsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		labels []string,
+	) error
+
+	RelabelItemsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		label string,
+		newLabel string,
+	) (int, error)
+
+	LabelCountsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) ([]domain.LabelCount, error)
+
 	UnreadPersistentItemsFn func(
 		ctx context.Context,
 		uid string,
@@ -217,6 +293,330 @@ type FakeEngagementRepository struct {
 		title string,
 	) (*feedlib.Nudge, error)
 
+	SaveElementTranslationsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		translations *domain.ElementTranslations,
+	) (*domain.ElementTranslations, error)
+
+	GetElementTranslationsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) (map[string]domain.ElementTranslations, error)
+
+	GetFeedChangesFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		after domain.SyncCursor,
+		limit int,
+	) (*domain.FeedChanges, error)
+
+	GetElementsExpiringBeforeFn func(
+		ctx context.Context,
+		elementType domain.ElementType,
+		before time.Time,
+		after *domain.ExpiryCursor,
+		limit int,
+	) ([]domain.ExpiringElement, error)
+
+	ArchiveFeedItemFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		item *feedlib.Item,
+	) error
+
+	ArchiveNudgeFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		nudge *feedlib.Nudge,
+	) error
+
+	ClaimExpiryReminderFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		elementType domain.ElementType,
+		elementID string,
+		expiry time.Time,
+	) (bool, error)
+
+	GetFeedElementsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) (*domain.Feed, error)
+
+	ReassignIncomingEventsFn func(
+		ctx context.Context,
+		flavour feedlib.Flavour,
+		fromUID string,
+		toUID string,
+	) (int, error)
+
+	SaveFeedMergeFn func(
+		ctx context.Context,
+		merge *domain.FeedMerge,
+	) error
+
+	GetFeedMergeFn func(
+		ctx context.Context,
+		anonymousUID string,
+		flavour feedlib.Flavour,
+	) (*domain.FeedMerge, error)
+
+	SetThreadMuteFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		participantUID string,
+		muted bool,
+	) error
+
+	GetThreadMutesFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+	) ([]string, error)
+
+	SaveChecklistFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		checklist domain.Checklist,
+	) error
+
+	GetChecklistFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+	) (*domain.Checklist, error)
+
+	GetChecklistsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) ([]domain.Checklist, error)
+
+	SaveChannelDeliveriesFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		deliveries []domain.ChannelDelivery,
+	) error
+
+	GetChannelDeliveriesFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		elementID string,
+	) ([]domain.ChannelDelivery, error)
+
+	SaveNotificationPreferencesFn func(
+		ctx context.Context,
+		preferences domain.NotificationPreferences,
+	) error
+
+	GetNotificationPreferencesFn func(
+		ctx context.Context,
+		uid string,
+	) (*domain.NotificationPreferences, error)
+
+	SaveDeferredNotificationFn func(
+		ctx context.Context,
+		notification domain.DeferredNotification,
+	) error
+
+	GetDueDeferredNotificationsFn func(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) ([]domain.DeferredNotification, error)
+
+	DeleteDeferredNotificationFn func(
+		ctx context.Context,
+		id string,
+	) error
+
+	SaveDigestSettingsFn func(
+		ctx context.Context,
+		settings domain.DigestSettings,
+	) error
+
+	GetDigestSettingsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) (*domain.DigestSettings, error)
+
+	ListDigestSettingsFn func(
+		ctx context.Context,
+		frequency domain.DigestFrequency,
+		after *domain.DigestSettings,
+		limit int,
+	) ([]domain.DigestSettings, error)
+
+	QueueInboxCountPushFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		dueAt time.Time,
+	) error
+
+	GetDueInboxCountPushesFn func(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) ([]domain.InboxCountPush, error)
+
+	DeleteInboxCountPushFn func(
+		ctx context.Context,
+		push domain.InboxCountPush,
+	) error
+
+	GetTokenHealthFn func(
+		ctx context.Context,
+		tokens []string,
+	) (map[string]domain.TokenHealth, error)
+
+	SaveTokenHealthFn func(
+		ctx context.Context,
+		health domain.TokenHealth,
+	) error
+
+	SaveFCMRetryFn func(
+		ctx context.Context,
+		retry domain.FCMRetry,
+	) error
+
+	GetDueFCMRetriesFn func(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) ([]domain.FCMRetry, error)
+
+	DeleteFCMRetryFn func(
+		ctx context.Context,
+		id string,
+	) error
+
+	SaveFCMDeadLetterFn func(
+		ctx context.Context,
+		letter domain.FCMDeadLetter,
+	) error
+
+	ListFCMDeadLettersFn func(
+		ctx context.Context,
+		limit int,
+	) ([]domain.FCMDeadLetter, error)
+
+	GetFCMDeadLetterFn func(
+		ctx context.Context,
+		id string,
+	) (*domain.FCMDeadLetter, error)
+
+	DeleteFCMDeadLetterFn func(
+		ctx context.Context,
+		id string,
+	) error
+
+	GetNotificationsByMessageIDFn func(
+		ctx context.Context,
+		messageID string,
+	) ([]*dto.SavedNotification, error)
+
+	UpdateNotificationStatusFn func(
+		ctx context.Context,
+		messageID string,
+		status domain.NotificationStatus,
+		at time.Time,
+	) error
+
+	RetrieveNotificationsForTokensFn func(
+		ctx context.Context,
+		registrationTokens []string,
+		newerThan time.Time,
+		limit int,
+	) ([]*dto.SavedNotification, error)
+
+	NotificationOpenRatesFn func(
+		ctx context.Context,
+		since time.Time,
+		until time.Time,
+	) ([]domain.NotificationOpenRate, error)
+
+	ClaimPubSubMessageFn func(
+		ctx context.Context,
+		message domain.ProcessedMessage,
+	) (*domain.ProcessedMessage, bool, error)
+
+	CompletePubSubMessageFn func(
+		ctx context.Context,
+		topic string,
+		messageID string,
+	) error
+
+	CompletePubSubStepFn func(
+		ctx context.Context,
+		topic string,
+		messageID string,
+		step string,
+	) error
+
+	ReleasePubSubMessageFn func(
+		ctx context.Context,
+		topic string,
+		messageID string,
+	) error
+
+	SetMessageHiddenFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		hidden bool,
+	) error
+
+	ReportMessageFn func(
+		ctx context.Context,
+		moderationCase domain.ModerationCase,
+		report domain.MessageReport,
+	) (*domain.ModerationCase, error)
+
+	GetModerationCaseFn func(
+		ctx context.Context,
+		caseID string,
+	) (*domain.ModerationCase, error)
+
+	GetModerationCasesFn func(
+		ctx context.Context,
+		status domain.ModerationStatus,
+		limit int,
+	) ([]domain.ModerationCase, error)
+
+	UpdateModerationCaseFn func(
+		ctx context.Context,
+		moderationCase domain.ModerationCase,
+	) error
+
+	SaveModerationAuditEntryFn func(
+		ctx context.Context,
+		entry domain.ModerationAuditEntry,
+	) error
+
+	GetModerationAuditFn func(
+		ctx context.Context,
+		caseID string,
+	) ([]domain.ModerationAuditEntry, error)
+
 	SaveTwilioResponseFn func(
 		ctx context.Context,
 		data dto.Message,
@@ -254,6 +654,24 @@ type FakeEngagementRepository struct {
 		ctx context.Context,
 		data dto.CallbackData,
 	) error
+
+	ClaimIdempotencyKeyFn func(
+		ctx context.Context,
+		record domain.IdempotencyRecord,
+	) (*domain.IdempotencyRecord, bool, error)
+
+	CompleteIdempotencyKeyFn func(
+		ctx context.Context,
+		scope string,
+		key string,
+		response domain.IdempotentResponse,
+	) error
+
+	ReleaseIdempotencyKeyFn func(
+		ctx context.Context,
+		scope string,
+		key string,
+	) error
 }
 
 // GetFeed ...
@@ -282,13 +700,23 @@ func (f *FakeEngagementRepository) GetFeedItem(
 	return f.GetFeedItemFn(ctx, uid, flavour, itemID)
 }
 
-// SaveFeedItem ...
-func (f *FakeEngagementRepository) SaveFeedItem(
+// GetFeedItemStatuses ...
+func (f *FakeEngagementRepository) GetFeedItemStatuses(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-	item *feedlib.Item,
-) (*feedlib.Item, error) {
+	itemIDs []string,
+) (map[string]feedlib.Status, error) {
+	return f.GetFeedItemStatusesFn(ctx, uid, flavour, itemIDs)
+}
+
+// SaveFeedItem ...
+func (f *FakeEngagementRepository) SaveFeedItem(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	item *feedlib.Item,
+) (*feedlib.Item, error) {
 	return f.SaveFeedItemFn(ctx, uid, flavour, item)
 }
 
@@ -415,6 +843,72 @@ func (f *FakeEngagementRepository) DeleteMessage(
 	return f.DeleteMessageFn(ctx, uid, flavour, itemID, messageID)
 }
 
+// GetThreadMessages ...
+func (f *FakeEngagementRepository) GetThreadMessages(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) ([]domain.ThreadMessage, error) {
+	return f.GetThreadMessagesFn(ctx, uid, flavour, itemID)
+}
+
+// EditMessage ...
+func (f *FakeEngagementRepository) EditMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	text string,
+	editedByUID string,
+) (*domain.ThreadMessage, error) {
+	return f.EditMessageFn(ctx, uid, flavour, itemID, messageID, text, editedByUID)
+}
+
+// ReactToMessage ...
+func (f *FakeEngagementRepository) ReactToMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	emoji string,
+	reactorUID string,
+	reacted bool,
+) error {
+	return f.ReactToMessageFn(ctx, uid, flavour, itemID, messageID, emoji, reactorUID, reacted)
+}
+
+// AttachToMessage ...
+func (f *FakeEngagementRepository) AttachToMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	attachments []domain.MessageAttachment,
+) error {
+	return f.AttachToMessageFn(ctx, uid, flavour, itemID, messageID, attachments)
+}
+
+// SaveUploadOwner ...
+func (f *FakeEngagementRepository) SaveUploadOwner(
+	ctx context.Context,
+	uploadID string,
+	uid string,
+) error {
+	return f.SaveUploadOwnerFn(ctx, uploadID, uid)
+}
+
+// GetUploadOwner ...
+func (f *FakeEngagementRepository) GetUploadOwner(
+	ctx context.Context,
+	uploadID string,
+) (string, error) {
+	return f.GetUploadOwnerFn(ctx, uploadID)
+}
+
 // GetMessages retrieves a message
 func (f *FakeEngagementRepository) GetMessages(
 	ctx context.Context,
@@ -495,6 +989,36 @@ func (f *FakeEngagementRepository) SaveLabel(
 	return f.SaveLabelFn(ctx, uid, flavour, label)
 }
 
+// RemoveLabels ...
+func (f *FakeEngagementRepository) RemoveLabels(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	labels []string,
+) error {
+	return f.RemoveLabelsFn(ctx, uid, flavour, labels)
+}
+
+// RelabelItems ...
+func (f *FakeEngagementRepository) RelabelItems(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	label string,
+	newLabel string,
+) (int, error) {
+	return f.RelabelItemsFn(ctx, uid, flavour, label, newLabel)
+}
+
+// LabelCounts ...
+func (f *FakeEngagementRepository) LabelCounts(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) ([]domain.LabelCount, error) {
+	return f.LabelCountsFn(ctx, uid, flavour)
+}
+
 // UnreadPersistentItems ...
 func (f *FakeEngagementRepository) UnreadPersistentItems(
 	ctx context.Context,
@@ -523,6 +1047,489 @@ func (f *FakeEngagementRepository) GetDefaultNudgeByTitle(
 	return f.GetDefaultNudgeByTitleFn(ctx, uid, flavour, title)
 }
 
+// SaveElementTranslations ...
+func (f *FakeEngagementRepository) SaveElementTranslations(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	translations *domain.ElementTranslations,
+) (*domain.ElementTranslations, error) {
+	return f.SaveElementTranslationsFn(ctx, uid, flavour, translations)
+}
+
+// GetElementTranslations ...
+func (f *FakeEngagementRepository) GetElementTranslations(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (map[string]domain.ElementTranslations, error) {
+	return f.GetElementTranslationsFn(ctx, uid, flavour)
+}
+
+// GetFeedChanges ...
+func (f *FakeEngagementRepository) GetFeedChanges(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	after domain.SyncCursor,
+	limit int,
+) (*domain.FeedChanges, error) {
+	return f.GetFeedChangesFn(ctx, uid, flavour, after, limit)
+}
+
+// GetElementsExpiringBefore ...
+func (f *FakeEngagementRepository) GetElementsExpiringBefore(
+	ctx context.Context,
+	elementType domain.ElementType,
+	before time.Time,
+	after *domain.ExpiryCursor,
+	limit int,
+) ([]domain.ExpiringElement, error) {
+	return f.GetElementsExpiringBeforeFn(ctx, elementType, before, after, limit)
+}
+
+// ArchiveFeedItem ...
+func (f *FakeEngagementRepository) ArchiveFeedItem(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	item *feedlib.Item,
+) error {
+	return f.ArchiveFeedItemFn(ctx, uid, flavour, item)
+}
+
+// ArchiveNudge ...
+func (f *FakeEngagementRepository) ArchiveNudge(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	nudge *feedlib.Nudge,
+) error {
+	return f.ArchiveNudgeFn(ctx, uid, flavour, nudge)
+}
+
+// ClaimExpiryReminder ...
+func (f *FakeEngagementRepository) ClaimExpiryReminder(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	elementType domain.ElementType,
+	elementID string,
+	expiry time.Time,
+) (bool, error) {
+	return f.ClaimExpiryReminderFn(ctx, uid, flavour, elementType, elementID, expiry)
+}
+
+// GetFeedElements ...
+func (f *FakeEngagementRepository) GetFeedElements(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (*domain.Feed, error) {
+	return f.GetFeedElementsFn(ctx, uid, flavour)
+}
+
+// ReassignIncomingEvents ...
+func (f *FakeEngagementRepository) ReassignIncomingEvents(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	fromUID string,
+	toUID string,
+) (int, error) {
+	return f.ReassignIncomingEventsFn(ctx, flavour, fromUID, toUID)
+}
+
+// SaveFeedMerge ...
+func (f *FakeEngagementRepository) SaveFeedMerge(
+	ctx context.Context,
+	merge *domain.FeedMerge,
+) error {
+	return f.SaveFeedMergeFn(ctx, merge)
+}
+
+// GetFeedMerge ...
+func (f *FakeEngagementRepository) GetFeedMerge(
+	ctx context.Context,
+	anonymousUID string,
+	flavour feedlib.Flavour,
+) (*domain.FeedMerge, error) {
+	return f.GetFeedMergeFn(ctx, anonymousUID, flavour)
+}
+
+// SetThreadMute ...
+func (f *FakeEngagementRepository) SetThreadMute(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	participantUID string,
+	muted bool,
+) error {
+	return f.SetThreadMuteFn(ctx, uid, flavour, itemID, participantUID, muted)
+}
+
+// GetThreadMutes ...
+func (f *FakeEngagementRepository) GetThreadMutes(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) ([]string, error) {
+	return f.GetThreadMutesFn(ctx, uid, flavour, itemID)
+}
+
+// SaveChecklist ...
+func (f *FakeEngagementRepository) SaveChecklist(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	checklist domain.Checklist,
+) error {
+	return f.SaveChecklistFn(ctx, uid, flavour, checklist)
+}
+
+// GetChecklist ...
+func (f *FakeEngagementRepository) GetChecklist(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) (*domain.Checklist, error) {
+	return f.GetChecklistFn(ctx, uid, flavour, itemID)
+}
+
+// GetChecklists ...
+func (f *FakeEngagementRepository) GetChecklists(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) ([]domain.Checklist, error) {
+	return f.GetChecklistsFn(ctx, uid, flavour)
+}
+
+// SaveChannelDeliveries ...
+func (f *FakeEngagementRepository) SaveChannelDeliveries(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	deliveries []domain.ChannelDelivery,
+) error {
+	return f.SaveChannelDeliveriesFn(ctx, uid, flavour, deliveries)
+}
+
+// GetChannelDeliveries ...
+func (f *FakeEngagementRepository) GetChannelDeliveries(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	elementID string,
+) ([]domain.ChannelDelivery, error) {
+	return f.GetChannelDeliveriesFn(ctx, uid, flavour, elementID)
+}
+
+// SaveNotificationPreferences ...
+func (f *FakeEngagementRepository) SaveNotificationPreferences(
+	ctx context.Context,
+	preferences domain.NotificationPreferences,
+) error {
+	return f.SaveNotificationPreferencesFn(ctx, preferences)
+}
+
+// GetNotificationPreferences ...
+func (f *FakeEngagementRepository) GetNotificationPreferences(
+	ctx context.Context,
+	uid string,
+) (*domain.NotificationPreferences, error) {
+	return f.GetNotificationPreferencesFn(ctx, uid)
+}
+
+// SaveDeferredNotification ...
+func (f *FakeEngagementRepository) SaveDeferredNotification(
+	ctx context.Context,
+	notification domain.DeferredNotification,
+) error {
+	return f.SaveDeferredNotificationFn(ctx, notification)
+}
+
+// GetDueDeferredNotifications ...
+func (f *FakeEngagementRepository) GetDueDeferredNotifications(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]domain.DeferredNotification, error) {
+	return f.GetDueDeferredNotificationsFn(ctx, before, limit)
+}
+
+// DeleteDeferredNotification ...
+func (f *FakeEngagementRepository) DeleteDeferredNotification(
+	ctx context.Context,
+	id string,
+) error {
+	return f.DeleteDeferredNotificationFn(ctx, id)
+}
+
+// SaveDigestSettings ...
+func (f *FakeEngagementRepository) SaveDigestSettings(
+	ctx context.Context,
+	settings domain.DigestSettings,
+) error {
+	return f.SaveDigestSettingsFn(ctx, settings)
+}
+
+// GetDigestSettings ...
+func (f *FakeEngagementRepository) GetDigestSettings(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (*domain.DigestSettings, error) {
+	return f.GetDigestSettingsFn(ctx, uid, flavour)
+}
+
+// ListDigestSettings ...
+func (f *FakeEngagementRepository) ListDigestSettings(
+	ctx context.Context,
+	frequency domain.DigestFrequency,
+	after *domain.DigestSettings,
+	limit int,
+) ([]domain.DigestSettings, error) {
+	return f.ListDigestSettingsFn(ctx, frequency, after, limit)
+}
+
+// QueueInboxCountPush ...
+func (f *FakeEngagementRepository) QueueInboxCountPush(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	dueAt time.Time,
+) error {
+	return f.QueueInboxCountPushFn(ctx, uid, flavour, dueAt)
+}
+
+// GetDueInboxCountPushes ...
+func (f *FakeEngagementRepository) GetDueInboxCountPushes(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]domain.InboxCountPush, error) {
+	return f.GetDueInboxCountPushesFn(ctx, before, limit)
+}
+
+// DeleteInboxCountPush ...
+func (f *FakeEngagementRepository) DeleteInboxCountPush(
+	ctx context.Context,
+	push domain.InboxCountPush,
+) error {
+	return f.DeleteInboxCountPushFn(ctx, push)
+}
+
+// GetTokenHealth ...
+func (f *FakeEngagementRepository) GetTokenHealth(
+	ctx context.Context,
+	tokens []string,
+) (map[string]domain.TokenHealth, error) {
+	return f.GetTokenHealthFn(ctx, tokens)
+}
+
+// SaveTokenHealth ...
+func (f *FakeEngagementRepository) SaveTokenHealth(
+	ctx context.Context,
+	health domain.TokenHealth,
+) error {
+	return f.SaveTokenHealthFn(ctx, health)
+}
+
+// SaveFCMRetry ...
+func (f *FakeEngagementRepository) SaveFCMRetry(
+	ctx context.Context,
+	retry domain.FCMRetry,
+) error {
+	return f.SaveFCMRetryFn(ctx, retry)
+}
+
+// GetDueFCMRetries ...
+func (f *FakeEngagementRepository) GetDueFCMRetries(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]domain.FCMRetry, error) {
+	return f.GetDueFCMRetriesFn(ctx, before, limit)
+}
+
+// DeleteFCMRetry ...
+func (f *FakeEngagementRepository) DeleteFCMRetry(
+	ctx context.Context,
+	id string,
+) error {
+	return f.DeleteFCMRetryFn(ctx, id)
+}
+
+// SaveFCMDeadLetter ...
+func (f *FakeEngagementRepository) SaveFCMDeadLetter(
+	ctx context.Context,
+	letter domain.FCMDeadLetter,
+) error {
+	return f.SaveFCMDeadLetterFn(ctx, letter)
+}
+
+// ListFCMDeadLetters ...
+func (f *FakeEngagementRepository) ListFCMDeadLetters(
+	ctx context.Context,
+	limit int,
+) ([]domain.FCMDeadLetter, error) {
+	return f.ListFCMDeadLettersFn(ctx, limit)
+}
+
+// GetFCMDeadLetter ...
+func (f *FakeEngagementRepository) GetFCMDeadLetter(
+	ctx context.Context,
+	id string,
+) (*domain.FCMDeadLetter, error) {
+	return f.GetFCMDeadLetterFn(ctx, id)
+}
+
+// DeleteFCMDeadLetter ...
+func (f *FakeEngagementRepository) DeleteFCMDeadLetter(
+	ctx context.Context,
+	id string,
+) error {
+	return f.DeleteFCMDeadLetterFn(ctx, id)
+}
+
+// GetNotificationsByMessageID ...
+func (f *FakeEngagementRepository) GetNotificationsByMessageID(
+	ctx context.Context,
+	messageID string,
+) ([]*dto.SavedNotification, error) {
+	return f.GetNotificationsByMessageIDFn(ctx, messageID)
+}
+
+// UpdateNotificationStatus ...
+func (f *FakeEngagementRepository) UpdateNotificationStatus(
+	ctx context.Context,
+	messageID string,
+	status domain.NotificationStatus,
+	at time.Time,
+) error {
+	return f.UpdateNotificationStatusFn(ctx, messageID, status, at)
+}
+
+// RetrieveNotificationsForTokens ...
+func (f *FakeEngagementRepository) RetrieveNotificationsForTokens(
+	ctx context.Context,
+	registrationTokens []string,
+	newerThan time.Time,
+	limit int,
+) ([]*dto.SavedNotification, error) {
+	return f.RetrieveNotificationsForTokensFn(ctx, registrationTokens, newerThan, limit)
+}
+
+// NotificationOpenRates ...
+func (f *FakeEngagementRepository) NotificationOpenRates(
+	ctx context.Context,
+	since time.Time,
+	until time.Time,
+) ([]domain.NotificationOpenRate, error) {
+	return f.NotificationOpenRatesFn(ctx, since, until)
+}
+
+// ClaimPubSubMessage ...
+func (f *FakeEngagementRepository) ClaimPubSubMessage(
+	ctx context.Context,
+	message domain.ProcessedMessage,
+) (*domain.ProcessedMessage, bool, error) {
+	return f.ClaimPubSubMessageFn(ctx, message)
+}
+
+// CompletePubSubMessage ...
+func (f *FakeEngagementRepository) CompletePubSubMessage(
+	ctx context.Context,
+	topic string,
+	messageID string,
+) error {
+	return f.CompletePubSubMessageFn(ctx, topic, messageID)
+}
+
+// CompletePubSubStep ...
+func (f *FakeEngagementRepository) CompletePubSubStep(
+	ctx context.Context,
+	topic string,
+	messageID string,
+	step string,
+) error {
+	return f.CompletePubSubStepFn(ctx, topic, messageID, step)
+}
+
+// ReleasePubSubMessage ...
+func (f *FakeEngagementRepository) ReleasePubSubMessage(
+	ctx context.Context,
+	topic string,
+	messageID string,
+) error {
+	return f.ReleasePubSubMessageFn(ctx, topic, messageID)
+}
+
+// SetMessageHidden ...
+func (f *FakeEngagementRepository) SetMessageHidden(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	hidden bool,
+) error {
+	return f.SetMessageHiddenFn(ctx, uid, flavour, itemID, messageID, hidden)
+}
+
+// ReportMessage ...
+func (f *FakeEngagementRepository) ReportMessage(
+	ctx context.Context,
+	moderationCase domain.ModerationCase,
+	report domain.MessageReport,
+) (*domain.ModerationCase, error) {
+	return f.ReportMessageFn(ctx, moderationCase, report)
+}
+
+// GetModerationCase ...
+func (f *FakeEngagementRepository) GetModerationCase(
+	ctx context.Context,
+	caseID string,
+) (*domain.ModerationCase, error) {
+	return f.GetModerationCaseFn(ctx, caseID)
+}
+
+// GetModerationCases ...
+func (f *FakeEngagementRepository) GetModerationCases(
+	ctx context.Context,
+	status domain.ModerationStatus,
+	limit int,
+) ([]domain.ModerationCase, error) {
+	return f.GetModerationCasesFn(ctx, status, limit)
+}
+
+// UpdateModerationCase ...
+func (f *FakeEngagementRepository) UpdateModerationCase(
+	ctx context.Context,
+	moderationCase domain.ModerationCase,
+) error {
+	return f.UpdateModerationCaseFn(ctx, moderationCase)
+}
+
+// SaveModerationAuditEntry ...
+func (f *FakeEngagementRepository) SaveModerationAuditEntry(
+	ctx context.Context,
+	entry domain.ModerationAuditEntry,
+) error {
+	return f.SaveModerationAuditEntryFn(ctx, entry)
+}
+
+// GetModerationAudit ...
+func (f *FakeEngagementRepository) GetModerationAudit(
+	ctx context.Context,
+	caseID string,
+) ([]domain.ModerationAuditEntry, error) {
+	return f.GetModerationAuditFn(ctx, caseID)
+}
+
 // SaveTwilioResponse saves the callback data for future analysis
 func (f *FakeEngagementRepository) SaveTwilioResponse(
 	ctx context.Context,
@@ -584,3 +1591,30 @@ func (f *FakeEngagementRepository) SaveTwilioVideoCallbackStatus(
 ) error {
 	return f.SaveTwilioVideoCallbackStatusFn(ctx, data)
 }
+
+// ClaimIdempotencyKey ...
+func (f *FakeEngagementRepository) ClaimIdempotencyKey(
+	ctx context.Context,
+	record domain.IdempotencyRecord,
+) (*domain.IdempotencyRecord, bool, error) {
+	return f.ClaimIdempotencyKeyFn(ctx, record)
+}
+
+// CompleteIdempotencyKey ...
+func (f *FakeEngagementRepository) CompleteIdempotencyKey(
+	ctx context.Context,
+	scope string,
+	key string,
+	response domain.IdempotentResponse,
+) error {
+	return f.CompleteIdempotencyKeyFn(ctx, scope, key, response)
+}
+
+// ReleaseIdempotencyKey ...
+func (f *FakeEngagementRepository) ReleaseIdempotencyKey(
+	ctx context.Context,
+	scope string,
+	key string,
+) error {
+	return f.ReleaseIdempotencyKeyFn(ctx, scope, key)
+}
@@ -39,6 +39,13 @@ type Repository interface {
 		itemID string,
 	) (*feedlib.Item, error)
 
+	GetFeedItemStatuses(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemIDs []string,
+	) (map[string]feedlib.Status, error)
+
 	// saving a new feed item
 	SaveFeedItem(
 		ctx context.Context,
@@ -146,6 +153,54 @@ type Repository interface {
 		messageID string,
 	) error
 
+	GetThreadMessages(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+	) ([]domain.ThreadMessage, error)
+
+	EditMessage(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		text string,
+		editedByUID string,
+	) (*domain.ThreadMessage, error)
+
+	ReactToMessage(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		emoji string,
+		reactorUID string,
+		reacted bool,
+	) error
+
+	AttachToMessage(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		attachments []domain.MessageAttachment,
+	) error
+
+	SaveUploadOwner(
+		ctx context.Context,
+		uploadID string,
+		uid string,
+	) error
+
+	GetUploadOwner(
+		ctx context.Context,
+		uploadID string,
+	) (string, error)
+
 	// GetMessages retrieves a message
 	GetMessages(
 		ctx context.Context,
@@ -203,6 +258,27 @@ type Repository interface {
 		label string,
 	) error
 
+	RemoveLabels(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		labels []string,
+	) error
+
+	RelabelItems(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		label string,
+		newLabel string,
+	) (int, error)
+
+	LabelCounts(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) ([]domain.LabelCount, error)
+
 	UnreadPersistentItems(
 		ctx context.Context,
 		uid string,
@@ -222,6 +298,330 @@ type Repository interface {
 		title string,
 	) (*feedlib.Nudge, error)
 
+	SaveElementTranslations(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		translations *domain.ElementTranslations,
+	) (*domain.ElementTranslations, error)
+
+	GetElementTranslations(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) (map[string]domain.ElementTranslations, error)
+
+	GetFeedChanges(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		after domain.SyncCursor,
+		limit int,
+	) (*domain.FeedChanges, error)
+
+	GetElementsExpiringBefore(
+		ctx context.Context,
+		elementType domain.ElementType,
+		before time.Time,
+		after *domain.ExpiryCursor,
+		limit int,
+	) ([]domain.ExpiringElement, error)
+
+	ArchiveFeedItem(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		item *feedlib.Item,
+	) error
+
+	ArchiveNudge(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		nudge *feedlib.Nudge,
+	) error
+
+	ClaimExpiryReminder(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		elementType domain.ElementType,
+		elementID string,
+		expiry time.Time,
+	) (bool, error)
+
+	GetFeedElements(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) (*domain.Feed, error)
+
+	ReassignIncomingEvents(
+		ctx context.Context,
+		flavour feedlib.Flavour,
+		fromUID string,
+		toUID string,
+	) (int, error)
+
+	SaveFeedMerge(
+		ctx context.Context,
+		merge *domain.FeedMerge,
+	) error
+
+	GetFeedMerge(
+		ctx context.Context,
+		anonymousUID string,
+		flavour feedlib.Flavour,
+	) (*domain.FeedMerge, error)
+
+	SetThreadMute(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		participantUID string,
+		muted bool,
+	) error
+
+	GetThreadMutes(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+	) ([]string, error)
+
+	SaveChecklist(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		checklist domain.Checklist,
+	) error
+
+	GetChecklist(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+	) (*domain.Checklist, error)
+
+	GetChecklists(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) ([]domain.Checklist, error)
+
+	SaveChannelDeliveries(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		deliveries []domain.ChannelDelivery,
+	) error
+
+	GetChannelDeliveries(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		elementID string,
+	) ([]domain.ChannelDelivery, error)
+
+	SaveNotificationPreferences(
+		ctx context.Context,
+		preferences domain.NotificationPreferences,
+	) error
+
+	GetNotificationPreferences(
+		ctx context.Context,
+		uid string,
+	) (*domain.NotificationPreferences, error)
+
+	SaveDeferredNotification(
+		ctx context.Context,
+		notification domain.DeferredNotification,
+	) error
+
+	GetDueDeferredNotifications(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) ([]domain.DeferredNotification, error)
+
+	DeleteDeferredNotification(
+		ctx context.Context,
+		id string,
+	) error
+
+	SaveDigestSettings(
+		ctx context.Context,
+		settings domain.DigestSettings,
+	) error
+
+	GetDigestSettings(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) (*domain.DigestSettings, error)
+
+	ListDigestSettings(
+		ctx context.Context,
+		frequency domain.DigestFrequency,
+		after *domain.DigestSettings,
+		limit int,
+	) ([]domain.DigestSettings, error)
+
+	QueueInboxCountPush(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		dueAt time.Time,
+	) error
+
+	GetDueInboxCountPushes(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) ([]domain.InboxCountPush, error)
+
+	DeleteInboxCountPush(
+		ctx context.Context,
+		push domain.InboxCountPush,
+	) error
+
+	GetTokenHealth(
+		ctx context.Context,
+		tokens []string,
+	) (map[string]domain.TokenHealth, error)
+
+	SaveTokenHealth(
+		ctx context.Context,
+		health domain.TokenHealth,
+	) error
+
+	SaveFCMRetry(
+		ctx context.Context,
+		retry domain.FCMRetry,
+	) error
+
+	GetDueFCMRetries(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) ([]domain.FCMRetry, error)
+
+	DeleteFCMRetry(
+		ctx context.Context,
+		id string,
+	) error
+
+	SaveFCMDeadLetter(
+		ctx context.Context,
+		letter domain.FCMDeadLetter,
+	) error
+
+	ListFCMDeadLetters(
+		ctx context.Context,
+		limit int,
+	) ([]domain.FCMDeadLetter, error)
+
+	GetFCMDeadLetter(
+		ctx context.Context,
+		id string,
+	) (*domain.FCMDeadLetter, error)
+
+	DeleteFCMDeadLetter(
+		ctx context.Context,
+		id string,
+	) error
+
+	GetNotificationsByMessageID(
+		ctx context.Context,
+		messageID string,
+	) ([]*dto.SavedNotification, error)
+
+	UpdateNotificationStatus(
+		ctx context.Context,
+		messageID string,
+		status domain.NotificationStatus,
+		at time.Time,
+	) error
+
+	RetrieveNotificationsForTokens(
+		ctx context.Context,
+		registrationTokens []string,
+		newerThan time.Time,
+		limit int,
+	) ([]*dto.SavedNotification, error)
+
+	NotificationOpenRates(
+		ctx context.Context,
+		since time.Time,
+		until time.Time,
+	) ([]domain.NotificationOpenRate, error)
+
+	ClaimPubSubMessage(
+		ctx context.Context,
+		message domain.ProcessedMessage,
+	) (*domain.ProcessedMessage, bool, error)
+
+	CompletePubSubMessage(
+		ctx context.Context,
+		topic string,
+		messageID string,
+	) error
+
+	CompletePubSubStep(
+		ctx context.Context,
+		topic string,
+		messageID string,
+		step string,
+	) error
+
+	ReleasePubSubMessage(
+		ctx context.Context,
+		topic string,
+		messageID string,
+	) error
+
+	SetMessageHidden(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		hidden bool,
+	) error
+
+	ReportMessage(
+		ctx context.Context,
+		moderationCase domain.ModerationCase,
+		report domain.MessageReport,
+	) (*domain.ModerationCase, error)
+
+	GetModerationCase(
+		ctx context.Context,
+		caseID string,
+	) (*domain.ModerationCase, error)
+
+	GetModerationCases(
+		ctx context.Context,
+		status domain.ModerationStatus,
+		limit int,
+	) ([]domain.ModerationCase, error)
+
+	UpdateModerationCase(
+		ctx context.Context,
+		moderationCase domain.ModerationCase,
+	) error
+
+	SaveModerationAuditEntry(
+		ctx context.Context,
+		entry domain.ModerationAuditEntry,
+	) error
+
+	GetModerationAudit(
+		ctx context.Context,
+		caseID string,
+	) ([]domain.ModerationAuditEntry, error)
+
 	SaveTwilioResponse(
 		ctx context.Context,
 		data dto.Message,
@@ -264,6 +664,24 @@ type Repository interface {
 		ctx context.Context,
 		data dto.CallbackData,
 	) error
+
+	ClaimIdempotencyKey(
+		ctx context.Context,
+		record domain.IdempotencyRecord,
+	) (*domain.IdempotencyRecord, bool, error)
+
+	CompleteIdempotencyKey(
+		ctx context.Context,
+		scope string,
+		key string,
+		response domain.IdempotentResponse,
+	) error
+
+	ReleaseIdempotencyKey(
+		ctx context.Context,
+		scope string,
+		key string,
+	) error
 }
 
 // DbService is an implementation of the database repository
@@ -319,13 +737,24 @@ func (d *DbService) GetFeedItem(
 	return d.firestore.GetFeedItem(ctx, uid, flavour, itemID)
 }
 
-// SaveFeedItem ...
-func (d *DbService) SaveFeedItem(
+// GetFeedItemStatuses retrieves the statuses of several feed items at once.
+// Items that do not exist are left out.
+func (d *DbService) GetFeedItemStatuses(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-	item *feedlib.Item,
-) (*feedlib.Item, error) {
+	itemIDs []string,
+) (map[string]feedlib.Status, error) {
+	return d.firestore.GetFeedItemStatuses(ctx, uid, flavour, itemIDs)
+}
+
+// SaveFeedItem ...
+func (d *DbService) SaveFeedItem(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	item *feedlib.Item,
+) (*feedlib.Item, error) {
 	return d.firestore.SaveFeedItem(ctx, uid, flavour, item)
 }
 
@@ -452,6 +881,74 @@ func (d *DbService) DeleteMessage(
 	return d.firestore.DeleteMessage(ctx, uid, flavour, itemID, messageID)
 }
 
+// GetThreadMessages retrieves an item's messages with their edits, reactions
+// and attachments
+func (d *DbService) GetThreadMessages(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) ([]domain.ThreadMessage, error) {
+	return d.firestore.GetThreadMessages(ctx, uid, flavour, itemID)
+}
+
+// EditMessage replaces a message's text and keeps the replaced text
+func (d *DbService) EditMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	text string,
+	editedByUID string,
+) (*domain.ThreadMessage, error) {
+	return d.firestore.EditMessage(ctx, uid, flavour, itemID, messageID, text, editedByUID)
+}
+
+// ReactToMessage adds or removes a user's emoji reaction to a message
+func (d *DbService) ReactToMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	emoji string,
+	reactorUID string,
+	reacted bool,
+) error {
+	return d.firestore.ReactToMessage(ctx, uid, flavour, itemID, messageID, emoji, reactorUID, reacted)
+}
+
+// AttachToMessage adds attachments to a message
+func (d *DbService) AttachToMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	attachments []domain.MessageAttachment,
+) error {
+	return d.firestore.AttachToMessage(ctx, uid, flavour, itemID, messageID, attachments)
+}
+
+// SaveUploadOwner records the user that made an upload
+func (d *DbService) SaveUploadOwner(
+	ctx context.Context,
+	uploadID string,
+	uid string,
+) error {
+	return d.firestore.SaveUploadOwner(ctx, uploadID, uid)
+}
+
+// GetUploadOwner returns the user that made an upload, or a blank UID when
+// the upload's owner was not recorded
+func (d *DbService) GetUploadOwner(
+	ctx context.Context,
+	uploadID string,
+) (string, error) {
+	return d.firestore.GetUploadOwner(ctx, uploadID)
+}
+
 // GetMessages retrieves a message
 func (d *DbService) GetMessages(
 	ctx context.Context,
@@ -532,6 +1029,36 @@ func (d *DbService) SaveLabel(
 	return d.firestore.SaveLabel(ctx, uid, flavour, label)
 }
 
+// RemoveLabels removes labels from a feed's list of labels
+func (d *DbService) RemoveLabels(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	labels []string,
+) error {
+	return d.firestore.RemoveLabels(ctx, uid, flavour, labels)
+}
+
+// RelabelItems moves every item that carries a label to another label
+func (d *DbService) RelabelItems(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	label string,
+	newLabel string,
+) (int, error) {
+	return d.firestore.RelabelItems(ctx, uid, flavour, label, newLabel)
+}
+
+// LabelCounts counts the items that carry each of a feed's labels
+func (d *DbService) LabelCounts(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) ([]domain.LabelCount, error) {
+	return d.firestore.LabelCounts(ctx, uid, flavour)
+}
+
 // UnreadPersistentItems ...
 func (d *DbService) UnreadPersistentItems(
 	ctx context.Context,
@@ -560,6 +1087,504 @@ func (d *DbService) GetDefaultNudgeByTitle(
 	return d.firestore.GetDefaultNudgeByTitle(ctx, uid, flavour, title)
 }
 
+// SaveElementTranslations adds or replaces the language variants of a feed
+// element's text
+func (d *DbService) SaveElementTranslations(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	translations *domain.ElementTranslations,
+) (*domain.ElementTranslations, error) {
+	return d.firestore.SaveElementTranslations(ctx, uid, flavour, translations)
+}
+
+// GetElementTranslations retrieves the translations of the elements in a feed
+func (d *DbService) GetElementTranslations(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (map[string]domain.ElementTranslations, error) {
+	return d.firestore.GetElementTranslations(ctx, uid, flavour)
+}
+
+// GetFeedChanges retrieves the elements of a feed that changed after the
+// supplied position in its change log
+func (d *DbService) GetFeedChanges(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	after domain.SyncCursor,
+	limit int,
+) (*domain.FeedChanges, error) {
+	return d.firestore.GetFeedChanges(ctx, uid, flavour, after, limit)
+}
+
+// GetElementsExpiringBefore retrieves a page of the items or nudges, across
+// all feeds, whose expiry is before the supplied time
+func (d *DbService) GetElementsExpiringBefore(
+	ctx context.Context,
+	elementType domain.ElementType,
+	before time.Time,
+	after *domain.ExpiryCursor,
+	limit int,
+) ([]domain.ExpiringElement, error) {
+	return d.firestore.GetElementsExpiringBefore(ctx, elementType, before, after, limit)
+}
+
+// ArchiveFeedItem moves an item out of a user's feed and into the archive
+func (d *DbService) ArchiveFeedItem(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	item *feedlib.Item,
+) error {
+	return d.firestore.ArchiveFeedItem(ctx, uid, flavour, item)
+}
+
+// ArchiveNudge moves a nudge out of a user's feed and into the archive
+func (d *DbService) ArchiveNudge(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	nudge *feedlib.Nudge,
+) error {
+	return d.firestore.ArchiveNudge(ctx, uid, flavour, nudge)
+}
+
+// ClaimExpiryReminder records that a "due soon" reminder is being sent for an
+// element's expiry. It returns false if one was already sent.
+func (d *DbService) ClaimExpiryReminder(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	elementType domain.ElementType,
+	elementID string,
+	expiry time.Time,
+) (bool, error) {
+	return d.firestore.ClaimExpiryReminder(ctx, uid, flavour, elementType, elementID, expiry)
+}
+
+// GetFeedElements retrieves every action, nudge and item in a feed
+func (d *DbService) GetFeedElements(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (*domain.Feed, error) {
+	return d.firestore.GetFeedElements(ctx, uid, flavour)
+}
+
+// ReassignIncomingEvents moves a user's incoming events to another user
+func (d *DbService) ReassignIncomingEvents(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	fromUID string,
+	toUID string,
+) (int, error) {
+	return d.firestore.ReassignIncomingEvents(ctx, flavour, fromUID, toUID)
+}
+
+// SaveFeedMerge records that an anonymous feed was merged
+func (d *DbService) SaveFeedMerge(
+	ctx context.Context,
+	merge *domain.FeedMerge,
+) error {
+	return d.firestore.SaveFeedMerge(ctx, merge)
+}
+
+// GetFeedMerge retrieves the record of an anonymous feed's merge
+func (d *DbService) GetFeedMerge(
+	ctx context.Context,
+	anonymousUID string,
+	flavour feedlib.Flavour,
+) (*domain.FeedMerge, error) {
+	return d.firestore.GetFeedMerge(ctx, anonymousUID, flavour)
+}
+
+// SetThreadMute mutes or unmutes an item's conversation for a participant
+func (d *DbService) SetThreadMute(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	participantUID string,
+	muted bool,
+) error {
+	return d.firestore.SetThreadMute(ctx, uid, flavour, itemID, participantUID, muted)
+}
+
+// GetThreadMutes retrieves the participants that muted an item's conversation
+func (d *DbService) GetThreadMutes(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) ([]string, error) {
+	return d.firestore.GetThreadMutes(ctx, uid, flavour, itemID)
+}
+
+// SaveChecklist saves the checklist of a feed item
+func (d *DbService) SaveChecklist(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	checklist domain.Checklist,
+) error {
+	return d.firestore.SaveChecklist(ctx, uid, flavour, checklist)
+}
+
+// GetChecklist retrieves the checklist of a feed item, if it has one
+func (d *DbService) GetChecklist(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) (*domain.Checklist, error) {
+	return d.firestore.GetChecklist(ctx, uid, flavour, itemID)
+}
+
+// GetChecklists retrieves the checklists of every item in a feed
+func (d *DbService) GetChecklists(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) ([]domain.Checklist, error) {
+	return d.firestore.GetChecklists(ctx, uid, flavour)
+}
+
+// SaveChannelDeliveries records the outcome of sending a feed element's
+// notification over its channels
+func (d *DbService) SaveChannelDeliveries(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	deliveries []domain.ChannelDelivery,
+) error {
+	return d.firestore.SaveChannelDeliveries(ctx, uid, flavour, deliveries)
+}
+
+// GetChannelDeliveries retrieves the recorded notification deliveries of a
+// feed element
+func (d *DbService) GetChannelDeliveries(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	elementID string,
+) ([]domain.ChannelDelivery, error) {
+	return d.firestore.GetChannelDeliveries(ctx, uid, flavour, elementID)
+}
+
+// SaveNotificationPreferences saves a user's notification preferences
+func (d *DbService) SaveNotificationPreferences(
+	ctx context.Context,
+	preferences domain.NotificationPreferences,
+) error {
+	return d.firestore.SaveNotificationPreferences(ctx, preferences)
+}
+
+// GetNotificationPreferences retrieves a user's notification preferences
+func (d *DbService) GetNotificationPreferences(
+	ctx context.Context,
+	uid string,
+) (*domain.NotificationPreferences, error) {
+	return d.firestore.GetNotificationPreferences(ctx, uid)
+}
+
+// SaveDeferredNotification holds a notification back for later delivery
+func (d *DbService) SaveDeferredNotification(
+	ctx context.Context,
+	notification domain.DeferredNotification,
+) error {
+	return d.firestore.SaveDeferredNotification(ctx, notification)
+}
+
+// GetDueDeferredNotifications retrieves the deferred notifications that are
+// due for delivery
+func (d *DbService) GetDueDeferredNotifications(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]domain.DeferredNotification, error) {
+	return d.firestore.GetDueDeferredNotifications(ctx, before, limit)
+}
+
+// DeleteDeferredNotification removes a deferred notification
+func (d *DbService) DeleteDeferredNotification(
+	ctx context.Context,
+	id string,
+) error {
+	return d.firestore.DeleteDeferredNotification(ctx, id)
+}
+
+// SaveDigestSettings saves a user's digest settings for one of their feeds
+func (d *DbService) SaveDigestSettings(
+	ctx context.Context,
+	settings domain.DigestSettings,
+) error {
+	return d.firestore.SaveDigestSettings(ctx, settings)
+}
+
+// GetDigestSettings retrieves a user's digest settings for one of their feeds
+func (d *DbService) GetDigestSettings(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (*domain.DigestSettings, error) {
+	return d.firestore.GetDigestSettings(ctx, uid, flavour)
+}
+
+// ListDigestSettings retrieves a page of the digest settings of the feeds that
+// get digests at a frequency, starting after the supplied settings
+func (d *DbService) ListDigestSettings(
+	ctx context.Context,
+	frequency domain.DigestFrequency,
+	after *domain.DigestSettings,
+	limit int,
+) ([]domain.DigestSettings, error) {
+	return d.firestore.ListDigestSettings(ctx, frequency, after, limit)
+}
+
+// QueueInboxCountPush records a change to a feed's inbox, collapsing it into
+// the pending push of the inbox count if there is one
+func (d *DbService) QueueInboxCountPush(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	dueAt time.Time,
+) error {
+	return d.firestore.QueueInboxCountPush(ctx, uid, flavour, dueAt)
+}
+
+// GetDueInboxCountPushes retrieves the inbox count pushes that are due
+// before a time, the earliest first
+func (d *DbService) GetDueInboxCountPushes(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]domain.InboxCountPush, error) {
+	return d.firestore.GetDueInboxCountPushes(ctx, before, limit)
+}
+
+// DeleteInboxCountPush removes an inbox count push once it has gone out
+func (d *DbService) DeleteInboxCountPush(
+	ctx context.Context,
+	push domain.InboxCountPush,
+) error {
+	return d.firestore.DeleteInboxCountPush(ctx, push)
+}
+
+// GetTokenHealth retrieves the delivery records of registration tokens
+func (d *DbService) GetTokenHealth(
+	ctx context.Context,
+	tokens []string,
+) (map[string]domain.TokenHealth, error) {
+	return d.firestore.GetTokenHealth(ctx, tokens)
+}
+
+// SaveTokenHealth saves the delivery record of a registration token
+func (d *DbService) SaveTokenHealth(
+	ctx context.Context,
+	health domain.TokenHealth,
+) error {
+	return d.firestore.SaveTokenHealth(ctx, health)
+}
+
+// SaveFCMRetry schedules an FCM message to be sent again
+func (d *DbService) SaveFCMRetry(
+	ctx context.Context,
+	retry domain.FCMRetry,
+) error {
+	return d.firestore.SaveFCMRetry(ctx, retry)
+}
+
+// GetDueFCMRetries retrieves the FCM retries that are due
+func (d *DbService) GetDueFCMRetries(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]domain.FCMRetry, error) {
+	return d.firestore.GetDueFCMRetries(ctx, before, limit)
+}
+
+// DeleteFCMRetry removes an FCM retry
+func (d *DbService) DeleteFCMRetry(
+	ctx context.Context,
+	id string,
+) error {
+	return d.firestore.DeleteFCMRetry(ctx, id)
+}
+
+// SaveFCMDeadLetter keeps an FCM message that was given up on
+func (d *DbService) SaveFCMDeadLetter(
+	ctx context.Context,
+	letter domain.FCMDeadLetter,
+) error {
+	return d.firestore.SaveFCMDeadLetter(ctx, letter)
+}
+
+// ListFCMDeadLetters retrieves the latest FCM dead letters
+func (d *DbService) ListFCMDeadLetters(
+	ctx context.Context,
+	limit int,
+) ([]domain.FCMDeadLetter, error) {
+	return d.firestore.ListFCMDeadLetters(ctx, limit)
+}
+
+// GetFCMDeadLetter retrieves an FCM dead letter
+func (d *DbService) GetFCMDeadLetter(
+	ctx context.Context,
+	id string,
+) (*domain.FCMDeadLetter, error) {
+	return d.firestore.GetFCMDeadLetter(ctx, id)
+}
+
+// DeleteFCMDeadLetter removes an FCM dead letter
+func (d *DbService) DeleteFCMDeadLetter(
+	ctx context.Context,
+	id string,
+) error {
+	return d.firestore.DeleteFCMDeadLetter(ctx, id)
+}
+
+// GetNotificationsByMessageID retrieves the saved notifications with an FCM
+// message ID
+func (d *DbService) GetNotificationsByMessageID(
+	ctx context.Context,
+	messageID string,
+) ([]*dto.SavedNotification, error) {
+	return d.firestore.GetNotificationsByMessageID(ctx, messageID)
+}
+
+// UpdateNotificationStatus records an app's acknowledgement of a notification
+func (d *DbService) UpdateNotificationStatus(
+	ctx context.Context,
+	messageID string,
+	status domain.NotificationStatus,
+	at time.Time,
+) error {
+	return d.firestore.UpdateNotificationStatus(ctx, messageID, status, at)
+}
+
+// RetrieveNotificationsForTokens retrieves the latest notifications sent to
+// any of the registration tokens
+func (d *DbService) RetrieveNotificationsForTokens(
+	ctx context.Context,
+	registrationTokens []string,
+	newerThan time.Time,
+	limit int,
+) ([]*dto.SavedNotification, error) {
+	return d.firestore.RetrieveNotificationsForTokens(ctx, registrationTokens, newerThan, limit)
+}
+
+// NotificationOpenRates summarizes the notifications sent in a period by
+// sender type
+func (d *DbService) NotificationOpenRates(
+	ctx context.Context,
+	since time.Time,
+	until time.Time,
+) ([]domain.NotificationOpenRate, error) {
+	return d.firestore.NotificationOpenRates(ctx, since, until)
+}
+
+// ClaimPubSubMessage records that a Pub/Sub message is being processed
+func (d *DbService) ClaimPubSubMessage(
+	ctx context.Context,
+	message domain.ProcessedMessage,
+) (*domain.ProcessedMessage, bool, error) {
+	return d.firestore.ClaimPubSubMessage(ctx, message)
+}
+
+// CompletePubSubMessage records that a Pub/Sub message was processed
+func (d *DbService) CompletePubSubMessage(
+	ctx context.Context,
+	topic string,
+	messageID string,
+) error {
+	return d.firestore.CompletePubSubMessage(ctx, topic, messageID)
+}
+
+// CompletePubSubStep records that a side effect of processing a Pub/Sub
+// message is done
+func (d *DbService) CompletePubSubStep(
+	ctx context.Context,
+	topic string,
+	messageID string,
+	step string,
+) error {
+	return d.firestore.CompletePubSubStep(ctx, topic, messageID, step)
+}
+
+// ReleasePubSubMessage ends the lease of a Pub/Sub message that failed to
+// process so that its redelivery is processed
+func (d *DbService) ReleasePubSubMessage(
+	ctx context.Context,
+	topic string,
+	messageID string,
+) error {
+	return d.firestore.ReleasePubSubMessage(ctx, topic, messageID)
+}
+
+// SetMessageHidden hides a message from an item's conversation or restores it
+func (d *DbService) SetMessageHidden(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	hidden bool,
+) error {
+	return d.firestore.SetMessageHidden(ctx, uid, flavour, itemID, messageID, hidden)
+}
+
+// ReportMessage adds a report to the moderation case of a message
+func (d *DbService) ReportMessage(
+	ctx context.Context,
+	moderationCase domain.ModerationCase,
+	report domain.MessageReport,
+) (*domain.ModerationCase, error) {
+	return d.firestore.ReportMessage(ctx, moderationCase, report)
+}
+
+// GetModerationCase retrieves a moderation case
+func (d *DbService) GetModerationCase(
+	ctx context.Context,
+	caseID string,
+) (*domain.ModerationCase, error) {
+	return d.firestore.GetModerationCase(ctx, caseID)
+}
+
+// GetModerationCases retrieves the moderation cases with a status
+func (d *DbService) GetModerationCases(
+	ctx context.Context,
+	status domain.ModerationStatus,
+	limit int,
+) ([]domain.ModerationCase, error) {
+	return d.firestore.GetModerationCases(ctx, status, limit)
+}
+
+// UpdateModerationCase saves a decision on a moderation case
+func (d *DbService) UpdateModerationCase(
+	ctx context.Context,
+	moderationCase domain.ModerationCase,
+) error {
+	return d.firestore.UpdateModerationCase(ctx, moderationCase)
+}
+
+// SaveModerationAuditEntry records a moderation decision
+func (d *DbService) SaveModerationAuditEntry(
+	ctx context.Context,
+	entry domain.ModerationAuditEntry,
+) error {
+	return d.firestore.SaveModerationAuditEntry(ctx, entry)
+}
+
+// GetModerationAudit retrieves the decisions on a moderation case
+func (d *DbService) GetModerationAudit(
+	ctx context.Context,
+	caseID string,
+) ([]domain.ModerationAuditEntry, error) {
+	return d.firestore.GetModerationAudit(ctx, caseID)
+}
+
 // SaveTwilioResponse saves the callback data for future analysis
 func (d *DbService) SaveTwilioResponse(
 	ctx context.Context,
@@ -621,3 +1646,31 @@ func (d *DbService) SaveTwilioVideoCallbackStatus(
 ) error {
 	return d.firestore.SaveTwilioVideoCallbackStatus(ctx, data)
 }
+
+// ClaimIdempotencyKey records the first use of an idempotency key
+func (d *DbService) ClaimIdempotencyKey(
+	ctx context.Context,
+	record domain.IdempotencyRecord,
+) (*domain.IdempotencyRecord, bool, error) {
+	return d.firestore.ClaimIdempotencyKey(ctx, record)
+}
+
+// CompleteIdempotencyKey stores the response to the first request made with
+// an idempotency key
+func (d *DbService) CompleteIdempotencyKey(
+	ctx context.Context,
+	scope string,
+	key string,
+	response domain.IdempotentResponse,
+) error {
+	return d.firestore.CompleteIdempotencyKey(ctx, scope, key, response)
+}
+
+// ReleaseIdempotencyKey forgets an idempotency key whose request failed
+func (d *DbService) ReleaseIdempotencyKey(
+	ctx context.Context,
+	scope string,
+	key string,
+) error {
+	return d.firestore.ReleaseIdempotencyKey(ctx, scope, key)
+}
@@ -38,6 +38,13 @@ type FakeInfrastructure struct {
 		itemID string,
 	) (*feedlib.Item, error)
 
+	GetFeedItemStatusesFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemIDs []string,
+	) (map[string]feedlib.Status, error)
+
 	// saving a new feed item
 	SaveFeedItemFn func(
 		ctx context.Context,
@@ -145,6 +152,54 @@ type FakeInfrastructure struct {
 		messageID string,
 	) error
 
+	GetThreadMessagesFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+	) ([]domain.ThreadMessage, error)
+
+	EditMessageFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		text string,
+		editedByUID string,
+	) (*domain.ThreadMessage, error)
+
+	ReactToMessageFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		emoji string,
+		reactorUID string,
+		reacted bool,
+	) error
+
+	AttachToMessageFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		attachments []domain.MessageAttachment,
+	) error
+
+	SaveUploadOwnerFn func(
+		ctx context.Context,
+		uploadID string,
+		uid string,
+	) error
+
+	GetUploadOwnerFn func(
+		ctx context.Context,
+		uploadID string,
+	) (string, error)
+
 	// GetMessages retrieves a message
 	GetMessagesFn func(
 		ctx context.Context,
@@ -202,6 +257,27 @@ type FakeInfrastructure struct {
 		label string,
 	) error
 
+	RemoveLabelsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		labels []string,
+	) error
+
+	RelabelItemsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		label string,
+		newLabel string,
+	) (int, error)
+
+	LabelCountsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) ([]domain.LabelCount, error)
+
 	UnreadPersistentItemsFn func(
 		ctx context.Context,
 		uid string,
@@ -221,525 +297,1459 @@ type FakeInfrastructure struct {
 		title string,
 	) (*feedlib.Nudge, error)
 
-	SaveTwilioResponseFn func(
+	SaveElementTranslationsFn func(
 		ctx context.Context,
-		data dto.Message,
-	) error
+		uid string,
+		flavour feedlib.Flavour,
+		translations *domain.ElementTranslations,
+	) (*domain.ElementTranslations, error)
 
-	SaveNotificationFn func(
+	GetElementTranslationsFn func(
 		ctx context.Context,
-		firestoreClient *firestore.Client,
-		notification dto.SavedNotification,
-	) error
+		uid string,
+		flavour feedlib.Flavour,
+	) (map[string]domain.ElementTranslations, error)
 
-	RetrieveNotificationFn func(
+	GetFeedChangesFn func(
 		ctx context.Context,
-		firestoreClient *firestore.Client,
-		registrationToken string,
-		newerThan time.Time,
+		uid string,
+		flavour feedlib.Flavour,
+		after domain.SyncCursor,
 		limit int,
-	) ([]*dto.SavedNotification, error)
+	) (*domain.FeedChanges, error)
 
-	SaveNPSResponseFn func(
+	GetElementsExpiringBeforeFn func(
 		ctx context.Context,
-		response *dto.NPSResponse,
-	) error
+		elementType domain.ElementType,
+		before time.Time,
+		after *domain.ExpiryCursor,
+		limit int,
+	) ([]domain.ExpiringElement, error)
 
-	SaveSurveyFeedbackResponseFn func(
+	ArchiveFeedItemFn func(
 		ctx context.Context,
-		response *domain.SurveyFeedbackResponse,
+		uid string,
+		flavour feedlib.Flavour,
+		item *feedlib.Item,
 	) error
 
-	SaveTwilioVideoCallbackStatusFn func(
+	ArchiveNudgeFn func(
 		ctx context.Context,
-		data dto.CallbackData,
+		uid string,
+		flavour feedlib.Flavour,
+		nudge *feedlib.Nudge,
 	) error
 
-	SendNotificationFn func(
+	ClaimExpiryReminderFn func(
 		ctx context.Context,
-		registrationTokens []string,
-		data map[string]string,
-		notification *firebasetools.FirebaseSimpleNotificationInput,
-		android *firebasetools.FirebaseAndroidConfigInput,
-		ios *firebasetools.FirebaseAPNSConfigInput,
-		web *firebasetools.FirebaseWebpushConfigInput,
+		uid string,
+		flavour feedlib.Flavour,
+		elementType domain.ElementType,
+		elementID string,
+		expiry time.Time,
 	) (bool, error)
 
-	NotificationsFn func(
+	GetFeedElementsFn func(
 		ctx context.Context,
-		registrationToken string,
-		newerThan time.Time,
-		limit int,
-	) ([]*dto.SavedNotification, error)
+		uid string,
+		flavour feedlib.Flavour,
+	) (*domain.Feed, error)
 
-	SendFCMByPhoneOrEmailFn func(
+	ReassignIncomingEventsFn func(
 		ctx context.Context,
-		phoneNumber *string,
-		email *string,
-		data map[string]interface{},
-		notification firebasetools.FirebaseSimpleNotificationInput,
-		android *firebasetools.FirebaseAndroidConfigInput,
-		ios *firebasetools.FirebaseAPNSConfigInput,
-		web *firebasetools.FirebaseWebpushConfigInput,
-	) (bool, error)
-
-	GetFeedContentFn    func(ctx context.Context, flavour feedlib.Flavour) ([]*domain.GhostCMSPost, error)
-	GetFaqsContentFn    func(ctx context.Context, flavour feedlib.Flavour) ([]*domain.GhostCMSPost, error)
-	GetLibraryContentFn func(ctx context.Context) ([]*domain.GhostCMSPost, error)
+		flavour feedlib.Flavour,
+		fromUID string,
+		toUID string,
+	) (int, error)
 
-	SendInBlueFn  func(ctx context.Context, subject, text string, to ...string) (string, string, error)
-	SendMailgunFn func(
+	SaveFeedMergeFn func(
 		ctx context.Context,
-		subject, text string,
-		body *string,
-		to ...string,
-	) (string, string, error)
-	SendEmailFn func(
+		merge *domain.FeedMerge,
+	) error
+
+	GetFeedMergeFn func(
 		ctx context.Context,
-		subject, text string,
-		body *string,
-		to ...string,
-	) (string, string, error)
-	SimpleEmailFn func(
+		anonymousUID string,
+		flavour feedlib.Flavour,
+	) (*domain.FeedMerge, error)
+
+	SetThreadMuteFn func(
 		ctx context.Context,
-		subject, text string,
-		body *string,
-		to ...string,
-	) (string, error)
-	SaveOutgoingEmailsFn          func(ctx context.Context, payload *dto.OutgoingEmailsLog) error
-	UpdateMailgunDeliveryStatusFn func(
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		participantUID string,
+		muted bool,
+	) error
+
+	GetThreadMutesFn func(
 		ctx context.Context,
-		payload *dto.MailgunEvent,
-	) (*dto.OutgoingEmailsLog, error)
-	GenerateEmailTemplateFn func(name string, templateName string) string
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+	) ([]string, error)
 
-	NotifyFn func(
+	SaveChecklistFn func(
 		ctx context.Context,
-		topicID string,
 		uid string,
 		flavour feedlib.Flavour,
-		payload feedlib.Element,
-		metadata map[string]interface{},
+		checklist domain.Checklist,
 	) error
 
-	// Ask the notification service about the topics that it knows about
-	TopicIDsFn func() []string
+	GetChecklistFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+	) (*domain.Checklist, error)
 
-	SubscriptionIDsFn func() map[string]string
+	GetChecklistsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) ([]domain.Checklist, error)
 
-	ReverseSubscriptionIDsFn func() map[string]string
+	SaveChannelDeliveriesFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		deliveries []domain.ChannelDelivery,
+	) error
 
-	GetEmailAddressesFn            func(ctx context.Context, uids onboarding.UserUIDs) (map[string][]string, error)
-	GetPhoneNumbersFn              func(ctx context.Context, uids onboarding.UserUIDs) (map[string][]string, error)
-	GetDeviceTokensFn              func(ctx context.Context, uid onboarding.UserUIDs) (map[string][]string, error)
-	GetUserProfileFn               func(ctx context.Context, uid string) (*profileutils.UserProfile, error)
-	GetUserProfileByPhoneOrEmailFn func(ctx context.Context, payload *dto.RetrieveUserProfileInput) (*profileutils.UserProfile, error)
+	GetChannelDeliveriesFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		elementID string,
+	) ([]domain.ChannelDelivery, error)
 
-	GenerateAndSendOTPFn   func(ctx context.Context, msisdn string, appID *string) (string, error)
-	SendOTPToEmailFn       func(ctx context.Context, msisdn, email *string, appID *string) (string, error)
-	SaveOTPToFirestoreFn   func(otp dto.OTP) error
-	VerifyOtpFn            func(ctx context.Context, msisdn, verificationCode *string) (bool, error)
-	VerifyEmailOtpFn       func(ctx context.Context, email, verificationCode *string) (bool, error)
-	GenerateRetryOTPFn     func(ctx context.Context, msisdn *string, retryStep int, appID *string) (string, error)
-	EmailVerificationOtpFn func(ctx context.Context, email *string) (string, error)
-	GenerateOTPFn          func(ctx context.Context) (string, error)
+	SaveNotificationPreferencesFn func(
+		ctx context.Context,
+		preferences domain.NotificationPreferences,
+	) error
 
-	SendToManyFn func(
+	GetNotificationPreferencesFn func(
 		ctx context.Context,
-		message string,
-		to []string,
-		from enumutils.SenderID,
-	) (*dto.SendMessageResponse, error)
+		uid string,
+	) (*domain.NotificationPreferences, error)
 
-	SendFn func(
+	SaveDeferredNotificationFn func(
 		ctx context.Context,
-		to, message string,
-		from enumutils.SenderID,
-	) (*dto.SendMessageResponse, error)
+		notification domain.DeferredNotification,
+	) error
 
-	RecordNPSResponseFn func(ctx context.Context, input dto.NPSInput) (bool, error)
+	GetDueDeferredNotificationsFn func(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) ([]domain.DeferredNotification, error)
 
-	RecordSurveyFeedbackResponseFn func(ctx context.Context, input domain.SurveyInput) (bool, error)
+	DeleteDeferredNotificationFn func(
+		ctx context.Context,
+		id string,
+	) error
 
-	RoomFn func(ctx context.Context) (*dto.Room, error)
+	SaveDigestSettingsFn func(
+		ctx context.Context,
+		settings domain.DigestSettings,
+	) error
 
-	TwilioAccessTokenFn func(ctx context.Context) (*dto.AccessToken, error)
+	GetDigestSettingsFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) (*domain.DigestSettings, error)
 
-	SendSMSFn func(ctx context.Context, to string, msg string) error
+	ListDigestSettingsFn func(
+		ctx context.Context,
+		frequency domain.DigestFrequency,
+		after *domain.DigestSettings,
+		limit int,
+	) ([]domain.DigestSettings, error)
 
-	UploadFn func(
+	QueueInboxCountPushFn func(
 		ctx context.Context,
-		inp profileutils.UploadInput,
-	) (*profileutils.Upload, error)
+		uid string,
+		flavour feedlib.Flavour,
+		dueAt time.Time,
+	) error
 
-	FindUploadByIDFn func(
+	GetDueInboxCountPushesFn func(
 		ctx context.Context,
-		id string,
-	) (*profileutils.Upload, error)
+		before time.Time,
+		limit int,
+	) ([]domain.InboxCountPush, error)
 
-	PhoneNumberVerificationCodeFn func(
+	DeleteInboxCountPushFn func(
 		ctx context.Context,
-		to string,
-		code string,
-		marketingMessage string,
-	) (bool, error)
+		push domain.InboxCountPush,
+	) error
 
-	WellnessCardActivationDependantFn func(
+	GetTokenHealthFn func(
 		ctx context.Context,
-		to string,
-		memberName string,
-		cardName string,
-		marketingMessage string,
-	) (bool, error)
+		tokens []string,
+	) (map[string]domain.TokenHealth, error)
 
-	WellnessCardActivationPrincipalFn func(
+	SaveTokenHealthFn func(
 		ctx context.Context,
-		to string,
-		memberName string,
-		cardName string,
-		minorAgeThreshold string,
-		marketingMessage string,
-	) (bool, error)
+		health domain.TokenHealth,
+	) error
 
-	BillNotificationFn func(
+	SaveFCMRetryFn func(
 		ctx context.Context,
-		to string,
-		productName string,
-		billingPeriod string,
-		billAmount string,
-		paymentInstruction string,
-		marketingMessage string,
-	) (bool, error)
+		retry domain.FCMRetry,
+	) error
 
-	VirtualCardsFn func(
+	GetDueFCMRetriesFn func(
 		ctx context.Context,
-		to string,
-		wellnessCardFamily string,
+		before time.Time,
+		limit int,
+	) ([]domain.FCMRetry, error)
+
+	DeleteFCMRetryFn func(
+		ctx context.Context,
+		id string,
+	) error
+
+	SaveFCMDeadLetterFn func(
+		ctx context.Context,
+		letter domain.FCMDeadLetter,
+	) error
+
+	ListFCMDeadLettersFn func(
+		ctx context.Context,
+		limit int,
+	) ([]domain.FCMDeadLetter, error)
+
+	GetFCMDeadLetterFn func(
+		ctx context.Context,
+		id string,
+	) (*domain.FCMDeadLetter, error)
+
+	DeleteFCMDeadLetterFn func(
+		ctx context.Context,
+		id string,
+	) error
+
+	GetNotificationsByMessageIDFn func(
+		ctx context.Context,
+		messageID string,
+	) ([]*dto.SavedNotification, error)
+
+	UpdateNotificationStatusFn func(
+		ctx context.Context,
+		messageID string,
+		status domain.NotificationStatus,
+		at time.Time,
+	) error
+
+	RetrieveNotificationsForTokensFn func(
+		ctx context.Context,
+		registrationTokens []string,
+		newerThan time.Time,
+		limit int,
+	) ([]*dto.SavedNotification, error)
+
+	NotificationOpenRatesFn func(
+		ctx context.Context,
+		since time.Time,
+		until time.Time,
+	) ([]domain.NotificationOpenRate, error)
+
+	ClaimPubSubMessageFn func(
+		ctx context.Context,
+		message domain.ProcessedMessage,
+	) (*domain.ProcessedMessage, bool, error)
+
+	CompletePubSubMessageFn func(
+		ctx context.Context,
+		topic string,
+		messageID string,
+	) error
+
+	CompletePubSubStepFn func(
+		ctx context.Context,
+		topic string,
+		messageID string,
+		step string,
+	) error
+
+	ReleasePubSubMessageFn func(
+		ctx context.Context,
+		topic string,
+		messageID string,
+	) error
+
+	SetMessageHiddenFn func(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		hidden bool,
+	) error
+
+	ReportMessageFn func(
+		ctx context.Context,
+		moderationCase domain.ModerationCase,
+		report domain.MessageReport,
+	) (*domain.ModerationCase, error)
+
+	GetModerationCaseFn func(
+		ctx context.Context,
+		caseID string,
+	) (*domain.ModerationCase, error)
+
+	GetModerationCasesFn func(
+		ctx context.Context,
+		status domain.ModerationStatus,
+		limit int,
+	) ([]domain.ModerationCase, error)
+
+	UpdateModerationCaseFn func(
+		ctx context.Context,
+		moderationCase domain.ModerationCase,
+	) error
+
+	SaveModerationAuditEntryFn func(
+		ctx context.Context,
+		entry domain.ModerationAuditEntry,
+	) error
+
+	GetModerationAuditFn func(
+		ctx context.Context,
+		caseID string,
+	) ([]domain.ModerationAuditEntry, error)
+
+	SaveTwilioResponseFn func(
+		ctx context.Context,
+		data dto.Message,
+	) error
+
+	SaveNotificationFn func(
+		ctx context.Context,
+		firestoreClient *firestore.Client,
+		notification dto.SavedNotification,
+	) error
+
+	RetrieveNotificationFn func(
+		ctx context.Context,
+		firestoreClient *firestore.Client,
+		registrationToken string,
+		newerThan time.Time,
+		limit int,
+	) ([]*dto.SavedNotification, error)
+
+	SaveNPSResponseFn func(
+		ctx context.Context,
+		response *dto.NPSResponse,
+	) error
+
+	SaveSurveyFeedbackResponseFn func(
+		ctx context.Context,
+		response *domain.SurveyFeedbackResponse,
+	) error
+
+	SaveTwilioVideoCallbackStatusFn func(
+		ctx context.Context,
+		data dto.CallbackData,
+	) error
+
+	ClaimIdempotencyKeyFn func(
+		ctx context.Context,
+		record domain.IdempotencyRecord,
+	) (*domain.IdempotencyRecord, bool, error)
+
+	CompleteIdempotencyKeyFn func(
+		ctx context.Context,
+		scope string,
+		key string,
+		response domain.IdempotentResponse,
+	) error
+
+	ReleaseIdempotencyKeyFn func(
+		ctx context.Context,
+		scope string,
+		key string,
+	) error
+
+	SendNotificationFn func(
+		ctx context.Context,
+		registrationTokens []string,
+		data map[string]string,
+		notification *firebasetools.FirebaseSimpleNotificationInput,
+		android *firebasetools.FirebaseAndroidConfigInput,
+		ios *firebasetools.FirebaseAPNSConfigInput,
+		web *firebasetools.FirebaseWebpushConfigInput,
+	) (bool, error)
+
+	NotificationsFn func(
+		ctx context.Context,
+		registrationToken string,
+		newerThan time.Time,
+		limit int,
+	) ([]*dto.SavedNotification, error)
+
+	SendFCMByPhoneOrEmailFn func(
+		ctx context.Context,
+		phoneNumber *string,
+		email *string,
+		data map[string]interface{},
+		notification firebasetools.FirebaseSimpleNotificationInput,
+		android *firebasetools.FirebaseAndroidConfigInput,
+		ios *firebasetools.FirebaseAPNSConfigInput,
+		web *firebasetools.FirebaseWebpushConfigInput,
+	) (bool, error)
+
+	GetFeedContentFn    func(ctx context.Context, flavour feedlib.Flavour) ([]*domain.GhostCMSPost, error)
+	GetFaqsContentFn    func(ctx context.Context, flavour feedlib.Flavour) ([]*domain.GhostCMSPost, error)
+	GetLibraryContentFn func(ctx context.Context) ([]*domain.GhostCMSPost, error)
+
+	SendInBlueFn  func(ctx context.Context, subject, text string, to ...string) (string, string, error)
+	SendMailgunFn func(
+		ctx context.Context,
+		subject, text string,
+		body *string,
+		to ...string,
+	) (string, string, error)
+	SendEmailFn func(
+		ctx context.Context,
+		subject, text string,
+		body *string,
+		to ...string,
+	) (string, string, error)
+	SimpleEmailFn func(
+		ctx context.Context,
+		subject, text string,
+		body *string,
+		to ...string,
+	) (string, error)
+	SaveOutgoingEmailsFn          func(ctx context.Context, payload *dto.OutgoingEmailsLog) error
+	UpdateMailgunDeliveryStatusFn func(
+		ctx context.Context,
+		payload *dto.MailgunEvent,
+	) (*dto.OutgoingEmailsLog, error)
+	GenerateEmailTemplateFn func(name string, templateName string) string
+
+	NotifyFn func(
+		ctx context.Context,
+		topicID string,
+		uid string,
+		flavour feedlib.Flavour,
+		payload feedlib.Element,
+		metadata map[string]interface{},
+	) error
+
+	// Ask the notification service about the topics that it knows about
+	TopicIDsFn func() []string
+
+	SubscriptionIDsFn func() map[string]string
+
+	ReverseSubscriptionIDsFn func() map[string]string
+
+	GetEmailAddressesFn            func(ctx context.Context, uids onboarding.UserUIDs) (map[string][]string, error)
+	GetPhoneNumbersFn              func(ctx context.Context, uids onboarding.UserUIDs) (map[string][]string, error)
+	GetDeviceTokensFn              func(ctx context.Context, uid onboarding.UserUIDs) (map[string][]string, error)
+	GetUserProfileFn               func(ctx context.Context, uid string) (*profileutils.UserProfile, error)
+	GetUserProfileByPhoneOrEmailFn func(ctx context.Context, payload *dto.RetrieveUserProfileInput) (*profileutils.UserProfile, error)
+	GetPreferredLanguageFn         func(ctx context.Context, uid string) (string, error)
+	IsOptedOutFn                   func(ctx context.Context, phoneNumber string) (bool, error)
+	PhonesWithoutOptOutFn          func(ctx context.Context, phones []string) ([]string, error)
+
+	GenerateAndSendOTPFn   func(ctx context.Context, msisdn string, appID *string) (string, error)
+	SendOTPToEmailFn       func(ctx context.Context, msisdn, email *string, appID *string) (string, error)
+	SaveOTPToFirestoreFn   func(otp dto.OTP) error
+	VerifyOtpFn            func(ctx context.Context, msisdn, verificationCode *string) (bool, error)
+	VerifyEmailOtpFn       func(ctx context.Context, email, verificationCode *string) (bool, error)
+	GenerateRetryOTPFn     func(ctx context.Context, msisdn *string, retryStep int, appID *string) (string, error)
+	EmailVerificationOtpFn func(ctx context.Context, email *string) (string, error)
+	GenerateOTPFn          func(ctx context.Context) (string, error)
+
+	SendToManyFn func(
+		ctx context.Context,
+		message string,
+		to []string,
+		from enumutils.SenderID,
+	) (*dto.SendMessageResponse, error)
+
+	SendFn func(
+		ctx context.Context,
+		to, message string,
+		from enumutils.SenderID,
+	) (*dto.SendMessageResponse, error)
+
+	RecordNPSResponseFn func(ctx context.Context, input dto.NPSInput) (bool, error)
+
+	RecordSurveyFeedbackResponseFn func(ctx context.Context, input domain.SurveyInput) (bool, error)
+
+	RoomFn func(ctx context.Context) (*dto.Room, error)
+
+	TwilioAccessTokenFn func(ctx context.Context) (*dto.AccessToken, error)
+
+	SendSMSFn func(ctx context.Context, to string, msg string) error
+
+	UploadFn func(
+		ctx context.Context,
+		inp profileutils.UploadInput,
+	) (*profileutils.Upload, error)
+
+	FindUploadByIDFn func(
+		ctx context.Context,
+		id string,
+	) (*profileutils.Upload, error)
+
+	PhoneNumberVerificationCodeFn func(
+		ctx context.Context,
+		to string,
+		code string,
+		marketingMessage string,
+	) (bool, error)
+
+	WellnessCardActivationDependantFn func(
+		ctx context.Context,
+		to string,
+		memberName string,
+		cardName string,
+		marketingMessage string,
+	) (bool, error)
+
+	WellnessCardActivationPrincipalFn func(
+		ctx context.Context,
+		to string,
+		memberName string,
+		cardName string,
+		minorAgeThreshold string,
+		marketingMessage string,
+	) (bool, error)
+
+	BillNotificationFn func(
+		ctx context.Context,
+		to string,
+		productName string,
+		billingPeriod string,
+		billAmount string,
+		paymentInstruction string,
+		marketingMessage string,
+	) (bool, error)
+
+	VirtualCardsFn func(
+		ctx context.Context,
+		to string,
+		wellnessCardFamily string,
 		virtualCardLink string,
 		marketingMessage string,
 	) (bool, error)
 
-	VisitStartFn func(
-		ctx context.Context,
-		to string,
-		memberName string,
-		benefitName string,
-		locationName string,
-		startTime string,
-		balance string,
-		marketingMessage string,
-	) (bool, error)
+	VisitStartFn func(
+		ctx context.Context,
+		to string,
+		memberName string,
+		benefitName string,
+		locationName string,
+		startTime string,
+		balance string,
+		marketingMessage string,
+	) (bool, error)
+
+	ClaimNotificationFn func(
+		ctx context.Context,
+		to string,
+		claimReference string,
+		claimTypeParenthesized string,
+		provider string,
+		visitType string,
+		claimTime string,
+		marketingMessage string,
+	) (bool, error)
+
+	PreauthApprovalFn func(
+		ctx context.Context,
+		to string,
+		currency string,
+		amount string,
+		benefit string,
+		provider string,
+		member string,
+		careContact string,
+		marketingMessage string,
+	) (bool, error)
+
+	PreauthRequestFn func(
+		ctx context.Context,
+		to string,
+		currency string,
+		amount string,
+		benefit string,
+		provider string,
+		requestTime string,
+		member string,
+		careContact string,
+		marketingMessage string,
+	) (bool, error)
+
+	SladeOTPFn func(
+		ctx context.Context,
+		to string,
+		name string,
+		otp string,
+		marketingMessage string,
+	) (bool, error)
+}
+
+// GetFeed ...
+func (f *FakeInfrastructure) GetFeed(
+	ctx context.Context,
+	uid *string,
+	isAnonymous *bool,
+	flavour feedlib.Flavour,
+	playMP4 bool,
+	persistent feedlib.BooleanFilter,
+	status *feedlib.Status,
+	visibility *feedlib.Visibility,
+	expired *feedlib.BooleanFilter,
+	filterParams *helpers.FilterParams,
+) (*domain.Feed, error) {
+	return f.GetFeedFn(ctx, uid, isAnonymous, flavour, playMP4, persistent, status, visibility, expired, filterParams)
+}
+
+// GetFeedItem ...
+func (f *FakeInfrastructure) GetFeedItem(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) (*feedlib.Item, error) {
+	return f.GetFeedItemFn(ctx, uid, flavour, itemID)
+}
+
+// GetFeedItemStatuses ...
+func (f *FakeInfrastructure) GetFeedItemStatuses(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemIDs []string,
+) (map[string]feedlib.Status, error) {
+	return f.GetFeedItemStatusesFn(ctx, uid, flavour, itemIDs)
+}
+
+// SaveFeedItem ...
+func (f *FakeInfrastructure) SaveFeedItem(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	item *feedlib.Item,
+) (*feedlib.Item, error) {
+	return f.SaveFeedItemFn(ctx, uid, flavour, item)
+}
+
+// UpdateFeedItem ...
+func (f *FakeInfrastructure) UpdateFeedItem(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	item *feedlib.Item,
+) (*feedlib.Item, error) {
+	return f.UpdateFeedItemFn(ctx, uid, flavour, item)
+}
+
+// DeleteFeedItem permanently deletes a feed item and it's copies
+func (f *FakeInfrastructure) DeleteFeedItem(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) error {
+	return f.DeleteFeedItemFn(ctx, uid, flavour, itemID)
+}
+
+// GetNudge gets THE LATEST VERSION OF a nudge from a feed
+func (f *FakeInfrastructure) GetNudge(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	nudgeID string,
+) (*feedlib.Nudge, error) {
+	return f.GetNudgeFn(ctx, uid, flavour, nudgeID)
+}
+
+// SaveNudge saves a new modified nudge
+func (f *FakeInfrastructure) SaveNudge(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	nudge *feedlib.Nudge,
+) (*feedlib.Nudge, error) {
+	return f.SaveNudgeFn(ctx, uid, flavour, nudge)
+}
+
+// UpdateNudge updates an existing nudge
+func (f *FakeInfrastructure) UpdateNudge(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	nudge *feedlib.Nudge,
+) (*feedlib.Nudge, error) {
+	return f.UpdateNudgeFn(ctx, uid, flavour, nudge)
+}
+
+// DeleteNudge permanently deletes a nudge and it's copies
+func (f *FakeInfrastructure) DeleteNudge(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	nudgeID string,
+) error {
+	return f.DeleteNudgeFn(ctx, uid, flavour, nudgeID)
+}
+
+// GetAction gets THE LATEST VERSION OF a single action
+func (f *FakeInfrastructure) GetAction(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	actionID string,
+) (*feedlib.Action, error) {
+	return f.GetActionFn(ctx, uid, flavour, actionID)
+}
+
+// SaveAction saves a new action
+func (f *FakeInfrastructure) SaveAction(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	action *feedlib.Action,
+) (*feedlib.Action, error) {
+	return f.SaveActionFn(ctx, uid, flavour, action)
+}
+
+// DeleteAction permanently deletes an action and it's copies
+func (f *FakeInfrastructure) DeleteAction(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	actionID string,
+) error {
+	return f.DeleteActionFn(ctx, uid, flavour, actionID)
+}
+
+// PostMessage posts a message or a reply to a message/thread
+func (f *FakeInfrastructure) PostMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	message *feedlib.Message,
+) (*feedlib.Message, error) {
+	return f.PostMessageFn(ctx, uid, flavour, itemID, message)
+}
+
+// GetMessage retrieves THE LATEST VERSION OF a message
+func (f *FakeInfrastructure) GetMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+) (*feedlib.Message, error) {
+	return f.GetMessageFn(ctx, uid, flavour, itemID, messageID)
+}
+
+// DeleteMessage deletes a message
+func (f *FakeInfrastructure) DeleteMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+) error {
+	return f.DeleteMessageFn(ctx, uid, flavour, itemID, messageID)
+}
+
+// GetThreadMessages ...
+func (f *FakeInfrastructure) GetThreadMessages(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) ([]domain.ThreadMessage, error) {
+	return f.GetThreadMessagesFn(ctx, uid, flavour, itemID)
+}
+
+// EditMessage ...
+func (f *FakeInfrastructure) EditMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	text string,
+	editedByUID string,
+) (*domain.ThreadMessage, error) {
+	return f.EditMessageFn(ctx, uid, flavour, itemID, messageID, text, editedByUID)
+}
 
-	ClaimNotificationFn func(
-		ctx context.Context,
-		to string,
-		claimReference string,
-		claimTypeParenthesized string,
-		provider string,
-		visitType string,
-		claimTime string,
-		marketingMessage string,
-	) (bool, error)
+// ReactToMessage ...
+func (f *FakeInfrastructure) ReactToMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	emoji string,
+	reactorUID string,
+	reacted bool,
+) error {
+	return f.ReactToMessageFn(ctx, uid, flavour, itemID, messageID, emoji, reactorUID, reacted)
+}
 
-	PreauthApprovalFn func(
-		ctx context.Context,
-		to string,
-		currency string,
-		amount string,
-		benefit string,
-		provider string,
-		member string,
-		careContact string,
-		marketingMessage string,
-	) (bool, error)
+// AttachToMessage ...
+func (f *FakeInfrastructure) AttachToMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	attachments []domain.MessageAttachment,
+) error {
+	return f.AttachToMessageFn(ctx, uid, flavour, itemID, messageID, attachments)
+}
 
-	PreauthRequestFn func(
-		ctx context.Context,
-		to string,
-		currency string,
-		amount string,
-		benefit string,
-		provider string,
-		requestTime string,
-		member string,
-		careContact string,
-		marketingMessage string,
-	) (bool, error)
+// SaveUploadOwner ...
+func (f *FakeInfrastructure) SaveUploadOwner(
+	ctx context.Context,
+	uploadID string,
+	uid string,
+) error {
+	return f.SaveUploadOwnerFn(ctx, uploadID, uid)
+}
 
-	SladeOTPFn func(
-		ctx context.Context,
-		to string,
-		name string,
-		otp string,
-		marketingMessage string,
-	) (bool, error)
+// GetUploadOwner ...
+func (f *FakeInfrastructure) GetUploadOwner(
+	ctx context.Context,
+	uploadID string,
+) (string, error) {
+	return f.GetUploadOwnerFn(ctx, uploadID)
 }
 
-// GetFeed ...
-func (f *FakeInfrastructure) GetFeed(
+// GetMessages retrieves a message
+func (f *FakeInfrastructure) GetMessages(
 	ctx context.Context,
-	uid *string,
-	isAnonymous *bool,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) ([]feedlib.Message, error) {
+	return f.GetMessagesFn(ctx, uid, flavour, itemID)
+}
+
+// SaveIncomingEvent ...
+func (f *FakeInfrastructure) SaveIncomingEvent(
+	ctx context.Context,
+	event *feedlib.Event,
+) error {
+	return f.SaveIncomingEventFn(ctx, event)
+}
+
+// SaveOutgoingEvent ...
+func (f *FakeInfrastructure) SaveOutgoingEvent(
+	ctx context.Context,
+	event *feedlib.Event,
+) error {
+	return f.SaveOutgoingEventFn(ctx, event)
+}
+
+// GetNudges ...
+func (f *FakeInfrastructure) GetNudges(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	status *feedlib.Status,
+	visibility *feedlib.Visibility,
+	expired *feedlib.BooleanFilter,
+) ([]feedlib.Nudge, error) {
+	return f.GetNudgesFn(ctx, uid, flavour, status, visibility, expired)
+}
+
+// GetActions ...
+func (f *FakeInfrastructure) GetActions(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) ([]feedlib.Action, error) {
+	return f.GetActionsFn(ctx, uid, flavour)
+}
+
+// GetItems ...
+func (f *FakeInfrastructure) GetItems(
+	ctx context.Context,
+	uid string,
 	flavour feedlib.Flavour,
-	playMP4 bool,
 	persistent feedlib.BooleanFilter,
 	status *feedlib.Status,
 	visibility *feedlib.Visibility,
 	expired *feedlib.BooleanFilter,
 	filterParams *helpers.FilterParams,
+) ([]feedlib.Item, error) {
+	return f.GetItemsFn(ctx, uid, flavour, persistent, status, visibility, expired, filterParams)
+}
+
+// Labels ...
+func (f *FakeInfrastructure) Labels(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) ([]string, error) {
+	return f.LabelsFn(ctx, uid, flavour)
+}
+
+// SaveLabel ...
+func (f *FakeInfrastructure) SaveLabel(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	label string,
+) error {
+	return f.SaveLabelFn(ctx, uid, flavour, label)
+}
+
+// RemoveLabels ...
+func (f *FakeInfrastructure) RemoveLabels(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	labels []string,
+) error {
+	return f.RemoveLabelsFn(ctx, uid, flavour, labels)
+}
+
+// RelabelItems ...
+func (f *FakeInfrastructure) RelabelItems(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	label string,
+	newLabel string,
+) (int, error) {
+	return f.RelabelItemsFn(ctx, uid, flavour, label, newLabel)
+}
+
+// LabelCounts ...
+func (f *FakeInfrastructure) LabelCounts(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) ([]domain.LabelCount, error) {
+	return f.LabelCountsFn(ctx, uid, flavour)
+}
+
+// UnreadPersistentItems ...
+func (f *FakeInfrastructure) UnreadPersistentItems(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (int, error) {
+	return f.UnreadPersistentItemsFn(ctx, uid, flavour)
+}
+
+// UpdateUnreadPersistentItemsCount ...
+func (f *FakeInfrastructure) UpdateUnreadPersistentItemsCount(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) error {
+	return f.UpdateUnreadPersistentItemsCountFn(ctx, uid, flavour)
+}
+
+// GetDefaultNudgeByTitle ...
+func (f *FakeInfrastructure) GetDefaultNudgeByTitle(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	title string,
+) (*feedlib.Nudge, error) {
+	return f.GetDefaultNudgeByTitleFn(ctx, uid, flavour, title)
+}
+
+// SaveElementTranslations ...
+func (f *FakeInfrastructure) SaveElementTranslations(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	translations *domain.ElementTranslations,
+) (*domain.ElementTranslations, error) {
+	return f.SaveElementTranslationsFn(ctx, uid, flavour, translations)
+}
+
+// GetElementTranslations ...
+func (f *FakeInfrastructure) GetElementTranslations(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (map[string]domain.ElementTranslations, error) {
+	return f.GetElementTranslationsFn(ctx, uid, flavour)
+}
+
+// GetFeedChanges ...
+func (f *FakeInfrastructure) GetFeedChanges(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	after domain.SyncCursor,
+	limit int,
+) (*domain.FeedChanges, error) {
+	return f.GetFeedChangesFn(ctx, uid, flavour, after, limit)
+}
+
+// GetElementsExpiringBefore ...
+func (f *FakeInfrastructure) GetElementsExpiringBefore(
+	ctx context.Context,
+	elementType domain.ElementType,
+	before time.Time,
+	after *domain.ExpiryCursor,
+	limit int,
+) ([]domain.ExpiringElement, error) {
+	return f.GetElementsExpiringBeforeFn(ctx, elementType, before, after, limit)
+}
+
+// ArchiveFeedItem ...
+func (f *FakeInfrastructure) ArchiveFeedItem(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	item *feedlib.Item,
+) error {
+	return f.ArchiveFeedItemFn(ctx, uid, flavour, item)
+}
+
+// ArchiveNudge ...
+func (f *FakeInfrastructure) ArchiveNudge(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	nudge *feedlib.Nudge,
+) error {
+	return f.ArchiveNudgeFn(ctx, uid, flavour, nudge)
+}
+
+// ClaimExpiryReminder ...
+func (f *FakeInfrastructure) ClaimExpiryReminder(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	elementType domain.ElementType,
+	elementID string,
+	expiry time.Time,
+) (bool, error) {
+	return f.ClaimExpiryReminderFn(ctx, uid, flavour, elementType, elementID, expiry)
+}
+
+// GetFeedElements ...
+func (f *FakeInfrastructure) GetFeedElements(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
 ) (*domain.Feed, error) {
-	return f.GetFeedFn(ctx, uid, isAnonymous, flavour, playMP4, persistent, status, visibility, expired, filterParams)
+	return f.GetFeedElementsFn(ctx, uid, flavour)
+}
+
+// ReassignIncomingEvents ...
+func (f *FakeInfrastructure) ReassignIncomingEvents(
+	ctx context.Context,
+	flavour feedlib.Flavour,
+	fromUID string,
+	toUID string,
+) (int, error) {
+	return f.ReassignIncomingEventsFn(ctx, flavour, fromUID, toUID)
+}
+
+// SaveFeedMerge ...
+func (f *FakeInfrastructure) SaveFeedMerge(
+	ctx context.Context,
+	merge *domain.FeedMerge,
+) error {
+	return f.SaveFeedMergeFn(ctx, merge)
 }
 
-// GetFeedItem ...
-func (f *FakeInfrastructure) GetFeedItem(
+// GetFeedMerge ...
+func (f *FakeInfrastructure) GetFeedMerge(
 	ctx context.Context,
-	uid string,
+	anonymousUID string,
 	flavour feedlib.Flavour,
-	itemID string,
-) (*feedlib.Item, error) {
-	return f.GetFeedItemFn(ctx, uid, flavour, itemID)
+) (*domain.FeedMerge, error) {
+	return f.GetFeedMergeFn(ctx, anonymousUID, flavour)
 }
 
-// SaveFeedItem ...
-func (f *FakeInfrastructure) SaveFeedItem(
+// SetThreadMute ...
+func (f *FakeInfrastructure) SetThreadMute(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-	item *feedlib.Item,
-) (*feedlib.Item, error) {
-	return f.SaveFeedItemFn(ctx, uid, flavour, item)
+	itemID string,
+	participantUID string,
+	muted bool,
+) error {
+	return f.SetThreadMuteFn(ctx, uid, flavour, itemID, participantUID, muted)
 }
 
-// UpdateFeedItem ...
-func (f *FakeInfrastructure) UpdateFeedItem(
+// GetThreadMutes ...
+func (f *FakeInfrastructure) GetThreadMutes(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-	item *feedlib.Item,
-) (*feedlib.Item, error) {
-	return f.UpdateFeedItemFn(ctx, uid, flavour, item)
+	itemID string,
+) ([]string, error) {
+	return f.GetThreadMutesFn(ctx, uid, flavour, itemID)
 }
 
-// DeleteFeedItem permanently deletes a feed item and it's copies
-func (f *FakeInfrastructure) DeleteFeedItem(
+// SaveChecklist ...
+func (f *FakeInfrastructure) SaveChecklist(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-	itemID string,
+	checklist domain.Checklist,
 ) error {
-	return f.DeleteFeedItemFn(ctx, uid, flavour, itemID)
+	return f.SaveChecklistFn(ctx, uid, flavour, checklist)
 }
 
-// GetNudge gets THE LATEST VERSION OF a nudge from a feed
-func (f *FakeInfrastructure) GetNudge(
+// GetChecklist ...
+func (f *FakeInfrastructure) GetChecklist(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-	nudgeID string,
-) (*feedlib.Nudge, error) {
-	return f.GetNudgeFn(ctx, uid, flavour, nudgeID)
+	itemID string,
+) (*domain.Checklist, error) {
+	return f.GetChecklistFn(ctx, uid, flavour, itemID)
 }
 
-// SaveNudge saves a new modified nudge
-func (f *FakeInfrastructure) SaveNudge(
+// GetChecklists ...
+func (f *FakeInfrastructure) GetChecklists(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-	nudge *feedlib.Nudge,
-) (*feedlib.Nudge, error) {
-	return f.SaveNudgeFn(ctx, uid, flavour, nudge)
+) ([]domain.Checklist, error) {
+	return f.GetChecklistsFn(ctx, uid, flavour)
 }
 
-// UpdateNudge updates an existing nudge
-func (f *FakeInfrastructure) UpdateNudge(
+// SaveChannelDeliveries ...
+func (f *FakeInfrastructure) SaveChannelDeliveries(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-	nudge *feedlib.Nudge,
-) (*feedlib.Nudge, error) {
-	return f.UpdateNudgeFn(ctx, uid, flavour, nudge)
+	deliveries []domain.ChannelDelivery,
+) error {
+	return f.SaveChannelDeliveriesFn(ctx, uid, flavour, deliveries)
 }
 
-// DeleteNudge permanently deletes a nudge and it's copies
-func (f *FakeInfrastructure) DeleteNudge(
+// GetChannelDeliveries ...
+func (f *FakeInfrastructure) GetChannelDeliveries(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-	nudgeID string,
+	elementID string,
+) ([]domain.ChannelDelivery, error) {
+	return f.GetChannelDeliveriesFn(ctx, uid, flavour, elementID)
+}
+
+// SaveNotificationPreferences ...
+func (f *FakeInfrastructure) SaveNotificationPreferences(
+	ctx context.Context,
+	preferences domain.NotificationPreferences,
 ) error {
-	return f.DeleteNudgeFn(ctx, uid, flavour, nudgeID)
+	return f.SaveNotificationPreferencesFn(ctx, preferences)
 }
 
-// GetAction gets THE LATEST VERSION OF a single action
-func (f *FakeInfrastructure) GetAction(
+// GetNotificationPreferences ...
+func (f *FakeInfrastructure) GetNotificationPreferences(
 	ctx context.Context,
 	uid string,
-	flavour feedlib.Flavour,
-	actionID string,
-) (*feedlib.Action, error) {
-	return f.GetActionFn(ctx, uid, flavour, actionID)
+) (*domain.NotificationPreferences, error) {
+	return f.GetNotificationPreferencesFn(ctx, uid)
 }
 
-// SaveAction saves a new action
-func (f *FakeInfrastructure) SaveAction(
+// SaveDeferredNotification ...
+func (f *FakeInfrastructure) SaveDeferredNotification(
 	ctx context.Context,
-	uid string,
-	flavour feedlib.Flavour,
-	action *feedlib.Action,
-) (*feedlib.Action, error) {
-	return f.SaveActionFn(ctx, uid, flavour, action)
+	notification domain.DeferredNotification,
+) error {
+	return f.SaveDeferredNotificationFn(ctx, notification)
 }
 
-// DeleteAction permanently deletes an action and it's copies
-func (f *FakeInfrastructure) DeleteAction(
+// GetDueDeferredNotifications ...
+func (f *FakeInfrastructure) GetDueDeferredNotifications(
 	ctx context.Context,
-	uid string,
-	flavour feedlib.Flavour,
-	actionID string,
+	before time.Time,
+	limit int,
+) ([]domain.DeferredNotification, error) {
+	return f.GetDueDeferredNotificationsFn(ctx, before, limit)
+}
+
+// DeleteDeferredNotification ...
+func (f *FakeInfrastructure) DeleteDeferredNotification(
+	ctx context.Context,
+	id string,
 ) error {
-	return f.DeleteActionFn(ctx, uid, flavour, actionID)
+	return f.DeleteDeferredNotificationFn(ctx, id)
 }
 
-// PostMessage posts a message or a reply to a message/thread
-func (f *FakeInfrastructure) PostMessage(
+// SaveDigestSettings ...
+func (f *FakeInfrastructure) SaveDigestSettings(
+	ctx context.Context,
+	settings domain.DigestSettings,
+) error {
+	return f.SaveDigestSettingsFn(ctx, settings)
+}
+
+// GetDigestSettings ...
+func (f *FakeInfrastructure) GetDigestSettings(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-	itemID string,
-	message *feedlib.Message,
-) (*feedlib.Message, error) {
-	return f.PostMessageFn(ctx, uid, flavour, itemID, message)
+) (*domain.DigestSettings, error) {
+	return f.GetDigestSettingsFn(ctx, uid, flavour)
 }
 
-// GetMessage retrieves THE LATEST VERSION OF a message
-func (f *FakeInfrastructure) GetMessage(
+// ListDigestSettings ...
+func (f *FakeInfrastructure) ListDigestSettings(
+	ctx context.Context,
+	frequency domain.DigestFrequency,
+	after *domain.DigestSettings,
+	limit int,
+) ([]domain.DigestSettings, error) {
+	return f.ListDigestSettingsFn(ctx, frequency, after, limit)
+}
+
+// QueueInboxCountPush ...
+func (f *FakeInfrastructure) QueueInboxCountPush(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-	itemID string,
+	dueAt time.Time,
+) error {
+	return f.QueueInboxCountPushFn(ctx, uid, flavour, dueAt)
+}
+
+// GetDueInboxCountPushes ...
+func (f *FakeInfrastructure) GetDueInboxCountPushes(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]domain.InboxCountPush, error) {
+	return f.GetDueInboxCountPushesFn(ctx, before, limit)
+}
+
+// DeleteInboxCountPush ...
+func (f *FakeInfrastructure) DeleteInboxCountPush(
+	ctx context.Context,
+	push domain.InboxCountPush,
+) error {
+	return f.DeleteInboxCountPushFn(ctx, push)
+}
+
+// GetTokenHealth ...
+func (f *FakeInfrastructure) GetTokenHealth(
+	ctx context.Context,
+	tokens []string,
+) (map[string]domain.TokenHealth, error) {
+	return f.GetTokenHealthFn(ctx, tokens)
+}
+
+// SaveTokenHealth ...
+func (f *FakeInfrastructure) SaveTokenHealth(
+	ctx context.Context,
+	health domain.TokenHealth,
+) error {
+	return f.SaveTokenHealthFn(ctx, health)
+}
+
+// SaveFCMRetry ...
+func (f *FakeInfrastructure) SaveFCMRetry(
+	ctx context.Context,
+	retry domain.FCMRetry,
+) error {
+	return f.SaveFCMRetryFn(ctx, retry)
+}
+
+// GetDueFCMRetries ...
+func (f *FakeInfrastructure) GetDueFCMRetries(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]domain.FCMRetry, error) {
+	return f.GetDueFCMRetriesFn(ctx, before, limit)
+}
+
+// DeleteFCMRetry ...
+func (f *FakeInfrastructure) DeleteFCMRetry(
+	ctx context.Context,
+	id string,
+) error {
+	return f.DeleteFCMRetryFn(ctx, id)
+}
+
+// SaveFCMDeadLetter ...
+func (f *FakeInfrastructure) SaveFCMDeadLetter(
+	ctx context.Context,
+	letter domain.FCMDeadLetter,
+) error {
+	return f.SaveFCMDeadLetterFn(ctx, letter)
+}
+
+// ListFCMDeadLetters ...
+func (f *FakeInfrastructure) ListFCMDeadLetters(
+	ctx context.Context,
+	limit int,
+) ([]domain.FCMDeadLetter, error) {
+	return f.ListFCMDeadLettersFn(ctx, limit)
+}
+
+// GetFCMDeadLetter ...
+func (f *FakeInfrastructure) GetFCMDeadLetter(
+	ctx context.Context,
+	id string,
+) (*domain.FCMDeadLetter, error) {
+	return f.GetFCMDeadLetterFn(ctx, id)
+}
+
+// DeleteFCMDeadLetter ...
+func (f *FakeInfrastructure) DeleteFCMDeadLetter(
+	ctx context.Context,
+	id string,
+) error {
+	return f.DeleteFCMDeadLetterFn(ctx, id)
+}
+
+// GetNotificationsByMessageID ...
+func (f *FakeInfrastructure) GetNotificationsByMessageID(
+	ctx context.Context,
 	messageID string,
-) (*feedlib.Message, error) {
-	return f.GetMessageFn(ctx, uid, flavour, itemID, messageID)
+) ([]*dto.SavedNotification, error) {
+	return f.GetNotificationsByMessageIDFn(ctx, messageID)
 }
 
-// DeleteMessage deletes a message
-func (f *FakeInfrastructure) DeleteMessage(
+// UpdateNotificationStatus ...
+func (f *FakeInfrastructure) UpdateNotificationStatus(
 	ctx context.Context,
-	uid string,
-	flavour feedlib.Flavour,
-	itemID string,
 	messageID string,
+	status domain.NotificationStatus,
+	at time.Time,
 ) error {
-	return f.DeleteMessageFn(ctx, uid, flavour, itemID, messageID)
+	return f.UpdateNotificationStatusFn(ctx, messageID, status, at)
 }
 
-// GetMessages retrieves a message
-func (f *FakeInfrastructure) GetMessages(
+// RetrieveNotificationsForTokens ...
+func (f *FakeInfrastructure) RetrieveNotificationsForTokens(
 	ctx context.Context,
-	uid string,
-	flavour feedlib.Flavour,
-	itemID string,
-) ([]feedlib.Message, error) {
-	return f.GetMessagesFn(ctx, uid, flavour, itemID)
+	registrationTokens []string,
+	newerThan time.Time,
+	limit int,
+) ([]*dto.SavedNotification, error) {
+	return f.RetrieveNotificationsForTokensFn(ctx, registrationTokens, newerThan, limit)
 }
 
-// SaveIncomingEvent ...
-func (f *FakeInfrastructure) SaveIncomingEvent(
+// NotificationOpenRates ...
+func (f *FakeInfrastructure) NotificationOpenRates(
 	ctx context.Context,
-	event *feedlib.Event,
+	since time.Time,
+	until time.Time,
+) ([]domain.NotificationOpenRate, error) {
+	return f.NotificationOpenRatesFn(ctx, since, until)
+}
+
+// ClaimPubSubMessage ...
+func (f *FakeInfrastructure) ClaimPubSubMessage(
+	ctx context.Context,
+	message domain.ProcessedMessage,
+) (*domain.ProcessedMessage, bool, error) {
+	return f.ClaimPubSubMessageFn(ctx, message)
+}
+
+// CompletePubSubMessage ...
+func (f *FakeInfrastructure) CompletePubSubMessage(
+	ctx context.Context,
+	topic string,
+	messageID string,
 ) error {
-	return f.SaveIncomingEventFn(ctx, event)
+	return f.CompletePubSubMessageFn(ctx, topic, messageID)
 }
 
-// SaveOutgoingEvent ...
-func (f *FakeInfrastructure) SaveOutgoingEvent(
+// CompletePubSubStep ...
+func (f *FakeInfrastructure) CompletePubSubStep(
 	ctx context.Context,
-	event *feedlib.Event,
+	topic string,
+	messageID string,
+	step string,
 ) error {
-	return f.SaveOutgoingEventFn(ctx, event)
+	return f.CompletePubSubStepFn(ctx, topic, messageID, step)
 }
 
-// GetNudges ...
-func (f *FakeInfrastructure) GetNudges(
+// ReleasePubSubMessage ...
+func (f *FakeInfrastructure) ReleasePubSubMessage(
 	ctx context.Context,
-	uid string,
-	flavour feedlib.Flavour,
-	status *feedlib.Status,
-	visibility *feedlib.Visibility,
-	expired *feedlib.BooleanFilter,
-) ([]feedlib.Nudge, error) {
-	return f.GetNudgesFn(ctx, uid, flavour, status, visibility, expired)
+	topic string,
+	messageID string,
+) error {
+	return f.ReleasePubSubMessageFn(ctx, topic, messageID)
 }
 
-// GetActions ...
-func (f *FakeInfrastructure) GetActions(
+// SetMessageHidden ...
+func (f *FakeInfrastructure) SetMessageHidden(
 	ctx context.Context,
 	uid string,
 	flavour feedlib.Flavour,
-) ([]feedlib.Action, error) {
-	return f.GetActionsFn(ctx, uid, flavour)
+	itemID string,
+	messageID string,
+	hidden bool,
+) error {
+	return f.SetMessageHiddenFn(ctx, uid, flavour, itemID, messageID, hidden)
 }
 
-// GetItems ...
-func (f *FakeInfrastructure) GetItems(
+// ReportMessage ...
+func (f *FakeInfrastructure) ReportMessage(
 	ctx context.Context,
-	uid string,
-	flavour feedlib.Flavour,
-	persistent feedlib.BooleanFilter,
-	status *feedlib.Status,
-	visibility *feedlib.Visibility,
-	expired *feedlib.BooleanFilter,
-	filterParams *helpers.FilterParams,
-) ([]feedlib.Item, error) {
-	return f.GetItemsFn(ctx, uid, flavour, persistent, status, visibility, expired, filterParams)
+	moderationCase domain.ModerationCase,
+	report domain.MessageReport,
+) (*domain.ModerationCase, error) {
+	return f.ReportMessageFn(ctx, moderationCase, report)
 }
 
-// Labels ...
-func (f *FakeInfrastructure) Labels(
+// GetModerationCase ...
+func (f *FakeInfrastructure) GetModerationCase(
 	ctx context.Context,
-	uid string,
-	flavour feedlib.Flavour,
-) ([]string, error) {
-	return f.LabelsFn(ctx, uid, flavour)
+	caseID string,
+) (*domain.ModerationCase, error) {
+	return f.GetModerationCaseFn(ctx, caseID)
 }
 
-// SaveLabel ...
-func (f *FakeInfrastructure) SaveLabel(
+// GetModerationCases ...
+func (f *FakeInfrastructure) GetModerationCases(
 	ctx context.Context,
-	uid string,
-	flavour feedlib.Flavour,
-	label string,
-) error {
-	return f.SaveLabelFn(ctx, uid, flavour, label)
+	status domain.ModerationStatus,
+	limit int,
+) ([]domain.ModerationCase, error) {
+	return f.GetModerationCasesFn(ctx, status, limit)
 }
 
-// UnreadPersistentItems ...
-func (f *FakeInfrastructure) UnreadPersistentItems(
+// UpdateModerationCase ...
+func (f *FakeInfrastructure) UpdateModerationCase(
 	ctx context.Context,
-	uid string,
-	flavour feedlib.Flavour,
-) (int, error) {
-	return f.UnreadPersistentItemsFn(ctx, uid, flavour)
+	moderationCase domain.ModerationCase,
+) error {
+	return f.UpdateModerationCaseFn(ctx, moderationCase)
 }
 
-// UpdateUnreadPersistentItemsCount ...
-func (f *FakeInfrastructure) UpdateUnreadPersistentItemsCount(
+// SaveModerationAuditEntry ...
+func (f *FakeInfrastructure) SaveModerationAuditEntry(
 	ctx context.Context,
-	uid string,
-	flavour feedlib.Flavour,
+	entry domain.ModerationAuditEntry,
 ) error {
-	return f.UpdateUnreadPersistentItemsCountFn(ctx, uid, flavour)
+	return f.SaveModerationAuditEntryFn(ctx, entry)
 }
 
-// GetDefaultNudgeByTitle ...
-func (f *FakeInfrastructure) GetDefaultNudgeByTitle(
+// GetModerationAudit ...
+func (f *FakeInfrastructure) GetModerationAudit(
 	ctx context.Context,
-	uid string,
-	flavour feedlib.Flavour,
-	title string,
-) (*feedlib.Nudge, error) {
-	return f.GetDefaultNudgeByTitleFn(ctx, uid, flavour, title)
+	caseID string,
+) ([]domain.ModerationAuditEntry, error) {
+	return f.GetModerationAuditFn(ctx, caseID)
 }
 
 // SaveTwilioResponse saves the callback data for future analysis
@@ -794,6 +1804,33 @@ func (f *FakeInfrastructure) SaveTwilioVideoCallbackStatus(
 	return f.SaveTwilioVideoCallbackStatusFn(ctx, data)
 }
 
+// ClaimIdempotencyKey ...
+func (f *FakeInfrastructure) ClaimIdempotencyKey(
+	ctx context.Context,
+	record domain.IdempotencyRecord,
+) (*domain.IdempotencyRecord, bool, error) {
+	return f.ClaimIdempotencyKeyFn(ctx, record)
+}
+
+// CompleteIdempotencyKey ...
+func (f *FakeInfrastructure) CompleteIdempotencyKey(
+	ctx context.Context,
+	scope string,
+	key string,
+	response domain.IdempotentResponse,
+) error {
+	return f.CompleteIdempotencyKeyFn(ctx, scope, key, response)
+}
+
+// ReleaseIdempotencyKey ...
+func (f *FakeInfrastructure) ReleaseIdempotencyKey(
+	ctx context.Context,
+	scope string,
+	key string,
+) error {
+	return f.ReleaseIdempotencyKeyFn(ctx, scope, key)
+}
+
 // SendInBlue ...
 func (f *FakeInfrastructure) SendInBlue(ctx context.Context, subject, text string, to ...string) (string, string, error) {
 	return f.SendInBlueFn(ctx, subject, text, to...)
@@ -899,6 +1936,21 @@ func (f *FakeInfrastructure) GetUserProfileByPhoneOrEmail(ctx context.Context, p
 	return f.GetUserProfileByPhoneOrEmailFn(ctx, payload)
 }
 
+// GetPreferredLanguage ...
+func (f *FakeInfrastructure) GetPreferredLanguage(ctx context.Context, uid string) (string, error) {
+	return f.GetPreferredLanguageFn(ctx, uid)
+}
+
+// IsOptedOut ...
+func (f *FakeInfrastructure) IsOptedOut(ctx context.Context, phoneNumber string) (bool, error) {
+	return f.IsOptedOutFn(ctx, phoneNumber)
+}
+
+// PhonesWithoutOptOut ...
+func (f *FakeInfrastructure) PhonesWithoutOptOut(ctx context.Context, phones []string) ([]string, error) {
+	return f.PhonesWithoutOptOutFn(ctx, phones)
+}
+
 // GenerateAndSendOTP ...
 func (f *FakeInfrastructure) GenerateAndSendOTP(ctx context.Context, msisdn string, appID *string) (string, error) {
 	return f.GenerateAndSendOTPFn(ctx, msisdn, appID)
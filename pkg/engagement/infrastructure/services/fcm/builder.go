@@ -0,0 +1,257 @@
+package fcm
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"firebase.google.com/go/messaging"
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/firebasetools"
+)
+
+const (
+	// maxDataPayloadSize is the most bytes of custom data, keys and values,
+	// that FCM accepts in a message
+	maxDataPayloadSize = 4096
+
+	// maxAndroidTTL is the longest that FCM keeps a message for an offline
+	// Android device
+	maxAndroidTTL = 28 * 24 * time.Hour
+
+	// the APNs priorities. Background pushes have to be sent with the low
+	// one.
+	apnsPriorityHigh = "10"
+	apnsPriorityLow  = "5"
+)
+
+// colorPattern matches the #RRGGBB colours that Android notifications take
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// NotificationBuilder assembles an FCM message from the notification inputs
+// after checking them against the constraints of each platform
+type NotificationBuilder struct {
+	Data         map[string]string
+	Notification *firebasetools.FirebaseSimpleNotificationInput
+	Android      *firebasetools.FirebaseAndroidConfigInput
+	Ios          *firebasetools.FirebaseAPNSConfigInput
+	Web          *firebasetools.FirebaseWebpushConfigInput
+	Options      dto.NotificationOptions
+}
+
+// NewNotificationBuilder starts an FCM message from a notification payload
+func NewNotificationBuilder(payload dto.NotificationPayload) NotificationBuilder {
+	return NotificationBuilder{
+		Data:         payload.Data,
+		Notification: payload.Notification,
+		Android:      payload.Android,
+		Ios:          payload.Ios,
+		Web:          payload.Web,
+		Options:      payload.NotificationOptions,
+	}
+}
+
+// Validate checks that FCM and the platforms will accept the message
+func (b NotificationBuilder) Validate() error {
+	err := ValidateFCMData(b.Data)
+	if err != nil {
+		return err
+	}
+	size := 0
+	for key, value := range b.Data {
+		size += len(key) + len(value)
+	}
+	if size > maxDataPayloadSize {
+		return fmt.Errorf(
+			"the data is %d bytes, more than the %d bytes allowed by FCM",
+			size, maxDataPayloadSize)
+	}
+
+	err = b.validateAndroid()
+	if err != nil {
+		return fmt.Errorf("invalid Android options: %w", err)
+	}
+	err = b.validateAPNS()
+	if err != nil {
+		return fmt.Errorf("invalid APNs options: %w", err)
+	}
+	return nil
+}
+
+func (b NotificationBuilder) validateAndroid() error {
+	if b.Android != nil {
+		switch b.Android.Priority {
+		case "", "normal", "high":
+		default:
+			return fmt.Errorf("the priority should be normal or high, not %s", b.Android.Priority)
+		}
+	}
+
+	options := b.Options.AndroidOptions
+	if options == nil {
+		return nil
+	}
+	if options.TTL != nil {
+		ttl := time.Duration(*options.TTL) * time.Second
+		if ttl < 0 || ttl > maxAndroidTTL {
+			return fmt.Errorf("the TTL should be between 0 and %d seconds", int(maxAndroidTTL.Seconds()))
+		}
+	}
+	if options.Color != nil && !colorPattern.MatchString(*options.Color) {
+		return fmt.Errorf("the colour %s is not in the #RRGGBB format", *options.Color)
+	}
+	styled := options.ChannelID != nil ||
+		options.ClickAction != nil ||
+		options.Icon != nil ||
+		options.Color != nil
+	if styled && b.Notification == nil {
+		return fmt.Errorf("the channel, click action, icon and colour only apply to notifications")
+	}
+	return nil
+}
+
+func (b NotificationBuilder) validateAPNS() error {
+	priority := ""
+	if b.Ios != nil {
+		headers := converterandformatter.ConvertInterfaceMap(b.Ios.Headers)
+		priority = headers["apns-priority"]
+		switch priority {
+		case "", apnsPriorityHigh, apnsPriorityLow:
+		default:
+			return fmt.Errorf("the apns-priority header should be 5 or 10, not %s", priority)
+		}
+	}
+
+	aps := b.Options.APNSPayload
+	if aps == nil {
+		return nil
+	}
+	if aps.Badge != nil && *aps.Badge < 0 {
+		return fmt.Errorf("the badge can't be negative")
+	}
+	if aps.Sound != nil && *aps.Sound == "" {
+		return fmt.Errorf("the sound can't be blank")
+	}
+	if aps.MutableContent && b.Notification == nil {
+		return fmt.Errorf("mutable content needs a notification to modify")
+	}
+	if aps.ContentAvailable && b.Notification == nil && priority == apnsPriorityHigh {
+		return fmt.Errorf("background pushes have to be sent with an apns-priority of 5")
+	}
+	return nil
+}
+
+// build validates the message and translates it to FCM message parts
+func (b NotificationBuilder) build() (*messageContent, error) {
+	err := b.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	content := &messageContent{data: b.Data}
+	if b.Notification != nil {
+		content.notification = &messaging.Notification{
+			Title: b.Notification.Title,
+			Body:  b.Notification.Body,
+		}
+		if b.Notification.ImageURL != nil {
+			content.notification.ImageURL = *b.Notification.ImageURL
+		}
+	}
+	content.android = b.buildAndroid()
+	content.apns = b.buildAPNS()
+	if b.Web != nil {
+		content.webpush = &messaging.WebpushConfig{
+			Headers: converterandformatter.ConvertInterfaceMap(b.Web.Headers),
+			Data:    converterandformatter.ConvertInterfaceMap(b.Web.Data),
+		}
+	}
+	return content, nil
+}
+
+func (b NotificationBuilder) buildAndroid() *messaging.AndroidConfig {
+	options := b.Options.AndroidOptions
+	if b.Android == nil && options == nil {
+		return nil
+	}
+
+	android := &messaging.AndroidConfig{}
+	if b.Android != nil {
+		android.Priority = b.Android.Priority
+		android.Data = converterandformatter.ConvertInterfaceMap(b.Android.Data)
+		if b.Android.CollapseKey != nil {
+			android.CollapseKey = *b.Android.CollapseKey
+		}
+		if b.Android.RestrictedPackageName != nil {
+			android.RestrictedPackageName = *b.Android.RestrictedPackageName
+		}
+	}
+	if options == nil {
+		return android
+	}
+
+	if options.TTL != nil {
+		ttl := time.Duration(*options.TTL) * time.Second
+		android.TTL = &ttl
+	}
+	if b.Notification != nil {
+		notification := &messaging.AndroidNotification{}
+		if options.ChannelID != nil {
+			notification.ChannelID = *options.ChannelID
+		}
+		if options.ClickAction != nil {
+			notification.ClickAction = *options.ClickAction
+		}
+		if options.Icon != nil {
+			notification.Icon = *options.Icon
+		}
+		if options.Color != nil {
+			notification.Color = *options.Color
+		}
+		android.Notification = notification
+	}
+	return android
+}
+
+func (b NotificationBuilder) buildAPNS() *messaging.APNSConfig {
+	aps := b.Options.APNSPayload
+	if b.Ios == nil && aps == nil {
+		return nil
+	}
+
+	apns := &messaging.APNSConfig{Headers: map[string]string{}}
+	if b.Ios != nil {
+		apns.Headers = converterandformatter.ConvertInterfaceMap(b.Ios.Headers)
+	}
+	if aps == nil {
+		return apns
+	}
+
+	apns.Payload = &messaging.APNSPayload{
+		Aps: &messaging.Aps{
+			Badge:            aps.Badge,
+			MutableContent:   aps.MutableContent,
+			ContentAvailable: aps.ContentAvailable,
+		},
+	}
+	if aps.Sound != nil {
+		apns.Payload.Aps.Sound = *aps.Sound
+	}
+	if aps.Category != nil {
+		apns.Payload.Aps.Category = *aps.Category
+	}
+	if aps.ThreadID != nil {
+		apns.Payload.Aps.ThreadID = *aps.ThreadID
+	}
+	if aps.ContentAvailable && b.Notification == nil {
+		// APNs drops background pushes that are not marked as such
+		if _, ok := apns.Headers["apns-priority"]; !ok {
+			apns.Headers["apns-priority"] = apnsPriorityLow
+		}
+		if _, ok := apns.Headers["apns-push-type"]; !ok {
+			apns.Headers["apns-push-type"] = "background"
+		}
+	}
+	return apns
+}
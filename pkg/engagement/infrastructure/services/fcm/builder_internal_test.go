@@ -0,0 +1,228 @@
+package fcm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/firebasetools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationBuilder_Validate(t *testing.T) {
+	notification := &firebasetools.FirebaseSimpleNotificationInput{Title: "Title", Body: "Body"}
+	badge := 3
+	negative := -1
+	blank := ""
+	ttl := 3600
+	tooLong := int((maxAndroidTTL + time.Second).Seconds())
+	color := "#00FF7f"
+	named := "green"
+	channel := "reminders"
+
+	tests := []struct {
+		name    string
+		builder NotificationBuilder
+		wantErr bool
+	}{
+		{
+			name: "a complete notification",
+			builder: NotificationBuilder{
+				Data:         map[string]string{"item": "1"},
+				Notification: notification,
+				Android:      &firebasetools.FirebaseAndroidConfigInput{Priority: "high"},
+				Ios: &firebasetools.FirebaseAPNSConfigInput{
+					Headers: map[string]interface{}{"apns-priority": "10"},
+				},
+				Options: dto.NotificationOptions{
+					APNSPayload: &dto.APNSPayloadInput{Badge: &badge, MutableContent: true},
+					AndroidOptions: &dto.AndroidOptionsInput{
+						TTL:       &ttl,
+						ChannelID: &channel,
+						Color:     &color,
+					},
+				},
+			},
+		},
+		{
+			name: "a silent background push",
+			builder: NotificationBuilder{
+				Data: map[string]string{"item": "1"},
+				Options: dto.NotificationOptions{
+					APNSPayload:    &dto.APNSPayloadInput{ContentAvailable: true},
+					AndroidOptions: &dto.AndroidOptionsInput{TTL: &ttl},
+				},
+			},
+		},
+		{
+			name:    "reserved data keys",
+			builder: NotificationBuilder{Data: map[string]string{"from": "x"}},
+			wantErr: true,
+		},
+		{
+			name: "too much data",
+			builder: NotificationBuilder{
+				Data: map[string]string{"item": strings.Repeat("x", maxDataPayloadSize)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "an unknown Android priority",
+			builder: NotificationBuilder{
+				Android: &firebasetools.FirebaseAndroidConfigInput{Priority: "urgent"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "a TTL beyond what FCM keeps",
+			builder: NotificationBuilder{Options: dto.NotificationOptions{
+				AndroidOptions: &dto.AndroidOptionsInput{TTL: &tooLong},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "a named colour",
+			builder: NotificationBuilder{
+				Notification: notification,
+				Options: dto.NotificationOptions{
+					AndroidOptions: &dto.AndroidOptionsInput{Color: &named},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "a channel without a notification",
+			builder: NotificationBuilder{Options: dto.NotificationOptions{
+				AndroidOptions: &dto.AndroidOptionsInput{ChannelID: &channel},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "an unknown APNs priority",
+			builder: NotificationBuilder{
+				Ios: &firebasetools.FirebaseAPNSConfigInput{
+					Headers: map[string]interface{}{"apns-priority": "1"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "a negative badge",
+			builder: NotificationBuilder{Options: dto.NotificationOptions{
+				APNSPayload: &dto.APNSPayloadInput{Badge: &negative},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "a blank sound",
+			builder: NotificationBuilder{Options: dto.NotificationOptions{
+				APNSPayload: &dto.APNSPayloadInput{Sound: &blank},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "mutable content without a notification",
+			builder: NotificationBuilder{Options: dto.NotificationOptions{
+				APNSPayload: &dto.APNSPayloadInput{MutableContent: true},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "a background push with a high priority",
+			builder: NotificationBuilder{
+				Ios: &firebasetools.FirebaseAPNSConfigInput{
+					Headers: map[string]interface{}{"apns-priority": "10"},
+				},
+				Options: dto.NotificationOptions{
+					APNSPayload: &dto.APNSPayloadInput{ContentAvailable: true},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.builder.Validate()
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestNotificationBuilder_build(t *testing.T) {
+	badge := 2
+	sound := "default"
+	category := "REMINDER"
+	thread := "programme-mch"
+	ttl := 90
+	channel := "reminders"
+	action := "OPEN_ITEM"
+	icon := "ic_reminder"
+	color := "#336699"
+
+	content, err := NotificationBuilder{
+		Notification: &firebasetools.FirebaseSimpleNotificationInput{Title: "Title", Body: "Body"},
+		Android:      &firebasetools.FirebaseAndroidConfigInput{Priority: "high"},
+		Options: dto.NotificationOptions{
+			APNSPayload: &dto.APNSPayloadInput{
+				Badge:          &badge,
+				Sound:          &sound,
+				Category:       &category,
+				ThreadID:       &thread,
+				MutableContent: true,
+			},
+			AndroidOptions: &dto.AndroidOptionsInput{
+				TTL:         &ttl,
+				ChannelID:   &channel,
+				ClickAction: &action,
+				Icon:        &icon,
+				Color:       &color,
+			},
+		},
+	}.build()
+	assert.Nil(t, err)
+
+	assert.Equal(t, "high", content.android.Priority)
+	assert.Equal(t, 90*time.Second, *content.android.TTL)
+	assert.Equal(t, "reminders", content.android.Notification.ChannelID)
+	assert.Equal(t, "OPEN_ITEM", content.android.Notification.ClickAction)
+	assert.Equal(t, "ic_reminder", content.android.Notification.Icon)
+	assert.Equal(t, "#336699", content.android.Notification.Color)
+
+	aps := content.apns.Payload.Aps
+	assert.Equal(t, &badge, aps.Badge)
+	assert.Equal(t, "default", aps.Sound)
+	assert.Equal(t, "REMINDER", aps.Category)
+	assert.Equal(t, "programme-mch", aps.ThreadID)
+	assert.True(t, aps.MutableContent)
+	assert.False(t, aps.ContentAvailable)
+	assert.Empty(t, content.apns.Headers)
+	assert.Nil(t, content.webpush)
+}
+
+func TestNotificationBuilder_buildIOS(t *testing.T) {
+	content, err := NotificationBuilder{
+		Ios: &firebasetools.FirebaseAPNSConfigInput{
+			Headers: map[string]interface{}{"apns-expiration": "1604750400"},
+		},
+	}.build()
+	assert.Nil(t, err, "iOS options are read without web ones")
+	assert.Equal(t, "1604750400", content.apns.Headers["apns-expiration"])
+	assert.Nil(t, content.apns.Payload)
+	assert.Nil(t, content.android)
+
+	content, err = NotificationBuilder{
+		Data: map[string]string{"item": "1"},
+		Options: dto.NotificationOptions{
+			APNSPayload: &dto.APNSPayloadInput{ContentAvailable: true},
+		},
+	}.build()
+	assert.Nil(t, err)
+	assert.True(t, content.apns.Payload.Aps.ContentAvailable)
+	assert.Equal(t, "5", content.apns.Headers["apns-priority"], "background pushes are sent with a low priority")
+	assert.Equal(t, "background", content.apns.Headers["apns-push-type"])
+}
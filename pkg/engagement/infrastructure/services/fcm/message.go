@@ -0,0 +1,98 @@
+package fcm
+
+import (
+	"time"
+
+	"firebase.google.com/go/messaging"
+	"github.com/google/uuid"
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/firebasetools"
+)
+
+// messageContent is what an FCM message carries, whoever it is sent to
+type messageContent struct {
+	data         map[string]string
+	notification *messaging.Notification
+	android      *messaging.AndroidConfig
+	webpush      *messaging.WebpushConfig
+	apns         *messaging.APNSConfig
+}
+
+// multicast addresses the content to registration tokens
+func (c messageContent) multicast(tokens []string) *messaging.MulticastMessage {
+	return &messaging.MulticastMessage{
+		Tokens:       tokens,
+		Data:         c.data,
+		Notification: c.notification,
+		Android:      c.android,
+		Webpush:      c.webpush,
+		APNS:         c.apns,
+	}
+}
+
+// broadcast addresses the content to the devices subscribed to a topic, or
+// to a combination of topics
+func (c messageContent) broadcast(topic string, condition string) *messaging.Message {
+	return &messaging.Message{
+		Topic:        topic,
+		Condition:    condition,
+		Data:         c.data,
+		Notification: c.notification,
+		Android:      c.android,
+		Webpush:      c.webpush,
+		APNS:         c.apns,
+	}
+}
+
+// savedNotification is the record of a sent message, without its recipient
+// and message ID. Messages that were not sent for a feed sender have the
+// other sender type.
+func savedNotification(
+	senderType string,
+	data map[string]string,
+	notification *firebasetools.FirebaseSimpleNotificationInput,
+	android *firebasetools.FirebaseAndroidConfigInput,
+	ios *firebasetools.FirebaseAPNSConfigInput,
+	web *firebasetools.FirebaseWebpushConfigInput,
+) dto.SavedNotification {
+	if senderType == "" {
+		senderType = domain.NotificationSenderOther
+	}
+	saved := dto.SavedNotification{
+		ID:         uuid.New().String(),
+		Timestamp:  time.Now(),
+		SenderType: senderType,
+		Status:     domain.NotificationStatusSent,
+	}
+	if notification != nil {
+		saved.Notification = &dto.FirebaseSimpleNotification{
+			Title:    notification.Title,
+			Body:     notification.Body,
+			ImageURL: notification.ImageURL,
+		}
+	}
+	if data != nil {
+		saved.Data = converterandformatter.ConvertStringMap(data)
+	}
+	if android != nil {
+		saved.AndroidConfig = &dto.FirebaseAndroidConfig{
+			CollapseKey: android.CollapseKey,
+			Priority:    android.Priority,
+			Data:        android.Data,
+		}
+	}
+	if web != nil {
+		saved.WebpushConfig = &dto.FirebaseWebpushConfig{
+			Headers: web.Headers,
+			Data:    web.Data,
+		}
+	}
+	if ios != nil {
+		saved.APNSConfig = &dto.FirebaseAPNSConfig{
+			Headers: ios.Headers,
+		}
+	}
+	return saved
+}
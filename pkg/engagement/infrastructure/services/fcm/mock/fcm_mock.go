@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 	"github.com/savannahghi/firebasetools"
 )
 
@@ -20,6 +21,11 @@ type FakeServiceFcm struct {
 		web *firebasetools.FirebaseWebpushConfigInput,
 	) (bool, error)
 
+	SendNotificationWithOptionsFn func(
+		ctx context.Context,
+		payload dto.NotificationPayload,
+	) (bool, error)
+
 	NotificationsFn func(
 		ctx context.Context,
 		registrationToken string,
@@ -37,6 +43,66 @@ type FakeServiceFcm struct {
 		ios *firebasetools.FirebaseAPNSConfigInput,
 		web *firebasetools.FirebaseWebpushConfigInput,
 	) (bool, error)
+
+	SubscribeToTopicFn func(
+		ctx context.Context,
+		tokens []string,
+		topic string,
+	) (*domain.TopicSubscriptionResult, error)
+
+	UnsubscribeFromTopicFn func(
+		ctx context.Context,
+		tokens []string,
+		topic string,
+	) (*domain.TopicSubscriptionResult, error)
+
+	SendToTopicFn func(
+		ctx context.Context,
+		topic string,
+		condition string,
+		data map[string]string,
+		notification *firebasetools.FirebaseSimpleNotificationInput,
+		android *firebasetools.FirebaseAndroidConfigInput,
+		ios *firebasetools.FirebaseAPNSConfigInput,
+		web *firebasetools.FirebaseWebpushConfigInput,
+	) (string, error)
+
+	SubscribeUserToTopicFn func(
+		ctx context.Context,
+		uid string,
+		topic string,
+	) (*domain.TopicSubscriptionResult, error)
+
+	UnsubscribeUserFromTopicFn func(
+		ctx context.Context,
+		uid string,
+		topic string,
+	) (*domain.TopicSubscriptionResult, error)
+
+	TokenHealthFn func(
+		ctx context.Context,
+		uid string,
+	) (*domain.TokenHealthReport, error)
+
+	AcknowledgeNotificationFn func(
+		ctx context.Context,
+		uid string,
+		messageID string,
+		status domain.NotificationStatus,
+	) (bool, error)
+
+	UserNotificationsFn func(
+		ctx context.Context,
+		uid string,
+		newerThan time.Time,
+		limit int,
+	) ([]*dto.SavedNotification, error)
+
+	NotificationOpenRatesFn func(
+		ctx context.Context,
+		since time.Time,
+		until time.Time,
+	) ([]domain.NotificationOpenRate, error)
 }
 
 // SendNotification is a mock of the SendNotification method
@@ -60,6 +126,14 @@ func (f *FakeServiceFcm) SendNotification(
 	)
 }
 
+// SendNotificationWithOptions is a mock of the SendNotificationWithOptions method
+func (f *FakeServiceFcm) SendNotificationWithOptions(
+	ctx context.Context,
+	payload dto.NotificationPayload,
+) (bool, error) {
+	return f.SendNotificationWithOptionsFn(ctx, payload)
+}
+
 // Notifications is a mock of the Notifications method
 func (f *FakeServiceFcm) Notifications(
 	ctx context.Context,
@@ -97,3 +171,99 @@ func (f *FakeServiceFcm) SendFCMByPhoneOrEmail(
 		web,
 	)
 }
+
+// TokenHealth is a mock of the TokenHealth method
+func (f *FakeServiceFcm) TokenHealth(
+	ctx context.Context,
+	uid string,
+) (*domain.TokenHealthReport, error) {
+	return f.TokenHealthFn(ctx, uid)
+}
+
+// SubscribeToTopic is a mock of the SubscribeToTopic method
+func (f *FakeServiceFcm) SubscribeToTopic(
+	ctx context.Context,
+	tokens []string,
+	topic string,
+) (*domain.TopicSubscriptionResult, error) {
+	return f.SubscribeToTopicFn(ctx, tokens, topic)
+}
+
+// UnsubscribeFromTopic is a mock of the UnsubscribeFromTopic method
+func (f *FakeServiceFcm) UnsubscribeFromTopic(
+	ctx context.Context,
+	tokens []string,
+	topic string,
+) (*domain.TopicSubscriptionResult, error) {
+	return f.UnsubscribeFromTopicFn(ctx, tokens, topic)
+}
+
+// SendToTopic is a mock of the SendToTopic method
+func (f *FakeServiceFcm) SendToTopic(
+	ctx context.Context,
+	topic string,
+	condition string,
+	data map[string]string,
+	notification *firebasetools.FirebaseSimpleNotificationInput,
+	android *firebasetools.FirebaseAndroidConfigInput,
+	ios *firebasetools.FirebaseAPNSConfigInput,
+	web *firebasetools.FirebaseWebpushConfigInput,
+) (string, error) {
+	return f.SendToTopicFn(
+		ctx,
+		topic,
+		condition,
+		data,
+		notification,
+		android,
+		ios,
+		web,
+	)
+}
+
+// SubscribeUserToTopic is a mock of the SubscribeUserToTopic method
+func (f *FakeServiceFcm) SubscribeUserToTopic(
+	ctx context.Context,
+	uid string,
+	topic string,
+) (*domain.TopicSubscriptionResult, error) {
+	return f.SubscribeUserToTopicFn(ctx, uid, topic)
+}
+
+// UnsubscribeUserFromTopic is a mock of the UnsubscribeUserFromTopic method
+func (f *FakeServiceFcm) UnsubscribeUserFromTopic(
+	ctx context.Context,
+	uid string,
+	topic string,
+) (*domain.TopicSubscriptionResult, error) {
+	return f.UnsubscribeUserFromTopicFn(ctx, uid, topic)
+}
+
+// AcknowledgeNotification is a mock of the AcknowledgeNotification method
+func (f *FakeServiceFcm) AcknowledgeNotification(
+	ctx context.Context,
+	uid string,
+	messageID string,
+	status domain.NotificationStatus,
+) (bool, error) {
+	return f.AcknowledgeNotificationFn(ctx, uid, messageID, status)
+}
+
+// UserNotifications is a mock of the UserNotifications method
+func (f *FakeServiceFcm) UserNotifications(
+	ctx context.Context,
+	uid string,
+	newerThan time.Time,
+	limit int,
+) ([]*dto.SavedNotification, error) {
+	return f.UserNotificationsFn(ctx, uid, newerThan, limit)
+}
+
+// NotificationOpenRates is a mock of the NotificationOpenRates method
+func (f *FakeServiceFcm) NotificationOpenRates(
+	ctx context.Context,
+	since time.Time,
+	until time.Time,
+) ([]domain.NotificationOpenRate, error) {
+	return f.NotificationOpenRatesFn(ctx, since, until)
+}
@@ -0,0 +1,108 @@
+package fcm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"firebase.google.com/go/messaging"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+)
+
+const (
+	// multicastBatchSize is the most registration tokens that FCM accepts in
+	// one multicast message
+	multicastBatchSize = 500
+
+	// multicastParallelism is the most multicast batches that are sent at the
+	// same time
+	multicastParallelism = 4
+)
+
+// multicastSender sends a multicast message e.g `messaging.Client.SendMulticast`
+type multicastSender func(
+	ctx context.Context,
+	message *messaging.MulticastMessage,
+) (*messaging.BatchResponse, error)
+
+// multicastBatch is the result of sending a message to one batch of tokens
+type multicastBatch struct {
+	tokens   []string
+	response *messaging.BatchResponse
+
+	// the failure of the whole request, in which case none of the tokens
+	// were sent to
+	err error
+}
+
+// sendMulticast sends a message to the tokens in FCM sized batches, a few at
+// a time. The batches are returned in the order of the tokens.
+func sendMulticast(
+	ctx context.Context,
+	send multicastSender,
+	content *messageContent,
+	tokens []string,
+	batchSize int,
+	parallelism int,
+) []multicastBatch {
+	batches := []multicastBatch{}
+	for _, batch := range tokenBatches(tokens, batchSize) {
+		batches = append(batches, multicastBatch{tokens: batch})
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallelism)
+	for idx := range batches {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(batch *multicastBatch) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			batch.response, batch.err = send(ctx, content.multicast(batch.tokens))
+			if batch.err == nil && batch.response == nil {
+				batch.err = fmt.Errorf("no response from FCM")
+			}
+		}(&batches[idx])
+	}
+	wg.Wait()
+	return batches
+}
+
+// multicastOutcomes pairs every token that a message was sent to with the
+// response it got. The tokens of a batch whose request failed as a whole
+// share that failure, which is not held against them, and tokens that FCM
+// did not return a response for get a failed one.
+func multicastOutcomes(
+	batches []multicastBatch,
+) ([]tokenOutcome, []*messaging.SendResponse) {
+	outcomes := []tokenOutcome{}
+	responses := []*messaging.SendResponse{}
+	for _, batch := range batches {
+		if batch.err != nil {
+			err := fmt.Errorf("unable to send FCM messages: %w", batch.err)
+			for _, token := range batch.tokens {
+				outcomes = append(outcomes, tokenOutcome{
+					token: token,
+					code:  domain.TokenErrorOther,
+					err:   err,
+				})
+				responses = append(responses, &messaging.SendResponse{Error: err})
+			}
+			continue
+		}
+		batchResponses := batch.response.Responses
+		outcomes = append(outcomes, batchOutcomes(batch.tokens, batchResponses)...)
+		if len(batchResponses) > len(batch.tokens) {
+			batchResponses = batchResponses[:len(batch.tokens)]
+		}
+		responses = append(responses, batchResponses...)
+		for range batch.tokens[len(batchResponses):] {
+			responses = append(responses, &messaging.SendResponse{
+				Error: errMissingResponse,
+			})
+		}
+	}
+	return outcomes, responses
+}
@@ -0,0 +1,107 @@
+package fcm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/messaging"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendMulticast(t *testing.T) {
+	ctx := context.Background()
+	tokens := []string{}
+	for i := 0; i < 23; i++ {
+		tokens = append(tokens, fmt.Sprintf("token-%d", i))
+	}
+
+	var mu sync.Mutex
+	inFlight, mostInFlight := 0, 0
+	send := func(
+		ctx context.Context,
+		message *messaging.MulticastMessage,
+	) (*messaging.BatchResponse, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > mostInFlight {
+			mostInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		assert.LessOrEqual(t, len(message.Tokens), 5)
+		if message.Tokens[0] == "token-10" {
+			return nil, fmt.Errorf("server unavailable")
+		}
+		resp := &messaging.BatchResponse{}
+		for _, token := range message.Tokens {
+			if token == "token-18" {
+				// FCM leaves the rest of the batch out
+				break
+			}
+			if token == "token-3" {
+				resp.Responses = append(resp.Responses, &messaging.SendResponse{
+					Error: fmt.Errorf("internal error"),
+				})
+				resp.FailureCount++
+				continue
+			}
+			resp.Responses = append(resp.Responses, &messaging.SendResponse{
+				Success:   true,
+				MessageID: "id-" + token,
+			})
+			resp.SuccessCount++
+		}
+		return resp, nil
+	}
+
+	content, err := NotificationBuilder{Data: map[string]string{"item": "1"}}.build()
+	assert.Nil(t, err)
+
+	batches := sendMulticast(ctx, send, content, tokens, 5, 2)
+	assert.Len(t, batches, 5)
+	assert.Equal(t, 2, mostInFlight, "no more than two batches are sent at a time")
+
+	outcomes, responses := multicastOutcomes(batches)
+	assert.Len(t, outcomes, len(tokens))
+	assert.Len(t, responses, len(tokens))
+	for idx, outcome := range outcomes {
+		assert.Equal(t, tokens[idx], outcome.token, "outcomes are in the order of the tokens")
+	}
+	assert.Equal(t, "id-token-0", responses[0].MessageID)
+	assert.NotNil(t, outcomes[3].err)
+	for idx := 10; idx < 15; idx++ {
+		assert.False(t, responses[idx].Success)
+		assert.Equal(t, domain.TokenErrorOther, outcomes[idx].code)
+		assert.Contains(t, outcomes[idx].err.Error(), "server unavailable")
+	}
+	for idx := 18; idx < 20; idx++ {
+		assert.False(t, responses[idx].Success)
+		assert.Equal(t, domain.TokenErrorOther, outcomes[idx].code)
+		assert.Equal(t, errMissingResponse, outcomes[idx].err)
+	}
+	assert.True(t, responses[22].Success)
+}
+
+func TestSendErrorSummary(t *testing.T) {
+	sendErr := &SendError{Sent: 4}
+	for i := 0; i < maxReportedFailures+3; i++ {
+		sendErr.Failures = append(sendErr.Failures, TokenFailure{
+			Token: fmt.Sprintf("token-%d", i),
+			Err:   fmt.Errorf("server unavailable"),
+		})
+	}
+	assert.True(t, sendErr.Partial())
+	assert.Contains(t, sendErr.Error(), "; and 3 more")
+	assert.NotContains(t, sendErr.Error(), fmt.Sprintf("token-%d", maxReportedFailures))
+	assert.Len(t, sendErr.Tokens(), maxReportedFailures+3)
+
+	assert.False(t, (&SendError{}).Partial())
+}
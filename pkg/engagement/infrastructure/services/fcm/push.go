@@ -8,6 +8,7 @@ import (
 
 	"cloud.google.com/go/pubsub"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
 	"github.com/savannahghi/firebasetools"
 	"github.com/savannahghi/pubsubtools"
@@ -76,7 +77,16 @@ func (rfs RemotePushService) Push(
 	defer span.End()
 	rfs.checkPreconditions()
 	env := serverutils.GetRunningEnvironment()
-	payload, err := json.Marshal(notificationPayload)
+	// the feed screens its notifications before pushing them, so they are
+	// not screened again when they are sent
+	transactional := true
+	payload, err := json.Marshal(dto.NotificationPayload{
+		SendNotificationPayload: notificationPayload,
+		NotificationOptions: dto.NotificationOptions{
+			SenderType:    sender,
+			Transactional: &transactional,
+		},
+	})
 	if err != nil {
 		helpers.RecordSpanError(span, err)
 		return fmt.Errorf("can't marshal notification payload: %w", err)
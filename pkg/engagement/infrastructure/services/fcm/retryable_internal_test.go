@@ -0,0 +1,79 @@
+package fcm
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	firebase "firebase.google.com/go"
+	"firebase.google.com/go/messaging"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+)
+
+// errorTransport answers every request with an FCM error
+type errorTransport struct {
+	status int
+	body   string
+}
+
+func (e errorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: e.status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(e.body)),
+		Request:    req,
+	}, nil
+}
+
+// fcmError gets the error that the FCM client returns for a failed send. The
+// client sends again on some HTTP statuses so the code is what varies.
+func fcmError(t *testing.T, code string, fcmCode string) error {
+	ctx := context.Background()
+	details := ""
+	if fcmCode != "" {
+		details = fmt.Sprintf(
+			`, "details": [{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": %q}]`,
+			fcmCode,
+		)
+	}
+	transport := errorTransport{
+		status: http.StatusBadRequest,
+		body:   fmt.Sprintf(`{"error": {"status": %q, "message": "failed"%s}}`, code, details),
+	}
+	app, err := firebase.NewApp(
+		ctx,
+		&firebase.Config{ProjectID: "test"},
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+	)
+	assert.Nil(t, err)
+	client, err := app.Messaging(ctx)
+	assert.Nil(t, err)
+	_, err = client.Send(ctx, &messaging.Message{Token: "token"})
+	assert.NotNil(t, err)
+	return err
+}
+
+func TestRetryable(t *testing.T) {
+	unavailable := fcmError(t, "UNAVAILABLE", "UNAVAILABLE")
+	internal := fcmError(t, "INTERNAL", "INTERNAL")
+	quota := fcmError(t, "RESOURCE_EXHAUSTED", "QUOTA_EXCEEDED")
+	unregistered := fcmError(t, "NOT_FOUND", "UNREGISTERED")
+	invalid := fcmError(t, "INVALID_ARGUMENT", "INVALID_ARGUMENT")
+	mismatched := fcmError(t, "PERMISSION_DENIED", "SENDER_ID_MISMATCH")
+
+	assert.True(t, messaging.IsServerUnavailable(unavailable))
+	assert.True(t, Retryable(unavailable))
+	assert.True(t, Retryable(internal))
+	assert.True(t, Retryable(quota))
+	assert.True(t, Retryable(fmt.Errorf("wrapped: %w", quota)))
+	assert.True(t, Retryable(fmt.Errorf("connection reset")), "errors without an FCM code are retried")
+
+	assert.False(t, Retryable(unregistered))
+	assert.False(t, Retryable(invalid))
+	assert.False(t, Retryable(mismatched))
+	assert.False(t, Retryable(fmt.Errorf("wrapped: %w", invalid)))
+}
@@ -4,15 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"firebase.google.com/go/messaging"
-	"github.com/google/uuid"
 	"github.com/savannahghi/converterandformatter"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
 	"github.com/savannahghi/firebasetools"
@@ -72,6 +71,11 @@ type ServiceFCM interface {
 		web *firebasetools.FirebaseWebpushConfigInput,
 	) (bool, error)
 
+	SendNotificationWithOptions(
+		ctx context.Context,
+		payload dto.NotificationPayload,
+	) (bool, error)
+
 	Notifications(
 		ctx context.Context,
 		registrationToken string,
@@ -89,6 +93,29 @@ type ServiceFCM interface {
 		ios *firebasetools.FirebaseAPNSConfigInput,
 		web *firebasetools.FirebaseWebpushConfigInput,
 	) (bool, error)
+
+	SubscribeToTopic(
+		ctx context.Context,
+		tokens []string,
+		topic string,
+	) (*domain.TopicSubscriptionResult, error)
+
+	UnsubscribeFromTopic(
+		ctx context.Context,
+		tokens []string,
+		topic string,
+	) (*domain.TopicSubscriptionResult, error)
+
+	SendToTopic(
+		ctx context.Context,
+		topic string,
+		condition string,
+		data map[string]string,
+		notification *firebasetools.FirebaseSimpleNotificationInput,
+		android *firebasetools.FirebaseAndroidConfigInput,
+		ios *firebasetools.FirebaseAPNSConfigInput,
+		web *firebasetools.FirebaseWebpushConfigInput,
+	) (string, error)
 }
 
 // NewService initializes a service to interact with Firebase Cloud Messaging
@@ -149,8 +176,23 @@ func (s ServiceFCMImpl) checkPreconditions() {
 // `Urgency` header e.g "Urgency": "high". For iOS, the "apns-priority" header
 // is used, with "5" for normal/low and "10" to mean urgent/high.
 //
-// The callers of this method should implement retries and exponential backoff,
-// if necessary.
+// The tokens are sent to in batches of 500, the most that FCM accepts in one
+// multicast message, with a few batches in flight at a time.
+//
+// Tokens that could not be sent to for other reasons, including those of a
+// batch that failed as a whole, are returned in a `*SendError` so that
+// callers can retry just those tokens. The send partly succeeded when the
+// message still reached some tokens, in which case `true` is returned along
+// with the error.
+//
+// Tokens that FCM reports as unregistered, or that keep failing as invalid
+// arguments, are recorded as invalid and are no longer sent to. Their failures
+// are not returned as errors since retrying them will not help. When none of
+// the tokens can be delivered to, `ErrNoValidTokens` is returned.
+//
+// Messages that come in over the FCM Pub/Sub topic are retried with
+// exponential backoff by the notification usecases. Other callers of this
+// method should implement retries and exponential backoff, if necessary.
 func (s ServiceFCMImpl) SendNotification(
 	ctx context.Context,
 	registrationTokens []string,
@@ -164,114 +206,115 @@ func (s ServiceFCMImpl) SendNotification(
 	defer span.End()
 	s.checkPreconditions()
 
+	return s.sendNotification(ctx, registrationTokens, NotificationBuilder{
+		Data:         data,
+		Notification: notification,
+		Android:      android,
+		Ios:          ios,
+		Web:          web,
+	})
+}
+
+// SendNotificationWithOptions sends a notification like `SendNotification`,
+// with the APNs `aps` payload and the Android options that the firebasetools
+// inputs can't carry
+func (s ServiceFCMImpl) SendNotificationWithOptions(
+	ctx context.Context,
+	payload dto.NotificationPayload,
+) (bool, error) {
+	ctx, span := tracer.Start(ctx, "SendNotificationWithOptions")
+	defer span.End()
+	s.checkPreconditions()
+
+	return s.sendNotification(
+		ctx,
+		payload.RegistrationTokens,
+		NewNotificationBuilder(payload),
+	)
+}
+
+func (s ServiceFCMImpl) sendNotification(
+	ctx context.Context,
+	registrationTokens []string,
+	builder NotificationBuilder,
+) (bool, error) {
+	ctx, span := tracer.Start(ctx, "sendNotification")
+	defer span.End()
+
 	if registrationTokens == nil {
 		return false, fmt.Errorf("can't send FCM notifications to nil registration tokens")
 	}
 
-	message := &messaging.MulticastMessage{Tokens: registrationTokens}
-
-	if data != nil {
-		err := ValidateFCMData(data)
-		if err != nil {
-			helpers.RecordSpanError(span, err)
-			return false, err
-		}
-		message.Data = data
-	}
-	if notification != nil {
-		message.Notification = &messaging.Notification{
-			Title: notification.Title,
-			Body:  notification.Body,
-		}
-		if notification.ImageURL != nil {
-			message.Notification.ImageURL = *notification.ImageURL
-		}
-	}
-	if android != nil {
-		message.Android = &messaging.AndroidConfig{
-			Priority: android.Priority,
-			Data:     converterandformatter.ConvertInterfaceMap(android.Data),
-		}
-		if android.CollapseKey != nil {
-			message.Android.CollapseKey = *android.CollapseKey
-		}
-		if android.RestrictedPackageName != nil {
-			message.Android.RestrictedPackageName = *android.RestrictedPackageName
-		}
-	}
-	if web != nil {
-		message.Webpush = &messaging.WebpushConfig{
-			Headers: converterandformatter.ConvertInterfaceMap(web.Headers),
-			Data:    converterandformatter.ConvertInterfaceMap(web.Data),
-		}
+	health, err := s.Repository.GetTokenHealth(ctx, registrationTokens)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		log.Printf("unable to get the health of registration tokens: %v", err)
+		health = map[string]domain.TokenHealth{}
 	}
-	if ios != nil {
-		message.APNS = &messaging.APNSConfig{
-			Headers: converterandformatter.ConvertInterfaceMap(web.Headers),
-		}
+	tokens := usableTokens(registrationTokens, health)
+	if len(registrationTokens) > 0 && len(tokens) == 0 {
+		return false, ErrNoValidTokens
 	}
 
-	batchResp, err := s.fcmClient.SendMulticast(ctx, message)
+	content, err := builder.build()
 	if err != nil {
 		helpers.RecordSpanError(span, err)
-		return false, fmt.Errorf("unable to send FCM messages: %w", err)
+		return false, err
 	}
 
-	var errorMessages []string
-	for idx, resp := range batchResp.Responses {
-		if !resp.Success {
-			// The order of responses corresponds to the order of the registration tokens.
-			msg := fmt.Sprintf(
-				"fcm: failed to send message to %s: %v",
-				registrationTokens[idx],
-				resp.Error,
-			)
-			errorMessages = append(errorMessages, msg)
+	batches := sendMulticast(
+		ctx,
+		s.fcmClient.SendMulticast,
+		content,
+		tokens,
+		multicastBatchSize,
+		multicastParallelism,
+	)
+
+	// The order of outcomes and responses corresponds to the order of the
+	// registration tokens.
+	outcomes, responses := multicastOutcomes(batches)
+	sendErr := &SendError{}
+	for idx, resp := range responses {
+		if resp.Success {
+			sendErr.Sent++
 		}
-		if notification != nil {
-			savedNotification := dto.SavedNotification{
-				ID:                uuid.New().String(),
-				RegistrationToken: registrationTokens[idx],
-				MessageID:         resp.MessageID,
-				Timestamp:         time.Now(),
-			}
-			if notification != nil {
-				savedNotification.Notification = &dto.FirebaseSimpleNotification{
-					Title:    notification.Title,
-					Body:     notification.Body,
-					ImageURL: notification.ImageURL,
-				}
-			}
-			if data != nil {
-				savedNotification.Data = converterandformatter.ConvertStringMap(data)
-			}
-			if android != nil {
-				savedNotification.AndroidConfig = &dto.FirebaseAndroidConfig{
-					CollapseKey: android.CollapseKey,
-					Priority:    android.Priority,
-					Data:        android.Data,
-				}
-			}
-			if web != nil {
-				savedNotification.WebpushConfig = &dto.FirebaseWebpushConfig{
-					Headers: web.Headers,
-					Data:    web.Data,
-				}
-			}
-			if ios != nil {
-				savedNotification.APNSConfig = &dto.FirebaseAPNSConfig{
-					Headers: ios.Headers,
-				}
+		if !resp.Success && outcomes[idx].code == domain.TokenErrorOther {
+			sendErr.Failures = append(sendErr.Failures, TokenFailure{
+				Token: outcomes[idx].token,
+				Code:  outcomes[idx].code,
+				Err:   outcomes[idx].err,
+			})
+		}
+		if builder.Notification != nil {
+			saved := savedNotification(
+				builder.Options.SenderType,
+				builder.Data,
+				builder.Notification,
+				builder.Android,
+				builder.Ios,
+				builder.Web,
+			)
+			saved.RegistrationToken = outcomes[idx].token
+			saved.MessageID = resp.MessageID
+			if !resp.Success {
+				saved.Status = domain.NotificationStatusFailed
 			}
-			err = s.Repository.SaveNotification(ctx, s.firestoreClient, savedNotification)
+			err = s.Repository.SaveNotification(ctx, s.firestoreClient, saved)
 			if err != nil {
 				helpers.RecordSpanError(span, err)
 				log.Printf("unable to save notification: %v", err)
 			}
 		}
 	}
-	if len(errorMessages) > 0 {
-		return false, fmt.Errorf(strings.Join(errorMessages, "; "))
+	recordTokenOutcomes(ctx, s.Repository, health, outcomes)
+
+	if len(sendErr.Failures) > 0 {
+		helpers.RecordSpanError(span, sendErr)
+		return sendErr.Partial(), sendErr
+	}
+	if sendErr.Sent == 0 && len(tokens) > 0 {
+		return false, ErrNoValidTokens
 	}
 	return true, nil
 }
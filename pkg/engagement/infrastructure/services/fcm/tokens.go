@@ -0,0 +1,240 @@
+package fcm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"firebase.google.com/go/messaging"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database"
+)
+
+// invalidArgumentFailureLimit is the number of invalid argument failures in a
+// row after which a token is no longer sent to
+const invalidArgumentFailureLimit = 3
+
+// ErrNoValidTokens is returned when nothing was sent because none of the
+// registration tokens can be delivered to. Retrying will not help.
+var ErrNoValidTokens = errors.New("none of the registration tokens is valid")
+
+// errMissingResponse is the failure of a token that FCM did not return a
+// response for
+var errMissingResponse = errors.New("FCM did not return a response for the token")
+
+// TokenFailure is a failure to send a message to one registration token
+type TokenFailure struct {
+	Token string
+	Code  domain.TokenErrorCode
+	Err   error
+}
+
+// maxReportedFailures is the most token failures spelt out in the text of a
+// `SendError`
+const maxReportedFailures = 10
+
+// SendError lists the registration tokens that a message could not be sent
+// to for reasons other than the tokens being invalid. Sending to them again
+// may succeed.
+type SendError struct {
+	Failures []TokenFailure
+
+	// the number of tokens that the message was sent to. When it is more than
+	// zero, the send partly succeeded.
+	Sent int
+}
+
+func (e *SendError) Error() string {
+	messages := []string{}
+	for idx, failure := range e.Failures {
+		if idx == maxReportedFailures {
+			messages = append(messages, fmt.Sprintf(
+				"and %d more", len(e.Failures)-maxReportedFailures))
+			break
+		}
+		messages = append(messages, fmt.Sprintf(
+			"fcm: failed to send message to %s: %v", failure.Token, failure.Err))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Partial reports whether the message reached some of the tokens
+func (e *SendError) Partial() bool {
+	return e.Sent > 0
+}
+
+// Tokens returns the registration tokens that the message was not sent to
+func (e *SendError) Tokens() []string {
+	tokens := []string{}
+	for _, failure := range e.Failures {
+		tokens = append(tokens, failure.Token)
+	}
+	return tokens
+}
+
+// Retryable reports whether sending a message again may get past an error.
+// Only FCM's unavailable, internal and quota errors clear up by themselves;
+// errors with any other FCM code are permanent. Errors without an FCM code
+// e.g network failures are retryable.
+func Retryable(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch {
+		case messaging.IsServerUnavailable(err),
+			messaging.IsInternal(err),
+			messaging.IsMessageRateExceeded(err):
+			return true
+		case messaging.IsInvalidArgument(err),
+			messaging.IsRegistrationTokenNotRegistered(err),
+			messaging.IsMismatchedCredential(err),
+			messaging.IsInvalidAPNSCredentials(err),
+			messaging.IsTooManyTopics(err),
+			messaging.IsUnknown(err):
+			return false
+		}
+	}
+	return true
+}
+
+// classifyTokenError tells token failures apart from other failures
+func classifyTokenError(err error) domain.TokenErrorCode {
+	switch {
+	case messaging.IsRegistrationTokenNotRegistered(err):
+		return domain.TokenErrorUnregistered
+	case messaging.IsInvalidArgument(err):
+		return domain.TokenErrorInvalidArgument
+	default:
+		return domain.TokenErrorOther
+	}
+}
+
+// tokenOutcome is the result of sending a message to one token
+type tokenOutcome struct {
+	token string
+	code  domain.TokenErrorCode
+	err   error
+}
+
+// batchOutcomes pairs the multicast responses with the tokens they were sent
+// to, in order. Tokens that did not get a response have failed.
+//
+// FCM uses invalid argument both for malformed tokens and invalid messages.
+// When every token of a batch fails with it, the message is to blame and the
+// failures are not held against the tokens.
+func batchOutcomes(
+	tokens []string,
+	responses []*messaging.SendResponse,
+) []tokenOutcome {
+	outcomes := []tokenOutcome{}
+	invalidArguments := 0
+	for idx, resp := range responses {
+		if idx >= len(tokens) {
+			break
+		}
+		outcome := tokenOutcome{token: tokens[idx]}
+		if !resp.Success {
+			outcome.err = resp.Error
+			outcome.code = classifyTokenError(resp.Error)
+			if outcome.code == domain.TokenErrorInvalidArgument {
+				invalidArguments++
+			}
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	if len(outcomes) > 1 && invalidArguments == len(outcomes) {
+		for idx := range outcomes {
+			outcomes[idx].code = domain.TokenErrorOther
+		}
+	}
+	for _, token := range tokens[len(outcomes):] {
+		outcomes = append(outcomes, tokenOutcome{
+			token: token,
+			code:  domain.TokenErrorOther,
+			err:   errMissingResponse,
+		})
+	}
+	return outcomes
+}
+
+// usableTokens leaves out the tokens that have been found to be invalid
+func usableTokens(
+	tokens []string,
+	health map[string]domain.TokenHealth,
+) []string {
+	usable := []string{}
+	for _, token := range tokens {
+		if health[token].Invalid {
+			continue
+		}
+		usable = append(usable, token)
+	}
+	return usable
+}
+
+// nextTokenHealth updates a token's delivery record with the result of a
+// send. It reports false when the record does not need to be saved.
+func nextTokenHealth(
+	current domain.TokenHealth,
+	outcome tokenOutcome,
+	now time.Time,
+) (domain.TokenHealth, bool) {
+	next := current
+	next.Token = outcome.token
+
+	if outcome.err == nil {
+		if current.ConsecutiveFailures == 0 {
+			return current, false
+		}
+		next.ConsecutiveFailures = 0
+		next.LastSuccessAt = &now
+		return next, true
+	}
+
+	next.Failures++
+	next.ConsecutiveFailures++
+	next.LastErrorCode = outcome.code
+	next.LastError = outcome.err.Error()
+	next.LastFailureAt = now
+
+	invalid := outcome.code == domain.TokenErrorUnregistered ||
+		(outcome.code == domain.TokenErrorInvalidArgument &&
+			next.ConsecutiveFailures >= invalidArgumentFailureLimit)
+	if invalid && !next.Invalid {
+		next.Invalid = true
+		next.InvalidatedAt = &now
+	}
+	return next, true
+}
+
+// recordTokenOutcomes saves the delivery records of the tokens that failed,
+// or that recovered from earlier failures. Recording is best effort and does
+// not fail the send.
+func recordTokenOutcomes(
+	ctx context.Context,
+	repository database.Repository,
+	health map[string]domain.TokenHealth,
+	outcomes []tokenOutcome,
+) {
+	now := time.Now()
+	for _, outcome := range outcomes {
+		next, changed := nextTokenHealth(health[outcome.token], outcome, now)
+		if !changed {
+			continue
+		}
+		if next.Invalid && !health[outcome.token].Invalid {
+			log.Printf(
+				"fcm: token %s is no longer valid (%s) and will not be sent to",
+				outcome.token,
+				outcome.code,
+			)
+		}
+		err := repository.SaveTokenHealth(ctx, next)
+		if err != nil {
+			log.Printf("unable to record the health of token %s: %v", outcome.token, err)
+			continue
+		}
+		health[outcome.token] = next
+	}
+}
@@ -0,0 +1,207 @@
+package fcm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/messaging"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchOutcomes(t *testing.T) {
+	failed := &messaging.SendResponse{Error: fmt.Errorf("server unavailable")}
+	ok := &messaging.SendResponse{Success: true, MessageID: "id"}
+
+	outcomes := batchOutcomes(
+		[]string{"a", "b"},
+		[]*messaging.SendResponse{ok, failed, failed},
+	)
+	assert.Len(t, outcomes, 2, "responses without a token are ignored")
+	assert.Equal(t, "a", outcomes[0].token)
+	assert.Nil(t, outcomes[0].err)
+	assert.Equal(t, "b", outcomes[1].token)
+	assert.Equal(t, domain.TokenErrorOther, outcomes[1].code)
+	assert.NotNil(t, outcomes[1].err)
+
+	invalid := &messaging.SendResponse{Error: fcmError(t, "INVALID_ARGUMENT", "INVALID_ARGUMENT")}
+	outcomes = batchOutcomes(
+		[]string{"a", "b", "c"},
+		[]*messaging.SendResponse{invalid},
+	)
+	assert.Len(t, outcomes, 3, "tokens without a response have failed")
+	assert.Equal(t, domain.TokenErrorInvalidArgument, outcomes[0].code)
+	for _, outcome := range outcomes[1:] {
+		assert.Equal(t, domain.TokenErrorOther, outcome.code)
+		assert.Equal(t, errMissingResponse, outcome.err)
+	}
+}
+
+func TestUsableTokens(t *testing.T) {
+	health := map[string]domain.TokenHealth{
+		"dead":    {Token: "dead", Invalid: true},
+		"failing": {Token: "failing", ConsecutiveFailures: 2},
+	}
+	assert.Equal(
+		t,
+		[]string{"ok", "failing"},
+		usableTokens([]string{"ok", "dead", "failing"}, health),
+	)
+	assert.Empty(t, usableTokens([]string{"dead"}, health))
+}
+
+func TestNextTokenHealth(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	failure := fmt.Errorf("failed")
+
+	tests := []struct {
+		name        string
+		current     domain.TokenHealth
+		outcome     tokenOutcome
+		wantChanged bool
+		wantInvalid bool
+		wantStreak  int
+	}{
+		{
+			name:    "a success without earlier failures is not recorded",
+			outcome: tokenOutcome{token: "t"},
+		},
+		{
+			name:        "a success ends a failure streak",
+			current:     domain.TokenHealth{Token: "t", Failures: 2, ConsecutiveFailures: 2},
+			outcome:     tokenOutcome{token: "t"},
+			wantChanged: true,
+		},
+		{
+			name:        "an unregistered token is invalid at once",
+			outcome:     tokenOutcome{token: "t", code: domain.TokenErrorUnregistered, err: failure},
+			wantChanged: true,
+			wantInvalid: true,
+			wantStreak:  1,
+		},
+		{
+			name:        "an invalid argument is tolerated at first",
+			outcome:     tokenOutcome{token: "t", code: domain.TokenErrorInvalidArgument, err: failure},
+			wantChanged: true,
+			wantStreak:  1,
+		},
+		{
+			name: "repeated invalid arguments make a token invalid",
+			current: domain.TokenHealth{
+				Token:               "t",
+				Failures:            invalidArgumentFailureLimit - 1,
+				ConsecutiveFailures: invalidArgumentFailureLimit - 1,
+			},
+			outcome:     tokenOutcome{token: "t", code: domain.TokenErrorInvalidArgument, err: failure},
+			wantChanged: true,
+			wantInvalid: true,
+			wantStreak:  invalidArgumentFailureLimit,
+		},
+		{
+			name: "other failures never make a token invalid",
+			current: domain.TokenHealth{
+				Token:               "t",
+				Failures:            10,
+				ConsecutiveFailures: 10,
+			},
+			outcome:     tokenOutcome{token: "t", code: domain.TokenErrorOther, err: failure},
+			wantChanged: true,
+			wantStreak:  11,
+		},
+		{
+			name: "an invalid token keeps its invalidation time",
+			current: domain.TokenHealth{
+				Token:               "t",
+				Failures:            1,
+				ConsecutiveFailures: 1,
+				Invalid:             true,
+				InvalidatedAt:       &earlier,
+			},
+			outcome:     tokenOutcome{token: "t", code: domain.TokenErrorUnregistered, err: failure},
+			wantChanged: true,
+			wantInvalid: true,
+			wantStreak:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, changed := nextTokenHealth(tt.current, tt.outcome, now)
+			assert.Equal(t, tt.wantChanged, changed)
+			if !changed {
+				return
+			}
+			assert.Equal(t, "t", next.Token)
+			assert.Equal(t, tt.wantInvalid, next.Invalid)
+			assert.Equal(t, tt.wantStreak, next.ConsecutiveFailures)
+			if tt.outcome.err == nil {
+				assert.Equal(t, tt.current.Failures, next.Failures)
+				assert.Equal(t, &now, next.LastSuccessAt)
+				return
+			}
+			assert.Equal(t, tt.current.Failures+1, next.Failures)
+			assert.Equal(t, tt.outcome.code, next.LastErrorCode)
+			assert.Equal(t, "failed", next.LastError)
+			assert.Equal(t, now, next.LastFailureAt)
+			if tt.wantInvalid {
+				assert.NotNil(t, next.InvalidatedAt)
+				if tt.current.InvalidatedAt != nil {
+					assert.Equal(t, tt.current.InvalidatedAt, next.InvalidatedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordTokenOutcomes(t *testing.T) {
+	ctx := context.Background()
+	saved := map[string]domain.TokenHealth{}
+	repository := &mock.FakeEngagementRepository{
+		SaveTokenHealthFn: func(
+			ctx context.Context,
+			health domain.TokenHealth,
+		) error {
+			if health.Token == "unsaveable" {
+				return fmt.Errorf("unable to save")
+			}
+			saved[health.Token] = health
+			return nil
+		},
+	}
+	health := map[string]domain.TokenHealth{
+		"recovered": {Token: "recovered", Failures: 1, ConsecutiveFailures: 1},
+	}
+
+	recordTokenOutcomes(ctx, repository, health, []tokenOutcome{
+		{token: "ok"},
+		{token: "recovered"},
+		{token: "dead", code: domain.TokenErrorUnregistered, err: fmt.Errorf("gone")},
+		{token: "unsaveable", code: domain.TokenErrorOther, err: fmt.Errorf("busy")},
+	})
+
+	assert.Len(t, saved, 2)
+	assert.Equal(t, 0, saved["recovered"].ConsecutiveFailures)
+	assert.True(t, saved["dead"].Invalid)
+	assert.Equal(t, saved["dead"], health["dead"], "the cached health is kept up to date")
+	_, ok := health["unsaveable"]
+	assert.False(t, ok, "health that could not be saved is not cached")
+}
+
+func TestSendError(t *testing.T) {
+	sendErr := &SendError{Failures: []TokenFailure{
+		{Token: "a", Code: domain.TokenErrorOther, Err: fmt.Errorf("server unavailable")},
+		{Token: "b", Code: domain.TokenErrorOther, Err: fmt.Errorf("internal error")},
+	}}
+	assert.Equal(
+		t,
+		"fcm: failed to send message to a: server unavailable; "+
+			"fcm: failed to send message to b: internal error",
+		sendErr.Error(),
+	)
+	assert.Equal(t, []string{"a", "b"}, sendErr.Tokens())
+	assert.True(t, Retryable(sendErr), "errors without an FCM code are retried")
+	assert.True(t, Retryable(fmt.Errorf("unable to send FCM messages: %w", sendErr)))
+}
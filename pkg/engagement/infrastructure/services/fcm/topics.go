@@ -0,0 +1,237 @@
+package fcm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+
+	"firebase.google.com/go/messaging"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/firebasetools"
+)
+
+// topicManagementBatchSize is the most registration tokens that FCM
+// subscribes to, or unsubscribes from, a topic in one request
+const topicManagementBatchSize = 1000
+
+// maxConditionTopics is the most topics that an FCM condition can combine
+const maxConditionTopics = 5
+
+var (
+	// topicPattern matches the topic names that FCM accepts
+	topicPattern = regexp.MustCompile(`^[a-zA-Z0-9-_.~%]+$`)
+
+	// conditionTopicPattern picks the topics out of a condition expression
+	// e.g `'county-nairobi' in topics && !('staff' in topics)`
+	conditionTopicPattern = regexp.MustCompile(`'([^']*)'\s+in\s+topics`)
+)
+
+// ValidateTopic checks that a topic name can be used with FCM
+func ValidateTopic(topic string) error {
+	if !topicPattern.MatchString(topic) {
+		return fmt.Errorf(
+			"invalid topic `%s`: only letters, digits and `-_.~%%` are allowed", topic)
+	}
+	return nil
+}
+
+// ValidateCondition checks that an FCM condition expression names between one
+// and five valid topics
+func ValidateCondition(condition string) error {
+	matches := conditionTopicPattern.FindAllStringSubmatch(condition, -1)
+	if len(matches) == 0 {
+		return fmt.Errorf(
+			"invalid condition `%s`: expected topics like `'news' in topics`", condition)
+	}
+	if len(matches) > maxConditionTopics {
+		return fmt.Errorf(
+			"invalid condition `%s`: at most %d topics can be combined",
+			condition,
+			maxConditionTopics,
+		)
+	}
+	for _, match := range matches {
+		err := ValidateTopic(match[1])
+		if err != nil {
+			return fmt.Errorf("invalid condition `%s`: %w", condition, err)
+		}
+	}
+	return nil
+}
+
+// subscriptionResult tallies the responses to topic management requests
+func subscriptionResult(
+	topic string,
+	batches [][]string,
+	responses []*messaging.TopicManagementResponse,
+) *domain.TopicSubscriptionResult {
+	result := &domain.TopicSubscriptionResult{
+		Topic:  topic,
+		Errors: []string{},
+	}
+	for idx, resp := range responses {
+		if resp == nil || idx >= len(batches) {
+			continue
+		}
+		result.Succeeded += resp.SuccessCount
+		result.Failed += resp.FailureCount
+		for _, info := range resp.Errors {
+			token := ""
+			if info.Index >= 0 && info.Index < len(batches[idx]) {
+				token = batches[idx][info.Index]
+			}
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", token, info.Reason))
+		}
+	}
+	return result
+}
+
+// tokenBatches splits registration tokens into batches of at most `size`
+func tokenBatches(tokens []string, size int) [][]string {
+	batches := [][]string{}
+	for start := 0; start < len(tokens); start += size {
+		end := start + size
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		batches = append(batches, tokens[start:end])
+	}
+	return batches
+}
+
+// manageTopic subscribes registration tokens to a topic, or unsubscribes them
+// from it. Tokens that are known to be invalid are left out.
+func (s ServiceFCMImpl) manageTopic(
+	ctx context.Context,
+	tokens []string,
+	topic string,
+	subscribe bool,
+) (*domain.TopicSubscriptionResult, error) {
+	err := ValidateTopic(topic)
+	if err != nil {
+		return nil, err
+	}
+	health, err := s.Repository.GetTokenHealth(ctx, tokens)
+	if err != nil {
+		log.Printf("unable to get the health of registration tokens: %v", err)
+		health = map[string]domain.TokenHealth{}
+	}
+
+	batches := tokenBatches(usableTokens(tokens, health), topicManagementBatchSize)
+	responses := []*messaging.TopicManagementResponse{}
+	for _, batch := range batches {
+		var resp *messaging.TopicManagementResponse
+		if subscribe {
+			resp, err = s.fcmClient.SubscribeToTopic(ctx, batch, topic)
+		} else {
+			resp, err = s.fcmClient.UnsubscribeFromTopic(ctx, batch, topic)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to manage subscriptions to topic %s: %w", topic, err)
+		}
+		responses = append(responses, resp)
+	}
+	return subscriptionResult(topic, batches, responses), nil
+}
+
+// SubscribeToTopic subscribes registration tokens to an FCM topic
+func (s ServiceFCMImpl) SubscribeToTopic(
+	ctx context.Context,
+	tokens []string,
+	topic string,
+) (*domain.TopicSubscriptionResult, error) {
+	ctx, span := tracer.Start(ctx, "SubscribeToTopic")
+	defer span.End()
+	s.checkPreconditions()
+
+	result, err := s.manageTopic(ctx, tokens, topic, true)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// UnsubscribeFromTopic unsubscribes registration tokens from an FCM topic
+func (s ServiceFCMImpl) UnsubscribeFromTopic(
+	ctx context.Context,
+	tokens []string,
+	topic string,
+) (*domain.TopicSubscriptionResult, error) {
+	ctx, span := tracer.Start(ctx, "UnsubscribeFromTopic")
+	defer span.End()
+	s.checkPreconditions()
+
+	result, err := s.manageTopic(ctx, tokens, topic, false)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// SendToTopic sends a message to the devices subscribed to a topic, or to
+// the devices that match a condition that combines topics. Exactly one of
+// the topic and the condition should be given.
+//
+// The send is recorded in the notifications store against the topic or
+// condition. It returns the FCM message ID.
+func (s ServiceFCMImpl) SendToTopic(
+	ctx context.Context,
+	topic string,
+	condition string,
+	data map[string]string,
+	notification *firebasetools.FirebaseSimpleNotificationInput,
+	android *firebasetools.FirebaseAndroidConfigInput,
+	ios *firebasetools.FirebaseAPNSConfigInput,
+	web *firebasetools.FirebaseWebpushConfigInput,
+) (string, error) {
+	ctx, span := tracer.Start(ctx, "SendToTopic")
+	defer span.End()
+	s.checkPreconditions()
+
+	switch {
+	case topic != "" && condition != "":
+		return "", fmt.Errorf("either a topic or a condition should be given, not both")
+	case topic != "":
+		if err := ValidateTopic(topic); err != nil {
+			return "", err
+		}
+	case condition != "":
+		if err := ValidateCondition(condition); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("a topic or a condition is required")
+	}
+
+	content, err := NotificationBuilder{
+		Data:         data,
+		Notification: notification,
+		Android:      android,
+		Ios:          ios,
+		Web:          web,
+	}.build()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return "", err
+	}
+	messageID, err := s.fcmClient.Send(ctx, content.broadcast(topic, condition))
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return "", fmt.Errorf("unable to send FCM message: %w", err)
+	}
+
+	saved := savedNotification("", data, notification, android, ios, web)
+	saved.Topic = topic
+	saved.Condition = condition
+	saved.MessageID = messageID
+	err = s.Repository.SaveNotification(ctx, s.firestoreClient, saved)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		log.Printf("unable to save notification: %v", err)
+	}
+	return messageID, nil
+}
@@ -0,0 +1,111 @@
+package fcm
+
+import (
+	"strings"
+	"testing"
+
+	"firebase.google.com/go/messaging"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/firebasetools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTopic(t *testing.T) {
+	for _, topic := range []string{"consumer", "county-nairobi", "programme_mch.2021", "a~b%20"} {
+		assert.Nil(t, ValidateTopic(topic), topic)
+	}
+	for _, topic := range []string{"", "/topics/news", "two words", "kenya's"} {
+		assert.NotNil(t, ValidateTopic(topic), topic)
+	}
+}
+
+func TestValidateCondition(t *testing.T) {
+	valid := []string{
+		"'consumer' in topics",
+		"'county-nairobi' in topics && ('programme-mch' in topics || 'programme-tb' in topics)",
+		"!('staff' in topics)",
+	}
+	for _, condition := range valid {
+		assert.Nil(t, ValidateCondition(condition), condition)
+	}
+
+	invalid := []string{
+		"",
+		"consumer",
+		"'two words' in topics",
+		"'a' in topics || 'b' in topics || 'c' in topics || 'd' in topics || 'e' in topics || 'f' in topics",
+	}
+	for _, condition := range invalid {
+		assert.NotNil(t, ValidateCondition(condition), condition)
+	}
+}
+
+func TestTokenBatches(t *testing.T) {
+	assert.Empty(t, tokenBatches([]string{}, 2))
+	assert.Equal(
+		t,
+		[][]string{{"a", "b"}, {"c", "d"}, {"e"}},
+		tokenBatches([]string{"a", "b", "c", "d", "e"}, 2),
+	)
+}
+
+func TestSubscriptionResult(t *testing.T) {
+	batches := [][]string{{"a", "b"}, {"c"}}
+	responses := []*messaging.TopicManagementResponse{
+		{
+			SuccessCount: 1,
+			FailureCount: 1,
+			Errors:       []*messaging.ErrorInfo{{Index: 1, Reason: "invalid-argument"}},
+		},
+		{SuccessCount: 1},
+	}
+
+	result := subscriptionResult("news", batches, responses)
+	assert.Equal(t, "news", result.Topic)
+	assert.Equal(t, 2, result.Succeeded)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, []string{"b: invalid-argument"}, result.Errors)
+}
+
+func TestMessageContent(t *testing.T) {
+	imageURL := "https://example.com/image.png"
+	content, err := NotificationBuilder{
+		Data: map[string]string{"item": "1"},
+		Notification: &firebasetools.FirebaseSimpleNotificationInput{
+			Title:    "Title",
+			Body:     "Body",
+			ImageURL: &imageURL,
+		},
+		Android: &firebasetools.FirebaseAndroidConfigInput{Priority: "high"},
+	}.build()
+	assert.Nil(t, err)
+
+	message := content.broadcast("", "'news' in topics")
+	assert.Equal(t, "'news' in topics", message.Condition)
+	assert.Empty(t, message.Topic)
+	assert.Equal(t, "Title", message.Notification.Title)
+	assert.Equal(t, imageURL, message.Notification.ImageURL)
+	assert.Equal(t, "high", message.Android.Priority)
+	assert.Nil(t, message.APNS)
+
+	multicast := content.multicast([]string{"a"})
+	assert.Equal(t, []string{"a"}, multicast.Tokens)
+	assert.Equal(t, message.Data, multicast.Data)
+
+	_, err = NotificationBuilder{Data: map[string]string{"from": "x"}}.build()
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "reserved"))
+}
+
+func TestSavedNotificationSenderType(t *testing.T) {
+	saved := savedNotification("ITEM_PUBLISHED", map[string]string{"item": "1"}, nil, nil, nil, nil)
+	assert.Equal(t, "ITEM_PUBLISHED", saved.SenderType)
+
+	saved = savedNotification("", map[string]string{"ALERT": "{}"}, nil, nil, nil, nil)
+	assert.Equal(
+		t,
+		domain.NotificationSenderOther,
+		saved.SenderType,
+		"the sender type is not guessed from the data",
+	)
+}
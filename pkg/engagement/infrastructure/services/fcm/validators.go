@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
 	"github.com/savannahghi/errorcodeutil"
 	"github.com/savannahghi/firebasetools"
 	"github.com/savannahghi/serverutils"
@@ -51,3 +52,24 @@ func ValidateSendNotificationPayload(w http.ResponseWriter, r *http.Request) (*f
 
 	return payload, nil
 }
+
+// ValidateNotificationPayload checks that the notification payload supplied
+// in the indicated request, including its platform options, is valid
+func ValidateNotificationPayload(w http.ResponseWriter, r *http.Request) (*dto.NotificationPayload, error) {
+	payload := &dto.NotificationPayload{}
+	serverutils.DecodeJSONToTargetStruct(w, r, payload)
+
+	if payload.RegistrationTokens == nil {
+		err := fmt.Errorf("can't send FCM notifications to nil registration tokens")
+		errorcodeutil.ReportErr(w, err, http.StatusBadRequest)
+		return nil, err
+	}
+
+	err := NewNotificationBuilder(*payload).Validate()
+	if err != nil {
+		errorcodeutil.ReportErr(w, err, http.StatusBadRequest)
+		return nil, err
+	}
+
+	return payload, nil
+}
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/fcm"
 	"github.com/savannahghi/firebasetools"
 )
@@ -161,3 +162,75 @@ func TestValidateSendNotificationPayload(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateNotificationPayload(t *testing.T) {
+	badge := 1
+	goodData := &dto.NotificationPayload{
+		SendNotificationPayload: *GetSendNotificationPayload(),
+		NotificationOptions: dto.NotificationOptions{
+			APNSPayload: &dto.APNSPayloadInput{Badge: &badge},
+		},
+	}
+	goodDataJSONBytes, err := json.Marshal(goodData)
+	if err != nil {
+		t.Errorf("struct could not be marshalled: %v", err)
+		return
+	}
+
+	negative := -1
+	badData := &dto.NotificationPayload{
+		SendNotificationPayload: *GetSendNotificationPayload(),
+		NotificationOptions: dto.NotificationOptions{
+			APNSPayload: &dto.APNSPayloadInput{Badge: &negative},
+		},
+	}
+	badDataJSONBytes, err := json.Marshal(badData)
+	if err != nil {
+		t.Errorf("struct could not be marshalled: %v", err)
+		return
+	}
+
+	validRequest := httptest.NewRequest(http.MethodPost, "/", nil)
+	validRequest.Body = ioutil.NopCloser(bytes.NewReader(goodDataJSONBytes))
+
+	invalidOptionsRequest := httptest.NewRequest(http.MethodPost, "/", nil)
+	invalidOptionsRequest.Body = ioutil.NopCloser(bytes.NewReader(badDataJSONBytes))
+
+	emptyDataRequest := httptest.NewRequest(http.MethodPost, "/", nil)
+	emptyDataRequest.Body = ioutil.NopCloser(bytes.NewReader([]byte{}))
+
+	tests := []struct {
+		name    string
+		r       *http.Request
+		want    *dto.NotificationPayload
+		wantErr bool
+	}{
+		{
+			name: "valid data",
+			r:    validRequest,
+			want: goodData,
+		},
+		{
+			name:    "invalid platform options",
+			r:       invalidOptionsRequest,
+			wantErr: true,
+		},
+		{
+			name:    "invalid data",
+			r:       emptyDataRequest,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fcm.ValidateNotificationPayload(httptest.NewRecorder(), tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNotificationPayload() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateNotificationPayload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
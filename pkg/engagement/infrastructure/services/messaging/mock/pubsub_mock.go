@@ -18,6 +18,12 @@ type FakeServiceMessaging struct {
 		metadata map[string]interface{},
 	) error
 
+	PublishFn func(
+		ctx context.Context,
+		topicID string,
+		payload interface{},
+	) error
+
 	// Ask the notification service about the topics that it knows about
 	TopicIDsFn func() []string
 
@@ -43,6 +49,15 @@ func (f *FakeServiceMessaging) Notify(
 	return f.NotifyFn(ctx, topicID, uid, flavour, payload, metadata)
 }
 
+// Publish sends a message that is not about a feed element to a topic
+func (f *FakeServiceMessaging) Publish(
+	ctx context.Context,
+	topicID string,
+	payload interface{},
+) error {
+	return f.PublishFn(ctx, topicID, payload)
+}
+
 // TopicIDs gets topic IDs
 func (f *FakeServiceMessaging) TopicIDs() []string {
 	return f.TopicIDsFn()
@@ -42,6 +42,13 @@ type NotificationService interface {
 		metadata map[string]interface{},
 	) error
 
+	// Send a message that is not about a feed element to a topic
+	Publish(
+		ctx context.Context,
+		topicID string,
+		payload interface{},
+	) error
+
 	// Ask the notification service about the topics that it knows about
 	TopicIDs() []string
 
@@ -80,11 +87,15 @@ func NewPubSubNotificationService(
 		client:      client,
 		environment: environment,
 		callbackURL: callbackURL,
+		topics:      DefaultTopicRegistry,
 	}
 	if err := ns.checkPreconditions(); err != nil {
 		return nil, fmt.Errorf(
 			"pubsub notification service failed preconditions: %w", err)
 	}
+	if err := ns.topics.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid pubsub topics: %w", err)
+	}
 
 	topicIDs := ns.TopicIDs()
 	if err := pubsubtools.EnsureTopicsExist(ctx, client, topicIDs); err != nil {
@@ -92,7 +103,7 @@ func NewPubSubNotificationService(
 			"error when ensuring that pubsub topics exist: %w", err)
 	}
 
-	subscriptionIDs := pubsubtools.SubscriptionIDs(topicIDs)
+	subscriptionIDs := ns.SubscriptionIDs()
 	if err := pubsubtools.EnsureSubscriptionsExist(
 		ctx,
 		client,
@@ -110,6 +121,7 @@ type PubSubNotificationService struct {
 	client      *pubsub.Client
 	environment string
 	callbackURL string
+	topics      *TopicRegistry
 }
 
 func (ps PubSubNotificationService) checkPreconditions() error {
@@ -125,6 +137,10 @@ func (ps PubSubNotificationService) checkPreconditions() error {
 		return fmt.Errorf("blank callback URL in notification service")
 	}
 
+	if ps.topics == nil {
+		return fmt.Errorf("nil topic registry in notification service")
+	}
+
 	return nil
 }
 
@@ -180,42 +196,51 @@ func (ps PubSubNotificationService) Notify(
 	)
 }
 
+// Publish sends a message that is not about a feed element e.g a unit of
+// background work, marshalled to JSON, to the specified topic
+func (ps PubSubNotificationService) Publish(
+	ctx context.Context,
+	topicID string,
+	payload interface{},
+) error {
+	ctx, span := tracer.Start(ctx, "Publish")
+	defer span.End()
+	if err := ps.checkPreconditions(); err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"pubsub service precondition check failed when publishing: %w", err)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't marshal %s message to JSON: %w", topicID, err)
+	}
+	return pubsubtools.PublishToPubsub(
+		ctx,
+		ps.client,
+		topicID,
+		ps.environment,
+		helpers.ServiceName,
+		helpers.TopicVersion,
+		data,
+	)
+}
+
 // TopicIDs returns the known (registered) topic IDs
 func (ps PubSubNotificationService) TopicIDs() []string {
-	return []string{
-		helpers.AddPubSubNamespace(common.ItemPublishTopic),
-		helpers.AddPubSubNamespace(ps.environment),
-		helpers.AddPubSubNamespace(common.ItemResolveTopic),
-		helpers.AddPubSubNamespace(common.ItemUnresolveTopic),
-		helpers.AddPubSubNamespace(common.ItemHideTopic),
-		helpers.AddPubSubNamespace(common.ItemShowTopic),
-		helpers.AddPubSubNamespace(common.ItemPinTopic),
-		helpers.AddPubSubNamespace(common.ItemUnpinTopic),
-		helpers.AddPubSubNamespace(common.NudgePublishTopic),
-		helpers.AddPubSubNamespace(common.NudgeDeleteTopic),
-		helpers.AddPubSubNamespace(common.NudgeResolveTopic),
-		helpers.AddPubSubNamespace(common.NudgeUnresolveTopic),
-		helpers.AddPubSubNamespace(common.NudgeHideTopic),
-		helpers.AddPubSubNamespace(common.NudgeShowTopic),
-		helpers.AddPubSubNamespace(common.ActionPublishTopic),
-		helpers.AddPubSubNamespace(common.ActionDeleteTopic),
-		helpers.AddPubSubNamespace(common.MessagePostTopic),
-		helpers.AddPubSubNamespace(common.MessageDeleteTopic),
-		helpers.AddPubSubNamespace(common.IncomingEventTopic),
-		helpers.AddPubSubNamespace(common.SentEmailTopic),
-	}
+	return ps.topics.TopicIDs()
 }
 
-// SubscriptionIDs ...
-// TODO Implement this
+// SubscriptionIDs maps the known topic IDs to the IDs of their subscriptions
 func (ps PubSubNotificationService) SubscriptionIDs() map[string]string {
-	return nil
+	return ps.topics.SubscriptionIDs()
 }
 
-// ReverseSubscriptionIDs ...
-// TODO implement this
+// ReverseSubscriptionIDs maps the subscription IDs of the known topics to the
+// topic IDs
 func (ps PubSubNotificationService) ReverseSubscriptionIDs() map[string]string {
-	return nil
+	return ps.topics.ReverseSubscriptionIDs()
 }
 
 // Push instructs a remote FCM service to send a push notification.
@@ -234,7 +259,16 @@ func (ps PubSubNotificationService) Push(
 			"pubsub service precondition check failed when notifying: %w", err)
 	}
 	env := serverutils.GetRunningEnvironment()
-	payload, err := json.Marshal(notificationPayload)
+	// the feed screens its notifications before pushing them, so they are
+	// not screened again when they are sent
+	transactional := true
+	payload, err := json.Marshal(dto.NotificationPayload{
+		SendNotificationPayload: notificationPayload,
+		NotificationOptions: dto.NotificationOptions{
+			SenderType:    sender,
+			Transactional: &transactional,
+		},
+	})
 	if err != nil {
 		helpers.RecordSpanError(span, err)
 		return fmt.Errorf("can't marshal notification payload: %w", err)
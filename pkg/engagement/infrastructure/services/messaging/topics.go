@@ -0,0 +1,267 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/pubsubtools"
+)
+
+// subscriptionSuffix is added to a topic ID to name its default subscription
+const subscriptionSuffix = "-default-subscription"
+
+// PubSubHandler processes a message that was published to a topic
+type PubSubHandler func(ctx context.Context, m *pubsubtools.PubSubPayload) error
+
+// Topic declares a Pub/Sub topic and how the messages published to it are
+// handled
+type Topic struct {
+	// the topic name before it is namespaced e.g `items.publish`
+	Name string
+
+	// the push subscription that delivers the topic's messages. It defaults
+	// to the namespaced topic ID with a `-default-subscription` suffix.
+	Subscription string
+
+	// a value of the type that the messages' data decode to e.g
+	// `dto.NotificationEnvelope{}`. Messages whose data does not decode are
+	// rejected before they get to the handler. A nil payload accepts any
+	// data.
+	Payload interface{}
+
+	Handler PubSubHandler
+}
+
+// ID returns the namespaced topic ID
+func (t Topic) ID() string {
+	return helpers.AddPubSubNamespace(t.Name)
+}
+
+// SubscriptionID returns the ID of the topic's push subscription
+func (t Topic) SubscriptionID() string {
+	if t.Subscription != "" {
+		return t.Subscription
+	}
+	return t.ID() + subscriptionSuffix
+}
+
+// Decode unmarshals a message's data into a new value of the topic's payload
+// type. It returns nil for topics that do not declare a payload type.
+func (t Topic) Decode(data []byte) (interface{}, error) {
+	if t.Payload == nil {
+		return nil, nil
+	}
+	payload := reflect.New(reflect.TypeOf(t.Payload)).Interface()
+	if err := json.Unmarshal(data, payload); err != nil {
+		return nil, fmt.Errorf(
+			"can't unmarshal %s message data to %T: %w", t.Name, t.Payload, err)
+	}
+	return payload, nil
+}
+
+// TopicRegistry keeps the topics that a service publishes to and handles
+type TopicRegistry struct {
+	mu     sync.RWMutex
+	topics map[string]Topic
+
+	// topic names in the order they were registered. Topics are kept by
+	// name because their IDs depend on the environment the service runs in.
+	order []string
+}
+
+// NewTopicRegistry initializes a registry with the supplied topics
+func NewTopicRegistry(topics ...Topic) (*TopicRegistry, error) {
+	r := &TopicRegistry{topics: map[string]Topic{}}
+	for _, topic := range topics {
+		if err := r.Register(topic); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register adds a topic to the registry. A topic can only be registered once.
+// It does not namespace the topic, so that topics can be registered before
+// the environment is known. Only the subscriptions that are set explicitly
+// are checked here; `Validate` checks the rest once the environment is
+// known.
+func (r *TopicRegistry) Register(topic Topic) error {
+	if topic.Name == "" {
+		return fmt.Errorf("a topic must have a name")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.topics[topic.Name]; ok {
+		return fmt.Errorf("topic %s is already registered", topic.Name)
+	}
+	for _, other := range r.topics {
+		if topic.Subscription != "" && other.Subscription == topic.Subscription {
+			return fmt.Errorf(
+				"subscription %s is already used by topic %s",
+				topic.Subscription,
+				other.Name,
+			)
+		}
+	}
+	r.topics[topic.Name] = topic
+	r.order = append(r.order, topic.Name)
+	return nil
+}
+
+// Validate checks that no two registered topics share an ID or a
+// subscription ID. It namespaces the topics, so it is called once the
+// environment is known.
+func (r *TopicRegistry) Validate() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	topics := map[string]string{}
+	subscriptions := map[string]string{}
+	for _, name := range r.order {
+		topic := r.topics[name]
+		if other, ok := topics[topic.ID()]; ok {
+			return fmt.Errorf(
+				"topics %s and %s have the same ID %s", other, name, topic.ID())
+		}
+		topics[topic.ID()] = name
+		if other, ok := subscriptions[topic.SubscriptionID()]; ok {
+			return fmt.Errorf(
+				"subscription %s is used by topics %s and %s",
+				topic.SubscriptionID(),
+				other,
+				name,
+			)
+		}
+		subscriptions[topic.SubscriptionID()] = name
+	}
+	return nil
+}
+
+// Handle sets the handler of a registered topic, replacing any earlier one
+func (r *TopicRegistry) Handle(name string, handler PubSubHandler) error {
+	if handler == nil {
+		return fmt.Errorf("nil handler for topic %s", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	topic, ok := r.topics[name]
+	if !ok {
+		return fmt.Errorf("topic %s is not registered", name)
+	}
+	topic.Handler = handler
+	r.topics[name] = topic
+	return nil
+}
+
+// Topic looks up a topic by its namespaced ID
+func (r *TopicRegistry) Topic(topicID string) (Topic, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, topic := range r.topics {
+		if topic.ID() == topicID {
+			return topic, true
+		}
+	}
+	return Topic{}, false
+}
+
+// TopicIDs returns the namespaced IDs of the registered topics
+func (r *TopicRegistry) TopicIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	topicIDs := []string{}
+	for _, name := range r.order {
+		topicIDs = append(topicIDs, r.topics[name].ID())
+	}
+	return topicIDs
+}
+
+// SubscriptionIDs maps the registered topic IDs to their subscription IDs
+func (r *TopicRegistry) SubscriptionIDs() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	output := map[string]string{}
+	for _, topic := range r.topics {
+		output[topic.ID()] = topic.SubscriptionID()
+	}
+	return output
+}
+
+// ReverseSubscriptionIDs maps the subscription IDs of the registered topics
+// to the topic IDs
+func (r *TopicRegistry) ReverseSubscriptionIDs() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	output := map[string]string{}
+	for _, topic := range r.topics {
+		output[topic.SubscriptionID()] = topic.ID()
+	}
+	return output
+}
+
+// BuiltinTopics returns the topics that this service handles. Their handlers
+// are set by the presentation layer, which has access to the usecases.
+//
+// The topic named after the environment that the service used to create is
+// not among them. Nothing publishes to or handles it; it took the place of
+// the item delete topic, which is registered instead. Topics that were
+// created before are left as they are.
+func BuiltinTopics() []Topic {
+	envelope := dto.NotificationEnvelope{}
+	return []Topic{
+		{Name: common.ItemPublishTopic, Payload: envelope},
+		{Name: common.ItemDeleteTopic, Payload: envelope},
+		{Name: common.ItemResolveTopic, Payload: envelope},
+		{Name: common.ItemUnresolveTopic, Payload: envelope},
+		{Name: common.ItemHideTopic, Payload: envelope},
+		{Name: common.ItemShowTopic, Payload: envelope},
+		{Name: common.ItemPinTopic, Payload: envelope},
+		{Name: common.ItemUnpinTopic, Payload: envelope},
+		{Name: common.ItemExpireTopic, Payload: envelope},
+		{Name: common.ItemDueSoonTopic, Payload: envelope},
+		{Name: common.NudgePublishTopic, Payload: envelope},
+		{Name: common.NudgeDeleteTopic, Payload: envelope},
+		{Name: common.NudgeResolveTopic, Payload: envelope},
+		{Name: common.NudgeUnresolveTopic, Payload: envelope},
+		{Name: common.NudgeHideTopic, Payload: envelope},
+		{Name: common.NudgeShowTopic, Payload: envelope},
+		{Name: common.NudgeExpireTopic, Payload: envelope},
+		{Name: common.NudgeDueSoonTopic, Payload: envelope},
+		{Name: common.FeedBulkUpdateTopic, Payload: envelope},
+		{Name: common.ActionPublishTopic, Payload: envelope},
+		{Name: common.ActionDeleteTopic, Payload: envelope},
+		{Name: common.MessagePostTopic, Payload: envelope},
+		{Name: common.MessageDeleteTopic, Payload: envelope},
+		{Name: common.MessageUpdateTopic, Payload: envelope},
+		{Name: common.IncomingEventTopic},
+		{Name: common.FcmPublishTopic, Payload: dto.NotificationPayload{}},
+		{Name: common.SentEmailTopic, Payload: dto.EMailMessage{}},
+		{Name: common.DigestDueTopic, Payload: dto.DigestDue{}},
+	}
+}
+
+// DefaultTopicRegistry holds the built in topics and those registered by the
+// services that embed this package
+var DefaultTopicRegistry = mustTopicRegistry(BuiltinTopics()...)
+
+func mustTopicRegistry(topics ...Topic) *TopicRegistry {
+	r, err := NewTopicRegistry(topics...)
+	if err != nil {
+		panic(fmt.Sprintf("invalid topic registry: %s", err))
+	}
+	return r
+}
+
+// RegisterTopic adds a topic to the default registry. Services that embed
+// this package register their topics before the infrastructure is
+// initialized, so that the topics and their subscriptions are created.
+func RegisterTopic(topic Topic) error {
+	return DefaultTopicRegistry.Register(topic)
+}
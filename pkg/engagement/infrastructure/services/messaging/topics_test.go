@@ -0,0 +1,128 @@
+package messaging_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/messaging"
+	"github.com/savannahghi/pubsubtools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicRegistry_Register(t *testing.T) {
+	registry, err := messaging.NewTopicRegistry(
+		messaging.Topic{Name: "orders.paid"},
+		messaging.Topic{Name: "orders.refunded", Subscription: "refunds"},
+	)
+	assert.Nil(t, err)
+
+	assert.NotNil(t, registry.Register(messaging.Topic{}), "a topic needs a name")
+	assert.NotNil(t, registry.Register(messaging.Topic{Name: "orders.paid"}))
+	assert.NotNil(
+		t,
+		registry.Register(messaging.Topic{Name: "orders.voided", Subscription: "refunds"}),
+		"subscriptions can't be shared",
+	)
+
+	paid := helpers.AddPubSubNamespace("orders.paid")
+	refunded := helpers.AddPubSubNamespace("orders.refunded")
+	assert.Equal(t, []string{paid, refunded}, registry.TopicIDs())
+	assert.Equal(
+		t,
+		map[string]string{paid: paid + "-default-subscription", refunded: "refunds"},
+		registry.SubscriptionIDs(),
+	)
+	assert.Equal(
+		t,
+		map[string]string{paid + "-default-subscription": paid, "refunds": refunded},
+		registry.ReverseSubscriptionIDs(),
+	)
+
+	_, err = messaging.NewTopicRegistry(
+		messaging.Topic{Name: "orders.paid"},
+		messaging.Topic{Name: "orders.paid"},
+	)
+	assert.NotNil(t, err)
+}
+
+func TestTopicRegistry_Validate(t *testing.T) {
+	registry, err := messaging.NewTopicRegistry(
+		messaging.Topic{Name: "orders.paid"},
+		messaging.Topic{Name: "orders.refunded", Subscription: "refunds"},
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, registry.Validate())
+
+	paid := helpers.AddPubSubNamespace("orders.paid")
+	err = registry.Register(messaging.Topic{
+		Name:         "orders.voided",
+		Subscription: paid + "-default-subscription",
+	})
+	assert.Nil(t, err, "default subscriptions are not worked out when registering")
+	assert.NotNil(
+		t,
+		registry.Validate(),
+		"a subscription can't be another topic's default subscription",
+	)
+
+	registry, err = messaging.NewTopicRegistry(messaging.BuiltinTopics()...)
+	assert.Nil(t, err)
+	assert.Nil(t, registry.Validate())
+}
+
+func TestTopicRegistry_Handle(t *testing.T) {
+	registry, err := messaging.NewTopicRegistry(messaging.Topic{Name: "orders.paid"})
+	assert.Nil(t, err)
+
+	handled := 0
+	handler := func(ctx context.Context, m *pubsubtools.PubSubPayload) error {
+		handled++
+		return nil
+	}
+	assert.NotNil(t, registry.Handle("orders.paid", nil))
+	assert.NotNil(t, registry.Handle("orders.unknown", handler))
+	assert.Nil(t, registry.Handle("orders.paid", handler))
+
+	topic, ok := registry.Topic(helpers.AddPubSubNamespace("orders.paid"))
+	assert.True(t, ok)
+	assert.Nil(t, topic.Handler(context.Background(), &pubsubtools.PubSubPayload{}))
+	assert.Equal(t, 1, handled)
+
+	_, ok = registry.Topic("orders.paid")
+	assert.False(t, ok, "topics are looked up by their namespaced ID")
+}
+
+func TestTopic_Decode(t *testing.T) {
+	topic := messaging.Topic{Name: "mails", Payload: dto.EMailMessage{}}
+	payload, err := topic.Decode([]byte(`{"subject": "Hello", "to": ["a@example.com"]}`))
+	assert.Nil(t, err)
+	mail, ok := payload.(*dto.EMailMessage)
+	assert.True(t, ok)
+	assert.Equal(t, "Hello", mail.Subject)
+
+	_, err = topic.Decode([]byte(`not json`))
+	assert.NotNil(t, err)
+
+	payload, err = messaging.Topic{Name: "events"}.Decode([]byte(`not json`))
+	assert.Nil(t, err, "topics without a payload type accept any data")
+	assert.Nil(t, payload)
+}
+
+func TestBuiltinTopics(t *testing.T) {
+	registry, err := messaging.NewTopicRegistry(messaging.BuiltinTopics()...)
+	assert.Nil(t, err)
+	for _, name := range []string{
+		common.ItemPublishTopic,
+		common.ItemDeleteTopic,
+		common.FcmPublishTopic,
+		common.SentEmailTopic,
+	} {
+		_, ok := registry.Topic(helpers.AddPubSubNamespace(name))
+		assert.True(t, ok, fmt.Sprintf("%s is a built in topic", name))
+	}
+	assert.Len(t, registry.SubscriptionIDs(), len(messaging.BuiltinTopics()))
+}
@@ -17,6 +17,7 @@ type FakeServiceOnboarding struct {
 	IsOptedOutFn                   func(ctx context.Context, phoneNumber string) (bool, error)
 	PhonesWithoutOptOutFn          func(ctx context.Context, phones []string) ([]string, error)
 	GetUserProfileByPhoneOrEmailFn func(ctx context.Context, payload *dto.RetrieveUserProfileInput) (*profileutils.UserProfile, error)
+	GetPreferredLanguageFn         func(ctx context.Context, uid string) (string, error)
 }
 
 // GetEmailAddresses ...
@@ -53,3 +54,8 @@ func (f *FakeServiceOnboarding) PhonesWithoutOptOut(ctx context.Context, phones
 func (f *FakeServiceOnboarding) GetUserProfileByPhoneOrEmail(ctx context.Context, payload *dto.RetrieveUserProfileInput) (*profileutils.UserProfile, error) {
 	return f.GetUserProfileByPhoneOrEmailFn(ctx, payload)
 }
+
+// GetPreferredLanguage ...
+func (f *FakeServiceOnboarding) GetPreferredLanguage(ctx context.Context, uid string) (string, error) {
+	return f.GetPreferredLanguageFn(ctx, uid)
+}
@@ -56,6 +56,18 @@ type ProfileService interface {
 		ctx context.Context,
 		payload *dto.RetrieveUserProfileInput,
 	) (*profileutils.UserProfile, error)
+	GetPreferredLanguage(
+		ctx context.Context,
+		uid string,
+	) (string, error)
+	IsOptedOut(
+		ctx context.Context,
+		phoneNumber string,
+	) (bool, error)
+	PhonesWithoutOptOut(
+		ctx context.Context,
+		phones []string,
+	) ([]string, error)
 }
 
 // NewRemoteProfileService initializes a connection to a remote profile service
@@ -239,3 +251,128 @@ func (rps RemoteProfileService) GetUserProfileByPhoneOrEmail(
 	}
 	return &user, nil
 }
+
+// GetPreferredLanguage gets the language that the specified user picked on
+// their profile. An empty string is returned when the user has not picked one.
+func (rps RemoteProfileService) GetPreferredLanguage(
+	ctx context.Context,
+	uid string,
+) (string, error) {
+	ctx, span := tracer.Start(ctx, "GetPreferredLanguage")
+	defer span.End()
+	uidPayload := dto.UIDPayload{
+		UID: &uid,
+	}
+	resp, err := rps.profileClient.MakeRequest(
+		ctx,
+		http.MethodPost,
+		userProfile,
+		uidPayload,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return "", fmt.Errorf("error calling profile service: %w", err)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return "", fmt.Errorf("error reading profile response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf(
+			"failed to get user profile with status %v and data: %s",
+			resp.Status,
+			string(data),
+		)
+	}
+
+	// the language preference is not part of the shared profile model
+	profile := struct {
+		PreferredLanguage string `json:"preferredLanguage"`
+	}{}
+	err = json.Unmarshal(data, &profile)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return "", fmt.Errorf("error parsing user profile data: %w", err)
+	}
+	return profile.PreferredLanguage, nil
+}
+
+// IsOptedOut checks with the profile service whether the owner of a phone
+// number has opted out of promotional messages
+func (rps RemoteProfileService) IsOptedOut(
+	ctx context.Context,
+	phoneNumber string,
+) (bool, error) {
+	ctx, span := tracer.Start(ctx, "IsOptedOut")
+	defer span.End()
+	resp, err := rps.profileClient.MakeRequest(
+		ctx,
+		http.MethodPost,
+		isOptedOut,
+		dto.PhoneNumberPayload{PhoneNumber: &phoneNumber},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return false, fmt.Errorf("error calling profile service: %w", err)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return false, fmt.Errorf("error reading profile response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf(
+			"failed to check opt out with status %v and data: %s",
+			resp.Status,
+			string(data),
+		)
+	}
+
+	optOut := struct {
+		OptedOut bool `json:"optedOut"`
+	}{}
+	err = json.Unmarshal(data, &optOut)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return false, fmt.Errorf("error parsing opt out data: %w", err)
+	}
+	return optOut.OptedOut, nil
+}
+
+// PhonesWithoutOptOut filters out the phone numbers whose owners have opted
+// out of promotional messages
+func (rps RemoteProfileService) PhonesWithoutOptOut(
+	ctx context.Context,
+	phones []string,
+) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "PhonesWithoutOptOut")
+	defer span.End()
+	// the profile service checks one phone number at a time
+	optedOut := make([]bool, len(phones))
+	err := helpers.ForEachConcurrently(
+		len(phones),
+		helpers.ConcurrentRequests,
+		func(i int) error {
+			var err error
+			optedOut[i], err = rps.IsOptedOut(ctx, phones[i])
+			return err
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to check opt outs: %w", err)
+	}
+
+	allowed := []string{}
+	for i, phone := range phones {
+		if !optedOut[i] {
+			allowed = append(allowed, phone)
+		}
+	}
+	return allowed, nil
+}
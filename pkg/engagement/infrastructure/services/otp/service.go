@@ -13,6 +13,7 @@ import (
 	"github.com/savannahghi/converterandformatter"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/i18n"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/mail"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/sms"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/twilio"
@@ -28,15 +29,17 @@ var tracer = otel.Tracer("github.com/savannahghi/engagementcore/pkg/engagement/s
 const (
 	issuer       = "Savannah Informatics Limited"
 	accountName  = "info@healthcloud.co.ke"
-	subject      = "Be.Well Verification Code"
 	whatsappStep = 1
 	twilioStep   = 2
-	otpMsg       = "%s is your Be.Well verification code %s"
 
 	//PINSMS is the sms formart to be send
+	//
+	// Deprecated: the text is looked up in the i18n bundles, see i18n.TemporaryPINSMS
 	PINSMS = "You have been successfully registered on Be.Well. Please use this One Time PIN: %s to log in using your phone number and set a new PIN on login."
 
 	//PINWhatsApp is the whatsapp formart to be send
+	//
+	// Deprecated: the text is looked up in the i18n bundles, see i18n.TemporaryPINWhatsApp
 	PINWhatsApp = "Hi %s, welcome to Be.Well. Please use this One Time PIN: %s to log in using your phone number. You will be prompted to set a new PIN on login."
 )
 
@@ -62,7 +65,11 @@ type ServiceOTP interface {
 	SendTemporaryPIN(ctx context.Context, input dto.TemporaryPIN) error
 }
 
-// ServiceOTPImpl is an OTP generation and validation service
+// ServiceOTPImpl is an OTP generation and validation service.
+//
+// OTPs, temporary PINs and verification emails are transactional: they go
+// straight to the SMS, Twilio and mail services and are never screened
+// against the recipients' notification preferences.
 type ServiceOTPImpl struct {
 	mail   mail.ServiceMail
 	sms    sms.ServiceSMS
@@ -148,7 +155,7 @@ func (s ServiceOTPImpl) SendOTP(
 	if appID == nil {
 		appID = &appidentifier
 	}
-	msg := fmt.Sprintf(otpMsg, code, *appID)
+	msg := i18n.TranslateContext(ctx, i18n.OTPMessage, code, *appID)
 
 	if interserviceclient.IsKenyanNumber(normalizedPhoneNumber) {
 		_, err := s.sms.Send(ctx, normalizedPhoneNumber, msg)
@@ -200,7 +207,7 @@ func (s ServiceOTPImpl) GenerateAndSendOTP(ctx context.Context, msisdn string, a
 	if appID == nil {
 		appID = &appidentifier
 	}
-	msg := fmt.Sprintf(otpMsg, code, *appID)
+	msg := i18n.TranslateContext(ctx, i18n.OTPMessage, code, *appID)
 	otp := dto.OTP{
 		MSISDN:            msisdn,
 		Message:           msg,
@@ -253,7 +260,13 @@ func (s ServiceOTPImpl) SendOTPToEmail(ctx context.Context, msisdn, email *strin
 		return code, fmt.Errorf("%s is not a valid email", emailstr)
 	}
 
-	_, _, err = s.mail.SendEmail(ctx, subject, text, nil, emailstr)
+	_, _, err = s.mail.SendEmail(
+		ctx,
+		i18n.TranslateContext(ctx, i18n.OTPEmailSubject),
+		text,
+		nil,
+		emailstr,
+	)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
 		return code, fmt.Errorf("unable to send OTP to email: %w", err)
@@ -412,7 +425,7 @@ func (s ServiceOTPImpl) GenerateRetryOTP(
 	if appID == nil {
 		appID = &appidentifier
 	}
-	msg := fmt.Sprintf(otpMsg, code, *appID)
+	msg := i18n.TranslateContext(ctx, i18n.OTPMessage, code, *appID)
 
 	otp := dto.OTP{
 		MSISDN:            *msisdn,
@@ -482,7 +495,7 @@ func (s ServiceOTPImpl) EmailVerificationOtp(ctx context.Context, email *string)
 		return code, fmt.Errorf("%s is not a valid email", *email)
 	}
 
-	msg := fmt.Sprintf("Your phone number verification code is %s. ", code)
+	msg := i18n.TranslateContext(ctx, i18n.EmailVerificationCode, code)
 
 	otp := dto.OTP{
 		Email:             *email,
@@ -499,7 +512,13 @@ func (s ServiceOTPImpl) EmailVerificationOtp(ctx context.Context, email *string)
 
 	emailstr := *email
 
-	_, _, err = s.mail.SendEmail(ctx, subject, text, nil, emailstr)
+	_, _, err = s.mail.SendEmail(
+		ctx,
+		i18n.TranslateContext(ctx, i18n.OTPEmailSubject),
+		text,
+		nil,
+		emailstr,
+	)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
 		return code, fmt.Errorf("unable to send OTP to email: %w", err)
@@ -536,8 +555,18 @@ func (s ServiceOTPImpl) SendTemporaryPIN(ctx context.Context, input dto.Temporar
 		return errors.Wrap(err, "unable to normalize the recipient phone number")
 	}
 
+	language := i18n.ContextLanguage(ctx)
+	if input.Language != "" {
+		language = i18n.Resolve(input.Language)
+	}
+
 	if input.Channel == whatsappStep {
-		msg := fmt.Sprintf(PINWhatsApp, input.FirstName, input.PIN)
+		msg := i18n.Translate(
+			language,
+			i18n.TemporaryPINWhatsApp,
+			input.FirstName,
+			input.PIN,
+		)
 
 		sent, err := s.twilio.TemporaryPIN(ctx, *cleanNo, msg)
 		if err != nil {
@@ -552,7 +581,7 @@ func (s ServiceOTPImpl) SendTemporaryPIN(ctx context.Context, input dto.Temporar
 		return nil
 
 	} else if input.Channel == twilioStep {
-		msg := fmt.Sprintf(PINSMS, input.PIN)
+		msg := i18n.Translate(language, i18n.TemporaryPINSMS, input.PIN)
 		err := s.twilio.SendSMS(ctx, *cleanNo, msg)
 		if err != nil {
 			helpers.RecordSpanError(span, err)
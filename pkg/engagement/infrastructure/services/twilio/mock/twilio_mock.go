@@ -44,6 +44,11 @@ type FakeServiceTwilio struct {
 		to string,
 		message string,
 	) (bool, error)
+	WhatsAppMessageFn func(
+		ctx context.Context,
+		to string,
+		message string,
+	) (bool, error)
 	MakeWhatsappTwilioRequestFn func(
 		ctx context.Context,
 		method string,
@@ -113,6 +118,15 @@ func (f *FakeServiceTwilio) TemporaryPIN(
 	return f.TemporaryPINFn(ctx, to, message)
 }
 
+// WhatsAppMessage is a mock of the WhatsAppMessage method
+func (f *FakeServiceTwilio) WhatsAppMessage(
+	ctx context.Context,
+	to string,
+	message string,
+) (bool, error) {
+	return f.WhatsAppMessageFn(ctx, to, message)
+}
+
 // MakeWhatsappTwilioRequest is a mock of the MakeWhatsappTwilioRequest method
 func (f *FakeServiceTwilio) MakeWhatsappTwilioRequest(
 	ctx context.Context,
@@ -18,6 +18,7 @@ import (
 	"github.com/savannahghi/converterandformatter"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/i18n"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/sms"
 	"github.com/savannahghi/firebasetools"
@@ -87,6 +88,12 @@ type ServiceTwilio interface {
 		message string,
 	) (bool, error)
 
+	WhatsAppMessage(
+		ctx context.Context,
+		to string,
+		message string,
+	) (bool, error)
+
 	MakeTwilioRequest(
 		method string,
 		urlPath string,
@@ -470,7 +477,7 @@ func (s ServiceTwilioImpl) PhoneNumberVerificationCode(
 
 	msgFrom := fmt.Sprintf("whatsapp:%s", s.Sender)
 	msgTo := fmt.Sprintf("whatsapp:%s", *normalizedPhoneNo)
-	msg := fmt.Sprintf("Your phone number verification code is %s", code)
+	msg := i18n.TranslateContext(ctx, i18n.PhoneVerificationCode, code)
 
 	payload := url.Values{}
 	payload.Add("From", msgFrom)
@@ -520,6 +527,18 @@ func (s ServiceTwilioImpl) TemporaryPIN(
 	ctx, span := tracer.Start(ctx, "TemporaryPIN")
 	defer span.End()
 
+	return s.WhatsAppMessage(ctx, to, message)
+}
+
+// WhatsAppMessage sends a text message to a phone number over WhatsApp
+func (s ServiceTwilioImpl) WhatsAppMessage(
+	ctx context.Context,
+	to string,
+	message string,
+) (bool, error) {
+	ctx, span := tracer.Start(ctx, "WhatsAppMessage")
+	defer span.End()
+
 	s.CheckPreconditions()
 
 	normalizedPhoneNo, err := converterandformatter.NormalizeMSISDN(to)
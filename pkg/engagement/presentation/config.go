@@ -118,6 +118,6 @@ func GQLHandler(ctx context.Context,
 		),
 	)
 	return func(w http.ResponseWriter, r *http.Request) {
-		srv.ServeHTTP(w, r)
+		srv.ServeHTTP(w, r.WithContext(graph.WithLoggedInUserCache(r.Context())))
 	}
 }
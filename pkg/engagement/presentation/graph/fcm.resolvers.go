@@ -9,13 +9,15 @@ import (
 	"time"
 
 	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/authorization/permission"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 	"github.com/savannahghi/engagementcore/pkg/engagement/presentation/graph/generated"
 	"github.com/savannahghi/firebasetools"
 	"github.com/savannahghi/serverutils"
 )
 
-func (r *mutationResolver) SendNotification(ctx context.Context, registrationTokens []string, data map[string]interface{}, notification firebasetools.FirebaseSimpleNotificationInput, android *firebasetools.FirebaseAndroidConfigInput, ios *firebasetools.FirebaseAPNSConfigInput, web *firebasetools.FirebaseWebpushConfigInput) (bool, error) {
+func (r *mutationResolver) SendNotification(ctx context.Context, registrationTokens []string, data map[string]interface{}, notification firebasetools.FirebaseSimpleNotificationInput, android *firebasetools.FirebaseAndroidConfigInput, ios *firebasetools.FirebaseAPNSConfigInput, web *firebasetools.FirebaseWebpushConfigInput, idempotencyKey *string, apnsPayload *dto.APNSPayloadInput, androidOptions *dto.AndroidOptionsInput, uids []string, transactional *bool) (bool, error) {
 	startTime := time.Now()
 
 	r.checkPreconditions()
@@ -26,14 +28,50 @@ func (r *mutationResolver) SendNotification(ctx context.Context, registrationTok
 		return false, err
 	}
 
-	sent, err := r.infra.SendNotification(
+	var sent bool
+	err = r.idempotent(
 		ctx,
-		registrationTokens,
-		notificationData,
-		&notification,
-		android,
-		ios,
-		web,
+		"sendNotification",
+		idempotencyKey,
+		map[string]interface{}{
+			"registrationTokens": registrationTokens,
+			"data":               notificationData,
+			"notification":       notification,
+			"android":            android,
+			"ios":                ios,
+			"web":                web,
+			"apnsPayload":        apnsPayload,
+			"androidOptions":     androidOptions,
+			"uids":               uids,
+			"transactional":      transactional,
+		},
+		&sent,
+		func(ctx context.Context) (interface{}, error) {
+			payload, err := r.usecases.ScreenPushNotification(ctx, dto.NotificationPayload{
+				SendNotificationPayload: firebasetools.SendNotificationPayload{
+					RegistrationTokens: registrationTokens,
+					Data:               notificationData,
+					Notification:       &notification,
+					Android:            android,
+					Ios:                ios,
+					Web:                web,
+				},
+				NotificationOptions: dto.NotificationOptions{
+					APNSPayload:    apnsPayload,
+					AndroidOptions: androidOptions,
+					UIDs:           uids,
+					Transactional:  transactional,
+				},
+			})
+			if err != nil {
+				return false, err
+			}
+			if len(payload.RegistrationTokens) == 0 {
+				// none of the users accept the notification now
+				return false, nil
+			}
+			return r.infra.SendNotificationWithOptions(ctx, *payload)
+		},
 	)
 	if err != nil {
 		return false, fmt.Errorf("failed to send a notification : %w", err)
@@ -49,13 +87,13 @@ func (r *mutationResolver) SendNotification(ctx context.Context, registrationTok
 	return sent, nil
 }
 
-func (r *mutationResolver) SendFCMByPhoneOrEmail(ctx context.Context, phoneNumber *string, email *string, data map[string]interface{}, notification firebasetools.FirebaseSimpleNotificationInput, android *firebasetools.FirebaseAndroidConfigInput, ios *firebasetools.FirebaseAPNSConfigInput, web *firebasetools.FirebaseWebpushConfigInput) (bool, error) {
+func (r *mutationResolver) SendFCMByPhoneOrEmail(ctx context.Context, phoneNumber *string, email *string, data map[string]interface{}, notification firebasetools.FirebaseSimpleNotificationInput, android *firebasetools.FirebaseAndroidConfigInput, ios *firebasetools.FirebaseAPNSConfigInput, web *firebasetools.FirebaseWebpushConfigInput, transactional *bool) (bool, error) {
 	startTime := time.Now()
 
 	r.checkPreconditions()
 	r.CheckUserTokenInContext(ctx)
 
-	sent, err := r.infra.SendFCMByPhoneOrEmail(
+	sent, err := r.usecases.SendFCMByPhoneOrEmailWithPreferences(
 		ctx,
 		phoneNumber,
 		email,
@@ -64,6 +102,7 @@ func (r *mutationResolver) SendFCMByPhoneOrEmail(ctx context.Context, phoneNumbe
 		android,
 		ios,
 		web,
+		transactional != nil && *transactional,
 	)
 	if err != nil {
 		return false, fmt.Errorf("failed to send an FCM notification by email or phone : %w", err)
@@ -79,6 +118,95 @@ func (r *mutationResolver) SendFCMByPhoneOrEmail(ctx context.Context, phoneNumbe
 	return sent, nil
 }
 
+func (r *mutationResolver) SubscribeToTopic(ctx context.Context, topic string) (*domain.TopicSubscriptionResult, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	result, err := r.usecases.SubscribeUserToTopic(ctx, uid, topic)
+	if err != nil {
+		return nil, fmt.Errorf("unable to subscribe to topic: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "subscribeToTopic", err)
+
+	return result, nil
+}
+
+func (r *mutationResolver) UnsubscribeFromTopic(ctx context.Context, topic string) (*domain.TopicSubscriptionResult, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	result, err := r.usecases.UnsubscribeUserFromTopic(ctx, uid, topic)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unsubscribe from topic: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "unsubscribeFromTopic", err)
+
+	return result, nil
+}
+
+func (r *mutationResolver) SendToTopic(ctx context.Context, topic *string, condition *string, data map[string]interface{}, notification firebasetools.FirebaseSimpleNotificationInput, android *firebasetools.FirebaseAndroidConfigInput, ios *firebasetools.FirebaseAPNSConfigInput, web *firebasetools.FirebaseWebpushConfigInput) (string, error) {
+	startTime := time.Now()
+
+	err := r.checkPermission(ctx, permission.SendToTopic)
+	if err != nil {
+		return "", err
+	}
+	notificationData, err := converterandformatter.MapInterfaceToMapString(data)
+	if err != nil {
+		return "", err
+	}
+	var topicName, conditionExpression string
+	if topic != nil {
+		topicName = *topic
+	}
+	if condition != nil {
+		conditionExpression = *condition
+	}
+
+	messageID, err := r.usecases.SendToTopic(
+		ctx,
+		topicName,
+		conditionExpression,
+		notificationData,
+		&notification,
+		android,
+		ios,
+		web,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to send a topic notification: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "sendToTopic", err)
+
+	return messageID, nil
+}
+
+func (r *mutationResolver) AcknowledgeNotification(ctx context.Context, messageID string, status domain.NotificationStatus) (bool, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return false, fmt.Errorf("can't get logged in user UID")
+	}
+	acknowledged, err := r.usecases.AcknowledgeNotification(ctx, uid, messageID, status)
+	if err != nil {
+		return false, fmt.Errorf("unable to acknowledge notification: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "acknowledgeNotification", err)
+
+	return acknowledged, nil
+}
+
 func (r *queryResolver) Notifications(ctx context.Context, registrationToken string, newerThan time.Time, limit int) ([]*dto.SavedNotification, error) {
 	startTime := time.Now()
 
@@ -100,6 +228,44 @@ func (r *queryResolver) Notifications(ctx context.Context, registrationToken str
 	return notification, nil
 }
 
+func (r *queryResolver) NotificationHistory(ctx context.Context, newerThan time.Time, limit int) ([]*dto.SavedNotification, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	notifications, err := r.usecases.UserNotifications(ctx, uid, newerThan, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve notification history: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "notificationHistory", err)
+
+	return notifications, nil
+}
+
+func (r *queryResolver) NotificationOpenRates(ctx context.Context, since time.Time, until time.Time) ([]*domain.NotificationOpenRate, error) {
+	startTime := time.Now()
+
+	err := r.checkPermission(ctx, permission.ViewNotificationReports)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := r.usecases.NotificationOpenRates(ctx, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification open rates: %w", err)
+	}
+	report := []*domain.NotificationOpenRate{}
+	for idx := range rates {
+		report = append(report, &rates[idx])
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "notificationOpenRates", err)
+
+	return report, nil
+}
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/authorization/permission"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/i18n"
 	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 	"github.com/savannahghi/feedlib"
 	"github.com/savannahghi/serverutils"
@@ -150,14 +152,24 @@ func (r *mutationResolver) ShowNudge(ctx context.Context, flavour feedlib.Flavou
 	return nudge, nil
 }
 
-func (r *mutationResolver) PostMessage(ctx context.Context, flavour feedlib.Flavour, itemID string, message feedlib.Message) (*feedlib.Message, error) {
+func (r *mutationResolver) PostMessage(ctx context.Context, flavour feedlib.Flavour, itemID string, message feedlib.Message, idempotencyKey *string) (*feedlib.Message, error) {
 	startTime := time.Now()
 
 	uid, err := r.getLoggedInUserUID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("can't get logged in user UID")
 	}
-	msg, err := r.usecases.PostMessage(ctx, uid, flavour, itemID, &message)
+	msg := &feedlib.Message{}
+	err = r.idempotent(
+		ctx,
+		"postMessage",
+		idempotencyKey,
+		map[string]interface{}{"flavour": flavour, "itemID": itemID, "message": message},
+		msg,
+		func(ctx context.Context) (interface{}, error) {
+			return r.usecases.PostMessage(ctx, uid, flavour, itemID, &message)
+		},
+	)
 	if err != nil {
 		return nil, fmt.Errorf("unable to post a message: %v", err)
 	}
@@ -201,12 +213,376 @@ func (r *mutationResolver) ProcessEvent(ctx context.Context, flavour feedlib.Fla
 	return true, nil
 }
 
-func (r *queryResolver) GetFeed(ctx context.Context, flavour feedlib.Flavour, playMp4 *bool, isAnonymous bool, persistent feedlib.BooleanFilter, status *feedlib.Status, visibility *feedlib.Visibility, expired *feedlib.BooleanFilter, filterParams *helpers.FilterParams) (*domain.Feed, error) {
+func (r *mutationResolver) BulkUpdateFeed(ctx context.Context, flavour feedlib.Flavour, operation domain.BulkOperation, itemIDs []string, nudgeIDs []string) (*domain.BulkResult, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	result, err := r.usecases.BulkUpdateFeed(ctx, uid, flavour, operation, itemIDs, nudgeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("can't update feed: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "bulkUpdateFeed", err)
+
+	return result, nil
+}
+
+func (r *mutationResolver) RenameLabel(ctx context.Context, flavour feedlib.Flavour, label string, newLabel string) (bool, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return false, fmt.Errorf("can't get logged in user UID")
+	}
+	err = r.usecases.RenameLabel(ctx, uid, flavour, label, newLabel)
+	if err != nil {
+		return false, fmt.Errorf("can't rename label: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "renameLabel", err)
+
+	return true, nil
+}
+
+func (r *mutationResolver) MergeLabels(ctx context.Context, flavour feedlib.Flavour, labels []string, target string) (bool, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return false, fmt.Errorf("can't get logged in user UID")
+	}
+	err = r.usecases.MergeLabels(ctx, uid, flavour, labels, target)
+	if err != nil {
+		return false, fmt.Errorf("can't merge labels: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "mergeLabels", err)
+
+	return true, nil
+}
+
+func (r *mutationResolver) DeleteLabel(ctx context.Context, flavour feedlib.Flavour, label string) (bool, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return false, fmt.Errorf("can't get logged in user UID")
+	}
+	err = r.usecases.DeleteLabel(ctx, uid, flavour, label)
+	if err != nil {
+		return false, fmt.Errorf("can't delete label: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "deleteLabel", err)
+
+	return true, nil
+}
+
+func (r *mutationResolver) MuteThread(ctx context.Context, flavour feedlib.Flavour, itemID string, muted bool) (bool, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return false, fmt.Errorf("can't get logged in user UID")
+	}
+	err = r.usecases.MuteThread(ctx, uid, flavour, itemID, uid, muted)
+	if err != nil {
+		return false, fmt.Errorf("can't mute thread: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "muteThread", err)
+
+	return true, nil
+}
+
+func (r *mutationResolver) InvokeAction(ctx context.Context, flavour feedlib.Flavour, actionID string, payload map[string]interface{}) (*domain.ActionResult, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	result, err := r.usecases.InvokeAction(ctx, uid, flavour, actionID, payload)
+	if err != nil {
+		return nil, fmt.Errorf("can't invoke action: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "invokeAction", err)
+
+	return result, nil
+}
+
+func (r *mutationResolver) EditMessage(ctx context.Context, flavour feedlib.Flavour, itemID string, messageID string, text string) (*domain.ThreadMessage, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	message, err := r.usecases.EditMessage(ctx, uid, flavour, itemID, messageID, text, uid)
+	if err != nil {
+		return nil, fmt.Errorf("can't edit message: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "editMessage", err)
+
+	return message, nil
+}
+
+func (r *mutationResolver) ReactToMessage(ctx context.Context, flavour feedlib.Flavour, itemID string, messageID string, emoji string, reacted bool) (bool, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return false, fmt.Errorf("can't get logged in user UID")
+	}
+	err = r.usecases.ReactToMessage(ctx, uid, flavour, itemID, messageID, emoji, uid, reacted)
+	if err != nil {
+		return false, fmt.Errorf("can't react to message: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "reactToMessage", err)
+
+	return true, nil
+}
+
+func (r *mutationResolver) PublishFeedItem(ctx context.Context, uid string, flavour feedlib.Flavour, item feedlib.Item, idempotencyKey *string) (*feedlib.Item, error) {
+	startTime := time.Now()
+
+	err := r.checkPermission(ctx, permission.PublishItem)
+	if err != nil {
+		return nil, err
+	}
+	published := &feedlib.Item{}
+	err = r.idempotent(
+		ctx,
+		"publishFeedItem",
+		idempotencyKey,
+		map[string]interface{}{"uid": uid, "flavour": flavour, "item": item},
+		published,
+		func(ctx context.Context) (interface{}, error) {
+			return r.usecases.PublishFeedItem(ctx, uid, flavour, &item)
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't publish feed item: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "publishFeedItem", err)
+
+	return published, nil
+}
+
+func (r *mutationResolver) DeleteFeedItem(ctx context.Context, uid string, flavour feedlib.Flavour, itemID string) (bool, error) {
+	startTime := time.Now()
+
+	err := r.checkPermission(ctx, permission.DeleteItem)
+	if err != nil {
+		return false, err
+	}
+	err = r.usecases.DeleteFeedItem(ctx, uid, flavour, itemID)
+	if err != nil {
+		return false, fmt.Errorf("can't delete feed item: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "deleteFeedItem", err)
+
+	return true, nil
+}
+
+func (r *mutationResolver) PublishNudge(ctx context.Context, uid string, flavour feedlib.Flavour, nudge feedlib.Nudge, idempotencyKey *string) (*feedlib.Nudge, error) {
+	startTime := time.Now()
+
+	err := r.checkPermission(ctx, permission.PublishNudge)
+	if err != nil {
+		return nil, err
+	}
+	published := &feedlib.Nudge{}
+	err = r.idempotent(
+		ctx,
+		"publishNudge",
+		idempotencyKey,
+		map[string]interface{}{"uid": uid, "flavour": flavour, "nudge": nudge},
+		published,
+		func(ctx context.Context) (interface{}, error) {
+			return r.usecases.PublishNudge(ctx, uid, flavour, &nudge)
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't publish nudge: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "publishNudge", err)
+
+	return published, nil
+}
+
+func (r *mutationResolver) DeleteNudge(ctx context.Context, uid string, flavour feedlib.Flavour, nudgeID string) (bool, error) {
+	startTime := time.Now()
+
+	err := r.checkPermission(ctx, permission.DeleteNudge)
+	if err != nil {
+		return false, err
+	}
+	err = r.usecases.DeleteNudge(ctx, uid, flavour, nudgeID)
+	if err != nil {
+		return false, fmt.Errorf("can't delete nudge: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "deleteNudge", err)
+
+	return true, nil
+}
+
+func (r *mutationResolver) PublishAction(ctx context.Context, uid string, flavour feedlib.Flavour, action feedlib.Action, idempotencyKey *string) (*feedlib.Action, error) {
+	startTime := time.Now()
+
+	err := r.checkPermission(ctx, permission.PublishAction)
+	if err != nil {
+		return nil, err
+	}
+	published := &feedlib.Action{}
+	err = r.idempotent(
+		ctx,
+		"publishAction",
+		idempotencyKey,
+		map[string]interface{}{"uid": uid, "flavour": flavour, "action": action},
+		published,
+		func(ctx context.Context) (interface{}, error) {
+			return r.usecases.PublishAction(ctx, uid, flavour, &action)
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't publish action: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "publishAction", err)
+
+	return published, nil
+}
+
+func (r *mutationResolver) DeleteAction(ctx context.Context, uid string, flavour feedlib.Flavour, actionID string) (bool, error) {
+	startTime := time.Now()
+
+	err := r.checkPermission(ctx, permission.DeleteAction)
+	if err != nil {
+		return false, err
+	}
+	err = r.usecases.DeleteAction(ctx, uid, flavour, actionID)
+	if err != nil {
+		return false, fmt.Errorf("can't delete action: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "deleteAction", err)
+
+	return true, nil
+}
+
+func (r *mutationResolver) AttachToMessage(ctx context.Context, flavour feedlib.Flavour, itemID string, messageID string, uploadIDs []string) (*domain.ThreadMessage, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	message, err := r.usecases.AttachToMessage(ctx, uid, flavour, itemID, messageID, uploadIDs)
+	if err != nil {
+		return nil, fmt.Errorf("can't attach uploads to message: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "attachToMessage", err)
+
+	return message, nil
+}
+
+func (r *mutationResolver) SetChecklist(ctx context.Context, uid string, flavour feedlib.Flavour, itemID string, checklist domain.Checklist) (*domain.Checklist, error) {
+	startTime := time.Now()
+
+	err := r.checkPermission(ctx, permission.PublishItem)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := r.usecases.SetChecklist(ctx, uid, flavour, itemID, checklist)
+	if err != nil {
+		return nil, fmt.Errorf("can't set checklist: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "setChecklist", err)
+
+	return saved, nil
+}
+
+func (r *mutationResolver) CompleteChecklistStep(ctx context.Context, flavour feedlib.Flavour, itemID string, stepID string) (*domain.Checklist, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	checklist, err := r.usecases.CompleteChecklistStep(ctx, uid, flavour, itemID, stepID)
+	if err != nil {
+		return nil, fmt.Errorf("can't complete checklist step: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "completeChecklistStep", err)
+
+	return checklist, nil
+}
+
+func (r *mutationResolver) ReportMessage(ctx context.Context, flavour feedlib.Flavour, itemID string, messageID string, reason string) (*domain.ModerationCase, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	moderationCase, err := r.usecases.ReportMessage(ctx, uid, flavour, itemID, messageID, uid, reason)
+	if err != nil {
+		return nil, fmt.Errorf("can't report message: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "reportMessage", err)
+
+	return moderationCase, nil
+}
+
+func (r *mutationResolver) ModerateMessage(ctx context.Context, caseID string, action domain.ModerationAction, reason *string) (*domain.ModerationCase, error) {
+	startTime := time.Now()
+
+	err := r.checkPermission(ctx, permission.ModerateMessages)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	decisionReason := ""
+	if reason != nil {
+		decisionReason = *reason
+	}
+	moderationCase, err := r.usecases.ModerateMessage(ctx, caseID, action, uid, decisionReason)
+	if err != nil {
+		return nil, fmt.Errorf("can't moderate message: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "moderateMessage", err)
+
+	return moderationCase, nil
+}
+
+func (r *queryResolver) GetFeed(ctx context.Context, flavour feedlib.Flavour, playMp4 *bool, isAnonymous bool, persistent feedlib.BooleanFilter, status *feedlib.Status, visibility *feedlib.Visibility, expired *feedlib.BooleanFilter, filterParams *helpers.FilterParams, language *string) (*domain.Feed, error) {
 	startTime := time.Now()
 	uid, err := r.getLoggedInUserUID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("can't get logged in user UID")
 	}
+	if language != nil {
+		ctx = i18n.WithLanguage(ctx, *language)
+	}
 	var playmp4FeedVideos bool
 	if playMp4 != nil {
 		playmp4FeedVideos = true
@@ -232,6 +608,28 @@ func (r *queryResolver) GetFeed(ctx context.Context, flavour feedlib.Flavour, pl
 	return feed, nil
 }
 
+func (r *queryResolver) FeedChanges(ctx context.Context, flavour feedlib.Flavour, since *string) (*domain.FeedChanges, error) {
+	startTime := time.Now()
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	changes, err := r.usecases.FeedChanges(
+		ctx,
+		uid,
+		false,
+		flavour,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't get feed changes: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "feedChanges", err)
+
+	return changes, nil
+}
+
 func (r *queryResolver) Labels(ctx context.Context, flavour feedlib.Flavour) ([]string, error) {
 	startTime := time.Now()
 
@@ -249,6 +647,27 @@ func (r *queryResolver) Labels(ctx context.Context, flavour feedlib.Flavour) ([]
 	return labels, nil
 }
 
+func (r *queryResolver) LabelCounts(ctx context.Context, flavour feedlib.Flavour) ([]*domain.LabelCount, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	counts, err := r.usecases.LabelCounts(ctx, uid, flavour)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get label counts: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "labelCounts", err)
+
+	labelCounts := []*domain.LabelCount{}
+	for i := range counts {
+		labelCounts = append(labelCounts, &counts[i])
+	}
+	return labelCounts, nil
+}
+
 func (r *queryResolver) UnreadPersistentItems(ctx context.Context, flavour feedlib.Flavour) (int, error) {
 	startTime := time.Now()
 
@@ -265,3 +684,83 @@ func (r *queryResolver) UnreadPersistentItems(ctx context.Context, flavour feedl
 
 	return count, nil
 }
+
+func (r *queryResolver) Thread(ctx context.Context, flavour feedlib.Flavour, itemID string) ([]*domain.ThreadMessage, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	thread, err := r.usecases.GetThread(ctx, uid, flavour, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get thread: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "thread", err)
+
+	return thread, nil
+}
+
+func (r *queryResolver) Checklist(ctx context.Context, flavour feedlib.Flavour, itemID string) (*domain.Checklist, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	checklist, err := r.usecases.GetChecklist(ctx, uid, flavour, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get checklist: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "checklist", err)
+
+	return checklist, nil
+}
+
+func (r *queryResolver) ModerationQueue(ctx context.Context, status *domain.ModerationStatus) ([]*domain.ModerationCase, error) {
+	startTime := time.Now()
+
+	err := r.checkPermission(ctx, permission.ModerateMessages)
+	if err != nil {
+		return nil, err
+	}
+	var queueStatus domain.ModerationStatus
+	if status != nil {
+		queueStatus = *status
+	}
+	cases, err := r.usecases.ModerationQueue(ctx, queueStatus)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get moderation queue: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "moderationQueue", err)
+
+	moderationCases := []*domain.ModerationCase{}
+	for i := range cases {
+		moderationCases = append(moderationCases, &cases[i])
+	}
+	return moderationCases, nil
+}
+
+func (r *queryResolver) ModerationAudit(ctx context.Context, caseID string) ([]*domain.ModerationAuditEntry, error) {
+	startTime := time.Now()
+
+	err := r.checkPermission(ctx, permission.ModerateMessages)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := r.usecases.ModerationAudit(ctx, caseID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get moderation audit: %w", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "moderationAudit", err)
+
+	auditEntries := []*domain.ModerationAuditEntry{}
+	for i := range entries {
+		auditEntries = append(auditEntries, &entries[i])
+	}
+	return auditEntries, nil
+}
@@ -11,12 +11,29 @@ import (
 	"github.com/savannahghi/serverutils"
 )
 
-func (r *mutationResolver) SimpleEmail(ctx context.Context, subject string, text string, to []string) (string, error) {
+func (r *mutationResolver) SimpleEmail(ctx context.Context, subject string, text string, to []string, idempotencyKey *string, transactional *bool) (string, error) {
 	startTime := time.Now()
 
 	r.checkPreconditions()
 	r.CheckUserTokenInContext(ctx)
-	status, err := r.infra.SimpleEmail(ctx, subject, text, nil, to...)
+	var status string
+	err := r.idempotent(
+		ctx,
+		"simpleEmail",
+		idempotencyKey,
+		map[string]interface{}{"subject": subject, "text": text, "to": to},
+		&status,
+		func(ctx context.Context) (interface{}, error) {
+			return r.usecases.SendEmailWithPreferences(
+				ctx,
+				subject,
+				text,
+				nil,
+				transactional != nil && *transactional,
+				to...,
+			)
+		},
+	)
 	if err != nil {
 		return "", fmt.Errorf("unable to send an email: %v", err)
 	}
@@ -0,0 +1,82 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/serverutils"
+)
+
+func (r *mutationResolver) SetNotificationPreferences(ctx context.Context, input domain.NotificationPreferences) (*domain.NotificationPreferences, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	preferences, err := r.usecases.SetNotificationPreferences(ctx, uid, input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to set notification preferences: %v", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "setNotificationPreferences", err)
+
+	return preferences, nil
+}
+
+func (r *mutationResolver) SetDigestSettings(ctx context.Context, flavour feedlib.Flavour, frequency domain.DigestFrequency, channel feedlib.Channel) (*domain.DigestSettings, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	settings, err := r.usecases.SetDigestSettings(ctx, uid, flavour, frequency, channel)
+	if err != nil {
+		return nil, fmt.Errorf("unable to set digest settings: %v", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "setDigestSettings", err)
+
+	return settings, nil
+}
+
+func (r *queryResolver) NotificationPreferences(ctx context.Context) (*domain.NotificationPreferences, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	preferences, err := r.usecases.NotificationPreferences(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get notification preferences: %v", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "notificationPreferences", err)
+
+	return preferences, nil
+}
+
+func (r *queryResolver) DigestSettings(ctx context.Context, flavour feedlib.Flavour) (*domain.DigestSettings, error) {
+	startTime := time.Now()
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get logged in user UID")
+	}
+	settings, err := r.usecases.DigestSettings(ctx, uid, flavour)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get digest settings: %v", err)
+	}
+
+	defer serverutils.RecordGraphqlResolverMetrics(ctx, startTime, "digestSettings", err)
+
+	return settings, nil
+}
@@ -2,12 +2,18 @@ package graph
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"sync"
 
 	"firebase.google.com/go/auth"
 	"github.com/savannahghi/firebasetools"
+	"github.com/savannahghi/profileutils"
 
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/authorization"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases"
 )
@@ -46,6 +52,113 @@ func (r Resolver) getLoggedInUserUID(ctx context.Context) (string, error) {
 	return authToken.UID, nil
 }
 
+type loggedInUserContextKey struct{}
+
+// loggedInUser holds the logged in user for the duration of a request
+type loggedInUser struct {
+	once sync.Once
+	user *profileutils.UserInfo
+	err  error
+}
+
+// WithLoggedInUserCache returns a context in which the logged in user is only
+// looked up once, however many of a request's resolvers check permissions
+func WithLoggedInUserCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loggedInUserContextKey{}, &loggedInUser{})
+}
+
+// getLoggedInUser returns the logged in user, from the request's cache when
+// the context has one
+func (r Resolver) getLoggedInUser(ctx context.Context) (*profileutils.UserInfo, error) {
+	cached, ok := ctx.Value(loggedInUserContextKey{}).(*loggedInUser)
+	if !ok {
+		return profileutils.GetLoggedInUser(ctx)
+	}
+	cached.once.Do(func() {
+		cached.user, cached.err = profileutils.GetLoggedInUser(ctx)
+	})
+	return cached.user, cached.err
+}
+
+// checkPermission ensures that the logged in user has been granted a permission
+func (r Resolver) checkPermission(
+	ctx context.Context,
+	permission profileutils.PermissionInput,
+) error {
+	user, err := r.getLoggedInUser(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get logged in user: %w", err)
+	}
+	ok, err := authorization.IsAuthorized(user, permission)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("user not authorized to access this resource")
+	}
+	return nil
+}
+
+// idempotent runs a mutation at most once per idempotency key. The arguments
+// of the mutation identify the request and the result of the first run is
+// decoded into `result` for repeats of the mutation. Mutations without a key
+// are run every time.
+func (r Resolver) idempotent(
+	ctx context.Context,
+	mutation string,
+	key *string,
+	args interface{},
+	result interface{},
+	run func(ctx context.Context) (interface{}, error),
+) error {
+	if key == nil || *key == "" {
+		out, err := run(ctx)
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(out)
+		if err != nil {
+			return fmt.Errorf("unable to marshal mutation result: %w", err)
+		}
+		return json.Unmarshal(body, result)
+	}
+
+	uid, err := r.getLoggedInUserUID(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get logged in user UID")
+	}
+	request, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("unable to marshal mutation arguments: %w", err)
+	}
+
+	response, _, err := r.usecases.Idempotent(
+		ctx,
+		fmt.Sprintf("graphql %s %s", mutation, uid),
+		*key,
+		request,
+		func(ctx context.Context) (*domain.IdempotentResponse, error) {
+			out, err := run(ctx)
+			if err != nil {
+				return nil, err
+			}
+			body, err := json.Marshal(out)
+			if err != nil {
+				return nil, fmt.Errorf("unable to marshal mutation result: %w", err)
+			}
+			return &domain.IdempotentResponse{
+				StatusCode:  http.StatusOK,
+				ContentType: "application/json",
+				Body:        body,
+			}, nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(response.Body, result)
+}
+
 // CheckUserTokenInContext ensures that the context has a valid Firebase auth token
 func (r *Resolver) CheckUserTokenInContext(ctx context.Context) *auth.Token {
 	token, err := firebasetools.GetUserTokenFromContext(ctx)
@@ -12,12 +12,27 @@ import (
 	"github.com/savannahghi/silcomms"
 )
 
-func (r *mutationResolver) Send(ctx context.Context, to string, message string) (*silcomms.BulkSMSResponse, error) {
+func (r *mutationResolver) Send(ctx context.Context, to string, message string, idempotencyKey *string, transactional *bool) (*silcomms.BulkSMSResponse, error) {
 	startTime := time.Now()
 
 	r.checkPreconditions()
 	r.CheckUserTokenInContext(ctx)
-	smsResponse, err := r.infra.Send(ctx, to, message)
+	smsResponse := &silcomms.BulkSMSResponse{}
+	err := r.idempotent(
+		ctx,
+		"send",
+		idempotencyKey,
+		map[string]interface{}{"to": to, "message": message},
+		smsResponse,
+		func(ctx context.Context) (interface{}, error) {
+			return r.usecases.SendSMSWithPreferences(
+				ctx,
+				[]string{to},
+				message,
+				transactional != nil && *transactional,
+			)
+		},
+	)
 	if err != nil {
 		return nil, fmt.Errorf("unable send SMS: %v", err)
 	}
@@ -32,12 +47,27 @@ func (r *mutationResolver) Send(ctx context.Context, to string, message string)
 	return smsResponse, nil
 }
 
-func (r *mutationResolver) SendToMany(ctx context.Context, message string, to []string) (*silcomms.BulkSMSResponse, error) {
+func (r *mutationResolver) SendToMany(ctx context.Context, message string, to []string, idempotencyKey *string, transactional *bool) (*silcomms.BulkSMSResponse, error) {
 	startTime := time.Now()
 
 	r.checkPreconditions()
 	r.CheckUserTokenInContext(ctx)
-	smsResponse, err := r.infra.SendToMany(ctx, to, message)
+	smsResponse := &silcomms.BulkSMSResponse{}
+	err := r.idempotent(
+		ctx,
+		"sendToMany",
+		idempotencyKey,
+		map[string]interface{}{"message": message, "to": to},
+		smsResponse,
+		func(ctx context.Context) (interface{}, error) {
+			return r.usecases.SendSMSWithPreferences(
+				ctx,
+				to,
+				message,
+				transactional != nil && *transactional,
+			)
+		},
+	)
 	if err != nil {
 		return nil, fmt.Errorf("unable to send SMS to many: %v", err)
 	}
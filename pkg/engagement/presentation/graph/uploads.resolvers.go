@@ -21,6 +21,14 @@ func (r *mutationResolver) Upload(ctx context.Context, input profileutils.Upload
 		return nil, fmt.Errorf("unable to upload: %v", err)
 	}
 
+	// only the user that made an upload can attach it to messages
+	if uid, uidErr := r.getLoggedInUserUID(ctx); uidErr == nil {
+		err = r.infra.SaveUploadOwner(ctx, upload.ID, uid)
+		if err != nil {
+			return nil, fmt.Errorf("unable to record the upload's owner: %v", err)
+		}
+	}
+
 	defer serverutils.RecordGraphqlResolverMetrics(
 		ctx,
 		startTime,
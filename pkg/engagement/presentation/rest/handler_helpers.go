@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -12,9 +13,11 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/exceptions"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 	"github.com/savannahghi/errorcodeutil"
 	"github.com/savannahghi/feedlib"
 	"github.com/savannahghi/firebasetools"
+	"github.com/savannahghi/interserviceclient"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -40,6 +43,61 @@ func respondWithJSON(w http.ResponseWriter, code int, payload []byte) {
 	}
 }
 
+// responseRecorder captures a handler's response so that it can be stored
+// and replayed
+type responseRecorder struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: http.Header{}}
+}
+
+func (rr *responseRecorder) Header() http.Header {
+	return rr.header
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.statusCode == 0 {
+		rr.statusCode = http.StatusOK
+	}
+	return rr.body.Write(b)
+}
+
+func (rr *responseRecorder) WriteHeader(statusCode int) {
+	if rr.statusCode == 0 {
+		rr.statusCode = statusCode
+	}
+}
+
+func (rr *responseRecorder) response() *domain.IdempotentResponse {
+	statusCode := rr.statusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	return &domain.IdempotentResponse{
+		StatusCode:  statusCode,
+		ContentType: rr.header.Get("Content-Type"),
+		Body:        rr.body.Bytes(),
+	}
+}
+
+// idempotencyCaller identifies the caller that an idempotency key belongs to:
+// the logged in user, or any of the services that share the inter-service
+// credentials
+func idempotencyCaller(r *http.Request) (string, error) {
+	token, err := firebasetools.GetUserTokenFromContext(r.Context())
+	if err == nil && token.UID != "" {
+		return fmt.Sprintf("user %s", token.UID), nil
+	}
+	if ok, _, _ := interserviceclient.HasValidJWTBearerToken(r); ok {
+		return "service", nil
+	}
+	return "", fmt.Errorf("idempotency keys can only be used by authenticated callers")
+}
+
 func getUIDFlavourAndIsAnonymous(r *http.Request) (*string, *feedlib.Flavour, *bool, error) {
 	if r == nil {
 		return nil, nil, nil, fmt.Errorf("nil request")
@@ -114,6 +172,75 @@ func patchItem(
 	respondWithJSON(w, http.StatusOK, marshalled)
 }
 
+type muteThreadFunc func(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	participantUID string,
+	muted bool,
+) error
+
+func muteThread(
+	ctx context.Context,
+	muteFunc muteThreadFunc,
+	muted bool,
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	itemID, err := getStringVar(r, "itemID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	participantUID := r.FormValue("participantUID")
+	if participantUID == "" {
+		participantUID = *uid
+	}
+
+	err = muteFunc(
+		addUIDToContext(ctx, *uid),
+		*uid,
+		*flavour,
+		itemID,
+		participantUID,
+		muted,
+	)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := map[string]string{"status": "success"}
+	marshalled, err := json.Marshal(resp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, marshalled)
+}
+
+// getItemAndMessageIDs returns the item and message IDs in a message's path
+func getItemAndMessageIDs(r *http.Request) (string, string, error) {
+	itemID, err := getStringVar(r, "itemID")
+	if err != nil {
+		return "", "", err
+	}
+	messageID, err := getStringVar(r, "messageID")
+	if err != nil {
+		return "", "", err
+	}
+	return itemID, messageID, nil
+}
+
 type patchNudgeFunc func(ctx context.Context, uid string, flavour feedlib.Flavour, nudgeID string) (*feedlib.Nudge, error)
 
 func patchNudge(
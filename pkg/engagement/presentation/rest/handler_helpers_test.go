@@ -1,8 +1,12 @@
 package rest
 
 import (
+	"context"
 	"net/http"
+	"os"
 	"testing"
+
+	"github.com/savannahghi/interserviceclient"
 )
 
 func Test_getplayMP4QueryParam(t *testing.T) {
@@ -359,3 +363,40 @@ func Test_getUIDFlavourAndIsAnonymous(t *testing.T) {
 		})
 	}
 }
+
+func Test_idempotencyCaller(t *testing.T) {
+	anonymous, err := http.NewRequest(http.MethodPost, "localhost/send_sms", nil)
+	if err != nil {
+		t.Fatalf("error in the request %v", err)
+	}
+	if _, err := idempotencyCaller(anonymous); err == nil {
+		t.Errorf("expected anonymous callers to be rejected")
+	}
+
+	user, err := http.NewRequest(http.MethodPost, "localhost/send_sms", nil)
+	if err != nil {
+		t.Fatalf("error in the request %v", err)
+	}
+	user = user.WithContext(addUIDToContext(user.Context(), "uid"))
+	caller, err := idempotencyCaller(user)
+	if err != nil || caller != "user uid" {
+		t.Errorf("idempotencyCaller() = %v, %v, want the logged in user", caller, err)
+	}
+
+	if os.Getenv(interserviceclient.JWTSecretKey) == "" {
+		os.Setenv(interserviceclient.JWTSecretKey, "a test secret")
+	}
+	token, err := interserviceclient.InterServiceClient{}.CreateAuthToken(context.Background())
+	if err != nil {
+		t.Fatalf("unable to create an inter-service token: %v", err)
+	}
+	service, err := http.NewRequest(http.MethodPost, "localhost/internal/send_sms", nil)
+	if err != nil {
+		t.Fatalf("error in the request %v", err)
+	}
+	service.Header.Set("Authorization", "Bearer "+token)
+	caller, err = idempotencyCaller(service)
+	if err != nil || caller != "service" {
+		t.Errorf("idempotencyCaller() = %v, %v, want a service", caller, err)
+	}
+}
@@ -1,19 +1,24 @@
 package rest
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/fcm"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/messaging"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/otp"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/idempotency"
 
 	"net/http"
 
@@ -29,7 +34,7 @@ import (
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/exceptions"
-	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 )
 
 const (
@@ -41,10 +46,21 @@ const (
 	bewellURL            = "https://bewell.co.ke/data_deletion.html/?id="
 )
 
+const (
+	// IdempotencyKeyHeader carries the key that makes a request safe to retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader marks responses that were replayed for a
+	// repeated request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
 var errNotFound = fmt.Errorf("not found")
 
 // PresentationHandlers represents all the REST API logic
 type PresentationHandlers interface {
+	Idempotent(next http.HandlerFunc) http.HandlerFunc
+
 	GoogleCloudPubSubHandler(w http.ResponseWriter, r *http.Request)
 	GetFeed() http.HandlerFunc
 
@@ -88,14 +104,78 @@ type PresentationHandlers interface {
 
 	DeleteAction() http.HandlerFunc
 
+	InvokeAction() http.HandlerFunc
+
 	PostMessage() http.HandlerFunc
 
 	DeleteMessage() http.HandlerFunc
 
+	BulkUpdateFeed() http.HandlerFunc
+
+	GetThread() http.HandlerFunc
+
+	EditMessage() http.HandlerFunc
+
+	ReactToMessage() http.HandlerFunc
+
+	AttachToMessage() http.HandlerFunc
+
+	GetChecklist() http.HandlerFunc
+
+	SetChecklist() http.HandlerFunc
+
+	CompleteChecklistStep() http.HandlerFunc
+
+	ReportMessage() http.HandlerFunc
+
+	ModerationQueue() http.HandlerFunc
+
+	ModerateMessage() http.HandlerFunc
+
+	ModerationAudit() http.HandlerFunc
+
+	NotificationDeliveries() http.HandlerFunc
+
+	MuteThread() http.HandlerFunc
+
+	UnmuteThread() http.HandlerFunc
+
 	ProcessEvent() http.HandlerFunc
 
+	SaveElementTranslations() http.HandlerFunc
+
+	FeedChanges() http.HandlerFunc
+
+	ProcessExpiredElements() http.HandlerFunc
+
+	ProcessDeferredNotifications() http.HandlerFunc
+
+	ProcessDigests() http.HandlerFunc
+
+	ProcessInboxCountPushes() http.HandlerFunc
+
+	TokenHealth() http.HandlerFunc
+
+	ProcessFCMRetries() http.HandlerFunc
+
+	FCMDeadLetters() http.HandlerFunc
+
+	ReplayFCMDeadLetter() http.HandlerFunc
+
+	SubscribeToTopic() http.HandlerFunc
+
+	UnsubscribeFromTopic() http.HandlerFunc
+
+	SendToTopic() http.HandlerFunc
+
+	NotificationOpenRates() http.HandlerFunc
+
+	MergeAnonymousFeed() http.HandlerFunc
+
 	Upload() http.HandlerFunc
 
+	UploadToFeed() http.HandlerFunc
+
 	FindUpload() http.HandlerFunc
 
 	SendEmail() http.HandlerFunc
@@ -135,11 +215,126 @@ type PresentationHandlers interface {
 type PresentationHandlersImpl struct {
 	usecases       usecases.Interactor
 	infrastructure infrastructure.Interactor
+	topics         *messaging.TopicRegistry
 }
 
 // NewPresentationHandlers initializes a new rest handlers usecase
 func NewPresentationHandlers(infrastructure infrastructure.Interactor, usecases usecases.Interactor) PresentationHandlers {
-	return &PresentationHandlersImpl{infrastructure: infrastructure, usecases: usecases}
+	topics := messaging.DefaultTopicRegistry
+	if err := handleBuiltinTopics(topics, usecases); err != nil {
+		log.Panicf("unable to set the pub sub topic handlers: %s", err)
+	}
+	return &PresentationHandlersImpl{
+		infrastructure: infrastructure,
+		usecases:       usecases,
+		topics:         topics,
+	}
+}
+
+// handleBuiltinTopics points the topics that this service handles at the
+// usecases that process their messages
+func handleBuiltinTopics(
+	topics *messaging.TopicRegistry,
+	u usecases.Interactor,
+) error {
+	handlers := map[string]messaging.PubSubHandler{
+		common.ItemPublishTopic:    u.HandleItemPublish,
+		common.ItemDeleteTopic:     u.HandleItemDelete,
+		common.ItemResolveTopic:    u.HandleItemResolve,
+		common.ItemUnresolveTopic:  u.HandleItemUnresolve,
+		common.ItemHideTopic:       u.HandleItemHide,
+		common.ItemShowTopic:       u.HandleItemShow,
+		common.ItemPinTopic:        u.HandleItemPin,
+		common.ItemUnpinTopic:      u.HandleItemUnpin,
+		common.ItemExpireTopic:     u.HandleItemExpire,
+		common.ItemDueSoonTopic:    u.HandleItemDueSoon,
+		common.NudgePublishTopic:   u.HandleNudgePublish,
+		common.NudgeDeleteTopic:    u.HandleNudgeDelete,
+		common.NudgeResolveTopic:   u.HandleNudgeResolve,
+		common.NudgeUnresolveTopic: u.HandleNudgeUnresolve,
+		common.NudgeHideTopic:      u.HandleNudgeHide,
+		common.NudgeShowTopic:      u.HandleNudgeShow,
+		common.NudgeExpireTopic:    u.HandleNudgeExpire,
+		common.NudgeDueSoonTopic:   u.HandleNudgeDueSoon,
+		common.FeedBulkUpdateTopic: u.HandleFeedBulkUpdate,
+		common.ActionPublishTopic:  u.HandleActionPublish,
+		common.ActionDeleteTopic:   u.HandleActionDelete,
+		common.MessagePostTopic:    u.HandleMessagePost,
+		common.MessageDeleteTopic:  u.HandleMessageDelete,
+		common.MessageUpdateTopic:  u.HandleMessageUpdate,
+		common.IncomingEventTopic:  u.HandleIncomingEvent,
+		common.FcmPublishTopic:     u.HandleSendNotification,
+		common.SentEmailTopic:      u.SendNotificationEmail,
+		common.DigestDueTopic:      u.HandleDigestDue,
+	}
+	for name, handler := range handlers {
+		if err := topics.Handle(name, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Idempotent wraps a handler so that requests made with an `Idempotency-Key`
+// header are only carried out once. Repeats of a request within the replay
+// window get the first response back, marked with an `Idempotent-Replayed`
+// header. Keys are scoped to the caller so that one caller can't get another
+// caller's responses back.
+func (p PresentationHandlersImpl) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		caller, err := idempotencyCaller(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		body, err := readBody(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		response, replayed, err := p.usecases.Idempotent(
+			r.Context(),
+			fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, caller),
+			key,
+			body,
+			func(ctx context.Context) (*domain.IdempotentResponse, error) {
+				recorder := newResponseRecorder()
+				next(recorder, r.WithContext(ctx))
+				return recorder.response(), nil
+			},
+		)
+		switch {
+		case errors.Is(err, idempotency.ErrKeyReused):
+			respondWithError(w, http.StatusUnprocessableEntity, err)
+			return
+		case errors.Is(err, idempotency.ErrRequestInProgress):
+			respondWithError(w, http.StatusConflict, err)
+			return
+		case err != nil:
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if replayed {
+			w.Header().Set(IdempotentReplayedHeader, "true")
+		}
+		if response.ContentType != "" {
+			w.Header().Set("Content-Type", response.ContentType)
+		}
+		w.WriteHeader(response.StatusCode)
+		if _, err := w.Write(response.Body); err != nil {
+			log.Printf("unable to write idempotent response: %s", err)
+		}
+	}
 }
 
 // GoogleCloudPubSubHandler receives push messages from Google Cloud Pub-Sub
@@ -169,9 +364,18 @@ func (p PresentationHandlersImpl) GoogleCloudPubSubHandler(
 		return
 	}
 
-	// get the UID frrom the payload
-	var envelope dto.NotificationEnvelope
-	err = json.Unmarshal(m.Message.Data, &envelope)
+	topic, ok := p.topics.Topic(topicID)
+	if !ok || topic.Handler == nil {
+		errMsg := fmt.Sprintf(
+			"pub sub handler error: unknown topic `%s`",
+			topicID,
+		)
+		log.Print(errMsg)
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	payload, err := topic.Decode(m.Message.Data)
 	if err != nil {
 		serverutils.WriteJSONResponse(
 			w,
@@ -180,231 +384,41 @@ func (p PresentationHandlersImpl) GoogleCloudPubSubHandler(
 		)
 		return
 	}
-	ctx = addUIDToContext(ctx, envelope.UID)
+	if envelope, ok := payload.(*dto.NotificationEnvelope); ok {
+		ctx = addUIDToContext(ctx, envelope.UID)
+	}
 
-	switch topicID {
-	case helpers.AddPubSubNamespace(common.ItemPublishTopic):
-		err = p.usecases.HandleItemPublish(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.ItemDeleteTopic):
-		err = p.usecases.HandleItemDelete(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.ItemResolveTopic):
-		err = p.usecases.HandleItemResolve(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.ItemUnresolveTopic):
-		err = p.usecases.HandleItemUnresolve(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.ItemHideTopic):
-		err = p.usecases.HandleItemHide(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.ItemShowTopic):
-		err = p.usecases.HandleItemShow(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.ItemPinTopic):
-		err = p.usecases.HandleItemPin(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.ItemUnpinTopic):
-		err = p.usecases.HandleItemUnpin(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.NudgePublishTopic):
-		err = p.usecases.HandleNudgePublish(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.NudgeDeleteTopic):
-		err = p.usecases.HandleNudgeDelete(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.NudgeResolveTopic):
-		err = p.usecases.HandleNudgeResolve(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.NudgeUnresolveTopic):
-		err = p.usecases.HandleNudgeUnresolve(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.NudgeHideTopic):
-		err = p.usecases.HandleNudgeHide(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.NudgeShowTopic):
-		err = p.usecases.HandleNudgeShow(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.ActionPublishTopic):
-		err = p.usecases.HandleActionPublish(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.ActionDeleteTopic):
-		err = p.usecases.HandleActionDelete(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.MessagePostTopic):
-		err = p.usecases.HandleMessagePost(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.MessageDeleteTopic):
-		err = p.usecases.HandleMessageDelete(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.IncomingEventTopic):
-		err = p.usecases.HandleIncomingEvent(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.FcmPublishTopic):
-		err = p.usecases.HandleSendNotification(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	case helpers.AddPubSubNamespace(common.SentEmailTopic):
-		err = p.usecases.SendNotificationEmail(ctx, m)
-		if err != nil {
-			serverutils.WriteJSONResponse(
-				w,
-				errorcode.ErrorMap(err),
-				http.StatusBadRequest,
-			)
-			return
-		}
-	default:
-		// the topic should be anticipated/handled here
-		errMsg := fmt.Sprintf(
-			"pub sub handler error: unknown topic `%s`",
-			topicID,
+	duplicate, err := p.usecases.ProcessPubSubMessage(
+		ctx,
+		topic.Name,
+		m.Message.MessageID,
+		func(ctx context.Context) error {
+			return topic.Handler(ctx, m)
+		},
+	)
+	if errors.Is(err, idempotency.ErrMessageInProgress) {
+		// Pub/Sub redelivers the message later, in case the earlier delivery
+		// fails
+		serverutils.WriteJSONResponse(
+			w,
+			errorcode.ErrorMap(err),
+			http.StatusConflict,
+		)
+		return
+	}
+	if err != nil {
+		serverutils.WriteJSONResponse(
+			w,
+			errorcode.ErrorMap(err),
+			http.StatusBadRequest,
 		)
-		log.Print(errMsg)
-		http.Error(w, errMsg, http.StatusBadRequest)
 		return
 	}
 
 	resp := map[string]string{"status": "success"}
+	if duplicate {
+		resp["status"] = "duplicate"
+	}
 	marshalledSuccessMsg, err := json.Marshal(resp)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err)
@@ -994,8 +1008,9 @@ func (p PresentationHandlersImpl) PublishAction() http.HandlerFunc {
 	}
 }
 
-// DeleteAction permanently removes an action from a user's feed
-func (p PresentationHandlersImpl) DeleteAction() http.HandlerFunc {
+// InvokeAction runs the server-side handler of an action in a user's feed.
+// The optional JSON body is passed to the handler as its payload.
+func (p PresentationHandlersImpl) InvokeAction() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
@@ -1011,19 +1026,912 @@ func (p PresentationHandlersImpl) DeleteAction() http.HandlerFunc {
 			return
 		}
 
-		err = p.usecases.DeleteAction(
-			addUIDToContext(ctx, *uid),
-			*uid,
-			*flavour,
-			actionID,
-		)
+		data, err := readBody(r)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		payload := map[string]interface{}{}
+		if len(data) > 0 {
+			err = json.Unmarshal(data, &payload)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		result, err := p.usecases.InvokeAction(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			actionID,
+			payload,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(result)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// DeleteAction permanently removes an action from a user's feed
+func (p PresentationHandlersImpl) DeleteAction() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		actionID, err := getStringVar(r, "actionID")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		err = p.usecases.DeleteAction(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			actionID,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		resp := map[string]string{"status": "success"}
+		marshalled, err := json.Marshal(resp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// PostMessage adds a message to a thread
+func (p PresentationHandlersImpl) PostMessage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		data, err := readBody(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		itemID, err := getStringVar(r, "itemID")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		message := &feedlib.Message{}
+		err = message.ValidateAndUnmarshal(data)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		postedMessage, err := p.usecases.PostMessage(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			itemID,
+			message,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(postedMessage)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// DeleteMessage removes a message from a thread
+func (p PresentationHandlersImpl) DeleteMessage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		itemID, err := getStringVar(r, "itemID")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		messageID, err := getStringVar(r, "messageID")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		err = p.usecases.DeleteMessage(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			itemID,
+			messageID,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		resp := map[string]string{"status": "success"}
+		marshalled, err := json.Marshal(resp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// BulkUpdateFeed resolves, unresolves, pins, unpins, hides, shows or deletes
+// many items and nudges at once
+func (p PresentationHandlersImpl) BulkUpdateFeed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		data, err := readBody(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		input := &dto.BulkUpdateInput{}
+		err = json.Unmarshal(data, input)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		result, err := p.usecases.BulkUpdateFeed(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			input.Operation,
+			input.ItemIDs,
+			input.NudgeIDs,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(result)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// GetThread returns the conversation on an item with replies nested under
+// the messages that they reply to
+func (p PresentationHandlersImpl) GetThread() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		itemID, err := getStringVar(r, "itemID")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		thread, err := p.usecases.GetThread(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			itemID,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(thread)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// GetChecklist retrieves the checklist of a feed item
+func (p PresentationHandlersImpl) GetChecklist() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		itemID, err := getStringVar(r, "itemID")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		checklist, err := p.usecases.GetChecklist(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			itemID,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if checklist == nil {
+			respondWithError(w, http.StatusNotFound, errNotFound)
+			return
+		}
+
+		marshalled, err := json.Marshal(checklist)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// SetChecklist adds steps and dependencies to a feed item or replaces the
+// ones that it had
+func (p PresentationHandlersImpl) SetChecklist() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		itemID, err := getStringVar(r, "itemID")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		data, err := readBody(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		input := domain.Checklist{}
+		err = json.Unmarshal(data, &input)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		checklist, err := p.usecases.SetChecklist(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			itemID,
+			input,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(checklist)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// CompleteChecklistStep marks a step of a feed item's checklist as done
+func (p PresentationHandlersImpl) CompleteChecklistStep() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		itemID, err := getStringVar(r, "itemID")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		stepID, err := getStringVar(r, "stepID")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		checklist, err := p.usecases.CompleteChecklistStep(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			itemID,
+			stepID,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(checklist)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// EditMessage changes the text of a message, keeping the earlier text in the
+// message's edit history
+func (p PresentationHandlersImpl) EditMessage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		itemID, messageID, err := getItemAndMessageIDs(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		data, err := readBody(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		input := &dto.EditMessageInput{}
+		err = json.Unmarshal(data, input)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		if input.EditedByUID == "" {
+			input.EditedByUID = *uid
+		}
+
+		message, err := p.usecases.EditMessage(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			itemID,
+			messageID,
+			input.Text,
+			input.EditedByUID,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(message)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// ReactToMessage adds or removes an emoji reaction to a message
+func (p PresentationHandlersImpl) ReactToMessage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		itemID, messageID, err := getItemAndMessageIDs(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		data, err := readBody(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		input := &dto.MessageReactionInput{}
+		err = json.Unmarshal(data, input)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		if input.ReactorUID == "" {
+			input.ReactorUID = *uid
+		}
+
+		err = p.usecases.ReactToMessage(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			itemID,
+			messageID,
+			input.Emoji,
+			input.ReactorUID,
+			input.Reacted,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		resp := map[string]string{"status": "success"}
+		marshalled, err := json.Marshal(resp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// AttachToMessage attaches uploads to a message
+func (p PresentationHandlersImpl) AttachToMessage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		itemID, messageID, err := getItemAndMessageIDs(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		data, err := readBody(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		input := &dto.MessageAttachmentInput{}
+		err = json.Unmarshal(data, input)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		message, err := p.usecases.AttachToMessage(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			itemID,
+			messageID,
+			input.UploadIDs,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(message)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// ReportMessage reports a message in an item's conversation for staff to
+// review
+func (p PresentationHandlersImpl) ReportMessage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		itemID, messageID, err := getItemAndMessageIDs(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		data, err := readBody(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		input := &dto.MessageReportInput{}
+		err = json.Unmarshal(data, input)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		if input.ReportedByUID == "" {
+			input.ReportedByUID = *uid
+		}
+
+		moderationCase, err := p.usecases.ReportMessage(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			itemID,
+			messageID,
+			input.ReportedByUID,
+			input.Reason,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(moderationCase)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// ModerationQueue lists the reported messages with the `status` query
+// parameter, by default those that are waiting for review
+func (p PresentationHandlersImpl) ModerationQueue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cases, err := p.usecases.ModerationQueue(
+			r.Context(),
+			domain.ModerationStatus(r.FormValue("status")),
+		)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(cases)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// ModerateMessage records a moderator's decision on a reported message
+func (p PresentationHandlersImpl) ModerateMessage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caseID, err := getStringVar(r, "caseID")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		data, err := readBody(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		input := &dto.ModerationDecisionInput{}
+		err = json.Unmarshal(data, input)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		moderationCase, err := p.usecases.ModerateMessage(
+			r.Context(),
+			caseID,
+			input.Action,
+			input.ModeratorUID,
+			input.Reason,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(moderationCase)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// NotificationDeliveries lists the outcome of sending a feed element's
+// notifications over each of its channels
+func (p PresentationHandlersImpl) NotificationDeliveries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		elementID, err := getStringVar(r, "elementID")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		deliveries, err := p.usecases.NotificationDeliveries(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			elementID,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(deliveries)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// ModerationAudit lists the decisions made on a reported message
+func (p PresentationHandlersImpl) ModerationAudit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caseID, err := getStringVar(r, "caseID")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		entries, err := p.usecases.ModerationAudit(r.Context(), caseID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(entries)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// MuteThread stops new message notifications from an item's conversation for
+// a participant. The participant is the feed's owner unless a
+// `participantUID` is supplied.
+func (p PresentationHandlersImpl) MuteThread() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		muteThread(r.Context(), p.usecases.MuteThread, true, w, r)
+	}
+}
+
+// UnmuteThread restores new message notifications from an item's
+// conversation for a participant
+func (p PresentationHandlersImpl) UnmuteThread() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		muteThread(r.Context(), p.usecases.MuteThread, false, w, r)
+	}
+}
+
+// ProcessEvent saves an event
+func (p PresentationHandlersImpl) ProcessEvent() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		data, err := readBody(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		event := &feedlib.Event{}
+		err = event.ValidateAndUnmarshal(data)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		err = p.usecases.ProcessEvent(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			event,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		resp := map[string]string{"status": "success"}
+		marshalled, err := json.Marshal(resp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// SaveElementTranslations saves the language variants of a feed item or
+// nudge's text
+func (p PresentationHandlersImpl) SaveElementTranslations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		data, err := readBody(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		translations := &domain.ElementTranslations{}
+		err = json.Unmarshal(data, translations)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		saved, err := p.usecases.SaveElementTranslations(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*flavour,
+			translations,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(saved)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// FeedChanges returns the feed elements that changed after the sync token
+// in the optional `since` query parameter
+func (p PresentationHandlersImpl) FeedChanges() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		uid, flavour, anonymous, err := getUIDFlavourAndIsAnonymous(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		since := r.FormValue("since")
+		changes, err := p.usecases.FeedChanges(
+			addUIDToContext(ctx, *uid),
+			*uid,
+			*anonymous,
+			*flavour,
+			&since,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(changes)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// ProcessExpiredElements archives expired items and nudges and sends
+// "due soon" reminders. It is meant to be called periodically by a scheduler.
+func (p PresentationHandlersImpl) ProcessExpiredElements() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := p.usecases.ProcessExpiredElements(r.Context())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		resp := map[string]string{"status": "success"}
-		marshalled, err := json.Marshal(resp)
+		marshalled, err := json.Marshal(report)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err)
 			return
@@ -1033,49 +1941,86 @@ func (p PresentationHandlersImpl) DeleteAction() http.HandlerFunc {
 	}
 }
 
-// PostMessage adds a message to a thread
-func (p PresentationHandlersImpl) PostMessage() http.HandlerFunc {
+// ProcessDeferredNotifications sends the notifications that were held back
+// for users' quiet hours. It is meant to be called periodically by a
+// scheduler.
+func (p PresentationHandlersImpl) ProcessDeferredNotifications() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+		report, err := p.usecases.ProcessDeferredNotifications(r.Context())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
 
-		data, err := readBody(r)
+		marshalled, err := json.Marshal(report)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, err)
+			respondWithError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		itemID, err := getStringVar(r, "itemID")
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// ProcessDigests queues the daily and weekly digests that are due to be built
+// and sent. It is meant to be called at least hourly by a scheduler.
+func (p PresentationHandlersImpl) ProcessDigests() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := p.usecases.ProcessDigests(r.Context())
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, err)
+			respondWithError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		message := &feedlib.Message{}
-		err = message.ValidateAndUnmarshal(data)
+		marshalled, err := json.Marshal(report)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, err)
+			respondWithError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// ProcessInboxCountPushes pushes the inbox counts that changed in the last
+// window. It is meant to be called every minute or so by a scheduler.
+func (p PresentationHandlersImpl) ProcessInboxCountPushes() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := p.usecases.ProcessInboxCountPushes(r.Context())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(report)
 		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// TokenHealth reports on the delivery health of a user's FCM registration
+// tokens
+func (p PresentationHandlersImpl) TokenHealth() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload := &dto.UIDPayload{}
+		serverutils.DecodeJSONToTargetStruct(w, r, payload)
+		if payload.UID == nil || *payload.UID == "" {
+			err := fmt.Errorf("a UID is required")
 			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		postedMessage, err := p.usecases.PostMessage(
-			addUIDToContext(ctx, *uid),
-			*uid,
-			*flavour,
-			itemID,
-			message,
-		)
+		report, err := p.usecases.TokenHealth(r.Context(), *payload.UID)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		marshalled, err := json.Marshal(postedMessage)
+		marshalled, err := json.Marshal(report)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err)
 			return
@@ -1085,43 +2030,73 @@ func (p PresentationHandlersImpl) PostMessage() http.HandlerFunc {
 	}
 }
 
-// DeleteMessage removes a message from a thread
-func (p PresentationHandlersImpl) DeleteMessage() http.HandlerFunc {
+// ProcessFCMRetries sends the FCM messages that are due for another attempt
+// to the tokens that they have not reached
+func (p PresentationHandlersImpl) ProcessFCMRetries() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+		report, err := p.usecases.ProcessFCMRetries(r.Context())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
 
-		itemID, err := getStringVar(r, "itemID")
+		marshalled, err := json.Marshal(report)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, err)
+			respondWithError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		messageID, err := getStringVar(r, "messageID")
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// FCMDeadLetters lists the FCM messages that were given up on. The optional
+// `limit` query parameter caps how many are returned.
+func (p PresentationHandlersImpl) FCMDeadLetters() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := 0
+		if val := r.FormValue("limit"); val != "" {
+			parsed, err := strconv.Atoi(val)
+			if err != nil {
+				err := fmt.Errorf("invalid limit `%s`: %w", val, err)
+				respondWithError(w, http.StatusBadRequest, err)
+				return
+			}
+			limit = parsed
+		}
+
+		letters, err := p.usecases.FCMDeadLetters(r.Context(), limit)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, err)
+			respondWithError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		marshalled, err := json.Marshal(letters)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// ReplayFCMDeadLetter sends an FCM message that was given up on again
+func (p PresentationHandlersImpl) ReplayFCMDeadLetter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		letterID, err := getStringVar(r, "letterID")
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		err = p.usecases.DeleteMessage(
-			addUIDToContext(ctx, *uid),
-			*uid,
-			*flavour,
-			itemID,
-			messageID,
-		)
+		report, err := p.usecases.ReplayFCMDeadLetter(r.Context(), letterID)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		resp := map[string]string{"status": "success"}
-		marshalled, err := json.Marshal(resp)
+		marshalled, err := json.Marshal(report)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err)
 			return
@@ -1131,43 +2106,88 @@ func (p PresentationHandlersImpl) DeleteMessage() http.HandlerFunc {
 	}
 }
 
-// ProcessEvent saves an event
-func (p PresentationHandlersImpl) ProcessEvent() http.HandlerFunc {
+// SubscribeToTopic subscribes a user's devices to an FCM topic
+func (p PresentationHandlersImpl) SubscribeToTopic() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+		payload := &dto.TopicSubscriptionInput{}
+		serverutils.DecodeJSONToTargetStruct(w, r, payload)
+		if payload.UID == "" || payload.Topic == "" {
+			err := fmt.Errorf("both a UID and a topic are required")
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
 
-		data, err := readBody(r)
+		result, err := p.usecases.SubscribeUserToTopic(r.Context(), payload.UID, payload.Topic)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, err)
+			respondWithError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		event := &feedlib.Event{}
-		err = event.ValidateAndUnmarshal(data)
+		marshalled, err := json.Marshal(result)
 		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// UnsubscribeFromTopic unsubscribes a user's devices from an FCM topic
+func (p PresentationHandlersImpl) UnsubscribeFromTopic() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload := &dto.TopicSubscriptionInput{}
+		serverutils.DecodeJSONToTargetStruct(w, r, payload)
+		if payload.UID == "" || payload.Topic == "" {
+			err := fmt.Errorf("both a UID and a topic are required")
 			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		uid, flavour, _, err := getUIDFlavourAndIsAnonymous(r)
+		result, err := p.usecases.UnsubscribeUserFromTopic(r.Context(), payload.UID, payload.Topic)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(result)
 		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// SendToTopic sends an FCM message to the devices subscribed to a topic, or
+// that match a condition that combines topics
+func (p PresentationHandlersImpl) SendToTopic() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload := &dto.TopicMessagePayload{}
+		serverutils.DecodeJSONToTargetStruct(w, r, payload)
+		if payload.Topic == "" && payload.Condition == "" {
+			err := fmt.Errorf("a topic or a condition is required")
 			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		err = p.usecases.ProcessEvent(
-			addUIDToContext(ctx, *uid),
-			*uid,
-			*flavour,
-			event,
+		messageID, err := p.usecases.SendToTopic(
+			r.Context(),
+			payload.Topic,
+			payload.Condition,
+			payload.Data,
+			payload.Notification,
+			payload.Android,
+			payload.Ios,
+			payload.Web,
 		)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		resp := map[string]string{"status": "success"}
-		marshalled, err := json.Marshal(resp)
+		marshalled, err := json.Marshal(map[string]string{"messageID": messageID})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err)
 			return
@@ -1177,53 +2197,131 @@ func (p PresentationHandlersImpl) ProcessEvent() http.HandlerFunc {
 	}
 }
 
-// Upload saves an upload in cloud storage
-func (p PresentationHandlersImpl) Upload() http.HandlerFunc {
+// NotificationOpenRates reports on how the push notifications sent in a
+// period were received, by sender type. The period is given as RFC 3339
+// `since` and `until` query parameters and defaults to the last week.
+func (p PresentationHandlersImpl) NotificationOpenRates() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+		until := time.Now()
+		if val := r.FormValue("until"); val != "" {
+			parsed, err := time.Parse(time.RFC3339, val)
+			if err != nil {
+				err := fmt.Errorf("invalid until `%s`: %w", val, err)
+				respondWithError(w, http.StatusBadRequest, err)
+				return
+			}
+			until = parsed
+		}
+		since := until.AddDate(0, 0, -7)
+		if val := r.FormValue("since"); val != "" {
+			parsed, err := time.Parse(time.RFC3339, val)
+			if err != nil {
+				err := fmt.Errorf("invalid since `%s`: %w", val, err)
+				respondWithError(w, http.StatusBadRequest, err)
+				return
+			}
+			since = parsed
+		}
 
-		data, err := readBody(r)
+		rates, err := p.usecases.NotificationOpenRates(r.Context(), since, until)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, err)
+			respondWithError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		uploadInput := profileutils.UploadInput{}
-		err = json.Unmarshal(data, &uploadInput)
+		marshalled, err := json.Marshal(rates)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, err)
+			respondWithError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		if uploadInput.Base64data == "" {
-			err := fmt.Errorf("blank upload base64 data")
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// MergeAnonymousFeed carries an anonymous user's feed over to the feed of the
+// user that they signed up as
+func (p PresentationHandlersImpl) MergeAnonymousFeed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload := &dto.MergeAnonymousFeedInput{}
+		serverutils.DecodeJSONToTargetStruct(w, r, payload)
+		if payload.AnonymousUID == "" || payload.UID == "" {
+			err := fmt.Errorf("both the anonymous and the permanent UID are required")
 			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
-
-		if uploadInput.Filename == "" {
-			err := fmt.Errorf("blank upload filename")
+		if !payload.Flavour.IsValid() {
+			err := fmt.Errorf("invalid flavour `%s`", payload.Flavour)
 			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		if uploadInput.Title == "" {
-			err := fmt.Errorf("blank upload title")
+		merge, err := p.usecases.MergeAnonymousFeed(
+			r.Context(),
+			payload.AnonymousUID,
+			payload.UID,
+			payload.Flavour,
+		)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		marshalled, err := json.Marshal(merge)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// Upload saves an upload in cloud storage
+func (p PresentationHandlersImpl) Upload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		upload, err := p.upload(ctx, r)
+		if err != nil {
 			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		upload, err := p.infrastructure.Upload(ctx, uploadInput)
+		marshalled, err := json.Marshal(upload)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, marshalled)
+	}
+}
+
+// UploadToFeed saves an upload in cloud storage for a feed's user. Only
+// uploads made for a feed's user can be attached to the feed's messages.
+func (p PresentationHandlersImpl) UploadToFeed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		uid, _, _, err := getUIDFlavourAndIsAnonymous(r)
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
-		if upload == nil {
-			err := fmt.Errorf("nil upload in response from upload service")
+
+		upload, err := p.upload(ctx, r)
+		if err != nil {
 			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
 
+		err = p.infrastructure.SaveUploadOwner(ctx, upload.ID, *uid)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
 		marshalled, err := json.Marshal(upload)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err)
@@ -1234,6 +2332,44 @@ func (p PresentationHandlersImpl) Upload() http.HandlerFunc {
 	}
 }
 
+// upload saves the upload in a request's body
+func (p PresentationHandlersImpl) upload(
+	ctx context.Context,
+	r *http.Request,
+) (*profileutils.Upload, error) {
+	data, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadInput := profileutils.UploadInput{}
+	err = json.Unmarshal(data, &uploadInput)
+	if err != nil {
+		return nil, err
+	}
+
+	if uploadInput.Base64data == "" {
+		return nil, fmt.Errorf("blank upload base64 data")
+	}
+
+	if uploadInput.Filename == "" {
+		return nil, fmt.Errorf("blank upload filename")
+	}
+
+	if uploadInput.Title == "" {
+		return nil, fmt.Errorf("blank upload title")
+	}
+
+	upload, err := p.infrastructure.Upload(ctx, uploadInput)
+	if err != nil {
+		return nil, err
+	}
+	if upload == nil {
+		return nil, fmt.Errorf("nil upload in response from upload service")
+	}
+	return upload, nil
+}
+
 // FindUpload retrieves an upload by it's ID
 func (p PresentationHandlersImpl) FindUpload() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1290,11 +2426,12 @@ func (p PresentationHandlersImpl) SendEmail() http.HandlerFunc {
 			return
 		}
 
-		resp, _, err := p.infrastructure.SendEmail(
+		resp, err := p.usecases.SendEmailWithPreferences(
 			ctx,
 			payload.Subject,
 			payload.Subject,
 			&payload.Text,
+			payload.IsTransactional(),
 			payload.To...,
 		)
 		if err != nil {
@@ -1337,10 +2474,11 @@ func (p PresentationHandlersImpl) SendToMany() http.HandlerFunc {
 			return
 		}
 
-		resp, err := p.infrastructure.SendToMany(
+		resp, err := p.usecases.SendSMSWithPreferences(
 			ctx,
 			payload.To,
 			payload.Message,
+			payload.IsTransactional(),
 		)
 		if err != nil {
 			err := fmt.Errorf("sms not sent: %s", err)
@@ -1472,7 +2610,7 @@ func (p PresentationHandlersImpl) PhoneNumberVerificationCodeHandler() http.Hand
 
 		serverutils.DecodeJSONToTargetStruct(rw, r, payloadRequest)
 
-		ok, err := p.infrastructure.PhoneNumberVerificationCode(
+		ok, err := p.usecases.PhoneNumberVerificationCodeWithPreferences(
 			ctx,
 			payloadRequest.To,
 			payloadRequest.Code,
@@ -1656,21 +2794,28 @@ func (p PresentationHandlersImpl) SendNotificationHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		payload, payloadErr := fcm.ValidateSendNotificationPayload(w, r)
+		payload, payloadErr := fcm.ValidateNotificationPayload(w, r)
 		if payloadErr != nil {
 			errorcode.ReportErr(w, payloadErr, http.StatusBadRequest)
 			return
 		}
 
-		_, err := p.infrastructure.SendNotification(
-			ctx,
-			payload.RegistrationTokens,
-			payload.Data,
-			payload.Notification,
-			payload.Android,
-			payload.Ios,
-			payload.Web,
-		)
+		type okResp struct {
+			Status       string   `json:"status"`
+			Sent         int      `json:"sent,omitempty"`
+			FailedTokens []string `json:"failedTokens,omitempty"`
+		}
+
+		_, err := p.infrastructure.SendNotificationWithOptions(ctx, *payload)
+		var sendErr *fcm.SendError
+		if errors.As(err, &sendErr) && sendErr.Partial() {
+			serverutils.WriteJSONResponse(w, okResp{
+				Status:       "partial",
+				Sent:         sendErr.Sent,
+				FailedTokens: sendErr.Tokens(),
+			}, http.StatusOK)
+			return
+		}
 		if err != nil {
 			err := fmt.Errorf("notification not sent: %s", err)
 
@@ -1685,9 +2830,6 @@ func (p PresentationHandlersImpl) SendNotificationHandler() http.HandlerFunc {
 			return
 		}
 
-		type okResp struct {
-			Status string `json:"status"`
-		}
 		serverutils.WriteJSONResponse(w, okResp{Status: "ok"}, http.StatusOK)
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/i18n"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/twilio"
 	"github.com/savannahghi/engagementcore/pkg/engagement/presentation/rest"
@@ -38,6 +39,9 @@ func SharedUnauthenticatedRoutes(ctx context.Context, r *mux.Router) {
 	) // recover from panics by writing a HTTP error
 	r.Use(serverutils.RequestDebugMiddleware())
 
+	// Record the language requested via `Accept-Language` on the context
+	r.Use(i18n.AcceptLanguageMiddleware())
+
 	// Add Middleware that records the metrics for our HTTP routes
 	r.Use(serverutils.CustomHTTPRequestMetricsMiddleware())
 
@@ -108,37 +112,97 @@ func SharedAuthenticatedISCRoutes(ctx context.Context, r *mux.Router) {
 		h.GetAction(),
 	).Name("getAction")
 
+	feedISC.Methods(
+		http.MethodGet,
+	).Path("/changes/").HandlerFunc(
+		h.FeedChanges(),
+	).Name("feedChanges")
+
+	feedISC.Methods(
+		http.MethodGet,
+	).Path("/{itemID}/thread/").HandlerFunc(
+		h.GetThread(),
+	).Name("getThread")
+
+	feedISC.Methods(
+		http.MethodGet,
+	).Path("/items/{itemID}/checklist/").HandlerFunc(
+		h.GetChecklist(),
+	).Name("getChecklist")
+
+	feedISC.Methods(
+		http.MethodGet,
+	).Path("/deliveries/{elementID}/").HandlerFunc(
+		h.NotificationDeliveries(),
+	).Name("notificationDeliveries")
+
 	// creation
 	feedISC.Methods(
 		http.MethodPost,
 	).Path("/items/").HandlerFunc(
-		h.PublishFeedItem(),
+		h.Idempotent(h.PublishFeedItem()),
 	).Name("publishFeedItem")
 
 	feedISC.Methods(
 		http.MethodPost,
 	).Path("/nudges/").HandlerFunc(
-		h.PublishNudge(),
+		h.Idempotent(h.PublishNudge()),
 	).Name("publishNudge")
 
 	feedISC.Methods(
 		http.MethodPost,
 	).Path("/actions/").HandlerFunc(
-		h.PublishAction(),
+		h.Idempotent(h.PublishAction()),
 	).Name("publishAction")
 
 	feedISC.Methods(
 		http.MethodPost,
 	).Path("/{itemID}/messages/").HandlerFunc(
-		h.PostMessage(),
+		h.Idempotent(h.PostMessage()),
 	).Name("postMessage")
 
+	feedISC.Methods(
+		http.MethodPost,
+	).Path("/{itemID}/messages/{messageID}/reactions/").HandlerFunc(
+		h.ReactToMessage(),
+	).Name("reactToMessage")
+
+	feedISC.Methods(
+		http.MethodPost,
+	).Path("/{itemID}/messages/{messageID}/reports/").HandlerFunc(
+		h.ReportMessage(),
+	).Name("reportMessage")
+
+	feedISC.Methods(
+		http.MethodPost,
+	).Path("/{itemID}/messages/{messageID}/attachments/").HandlerFunc(
+		h.AttachToMessage(),
+	).Name("attachToMessage")
+
+	feedISC.Methods(
+		http.MethodPost,
+	).Path("/uploads/").HandlerFunc(
+		h.UploadToFeed(),
+	).Name("uploadToFeed")
+
 	feedISC.Methods(
 		http.MethodPost,
 	).Path("/events/").HandlerFunc(
 		h.ProcessEvent(),
 	).Name("postEvent")
 
+	feedISC.Methods(
+		http.MethodPost,
+	).Path("/actions/{actionID}/invoke/").HandlerFunc(
+		h.InvokeAction(),
+	).Name("invokeAction")
+
+	feedISC.Methods(
+		http.MethodPost,
+	).Path("/translations/").HandlerFunc(
+		h.SaveElementTranslations(),
+	).Name("saveElementTranslations")
+
 	// deleting
 	feedISC.Methods(
 		http.MethodDelete,
@@ -165,6 +229,12 @@ func SharedAuthenticatedISCRoutes(ctx context.Context, r *mux.Router) {
 	).Name("deleteMessage")
 
 	// modifying (patching)
+	feedISC.Methods(
+		http.MethodPatch,
+	).Path("/bulk/").HandlerFunc(
+		h.BulkUpdateFeed(),
+	).Name("bulkUpdateFeed")
+
 	feedISC.Methods(
 		http.MethodPatch,
 	).Path("/items/{itemID}/resolve/").HandlerFunc(
@@ -201,6 +271,36 @@ func SharedAuthenticatedISCRoutes(ctx context.Context, r *mux.Router) {
 		h.ShowFeedItem(),
 	).Name("showFeedItem")
 
+	feedISC.Methods(
+		http.MethodPut,
+	).Path("/items/{itemID}/checklist/").HandlerFunc(
+		h.SetChecklist(),
+	).Name("setChecklist")
+
+	feedISC.Methods(
+		http.MethodPatch,
+	).Path("/items/{itemID}/checklist/{stepID}/complete/").HandlerFunc(
+		h.CompleteChecklistStep(),
+	).Name("completeChecklistStep")
+
+	feedISC.Methods(
+		http.MethodPatch,
+	).Path("/items/{itemID}/mute/").HandlerFunc(
+		h.MuteThread(),
+	).Name("muteThread")
+
+	feedISC.Methods(
+		http.MethodPatch,
+	).Path("/{itemID}/messages/{messageID}/").HandlerFunc(
+		h.EditMessage(),
+	).Name("editMessage")
+
+	feedISC.Methods(
+		http.MethodPatch,
+	).Path("/items/{itemID}/unmute/").HandlerFunc(
+		h.UnmuteThread(),
+	).Name("unmuteThread")
+
 	feedISC.Methods(
 		http.MethodPatch,
 	).Path("/nudges/{nudgeID}/resolve/").HandlerFunc(
@@ -249,7 +349,7 @@ func SharedAuthenticatedISCRoutes(ctx context.Context, r *mux.Router) {
 	isc.Methods(
 		http.MethodPost,
 	).Path("/send_email").HandlerFunc(
-		h.SendEmail(),
+		h.Idempotent(h.SendEmail()),
 	).Name("sendEmail")
 
 	isc.Methods(
@@ -261,7 +361,7 @@ func SharedAuthenticatedISCRoutes(ctx context.Context, r *mux.Router) {
 	isc.Methods(
 		http.MethodPost,
 	).Path("/send_sms").HandlerFunc(
-		h.SendToMany(),
+		h.Idempotent(h.SendToMany()),
 	).Name("sendToMany")
 
 	isc.Path("/verify_phonenumber").Methods(http.MethodPost).HandlerFunc(
@@ -290,11 +390,107 @@ func SharedAuthenticatedISCRoutes(ctx context.Context, r *mux.Router) {
 
 	isc.Path("/send_notification").Methods(
 		http.MethodPost, http.MethodOptions,
-	).HandlerFunc(h.SendNotificationHandler())
+	).HandlerFunc(h.Idempotent(h.SendNotificationHandler()))
 
 	isc.Path("/send_temporary_pin").Methods(
 		http.MethodPost, http.MethodOptions,
 	).HandlerFunc(h.SendTemporaryPIN())
+
+	isc.Methods(
+		http.MethodPost,
+	).Path("/process_expired_elements").HandlerFunc(
+		h.ProcessExpiredElements(),
+	).Name("processExpiredElements")
+
+	isc.Methods(
+		http.MethodPost,
+	).Path("/process_deferred_notifications").HandlerFunc(
+		h.ProcessDeferredNotifications(),
+	).Name("processDeferredNotifications")
+
+	isc.Methods(
+		http.MethodPost,
+	).Path("/process_digests").HandlerFunc(
+		h.ProcessDigests(),
+	).Name("processDigests")
+
+	isc.Methods(
+		http.MethodPost,
+	).Path("/process_inbox_count_pushes").HandlerFunc(
+		h.ProcessInboxCountPushes(),
+	).Name("processInboxCountPushes")
+
+	isc.Methods(
+		http.MethodPost,
+	).Path("/token_health").HandlerFunc(
+		h.TokenHealth(),
+	).Name("tokenHealth")
+
+	isc.Methods(
+		http.MethodPost,
+	).Path("/process_fcm_retries").HandlerFunc(
+		h.ProcessFCMRetries(),
+	).Name("processFCMRetries")
+
+	isc.Methods(
+		http.MethodGet,
+	).Path("/fcm/dead_letters/").HandlerFunc(
+		h.FCMDeadLetters(),
+	).Name("fcmDeadLetters")
+
+	isc.Methods(
+		http.MethodPost,
+	).Path("/fcm/dead_letters/{letterID}/replay/").HandlerFunc(
+		h.ReplayFCMDeadLetter(),
+	).Name("replayFCMDeadLetter")
+
+	isc.Methods(
+		http.MethodPost,
+	).Path("/fcm/topics/subscribe").HandlerFunc(
+		h.SubscribeToTopic(),
+	).Name("subscribeToTopic")
+
+	isc.Methods(
+		http.MethodPost,
+	).Path("/fcm/topics/unsubscribe").HandlerFunc(
+		h.UnsubscribeFromTopic(),
+	).Name("unsubscribeFromTopic")
+
+	isc.Methods(
+		http.MethodPost,
+	).Path("/fcm/topics/send").HandlerFunc(
+		h.SendToTopic(),
+	).Name("sendToTopic")
+
+	isc.Methods(
+		http.MethodGet,
+	).Path("/fcm/open_rates/").HandlerFunc(
+		h.NotificationOpenRates(),
+	).Name("notificationOpenRates")
+
+	isc.Methods(
+		http.MethodGet,
+	).Path("/moderation/cases/").HandlerFunc(
+		h.ModerationQueue(),
+	).Name("moderationQueue")
+
+	isc.Methods(
+		http.MethodPatch,
+	).Path("/moderation/cases/{caseID}/").HandlerFunc(
+		h.ModerateMessage(),
+	).Name("moderateMessage")
+
+	isc.Methods(
+		http.MethodGet,
+	).Path("/moderation/cases/{caseID}/audit/").HandlerFunc(
+		h.ModerationAudit(),
+	).Name("moderationAudit")
+
+	isc.Methods(
+		http.MethodPost,
+	).Path("/merge_anonymous_feed").HandlerFunc(
+		h.MergeAnonymousFeed(),
+	).Name("mergeAnonymousFeed")
 }
 
 // AuthenticatedGraphQLRoute inits an authenticated GraphQL route
@@ -2,13 +2,24 @@ package fcm
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
 	"github.com/savannahghi/firebasetools"
 )
 
+// SubscribableTopicsEnvVarName is the environment variable that lists the
+// comma separated FCM topics that users can be subscribed to. A topic that
+// ends with `*` allows every topic that starts with the text before it e.g
+// `county-*`.
+const SubscribableTopicsEnvVarName = "FCM_SUBSCRIBABLE_TOPICS"
+
 // UsecaseFCM defines FCM service usecases interface
 type UsecaseFCM interface {
 	SendNotification(
@@ -38,6 +49,54 @@ type UsecaseFCM interface {
 		ios *firebasetools.FirebaseAPNSConfigInput,
 		web *firebasetools.FirebaseWebpushConfigInput,
 	) (bool, error)
+
+	TokenHealth(
+		ctx context.Context,
+		uid string,
+	) (*domain.TokenHealthReport, error)
+
+	SubscribeUserToTopic(
+		ctx context.Context,
+		uid string,
+		topic string,
+	) (*domain.TopicSubscriptionResult, error)
+
+	UnsubscribeUserFromTopic(
+		ctx context.Context,
+		uid string,
+		topic string,
+	) (*domain.TopicSubscriptionResult, error)
+
+	SendToTopic(
+		ctx context.Context,
+		topic string,
+		condition string,
+		data map[string]string,
+		notification *firebasetools.FirebaseSimpleNotificationInput,
+		android *firebasetools.FirebaseAndroidConfigInput,
+		ios *firebasetools.FirebaseAPNSConfigInput,
+		web *firebasetools.FirebaseWebpushConfigInput,
+	) (string, error)
+
+	AcknowledgeNotification(
+		ctx context.Context,
+		uid string,
+		messageID string,
+		status domain.NotificationStatus,
+	) (bool, error)
+
+	UserNotifications(
+		ctx context.Context,
+		uid string,
+		newerThan time.Time,
+		limit int,
+	) ([]*dto.SavedNotification, error)
+
+	NotificationOpenRates(
+		ctx context.Context,
+		since time.Time,
+		until time.Time,
+	) ([]domain.NotificationOpenRate, error)
 }
 
 // ImplFCM is the FCM service implementation
@@ -113,3 +172,252 @@ func (f *ImplFCM) SendFCMByPhoneOrEmail(
 		web,
 	)
 }
+
+// TokenHealth reports on the delivery health of each of a user's registration
+// tokens, and which of them are no longer sent to
+func (f *ImplFCM) TokenHealth(
+	ctx context.Context,
+	uid string,
+) (*domain.TokenHealthReport, error) {
+	tokens, err := f.userTokens(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	health, err := f.infrastructure.GetTokenHealth(ctx, tokens)
+	if err != nil {
+		return nil, fmt.Errorf("can't get token health: %w", err)
+	}
+
+	report := &domain.TokenHealthReport{
+		UID:    uid,
+		Tokens: []domain.TokenHealth{},
+	}
+	for _, token := range tokens {
+		record, ok := health[token]
+		if !ok {
+			record = domain.TokenHealth{Token: token}
+		}
+		switch {
+		case record.Invalid:
+			report.Invalid++
+		case record.ConsecutiveFailures > 0:
+			report.Failing++
+		default:
+			report.Healthy++
+		}
+		report.Tokens = append(report.Tokens, record)
+	}
+	return report, nil
+}
+
+// userTokens gets a user's registration tokens from the profile service
+func (f *ImplFCM) userTokens(ctx context.Context, uid string) ([]string, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("a UID is required")
+	}
+	userTokens, err := f.infrastructure.GetDeviceTokens(
+		ctx,
+		onboarding.UserUIDs{UIDs: []string{uid}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't get push tokens: %w", err)
+	}
+	return userTokens[uid], nil
+}
+
+// SubscribeUserToTopic subscribes all of a user's devices to an FCM topic
+// e.g one for their county or programme
+func (f *ImplFCM) SubscribeUserToTopic(
+	ctx context.Context,
+	uid string,
+	topic string,
+) (*domain.TopicSubscriptionResult, error) {
+	if !subscribableTopic(topic) {
+		return nil, fmt.Errorf("users can't be subscribed to the topic %s", topic)
+	}
+	tokens, err := f.userTokens(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	i := f.infrastructure.ServiceFCMImpl
+	result, err := i.SubscribeToTopic(ctx, tokens, topic)
+	if err != nil {
+		return nil, err
+	}
+	result.UID = uid
+	return result, nil
+}
+
+// subscribableTopic checks that a topic is one of the configured topics that
+// users can be subscribed to. No topic is allowed when none are configured.
+func subscribableTopic(topic string) bool {
+	if topic == "" {
+		return false
+	}
+	for _, allowed := range strings.Split(os.Getenv(SubscribableTopicsEnvVarName), ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "" {
+			continue
+		}
+		if strings.HasSuffix(allowed, "*") {
+			if strings.HasPrefix(topic, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+			continue
+		}
+		if topic == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// UnsubscribeUserFromTopic unsubscribes all of a user's devices from an FCM
+// topic
+func (f *ImplFCM) UnsubscribeUserFromTopic(
+	ctx context.Context,
+	uid string,
+	topic string,
+) (*domain.TopicSubscriptionResult, error) {
+	tokens, err := f.userTokens(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	i := f.infrastructure.ServiceFCMImpl
+	result, err := i.UnsubscribeFromTopic(ctx, tokens, topic)
+	if err != nil {
+		return nil, err
+	}
+	result.UID = uid
+	return result, nil
+}
+
+// SendToTopic sends a message to the devices subscribed to a topic, or that
+// match a condition that combines topics
+func (f *ImplFCM) SendToTopic(
+	ctx context.Context,
+	topic string,
+	condition string,
+	data map[string]string,
+	notification *firebasetools.FirebaseSimpleNotificationInput,
+	android *firebasetools.FirebaseAndroidConfigInput,
+	ios *firebasetools.FirebaseAPNSConfigInput,
+	web *firebasetools.FirebaseWebpushConfigInput,
+) (string, error) {
+	i := f.infrastructure.ServiceFCMImpl
+	return i.SendToTopic(
+		ctx,
+		topic,
+		condition,
+		data,
+		notification,
+		android,
+		ios,
+		web,
+	)
+}
+
+// AcknowledgeNotification records that a user's app received, dismissed or
+// opened the notification with an FCM message ID. Acknowledgements that
+// arrive out of order do not set a notification's status back.
+func (f *ImplFCM) AcknowledgeNotification(
+	ctx context.Context,
+	uid string,
+	messageID string,
+	status domain.NotificationStatus,
+) (bool, error) {
+	if messageID == "" {
+		return false, fmt.Errorf("a message ID is required")
+	}
+	if !status.IsAcknowledgement() {
+		return false, fmt.Errorf("%s is not an acknowledgement", status)
+	}
+	notifications, err := f.infrastructure.GetNotificationsByMessageID(ctx, messageID)
+	if err != nil {
+		return false, fmt.Errorf("can't get notifications: %w", err)
+	}
+	if len(notifications) == 0 {
+		return false, fmt.Errorf("no notification with the message ID %s", messageID)
+	}
+	for _, notification := range notifications {
+		// one record stands for every device subscribed to the topic, so
+		// one user's acknowledgement can't be recorded on it
+		if notification.RegistrationToken == "" {
+			return false, fmt.Errorf(
+				"the notification %s was sent to a topic and can't be acknowledged",
+				messageID,
+			)
+		}
+	}
+
+	tokens, err := f.userTokens(ctx, uid)
+	if err != nil {
+		return false, err
+	}
+	if !sentToTokens(notifications, tokens) {
+		return false, fmt.Errorf(
+			"the notification %s was not sent to the user's devices", messageID)
+	}
+
+	err = f.infrastructure.UpdateNotificationStatus(ctx, messageID, status, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("can't acknowledge notification: %w", err)
+	}
+	return true, nil
+}
+
+// sentToTokens checks that notifications were sent to one of the tokens
+func sentToTokens(notifications []*dto.SavedNotification, tokens []string) bool {
+	for _, notification := range notifications {
+		for _, token := range tokens {
+			if notification.RegistrationToken == token {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UserNotifications retrieves the latest notifications sent to the devices
+// that a user has, the latest first
+func (f *ImplFCM) UserNotifications(
+	ctx context.Context,
+	uid string,
+	newerThan time.Time,
+	limit int,
+) ([]*dto.SavedNotification, error) {
+	tokens, err := f.userTokens(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return []*dto.SavedNotification{}, nil
+	}
+	notifications, err := f.infrastructure.RetrieveNotificationsForTokens(
+		ctx,
+		tokens,
+		newerThan,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't get notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// NotificationOpenRates reports on how many of the notifications sent in a
+// period were delivered and opened, by sender type
+func (f *ImplFCM) NotificationOpenRates(
+	ctx context.Context,
+	since time.Time,
+	until time.Time,
+) ([]domain.NotificationOpenRate, error) {
+	if !until.After(since) {
+		return nil, fmt.Errorf("the end of the period should be after its start")
+	}
+	rates, err := f.infrastructure.NotificationOpenRates(ctx, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("can't get notification open rates: %w", err)
+	}
+	return rates, nil
+}
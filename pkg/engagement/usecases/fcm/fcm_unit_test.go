@@ -3,15 +3,22 @@ package fcm_test
 import (
 	"context"
 	"github.com/pkg/errors"
+	"os"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
 	fcmMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/fcm/mock"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
+	onboardingMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding/mock"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/fcm"
 	"github.com/savannahghi/firebasetools"
 	"github.com/savannahghi/interserviceclient"
+	"github.com/stretchr/testify/assert"
 )
 
 var (
@@ -280,3 +287,256 @@ func TestUnit_SendFCMByPhoneOrEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestUnit_TokenHealth(t *testing.T) {
+	ctx := context.Background()
+	f := fcm.NewFCM(infrastructure.Interactor{
+		ProfileService: &onboardingMock.FakeServiceOnboarding{
+			GetDeviceTokensFn: func(
+				ctx context.Context,
+				uids onboarding.UserUIDs,
+			) (map[string][]string, error) {
+				if uids.UIDs[0] == "unknown" {
+					return nil, errors.New("profile service unavailable")
+				}
+				return map[string][]string{
+					"uid": {"ok", "failing", "dead"},
+				}, nil
+			},
+		},
+		Repository: &mock.FakeEngagementRepository{
+			GetTokenHealthFn: func(
+				ctx context.Context,
+				tokens []string,
+			) (map[string]domain.TokenHealth, error) {
+				return map[string]domain.TokenHealth{
+					"failing": {Token: "failing", Failures: 3, ConsecutiveFailures: 1},
+					"dead":    {Token: "dead", Failures: 1, Invalid: true},
+				}, nil
+			},
+		},
+	})
+
+	report, err := f.TokenHealth(ctx, "uid")
+	assert.Nil(t, err)
+	assert.Equal(t, "uid", report.UID)
+	assert.Equal(t, 1, report.Healthy)
+	assert.Equal(t, 1, report.Failing)
+	assert.Equal(t, 1, report.Invalid)
+	assert.Len(t, report.Tokens, 3)
+	assert.Equal(t, domain.TokenHealth{Token: "ok"}, report.Tokens[0])
+	assert.Equal(t, 3, report.Tokens[1].Failures)
+
+	_, err = f.TokenHealth(ctx, "")
+	assert.NotNil(t, err)
+
+	_, err = f.TokenHealth(ctx, "unknown")
+	assert.NotNil(t, err)
+}
+
+func TestUnit_TopicSubscriptionsNeedAUser(t *testing.T) {
+	ctx := context.Background()
+	initialTopics := os.Getenv(fcm.SubscribableTopicsEnvVarName)
+	defer os.Setenv(fcm.SubscribableTopicsEnvVarName, initialTopics)
+	os.Setenv(fcm.SubscribableTopicsEnvVarName, "news")
+
+	f := fcm.NewFCM(infrastructure.Interactor{
+		ProfileService: &onboardingMock.FakeServiceOnboarding{
+			GetDeviceTokensFn: func(
+				ctx context.Context,
+				uids onboarding.UserUIDs,
+			) (map[string][]string, error) {
+				return nil, errors.New("profile service unavailable")
+			},
+		},
+	})
+
+	_, err := f.SubscribeUserToTopic(ctx, "", "news")
+	assert.NotNil(t, err)
+
+	_, err = f.UnsubscribeUserFromTopic(ctx, "", "news")
+	assert.NotNil(t, err)
+
+	_, err = f.SubscribeUserToTopic(ctx, "uid", "news")
+	assert.NotNil(t, err)
+}
+
+func TestUnit_SubscribeUserToTopicAllowList(t *testing.T) {
+	ctx := context.Background()
+	initialTopics := os.Getenv(fcm.SubscribableTopicsEnvVarName)
+	defer os.Setenv(fcm.SubscribableTopicsEnvVarName, initialTopics)
+
+	lookups := 0
+	f := fcm.NewFCM(infrastructure.Interactor{
+		ProfileService: &onboardingMock.FakeServiceOnboarding{
+			GetDeviceTokensFn: func(
+				ctx context.Context,
+				uids onboarding.UserUIDs,
+			) (map[string][]string, error) {
+				lookups++
+				return nil, errors.New("profile service unavailable")
+			},
+		},
+	})
+
+	os.Setenv(fcm.SubscribableTopicsEnvVarName, "")
+	_, err := f.SubscribeUserToTopic(ctx, "uid", "news")
+	assert.NotNil(t, err, "no topic is allowed when none are configured")
+	assert.Equal(t, 0, lookups)
+
+	os.Setenv(fcm.SubscribableTopicsEnvVarName, "news, county-*")
+	for _, topic := range []string{"all-users", "newsletter", "programme-mch", "county"} {
+		_, err = f.SubscribeUserToTopic(ctx, "uid", topic)
+		assert.Contains(t, err.Error(), "can't be subscribed", topic)
+	}
+	assert.Equal(t, 0, lookups, "topics outside the allow list are rejected up front")
+
+	for _, topic := range []string{"news", "county-nairobi"} {
+		_, err = f.SubscribeUserToTopic(ctx, "uid", topic)
+		assert.Contains(t, err.Error(), "profile service unavailable", topic)
+	}
+	assert.Equal(t, 2, lookups)
+}
+
+func TestUnit_AcknowledgeNotification(t *testing.T) {
+	ctx := context.Background()
+	updated := map[string]domain.NotificationStatus{}
+	f := fcm.NewFCM(infrastructure.Interactor{
+		ProfileService: &onboardingMock.FakeServiceOnboarding{
+			GetDeviceTokensFn: func(
+				ctx context.Context,
+				uids onboarding.UserUIDs,
+			) (map[string][]string, error) {
+				return map[string][]string{"uid": {"mine"}}, nil
+			},
+		},
+		Repository: &mock.FakeEngagementRepository{
+			GetNotificationsByMessageIDFn: func(
+				ctx context.Context,
+				messageID string,
+			) ([]*dto.SavedNotification, error) {
+				switch messageID {
+				case "mine":
+					return []*dto.SavedNotification{{RegistrationToken: "mine"}}, nil
+				case "topic":
+					return []*dto.SavedNotification{{Topic: "news"}}, nil
+				case "theirs":
+					return []*dto.SavedNotification{{RegistrationToken: "theirs"}}, nil
+				}
+				return []*dto.SavedNotification{}, nil
+			},
+			UpdateNotificationStatusFn: func(
+				ctx context.Context,
+				messageID string,
+				status domain.NotificationStatus,
+				at time.Time,
+			) error {
+				updated[messageID] = status
+				return nil
+			},
+		},
+	})
+
+	ok, err := f.AcknowledgeNotification(ctx, "uid", "mine", domain.NotificationStatusOpened)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, domain.NotificationStatusOpened, updated["mine"])
+
+	_, err = f.AcknowledgeNotification(ctx, "uid", "topic", domain.NotificationStatusDelivered)
+	assert.NotNil(t, err, "topic notifications are not sent to particular users")
+	assert.NotContains(t, updated, "topic")
+
+	_, err = f.AcknowledgeNotification(ctx, "uid", "theirs", domain.NotificationStatusOpened)
+	assert.NotNil(t, err)
+	assert.NotContains(t, updated, "theirs")
+
+	_, err = f.AcknowledgeNotification(ctx, "uid", "unknown", domain.NotificationStatusOpened)
+	assert.NotNil(t, err)
+
+	_, err = f.AcknowledgeNotification(ctx, "uid", "mine", domain.NotificationStatusSent)
+	assert.NotNil(t, err, "only apps' acknowledgements are accepted")
+
+	_, err = f.AcknowledgeNotification(ctx, "uid", "", domain.NotificationStatusOpened)
+	assert.NotNil(t, err)
+}
+
+func TestUnit_UserNotifications(t *testing.T) {
+	ctx := context.Background()
+	f := fcm.NewFCM(infrastructure.Interactor{
+		ProfileService: &onboardingMock.FakeServiceOnboarding{
+			GetDeviceTokensFn: func(
+				ctx context.Context,
+				uids onboarding.UserUIDs,
+			) (map[string][]string, error) {
+				return map[string][]string{"uid": {"a", "b"}}, nil
+			},
+		},
+		Repository: &mock.FakeEngagementRepository{
+			RetrieveNotificationsForTokensFn: func(
+				ctx context.Context,
+				registrationTokens []string,
+				newerThan time.Time,
+				limit int,
+			) ([]*dto.SavedNotification, error) {
+				notifications := []*dto.SavedNotification{}
+				for _, token := range registrationTokens {
+					notifications = append(notifications, &dto.SavedNotification{
+						RegistrationToken: token,
+					})
+				}
+				return notifications, nil
+			},
+		},
+	})
+
+	notifications, err := f.UserNotifications(ctx, "uid", time.Now().Add(-time.Hour), 10)
+	assert.Nil(t, err)
+	assert.Len(t, notifications, 2)
+
+	notifications, err = f.UserNotifications(ctx, "deviceless", time.Now(), 10)
+	assert.Nil(t, err)
+	assert.Empty(t, notifications)
+
+	_, err = f.UserNotifications(ctx, "", time.Now(), 10)
+	assert.NotNil(t, err)
+}
+
+func TestUnit_NotificationOpenRates(t *testing.T) {
+	ctx := context.Background()
+	f := fcm.NewFCM(infrastructure.Interactor{
+		Repository: &mock.FakeEngagementRepository{
+			NotificationOpenRatesFn: func(
+				ctx context.Context,
+				since time.Time,
+				until time.Time,
+			) ([]domain.NotificationOpenRate, error) {
+				rate := domain.NotificationOpenRate{SenderType: "ITEM_PUBLISHED"}
+				for _, status := range []domain.NotificationStatus{
+					"",
+					domain.NotificationStatusSent,
+					domain.NotificationStatusFailed,
+					domain.NotificationStatusDelivered,
+					domain.NotificationStatusDismissed,
+					domain.NotificationStatusOpened,
+				} {
+					rate.Count(status)
+				}
+				return []domain.NotificationOpenRate{rate}, nil
+			},
+		},
+	})
+
+	now := time.Now()
+	rates, err := f.NotificationOpenRates(ctx, now.Add(-time.Hour), now)
+	assert.Nil(t, err)
+	assert.Len(t, rates, 1)
+	assert.Equal(t, 5, rates[0].Sent, "failed sends are left out")
+	assert.Equal(t, 3, rates[0].Delivered)
+	assert.Equal(t, 1, rates[0].Dismissed)
+	assert.Equal(t, 1, rates[0].Opened)
+	assert.Equal(t, 0.6, rates[0].DeliveryRate)
+	assert.Equal(t, 0.2, rates[0].OpenRate)
+
+	_, err = f.NotificationOpenRates(ctx, now, now.Add(-time.Hour))
+	assert.NotNil(t, err)
+}
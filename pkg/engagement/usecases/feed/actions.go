@@ -0,0 +1,152 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
+	"github.com/savannahghi/feedlib"
+)
+
+// VerifyEmailActionName is the name of the action that sends a user a code to
+// verify their email address with
+const VerifyEmailActionName = "VERIFY_EMAIL"
+
+// ActionHandler runs the server-side part of a feed action
+type ActionHandler func(
+	ctx context.Context,
+	infra infrastructure.Interactor,
+	invocation domain.ActionInvocation,
+) (*domain.ActionResult, error)
+
+// actionHandlers maps action names to their server-side handlers. Actions
+// without a handler are only interpreted by clients.
+var actionHandlers = struct {
+	sync.RWMutex
+	byName map[string]ActionHandler
+}{
+	byName: map[string]ActionHandler{
+		VerifyEmailActionName: verifyEmailAction,
+	},
+}
+
+// RegisterActionHandler sets the server-side handler of the actions with the
+// supplied name, replacing any handler that was registered before
+func RegisterActionHandler(name string, handler ActionHandler) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("an action handler needs an action name")
+	}
+	if handler == nil {
+		return fmt.Errorf("nil action handler for %s", name)
+	}
+
+	actionHandlers.Lock()
+	defer actionHandlers.Unlock()
+	actionHandlers.byName[name] = handler
+	return nil
+}
+
+// actionHandler returns the server-side handler of the actions with the
+// supplied name, if any
+func actionHandler(name string) (ActionHandler, bool) {
+	actionHandlers.RLock()
+	defer actionHandlers.RUnlock()
+	handler, ok := actionHandlers.byName[name]
+	return handler, ok
+}
+
+// InvokeAction runs the server-side handler of an action in a user's feed.
+// The payload carries any input that the handler needs e.g the email address
+// to verify.
+func (fe UseCaseImpl) InvokeAction(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	actionID string,
+	payload map[string]interface{},
+) (*domain.ActionResult, error) {
+	ctx, span := tracer.Start(ctx, "InvokeAction")
+	defer span.End()
+
+	action, err := fe.infrastructure.GetAction(ctx, uid, flavour, actionID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to retrieve action %s: %w", actionID, err)
+	}
+	if action == nil {
+		return nil, fmt.Errorf("no action with ID %s", actionID)
+	}
+
+	handler, ok := actionHandler(action.Name)
+	if !ok {
+		return nil, fmt.Errorf("the %s action has no server-side handler", action.Name)
+	}
+
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	result, err := handler(ctx, fe.infrastructure, domain.ActionInvocation{
+		UID:     uid,
+		Flavour: flavour,
+		Action:  *action,
+		Payload: payload,
+	})
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to run the %s action: %w", action.Name, err)
+	}
+	if result == nil {
+		result = &domain.ActionResult{}
+	}
+	result.ActionID, result.Name = action.ID, action.Name
+	if result.Data == nil {
+		result.Data = map[string]interface{}{}
+	}
+
+	return result, nil
+}
+
+// verifyEmailAction sends an email verification code to the email address in
+// the payload or, when none is supplied, to the user's email address on
+// their profile
+func verifyEmailAction(
+	ctx context.Context,
+	infra infrastructure.Interactor,
+	invocation domain.ActionInvocation,
+) (*domain.ActionResult, error) {
+	email, _ := invocation.Payload["email"].(string)
+	email = strings.TrimSpace(email)
+	if email == "" {
+		addresses, err := infra.GetEmailAddresses(ctx, onboarding.UserUIDs{
+			UIDs: []string{invocation.UID},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the user's email address: %w", err)
+		}
+		if userAddresses := addresses[invocation.UID]; len(userAddresses) > 0 {
+			email = userAddresses[0]
+		}
+	}
+	if email == "" {
+		return nil, fmt.Errorf("no email address to verify")
+	}
+
+	// the code is only sent to the email address, never to the client
+	_, err := infra.EmailVerificationOtp(ctx, &email)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send email verification code: %w", err)
+	}
+
+	return &domain.ActionResult{
+		Message: fmt.Sprintf("a verification code was sent to %s", email),
+		Data: map[string]interface{}{
+			"email": email,
+		},
+	}, nil
+}
@@ -0,0 +1,103 @@
+package feed
+
+import (
+	"context"
+	"testing"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterActionHandler(t *testing.T) {
+	handler := func(
+		ctx context.Context,
+		infra infrastructure.Interactor,
+		invocation domain.ActionInvocation,
+	) (*domain.ActionResult, error) {
+		return &domain.ActionResult{}, nil
+	}
+
+	assert.NotNil(t, RegisterActionHandler(" ", handler))
+	assert.NotNil(t, RegisterActionHandler("TEST_REGISTER", nil))
+	assert.Nil(t, RegisterActionHandler("TEST_REGISTER", handler))
+
+	_, ok := actionHandler("TEST_REGISTER")
+	assert.True(t, ok)
+	_, ok = actionHandler(VerifyEmailActionName)
+	assert.True(t, ok)
+}
+
+func TestUseCaseImpl_InvokeAction(t *testing.T) {
+	ctx := context.Background()
+	actions := map[string]*feedlib.Action{
+		"handled":   {ID: "handled", Name: "TEST_INVOKE"},
+		"unhandled": {ID: "unhandled", Name: "CLIENT_ONLY"},
+	}
+	repository := &mock.FakeEngagementRepository{
+		GetActionFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			actionID string,
+		) (*feedlib.Action, error) {
+			return actions[actionID], nil
+		},
+	}
+	fe := NewFeed(infrastructure.Interactor{Repository: repository})
+
+	err := RegisterActionHandler("TEST_INVOKE", func(
+		ctx context.Context,
+		infra infrastructure.Interactor,
+		invocation domain.ActionInvocation,
+	) (*domain.ActionResult, error) {
+		return &domain.ActionResult{
+			Message: invocation.UID,
+			Data:    invocation.Payload,
+		}, nil
+	})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name     string
+		actionID string
+		wantErr  bool
+	}{
+		{
+			name:     "happy case: action with a handler",
+			actionID: "handled",
+		},
+		{
+			name:     "sad case: action without a handler",
+			actionID: "unhandled",
+			wantErr:  true,
+		},
+		{
+			name:     "sad case: action not in the feed",
+			actionID: "missing",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fe.InvokeAction(
+				ctx,
+				"uid",
+				feedlib.FlavourConsumer,
+				tt.actionID,
+				map[string]interface{}{"key": "value"},
+			)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "handled", result.ActionID)
+			assert.Equal(t, "TEST_INVOKE", result.Name)
+			assert.Equal(t, "uid", result.Message)
+			assert.Equal(t, "value", result.Data["key"])
+		})
+	}
+}
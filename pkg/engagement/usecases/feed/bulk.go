@@ -0,0 +1,250 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// maxBulkElements is the most elements that one bulk operation can change
+const maxBulkElements = 500
+
+// BulkUpdateFeed applies an operation to many items and nudges in a feed.
+//
+// Each element is changed independently and its outcome is reported in the
+// result; one element failing does not stop the others from changing.
+// Instead of a notification per element, a single message is published on
+// the `feed.bulk_update` topic and the inbox is recounted once. The changes
+// stand when that message can't be published, so the failure is logged and
+// the result is still returned.
+func (fe UseCaseImpl) BulkUpdateFeed(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	operation domain.BulkOperation,
+	itemIDs []string,
+	nudgeIDs []string,
+) (*domain.BulkResult, error) {
+	ctx, span := tracer.Start(ctx, "BulkUpdateFeed")
+	defer span.End()
+
+	if !operation.IsValid() {
+		return nil, fmt.Errorf("invalid bulk operation: %s", operation)
+	}
+	itemIDs = uniqueIDs(itemIDs)
+	nudgeIDs = uniqueIDs(nudgeIDs)
+	if len(itemIDs)+len(nudgeIDs) == 0 {
+		return nil, fmt.Errorf("no items or nudges to %s", operation)
+	}
+	if len(itemIDs)+len(nudgeIDs) > maxBulkElements {
+		return nil, fmt.Errorf(
+			"a bulk operation can change at most %d elements", maxBulkElements)
+	}
+
+	result := &domain.BulkResult{
+		UID:       uid,
+		Flavour:   flavour,
+		Operation: operation,
+		Results:   []domain.BulkElementResult{},
+	}
+	users := []string{}
+	for _, itemID := range itemIDs {
+		item, err := fe.bulkUpdateItem(ctx, uid, flavour, operation, itemID)
+		if item != nil {
+			users = append(users, item.Users...)
+		}
+		result.Add(domain.ElementTypeItem, itemID, err)
+	}
+	for _, nudgeID := range nudgeIDs {
+		nudge, err := fe.bulkUpdateNudge(ctx, uid, flavour, operation, nudgeID)
+		if nudge != nil {
+			users = append(users, nudge.Users...)
+		}
+		result.Add(domain.ElementTypeNudge, nudgeID, err)
+	}
+
+	if result.Succeeded == 0 {
+		return result, nil
+	}
+
+	if err := fe.infrastructure.Notify(
+		ctx,
+		helpers.AddPubSubNamespace(common.FeedBulkUpdateTopic),
+		uid,
+		flavour,
+		result,
+		map[string]interface{}{
+			"operation": operation.String(),
+			"users":     uniqueIDs(users),
+		},
+	); err != nil {
+		helpers.RecordSpanError(span, err)
+		log.Printf("unable to notify bulk %s of %s's feed: %v", operation, uid, err)
+	}
+
+	return result, nil
+}
+
+// bulkUpdateItem applies a bulk operation to one item and returns the item
+// as it was before it was deleted or after it was updated
+func (fe UseCaseImpl) bulkUpdateItem(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	operation domain.BulkOperation,
+	itemID string,
+) (*feedlib.Item, error) {
+	item, err := fe.infrastructure.GetFeedItem(ctx, uid, flavour, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get feed item with ID %s", itemID)
+	}
+
+	if operation == domain.BulkOperationDelete {
+		if item == nil {
+			return nil, nil // does not exist, nothing to delete
+		}
+		err = fe.infrastructure.DeleteFeedItem(ctx, uid, flavour, itemID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to delete item: %w", err)
+		}
+		return item, nil
+	}
+
+	if item == nil {
+		return nil, exceptions.ErrNilFeedItem
+	}
+	if operation == domain.BulkOperationResolve {
+		if err := fe.checkResolvable(ctx, uid, flavour, itemID); err != nil {
+			return nil, err
+		}
+	}
+	applyItemOperation(item, operation)
+	item, err = fe.infrastructure.UpdateFeedItem(ctx, uid, flavour, item)
+	if err != nil {
+		return nil, fmt.Errorf("unable to update feed item: %w", err)
+	}
+	return item, nil
+}
+
+// bulkUpdateNudge applies a bulk operation to one nudge and returns the
+// nudge as it was before it was deleted or after it was updated
+func (fe UseCaseImpl) bulkUpdateNudge(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	operation domain.BulkOperation,
+	nudgeID string,
+) (*feedlib.Nudge, error) {
+	if !operation.AppliesTo(domain.ElementTypeNudge) {
+		return nil, fmt.Errorf("nudges can't be changed with %s", operation)
+	}
+
+	nudge, err := fe.infrastructure.GetNudge(ctx, uid, flavour, nudgeID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get nudge with ID %s", nudgeID)
+	}
+
+	if operation == domain.BulkOperationDelete {
+		if nudge == nil {
+			return nil, nil // "re-deleting" a nudge should not cause an error
+		}
+		err = fe.infrastructure.DeleteNudge(ctx, uid, flavour, nudgeID)
+		if err != nil {
+			return nil, fmt.Errorf("can't delete nudge: %w", err)
+		}
+		return nudge, nil
+	}
+
+	if nudge == nil {
+		return nil, exceptions.ErrNilNudge
+	}
+	applyNudgeOperation(nudge, operation)
+	nudge, err = fe.infrastructure.UpdateNudge(ctx, uid, flavour, nudge)
+	if err != nil {
+		return nil, fmt.Errorf("unable to update nudge: %w", err)
+	}
+	return nudge, nil
+}
+
+// applyItemOperation changes an item the same way that resolving, pinning,
+// hiding etc the item on its own would
+func applyItemOperation(item *feedlib.Item, operation domain.BulkOperation) {
+	var from, to string
+	switch operation {
+	case domain.BulkOperationResolve:
+		item.Status = feedlib.StatusDone
+		from, to = common.ResolveItemActionName, common.UnResolveItemActionName
+	case domain.BulkOperationUnresolve:
+		item.Status = feedlib.StatusPending
+		from, to = common.UnResolveItemActionName, common.ResolveItemActionName
+	case domain.BulkOperationPin:
+		item.Persistent = true
+		from, to = common.PinItemActionName, common.UnPinItemActionName
+	case domain.BulkOperationUnpin:
+		item.Persistent = false
+		from, to = common.UnPinItemActionName, common.PinItemActionName
+	case domain.BulkOperationHide:
+		item.Visibility = feedlib.VisibilityHide
+		from, to = common.HideItemActionName, common.ShowItemActionName
+	case domain.BulkOperationShow:
+		item.Visibility = feedlib.VisibilityShow
+		from, to = common.ShowItemActionName, common.HideItemActionName
+	default:
+		return
+	}
+	item.SequenceNumber = item.SequenceNumber + 1
+	renameActions(item.Actions, from, to)
+}
+
+// applyNudgeOperation changes a nudge the same way that resolving or hiding
+// etc the nudge on its own would
+func applyNudgeOperation(nudge *feedlib.Nudge, operation domain.BulkOperation) {
+	var from, to string
+	switch operation {
+	case domain.BulkOperationResolve:
+		nudge.Status = feedlib.StatusDone
+		from, to = common.ResolveItemActionName, common.UnResolveItemActionName
+	case domain.BulkOperationUnresolve:
+		nudge.Status = feedlib.StatusPending
+		from, to = common.UnResolveItemActionName, common.ResolveItemActionName
+	case domain.BulkOperationHide:
+		nudge.Visibility = feedlib.VisibilityHide
+		from, to = common.HideItemActionName, common.ShowItemActionName
+	case domain.BulkOperationShow:
+		nudge.Visibility = feedlib.VisibilityShow
+		from, to = common.ShowItemActionName, common.HideItemActionName
+	default:
+		return
+	}
+	nudge.SequenceNumber = nudge.SequenceNumber + 1
+	renameActions(nudge.Actions, from, to)
+}
+
+// renameActions swaps an action for its opposite e.g `RESOLVE_ITEM` for
+// `UNRESOLVE_ITEM` once the action has been carried out
+func renameActions(actions []feedlib.Action, from string, to string) {
+	for i, action := range actions {
+		if action.Name == from {
+			actions[i].Name = to
+			actions[i].SequenceNumber = action.SequenceNumber + 1
+		}
+	}
+}
+
+// uniqueIDs drops blank and repeated IDs, keeping the order of the rest
+func uniqueIDs(ids []string) []string {
+	unique := []string{}
+	for _, id := range ids {
+		if id != "" && !converterandformatter.StringSliceContains(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,160 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	messagingMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/messaging/mock"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyItemOperation(t *testing.T) {
+	tests := []struct {
+		name       string
+		operation  domain.BulkOperation
+		check      func(t *testing.T, item *feedlib.Item)
+		actionName string
+	}{
+		{
+			name:      "resolve",
+			operation: domain.BulkOperationResolve,
+			check: func(t *testing.T, item *feedlib.Item) {
+				assert.Equal(t, feedlib.StatusDone, item.Status)
+			},
+			actionName: common.UnResolveItemActionName,
+		},
+		{
+			name:      "pin",
+			operation: domain.BulkOperationPin,
+			check: func(t *testing.T, item *feedlib.Item) {
+				assert.True(t, item.Persistent)
+			},
+			actionName: common.UnPinItemActionName,
+		},
+		{
+			name:      "hide",
+			operation: domain.BulkOperationHide,
+			check: func(t *testing.T, item *feedlib.Item) {
+				assert.Equal(t, feedlib.VisibilityHide, item.Visibility)
+			},
+			actionName: common.ShowItemActionName,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &feedlib.Item{
+				SequenceNumber: 1,
+				Status:         feedlib.StatusPending,
+				Visibility:     feedlib.VisibilityShow,
+				Actions: []feedlib.Action{
+					{Name: common.ResolveItemActionName},
+					{Name: common.PinItemActionName},
+					{Name: common.HideItemActionName},
+				},
+			}
+			applyItemOperation(item, tt.operation)
+			tt.check(t, item)
+			assert.Equal(t, 2, item.SequenceNumber)
+
+			names := []string{}
+			for _, action := range item.Actions {
+				names = append(names, action.Name)
+			}
+			assert.Contains(t, names, tt.actionName)
+		})
+	}
+}
+
+func TestUseCaseImpl_BulkUpdateFeed(t *testing.T) {
+	ctx := context.Background()
+	repository := &mock.FakeEngagementRepository{
+		GetFeedItemFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			itemID string,
+		) (*feedlib.Item, error) {
+			return nil, nil
+		},
+	}
+	fe := NewFeed(infrastructure.Interactor{Repository: repository})
+
+	_, err := fe.BulkUpdateFeed(ctx, "uid", feedlib.FlavourConsumer, "ARCHIVE", []string{"a"}, nil)
+	assert.NotNil(t, err)
+
+	_, err = fe.BulkUpdateFeed(ctx, "uid", feedlib.FlavourConsumer, domain.BulkOperationPin, []string{""}, nil)
+	assert.NotNil(t, err)
+
+	result, err := fe.BulkUpdateFeed(
+		ctx,
+		"uid",
+		feedlib.FlavourConsumer,
+		domain.BulkOperationPin,
+		[]string{"missing", "missing"},
+		[]string{"nudge"},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, result.Succeeded)
+	assert.Equal(t, 2, result.Failed)
+	assert.Len(t, result.Results, 2)
+	assert.Equal(t, domain.ElementTypeItem, result.Results[0].ElementType)
+	assert.Equal(t, domain.ElementTypeNudge, result.Results[1].ElementType)
+	assert.NotEmpty(t, result.Results[1].Error)
+}
+
+func TestUseCaseImpl_BulkUpdateFeedNotifyFailure(t *testing.T) {
+	ctx := context.Background()
+	deleted := []string{}
+	fe := NewFeed(infrastructure.Interactor{
+		Repository: &mock.FakeEngagementRepository{
+			GetFeedItemFn: func(
+				ctx context.Context,
+				uid string,
+				flavour feedlib.Flavour,
+				itemID string,
+			) (*feedlib.Item, error) {
+				return &feedlib.Item{ID: itemID, Users: []string{uid}}, nil
+			},
+			DeleteFeedItemFn: func(
+				ctx context.Context,
+				uid string,
+				flavour feedlib.Flavour,
+				itemID string,
+			) error {
+				deleted = append(deleted, itemID)
+				return nil
+			},
+		},
+		NotificationService: &messagingMock.FakeServiceMessaging{
+			NotifyFn: func(
+				ctx context.Context,
+				topicID string,
+				uid string,
+				flavour feedlib.Flavour,
+				payload feedlib.Element,
+				metadata map[string]interface{},
+			) error {
+				return fmt.Errorf("pub sub is down")
+			},
+		},
+	})
+
+	result, err := fe.BulkUpdateFeed(
+		ctx,
+		"uid",
+		feedlib.FlavourConsumer,
+		domain.BulkOperationDelete,
+		[]string{"item"},
+		nil,
+	)
+	assert.Nil(t, err, "the changes stand when they can't be announced")
+	assert.NotNil(t, result)
+	assert.Equal(t, 1, result.Succeeded)
+	assert.Equal(t, []string{"item"}, deleted)
+}
@@ -0,0 +1,605 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/idempotency"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/preferences"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/firebasetools"
+	"github.com/segmentio/ksuid"
+)
+
+var emailNotificationTemplate = template.Must(template.New("notification").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #333333;">
+<h2>{{.Title}}</h2>
+<p>{{.Body}}</p>
+{{if .LinkURL}}<p><a href="{{.LinkURL}}">{{.LinkURL}}</a></p>{{end}}
+</body>
+</html>
+`))
+
+// channelNotification is the notification of a feed element. It is rendered
+// separately for each channel that it is delivered over.
+type channelNotification struct {
+	ElementType domain.ElementType
+	ElementID   string
+
+	// the kind of notification, which users can turn off
+	Category domain.NotificationCategory
+
+	Title    string
+	Body     string
+	ImageURL string
+
+	// an optional body sent instead of Body over channels other than push
+	// notifications e.g when Body may hold health information that should
+	// only be read in the app
+	OffAppBody string
+
+	// an optional link to more information e.g the element's first link
+	LinkURL string
+}
+
+// push renders the notification as an FCM tray notification
+func (c channelNotification) push() *firebasetools.FirebaseSimpleNotificationInput {
+	imageURL := c.ImageURL
+	if imageURL == "" {
+		imageURL = common.DefaultIconPath
+	}
+	return &firebasetools.FirebaseSimpleNotificationInput{
+		Title:    c.Title,
+		Body:     c.Body,
+		ImageURL: &imageURL,
+	}
+}
+
+// emailText renders the notification as the plain text of an email
+func (c channelNotification) emailText() string {
+	parts := []string{c.Title, c.Body}
+	if c.LinkURL != "" {
+		parts = append(parts, c.LinkURL)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// emailHTML renders the notification as the HTML body of an email
+func (c channelNotification) emailHTML() (string, error) {
+	buf := new(bytes.Buffer)
+	err := emailNotificationTemplate.Execute(buf, c)
+	if err != nil {
+		return "", fmt.Errorf("unable to render email notification: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// offApp returns the notification as it is sent over channels other than
+// push notifications
+func (c channelNotification) offApp() channelNotification {
+	if c.OffAppBody != "" {
+		c.Body = c.OffAppBody
+	}
+	return c
+}
+
+// sms renders the notification as a short text message. The body is
+// shortened when the message would not fit in two segments.
+func (c channelNotification) sms() string {
+	suffix := ""
+	if c.LinkURL != "" {
+		suffix = " " + c.LinkURL
+	}
+	return shortenSMS(c.Title+": "+c.Body, suffix)
+}
+
+// whatsApp renders the notification as a WhatsApp message, with the title in
+// bold
+func (c channelNotification) whatsApp() string {
+	parts := []string{"*" + c.Title + "*", c.Body}
+	if c.LinkURL != "" {
+		parts = append(parts, c.LinkURL)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// the side effects of handling a Pub/Sub message that a redelivery of the
+// message skips when an earlier delivery did them before failing
+const (
+	labelsStep   = "labels"
+	inboxStep    = "inbox"
+	dataPushStep = "data_push"
+	pushStep     = "push"
+)
+
+// stepName names a side effect of handling a Pub/Sub message that is done
+// for particular users or senders
+func stepName(step string, parts ...string) string {
+	return step + "/" + idempotency.Fingerprint([]byte(strings.Join(parts, "|")))
+}
+
+// deliveryKey identifies a notification sent while processing a Pub/Sub
+// message. One message can notify different users differently e.g those
+// following a conversation and those mentioned in it, so the key covers the
+// users and the title too.
+func deliveryKey(
+	messageID string,
+	users []string,
+	notification channelNotification,
+) string {
+	recipients := strings.Join(users, ",") + "|" + notification.Title
+	return messageID + "/" + idempotency.Fingerprint([]byte(recipients))
+}
+
+// deliveredChannels returns the channels that a notification with the
+// delivery key went out over, or was deliberately held back or left out on.
+// Channels whose delivery failed are left out so that they are tried again.
+// Nothing is returned when the deliveries can't be checked, so the
+// notification is sent.
+func (n NotificationImpl) deliveredChannels(
+	ctx context.Context,
+	envelope dto.NotificationEnvelope,
+	elementID string,
+	key string,
+) map[feedlib.Channel]bool {
+	delivered := map[feedlib.Channel]bool{}
+	deliveries, err := n.infrastructure.GetChannelDeliveries(
+		ctx,
+		envelope.UID,
+		envelope.Flavour,
+		elementID,
+	)
+	if err != nil {
+		log.Printf("unable to check earlier deliveries of %s: %v", elementID, err)
+		return delivered
+	}
+	for _, delivery := range deliveries {
+		if delivery.DeliveryKey == key &&
+			delivery.Status != domain.DeliveryStatusFailed {
+			delivered[delivery.Channel] = true
+		}
+	}
+	return delivered
+}
+
+// routeNotification delivers a feed element's notification to users over
+// each of the requested channels. Addresses are resolved from the users'
+// profiles and the outcome for every channel is recorded against the
+// element.
+//
+// The users' notification preferences are applied first. Users that do not
+// want the notification are left out, and users in their quiet hours get it
+// when the hours end.
+//
+// A failure on one channel does not stop the others. The outcome for each
+// channel is recorded against the Pub/Sub message that the notification is
+// sent for, and a redelivery of the message only tries the channels that
+// failed. An error is returned when any channel failed so that Pub/Sub
+// redelivers the message. Outside Pub/Sub, an error is returned only when the
+// notification could not be delivered over any channel, so that a retry does
+// not repeat notifications that were already sent.
+func (n NotificationImpl) routeNotification(
+	ctx context.Context,
+	channels []feedlib.Channel,
+	users []string,
+	sender string,
+	envelope dto.NotificationEnvelope,
+	notification channelNotification,
+) error {
+	ctx, span := tracer.Start(ctx, "routeNotification")
+	defer span.End()
+
+	key := ""
+	channels = uniqueChannels(channels)
+	if message, ok := idempotency.PubSubMessageFromContext(ctx); ok {
+		key = deliveryKey(message.ID, users, notification)
+		delivered := n.deliveredChannels(ctx, envelope, notification.ElementID, key)
+		pending := []feedlib.Channel{}
+		for _, channel := range channels {
+			if !delivered[channel] {
+				pending = append(pending, channel)
+			}
+		}
+		if len(pending) == 0 {
+			log.Printf(
+				"%s notification for message %s was already sent",
+				sender,
+				message.ID,
+			)
+			idempotency.RecordDuplicate(ctx, message.Topic, idempotency.DuplicateNotification)
+			return nil
+		}
+		channels = pending
+	}
+
+	// every channel is screened before anything goes out so that a
+	// screening failure does not leave the notification half sent
+	screenings := []*preferences.Screening{}
+	for _, channel := range channels {
+		if !channel.IsValid() {
+			// unknown channels are skipped rather than held back
+			screenings = append(screenings, &preferences.Screening{Allowed: users})
+			continue
+		}
+		screening, err := n.preferences.ScreenUsers(
+			ctx,
+			users,
+			channel,
+			notification.Category,
+			false,
+		)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return fmt.Errorf("unable to apply notification preferences: %w", err)
+		}
+		screenings = append(screenings, screening)
+	}
+
+	deliveries := []domain.ChannelDelivery{}
+	for i, channel := range channels {
+		screening := screenings[i]
+		delivery := domain.ChannelDelivery{Status: domain.DeliveryStatusSkipped}
+		if len(screening.Allowed) > 0 {
+			delivery = n.deliverOverChannel(
+				ctx,
+				channel,
+				screening.Allowed,
+				sender,
+				envelope,
+				notification,
+			)
+		}
+		for _, deferral := range screening.Deferred {
+			err := n.deferChannelNotification(
+				ctx,
+				channel,
+				deferral,
+				sender,
+				envelope,
+				notification,
+			)
+			if err != nil {
+				helpers.RecordSpanError(span, err)
+				delivery.Failed++
+				delivery.Error = err.Error()
+				continue
+			}
+			delivery.Deferred++
+		}
+		delivery.Suppressed += len(screening.Suppressed)
+		delivery.Status = channelDeliveryStatus(delivery)
+		if delivery.Status != domain.DeliveryStatusSkipped &&
+			delivery.Failed == 0 {
+			delivery.Error = ""
+		}
+
+		delivery.ID = ksuid.New().String()
+		delivery.ElementType = notification.ElementType
+		delivery.ElementID = notification.ElementID
+		delivery.Sender = sender
+		delivery.Channel = channel
+		delivery.DeliveryKey = key
+		delivery.Timestamp = time.Now()
+		deliveries = append(deliveries, delivery)
+	}
+
+	// the notifications are out, so failing to record them is not fatal
+	err := n.infrastructure.SaveChannelDeliveries(
+		ctx,
+		envelope.UID,
+		envelope.Flavour,
+		deliveries,
+	)
+	if err != nil {
+		log.Printf("unable to record %s deliveries: %v", sender, err)
+	}
+
+	failures := []string{}
+	for _, delivery := range deliveries {
+		switch delivery.Status {
+		case domain.DeliveryStatusSent,
+			domain.DeliveryStatusPartial,
+			domain.DeliveryStatusDeferred:
+			if key == "" {
+				return nil
+			}
+		case domain.DeliveryStatusFailed:
+			failures = append(
+				failures,
+				fmt.Sprintf("%s: %s", delivery.Channel, delivery.Error),
+			)
+		}
+	}
+	if len(failures) > 0 {
+		err := fmt.Errorf(
+			"unable to deliver notification: %s",
+			strings.Join(failures, "; "),
+		)
+		helpers.RecordSpanError(span, err)
+		return err
+	}
+	return nil
+}
+
+// deferChannelNotification holds a feed element's notification back until
+// the end of a user's quiet hours
+func (n NotificationImpl) deferChannelNotification(
+	ctx context.Context,
+	channel feedlib.Channel,
+	deferral preferences.Deferral,
+	sender string,
+	envelope dto.NotificationEnvelope,
+	notification channelNotification,
+) error {
+	marshalled, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("unable to marshal notification envelope: %w", err)
+	}
+	if channel != feedlib.ChannelFcm {
+		notification = notification.offApp()
+	}
+	return n.preferences.DeferNotification(ctx, domain.DeferredNotification{
+		RecipientUID: deferral.UID,
+		Channel:      channel,
+		Category:     notification.Category,
+		Sender:       sender,
+		Title:        notification.Title,
+		Body:         notification.Body,
+		ImageURL:     notification.ImageURL,
+		LinkURL:      notification.LinkURL,
+		UID:          envelope.UID,
+		Flavour:      envelope.Flavour,
+		ElementType:  notification.ElementType,
+		ElementID:    notification.ElementID,
+		Envelope:     marshalled,
+		DeliverAt:    deferral.Until,
+	})
+}
+
+// deliverOverChannel sends a notification over one channel and reports the
+// outcome
+func (n NotificationImpl) deliverOverChannel(
+	ctx context.Context,
+	channel feedlib.Channel,
+	users []string,
+	sender string,
+	envelope dto.NotificationEnvelope,
+	notification channelNotification,
+) domain.ChannelDelivery {
+	ctx, span := tracer.Start(ctx, "deliverOverChannel")
+	defer span.End()
+
+	uids := onboarding.UserUIDs{UIDs: users}
+	delivery := domain.ChannelDelivery{}
+	fail := func(count int, err error) {
+		helpers.RecordSpanError(span, err)
+		delivery.Failed += count
+		delivery.Error = err.Error()
+	}
+	if channel != feedlib.ChannelFcm {
+		notification = notification.offApp()
+	}
+
+	switch channel {
+	case feedlib.ChannelFcm:
+		tokens, err := n.GetUserTokens(ctx, users)
+		if err != nil {
+			fail(1, err)
+			break
+		}
+		tokens = uniqueContacts(tokens)
+		err = n.pushNotification(ctx, tokens, sender, envelope, notification.push())
+		if err != nil {
+			fail(len(tokens), err)
+			break
+		}
+		delivery.Sent = len(tokens)
+
+	case feedlib.ChannelEmail:
+		addresses, err := n.infrastructure.GetEmailAddresses(ctx, uids)
+		if err != nil {
+			fail(1, fmt.Errorf("can't get email addresses: %w", err))
+			break
+		}
+		html, err := notification.emailHTML()
+		if err != nil {
+			fail(1, err)
+			break
+		}
+		// each user gets their own email so that addresses are not shared
+		for _, uid := range uniqueContacts(users) {
+			to := uniqueContacts(addresses[uid])
+			if len(to) == 0 {
+				continue
+			}
+			_, _, err = n.infrastructure.SendEmail(
+				ctx,
+				notification.Title,
+				notification.emailText(),
+				&html,
+				to...,
+			)
+			if err != nil {
+				fail(len(to), err)
+				continue
+			}
+			delivery.Sent += len(to)
+		}
+
+	case feedlib.ChannelSms:
+		phones, err := n.infrastructure.GetPhoneNumbers(ctx, uids)
+		if err != nil {
+			fail(1, fmt.Errorf("can't get phone numbers: %w", err))
+			break
+		}
+		to, err := n.phonesWithoutOptOut(ctx, flattenContacts(phones), &delivery)
+		if err != nil {
+			fail(1, err)
+			break
+		}
+		if len(to) == 0 {
+			break
+		}
+		_, err = n.infrastructure.SendToMany(ctx, to, notification.sms())
+		if err != nil {
+			fail(len(to), err)
+			break
+		}
+		delivery.Sent = len(to)
+
+	case feedlib.ChannelWhatsapp:
+		phones, err := n.infrastructure.GetPhoneNumbers(ctx, uids)
+		if err != nil {
+			fail(1, fmt.Errorf("can't get phone numbers: %w", err))
+			break
+		}
+		to, err := n.phonesWithoutOptOut(ctx, flattenContacts(phones), &delivery)
+		if err != nil {
+			fail(1, err)
+			break
+		}
+		message := notification.whatsApp()
+		for _, phone := range to {
+			_, err = n.infrastructure.WhatsAppMessage(ctx, phone, message)
+			if err != nil {
+				fail(1, err)
+				continue
+			}
+			delivery.Sent++
+		}
+
+	default:
+		delivery.Status = domain.DeliveryStatusSkipped
+		delivery.Error = fmt.Sprintf("%s notifications are not supported", channel)
+		return delivery
+	}
+
+	delivery.Status = deliveryStatus(delivery.Sent, delivery.Failed)
+	if delivery.Status == domain.DeliveryStatusSkipped {
+		delivery.Error = fmt.Sprintf("the users have no %s addresses", channel)
+	}
+	return delivery
+}
+
+// phonesWithoutOptOut drops repeated phone numbers and those that opted out
+// of messages on the profile service. The numbers dropped for opting out are
+// counted as suppressed.
+func (n NotificationImpl) phonesWithoutOptOut(
+	ctx context.Context,
+	phones []string,
+	delivery *domain.ChannelDelivery,
+) ([]string, error) {
+	phones = uniqueContacts(phones)
+	if len(phones) == 0 {
+		return phones, nil
+	}
+	allowed, err := n.infrastructure.PhonesWithoutOptOut(ctx, phones)
+	if err != nil {
+		return nil, fmt.Errorf("can't check opt outs: %w", err)
+	}
+	delivery.Suppressed += len(phones) - len(allowed)
+	return allowed, nil
+}
+
+// NotificationDeliveries returns the outcome of sending an item's or nudge's
+// notifications over each of its channels, oldest first
+func (fe UseCaseImpl) NotificationDeliveries(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	elementID string,
+) ([]domain.ChannelDelivery, error) {
+	ctx, span := tracer.Start(ctx, "NotificationDeliveries")
+	defer span.End()
+
+	deliveries, err := fe.infrastructure.GetChannelDeliveries(
+		ctx,
+		uid,
+		flavour,
+		elementID,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get notification deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// deliveryStatus summarizes the number of addresses that a notification was
+// sent, and failed to be sent, to
+func deliveryStatus(sent int, failed int) domain.DeliveryStatus {
+	switch {
+	case sent > 0 && failed > 0:
+		return domain.DeliveryStatusPartial
+	case sent > 0:
+		return domain.DeliveryStatusSent
+	case failed > 0:
+		return domain.DeliveryStatusFailed
+	default:
+		return domain.DeliveryStatusSkipped
+	}
+}
+
+// channelDeliveryStatus summarizes the outcome of a notification on one
+// channel. Sending takes precedence over holding the notification back.
+func channelDeliveryStatus(delivery domain.ChannelDelivery) domain.DeliveryStatus {
+	status := deliveryStatus(delivery.Sent, delivery.Failed)
+	switch {
+	case status != domain.DeliveryStatusSkipped:
+		return status
+	case delivery.Deferred > 0:
+		return domain.DeliveryStatusDeferred
+	case delivery.Suppressed > 0:
+		return domain.DeliveryStatusSuppressed
+	default:
+		return domain.DeliveryStatusSkipped
+	}
+}
+
+// uniqueChannels drops repeated channels, keeping their order
+func uniqueChannels(channels []feedlib.Channel) []feedlib.Channel {
+	unique := []feedlib.Channel{}
+	for _, channel := range channels {
+		if !channelsInclude(unique, channel) {
+			unique = append(unique, channel)
+		}
+	}
+	return unique
+}
+
+// uniqueContacts drops blank and repeated contacts, keeping their order
+func uniqueContacts(contacts []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, contact := range contacts {
+		contact = strings.TrimSpace(contact)
+		if contact == "" || seen[contact] {
+			continue
+		}
+		seen[contact] = true
+		unique = append(unique, contact)
+	}
+	return unique
+}
+
+// firstLinkURL returns the URL of the first of an element's links, if any
+func firstLinkURL(links []feedlib.Link) string {
+	for _, link := range links {
+		if link.URL != "" {
+			return link.URL
+		}
+	}
+	return ""
+}
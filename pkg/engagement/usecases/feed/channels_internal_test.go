@@ -0,0 +1,453 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
+	onboardingMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding/mock"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/idempotency"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelNotification_Render(t *testing.T) {
+	notification := channelNotification{
+		Title:   "Your results are ready",
+		Body:    "Open the app to <view> them",
+		LinkURL: "https://example.com/results",
+	}
+
+	assert.Equal(
+		t,
+		"*Your results are ready*\n\nOpen the app to <view> them\n\nhttps://example.com/results",
+		notification.whatsApp(),
+	)
+	assert.Equal(
+		t,
+		"Your results are ready\n\nOpen the app to <view> them\n\nhttps://example.com/results",
+		notification.emailText(),
+	)
+	assert.Equal(
+		t,
+		"Your results are ready: Open the app to <view> them https://example.com/results",
+		notification.sms(),
+	)
+
+	html, err := notification.emailHTML()
+	assert.Nil(t, err)
+	assert.Contains(t, html, "<h2>Your results are ready</h2>")
+	assert.Contains(t, html, "Open the app to &lt;view&gt; them")
+	assert.Contains(t, html, `href="https://example.com/results"`)
+
+	assert.NotNil(t, notification.push().ImageURL, "the default icon is used")
+
+	notification.Body = strings.Repeat("long ", 100)
+	sms := notification.sms()
+	length, gsm7 := smsLength(sms)
+	assert.True(t, gsm7, "shortening keeps the message in the GSM 7-bit alphabet")
+	assert.LessOrEqual(t, length, maxGSM7SMSLength)
+	assert.True(t, strings.HasSuffix(sms, "... https://example.com/results"))
+
+	notification.OffAppBody = "Open the app to read it"
+	assert.Equal(t, "Open the app to read it", notification.offApp().Body)
+	assert.Equal(t, notification.Body, notification.push().Body)
+}
+
+func TestShortenSMS(t *testing.T) {
+	assert.Equal(t, "short message", shortenSMS("short message", ""))
+
+	// extension characters take two septets
+	length, gsm7 := smsLength("{a}")
+	assert.True(t, gsm7)
+	assert.Equal(t, 5, length)
+
+	shortened := shortenSMS(strings.Repeat("€", 200), " https://x.io")
+	length, gsm7 = smsLength(shortened)
+	assert.True(t, gsm7)
+	assert.LessOrEqual(t, length, maxGSM7SMSLength)
+	assert.True(t, strings.HasSuffix(shortened, "... https://x.io"))
+
+	// characters outside the GSM 7-bit alphabet are sent as UCS-2
+	shortened = shortenSMS(strings.Repeat("ü ✓ ", 100), "")
+	length, gsm7 = smsLength(shortened)
+	assert.False(t, gsm7)
+	assert.LessOrEqual(t, length, maxUCS2SMSLength)
+	assert.True(t, strings.HasSuffix(shortened, "..."))
+
+	length, _ = smsLength("😀")
+	assert.Equal(t, 2, length, "astral characters take two UTF-16 code units")
+}
+
+func TestDeliveryStatus(t *testing.T) {
+	assert.Equal(t, domain.DeliveryStatusSent, deliveryStatus(2, 0))
+	assert.Equal(t, domain.DeliveryStatusPartial, deliveryStatus(1, 1))
+	assert.Equal(t, domain.DeliveryStatusFailed, deliveryStatus(0, 1))
+	assert.Equal(t, domain.DeliveryStatusSkipped, deliveryStatus(0, 0))
+}
+
+func TestNotificationImpl_routeNotification(t *testing.T) {
+	ctx := context.Background()
+	recorded := []domain.ChannelDelivery{}
+	repository := &mock.FakeEngagementRepository{
+		SaveChannelDeliveriesFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			deliveries []domain.ChannelDelivery,
+		) error {
+			recorded = append(recorded, deliveries...)
+			return nil
+		},
+		GetNotificationPreferencesFn: func(
+			ctx context.Context,
+			uid string,
+		) (*domain.NotificationPreferences, error) {
+			return nil, nil
+		},
+	}
+	tokensErr := fmt.Errorf("profile service unavailable")
+	profiles := &onboardingMock.FakeServiceOnboarding{
+		GetDeviceTokensFn: func(
+			ctx context.Context,
+			uids onboarding.UserUIDs,
+		) (map[string][]string, error) {
+			return nil, tokensErr
+		},
+		GetEmailAddressesFn: func(
+			ctx context.Context,
+			uids onboarding.UserUIDs,
+		) (map[string][]string, error) {
+			return map[string][]string{"uid": {}}, nil
+		},
+	}
+	n := NewNotification(infrastructure.Interactor{
+		Repository:     repository,
+		ProfileService: profiles,
+	})
+	envelope := dto.NotificationEnvelope{UID: "uid", Flavour: feedlib.FlavourConsumer}
+	notification := channelNotification{
+		ElementType: domain.ElementTypeItem,
+		ElementID:   "item",
+		Title:       "Title",
+		Body:        "Body",
+	}
+	channels := []feedlib.Channel{
+		feedlib.ChannelFcm,
+		feedlib.ChannelEmail,
+		feedlib.ChannelFcm,
+		feedlib.Channel("PIGEON"),
+	}
+
+	err := n.routeNotification(ctx, channels, []string{"uid"}, itemPublishSender, envelope, notification)
+	assert.NotNil(t, err, "no channel delivered the notification")
+	assert.Len(t, recorded, 3, "repeated channels are sent over once")
+	for _, delivery := range recorded {
+		assert.Equal(t, "item", delivery.ElementID)
+		assert.Equal(t, itemPublishSender, delivery.Sender)
+		assert.NotEmpty(t, delivery.ID)
+	}
+	assert.Equal(t, domain.DeliveryStatusFailed, recorded[0].Status)
+	assert.Equal(t, domain.DeliveryStatusSkipped, recorded[1].Status)
+	assert.Equal(t, feedlib.ChannelEmail, recorded[1].Channel)
+	assert.Equal(t, domain.DeliveryStatusSkipped, recorded[2].Status)
+
+	// skipped channels are not an error
+	tokensErr = nil
+	recorded = []domain.ChannelDelivery{}
+	err = n.routeNotification(ctx, channels, []string{"uid"}, itemPublishSender, envelope, notification)
+	assert.Nil(t, err)
+	assert.Len(t, recorded, 3)
+	assert.Equal(t, domain.DeliveryStatusSkipped, recorded[0].Status)
+}
+
+func TestNotificationImpl_routeNotificationRedelivery(t *testing.T) {
+	ctx := context.Background()
+	recorded := []domain.ChannelDelivery{}
+	repository := &mock.FakeEngagementRepository{
+		SaveChannelDeliveriesFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			deliveries []domain.ChannelDelivery,
+		) error {
+			recorded = append(recorded, deliveries...)
+			return nil
+		},
+		GetChannelDeliveriesFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			elementID string,
+		) ([]domain.ChannelDelivery, error) {
+			return recorded, nil
+		},
+		GetNotificationPreferencesFn: func(
+			ctx context.Context,
+			uid string,
+		) (*domain.NotificationPreferences, error) {
+			return nil, nil
+		},
+		ClaimPubSubMessageFn: func(
+			ctx context.Context,
+			message domain.ProcessedMessage,
+		) (*domain.ProcessedMessage, bool, error) {
+			// every delivery of the message is processed
+			return &message, true, nil
+		},
+		CompletePubSubMessageFn: func(ctx context.Context, topic, messageID string) error {
+			return nil
+		},
+	}
+	profiles := &onboardingMock.FakeServiceOnboarding{
+		GetEmailAddressesFn: func(
+			ctx context.Context,
+			uids onboarding.UserUIDs,
+		) (map[string][]string, error) {
+			return map[string][]string{}, nil
+		},
+	}
+	infra := infrastructure.Interactor{Repository: repository, ProfileService: profiles}
+	n := NewNotification(infra)
+	messages := idempotency.NewIdempotency(infra)
+	envelope := dto.NotificationEnvelope{UID: "uid", Flavour: feedlib.FlavourConsumer}
+	notification := channelNotification{
+		ElementType: domain.ElementTypeItem,
+		ElementID:   "item",
+		Title:       "Title",
+	}
+	route := func(messageID string, users ...string) {
+		_, err := messages.ProcessPubSubMessage(
+			ctx,
+			"items.publish",
+			messageID,
+			func(ctx context.Context) error {
+				return n.routeNotification(
+					ctx,
+					[]feedlib.Channel{feedlib.ChannelEmail},
+					users,
+					itemPublishSender,
+					envelope,
+					notification,
+				)
+			},
+		)
+		assert.Nil(t, err)
+	}
+
+	route("message", "uid")
+	assert.Len(t, recorded, 1)
+	assert.NotEmpty(t, recorded[0].DeliveryKey)
+
+	route("message", "uid")
+	assert.Len(t, recorded, 1, "a redelivered message does not notify again")
+
+	route("message", "mentioned")
+	route("another message", "uid")
+	assert.Len(t, recorded, 3)
+
+	recorded = append(recorded, domain.ChannelDelivery{
+		Channel:     feedlib.ChannelEmail,
+		Status:      domain.DeliveryStatusFailed,
+		DeliveryKey: deliveryKey("failed message", []string{"uid"}, notification),
+	})
+	route("failed message", "uid")
+	assert.Len(t, recorded, 5, "channels that failed are tried again")
+}
+
+func TestNotificationImpl_UpdateInboxRedelivery(t *testing.T) {
+	ctx := context.Background()
+	steps := []string{}
+	recounts, queued := 0, 0
+	repository := &mock.FakeEngagementRepository{
+		UpdateUnreadPersistentItemsCountFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+		) error {
+			recounts++
+			return nil
+		},
+		QueueInboxCountPushFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			dueAt time.Time,
+		) error {
+			queued++
+			return nil
+		},
+		ClaimPubSubMessageFn: func(
+			ctx context.Context,
+			message domain.ProcessedMessage,
+		) (*domain.ProcessedMessage, bool, error) {
+			// the steps of the failed delivery are carried over
+			message.Steps = steps
+			return &message, true, nil
+		},
+		CompletePubSubStepFn: func(ctx context.Context, topic, messageID, step string) error {
+			steps = append(steps, step)
+			return nil
+		},
+		CompletePubSubMessageFn: func(ctx context.Context, topic, messageID string) error {
+			return nil
+		},
+		ReleasePubSubMessageFn: func(ctx context.Context, topic, messageID string) error {
+			return nil
+		},
+	}
+	infra := infrastructure.Interactor{Repository: repository}
+	n := NewNotification(infra)
+	messages := idempotency.NewIdempotency(infra)
+	process := func(uid string, fail error) error {
+		_, err := messages.ProcessPubSubMessage(
+			ctx,
+			"items.publish",
+			"message",
+			func(ctx context.Context) error {
+				err := n.UpdateInbox(ctx, uid, feedlib.FlavourConsumer)
+				if err != nil {
+					return err
+				}
+				return fail
+			},
+		)
+		return err
+	}
+
+	assert.NotNil(t, process("uid", fmt.Errorf("test error")))
+	assert.Equal(t, 1, recounts)
+	assert.Len(t, steps, 1)
+
+	assert.Nil(t, process("uid", nil))
+	assert.Equal(t, 1, recounts, "a redelivery does not recount the inbox again")
+	assert.Equal(t, 1, queued)
+
+	assert.Nil(t, process("other", nil))
+	assert.Equal(t, 2, recounts, "the steps are kept per user")
+
+	assert.Nil(t, n.UpdateInbox(ctx, "uid", feedlib.FlavourConsumer))
+	assert.Equal(t, 3, recounts, "outside a pub sub message the inbox is recounted")
+}
+
+func TestNotificationImpl_routeNotificationPreferences(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	saved := map[string]*domain.NotificationPreferences{
+		"opted-out": {UID: "opted-out", OptedOut: true},
+		"asleep": {
+			UID: "asleep",
+			QuietHours: &domain.QuietHours{
+				Start:    now.Add(-time.Hour).Format("15:04"),
+				End:      now.Add(time.Hour).Format("15:04"),
+				Timezone: "UTC",
+			},
+		},
+	}
+	recorded := []domain.ChannelDelivery{}
+	deferred := []domain.DeferredNotification{}
+	infra := infrastructure.Interactor{
+		Repository: &mock.FakeEngagementRepository{
+			GetNotificationPreferencesFn: func(
+				ctx context.Context,
+				uid string,
+			) (*domain.NotificationPreferences, error) {
+				return saved[uid], nil
+			},
+			SaveDeferredNotificationFn: func(
+				ctx context.Context,
+				notification domain.DeferredNotification,
+			) error {
+				deferred = append(deferred, notification)
+				return nil
+			},
+			SaveChannelDeliveriesFn: func(
+				ctx context.Context,
+				uid string,
+				flavour feedlib.Flavour,
+				deliveries []domain.ChannelDelivery,
+			) error {
+				recorded = append(recorded, deliveries...)
+				return nil
+			},
+		},
+		ProfileService: &onboardingMock.FakeServiceOnboarding{
+			GetPhoneNumbersFn: func(
+				ctx context.Context,
+				uids onboarding.UserUIDs,
+			) (map[string][]string, error) {
+				phones := map[string][]string{}
+				for _, uid := range uids.UIDs {
+					phones[uid] = []string{"+2547" + uid}
+				}
+				return phones, nil
+			},
+			PhonesWithoutOptOutFn: func(
+				ctx context.Context,
+				phones []string,
+			) ([]string, error) {
+				allowed := []string{}
+				for _, phone := range phones {
+					if phone != "+2547unsubscribed" {
+						allowed = append(allowed, phone)
+					}
+				}
+				return allowed, nil
+			},
+		},
+	}
+	n := NewNotification(infra)
+	envelope := dto.NotificationEnvelope{UID: "uid", Flavour: feedlib.FlavourConsumer}
+	notification := channelNotification{
+		ElementType: domain.ElementTypeNudge,
+		ElementID:   "nudge",
+		Category:    domain.NotificationCategoryNudges,
+		Title:       "Title",
+		Body:        "Body",
+	}
+
+	// nobody is texted straight away, so the SMS service is not needed
+	err := n.routeNotification(
+		ctx,
+		[]feedlib.Channel{feedlib.ChannelSms},
+		[]string{"opted-out", "asleep", "unsubscribed"},
+		nudgePublishSender,
+		envelope,
+		notification,
+	)
+	assert.Nil(t, err, "deferred notifications are not an error")
+	assert.Len(t, recorded, 1)
+	assert.Equal(t, domain.DeliveryStatusDeferred, recorded[0].Status)
+	assert.Equal(t, 1, recorded[0].Deferred)
+	assert.Equal(t, 2, recorded[0].Suppressed, "opted out in preferences and on the profile")
+	assert.Empty(t, recorded[0].Error)
+
+	assert.Len(t, deferred, 1)
+	assert.Equal(t, "asleep", deferred[0].RecipientUID)
+	assert.Equal(t, feedlib.ChannelSms, deferred[0].Channel)
+	assert.Equal(t, domain.NotificationCategoryNudges, deferred[0].Category)
+	assert.Equal(t, nudgePublishSender, deferred[0].Sender)
+	assert.Equal(t, "nudge", deferred[0].ElementID)
+	assert.Empty(t, deferred[0].Addresses)
+	assert.NotEmpty(t, deferred[0].Envelope)
+	assert.True(t, deferred[0].DeliverAt.After(time.Now()))
+
+	recorded = []domain.ChannelDelivery{}
+	err = n.routeNotification(
+		ctx,
+		[]feedlib.Channel{feedlib.ChannelSms},
+		[]string{"opted-out"},
+		nudgePublishSender,
+		envelope,
+		notification,
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.DeliveryStatusSuppressed, recorded[0].Status)
+}
@@ -0,0 +1,375 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/exceptions"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// SetChecklist adds steps and dependencies to a feed item, or replaces the
+// ones that it had. Steps that the earlier checklist had in common with the
+// new one stay done.
+func (fe UseCaseImpl) SetChecklist(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	checklist domain.Checklist,
+) (*domain.Checklist, error) {
+	ctx, span := tracer.Start(ctx, "SetChecklist")
+	defer span.End()
+
+	checklist.ItemID = itemID
+	if checklist.DependsOn == nil {
+		checklist.DependsOn = []string{}
+	}
+	for i := range checklist.Steps {
+		checklist.Steps[i].Done = false
+		checklist.Steps[i].CompletedAt = nil
+	}
+	if err := checklist.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid checklist: %w", err)
+	}
+
+	item, err := fe.infrastructure.GetFeedItem(ctx, uid, flavour, itemID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get feed item with ID %s", itemID)
+	}
+	if item == nil {
+		return nil, exceptions.ErrNilFeedItem
+	}
+	for _, dependency := range checklist.DependsOn {
+		dependent, err := fe.infrastructure.GetFeedItem(ctx, uid, flavour, dependency)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf(
+				"unable to get feed item with ID %s", dependency)
+		}
+		if dependent == nil {
+			return nil, fmt.Errorf("no item `%s` to depend on", dependency)
+		}
+	}
+
+	checklists, err := fe.infrastructure.GetChecklists(ctx, uid, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get checklists: %w", err)
+	}
+	dependencies := map[string][]string{}
+	for _, existing := range checklists {
+		if existing.ItemID == itemID {
+			checklist.CarryOver(&existing)
+			continue
+		}
+		dependencies[existing.ItemID] = existing.DependsOn
+	}
+	dependencies[itemID] = checklist.DependsOn
+	if cycle := dependencyCycle(dependencies, itemID); cycle != nil {
+		return nil, fmt.Errorf(
+			"circular item dependency: %s", strings.Join(cycle, " -> "))
+	}
+
+	err = fe.infrastructure.SaveChecklist(ctx, uid, flavour, checklist)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to save checklist: %w", err)
+	}
+	return &checklist, nil
+}
+
+// GetChecklist retrieves the checklist of a feed item. It is nil when the
+// item does not have one.
+func (fe UseCaseImpl) GetChecklist(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) (*domain.Checklist, error) {
+	ctx, span := tracer.Start(ctx, "GetChecklist")
+	defer span.End()
+
+	checklist, err := fe.infrastructure.GetChecklist(ctx, uid, flavour, itemID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get checklist: %w", err)
+	}
+	return checklist, nil
+}
+
+// CompleteChecklistStep marks a step of an item's checklist as done. The item
+// is resolved when its last step is done.
+//
+// Steps are done in order and only once the items that the item depends on
+// have been resolved.
+func (fe UseCaseImpl) CompleteChecklistStep(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	stepID string,
+) (*domain.Checklist, error) {
+	ctx, span := tracer.Start(ctx, "CompleteChecklistStep")
+	defer span.End()
+
+	checklist, err := fe.infrastructure.GetChecklist(ctx, uid, flavour, itemID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get checklist: %w", err)
+	}
+	if checklist == nil {
+		return nil, fmt.Errorf("item `%s` does not have a checklist", itemID)
+	}
+
+	blockedBy, err := fe.checklistBlockers(ctx, uid, flavour, *checklist, nil)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+	if len(blockedBy) > 0 {
+		return nil, fmt.Errorf(
+			"items %s should be resolved first", strings.Join(blockedBy, ", "))
+	}
+
+	completed, err := checklist.CompleteStep(stepID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if completed {
+		err = fe.infrastructure.SaveChecklist(ctx, uid, flavour, *checklist)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to save checklist: %w", err)
+		}
+	}
+
+	// the item is resolved even when the step was already done, so that
+	// completing the last step again retries a resolution that failed
+	if checklist.Done() {
+		item, err := fe.infrastructure.GetFeedItem(ctx, uid, flavour, itemID)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to get feed item with ID %s", itemID)
+		}
+		if item != nil && item.Status != feedlib.StatusDone {
+			_, err = fe.ResolveFeedItem(ctx, uid, flavour, itemID)
+			if err != nil {
+				helpers.RecordSpanError(span, err)
+				return nil, fmt.Errorf("unable to resolve completed item: %w", err)
+			}
+		}
+	}
+
+	return checklist, nil
+}
+
+// checkResolvable ensures that an item with a checklist is only resolved
+// once its steps are done and the items that it depends on are resolved
+func (fe UseCaseImpl) checkResolvable(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) error {
+	checklist, err := fe.infrastructure.GetChecklist(ctx, uid, flavour, itemID)
+	if err != nil {
+		return fmt.Errorf("unable to get checklist: %w", err)
+	}
+	if checklist == nil {
+		return nil
+	}
+
+	blockedBy, err := fe.checklistBlockers(ctx, uid, flavour, *checklist, nil)
+	if err != nil {
+		return err
+	}
+	if len(blockedBy) > 0 {
+		return fmt.Errorf(
+			"items %s should be resolved first", strings.Join(blockedBy, ", "))
+	}
+	if !checklist.Done() {
+		return fmt.Errorf(
+			"%d of the item's %d steps are not done",
+			len(checklist.Steps)-checklist.CompletedSteps(),
+			len(checklist.Steps),
+		)
+	}
+	return nil
+}
+
+// checklistBlockers returns the items that a checklist depends on that are
+// yet to be resolved. Known item statuses are used instead of reading the
+// items again, and the rest are read at once. Items that have been deleted do
+// not block.
+func (fe UseCaseImpl) checklistBlockers(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	checklist domain.Checklist,
+	statuses map[string]feedlib.Status,
+) ([]string, error) {
+	unknown := []string{}
+	for _, itemID := range checklist.DependsOn {
+		if _, ok := statuses[itemID]; !ok {
+			unknown = append(unknown, itemID)
+		}
+	}
+	found := map[string]feedlib.Status{}
+	if len(unknown) > 0 {
+		var err error
+		found, err = fe.infrastructure.GetFeedItemStatuses(ctx, uid, flavour, unknown)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get feed item statuses: %w", err)
+		}
+	}
+
+	blockedBy := []string{}
+	for _, itemID := range checklist.DependsOn {
+		itemStatus, ok := statuses[itemID]
+		if !ok {
+			itemStatus, ok = found[itemID]
+		}
+		if ok && itemStatus != feedlib.StatusDone {
+			blockedBy = append(blockedBy, itemID)
+		}
+	}
+	return blockedBy, nil
+}
+
+// completeStepsForEvent completes the next step of the checklists that wait
+// for an event. An `itemID` in the event's payload limits this to one item.
+func (fe UseCaseImpl) completeStepsForEvent(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	event *feedlib.Event,
+) error {
+	checklists, err := fe.infrastructure.GetChecklists(ctx, uid, flavour)
+	if err != nil {
+		return fmt.Errorf("unable to get checklists: %w", err)
+	}
+
+	itemID, _ := event.Payload.Data["itemID"].(string)
+	for _, checklist := range checklists {
+		if itemID != "" && checklist.ItemID != itemID {
+			continue
+		}
+		next := checklist.NextStep()
+		if next == nil || next.EventName == "" || next.EventName != event.Name {
+			continue
+		}
+
+		blockedBy, err := fe.checklistBlockers(ctx, uid, flavour, checklist, nil)
+		if err != nil {
+			return err
+		}
+		if len(blockedBy) > 0 {
+			continue
+		}
+
+		_, err = fe.CompleteChecklistStep(ctx, uid, flavour, checklist.ItemID, next.ID)
+		if err != nil {
+			return fmt.Errorf(
+				"unable to complete step `%s` of item `%s`: %w",
+				next.ID,
+				checklist.ItemID,
+				err,
+			)
+		}
+	}
+	return nil
+}
+
+// feedProgress reports the progress of the items in a feed that have
+// checklists. The statuses of the items that they depend on, and that the
+// (filtered) feed leaves out, are read at once.
+func (fe UseCaseImpl) feedProgress(
+	ctx context.Context,
+	feed *domain.Feed,
+) ([]domain.ItemProgress, error) {
+	checklists, err := fe.infrastructure.GetChecklists(ctx, feed.UID, feed.Flavour)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get checklists: %w", err)
+	}
+
+	statuses := map[string]feedlib.Status{}
+	for _, item := range feed.Items {
+		statuses[item.ID] = item.Status
+	}
+
+	inFeed := []domain.Checklist{}
+	unknown := []string{}
+	for _, checklist := range checklists {
+		if _, ok := statuses[checklist.ItemID]; !ok {
+			continue // not part of this (filtered) feed
+		}
+		inFeed = append(inFeed, checklist)
+		for _, itemID := range checklist.DependsOn {
+			_, ok := statuses[itemID]
+			if !ok && !converterandformatter.StringSliceContains(unknown, itemID) {
+				unknown = append(unknown, itemID)
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		found, err := fe.infrastructure.GetFeedItemStatuses(
+			ctx,
+			feed.UID,
+			feed.Flavour,
+			unknown,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get feed item statuses: %w", err)
+		}
+		for itemID, itemStatus := range found {
+			statuses[itemID] = itemStatus
+		}
+	}
+
+	progress := []domain.ItemProgress{}
+	for _, checklist := range inFeed {
+		blockedBy, err := fe.checklistBlockers(
+			ctx,
+			feed.UID,
+			feed.Flavour,
+			checklist,
+			statuses,
+		)
+		if err != nil {
+			return nil, err
+		}
+		progress = append(progress, checklist.Progress(blockedBy))
+	}
+	return progress, nil
+}
+
+// dependencyCycle returns the items that lead from an item back to itself,
+// if its dependencies loop
+func dependencyCycle(dependencies map[string][]string, itemID string) []string {
+	visited := map[string]bool{}
+	var walk func(path []string) []string
+	walk = func(path []string) []string {
+		current := path[len(path)-1]
+		for _, next := range dependencies[current] {
+			if next == itemID {
+				return append(path, next)
+			}
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			if cycle := walk(append(path, next)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return walk([]string{itemID})
+}
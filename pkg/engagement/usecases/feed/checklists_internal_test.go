@@ -0,0 +1,269 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func testChecklist() domain.Checklist {
+	return domain.Checklist{
+		ItemID: "onboarding",
+		Steps: []domain.ChecklistStep{
+			{ID: "profile", Title: "Complete your profile"},
+			{ID: "consent", Title: "Give consent", EventName: "CONSENT_GIVEN"},
+			{ID: "visit", Title: "Book a visit"},
+		},
+		DependsOn: []string{"welcome"},
+	}
+}
+
+func TestChecklist_CompleteStep(t *testing.T) {
+	checklist := testChecklist()
+
+	_, err := checklist.CompleteStep("consent", time.Now())
+	assert.NotNil(t, err, "steps should be done in order")
+
+	_, err = checklist.CompleteStep("unknown", time.Now())
+	assert.NotNil(t, err)
+
+	completed, err := checklist.CompleteStep("profile", time.Now())
+	assert.Nil(t, err)
+	assert.True(t, completed)
+
+	completed, err = checklist.CompleteStep("profile", time.Now())
+	assert.Nil(t, err)
+	assert.False(t, completed)
+
+	progress := checklist.Progress(nil)
+	assert.Equal(t, 1, progress.CompletedSteps)
+	assert.Equal(t, 3, progress.TotalSteps)
+	assert.Equal(t, "consent", progress.NextStepID)
+	assert.NotNil(t, progress.BlockedBy)
+	assert.False(t, checklist.Done())
+
+	for _, stepID := range []string{"consent", "visit"} {
+		_, err = checklist.CompleteStep(stepID, time.Now())
+		assert.Nil(t, err)
+	}
+	assert.True(t, checklist.Done())
+	assert.Nil(t, checklist.NextStep())
+
+	updated := testChecklist()
+	updated.Steps = append(updated.Steps, domain.ChecklistStep{ID: "review", Title: "Review"})
+	updated.CarryOver(&checklist)
+	assert.Equal(t, 3, updated.CompletedSteps())
+	assert.False(t, updated.Done())
+}
+
+func TestChecklist_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *domain.Checklist)
+		wantErr bool
+	}{
+		{name: "valid", change: func(c *domain.Checklist) {}},
+		{
+			name:    "no steps",
+			change:  func(c *domain.Checklist) { c.Steps = nil },
+			wantErr: true,
+		},
+		{
+			name:    "duplicate step",
+			change:  func(c *domain.Checklist) { c.Steps[1].ID = "profile" },
+			wantErr: true,
+		},
+		{
+			name:    "untitled step",
+			change:  func(c *domain.Checklist) { c.Steps[2].Title = " " },
+			wantErr: true,
+		},
+		{
+			name:    "depends on itself",
+			change:  func(c *domain.Checklist) { c.DependsOn = []string{"onboarding"} },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checklist := testChecklist()
+			tt.change(&checklist)
+			err := checklist.Validate()
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	dependencies := map[string][]string{
+		"a": {"b"},
+		"b": {"c", "d"},
+		"d": {},
+	}
+	assert.Nil(t, dependencyCycle(dependencies, "a"))
+
+	dependencies["c"] = []string{"a"}
+	assert.Equal(t, []string{"a", "b", "c", "a"}, dependencyCycle(dependencies, "a"))
+}
+
+func TestUseCaseImpl_CompleteChecklistStep(t *testing.T) {
+	ctx := context.Background()
+	saved := testChecklist()
+	statuses := map[string]feedlib.Status{
+		"welcome":    feedlib.StatusPending,
+		"onboarding": feedlib.StatusPending,
+	}
+	repository := &mock.FakeEngagementRepository{
+		GetChecklistFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			itemID string,
+		) (*domain.Checklist, error) {
+			checklist := saved
+			checklist.Steps = append([]domain.ChecklistStep{}, saved.Steps...)
+			return &checklist, nil
+		},
+		GetChecklistsFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+		) ([]domain.Checklist, error) {
+			return []domain.Checklist{saved}, nil
+		},
+		SaveChecklistFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			checklist domain.Checklist,
+		) error {
+			saved = checklist
+			return nil
+		},
+		GetFeedItemFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			itemID string,
+		) (*feedlib.Item, error) {
+			return &feedlib.Item{ID: itemID, Status: statuses[itemID]}, nil
+		},
+		GetFeedItemStatusesFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			itemIDs []string,
+		) (map[string]feedlib.Status, error) {
+			found := map[string]feedlib.Status{}
+			for _, itemID := range itemIDs {
+				if itemStatus, ok := statuses[itemID]; ok {
+					found[itemID] = itemStatus
+				}
+			}
+			return found, nil
+		},
+	}
+	fe := NewFeed(infrastructure.Interactor{Repository: repository})
+
+	_, err := fe.CompleteChecklistStep(ctx, "uid", feedlib.FlavourConsumer, "onboarding", "profile")
+	assert.NotNil(t, err, "the item it depends on is not resolved")
+
+	err = fe.checkResolvable(ctx, "uid", feedlib.FlavourConsumer, "onboarding")
+	assert.NotNil(t, err)
+
+	statuses["welcome"] = feedlib.StatusDone
+	checklist, err := fe.CompleteChecklistStep(ctx, "uid", feedlib.FlavourConsumer, "onboarding", "profile")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, checklist.CompletedSteps())
+	assert.Equal(t, 1, saved.CompletedSteps())
+
+	err = fe.checkResolvable(ctx, "uid", feedlib.FlavourConsumer, "onboarding")
+	assert.NotNil(t, err, "steps are not done")
+
+	// events only complete the step that is next
+	err = fe.completeStepsForEvent(ctx, "uid", feedlib.FlavourConsumer, &feedlib.Event{Name: "OTHER_EVENT"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, saved.CompletedSteps())
+
+	err = fe.completeStepsForEvent(ctx, "uid", feedlib.FlavourConsumer, &feedlib.Event{Name: "CONSENT_GIVEN"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, saved.CompletedSteps())
+
+	feed := &domain.Feed{
+		UID:     "uid",
+		Flavour: feedlib.FlavourConsumer,
+		Items: []feedlib.Item{
+			{ID: "onboarding", Status: feedlib.StatusPending},
+		},
+	}
+	progress, err := fe.feedProgress(ctx, feed)
+	assert.Nil(t, err)
+	assert.Len(t, progress, 1)
+	assert.Equal(t, "visit", progress[0].NextStepID)
+	assert.Empty(t, progress[0].BlockedBy)
+}
+
+func TestUseCaseImpl_CompleteChecklistStep_RetriesResolution(t *testing.T) {
+	ctx := context.Background()
+	saved := domain.Checklist{
+		ItemID:    "onboarding",
+		Steps:     []domain.ChecklistStep{{ID: "profile", Title: "Complete your profile"}},
+		DependsOn: []string{},
+	}
+	resolutions := 0
+	repository := &mock.FakeEngagementRepository{
+		GetChecklistFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			itemID string,
+		) (*domain.Checklist, error) {
+			checklist := saved
+			checklist.Steps = append([]domain.ChecklistStep{}, saved.Steps...)
+			return &checklist, nil
+		},
+		SaveChecklistFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			checklist domain.Checklist,
+		) error {
+			saved = checklist
+			return nil
+		},
+		GetFeedItemFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			itemID string,
+		) (*feedlib.Item, error) {
+			return &feedlib.Item{ID: itemID, Status: feedlib.StatusPending}, nil
+		},
+		UpdateFeedItemFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			item *feedlib.Item,
+		) (*feedlib.Item, error) {
+			resolutions++
+			return nil, fmt.Errorf("unavailable")
+		},
+	}
+	fe := NewFeed(infrastructure.Interactor{Repository: repository})
+
+	_, err := fe.CompleteChecklistStep(ctx, "uid", feedlib.FlavourConsumer, "onboarding", "profile")
+	assert.NotNil(t, err)
+	assert.True(t, saved.Done())
+
+	// the step is already done, the resolution is tried again
+	_, err = fe.CompleteChecklistStep(ctx, "uid", feedlib.FlavourConsumer, "onboarding", "profile")
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, resolutions)
+}
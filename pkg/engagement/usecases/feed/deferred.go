@@ -0,0 +1,238 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/preferences"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/firebasetools"
+	"github.com/segmentio/ksuid"
+)
+
+// deferredNotificationBatchSize is the most deferred notifications that are
+// sent in one run of the processor. The rest go out on the next run.
+const deferredNotificationBatchSize = 500
+
+// ProcessDeferredNotifications sends the notifications that were held back
+// for quiet hours that have since ended.
+//
+// The recipients' preferences are checked again first, since they may have
+// changed in the meantime. Notifications that fail to send are kept and
+// retried on the next run. It is meant to be run periodically e.g by a
+// scheduler calling the inter-service API.
+func (n NotificationImpl) ProcessDeferredNotifications(
+	ctx context.Context,
+) (*domain.DeferralReport, error) {
+	ctx, span := tracer.Start(ctx, "ProcessDeferredNotifications")
+	defer span.End()
+
+	due, err := n.infrastructure.GetDueDeferredNotifications(
+		ctx,
+		time.Now(),
+		deferredNotificationBatchSize,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get due deferred notifications: %w", err)
+	}
+
+	report := &domain.DeferralReport{Errors: []string{}}
+	for _, deferred := range due {
+		err := n.processDeferredNotification(ctx, deferred, report)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			report.Errors = append(report.Errors, fmt.Sprintf(
+				"%s notification %s: %s", deferred.Channel, deferred.ID, err))
+		}
+	}
+	return report, nil
+}
+
+func (n NotificationImpl) processDeferredNotification(
+	ctx context.Context,
+	deferred domain.DeferredNotification,
+	report *domain.DeferralReport,
+) error {
+	var screening *preferences.Screening
+	var err error
+	if len(deferred.Addresses) > 0 {
+		screening, err = n.preferences.ScreenAddresses(
+			ctx,
+			deferred.Addresses,
+			deferred.Channel,
+			deferred.Category,
+			false,
+		)
+	} else {
+		screening, err = n.preferences.ScreenUsers(
+			ctx,
+			[]string{deferred.RecipientUID},
+			deferred.Channel,
+			deferred.Category,
+			false,
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(screening.Allowed) > 0:
+		err = n.sendDeferredNotification(ctx, deferred, screening.Allowed)
+		if err != nil {
+			return err
+		}
+		report.Delivered++
+	case len(screening.Deferred) > 0:
+		deferred.DeliverAt = screening.Deferred[0].Until
+		err = n.infrastructure.SaveDeferredNotification(ctx, deferred)
+		if err != nil {
+			return fmt.Errorf("unable to defer notification again: %w", err)
+		}
+		report.Deferred++
+		return nil
+	default:
+		report.Suppressed++
+	}
+
+	err = n.infrastructure.DeleteDeferredNotification(ctx, deferred.ID)
+	if err != nil {
+		return fmt.Errorf("unable to remove deferred notification: %w", err)
+	}
+	return nil
+}
+
+// sendDeferredNotification sends a held back notification to the recipients
+// that still want it
+func (n NotificationImpl) sendDeferredNotification(
+	ctx context.Context,
+	deferred domain.DeferredNotification,
+	recipients []string,
+) error {
+	if len(deferred.Addresses) == 0 {
+		return n.sendDeferredChannelNotification(ctx, deferred, recipients)
+	}
+
+	switch deferred.Channel {
+	case feedlib.ChannelSms:
+		_, err := n.infrastructure.SendToMany(ctx, recipients, deferred.Body)
+		return err
+
+	case feedlib.ChannelWhatsapp:
+		for _, phone := range recipients {
+			_, err := n.infrastructure.WhatsAppMessage(ctx, phone, deferred.Body)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case feedlib.ChannelEmail:
+		var html *string
+		if deferred.HTML != "" {
+			html = &deferred.HTML
+		}
+		_, _, err := n.infrastructure.SendEmail(
+			ctx,
+			deferred.Title,
+			deferred.Body,
+			html,
+			recipients...,
+		)
+		return err
+
+	case feedlib.ChannelFcm:
+		for _, recipient := range recipients {
+			var phone, email *string
+			if strings.Contains(recipient, "@") {
+				email = &recipient
+			} else {
+				phone = &recipient
+			}
+			_, err := n.infrastructure.SendFCMByPhoneOrEmail(
+				ctx,
+				phone,
+				email,
+				deferred.Data,
+				firebasetools.FirebaseSimpleNotificationInput{
+					Title: deferred.Title,
+					Body:  deferred.Body,
+				},
+				nil,
+				nil,
+				nil,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%s notifications are not supported", deferred.Channel)
+	}
+}
+
+// sendDeferredChannelNotification sends a held back feed element
+// notification and records its delivery against the element
+func (n NotificationImpl) sendDeferredChannelNotification(
+	ctx context.Context,
+	deferred domain.DeferredNotification,
+	users []string,
+) error {
+	var envelope dto.NotificationEnvelope
+	if len(deferred.Envelope) > 0 {
+		err := json.Unmarshal(deferred.Envelope, &envelope)
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal notification envelope: %w", err)
+		}
+	}
+	notification := channelNotification{
+		ElementType: deferred.ElementType,
+		ElementID:   deferred.ElementID,
+		Category:    deferred.Category,
+		Title:       deferred.Title,
+		Body:        deferred.Body,
+		ImageURL:    deferred.ImageURL,
+		LinkURL:     deferred.LinkURL,
+	}
+
+	delivery := n.deliverOverChannel(
+		ctx,
+		deferred.Channel,
+		users,
+		deferred.Sender,
+		envelope,
+		notification,
+	)
+	if delivery.Status == domain.DeliveryStatusFailed {
+		return fmt.Errorf("unable to deliver notification: %s", delivery.Error)
+	}
+
+	if deferred.ElementID != "" {
+		delivery.ID = ksuid.New().String()
+		delivery.ElementType = deferred.ElementType
+		delivery.ElementID = deferred.ElementID
+		delivery.Sender = deferred.Sender
+		delivery.Channel = deferred.Channel
+		delivery.Timestamp = time.Now()
+		err := n.infrastructure.SaveChannelDeliveries(
+			ctx,
+			deferred.UID,
+			deferred.Flavour,
+			[]domain.ChannelDelivery{delivery},
+		)
+		if err != nil {
+			log.Printf("unable to record deferred %s delivery: %v", deferred.Sender, err)
+		}
+	}
+	return nil
+}
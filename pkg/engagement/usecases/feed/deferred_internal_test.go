@@ -0,0 +1,116 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
+	onboardingMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding/mock"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationImpl_ProcessDeferredNotifications(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	saved := map[string]*domain.NotificationPreferences{
+		"opted-out": {UID: "opted-out", OptedOut: true},
+		"asleep": {
+			UID: "asleep",
+			QuietHours: &domain.QuietHours{
+				Start:    now.Add(-time.Hour).Format("15:04"),
+				End:      now.Add(time.Hour).Format("15:04"),
+				Timezone: "UTC",
+			},
+		},
+	}
+	due := []domain.DeferredNotification{
+		{ID: "1", RecipientUID: "opted-out", Channel: feedlib.ChannelFcm},
+		{ID: "2", RecipientUID: "asleep", Channel: feedlib.ChannelFcm},
+		{
+			ID:           "3",
+			RecipientUID: "awake",
+			Channel:      feedlib.ChannelFcm,
+			Sender:       itemPublishSender,
+			UID:          "uid",
+			Flavour:      feedlib.FlavourConsumer,
+			ElementType:  domain.ElementTypeItem,
+			ElementID:    "item",
+			Envelope:     []byte(`{"uid":"uid","flavour":"CONSUMER"}`),
+		},
+		{ID: "4", RecipientUID: "unreachable", Channel: feedlib.ChannelFcm},
+	}
+	deleted := []string{}
+	redeferred := []domain.DeferredNotification{}
+	recorded := []domain.ChannelDelivery{}
+	n := NewNotification(infrastructure.Interactor{
+		Repository: &mock.FakeEngagementRepository{
+			GetDueDeferredNotificationsFn: func(
+				ctx context.Context,
+				before time.Time,
+				limit int,
+			) ([]domain.DeferredNotification, error) {
+				assert.Equal(t, deferredNotificationBatchSize, limit)
+				return due, nil
+			},
+			GetNotificationPreferencesFn: func(
+				ctx context.Context,
+				uid string,
+			) (*domain.NotificationPreferences, error) {
+				return saved[uid], nil
+			},
+			SaveDeferredNotificationFn: func(
+				ctx context.Context,
+				notification domain.DeferredNotification,
+			) error {
+				redeferred = append(redeferred, notification)
+				return nil
+			},
+			DeleteDeferredNotificationFn: func(ctx context.Context, id string) error {
+				deleted = append(deleted, id)
+				return nil
+			},
+			SaveChannelDeliveriesFn: func(
+				ctx context.Context,
+				uid string,
+				flavour feedlib.Flavour,
+				deliveries []domain.ChannelDelivery,
+			) error {
+				recorded = append(recorded, deliveries...)
+				return nil
+			},
+		},
+		ProfileService: &onboardingMock.FakeServiceOnboarding{
+			GetDeviceTokensFn: func(
+				ctx context.Context,
+				uids onboarding.UserUIDs,
+			) (map[string][]string, error) {
+				if uids.UIDs[0] == "unreachable" {
+					return nil, fmt.Errorf("profile service unavailable")
+				}
+				return map[string][]string{}, nil
+			},
+		},
+	})
+
+	report, err := n.ProcessDeferredNotifications(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.Delivered)
+	assert.Equal(t, 1, report.Suppressed)
+	assert.Equal(t, 1, report.Deferred)
+	assert.Len(t, report.Errors, 1, "failed notifications are kept for a retry")
+
+	assert.Equal(t, []string{"1", "3"}, deleted)
+	assert.Len(t, redeferred, 1)
+	assert.Equal(t, "2", redeferred[0].ID)
+	assert.True(t, redeferred[0].DeliverAt.After(time.Now()))
+
+	assert.Len(t, recorded, 1)
+	assert.Equal(t, "item", recorded[0].ElementID)
+	assert.Equal(t, itemPublishSender, recorded[0].Sender)
+}
@@ -0,0 +1,527 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/i18n"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/pubsubtools"
+)
+
+// DigestLinkURLEnvVarName is the environment variable with the link that
+// digests point users to e.g the app's inbox. Digests have no link when it
+// is not set.
+const DigestLinkURLEnvVarName = "DIGEST_LINK_URL"
+
+// digestScheduleTolerance lets a digest go out a little early so that a
+// scheduler that runs at about the same time every day does not skip a day
+const digestScheduleTolerance = time.Hour
+
+const (
+	// maxDigestEntries is the most items, and the most nudges, listed in a
+	// digest email
+	maxDigestEntries = 20
+
+	// digestPageSize is the number of digest settings read at a time
+	digestPageSize = 100
+)
+
+var digestEmailTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #333333;">
+<h2>{{.Title}}</h2>
+<p>{{.Summary}}</p>
+{{if .Items}}<h3>{{.ItemsHeading}}</h3>
+<ul>
+{{range .Items}}<li><strong>{{.Title}}</strong></li>
+{{end}}</ul>{{end}}
+{{if .Nudges}}<h3>{{.NudgesHeading}}</h3>
+<ul>
+{{range .Nudges}}<li><strong>{{.Title}}</strong></li>
+{{end}}</ul>{{end}}
+{{if .LinkURL}}<p><a href="{{.LinkURL}}">{{.LinkURL}}</a></p>{{end}}
+</body>
+</html>
+`))
+
+// digest is a summary of a feed's unread items and pending nudges. It only
+// carries counts and titles, as the items' and nudges' text can hold health
+// information that should only be read in the app.
+type digest struct {
+	Title         string
+	Summary       string
+	ItemsHeading  string
+	NudgesHeading string
+	Items         []digestEntry
+	Nudges        []digestEntry
+	LinkURL       string
+}
+
+type digestEntry struct {
+	Title string
+}
+
+// emailHTML renders the digest as the HTML body of an email
+func (d digest) emailHTML() (string, error) {
+	if len(d.Items) > maxDigestEntries {
+		d.Items = d.Items[:maxDigestEntries]
+	}
+	if len(d.Nudges) > maxDigestEntries {
+		d.Nudges = d.Nudges[:maxDigestEntries]
+	}
+	buf := new(bytes.Buffer)
+	err := digestEmailTemplate.Execute(buf, d)
+	if err != nil {
+		return "", fmt.Errorf("unable to render digest email: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// emailText renders the digest as the plain text of an email
+func (d digest) emailText() string {
+	parts := []string{d.Title, d.Summary}
+	for _, section := range []struct {
+		heading string
+		entries []digestEntry
+	}{
+		{d.ItemsHeading, d.Items},
+		{d.NudgesHeading, d.Nudges},
+	} {
+		if len(section.entries) == 0 {
+			continue
+		}
+		lines := []string{section.heading}
+		for i, entry := range section.entries {
+			if i == maxDigestEntries {
+				break
+			}
+			lines = append(lines, "- "+entry.Title)
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+	if d.LinkURL != "" {
+		parts = append(parts, d.LinkURL)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// sms renders the digest as a short text message with a link, shortened
+// to fit in two segments
+func (d digest) sms() string {
+	suffix := ""
+	if d.LinkURL != "" {
+		suffix = " " + d.LinkURL
+	}
+	return shortenSMS(d.Summary, suffix)
+}
+
+// DigestSettings returns how a user gets the digest of one of their feeds.
+// Digests are off until the user turns them on.
+func (n NotificationImpl) DigestSettings(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (*domain.DigestSettings, error) {
+	ctx, span := tracer.Start(ctx, "DigestSettings")
+	defer span.End()
+
+	settings, err := n.infrastructure.GetDigestSettings(ctx, uid, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get digest settings: %w", err)
+	}
+	if settings == nil {
+		settings = &domain.DigestSettings{
+			UID:       uid,
+			Flavour:   flavour,
+			Frequency: domain.DigestFrequencyOff,
+			Channel:   feedlib.ChannelEmail,
+		}
+	}
+	return settings, nil
+}
+
+// SetDigestSettings changes how often, and over which channel, a user gets
+// the digest of one of their feeds
+func (n NotificationImpl) SetDigestSettings(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	frequency domain.DigestFrequency,
+	channel feedlib.Channel,
+) (*domain.DigestSettings, error) {
+	ctx, span := tracer.Start(ctx, "SetDigestSettings")
+	defer span.End()
+
+	if uid == "" {
+		return nil, fmt.Errorf("the user is required")
+	}
+	settings, err := n.DigestSettings(ctx, uid, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+	// what was already covered by a digest is kept so that changing the
+	// settings does not repeat it
+	settings.Frequency = frequency
+	settings.Channel = channel
+	settings.UpdatedAt = time.Now()
+	err = settings.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = n.infrastructure.SaveDigestSettings(ctx, *settings)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to save digest settings: %w", err)
+	}
+	return settings, nil
+}
+
+// ProcessDigests queues a digest for every feed whose daily or weekly digest
+// is due. The subscribed feeds are read a page at a time and each due digest
+// is published to the `digests.due` topic, whose handler builds and sends it.
+//
+// It is meant to be run periodically, at least hourly, e.g by a scheduler
+// calling the inter-service API.
+func (n NotificationImpl) ProcessDigests(
+	ctx context.Context,
+) (*domain.DigestReport, error) {
+	ctx, span := tracer.Start(ctx, "ProcessDigests")
+	defer span.End()
+
+	now := time.Now()
+	report := &domain.DigestReport{Errors: []string{}}
+	for _, frequency := range []domain.DigestFrequency{
+		domain.DigestFrequencyDaily,
+		domain.DigestFrequencyWeekly,
+	} {
+		var after *domain.DigestSettings
+		for {
+			subscribed, err := n.infrastructure.ListDigestSettings(
+				ctx,
+				frequency,
+				after,
+				digestPageSize,
+			)
+			if err != nil {
+				helpers.RecordSpanError(span, err)
+				return nil, fmt.Errorf("unable to list digest settings: %w", err)
+			}
+
+			for _, settings := range subscribed {
+				if !digestDue(settings, now) {
+					continue
+				}
+				err := n.infrastructure.Publish(
+					ctx,
+					helpers.AddPubSubNamespace(common.DigestDueTopic),
+					dto.DigestDue{
+						UID:       settings.UID,
+						Flavour:   settings.Flavour,
+						Frequency: settings.Frequency,
+					},
+				)
+				if err != nil {
+					helpers.RecordSpanError(span, err)
+					report.Errors = append(report.Errors, fmt.Sprintf(
+						"%s digest of %s's %s feed: %s",
+						frequency, settings.UID, settings.Flavour, err))
+					continue
+				}
+				report.Queued++
+			}
+			if len(subscribed) < digestPageSize {
+				break
+			}
+			after = &subscribed[len(subscribed)-1]
+		}
+	}
+	return report, nil
+}
+
+// HandleDigestDue builds and sends a feed's digest. Digests that were turned
+// off, or sent by an earlier delivery of the message, since they were queued
+// are left alone. Digests that fall in quiet hours are queued again by a
+// later run.
+func (n NotificationImpl) HandleDigestDue(
+	ctx context.Context,
+	m *pubsubtools.PubSubPayload,
+) error {
+	ctx, span := tracer.Start(ctx, "HandleDigestDue")
+	defer span.End()
+	if m == nil {
+		return fmt.Errorf("nil pub sub payload")
+	}
+
+	var due dto.DigestDue
+	err := json.Unmarshal(m.Message.Data, &due)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't unmarshal due digest from pubsub data: %w", err)
+	}
+	settings, err := n.infrastructure.GetDigestSettings(ctx, due.UID, due.Flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to get digest settings: %w", err)
+	}
+	now := time.Now()
+	if settings == nil || settings.Frequency != due.Frequency || !digestDue(*settings, now) {
+		return nil
+	}
+
+	err = n.sendDigest(ctx, *settings, now)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"unable to send %s digest of %s's %s feed: %w",
+			settings.Frequency, settings.UID, settings.Flavour, err)
+	}
+	return nil
+}
+
+// digestDue reports whether a feed's digest should go out. A digest can go
+// out a little early so that a scheduler that runs at about the same time
+// every day does not skip a day.
+func digestDue(settings domain.DigestSettings, now time.Time) bool {
+	if !settings.Frequency.IsValid() || settings.Frequency == domain.DigestFrequencyOff {
+		return false
+	}
+	return settings.LastDigestAt.IsZero() ||
+		now.Sub(settings.LastDigestAt) >= settings.Frequency.Period()-digestScheduleTolerance
+}
+
+func (n NotificationImpl) sendDigest(
+	ctx context.Context,
+	settings domain.DigestSettings,
+	now time.Time,
+) error {
+	screening, err := n.preferences.ScreenUsers(
+		ctx,
+		[]string{settings.UID},
+		settings.Channel,
+		domain.NotificationCategoryDigests,
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	if len(screening.Deferred) > 0 {
+		return nil
+	}
+	if len(screening.Suppressed) > 0 {
+		settings.LastDigestAt = now
+		return n.saveDigestProgress(ctx, settings)
+	}
+
+	unread, items, nudges, err := n.digestElements(ctx, settings)
+	if err != nil {
+		return err
+	}
+	if unread == 0 {
+		items = nil
+	}
+	newItems := 0
+	for _, item := range items {
+		if item.Timestamp.After(settings.LastDigestAt) {
+			newItems++
+		}
+	}
+	newNudges := 0
+	for _, nudge := range nudges {
+		if !converterandformatter.StringSliceContains(settings.DigestedNudgeIDs, nudge.ID) {
+			newNudges++
+		}
+	}
+	if newItems == 0 && newNudges == 0 {
+		settings.LastDigestAt = now
+		return n.saveDigestProgress(ctx, settings)
+	}
+
+	d, err := n.buildDigest(ctx, settings, unread, items, nudges)
+	if err != nil {
+		return err
+	}
+	switch settings.Channel {
+	case feedlib.ChannelEmail:
+		err = n.emailDigest(ctx, settings.UID, d)
+	case feedlib.ChannelSms:
+		err = n.textDigest(ctx, settings.UID, d)
+	default:
+		err = fmt.Errorf("digests can't be sent over %s", settings.Channel)
+	}
+	if err != nil {
+		return err
+	}
+
+	settings.LastDigestAt = now
+	settings.DigestedNudgeIDs = []string{}
+	for _, nudge := range nudges {
+		settings.DigestedNudgeIDs = append(settings.DigestedNudgeIDs, nudge.ID)
+	}
+	return n.saveDigestProgress(ctx, settings)
+}
+
+// digestElements fetches a feed's unread count, the same one that the app
+// shows, along with its visible persistent items and its pending nudges
+func (n NotificationImpl) digestElements(
+	ctx context.Context,
+	settings domain.DigestSettings,
+) (int, []feedlib.Item, []feedlib.Nudge, error) {
+	unread, err := n.infrastructure.UnreadPersistentItems(
+		ctx,
+		settings.UID,
+		settings.Flavour,
+	)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("unable to get unread items count: %w", err)
+	}
+
+	pending := feedlib.StatusPending
+	show := feedlib.VisibilityShow
+	unexpired := feedlib.BooleanFilterFalse
+	items, err := n.infrastructure.GetItems(
+		ctx,
+		settings.UID,
+		settings.Flavour,
+		feedlib.BooleanFilterTrue,
+		nil,
+		&show,
+		&unexpired,
+		nil,
+	)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("unable to get unread items: %w", err)
+	}
+	nudges, err := n.infrastructure.GetNudges(
+		ctx,
+		settings.UID,
+		settings.Flavour,
+		&pending,
+		&show,
+		&unexpired,
+	)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("unable to get pending nudges: %w", err)
+	}
+	return unread, items, nudges, nil
+}
+
+// buildDigest summarizes a feed's elements in the user's language
+func (n NotificationImpl) buildDigest(
+	ctx context.Context,
+	settings domain.DigestSettings,
+	unread int,
+	items []feedlib.Item,
+	nudges []feedlib.Nudge,
+) (*digest, error) {
+	language := resolveLanguage(ctx, n.infrastructure, settings.UID, false)
+	translations, err := elementTranslations(
+		ctx,
+		n.infrastructure,
+		settings.UID,
+		settings.Flavour,
+		language,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to localize digest: %w", err)
+	}
+
+	d := &digest{
+		Title:         i18n.Translate(language, i18n.DigestTitle),
+		Summary:       i18n.Translate(language, i18n.DigestSummary, unread, len(nudges)),
+		ItemsHeading:  i18n.Translate(language, i18n.DigestItemsHeading),
+		NudgesHeading: i18n.Translate(language, i18n.DigestNudgesHeading),
+		Items:         []digestEntry{},
+		Nudges:        []digestEntry{},
+		LinkURL:       os.Getenv(DigestLinkURLEnvVarName),
+	}
+	for _, item := range items {
+		localizeItem(&item, translations, language)
+		d.Items = append(d.Items, digestEntry{Title: item.Tagline})
+	}
+	for _, nudge := range nudges {
+		localizeNudge(&nudge, translations, language)
+		d.Nudges = append(d.Nudges, digestEntry{Title: nudge.Title})
+	}
+	return d, nil
+}
+
+func (n NotificationImpl) emailDigest(
+	ctx context.Context,
+	uid string,
+	d *digest,
+) error {
+	addresses, err := n.infrastructure.GetEmailAddresses(
+		ctx,
+		onboarding.UserUIDs{UIDs: []string{uid}},
+	)
+	if err != nil {
+		return fmt.Errorf("can't get email addresses: %w", err)
+	}
+	to := uniqueContacts(addresses[uid])
+	if len(to) == 0 {
+		return fmt.Errorf("the user has no email address")
+	}
+	html, err := d.emailHTML()
+	if err != nil {
+		return err
+	}
+	_, _, err = n.infrastructure.SendEmail(ctx, d.Title, d.emailText(), &html, to...)
+	if err != nil {
+		return fmt.Errorf("unable to send digest email: %w", err)
+	}
+	return nil
+}
+
+func (n NotificationImpl) textDigest(
+	ctx context.Context,
+	uid string,
+	d *digest,
+) error {
+	phones, err := n.infrastructure.GetPhoneNumbers(
+		ctx,
+		onboarding.UserUIDs{UIDs: []string{uid}},
+	)
+	if err != nil {
+		return fmt.Errorf("can't get phone numbers: %w", err)
+	}
+	to, err := n.infrastructure.PhonesWithoutOptOut(ctx, uniqueContacts(phones[uid]))
+	if err != nil {
+		return fmt.Errorf("can't check opt outs: %w", err)
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("the user has no phone number that accepts messages")
+	}
+	_, err = n.infrastructure.SendToMany(ctx, to, d.sms())
+	if err != nil {
+		return fmt.Errorf("unable to send digest SMS: %w", err)
+	}
+	return nil
+}
+
+// saveDigestProgress records what a feed's last digest covered
+func (n NotificationImpl) saveDigestProgress(
+	ctx context.Context,
+	settings domain.DigestSettings,
+) error {
+	err := n.infrastructure.SaveDigestSettings(ctx, settings)
+	if err != nil {
+		return fmt.Errorf("unable to save digest progress: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	messagingMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/messaging/mock"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/pubsubtools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigest_Render(t *testing.T) {
+	d := digest{
+		Title:         "Your digest",
+		Summary:       "You have 2 unread item(s) and 1 pending nudge(s).",
+		ItemsHeading:  "Unread items",
+		NudgesHeading: "Pending nudges",
+		Items: []digestEntry{
+			{Title: "Results <ready>"},
+			{Title: "Appointment"},
+		},
+		Nudges:  []digestEntry{{Title: "Verify your email"}},
+		LinkURL: "https://example.com/inbox",
+	}
+
+	assert.Equal(
+		t,
+		"You have 2 unread item(s) and 1 pending nudge(s). https://example.com/inbox",
+		d.sms(),
+	)
+	assert.Equal(
+		t,
+		"Your digest\n\nYou have 2 unread item(s) and 1 pending nudge(s).\n\n"+
+			"Unread items\n- Results <ready>\n- Appointment\n\n"+
+			"Pending nudges\n- Verify your email\n\nhttps://example.com/inbox",
+		d.emailText(),
+	)
+
+	html, err := d.emailHTML()
+	assert.Nil(t, err)
+	assert.Contains(t, html, "<li><strong>Results &lt;ready&gt;</strong></li>")
+	assert.Contains(t, html, "<h3>Pending nudges</h3>")
+	assert.Contains(t, html, `href="https://example.com/inbox"`)
+
+	for i := 0; i < maxDigestEntries; i++ {
+		d.Items = append(d.Items, digestEntry{Title: "More"})
+	}
+	html, err = d.emailHTML()
+	assert.Nil(t, err)
+	assert.Equal(t, maxDigestEntries+1, strings.Count(html, "<li>"), "entries are capped")
+}
+
+func TestNotificationImpl_ProcessDigests(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	daily := []domain.DigestSettings{}
+	for i := 0; i < digestPageSize+1; i++ {
+		daily = append(daily, domain.DigestSettings{
+			UID:       fmt.Sprintf("user-%d", i),
+			Flavour:   feedlib.FlavourConsumer,
+			Frequency: domain.DigestFrequencyDaily,
+			Channel:   feedlib.ChannelEmail,
+		})
+	}
+	daily[0].LastDigestAt = now.Add(-time.Hour)
+
+	pages := 0
+	queued := []dto.DigestDue{}
+	n := NewNotification(infrastructure.Interactor{
+		Repository: &mock.FakeEngagementRepository{
+			ListDigestSettingsFn: func(
+				ctx context.Context,
+				frequency domain.DigestFrequency,
+				after *domain.DigestSettings,
+				limit int,
+			) ([]domain.DigestSettings, error) {
+				if frequency != domain.DigestFrequencyDaily {
+					return []domain.DigestSettings{}, nil
+				}
+				pages++
+				start := 0
+				if after != nil {
+					for i, settings := range daily {
+						if settings.UID == after.UID {
+							start = i + 1
+						}
+					}
+				}
+				end := start + limit
+				if end > len(daily) {
+					end = len(daily)
+				}
+				return daily[start:end], nil
+			},
+		},
+		NotificationService: &messagingMock.FakeServiceMessaging{
+			PublishFn: func(
+				ctx context.Context,
+				topicID string,
+				payload interface{},
+			) error {
+				assert.Equal(t, helpers.AddPubSubNamespace(common.DigestDueTopic), topicID)
+				queued = append(queued, payload.(dto.DigestDue))
+				return nil
+			},
+		},
+	})
+
+	report, err := n.ProcessDigests(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, 2, pages, "the settings are read a page at a time")
+	assert.Equal(t, digestPageSize, report.Queued, "digests that are not due are left alone")
+	assert.Len(t, queued, digestPageSize)
+	assert.Equal(t, "user-1", queued[0].UID)
+	assert.Equal(t, domain.DigestFrequencyDaily, queued[0].Frequency)
+}
+
+func TestNotificationImpl_HandleDigestDue(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	lastWeek := now.Add(-7 * 24 * time.Hour)
+	settings := map[string]domain.DigestSettings{
+		"recent": {UID: "recent", Flavour: feedlib.FlavourConsumer, Frequency: domain.DigestFrequencyDaily,
+			Channel: feedlib.ChannelEmail, LastDigestAt: now.Add(-time.Hour)},
+		"seen": {UID: "seen", Flavour: feedlib.FlavourConsumer, Frequency: domain.DigestFrequencyDaily,
+			Channel: feedlib.ChannelEmail, LastDigestAt: now.Add(-23 * time.Hour),
+			DigestedNudgeIDs: []string{"nudge"}},
+		"opted-out": {UID: "opted-out", Flavour: feedlib.FlavourConsumer, Frequency: domain.DigestFrequencyDaily,
+			Channel: feedlib.ChannelSms, LastDigestAt: lastWeek},
+		"weekly": {UID: "weekly", Flavour: feedlib.FlavourConsumer, Frequency: domain.DigestFrequencyWeekly,
+			Channel: feedlib.ChannelEmail, LastDigestAt: lastWeek},
+	}
+	saved := []domain.DigestSettings{}
+	n := NewNotification(infrastructure.Interactor{
+		Repository: &mock.FakeEngagementRepository{
+			GetDigestSettingsFn: func(
+				ctx context.Context,
+				uid string,
+				flavour feedlib.Flavour,
+			) (*domain.DigestSettings, error) {
+				found, ok := settings[uid]
+				if !ok {
+					return nil, nil
+				}
+				return &found, nil
+			},
+			GetNotificationPreferencesFn: func(
+				ctx context.Context,
+				uid string,
+			) (*domain.NotificationPreferences, error) {
+				if uid == "opted-out" {
+					return &domain.NotificationPreferences{
+						UID:                uid,
+						DisabledCategories: []domain.NotificationCategory{domain.NotificationCategoryDigests},
+					}, nil
+				}
+				return nil, nil
+			},
+			UnreadPersistentItemsFn: func(
+				ctx context.Context,
+				uid string,
+				flavour feedlib.Flavour,
+			) (int, error) {
+				return 1, nil
+			},
+			GetItemsFn: func(
+				ctx context.Context,
+				uid string,
+				flavour feedlib.Flavour,
+				persistent feedlib.BooleanFilter,
+				status *feedlib.Status,
+				visibility *feedlib.Visibility,
+				expired *feedlib.BooleanFilter,
+				filterParams *helpers.FilterParams,
+			) ([]feedlib.Item, error) {
+				assert.Equal(t, feedlib.BooleanFilterTrue, persistent)
+				assert.Nil(t, status, "items are not filtered by their status")
+				return []feedlib.Item{{ID: "item", Timestamp: lastWeek}}, nil
+			},
+			GetNudgesFn: func(
+				ctx context.Context,
+				uid string,
+				flavour feedlib.Flavour,
+				status *feedlib.Status,
+				visibility *feedlib.Visibility,
+				expired *feedlib.BooleanFilter,
+			) ([]feedlib.Nudge, error) {
+				return []feedlib.Nudge{{ID: "nudge"}}, nil
+			},
+			SaveDigestSettingsFn: func(
+				ctx context.Context,
+				settings domain.DigestSettings,
+			) error {
+				saved = append(saved, settings)
+				return nil
+			},
+		},
+	})
+
+	due := func(uid string, frequency domain.DigestFrequency) *pubsubtools.PubSubPayload {
+		data, err := json.Marshal(dto.DigestDue{
+			UID:       uid,
+			Flavour:   feedlib.FlavourConsumer,
+			Frequency: frequency,
+		})
+		assert.Nil(t, err)
+		return &pubsubtools.PubSubPayload{Message: pubsubtools.PubSubMessage{Data: data}}
+	}
+
+	for _, message := range []*pubsubtools.PubSubPayload{
+		due("recent", domain.DigestFrequencyDaily),
+		due("weekly", domain.DigestFrequencyDaily),
+		due("unknown", domain.DigestFrequencyDaily),
+	} {
+		assert.Nil(t, n.HandleDigestDue(ctx, message))
+	}
+	assert.Empty(t, saved, "digests that are no longer due are left alone")
+
+	assert.Nil(t, n.HandleDigestDue(ctx, due("seen", domain.DigestFrequencyDaily)))
+	assert.Nil(t, n.HandleDigestDue(ctx, due("opted-out", domain.DigestFrequencyDaily)))
+	assert.Len(t, saved, 2, "nothing is new for `seen` and `opted-out` turned digests off")
+	for _, settings := range saved {
+		assert.True(t, settings.LastDigestAt.After(now.Add(-time.Minute)))
+	}
+
+	assert.NotNil(t, n.HandleDigestDue(ctx, nil))
+}
@@ -0,0 +1,211 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// ExpiryReminderIntervalEnvVarName is the environment variable that sets how
+// long before an item or nudge expires a "due soon" reminder is sent e.g
+// `24h`. Reminders are not sent when it is not set.
+const ExpiryReminderIntervalEnvVarName = "EXPIRY_REMINDER_INTERVAL"
+
+const (
+	// expiringPageSize is the number of expiring elements read at a time
+	expiringPageSize = 100
+
+	// expiringRunLimit bounds the number of expiring items, and of expiring
+	// nudges, that one run processes so that a backlog does not hold up the
+	// request. The rest are left for the next run.
+	expiringRunLimit = 1000
+)
+
+// expiryReminderInterval returns the configured reminder interval. A zero
+// interval turns reminders off.
+func expiryReminderInterval() (time.Duration, error) {
+	val, ok := os.LookupEnv(ExpiryReminderIntervalEnvVarName)
+	if !ok || val == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"invalid %s `%s`: %w", ExpiryReminderIntervalEnvVarName, val, err)
+	}
+	if interval < 0 {
+		return 0, fmt.Errorf(
+			"%s should not be negative", ExpiryReminderIntervalEnvVarName)
+	}
+	return interval, nil
+}
+
+// ProcessExpiredElements archives the items and nudges whose expiry has
+// passed and queues "due soon" reminders for those that expire within the
+// configured reminder interval.
+//
+// It is meant to be run periodically e.g by a scheduler calling the
+// inter-service API. Each run processes a bounded number of elements and sets
+// `HasMore` on the report when it leaves some for the next run. Each archived
+// element is announced on the `items.expire` or `nudges.expire` topic and
+// each reminder on the `items.due_soon` or `nudges.due_soon` topic.
+func (fe UseCaseImpl) ProcessExpiredElements(
+	ctx context.Context,
+) (*domain.ExpiryReport, error) {
+	ctx, span := tracer.Start(ctx, "ProcessExpiredElements")
+	defer span.End()
+
+	interval, err := expiryReminderInterval()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+
+	now := time.Now()
+	report := &domain.ExpiryReport{Errors: []string{}}
+	for _, elementType := range []domain.ElementType{
+		domain.ElementTypeItem,
+		domain.ElementTypeNudge,
+	} {
+		var after *domain.ExpiryCursor
+		for processed := 0; ; {
+			if processed >= expiringRunLimit {
+				report.HasMore = true
+				break
+			}
+			expiring, err := fe.infrastructure.GetElementsExpiringBefore(
+				ctx,
+				elementType,
+				now.Add(interval),
+				after,
+				expiringPageSize,
+			)
+			if err != nil {
+				helpers.RecordSpanError(span, err)
+				return nil, fmt.Errorf("unable to get expiring elements: %w", err)
+			}
+
+			for _, el := range expiring {
+				if el.Expiry().After(now) {
+					err = fe.remindDueSoon(ctx, el, report)
+				} else {
+					err = fe.archiveExpired(ctx, el, report)
+				}
+				if err != nil {
+					helpers.RecordSpanError(span, err)
+					report.Errors = append(report.Errors, fmt.Sprintf(
+						"%s %s in %s's feed: %s", el.ElementType, el.ID(), el.UID, err))
+				}
+			}
+			processed += len(expiring)
+			if len(expiring) < expiringPageSize {
+				break
+			}
+			after = &expiring[len(expiring)-1].Cursor
+		}
+	}
+
+	return report, nil
+}
+
+func (fe UseCaseImpl) archiveExpired(
+	ctx context.Context,
+	el domain.ExpiringElement,
+	report *domain.ExpiryReport,
+) error {
+	var topic string
+	var element feedlib.Element
+	switch el.ElementType {
+	case domain.ElementTypeItem:
+		err := fe.infrastructure.ArchiveFeedItem(ctx, el.UID, el.Flavour, el.Item)
+		if err != nil {
+			return fmt.Errorf("unable to archive item: %w", err)
+		}
+		report.ArchivedItems++
+		topic, element = common.ItemExpireTopic, el.Item
+	case domain.ElementTypeNudge:
+		err := fe.infrastructure.ArchiveNudge(ctx, el.UID, el.Flavour, el.Nudge)
+		if err != nil {
+			return fmt.Errorf("unable to archive nudge: %w", err)
+		}
+		report.ArchivedNudges++
+		topic, element = common.NudgeExpireTopic, el.Nudge
+	default:
+		return fmt.Errorf("%s elements do not expire", el.ElementType)
+	}
+
+	if err := fe.infrastructure.Notify(
+		ctx,
+		helpers.AddPubSubNamespace(topic),
+		el.UID,
+		el.Flavour,
+		element,
+		map[string]interface{}{
+			"elementID": el.ID(),
+			"expiry":    el.Expiry(),
+		},
+	); err != nil {
+		return fmt.Errorf("unable to notify expiry to channel: %w", err)
+	}
+	return nil
+}
+
+func (fe UseCaseImpl) remindDueSoon(
+	ctx context.Context,
+	el domain.ExpiringElement,
+	report *domain.ExpiryReport,
+) error {
+	var topic string
+	var element feedlib.Element
+	switch el.ElementType {
+	case domain.ElementTypeItem:
+		topic, element = common.ItemDueSoonTopic, el.Item
+	case domain.ElementTypeNudge:
+		topic, element = common.NudgeDueSoonTopic, el.Nudge
+	default:
+		return fmt.Errorf("%s elements do not expire", el.ElementType)
+	}
+
+	claimed, err := fe.infrastructure.ClaimExpiryReminder(
+		ctx,
+		el.UID,
+		el.Flavour,
+		el.ElementType,
+		el.ID(),
+		el.Expiry(),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to claim expiry reminder: %w", err)
+	}
+	if !claimed {
+		return nil // already reminded
+	}
+
+	if err := fe.infrastructure.Notify(
+		ctx,
+		helpers.AddPubSubNamespace(topic),
+		el.UID,
+		el.Flavour,
+		element,
+		map[string]interface{}{
+			"elementID": el.ID(),
+			"expiry":    el.Expiry(),
+		},
+	); err != nil {
+		return fmt.Errorf("unable to notify due soon reminder to channel: %w", err)
+	}
+
+	switch el.ElementType {
+	case domain.ElementTypeItem:
+		report.ItemReminders++
+	case domain.ElementTypeNudge:
+		report.NudgeReminders++
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	messagingMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/messaging/mock"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiryReminderInterval(t *testing.T) {
+	initial, wasSet := os.LookupEnv(ExpiryReminderIntervalEnvVarName)
+	defer func() {
+		if wasSet {
+			os.Setenv(ExpiryReminderIntervalEnvVarName, initial)
+		} else {
+			os.Unsetenv(ExpiryReminderIntervalEnvVarName)
+		}
+	}()
+
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{
+			name:  "happy case: reminders turned off",
+			value: "",
+			want:  0,
+		},
+		{
+			name:  "happy case: a day before expiry",
+			value: "24h",
+			want:  24 * time.Hour,
+		},
+		{
+			name:    "sad case: not a duration",
+			value:   "tomorrow",
+			wantErr: true,
+		},
+		{
+			name:    "sad case: negative duration",
+			value:   "-1h",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv(ExpiryReminderIntervalEnvVarName, tt.value)
+			got, err := expiryReminderInterval()
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUseCaseImpl_ProcessExpiredElementsPaging(t *testing.T) {
+	os.Unsetenv(ExpiryReminderIntervalEnvVarName)
+	expired := time.Now().Add(-time.Hour)
+	backlog := []domain.ExpiringElement{}
+	for i := 0; i < expiringRunLimit+expiringPageSize/2; i++ {
+		backlog = append(backlog, domain.ExpiringElement{
+			UID:         "uid",
+			Flavour:     feedlib.FlavourConsumer,
+			ElementType: domain.ElementTypeItem,
+			Item:        &feedlib.Item{ID: fmt.Sprintf("item-%d", i), Expiry: expired},
+			Cursor:      domain.ExpiryCursor{Expiry: expired, Path: fmt.Sprintf("items/%05d", i)},
+		})
+	}
+
+	archived := 0
+	fe := UseCaseImpl{infrastructure: infrastructure.Interactor{
+		Repository: &mock.FakeEngagementRepository{
+			GetElementsExpiringBeforeFn: func(
+				ctx context.Context,
+				elementType domain.ElementType,
+				before time.Time,
+				after *domain.ExpiryCursor,
+				limit int,
+			) ([]domain.ExpiringElement, error) {
+				if elementType != domain.ElementTypeItem {
+					return []domain.ExpiringElement{}, nil
+				}
+				start := 0
+				if after != nil {
+					for i, el := range backlog {
+						if el.Cursor.Path == after.Path {
+							start = i + 1
+						}
+					}
+				}
+				end := start + limit
+				if end > len(backlog) {
+					end = len(backlog)
+				}
+				return backlog[start:end], nil
+			},
+			ArchiveFeedItemFn: func(
+				ctx context.Context,
+				uid string,
+				flavour feedlib.Flavour,
+				item *feedlib.Item,
+			) error {
+				archived++
+				return nil
+			},
+		},
+		NotificationService: &messagingMock.FakeServiceMessaging{
+			NotifyFn: func(
+				ctx context.Context,
+				topicID string,
+				uid string,
+				flavour feedlib.Flavour,
+				payload feedlib.Element,
+				metadata map[string]interface{},
+			) error {
+				return nil
+			},
+		},
+	}}
+
+	report, err := fe.ProcessExpiredElements(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, expiringRunLimit, report.ArchivedItems)
+	assert.Equal(t, expiringRunLimit, archived)
+	assert.True(t, report.HasMore, "the rest of the backlog is left for the next run")
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/exceptions"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/i18n"
 	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 )
 
@@ -231,6 +232,182 @@ type Usecases interface {
 		flavour feedlib.Flavour,
 		nudgeID string,
 	) error
+
+	SaveElementTranslations(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		translations *domain.ElementTranslations,
+	) (*domain.ElementTranslations, error)
+
+	FeedChanges(
+		ctx context.Context,
+		uid string,
+		isAnonymous bool,
+		flavour feedlib.Flavour,
+		since *string,
+	) (*domain.FeedChanges, error)
+
+	ProcessExpiredElements(
+		ctx context.Context,
+	) (*domain.ExpiryReport, error)
+
+	LabelCounts(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) ([]domain.LabelCount, error)
+
+	RenameLabel(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		label string,
+		newLabel string,
+	) error
+
+	MergeLabels(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		labels []string,
+		target string,
+	) error
+
+	DeleteLabel(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		label string,
+	) error
+
+	MergeAnonymousFeed(
+		ctx context.Context,
+		anonymousUID string,
+		uid string,
+		flavour feedlib.Flavour,
+	) (*domain.FeedMerge, error)
+
+	MuteThread(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		participantUID string,
+		muted bool,
+	) error
+
+	BulkUpdateFeed(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		operation domain.BulkOperation,
+		itemIDs []string,
+		nudgeIDs []string,
+	) (*domain.BulkResult, error)
+
+	GetThread(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+	) ([]*domain.ThreadMessage, error)
+
+	EditMessage(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		text string,
+		editedByUID string,
+	) (*domain.ThreadMessage, error)
+
+	ReactToMessage(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		emoji string,
+		reactorUID string,
+		reacted bool,
+	) error
+
+	AttachToMessage(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		uploadIDs []string,
+	) (*domain.ThreadMessage, error)
+
+	SetChecklist(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		checklist domain.Checklist,
+	) (*domain.Checklist, error)
+
+	GetChecklist(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+	) (*domain.Checklist, error)
+
+	CompleteChecklistStep(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		stepID string,
+	) (*domain.Checklist, error)
+
+	ReportMessage(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		itemID string,
+		messageID string,
+		reportedByUID string,
+		reason string,
+	) (*domain.ModerationCase, error)
+
+	ModerationQueue(
+		ctx context.Context,
+		status domain.ModerationStatus,
+	) ([]domain.ModerationCase, error)
+
+	ModerateMessage(
+		ctx context.Context,
+		caseID string,
+		action domain.ModerationAction,
+		moderatorUID string,
+		reason string,
+	) (*domain.ModerationCase, error)
+
+	ModerationAudit(
+		ctx context.Context,
+		caseID string,
+	) ([]domain.ModerationAuditEntry, error)
+
+	NotificationDeliveries(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		elementID string,
+	) ([]domain.ChannelDelivery, error)
+
+	InvokeAction(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		actionID string,
+		payload map[string]interface{},
+	) (*domain.ActionResult, error)
 }
 
 // UseCaseImpl represents the feed usecase implementation
@@ -263,6 +440,20 @@ func (fe UseCaseImpl) GetFeed(
 	ctx, span := tracer.Start(ctx, "GetFeed")
 	defer span.End()
 
+	// a merged anonymous feed is left empty and should not be filled with
+	// default content again
+	if isAnonymous != nil && *isAnonymous && uid != nil {
+		merge, err := fe.infrastructure.GetFeedMerge(ctx, *uid, flavour)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to check for a feed merge: %w", err)
+		}
+		if merge != nil {
+			return nil, fmt.Errorf(
+				"this anonymous feed was merged into a signed up user's feed")
+		}
+	}
+
 	feed, err := fe.infrastructure.GetFeed(
 		ctx,
 		uid,
@@ -280,6 +471,18 @@ func (fe UseCaseImpl) GetFeed(
 		return nil, fmt.Errorf("feed retrieval error: %w", err)
 	}
 
+	err = localizeFeed(ctx, fe.infrastructure, feed)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("feed localization error: %w", err)
+	}
+
+	feed.Progress, err = fe.feedProgress(ctx, feed)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("feed progress error: %w", err)
+	}
+
 	// set the ID (computed, not stored)
 	feed.ID = feed.GetID()
 	feed.SequenceNumber = int(time.Now().Unix())
@@ -336,6 +539,20 @@ func (fe UseCaseImpl) GetFeedItem(
 		return nil, nil
 	}
 
+	language := resolveLanguage(ctx, fe.infrastructure, uid, false)
+	translations, err := elementTranslations(
+		ctx,
+		fe.infrastructure,
+		uid,
+		flavour,
+		language,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("feed item localization error: %w", err)
+	}
+	localizeItem(item, translations, language)
+
 	return item, nil
 }
 
@@ -358,6 +575,20 @@ func (fe UseCaseImpl) GetNudge(
 		return nil, nil
 	}
 
+	language := resolveLanguage(ctx, fe.infrastructure, uid, false)
+	translations, err := elementTranslations(
+		ctx,
+		fe.infrastructure,
+		uid,
+		flavour,
+		language,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("nudge localization error: %w", err)
+	}
+	localizeNudge(nudge, translations, language)
+
 	return nudge, nil
 }
 
@@ -448,7 +679,7 @@ func (fe UseCaseImpl) DeleteFeedItem(
 	ctx, span := tracer.Start(ctx, "DeleteFeedItem")
 	defer span.End()
 
-	item, err := fe.GetFeedItem(ctx, uid, flavour, itemID)
+	item, err := fe.infrastructure.GetFeedItem(ctx, uid, flavour, itemID)
 	if err != nil || item == nil {
 		// fails to error because it should be safe to retry deletes
 		return nil // does not exist, nothing to delete
@@ -496,6 +727,12 @@ func (fe UseCaseImpl) ResolveFeedItem(
 		return nil, exceptions.ErrNilFeedItem
 	}
 
+	err = fe.checkResolvable(ctx, uid, flavour, itemID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("can't resolve feed item: %w", err)
+	}
+
 	item.Status = feedlib.StatusDone
 	item.SequenceNumber = item.SequenceNumber + 1
 
@@ -1125,7 +1362,7 @@ func (fe UseCaseImpl) DeleteNudge(
 ) error {
 	ctx, span := tracer.Start(ctx, "DeleteNudge")
 	defer span.End()
-	nudge, err := fe.GetNudge(ctx, uid, flavour, nudgeID)
+	nudge, err := fe.infrastructure.GetNudge(ctx, uid, flavour, nudgeID)
 	if err != nil || nudge == nil {
 		return nil // no error, "re-deleting" a nudge should not cause an error
 	}
@@ -1265,6 +1502,8 @@ func (fe UseCaseImpl) PostMessage(
 		return nil, fmt.Errorf("can't post nil message")
 	}
 
+	// the ID is assigned before the message is moderated so that the
+	// moderation audit can refer to it
 	if message.ID == "" {
 		message.ID = ksuid.New().String()
 	}
@@ -1279,6 +1518,20 @@ func (fe UseCaseImpl) PostMessage(
 		return nil, fmt.Errorf("invalid message: %w", err)
 	}
 
+	message.Text, err = fe.moderateText(
+		ctx,
+		uid,
+		flavour,
+		itemID,
+		message.ID,
+		message.PostedByUID,
+		message.Text,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to post a message: %w", err)
+	}
+
 	msg, err := fe.infrastructure.PostMessage(
 		ctx,
 		uid,
@@ -1428,6 +1681,12 @@ func (fe UseCaseImpl) ProcessEvent(
 			"unable to publish incoming event to channel: %w", err)
 	}
 
+	err = fe.completeStepsForEvent(ctx, uid, flavour, event)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to complete checklist steps: %w", err)
+	}
+
 	return nil
 }
 
@@ -1452,3 +1711,52 @@ func (fe UseCaseImpl) GetDefaultNudgeByTitle(
 
 	return nudge, nil
 }
+
+// SaveElementTranslations records the language variants of a feed item or
+// nudge's text. The element's own text is the default language variant.
+func (fe UseCaseImpl) SaveElementTranslations(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	translations *domain.ElementTranslations,
+) (*domain.ElementTranslations, error) {
+	ctx, span := tracer.Start(ctx, "SaveElementTranslations")
+	defer span.End()
+	if translations == nil {
+		return nil, fmt.Errorf("nil element translations")
+	}
+	if translations.ElementID == "" {
+		return nil, fmt.Errorf("element translations must have an element ID")
+	}
+	if len(translations.Variants) == 0 {
+		return nil, fmt.Errorf("element translations must have at least one variant")
+	}
+
+	variants := map[string]domain.LocalizedContent{}
+	for language, content := range translations.Variants {
+		if !i18n.IsSupported(language) {
+			return nil, fmt.Errorf(
+				"%s is not a supported language, expected one of %v",
+				language,
+				i18n.SupportedLanguages(),
+			)
+		}
+		variants[i18n.Resolve(language)] = content
+	}
+
+	saved, err := fe.infrastructure.SaveElementTranslations(
+		ctx,
+		uid,
+		flavour,
+		&domain.ElementTranslations{
+			ElementID: translations.ElementID,
+			Variants:  variants,
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to save element translations: %w", err)
+	}
+
+	return saved, nil
+}
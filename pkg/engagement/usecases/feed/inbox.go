@@ -0,0 +1,192 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// InboxCountPushWindowEnvVarName is the environment variable that sets how
+// long inbox changes are collected before the inbox count is pushed e.g
+// `2m`. It defaults to a minute.
+const InboxCountPushWindowEnvVarName = "INBOX_COUNT_PUSH_WINDOW"
+
+// InboxCountNotificationThresholdEnvVarName is the environment variable
+// that sets the unread count from which the inbox count push also shows a
+// tray notification. Only silent pushes are sent when it is not set.
+const InboxCountNotificationThresholdEnvVarName = "INBOX_COUNT_NOTIFICATION_THRESHOLD"
+
+const defaultInboxCountPushWindow = time.Minute
+
+// inboxCountPushBatchSize is the most inbox counts that are pushed in one
+// run of the processor. The rest go out on the next run.
+const inboxCountPushBatchSize = 500
+
+// inboxCountPushWindow returns the configured push window
+func inboxCountPushWindow() (time.Duration, error) {
+	val, ok := os.LookupEnv(InboxCountPushWindowEnvVarName)
+	if !ok || val == "" {
+		return defaultInboxCountPushWindow, nil
+	}
+	window, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"invalid %s `%s`: %w", InboxCountPushWindowEnvVarName, val, err)
+	}
+	if window < 0 {
+		return 0, fmt.Errorf(
+			"%s should not be negative", InboxCountPushWindowEnvVarName)
+	}
+	return window, nil
+}
+
+// inboxCountNotificationThreshold returns the configured tray notification
+// threshold. A zero threshold turns tray notifications off.
+func inboxCountNotificationThreshold() (int, error) {
+	val, ok := os.LookupEnv(InboxCountNotificationThresholdEnvVarName)
+	if !ok || val == "" {
+		return 0, nil
+	}
+	threshold, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"invalid %s `%s`: %w", InboxCountNotificationThresholdEnvVarName, val, err)
+	}
+	if threshold < 0 {
+		return 0, fmt.Errorf(
+			"%s should not be negative", InboxCountNotificationThresholdEnvVarName)
+	}
+	return threshold, nil
+}
+
+// queueInboxCountPush schedules a push of a feed's inbox count for the end
+// of the current window. Changes within the window share the push.
+func (n NotificationImpl) queueInboxCountPush(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) error {
+	window, err := inboxCountPushWindow()
+	if err != nil {
+		return err
+	}
+	return n.infrastructure.QueueInboxCountPush(
+		ctx,
+		uid,
+		flavour,
+		time.Now().Add(window),
+	)
+}
+
+// ProcessInboxCountPushes sends the inbox counts of the feeds whose push
+// window has ended. Each feed gets a single silent data push with its
+// current unread count, however many changes were made in the window. A tray
+// notification is shown too when the count reaches the configured
+// threshold and the user's notification preferences allow it.
+//
+// It is meant to be run periodically e.g every minute by a scheduler calling
+// the inter-service API.
+func (n NotificationImpl) ProcessInboxCountPushes(
+	ctx context.Context,
+) (*domain.InboxCountPushReport, error) {
+	ctx, span := tracer.Start(ctx, "ProcessInboxCountPushes")
+	defer span.End()
+
+	threshold, err := inboxCountNotificationThreshold()
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+	due, err := n.infrastructure.GetDueInboxCountPushes(
+		ctx,
+		time.Now(),
+		inboxCountPushBatchSize,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get due inbox count pushes: %w", err)
+	}
+
+	report := &domain.InboxCountPushReport{Errors: []string{}}
+	for _, push := range due {
+		err := n.pushInboxCount(ctx, push, threshold, report)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			report.Errors = append(report.Errors, fmt.Sprintf(
+				"inbox count of %s's %s feed: %s", push.UID, push.Flavour, err))
+		}
+	}
+	return report, nil
+}
+
+func (n NotificationImpl) pushInboxCount(
+	ctx context.Context,
+	push domain.InboxCountPush,
+	threshold int,
+	report *domain.InboxCountPushReport,
+) error {
+	count, err := n.infrastructure.UnreadPersistentItems(ctx, push.UID, push.Flavour)
+	if err != nil {
+		return fmt.Errorf("can't get inbox count: %w", err)
+	}
+
+	notify := false
+	if threshold > 0 && count >= threshold {
+		screening, err := n.preferences.ScreenUsers(
+			ctx,
+			[]string{push.UID},
+			feedlib.ChannelFcm,
+			domain.NotificationCategoryItems,
+			false,
+		)
+		if err != nil {
+			return err
+		}
+		notify = len(screening.Allowed) > 0
+	}
+
+	if notify {
+		err = n.NotifyInboxCountUpdate(ctx, push.UID, push.Flavour, count)
+	} else {
+		err = n.sendDataViaFCM(
+			ctx,
+			[]string{push.UID},
+			feedUpdate,
+			inboxCountEnvelope(push.UID, push.Flavour, count),
+		)
+	}
+	if err != nil {
+		return err
+	}
+	report.Pushed++
+	report.Changes += push.Changes
+	if notify {
+		report.Notified++
+	}
+
+	return n.infrastructure.DeleteInboxCountPush(ctx, push)
+}
+
+// inboxCountEnvelope carries a feed's unread inbox count to its devices
+func inboxCountEnvelope(
+	uid string,
+	flavour feedlib.Flavour,
+	count int,
+) dto.NotificationEnvelope {
+	return dto.NotificationEnvelope{
+		UID:     uid,
+		Flavour: flavour,
+		Payload: []byte(fmt.Sprintf("%d", count)),
+		Metadata: map[string]interface{}{
+			"sender": inboxCountUpdate,
+			"count":  count,
+		},
+	}
+}
@@ -0,0 +1,152 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
+	onboardingMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding/mock"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+// setEnv sets an environment variable for the rest of a test
+func setEnv(t *testing.T, name string, value string) {
+	initial, wasSet := os.LookupEnv(name)
+	t.Cleanup(func() {
+		if wasSet {
+			os.Setenv(name, initial)
+		} else {
+			os.Unsetenv(name)
+		}
+	})
+	os.Setenv(name, value)
+}
+
+func TestInboxCountPushSettings(t *testing.T) {
+	window, err := inboxCountPushWindow()
+	assert.Nil(t, err)
+	assert.Equal(t, defaultInboxCountPushWindow, window)
+
+	setEnv(t, InboxCountPushWindowEnvVarName, "5m")
+	window, err = inboxCountPushWindow()
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Minute, window)
+
+	setEnv(t, InboxCountPushWindowEnvVarName, "soon")
+	_, err = inboxCountPushWindow()
+	assert.NotNil(t, err)
+
+	threshold, err := inboxCountNotificationThreshold()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, threshold, "tray notifications are off by default")
+
+	setEnv(t, InboxCountNotificationThresholdEnvVarName, "-1")
+	_, err = inboxCountNotificationThreshold()
+	assert.NotNil(t, err)
+}
+
+func TestNotificationImpl_UpdateInbox(t *testing.T) {
+	ctx := context.Background()
+	queued := []time.Time{}
+	n := NewNotification(infrastructure.Interactor{
+		Repository: &mock.FakeEngagementRepository{
+			UpdateUnreadPersistentItemsCountFn: func(
+				ctx context.Context,
+				uid string,
+				flavour feedlib.Flavour,
+			) error {
+				return nil
+			},
+			QueueInboxCountPushFn: func(
+				ctx context.Context,
+				uid string,
+				flavour feedlib.Flavour,
+				dueAt time.Time,
+			) error {
+				queued = append(queued, dueAt)
+				return nil
+			},
+		},
+	})
+
+	before := time.Now()
+	for i := 0; i < 3; i++ {
+		err := n.UpdateInbox(ctx, "uid", feedlib.FlavourConsumer)
+		assert.Nil(t, err)
+	}
+	assert.Len(t, queued, 3, "every change is queued, to be collapsed into one push")
+	assert.False(t, queued[0].Before(before.Add(defaultInboxCountPushWindow)))
+}
+
+func TestNotificationImpl_ProcessInboxCountPushes(t *testing.T) {
+	ctx := context.Background()
+	setEnv(t, InboxCountNotificationThresholdEnvVarName, "3")
+	due := []domain.InboxCountPush{
+		{UID: "quiet", Flavour: feedlib.FlavourConsumer, Changes: 4},
+		{UID: "busy", Flavour: feedlib.FlavourConsumer, Changes: 2},
+		{UID: "broken", Flavour: feedlib.FlavourConsumer, Changes: 1},
+	}
+	counts := map[string]int{"quiet": 1, "busy": 5}
+	deleted := []string{}
+	n := NewNotification(infrastructure.Interactor{
+		Repository: &mock.FakeEngagementRepository{
+			GetDueInboxCountPushesFn: func(
+				ctx context.Context,
+				before time.Time,
+				limit int,
+			) ([]domain.InboxCountPush, error) {
+				return due, nil
+			},
+			UnreadPersistentItemsFn: func(
+				ctx context.Context,
+				uid string,
+				flavour feedlib.Flavour,
+			) (int, error) {
+				count, ok := counts[uid]
+				if !ok {
+					return -1, fmt.Errorf("no inbox count")
+				}
+				return count, nil
+			},
+			GetNotificationPreferencesFn: func(
+				ctx context.Context,
+				uid string,
+			) (*domain.NotificationPreferences, error) {
+				return nil, nil
+			},
+			DeleteInboxCountPushFn: func(
+				ctx context.Context,
+				push domain.InboxCountPush,
+			) error {
+				deleted = append(deleted, push.UID)
+				return nil
+			},
+		},
+		ProfileService: &onboardingMock.FakeServiceOnboarding{
+			GetDeviceTokensFn: func(
+				ctx context.Context,
+				uids onboarding.UserUIDs,
+			) (map[string][]string, error) {
+				return map[string][]string{}, nil
+			},
+			GetPreferredLanguageFn: func(ctx context.Context, uid string) (string, error) {
+				return "en", nil
+			},
+		},
+	})
+
+	report, err := n.ProcessInboxCountPushes(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.Pushed)
+	assert.Equal(t, 1, report.Notified, "only counts over the threshold show a tray notification")
+	assert.Equal(t, 6, report.Changes)
+	assert.Len(t, report.Errors, 1)
+	assert.Equal(t, []string{"quiet", "busy"}, deleted, "failed pushes are kept for a retry")
+}
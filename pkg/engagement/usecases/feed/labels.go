@@ -0,0 +1,201 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/feedlib"
+)
+
+// LabelCounts returns the number of items and unread items for each label
+func (fe UseCaseImpl) LabelCounts(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) ([]domain.LabelCount, error) {
+	ctx, span := tracer.Start(ctx, "LabelCounts")
+	defer span.End()
+
+	counts, err := fe.infrastructure.LabelCounts(ctx, uid, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to count labels: %w", err)
+	}
+	return counts, nil
+}
+
+// RenameLabel renames a label and moves the items that carry it to the new
+// name. Renaming to an existing label is not allowed; merge the labels
+// instead.
+func (fe UseCaseImpl) RenameLabel(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	label string,
+	newLabel string,
+) error {
+	ctx, span := tracer.Start(ctx, "RenameLabel")
+	defer span.End()
+
+	newLabel = strings.TrimSpace(newLabel)
+	if newLabel == "" {
+		return fmt.Errorf("a label can't be renamed to a blank label")
+	}
+	if newLabel == label {
+		return nil
+	}
+
+	labels, err := fe.infrastructure.Labels(ctx, uid, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to retrieve labels: %w", err)
+	}
+	if !converterandformatter.StringSliceContains(labels, label) {
+		return fmt.Errorf("no label `%s` found", label)
+	}
+	if converterandformatter.StringSliceContains(labels, newLabel) {
+		return fmt.Errorf(
+			"the label `%s` already exists, merge the labels instead", newLabel)
+	}
+
+	return fe.moveLabels(ctx, uid, flavour, []string{label}, newLabel)
+}
+
+// MergeLabels moves the items that carry any of the supplied labels to the
+// target label and removes the merged labels. The target label is created if
+// it does not exist.
+func (fe UseCaseImpl) MergeLabels(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	labels []string,
+	target string,
+) error {
+	ctx, span := tracer.Start(ctx, "MergeLabels")
+	defer span.End()
+
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return fmt.Errorf("labels can't be merged into a blank label")
+	}
+
+	merged := []string{}
+	for _, label := range labels {
+		if label != target &&
+			!converterandformatter.StringSliceContains(merged, label) {
+			merged = append(merged, label)
+		}
+	}
+	if len(merged) == 0 {
+		return fmt.Errorf("no labels to merge into `%s`", target)
+	}
+
+	return fe.moveLabels(ctx, uid, flavour, merged, target)
+}
+
+// moveLabels relabels the items that carry the supplied labels then replaces
+// the labels with the target label in the feed's list of labels
+func (fe UseCaseImpl) moveLabels(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	labels []string,
+	target string,
+) error {
+	ctx, span := tracer.Start(ctx, "moveLabels")
+	defer span.End()
+
+	err := fe.infrastructure.SaveLabel(ctx, uid, flavour, target)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to save label: %w", err)
+	}
+
+	for _, label := range labels {
+		_, err := fe.infrastructure.RelabelItems(ctx, uid, flavour, label, target)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return fmt.Errorf("unable to relabel `%s` items: %w", label, err)
+		}
+	}
+
+	err = fe.infrastructure.RemoveLabels(ctx, uid, flavour, labels)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to remove labels: %w", err)
+	}
+
+	return fe.infrastructure.UpdateUnreadPersistentItemsCount(ctx, uid, flavour)
+}
+
+// DeleteLabel removes a label that no items carry. Labels that still have
+// items should be merged into another label instead.
+func (fe UseCaseImpl) DeleteLabel(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	label string,
+) error {
+	ctx, span := tracer.Start(ctx, "DeleteLabel")
+	defer span.End()
+
+	counts, err := fe.infrastructure.LabelCounts(ctx, uid, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to count labels: %w", err)
+	}
+	for _, count := range counts {
+		if count.Label == label && count.Items > 0 {
+			return fmt.Errorf(
+				"the label `%s` still has %d item(s), merge it into another label instead",
+				label,
+				count.Items,
+			)
+		}
+	}
+
+	err = fe.infrastructure.RemoveLabels(ctx, uid, flavour, []string{label})
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to remove label: %w", err)
+	}
+	return nil
+}
+
+// cleanUpLabels removes the labels that no items carry any more. The default
+// label is kept since every new feed starts with it.
+func cleanUpLabels(
+	ctx context.Context,
+	infra infrastructure.Interactor,
+	uid string,
+	flavour feedlib.Flavour,
+) error {
+	ctx, span := tracer.Start(ctx, "cleanUpLabels")
+	defer span.End()
+
+	counts, err := infra.LabelCounts(ctx, uid, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to count labels: %w", err)
+	}
+
+	unused := []string{}
+	for _, count := range counts {
+		if count.Items == 0 && count.Label != common.DefaultLabel {
+			unused = append(unused, count.Label)
+		}
+	}
+
+	err = infra.RemoveLabels(ctx, uid, flavour, unused)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to remove unused labels: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/i18n"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+)
+
+// LanguageCacheTTL is how long a user's preferred language is kept before it
+// is looked up again. Every notification needs the language of its
+// recipient, so the lookups are cached and a new choice of language applies
+// once the cached one expires.
+const LanguageCacheTTL = 5 * time.Minute
+
+type cachedLanguage struct {
+	language  string
+	expiresAt time.Time
+}
+
+// languageCache keeps users' preferred languages for a while. It is shared by
+// all the usecase instances in the process.
+type languageCache struct {
+	mu        sync.RWMutex
+	languages map[string]cachedLanguage
+
+	// expired languages are dropped at most once per TTL
+	sweptAt time.Time
+}
+
+var languages = &languageCache{languages: map[string]cachedLanguage{}}
+
+func (c *languageCache) get(uid string, now time.Time) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cached, ok := c.languages[uid]
+	if !ok || !cached.expiresAt.After(now) {
+		return "", false
+	}
+	return cached.language, true
+}
+
+func (c *languageCache) set(uid string, language string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.sweptAt) >= LanguageCacheTTL {
+		for cachedUID, cached := range c.languages {
+			if !cached.expiresAt.After(now) {
+				delete(c.languages, cachedUID)
+			}
+		}
+		c.sweptAt = now
+	}
+	c.languages[uid] = cachedLanguage{
+		language:  language,
+		expiresAt: now.Add(LanguageCacheTTL),
+	}
+}
+
+// preferredLanguage returns the language that a user picked. A language set
+// in the user's notification preferences overrides the one on their profile,
+// and the default language is used when neither is set or supported.
+func preferredLanguage(
+	ctx context.Context,
+	infra infrastructure.Interactor,
+	uid string,
+) (string, error) {
+	ctx, span := tracer.Start(ctx, "preferredLanguage")
+	defer span.End()
+
+	now := time.Now()
+	if language, ok := languages.get(uid, now); ok {
+		return language, nil
+	}
+
+	preferred := ""
+	if infra.Repository != nil {
+		preferences, err := infra.GetNotificationPreferences(ctx, uid)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return "", fmt.Errorf("unable to get notification preferences: %w", err)
+		}
+		if preferences != nil && i18n.IsSupported(preferences.Language) {
+			preferred = preferences.Language
+		}
+	}
+	if preferred == "" && infra.ProfileService != nil {
+		language, err := infra.GetPreferredLanguage(ctx, uid)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return "", err
+		}
+		preferred = language
+	}
+
+	language := i18n.Resolve(preferred)
+	languages.set(uid, language, now)
+	return language, nil
+}
@@ -0,0 +1,65 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	onboardingMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreferredLanguage(t *testing.T) {
+	ctx := context.Background()
+	saved := map[string]*domain.NotificationPreferences{
+		"language-override": {UID: "language-override", Language: "sw"},
+	}
+	profileLookups := 0
+	infra := infrastructure.Interactor{
+		Repository: &mock.FakeEngagementRepository{
+			GetNotificationPreferencesFn: func(
+				ctx context.Context,
+				uid string,
+			) (*domain.NotificationPreferences, error) {
+				return saved[uid], nil
+			},
+		},
+		ProfileService: &onboardingMock.FakeServiceOnboarding{
+			GetPreferredLanguageFn: func(ctx context.Context, uid string) (string, error) {
+				profileLookups++
+				switch uid {
+				case "language-profile":
+					return "sw-KE", nil
+				case "language-failure":
+					return "", fmt.Errorf("profile service unavailable")
+				}
+				return "en", nil
+			},
+		},
+	}
+
+	language, err := preferredLanguage(ctx, infra, "language-profile")
+	assert.Nil(t, err)
+	assert.Equal(t, "sw", language, "the profile's language is used")
+
+	_, err = preferredLanguage(ctx, infra, "language-profile")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, profileLookups, "the language is cached")
+
+	language, err = preferredLanguage(ctx, infra, "language-override")
+	assert.Nil(t, err)
+	assert.Equal(t, "sw", language, "the preferences override the profile")
+	assert.Equal(t, 1, profileLookups)
+
+	_, err = preferredLanguage(ctx, infra, "language-failure")
+	assert.NotNil(t, err)
+	assert.Equal(
+		t,
+		"en",
+		resolveLanguage(ctx, infra, "language-failure", false),
+		"the default language is used when the lookup fails",
+	)
+}
@@ -0,0 +1,137 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/i18n"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/feedlib"
+)
+
+// resolveLanguage picks the language that a user's content should be shown
+// in. A language requested explicitly (GraphQL argument or `Accept-Language`
+// header) wins over the language that the user picked in their notification
+// preferences or on their profile. The default language is used when none is
+// set or supported.
+func resolveLanguage(
+	ctx context.Context,
+	infra infrastructure.Interactor,
+	uid string,
+	isAnonymous bool,
+) string {
+	ctx, span := tracer.Start(ctx, "resolveLanguage")
+	defer span.End()
+	if language, ok := i18n.LanguageFromContext(ctx); ok {
+		return language
+	}
+	if isAnonymous || uid == "" {
+		return i18n.DefaultLanguage
+	}
+
+	preferred, err := preferredLanguage(ctx, infra, uid)
+	if err != nil {
+		// the content is still usable in the default language
+		helpers.RecordSpanError(span, err)
+		log.Printf("unable to get the preferred language of %s: %s", uid, err)
+		return i18n.DefaultLanguage
+	}
+	return preferred
+}
+
+// elementTranslations fetches the translations for a feed. Nothing is
+// fetched for the default language since content is authored in it.
+func elementTranslations(
+	ctx context.Context,
+	infra infrastructure.Interactor,
+	uid string,
+	flavour feedlib.Flavour,
+	language string,
+) (map[string]domain.ElementTranslations, error) {
+	if language == i18n.DefaultLanguage {
+		return nil, nil
+	}
+	translations, err := infra.GetElementTranslations(ctx, uid, flavour)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get element translations: %w", err)
+	}
+	return translations, nil
+}
+
+func localizeItem(
+	item *feedlib.Item,
+	translations map[string]domain.ElementTranslations,
+	language string,
+) {
+	if item == nil {
+		return
+	}
+	if content, ok := translations[item.ID].Variant(language); ok {
+		content.LocalizeItem(item)
+	}
+}
+
+func localizeNudge(
+	nudge *feedlib.Nudge,
+	translations map[string]domain.ElementTranslations,
+	language string,
+) {
+	if nudge == nil {
+		return
+	}
+	if content, ok := translations[nudge.ID].Variant(language); ok {
+		content.LocalizeNudge(nudge)
+	}
+}
+
+// localizeFeed translates the items and nudges in a feed in place
+func localizeFeed(
+	ctx context.Context,
+	infra infrastructure.Interactor,
+	feed *domain.Feed,
+) error {
+	isAnonymous := feed.IsAnonymous != nil && *feed.IsAnonymous
+	return localizeElements(
+		ctx,
+		infra,
+		feed.UID,
+		feed.Flavour,
+		isAnonymous,
+		feed.Items,
+		feed.Nudges,
+	)
+}
+
+// localizeElements translates the supplied items and nudges in place
+func localizeElements(
+	ctx context.Context,
+	infra infrastructure.Interactor,
+	uid string,
+	flavour feedlib.Flavour,
+	isAnonymous bool,
+	items []feedlib.Item,
+	nudges []feedlib.Nudge,
+) error {
+	language := resolveLanguage(ctx, infra, uid, isAnonymous)
+	translations, err := elementTranslations(
+		ctx,
+		infra,
+		uid,
+		flavour,
+		language,
+	)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		localizeItem(&items[i], translations, language)
+	}
+	for i := range nudges {
+		localizeNudge(&nudges[i], translations, language)
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// MergeAnonymousFeed carries the feed of an anonymous user over to the
+// permanent feed of the user that they signed up as, then empties the
+// anonymous feed.
+//
+// Elements are matched by ID and, since default content gets new IDs in each
+// feed, by their content: items by tagline and label, nudges by title and
+// actions by name. Matched elements are marked done or hidden when the
+// anonymous user resolved or hid them; the rest of their state is kept.
+// Unmatched elements, messages, labels and incoming events are copied over.
+//
+// Merging is idempotent: merging the same feeds again returns the record of
+// the first merge. An anonymous feed can not be merged into a second user.
+func (fe UseCaseImpl) MergeAnonymousFeed(
+	ctx context.Context,
+	anonymousUID string,
+	uid string,
+	flavour feedlib.Flavour,
+) (*domain.FeedMerge, error) {
+	ctx, span := tracer.Start(ctx, "MergeAnonymousFeed")
+	defer span.End()
+
+	anonymousUID = strings.TrimSpace(anonymousUID)
+	uid = strings.TrimSpace(uid)
+	if anonymousUID == "" || uid == "" {
+		return nil, fmt.Errorf("both the anonymous and the permanent UID are required")
+	}
+	if anonymousUID == uid {
+		return nil, fmt.Errorf("a feed can't be merged into itself")
+	}
+	if !flavour.IsValid() {
+		return nil, fmt.Errorf("invalid flavour `%s`", flavour)
+	}
+
+	previous, err := fe.infrastructure.GetFeedMerge(ctx, anonymousUID, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to check for a previous merge: %w", err)
+	}
+	if previous != nil {
+		if previous.UID != uid {
+			return nil, fmt.Errorf(
+				"the anonymous feed was already merged into another user's feed")
+		}
+		return previous, nil
+	}
+
+	anonymous, err := fe.infrastructure.GetFeedElements(ctx, anonymousUID, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get the anonymous feed: %w", err)
+	}
+
+	// make sure that the permanent feed has its default content before
+	// matching, otherwise the defaults would be added again after the merge
+	_, err = fe.infrastructure.GetFeed(
+		ctx,
+		&uid,
+		nil,
+		flavour,
+		false,
+		feedlib.BooleanFilterBoth,
+		nil,
+		nil,
+		nil,
+		nil,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to initialize the permanent feed: %w", err)
+	}
+	permanent, err := fe.infrastructure.GetFeedElements(ctx, uid, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get the permanent feed: %w", err)
+	}
+
+	merge := &domain.FeedMerge{
+		AnonymousUID: anonymousUID,
+		UID:          uid,
+		Flavour:      flavour,
+	}
+
+	for _, item := range anonymous.Items {
+		err := fe.mergeItem(ctx, uid, flavour, item, permanent.Items, merge)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to merge item %s: %w", item.ID, err)
+		}
+	}
+	for _, nudge := range anonymous.Nudges {
+		err := fe.mergeNudge(ctx, uid, flavour, nudge, permanent.Nudges, merge)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to merge nudge %s: %w", nudge.ID, err)
+		}
+	}
+	for _, action := range anonymous.Actions {
+		action := action
+		if matchAction(action, permanent.Actions) != nil {
+			continue
+		}
+		_, err := fe.infrastructure.SaveAction(ctx, uid, flavour, &action)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to copy action %s: %w", action.ID, err)
+		}
+		merge.Actions++
+	}
+
+	err = fe.mergeLabels(ctx, anonymousUID, uid, flavour, merge)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+
+	merge.Events, err = fe.infrastructure.ReassignIncomingEvents(
+		ctx,
+		flavour,
+		anonymousUID,
+		uid,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to reassign incoming events: %w", err)
+	}
+
+	err = fe.clearFeed(ctx, anonymous)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to clear the anonymous feed: %w", err)
+	}
+
+	err = fe.infrastructure.UpdateUnreadPersistentItemsCount(ctx, uid, flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to update unread items count: %w", err)
+	}
+
+	merge.MergedAt = time.Now()
+	err = fe.infrastructure.SaveFeedMerge(ctx, merge)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to record feed merge: %w", err)
+	}
+
+	return merge, nil
+}
+
+// mergeItem applies the state of an anonymous item to the matching permanent
+// item or copies the item, together with its messages, when there is no match
+func (fe UseCaseImpl) mergeItem(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	item feedlib.Item,
+	permanentItems []feedlib.Item,
+	merge *domain.FeedMerge,
+) error {
+	messages := item.Conversations
+	target := matchItem(item, permanentItems)
+	if target == nil {
+		item.Conversations = []feedlib.Message{}
+		_, err := fe.infrastructure.SaveFeedItem(ctx, uid, flavour, &item)
+		if err != nil {
+			return fmt.Errorf("unable to copy item: %w", err)
+		}
+		merge.Items++
+		target = &item
+	} else if mergeItemState(item, target) {
+		target.SequenceNumber++
+		_, err := fe.infrastructure.UpdateFeedItem(ctx, uid, flavour, target)
+		if err != nil {
+			return fmt.Errorf("unable to update item: %w", err)
+		}
+		merge.Items++
+	}
+
+	posted := []string{}
+	for _, message := range target.Conversations {
+		posted = append(posted, message.ID)
+	}
+	for _, message := range messages {
+		message := message
+		if converterandformatter.StringSliceContains(posted, message.ID) {
+			continue
+		}
+		_, err := fe.infrastructure.PostMessage(
+			ctx,
+			uid,
+			flavour,
+			target.ID,
+			&message,
+		)
+		if err != nil {
+			return fmt.Errorf("unable to copy message %s: %w", message.ID, err)
+		}
+		merge.Messages++
+	}
+	return nil
+}
+
+// mergeNudge applies the state of an anonymous nudge to the matching
+// permanent nudge or copies the nudge when there is no match
+func (fe UseCaseImpl) mergeNudge(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	nudge feedlib.Nudge,
+	permanentNudges []feedlib.Nudge,
+	merge *domain.FeedMerge,
+) error {
+	target := matchNudge(nudge, permanentNudges)
+	if target == nil {
+		_, err := fe.infrastructure.SaveNudge(ctx, uid, flavour, &nudge)
+		if err != nil {
+			return fmt.Errorf("unable to copy nudge: %w", err)
+		}
+		merge.Nudges++
+		return nil
+	}
+
+	if !mergeNudgeState(nudge, target) {
+		return nil
+	}
+	target.SequenceNumber++
+	_, err := fe.infrastructure.UpdateNudge(ctx, uid, flavour, target)
+	if err != nil {
+		return fmt.Errorf("unable to update nudge: %w", err)
+	}
+	merge.Nudges++
+	return nil
+}
+
+// mergeLabels adds the anonymous feed's labels to the permanent feed
+func (fe UseCaseImpl) mergeLabels(
+	ctx context.Context,
+	anonymousUID string,
+	uid string,
+	flavour feedlib.Flavour,
+	merge *domain.FeedMerge,
+) error {
+	anonymousLabels, err := fe.infrastructure.Labels(ctx, anonymousUID, flavour)
+	if err != nil {
+		return fmt.Errorf("unable to get the anonymous feed's labels: %w", err)
+	}
+	labels, err := fe.infrastructure.Labels(ctx, uid, flavour)
+	if err != nil {
+		return fmt.Errorf("unable to get the permanent feed's labels: %w", err)
+	}
+	for _, label := range anonymousLabels {
+		if converterandformatter.StringSliceContains(labels, label) {
+			continue
+		}
+		err := fe.infrastructure.SaveLabel(ctx, uid, flavour, label)
+		if err != nil {
+			return fmt.Errorf("unable to save label `%s`: %w", label, err)
+		}
+		merge.Labels++
+	}
+	return nil
+}
+
+// clearFeed deletes every element of a feed. The deletions are recorded as
+// tombstones so that clients that sync the feed drop its content.
+func (fe UseCaseImpl) clearFeed(
+	ctx context.Context,
+	feed *domain.Feed,
+) error {
+	for _, item := range feed.Items {
+		err := fe.infrastructure.DeleteFeedItem(ctx, feed.UID, feed.Flavour, item.ID)
+		if err != nil {
+			return fmt.Errorf("unable to delete item %s: %w", item.ID, err)
+		}
+	}
+	for _, nudge := range feed.Nudges {
+		err := fe.infrastructure.DeleteNudge(ctx, feed.UID, feed.Flavour, nudge.ID)
+		if err != nil {
+			return fmt.Errorf("unable to delete nudge %s: %w", nudge.ID, err)
+		}
+	}
+	for _, action := range feed.Actions {
+		err := fe.infrastructure.DeleteAction(ctx, feed.UID, feed.Flavour, action.ID)
+		if err != nil {
+			return fmt.Errorf("unable to delete action %s: %w", action.ID, err)
+		}
+	}
+	return nil
+}
+
+func matchItem(item feedlib.Item, items []feedlib.Item) *feedlib.Item {
+	for i := range items {
+		if items[i].ID == item.ID {
+			return &items[i]
+		}
+	}
+	for i := range items {
+		if items[i].Tagline == item.Tagline && items[i].Label == item.Label {
+			return &items[i]
+		}
+	}
+	return nil
+}
+
+func matchNudge(nudge feedlib.Nudge, nudges []feedlib.Nudge) *feedlib.Nudge {
+	for i := range nudges {
+		if nudges[i].ID == nudge.ID || nudges[i].Title == nudge.Title {
+			return &nudges[i]
+		}
+	}
+	return nil
+}
+
+func matchAction(action feedlib.Action, actions []feedlib.Action) *feedlib.Action {
+	for i := range actions {
+		if actions[i].ID == action.ID || actions[i].Name == action.Name {
+			return &actions[i]
+		}
+	}
+	return nil
+}
+
+// mergeItemState marks a permanent item done if the anonymous user resolved
+// it and hidden if they hid it, and reports whether the item changed
+func mergeItemState(anonymous feedlib.Item, permanent *feedlib.Item) bool {
+	changed := false
+	if anonymous.Status == feedlib.StatusDone &&
+		permanent.Status != feedlib.StatusDone {
+		permanent.Status = feedlib.StatusDone
+		changed = true
+	}
+	if anonymous.Visibility == feedlib.VisibilityHide &&
+		permanent.Visibility != feedlib.VisibilityHide {
+		permanent.Visibility = feedlib.VisibilityHide
+		changed = true
+	}
+	return changed
+}
+
+// mergeNudgeState marks a permanent nudge done if the anonymous user resolved
+// it and hidden if they hid it, and reports whether the nudge changed
+func mergeNudgeState(anonymous feedlib.Nudge, permanent *feedlib.Nudge) bool {
+	changed := false
+	if anonymous.Status == feedlib.StatusDone &&
+		permanent.Status != feedlib.StatusDone {
+		permanent.Status = feedlib.StatusDone
+		changed = true
+	}
+	if anonymous.Visibility == feedlib.VisibilityHide &&
+		permanent.Visibility != feedlib.VisibilityHide {
+		permanent.Visibility = feedlib.VisibilityHide
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,98 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchItem(t *testing.T) {
+	items := []feedlib.Item{
+		{ID: "1", Tagline: "Welcome", Label: "WELCOME"},
+		{ID: "2", Tagline: "Get started", Label: "WELCOME"},
+	}
+
+	tests := []struct {
+		name   string
+		item   feedlib.Item
+		wantID string
+	}{
+		{
+			name:   "happy case: same ID",
+			item:   feedlib.Item{ID: "2", Tagline: "Renamed", Label: "OTHER"},
+			wantID: "2",
+		},
+		{
+			name:   "happy case: same content, different ID",
+			item:   feedlib.Item{ID: "3", Tagline: "Welcome", Label: "WELCOME"},
+			wantID: "1",
+		},
+		{
+			name: "sad case: same tagline, different label",
+			item: feedlib.Item{ID: "4", Tagline: "Welcome", Label: "OTHER"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchItem(tt.item, items)
+			if tt.wantID == "" {
+				assert.Nil(t, got)
+				return
+			}
+			assert.NotNil(t, got)
+			assert.Equal(t, tt.wantID, got.ID)
+		})
+	}
+}
+
+func TestMergeItemState(t *testing.T) {
+	pending := feedlib.Item{
+		Status:     feedlib.StatusPending,
+		Visibility: feedlib.VisibilityShow,
+	}
+	resolved := pending
+	resolved.Status = feedlib.StatusDone
+	hidden := pending
+	hidden.Visibility = feedlib.VisibilityHide
+	persistent := pending
+	persistent.Persistent = true
+
+	permanent := resolved
+	assert.True(t, mergeItemState(hidden, &permanent))
+	assert.Equal(t, feedlib.StatusDone, permanent.Status, "a resolved item stays done")
+	assert.Equal(t, feedlib.VisibilityHide, permanent.Visibility)
+
+	permanent = pending
+	assert.True(t, mergeItemState(resolved, &permanent))
+	assert.Equal(t, feedlib.StatusDone, permanent.Status)
+	assert.Equal(t, feedlib.VisibilityShow, permanent.Visibility)
+
+	permanent = pending
+	assert.False(t, mergeItemState(persistent, &permanent))
+	assert.False(t, permanent.Persistent, "persistence is not merged")
+
+	permanent = resolved
+	assert.False(t, mergeItemState(pending, &permanent))
+	assert.Equal(t, feedlib.StatusDone, permanent.Status)
+}
+
+func TestMergeNudgeState(t *testing.T) {
+	pending := feedlib.Nudge{
+		Status:     feedlib.StatusPending,
+		Visibility: feedlib.VisibilityShow,
+	}
+	resolved := pending
+	resolved.Status = feedlib.StatusDone
+	hidden := pending
+	hidden.Visibility = feedlib.VisibilityHide
+
+	permanent := resolved
+	assert.True(t, mergeNudgeState(hidden, &permanent))
+	assert.Equal(t, feedlib.StatusDone, permanent.Status, "a resolved nudge stays done")
+	assert.Equal(t, feedlib.VisibilityHide, permanent.Visibility)
+
+	permanent = hidden
+	assert.False(t, mergeNudgeState(pending, &permanent))
+	assert.Equal(t, feedlib.VisibilityHide, permanent.Visibility)
+}
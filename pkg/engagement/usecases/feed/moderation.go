@@ -0,0 +1,401 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// ModerationBlockedTermsEnvVarName is the environment variable that lists
+	// the comma separated terms that messages can't contain
+	ModerationBlockedTermsEnvVarName = "MODERATION_BLOCKED_TERMS"
+
+	// ModerationRedactPIIEnvVarName is the environment variable that turns
+	// the redaction of phone numbers and email addresses in messages on when
+	// it is `true`. Redaction is off by default.
+	ModerationRedactPIIEnvVarName = "MODERATION_REDACT_PII"
+
+	// redactedText replaces personal information in messages
+	redactedText = "[redacted]"
+
+	// hiddenMessageText is sent to clients in place of a hidden message
+	hiddenMessageText = "This message was hidden by a moderator."
+
+	messageHidden   = "HIDDEN"
+	messageRestored = "RESTORED"
+
+	maxReportReasonLength = 500
+	moderationQueueLimit  = 100
+)
+
+var piiPatterns = []*regexp.Regexp{
+	// email addresses
+	regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	// Kenyan mobile numbers in the local (07XX XXX XXX) or international
+	// (+254 7XX XXX XXX) formats
+	regexp.MustCompile(`(?:\+254[ -]?|\b254[ -]?|\b0)[17]\d{2}[ -]?\d{3}[ -]?\d{3}\b`),
+	// other international numbers, which are written with their `+`
+	regexp.MustCompile(`\+[1-9](?:[ -]?\d){7,14}\b`),
+}
+
+// blockedTerm is a blocked term and the pattern that finds it in text
+type blockedTerm struct {
+	term    string
+	pattern *regexp.Regexp
+}
+
+// blockedTermsCache keeps the patterns of the configured blocked terms. They
+// are compiled again only when the configured terms change.
+var blockedTermsCache struct {
+	mu         sync.Mutex
+	configured string
+	terms      []blockedTerm
+}
+
+// blockedTerms returns the configured blocked terms
+func blockedTerms() []blockedTerm {
+	configured := os.Getenv(ModerationBlockedTermsEnvVarName)
+	blockedTermsCache.mu.Lock()
+	defer blockedTermsCache.mu.Unlock()
+	if blockedTermsCache.terms != nil && blockedTermsCache.configured == configured {
+		return blockedTermsCache.terms
+	}
+
+	terms := []blockedTerm{}
+	for _, term := range strings.Split(configured, ",") {
+		term = strings.TrimSpace(term)
+		if term != "" {
+			terms = append(terms, blockedTerm{
+				term:    term,
+				pattern: regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(term) + `\b`),
+			})
+		}
+	}
+	blockedTermsCache.configured = configured
+	blockedTermsCache.terms = terms
+	return terms
+}
+
+// filterMessageText applies the moderation filter to a message's text. It
+// returns the text with personal information redacted, the number of
+// redactions and the blocked terms that the text contains.
+func filterMessageText(text string) (string, int, []string) {
+	found := []string{}
+	for _, blocked := range blockedTerms() {
+		if blocked.pattern.MatchString(text) {
+			found = append(found, blocked.term)
+		}
+	}
+
+	redactions := 0
+	if strings.EqualFold(os.Getenv(ModerationRedactPIIEnvVarName), "true") {
+		for _, pattern := range piiPatterns {
+			text = pattern.ReplaceAllStringFunc(text, func(string) string {
+				redactions++
+				return redactedText
+			})
+		}
+	}
+	return text, redactions, found
+}
+
+// moderateText filters the text of a message before it is saved. Messages
+// with blocked terms are rejected and personal information is redacted. The
+// filter's actions are audited.
+func (fe UseCaseImpl) moderateText(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	authorUID string,
+	text string,
+) (string, error) {
+	filtered, redactions, blocked := filterMessageText(text)
+
+	entry := domain.ModerationAuditEntry{
+		UID:       uid,
+		Flavour:   flavour,
+		ItemID:    itemID,
+		MessageID: messageID,
+		ActorUID:  authorUID,
+	}
+	switch {
+	case len(blocked) > 0:
+		entry.Action = domain.ModerationActionBlock
+		entry.Reason = fmt.Sprintf("blocked terms: %s", strings.Join(blocked, ", "))
+		fe.auditFilter(ctx, entry)
+		return "", fmt.Errorf("the message contains blocked terms")
+	case redactions > 0:
+		entry.Action = domain.ModerationActionRedact
+		entry.Reason = fmt.Sprintf("%d redaction(s)", redactions)
+		fe.auditFilter(ctx, entry)
+	}
+	return filtered, nil
+}
+
+// auditFilter records an action of the automatic filter. The filter's
+// decision stands even when it can't be recorded.
+func (fe UseCaseImpl) auditFilter(
+	ctx context.Context,
+	entry domain.ModerationAuditEntry,
+) {
+	entry.ID = ksuid.New().String()
+	entry.Timestamp = time.Now()
+	if err := fe.infrastructure.SaveModerationAuditEntry(ctx, entry); err != nil {
+		log.Printf("unable to audit moderation filter: %v", err)
+	}
+}
+
+// ReportMessage lets a user report a message in an item's conversation for
+// staff to review
+func (fe UseCaseImpl) ReportMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	reportedByUID string,
+	reason string,
+) (*domain.ModerationCase, error) {
+	ctx, span := tracer.Start(ctx, "ReportMessage")
+	defer span.End()
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("a reason for the report is required")
+	}
+	if utf8.RuneCountInString(reason) > maxReportReasonLength {
+		return nil, fmt.Errorf(
+			"the reason should not be longer than %d characters",
+			maxReportReasonLength,
+		)
+	}
+
+	message, err := fe.findThreadMessage(ctx, uid, flavour, itemID, messageID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+
+	now := time.Now()
+	moderationCase, err := fe.infrastructure.ReportMessage(
+		ctx,
+		domain.ModerationCase{
+			UID:         uid,
+			Flavour:     flavour,
+			ItemID:      itemID,
+			MessageID:   messageID,
+			Text:        message.Text,
+			PostedByUID: message.PostedByUID,
+			Status:      domain.ModerationStatusPending,
+			Reports:     []domain.MessageReport{},
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		},
+		domain.MessageReport{
+			ReportedByUID: reportedByUID,
+			Reason:        reason,
+			ReportedAt:    now,
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to report message: %w", err)
+	}
+	return moderationCase, nil
+}
+
+// ModerationQueue returns the reported messages with a status, the earliest
+// reported first. It defaults to the messages that are waiting for review.
+func (fe UseCaseImpl) ModerationQueue(
+	ctx context.Context,
+	status domain.ModerationStatus,
+) ([]domain.ModerationCase, error) {
+	ctx, span := tracer.Start(ctx, "ModerationQueue")
+	defer span.End()
+
+	if status == "" {
+		status = domain.ModerationStatusPending
+	}
+	if !status.IsValid() {
+		return nil, fmt.Errorf("%s is not a valid moderation status", status)
+	}
+
+	cases, err := fe.infrastructure.GetModerationCases(ctx, status, moderationQueueLimit)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get moderation queue: %w", err)
+	}
+	return cases, nil
+}
+
+// ModerateMessage records a moderator's decision on a reported message. The
+// message is hidden from, or restored to, the item's conversation and the
+// decision is audited.
+func (fe UseCaseImpl) ModerateMessage(
+	ctx context.Context,
+	caseID string,
+	action domain.ModerationAction,
+	moderatorUID string,
+	reason string,
+) (*domain.ModerationCase, error) {
+	ctx, span := tracer.Start(ctx, "ModerateMessage")
+	defer span.End()
+
+	if !action.IsDecision() {
+		return nil, fmt.Errorf("%s is not a moderation decision", action)
+	}
+	if moderatorUID == "" {
+		return nil, fmt.Errorf("the moderator is required")
+	}
+
+	moderationCase, err := fe.infrastructure.GetModerationCase(ctx, caseID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get moderation case: %w", err)
+	}
+	if moderationCase == nil {
+		return nil, fmt.Errorf("no moderation case with ID %s", caseID)
+	}
+	if err := checkModerationDecision(moderationCase.Status, action); err != nil {
+		return nil, err
+	}
+
+	if action != domain.ModerationActionDismiss {
+		err = fe.infrastructure.SetMessageHidden(
+			ctx,
+			moderationCase.UID,
+			moderationCase.Flavour,
+			moderationCase.ItemID,
+			moderationCase.MessageID,
+			action == domain.ModerationActionHide,
+		)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to moderate message: %w", err)
+		}
+	}
+
+	now := time.Now()
+	moderationCase.Status = action.Status()
+	moderationCase.DecidedByUID = moderatorUID
+	moderationCase.DecidedAt = &now
+	moderationCase.UpdatedAt = now
+	err = fe.infrastructure.UpdateModerationCase(ctx, *moderationCase)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to update moderation case: %w", err)
+	}
+
+	err = fe.infrastructure.SaveModerationAuditEntry(ctx, domain.ModerationAuditEntry{
+		ID:        ksuid.New().String(),
+		CaseID:    moderationCase.ID,
+		UID:       moderationCase.UID,
+		Flavour:   moderationCase.Flavour,
+		ItemID:    moderationCase.ItemID,
+		MessageID: moderationCase.MessageID,
+		Action:    action,
+		ActorUID:  moderatorUID,
+		Reason:    strings.TrimSpace(reason),
+		Timestamp: now,
+	})
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to audit moderation decision: %w", err)
+	}
+
+	switch action {
+	case domain.ModerationActionHide:
+		err = fe.notifyMessageUpdate(
+			ctx,
+			moderationCase.UID,
+			moderationCase.Flavour,
+			moderationCase.ItemID,
+			&domain.ThreadMessage{
+				ID:          moderationCase.MessageID,
+				Text:        hiddenMessageText,
+				PostedByUID: moderationCase.PostedByUID,
+				Timestamp:   now,
+			},
+			messageHidden,
+		)
+	case domain.ModerationActionRestore:
+		var message *domain.ThreadMessage
+		message, err = fe.findThreadMessage(
+			ctx,
+			moderationCase.UID,
+			moderationCase.Flavour,
+			moderationCase.ItemID,
+			moderationCase.MessageID,
+		)
+		if err == nil {
+			err = fe.notifyMessageUpdate(
+				ctx,
+				moderationCase.UID,
+				moderationCase.Flavour,
+				moderationCase.ItemID,
+				message,
+				messageRestored,
+			)
+		}
+	}
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+
+	return moderationCase, nil
+}
+
+// ModerationAudit returns the decisions made on a reported message, oldest
+// first
+func (fe UseCaseImpl) ModerationAudit(
+	ctx context.Context,
+	caseID string,
+) ([]domain.ModerationAuditEntry, error) {
+	ctx, span := tracer.Start(ctx, "ModerationAudit")
+	defer span.End()
+
+	entries, err := fe.infrastructure.GetModerationAudit(ctx, caseID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get moderation audit: %w", err)
+	}
+	return entries, nil
+}
+
+// checkModerationDecision ensures that a decision makes sense for a reported
+// message in its current state
+func checkModerationDecision(
+	status domain.ModerationStatus,
+	action domain.ModerationAction,
+) error {
+	switch action {
+	case domain.ModerationActionHide:
+		if status == domain.ModerationStatusHidden {
+			return fmt.Errorf("the message is already hidden")
+		}
+	case domain.ModerationActionRestore:
+		if status != domain.ModerationStatusHidden {
+			return fmt.Errorf("only hidden messages can be restored")
+		}
+	case domain.ModerationActionDismiss:
+		if status != domain.ModerationStatusPending {
+			return fmt.Errorf("only reports that are waiting for review can be dismissed")
+		}
+	}
+	return nil
+}
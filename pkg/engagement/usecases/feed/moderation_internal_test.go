@@ -0,0 +1,227 @@
+package feed
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterMessageText(t *testing.T) {
+	initialTerms := os.Getenv(ModerationBlockedTermsEnvVarName)
+	initialRedact := os.Getenv(ModerationRedactPIIEnvVarName)
+	defer func() {
+		os.Setenv(ModerationBlockedTermsEnvVarName, initialTerms)
+		os.Setenv(ModerationRedactPIIEnvVarName, initialRedact)
+	}()
+	os.Setenv(ModerationBlockedTermsEnvVarName, "scam, buy now")
+
+	tests := []struct {
+		name           string
+		text           string
+		redactPII      string
+		wantText       string
+		wantRedactions int
+		wantBlocked    []string
+	}{
+		{
+			name:        "clean message",
+			text:        "How are you feeling today?",
+			wantText:    "How are you feeling today?",
+			wantBlocked: []string{},
+		},
+		{
+			name:        "blocked terms are matched as words, ignoring case",
+			text:        "This is a SCAM, buy now! Scamper off.",
+			wantText:    "This is a SCAM, buy now! Scamper off.",
+			wantBlocked: []string{"scam", "buy now"},
+		},
+		{
+			name:           "personal information is redacted",
+			text:           "Call me on +254 712 345678 or mail jane@example.com",
+			redactPII:      "true",
+			wantText:       "Call me on [redacted] or mail [redacted]",
+			wantRedactions: 2,
+			wantBlocked:    []string{},
+		},
+		{
+			name:           "phone numbers in other formats are redacted",
+			text:           "Try 0712-345-678, 254112345678 or +44 20 7946 0958",
+			redactPII:      "true",
+			wantText:       "Try [redacted], [redacted] or [redacted]",
+			wantRedactions: 3,
+			wantBlocked:    []string{},
+		},
+		{
+			name:        "other numbers are not taken for phone numbers",
+			text:        "Take 2 tablets, ID 12345678, invoice 20231105 for KES 1500000",
+			redactPII:   "true",
+			wantText:    "Take 2 tablets, ID 12345678, invoice 20231105 for KES 1500000",
+			wantBlocked: []string{},
+		},
+		{
+			name:        "redaction is off by default",
+			text:        "Call me on 0712345678",
+			wantText:    "Call me on 0712345678",
+			wantBlocked: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv(ModerationRedactPIIEnvVarName, tt.redactPII)
+			text, redactions, blocked := filterMessageText(tt.text)
+			assert.Equal(t, tt.wantText, text)
+			assert.Equal(t, tt.wantRedactions, redactions)
+			assert.Equal(t, tt.wantBlocked, blocked)
+		})
+	}
+
+	// the terms are compiled again when they change
+	os.Setenv(ModerationBlockedTermsEnvVarName, "fraud")
+	_, _, blocked := filterMessageText("This is fraud, not a scam")
+	assert.Equal(t, []string{"fraud"}, blocked)
+}
+
+func TestUseCaseImpl_ModerateText_AuditsMessageID(t *testing.T) {
+	initialTerms := os.Getenv(ModerationBlockedTermsEnvVarName)
+	defer os.Setenv(ModerationBlockedTermsEnvVarName, initialTerms)
+	os.Setenv(ModerationBlockedTermsEnvVarName, "scam")
+
+	audit := []domain.ModerationAuditEntry{}
+	repository := &mock.FakeEngagementRepository{
+		SaveModerationAuditEntryFn: func(
+			ctx context.Context,
+			entry domain.ModerationAuditEntry,
+		) error {
+			audit = append(audit, entry)
+			return nil
+		},
+	}
+	fe := NewFeed(infrastructure.Interactor{Repository: repository})
+
+	_, err := fe.moderateText(
+		context.Background(),
+		"uid",
+		feedlib.FlavourConsumer,
+		"item",
+		"message",
+		"author",
+		"This is a scam",
+	)
+	assert.NotNil(t, err)
+	assert.Len(t, audit, 1)
+	assert.Equal(t, "message", audit[0].MessageID)
+	assert.Equal(t, domain.ModerationActionBlock, audit[0].Action)
+}
+
+func TestCheckModerationDecision(t *testing.T) {
+	tests := []struct {
+		status  domain.ModerationStatus
+		action  domain.ModerationAction
+		wantErr bool
+	}{
+		{domain.ModerationStatusPending, domain.ModerationActionHide, false},
+		{domain.ModerationStatusPending, domain.ModerationActionDismiss, false},
+		{domain.ModerationStatusPending, domain.ModerationActionRestore, true},
+		{domain.ModerationStatusHidden, domain.ModerationActionHide, true},
+		{domain.ModerationStatusHidden, domain.ModerationActionRestore, false},
+		{domain.ModerationStatusHidden, domain.ModerationActionDismiss, true},
+		{domain.ModerationStatusDismissed, domain.ModerationActionHide, false},
+		{domain.ModerationStatusDismissed, domain.ModerationActionDismiss, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status)+" "+string(tt.action), func(t *testing.T) {
+			err := checkModerationDecision(tt.status, tt.action)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestUseCaseImpl_Moderation(t *testing.T) {
+	ctx := context.Background()
+	audit := []domain.ModerationAuditEntry{}
+	var saved *domain.ModerationCase
+	repository := &mock.FakeEngagementRepository{
+		GetThreadMessagesFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			itemID string,
+		) ([]domain.ThreadMessage, error) {
+			return []domain.ThreadMessage{
+				{ID: "message", Text: "Buy my pills", PostedByUID: "author"},
+			}, nil
+		},
+		ReportMessageFn: func(
+			ctx context.Context,
+			moderationCase domain.ModerationCase,
+			report domain.MessageReport,
+		) (*domain.ModerationCase, error) {
+			moderationCase.ID = "case"
+			moderationCase.Reports = append(moderationCase.Reports, report)
+			saved = &moderationCase
+			return saved, nil
+		},
+		GetModerationCaseFn: func(
+			ctx context.Context,
+			caseID string,
+		) (*domain.ModerationCase, error) {
+			if saved == nil || caseID != saved.ID {
+				return nil, nil
+			}
+			moderationCase := *saved
+			return &moderationCase, nil
+		},
+		UpdateModerationCaseFn: func(
+			ctx context.Context,
+			moderationCase domain.ModerationCase,
+		) error {
+			saved = &moderationCase
+			return nil
+		},
+		SaveModerationAuditEntryFn: func(
+			ctx context.Context,
+			entry domain.ModerationAuditEntry,
+		) error {
+			audit = append(audit, entry)
+			return nil
+		},
+	}
+	fe := NewFeed(infrastructure.Interactor{Repository: repository})
+
+	_, err := fe.ReportMessage(ctx, "uid", feedlib.FlavourConsumer, "item", "message", "reporter", " ")
+	assert.NotNil(t, err, "a reason is required")
+
+	_, err = fe.ReportMessage(ctx, "uid", feedlib.FlavourConsumer, "item", "unknown", "reporter", "spam")
+	assert.NotNil(t, err)
+
+	reported, err := fe.ReportMessage(ctx, "uid", feedlib.FlavourConsumer, "item", "message", "reporter", "spam")
+	assert.Nil(t, err)
+	assert.Equal(t, domain.ModerationStatusPending, reported.Status)
+	assert.Equal(t, "Buy my pills", reported.Text)
+	assert.Len(t, reported.Reports, 1)
+
+	_, err = fe.ModerateMessage(ctx, "case", domain.ModerationActionBlock, "moderator", "")
+	assert.NotNil(t, err, "the filter's actions are not decisions")
+
+	_, err = fe.ModerateMessage(ctx, "unknown", domain.ModerationActionDismiss, "moderator", "")
+	assert.NotNil(t, err)
+
+	dismissed, err := fe.ModerateMessage(ctx, "case", domain.ModerationActionDismiss, "moderator", "not spam")
+	assert.Nil(t, err)
+	assert.Equal(t, domain.ModerationStatusDismissed, dismissed.Status)
+	assert.Equal(t, "moderator", dismissed.DecidedByUID)
+	assert.Len(t, audit, 1)
+	assert.Equal(t, "case", audit[0].CaseID)
+	assert.Equal(t, domain.ModerationActionDismiss, audit[0].Action)
+	assert.Equal(t, "not spam", audit[0].Reason)
+
+	_, err = fe.ModerateMessage(ctx, "case", domain.ModerationActionDismiss, "moderator", "")
+	assert.NotNil(t, err, "already dismissed")
+	assert.Len(t, audit, 1)
+}
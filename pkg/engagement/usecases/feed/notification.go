@@ -3,18 +3,26 @@ package feed
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/savannahghi/converterandformatter"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/fcm"
 	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/idempotency"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/preferences"
 	"github.com/savannahghi/feedlib"
 	"github.com/savannahghi/firebasetools"
 
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
 	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/i18n"
 	"github.com/savannahghi/pubsubtools"
 )
 
@@ -27,6 +35,8 @@ const (
 	itemShowSender      = "ITEM_SHOW"
 	itemPinSender       = "ITEM_PIN"
 	itemUnpinSender     = "ITEM_UNPIN"
+	itemExpireSender    = "ITEM_EXPIRED"
+	itemDueSoonSender   = "ITEM_DUE_SOON"
 
 	nudgePublishSender   = "NUDGE_PUBLISHED"
 	nudgeDeleteSender    = "NUDGE_DELETED"
@@ -34,6 +44,17 @@ const (
 	nudgeUnresolveSender = "NUDGE_UNRESOLVED"
 	nudgeShowSender      = "NUDGE_SHOW"
 	nudgeHideSender      = "NUDGE_HIDE"
+	nudgeExpireSender    = "NUDGE_EXPIRED"
+	nudgeDueSoonSender   = "NUDGE_DUE_SOON"
+
+	actionPublishSender = "ACTION_PUBLISHED"
+	actionDeleteSender  = "ACTION_DELETED"
+
+	messagePostSender   = "MESSAGE_POSTED"
+	messageDeleteSender = "MESSAGE_DELETED"
+	messageUpdateSender = "MESSAGE_UPDATED"
+
+	feedBulkUpdateSender = "FEED_BULK_UPDATED"
 
 	feedUpdate       = "FEED_UPDATE"
 	inboxCountUpdate = "INBOX_COUNT_CHANGED"
@@ -81,6 +102,16 @@ type NotificationUsecases interface {
 		m *pubsubtools.PubSubPayload,
 	) error
 
+	HandleItemExpire(
+		ctx context.Context,
+		m *pubsubtools.PubSubPayload,
+	) error
+
+	HandleItemDueSoon(
+		ctx context.Context,
+		m *pubsubtools.PubSubPayload,
+	) error
+
 	HandleNudgePublish(
 		ctx context.Context,
 		m *pubsubtools.PubSubPayload,
@@ -111,6 +142,16 @@ type NotificationUsecases interface {
 		m *pubsubtools.PubSubPayload,
 	) error
 
+	HandleNudgeExpire(
+		ctx context.Context,
+		m *pubsubtools.PubSubPayload,
+	) error
+
+	HandleNudgeDueSoon(
+		ctx context.Context,
+		m *pubsubtools.PubSubPayload,
+	) error
+
 	HandleActionPublish(
 		ctx context.Context,
 		m *pubsubtools.PubSubPayload,
@@ -131,6 +172,16 @@ type NotificationUsecases interface {
 		m *pubsubtools.PubSubPayload,
 	) error
 
+	HandleMessageUpdate(
+		ctx context.Context,
+		m *pubsubtools.PubSubPayload,
+	) error
+
+	HandleFeedBulkUpdate(
+		ctx context.Context,
+		m *pubsubtools.PubSubPayload,
+	) error
+
 	HandleIncomingEvent(
 		ctx context.Context,
 		m *pubsubtools.PubSubPayload,
@@ -155,6 +206,23 @@ type NotificationUsecases interface {
 		m *pubsubtools.PubSubPayload,
 	) error
 
+	NotifyActionUpdate(
+		ctx context.Context,
+		sender string,
+		m *pubsubtools.PubSubPayload,
+	) error
+
+	NotifyBulkUpdate(
+		ctx context.Context,
+		m *pubsubtools.PubSubPayload,
+	) error
+
+	NotifyMessageUpdate(
+		ctx context.Context,
+		sender string,
+		m *pubsubtools.PubSubPayload,
+	) error
+
 	NotifyInboxCountUpdate(
 		ctx context.Context,
 		uid string,
@@ -175,6 +243,12 @@ type NotificationUsecases interface {
 		notification *firebasetools.FirebaseSimpleNotificationInput,
 	) error
 
+	SendExpiryReminder(
+		ctx context.Context,
+		elementType domain.ElementType,
+		m *pubsubtools.PubSubPayload,
+	) error
+
 	HandleSendNotification(
 		ctx context.Context,
 		m *pubsubtools.PubSubPayload,
@@ -184,6 +258,51 @@ type NotificationUsecases interface {
 		ctx context.Context,
 		m *pubsubtools.PubSubPayload,
 	) error
+
+	ProcessDeferredNotifications(
+		ctx context.Context,
+	) (*domain.DeferralReport, error)
+
+	ProcessInboxCountPushes(
+		ctx context.Context,
+	) (*domain.InboxCountPushReport, error)
+
+	ProcessFCMRetries(
+		ctx context.Context,
+	) (*domain.FCMRetryReport, error)
+
+	FCMDeadLetters(
+		ctx context.Context,
+		limit int,
+	) ([]domain.FCMDeadLetter, error)
+
+	ReplayFCMDeadLetter(
+		ctx context.Context,
+		id string,
+	) (*domain.FCMRetryReport, error)
+
+	DigestSettings(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+	) (*domain.DigestSettings, error)
+
+	SetDigestSettings(
+		ctx context.Context,
+		uid string,
+		flavour feedlib.Flavour,
+		frequency domain.DigestFrequency,
+		channel feedlib.Channel,
+	) (*domain.DigestSettings, error)
+
+	ProcessDigests(
+		ctx context.Context,
+	) (*domain.DigestReport, error)
+
+	HandleDigestDue(
+		ctx context.Context,
+		m *pubsubtools.PubSubPayload,
+	) error
 }
 
 // HandlePubsubPayload defines the signature of a function that handles
@@ -193,12 +312,14 @@ type HandlePubsubPayload func(ctx context.Context, m *pubsubtools.PubSubPayload)
 // NotificationImpl represents the notification usecase implementation
 type NotificationImpl struct {
 	infrastructure infrastructure.Interactor
+	preferences    *preferences.ImplPreferences
 }
 
 // NewNotification initializes a notification usecase
 func NewNotification(infrastructure infrastructure.Interactor) *NotificationImpl {
 	return &NotificationImpl{
 		infrastructure: infrastructure,
+		preferences:    preferences.NewPreferences(infrastructure),
 	}
 }
 
@@ -362,6 +483,46 @@ func (n NotificationImpl) HandleItemUnpin(
 	return nil
 }
 
+// HandleItemExpire responds to item expiry messages
+func (n NotificationImpl) HandleItemExpire(
+	ctx context.Context,
+	m *pubsubtools.PubSubPayload,
+) error {
+	ctx, span := tracer.Start(ctx, "HandleItemExpire")
+	defer span.End()
+	if m == nil {
+		return fmt.Errorf("nil pub sub payload")
+	}
+
+	err := n.NotifyItemUpdate(ctx, itemExpireSender, false, m)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't notify item update over FCM: %w", err)
+	}
+
+	return nil
+}
+
+// HandleItemDueSoon responds to item "due soon" messages
+func (n NotificationImpl) HandleItemDueSoon(
+	ctx context.Context,
+	m *pubsubtools.PubSubPayload,
+) error {
+	ctx, span := tracer.Start(ctx, "HandleItemDueSoon")
+	defer span.End()
+	if m == nil {
+		return fmt.Errorf("nil pub sub payload")
+	}
+
+	err := n.SendExpiryReminder(ctx, domain.ElementTypeItem, m)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't send item expiry reminder: %w", err)
+	}
+
+	return nil
+}
+
 // HandleNudgePublish responds to nudge publish messages
 func (n NotificationImpl) HandleNudgePublish(
 	ctx context.Context,
@@ -483,35 +644,83 @@ func (n NotificationImpl) HandleNudgeShow(
 	return nil
 }
 
+// HandleNudgeExpire responds to nudge expiry messages
+func (n NotificationImpl) HandleNudgeExpire(
+	ctx context.Context,
+	m *pubsubtools.PubSubPayload,
+) error {
+	ctx, span := tracer.Start(ctx, "HandleNudgeExpire")
+	defer span.End()
+	if m == nil {
+		return fmt.Errorf("nil pub sub payload")
+	}
+
+	err := n.NotifyNudgeUpdate(ctx, nudgeExpireSender, m)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't notify nudge update over FCM: %w", err)
+	}
+
+	return nil
+}
+
+// HandleNudgeDueSoon responds to nudge "due soon" messages
+func (n NotificationImpl) HandleNudgeDueSoon(
+	ctx context.Context,
+	m *pubsubtools.PubSubPayload,
+) error {
+	ctx, span := tracer.Start(ctx, "HandleNudgeDueSoon")
+	defer span.End()
+	if m == nil {
+		return fmt.Errorf("nil pub sub payload")
+	}
+
+	err := n.SendExpiryReminder(ctx, domain.ElementTypeNudge, m)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't send nudge expiry reminder: %w", err)
+	}
+
+	return nil
+}
+
 // HandleActionPublish responds to action publish messages
 func (n NotificationImpl) HandleActionPublish(
 	ctx context.Context,
 	m *pubsubtools.PubSubPayload,
 ) error {
-	_, span := tracer.Start(ctx, "HandleActionPublish")
+	ctx, span := tracer.Start(ctx, "HandleActionPublish")
 	defer span.End()
 	if m == nil {
 		return fmt.Errorf("nil pub sub payload")
 	}
 
-	// TODO Notify action publish
+	err := n.NotifyActionUpdate(ctx, actionPublishSender, m)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't notify action update over FCM: %w", err)
+	}
 
 	return nil
 }
 
-// HandleActionDelete responds to action publish messages
+// HandleActionDelete responds to action delete messages
 func (n NotificationImpl) HandleActionDelete(
 	ctx context.Context,
 	m *pubsubtools.PubSubPayload,
 ) error {
-	_, span := tracer.Start(ctx, "HandleActionDelete")
+	ctx, span := tracer.Start(ctx, "HandleActionDelete")
 	defer span.End()
 
 	if m == nil {
 		return fmt.Errorf("nil pub sub payload")
 	}
 
-	// TODO Notify action delete
+	err := n.NotifyActionUpdate(ctx, actionDeleteSender, m)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't notify action update over FCM: %w", err)
+	}
 
 	return nil
 }
@@ -521,13 +730,17 @@ func (n NotificationImpl) HandleMessagePost(
 	ctx context.Context,
 	m *pubsubtools.PubSubPayload,
 ) error {
-	_, span := tracer.Start(ctx, "HandleMessagePost")
+	ctx, span := tracer.Start(ctx, "HandleMessagePost")
 	defer span.End()
 	if m == nil {
 		return fmt.Errorf("nil pub sub payload")
 	}
 
-	// TODO Notify the message and it's context i.e item and feed flavour
+	err := n.NotifyMessageUpdate(ctx, messagePostSender, m)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't notify message post: %w", err)
+	}
 
 	return nil
 }
@@ -537,13 +750,58 @@ func (n NotificationImpl) HandleMessageDelete(
 	ctx context.Context,
 	m *pubsubtools.PubSubPayload,
 ) error {
-	_, span := tracer.Start(ctx, "HandleMessageDelete")
+	ctx, span := tracer.Start(ctx, "HandleMessageDelete")
+	defer span.End()
+	if m == nil {
+		return fmt.Errorf("nil pub sub payload")
+	}
+
+	err := n.NotifyMessageUpdate(ctx, messageDeleteSender, m)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't notify message delete: %w", err)
+	}
+
+	return nil
+}
+
+// HandleMessageUpdate responds to message update pubsub messages i.e edits,
+// reactions and attachments
+func (n NotificationImpl) HandleMessageUpdate(
+	ctx context.Context,
+	m *pubsubtools.PubSubPayload,
+) error {
+	ctx, span := tracer.Start(ctx, "HandleMessageUpdate")
 	defer span.End()
 	if m == nil {
 		return fmt.Errorf("nil pub sub payload")
 	}
 
-	// TODO Notify the message delete and it's context i.e item and feed flavour
+	err := n.NotifyMessageUpdate(ctx, messageUpdateSender, m)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't notify message update: %w", err)
+	}
+
+	return nil
+}
+
+// HandleFeedBulkUpdate responds to bulk feed update pubsub messages
+func (n NotificationImpl) HandleFeedBulkUpdate(
+	ctx context.Context,
+	m *pubsubtools.PubSubPayload,
+) error {
+	ctx, span := tracer.Start(ctx, "HandleFeedBulkUpdate")
+	defer span.End()
+	if m == nil {
+		return fmt.Errorf("nil pub sub payload")
+	}
+
+	err := n.NotifyBulkUpdate(ctx, m)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't notify bulk update: %w", err)
+	}
 
 	return nil
 }
@@ -579,7 +837,7 @@ func (n NotificationImpl) HandleSendNotification(
 		return fmt.Errorf("nil pub sub payload")
 	}
 
-	payload := &firebasetools.SendNotificationPayload{}
+	payload := &dto.NotificationPayload{}
 	err := json.Unmarshal(m.Message.Data, payload)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
@@ -589,18 +847,32 @@ func (n NotificationImpl) HandleSendNotification(
 		)
 	}
 
-	_, err = n.infrastructure.SendNotification(
-		ctx,
-		payload.RegistrationTokens,
-		payload.Data,
-		payload.Notification,
-		payload.Android,
-		payload.Ios,
-		payload.Web,
-	)
+	payload, err = n.preferences.ScreenPushNotification(ctx, *payload)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
-		return fmt.Errorf("can't send notification: %v", err)
+		return fmt.Errorf("can't screen notification: %w", err)
+	}
+	if len(payload.RegistrationTokens) == 0 {
+		// none of the users accept the notification now
+		return nil
+	}
+
+	_, err = n.infrastructure.SendNotificationWithOptions(ctx, *payload)
+	if errors.Is(err, fcm.ErrNoValidTokens) {
+		// redelivering the message would not reach anyone either
+		log.Printf("notification not sent: %v", err)
+		return nil
+	}
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		// the failed tokens are retried in the background rather than by
+		// redelivering the message, which would reach the other tokens twice
+		retryErr := n.scheduleFCMRetry(ctx, m.Message.Data, payload.RegistrationTokens, err)
+		if retryErr != nil {
+			helpers.RecordSpanError(span, retryErr)
+			return fmt.Errorf("can't send notification: %w", err)
+		}
+		log.Printf("notification not sent to all tokens, it will be retried: %v", err)
 	}
 
 	return nil
@@ -629,23 +901,48 @@ func (n NotificationImpl) NotifyItemUpdate(
 		helpers.RecordSpanError(span, err)
 		return fmt.Errorf("can't unmarshal item from pubsub data: %w", err)
 	}
-	// include notifications for persistent items
-	var notification *firebasetools.FirebaseSimpleNotificationInput
-	iconURL := common.DefaultIconPath
-	if item.Persistent && includeNotification {
-		// also include a notification
-		notification = &firebasetools.FirebaseSimpleNotificationInput{
-			Title:    item.Tagline,
-			Body:     item.Summary,
-			ImageURL: &iconURL,
+	// persistent items are always shown as a tray notification, and the
+	// item's other notification channels are honoured when it is published
+	channels := item.NotificationChannels
+	if item.Persistent && !channelsInclude(channels, feedlib.ChannelFcm) {
+		channels = append([]feedlib.Channel{feedlib.ChannelFcm}, channels...)
+	}
+	if includeNotification && len(channels) > 0 {
+		// the notification is shown in the feed owner's language
+		language := resolveLanguage(ctx, n.infrastructure, envelope.UID, false)
+		translations, err := elementTranslations(
+			ctx,
+			n.infrastructure,
+			envelope.UID,
+			envelope.Flavour,
+			language,
+		)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return fmt.Errorf("unable to localize item notification: %w", err)
 		}
+		localized := item
+		localizeItem(&localized, translations, language)
 
-		err = n.SendNotificationViaFCM(
+		users := item.Users
+		if len(users) == 0 {
+			users = []string{envelope.UID}
+		}
+		err = n.routeNotification(
 			ctx,
-			item.Users,
+			channels,
+			users,
 			sender,
 			envelope,
-			notification,
+			channelNotification{
+				ElementType: domain.ElementTypeItem,
+				ElementID:   item.ID,
+				Category:    domain.NotificationCategoryItems,
+				Title:       localized.Tagline,
+				Body:        localized.Summary,
+				ImageURL:    common.DefaultIconPath,
+				LinkURL:     firstLinkURL(localized.Links),
+			},
 		)
 		if err != nil {
 			helpers.RecordSpanError(span, err)
@@ -653,38 +950,48 @@ func (n NotificationImpl) NotifyItemUpdate(
 		}
 	}
 
-	// TODO Send email notifications
-	// TODO For urgent (tray), consider whatsapp and sms notifications
-
 	switch sender {
 	case itemPublishSender:
-		existingLabels, err := n.infrastructure.Labels(
-			ctx,
-			envelope.UID,
-			envelope.Flavour,
-		)
-		if err != nil {
-			helpers.RecordSpanError(span, err)
-			return fmt.Errorf("can't fetch existing labels: %w", err)
-		}
-
-		if !converterandformatter.StringSliceContains(
-			existingLabels,
-			item.Label,
-		) {
-			err = n.infrastructure.SaveLabel(
+		err = idempotency.Step(ctx, n.infrastructure, labelsStep, func() error {
+			existingLabels, err := n.infrastructure.Labels(
 				ctx,
 				envelope.UID,
 				envelope.Flavour,
-				item.Label,
 			)
 			if err != nil {
-				helpers.RecordSpanError(span, err)
-				return fmt.Errorf("can't save label: %w", err)
+				return fmt.Errorf("can't fetch existing labels: %w", err)
 			}
+
+			if !converterandformatter.StringSliceContains(
+				existingLabels,
+				item.Label,
+			) {
+				err = n.infrastructure.SaveLabel(
+					ctx,
+					envelope.UID,
+					envelope.Flavour,
+					item.Label,
+				)
+				if err != nil {
+					return fmt.Errorf("can't save label: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return err
+		}
+	case itemDeleteSender, itemExpireSender:
+		// the item may have been the last one with its label
+		err = idempotency.Step(ctx, n.infrastructure, labelsStep, func() error {
+			return cleanUpLabels(ctx, n.infrastructure, envelope.UID, envelope.Flavour)
+		})
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return fmt.Errorf("can't clean up labels: %w", err)
 		}
-	case itemDeleteSender,
-		itemResolveSender,
+	case itemResolveSender,
 		itemUnresolveSender,
 		itemHideSender,
 		itemShowSender,
@@ -704,7 +1011,8 @@ func (n NotificationImpl) NotifyItemUpdate(
 	return nil
 }
 
-// UpdateInbox recalculates the inbox count and notifies the client over FCM
+// UpdateInbox recalculates the inbox count and queues a push of the new
+// count to the client over FCM
 func (n NotificationImpl) UpdateInbox(
 	ctx context.Context,
 	uid string,
@@ -712,26 +1020,26 @@ func (n NotificationImpl) UpdateInbox(
 ) error {
 	ctx, span := tracer.Start(ctx, "UpdateInbox")
 	defer span.End()
-	err := n.infrastructure.UpdateUnreadPersistentItemsCount(ctx, uid, flavour)
-	if err != nil {
-		helpers.RecordSpanError(span, err)
-		return fmt.Errorf("can't update inbox count: %w", err)
-	}
+	step := stepName(inboxStep, uid, string(flavour))
+	err := idempotency.Step(ctx, n.infrastructure, step, func() error {
+		err := n.infrastructure.UpdateUnreadPersistentItemsCount(ctx, uid, flavour)
+		if err != nil {
+			return fmt.Errorf("can't update inbox count: %w", err)
+		}
 
-	_, err = n.infrastructure.UnreadPersistentItems(ctx, uid, flavour)
+		// the count is pushed once the changes made around the same time
+		// are in, rather than on every change
+		err = n.queueInboxCountPush(ctx, uid, flavour)
+		if err != nil {
+			return fmt.Errorf("can't queue inbox count push: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
 		helpers.RecordSpanError(span, err)
-		return fmt.Errorf("can't get inbox count: %w", err)
+		return err
 	}
 
-	// The inbox has been descoped for this milestone
-	// Does not make sense to send notification updates to our users
-	// TODO: Restore after the milestone @mathenge
-	// err = n.NotifyInboxCountUpdate(ctx, uid, flavour, unread)
-	// if err != nil {
-	// 	return fmt.Errorf("can't notify inbox count: %w", err)
-	// }
-
 	return nil
 }
 
@@ -760,33 +1068,19 @@ func (n NotificationImpl) NotifyNudgeUpdate(
 		return fmt.Errorf("can't unmarshal nudge from pubsub data: %w", err)
 	}
 
-	links := nudge.Links
 	var imageURL string
-	for _, link := range links {
+	for _, link := range nudge.Links {
 		imageURL = link.Thumbnail
 	}
 
-	var notification *firebasetools.FirebaseSimpleNotificationInput
-
 	switch sender {
-	case nudgePublishSender:
-		notification = &firebasetools.FirebaseSimpleNotificationInput{
-			Title:    nudge.Title,
-			Body:     nudge.NotificationBody.PublishMessage,
-			ImageURL: &imageURL,
-		}
-
-	case nudgeResolveSender:
-		notification = &firebasetools.FirebaseSimpleNotificationInput{
-			Title:    nudge.Title,
-			Body:     nudge.NotificationBody.ResolveMessage,
-			ImageURL: &imageURL,
-		}
-
+	case nudgePublishSender, nudgeResolveSender:
+		// notified below
 	case nudgeDeleteSender,
 		nudgeUnresolveSender,
 		nudgeShowSender,
-		nudgeHideSender:
+		nudgeHideSender,
+		nudgeExpireSender:
 		// Do nothing..our scope for nudges does not contain these
 		return nil
 
@@ -794,12 +1088,49 @@ func (n NotificationImpl) NotifyNudgeUpdate(
 		return fmt.Errorf("unexpected nudge sender: %s", sender)
 	}
 
-	err = n.SendNotificationViaFCM(
+	language := resolveLanguage(ctx, n.infrastructure, envelope.UID, false)
+	translations, err := elementTranslations(
 		ctx,
-		nudge.Users,
+		n.infrastructure,
+		envelope.UID,
+		envelope.Flavour,
+		language,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to localize nudge notification: %w", err)
+	}
+	localized := nudge
+	localizeNudge(&localized, translations, language)
+	body := localized.NotificationBody.PublishMessage
+	if sender == nudgeResolveSender {
+		body = localized.NotificationBody.ResolveMessage
+	}
+
+	// nudges are always shown as a tray notification
+	channels := nudge.NotificationChannels
+	if !channelsInclude(channels, feedlib.ChannelFcm) {
+		channels = append([]feedlib.Channel{feedlib.ChannelFcm}, channels...)
+	}
+	users := nudge.Users
+	if len(users) == 0 {
+		users = []string{envelope.UID}
+	}
+	err = n.routeNotification(
+		ctx,
+		channels,
+		users,
 		sender,
 		envelope,
-		notification,
+		channelNotification{
+			ElementType: domain.ElementTypeNudge,
+			ElementID:   nudge.ID,
+			Category:    domain.NotificationCategoryNudges,
+			Title:       localized.Title,
+			Body:        body,
+			ImageURL:    imageURL,
+			LinkURL:     firstLinkURL(localized.Links),
+		},
 	)
 	if err != nil {
 		helpers.RecordSpanError(span, err)
@@ -809,6 +1140,304 @@ func (n NotificationImpl) NotifyNudgeUpdate(
 	return nil
 }
 
+// NotifyActionUpdate sends a published or deleted action to the feed owner's
+// devices so that their action bar is refreshed. No tray notification is
+// shown.
+func (n NotificationImpl) NotifyActionUpdate(
+	ctx context.Context,
+	sender string,
+	m *pubsubtools.PubSubPayload,
+) error {
+	ctx, span := tracer.Start(ctx, "NotifyActionUpdate")
+	defer span.End()
+	var envelope dto.NotificationEnvelope
+	err := json.Unmarshal(m.Message.Data, &envelope)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"can't unmarshal notification envelope from pubsub data: %w", err)
+	}
+
+	switch sender {
+	case actionPublishSender, actionDeleteSender:
+		// both refresh the action bar
+	default:
+		return fmt.Errorf("unexpected action sender: %s", sender)
+	}
+
+	var action feedlib.Action
+	err = json.Unmarshal(envelope.Payload, &action)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't unmarshal action from pubsub data: %w", err)
+	}
+
+	err = n.sendDataViaFCM(ctx, []string{envelope.UID}, sender, envelope)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to notify action %s: %w", action.ID, err)
+	}
+
+	return nil
+}
+
+// NotifyBulkUpdate sends the outcome of a bulk operation to the feed owner,
+// and to the users of the changed elements, as one silent update. Labels are
+// cleaned up and the inbox is recounted once for the whole operation.
+func (n NotificationImpl) NotifyBulkUpdate(
+	ctx context.Context,
+	m *pubsubtools.PubSubPayload,
+) error {
+	ctx, span := tracer.Start(ctx, "NotifyBulkUpdate")
+	defer span.End()
+	var envelope dto.NotificationEnvelope
+	err := json.Unmarshal(m.Message.Data, &envelope)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"can't unmarshal notification envelope from pubsub data: %w", err)
+	}
+
+	var result domain.BulkResult
+	err = result.ValidateAndUnmarshal(envelope.Payload)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't unmarshal bulk result from pubsub data: %w", err)
+	}
+
+	uids := []string{envelope.UID}
+	if users, ok := envelope.Metadata["users"].([]interface{}); ok {
+		for _, user := range users {
+			if uid, ok := user.(string); ok {
+				uids = append(uids, uid)
+			}
+		}
+	}
+	err = n.sendDataViaFCM(ctx, uniqueIDs(uids), feedBulkUpdateSender, envelope)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to notify bulk update: %w", err)
+	}
+
+	if result.Operation == domain.BulkOperationDelete {
+		// the deleted items may have been the last ones with their labels
+		err = idempotency.Step(ctx, n.infrastructure, labelsStep, func() error {
+			return cleanUpLabels(ctx, n.infrastructure, envelope.UID, envelope.Flavour)
+		})
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return fmt.Errorf("can't clean up labels: %w", err)
+		}
+	}
+
+	err = n.UpdateInbox(ctx, envelope.UID, envelope.Flavour)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to update inbox count: %w", err)
+	}
+
+	return nil
+}
+
+// NotifyMessageUpdate tells the participants of an item's conversation that
+// a message was posted to, changed or deleted from it.
+//
+// New messages are sent as push notifications, and over the item's other
+// notification channels, to every participant except the author. Participants
+// that muted the thread only get a silent update unless they are mentioned.
+// Participants mentioned as `@<uid>` get a mention notification instead.
+// Mentions of users who are not the item's users or part of its thread are
+// ignored so that messages only reach the people the item is shared with.
+// Changed and deleted messages are sent as silent updates.
+func (n NotificationImpl) NotifyMessageUpdate(
+	ctx context.Context,
+	sender string,
+	m *pubsubtools.PubSubPayload,
+) error {
+	ctx, span := tracer.Start(ctx, "NotifyMessageUpdate")
+	defer span.End()
+	var envelope dto.NotificationEnvelope
+	err := json.Unmarshal(m.Message.Data, &envelope)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"can't unmarshal notification envelope from pubsub data: %w", err)
+	}
+
+	var message feedlib.Message
+	err = json.Unmarshal(envelope.Payload, &message)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't unmarshal message from pubsub data: %w", err)
+	}
+
+	itemID, _ := envelope.Metadata["itemID"].(string)
+	if itemID == "" {
+		return fmt.Errorf("no item ID in the message's metadata")
+	}
+	item, err := n.infrastructure.GetFeedItem(
+		ctx,
+		envelope.UID,
+		envelope.Flavour,
+		itemID,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to get the message's item: %w", err)
+	}
+	if item == nil {
+		return nil // the item, and its thread, has since been deleted
+	}
+
+	participants := excludeUIDs(
+		threadParticipants(envelope.UID, item),
+		message.PostedByUID,
+	)
+
+	switch sender {
+	case messagePostSender:
+		// notified below
+	case messageDeleteSender, messageUpdateSender:
+		err = n.sendDataViaFCM(ctx, participants, sender, envelope)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return fmt.Errorf("unable to notify message change: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unexpected message sender: %s", sender)
+	}
+
+	muted, err := n.infrastructure.GetThreadMutes(
+		ctx,
+		envelope.UID,
+		envelope.Flavour,
+		itemID,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to get thread mutes: %w", err)
+	}
+	mentioned := includeUIDs(parseMentions(message.Text), participants...)
+	notified := excludeUIDs(participants, append(muted, mentioned...)...)
+	silenced := excludeUIDs(participants, append(notified, mentioned...)...)
+
+	language := resolveLanguage(ctx, n.infrastructure, envelope.UID, false)
+	translations, err := elementTranslations(
+		ctx,
+		n.infrastructure,
+		envelope.UID,
+		envelope.Flavour,
+		language,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to localize message notification: %w", err)
+	}
+	localizeItem(item, translations, language)
+
+	author := message.PostedByName
+	if author == "" {
+		author = message.PostedByUID
+	}
+	channels := item.NotificationChannels
+	if !channelsInclude(channels, feedlib.ChannelFcm) {
+		channels = append([]feedlib.Channel{feedlib.ChannelFcm}, channels...)
+	}
+	recipients := []struct {
+		uids  []string
+		title string
+	}{
+		{
+			uids:  notified,
+			title: i18n.Translate(language, i18n.MessagePostedTitle, author, item.Tagline),
+		},
+		{
+			uids:  mentioned,
+			title: i18n.Translate(language, i18n.MessageMentionTitle, author, item.Tagline),
+		},
+	}
+	for _, recipient := range recipients {
+		if len(recipient.uids) == 0 {
+			continue
+		}
+		err = n.routeNotification(
+			ctx,
+			channels,
+			recipient.uids,
+			sender,
+			envelope,
+			channelNotification{
+				ElementType: domain.ElementTypeItem,
+				ElementID:   item.ID,
+				Category:    domain.NotificationCategoryMessages,
+				Title:       recipient.title,
+				Body:        message.Text,
+				// messages can hold health information, which is only
+				// read in the app
+				OffAppBody: i18n.Translate(language, i18n.MessagePostedBody),
+			},
+		)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return fmt.Errorf("unable to notify message post: %w", err)
+		}
+	}
+
+	// muted participants still need the message to keep their thread current
+	err = n.sendDataViaFCM(ctx, silenced, sender, envelope)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to update muted participants: %w", err)
+	}
+
+	return nil
+}
+
+// sendDataViaFCM sends an element to the users' devices as a data message,
+// without a tray notification
+func (n NotificationImpl) sendDataViaFCM(
+	ctx context.Context,
+	uids []string,
+	sender string,
+	pl dto.NotificationEnvelope,
+) error {
+	ctx, span := tracer.Start(ctx, "sendDataViaFCM")
+	defer span.End()
+	if len(uids) == 0 {
+		return nil
+	}
+
+	tokens, err := n.GetUserTokens(ctx, uids)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't get user tokens: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	marshalled, err := json.Marshal(pl)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't marshal element for FCM: %w", err)
+	}
+
+	step := stepName(dataPushStep, sender, strings.Join(uids, ","))
+	err = idempotency.Step(ctx, n.infrastructure, step, func() error {
+		return n.infrastructure.Push(ctx, sender, firebasetools.SendNotificationPayload{
+			RegistrationTokens: tokens,
+			Data: map[string]string{
+				sender: string(marshalled),
+			},
+		})
+	})
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("can't send element over FCM: %w", err)
+	}
+	return nil
+}
+
 // NotifyInboxCountUpdate sends a message notifying of an update to inbox
 // item counts.
 func (n NotificationImpl) NotifyInboxCountUpdate(
@@ -819,19 +1448,12 @@ func (n NotificationImpl) NotifyInboxCountUpdate(
 ) error {
 	ctx, span := tracer.Start(ctx, "NotifyInboxCountUpdate")
 	defer span.End()
-	notificationEnvelope := dto.NotificationEnvelope{
-		UID:     uid,
-		Flavour: flavour,
-		Payload: []byte(fmt.Sprintf("%d", count)),
-		Metadata: map[string]interface{}{
-			"sender": inboxCountUpdate,
-			"count":  count,
-		},
-	}
+	notificationEnvelope := inboxCountEnvelope(uid, flavour, count)
 
+	language := resolveLanguage(ctx, n.infrastructure, uid, false)
 	notification := &firebasetools.FirebaseSimpleNotificationInput{
-		Title: "Be.Well Inbox",
-		Body:  fmt.Sprintf("You have %v unread notification(s).", count),
+		Title: i18n.Translate(language, i18n.InboxNotificationTitle),
+		Body:  i18n.Translate(language, i18n.InboxNotificationBody, count),
 	}
 
 	notifyUIDs := []string{uid}
@@ -885,6 +1507,23 @@ func (n NotificationImpl) SendNotificationViaFCM(
 		helpers.RecordSpanError(span, err)
 		return fmt.Errorf("can't get user tokens: %w", err)
 	}
+	step := stepName(pushStep, sender, strings.Join(uids, ","))
+	return idempotency.Step(ctx, n.infrastructure, step, func() error {
+		return n.pushNotification(ctx, tokens, sender, pl, notification)
+	})
+}
+
+// pushNotification sends an FCM notification, with the envelope as its data,
+// to device tokens
+func (n NotificationImpl) pushNotification(
+	ctx context.Context,
+	tokens []string,
+	sender string,
+	pl dto.NotificationEnvelope,
+	notification *firebasetools.FirebaseSimpleNotificationInput,
+) error {
+	ctx, span := tracer.Start(ctx, "pushNotification")
+	defer span.End()
 	if len(tokens) == 0 {
 		return nil
 	}
@@ -947,3 +1586,121 @@ func (n NotificationImpl) SendNotificationEmail(
 	}
 	return nil
 }
+
+// SendExpiryReminder tells the users of an item or nudge that it expires
+// soon. The reminder goes out through the element's notification channels,
+// or as a push notification when the element does not name any.
+func (n NotificationImpl) SendExpiryReminder(
+	ctx context.Context,
+	elementType domain.ElementType,
+	m *pubsubtools.PubSubPayload,
+) error {
+	ctx, span := tracer.Start(ctx, "SendExpiryReminder")
+	defer span.End()
+	var envelope dto.NotificationEnvelope
+	err := json.Unmarshal(m.Message.Data, &envelope)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf(
+			"can't unmarshal notification envelope from pubsub data: %w", err)
+	}
+
+	language := resolveLanguage(ctx, n.infrastructure, envelope.UID, false)
+	translations, err := elementTranslations(
+		ctx,
+		n.infrastructure,
+		envelope.UID,
+		envelope.Flavour,
+		language,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to localize expiry reminder: %w", err)
+	}
+
+	var sender, title, elementID, linkURL string
+	var expiry time.Time
+	var users []string
+	var channels []feedlib.Channel
+	switch elementType {
+	case domain.ElementTypeItem:
+		var item feedlib.Item
+		err = json.Unmarshal(envelope.Payload, &item)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return fmt.Errorf("can't unmarshal item from pubsub data: %w", err)
+		}
+		localizeItem(&item, translations, language)
+		sender, title, expiry = itemDueSoonSender, item.Tagline, item.Expiry
+		users, channels = item.Users, item.NotificationChannels
+		elementID, linkURL = item.ID, firstLinkURL(item.Links)
+	case domain.ElementTypeNudge:
+		var nudge feedlib.Nudge
+		err = json.Unmarshal(envelope.Payload, &nudge)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return fmt.Errorf("can't unmarshal nudge from pubsub data: %w", err)
+		}
+		localizeNudge(&nudge, translations, language)
+		sender, title, expiry = nudgeDueSoonSender, nudge.Title, nudge.Expiry
+		users, channels = nudge.Users, nudge.NotificationChannels
+		elementID, linkURL = nudge.ID, firstLinkURL(nudge.Links)
+	default:
+		return fmt.Errorf("%s elements do not expire", elementType)
+	}
+	if len(users) == 0 {
+		users = []string{envelope.UID}
+	}
+	if len(channels) == 0 {
+		channels = []feedlib.Channel{feedlib.ChannelFcm}
+	}
+
+	subject := i18n.Translate(language, i18n.ExpiryReminderTitle, title)
+	body := i18n.Translate(
+		language,
+		i18n.ExpiryReminderBody,
+		title,
+		expiry.Format(time.RFC1123),
+	)
+	err = n.routeNotification(
+		ctx,
+		channels,
+		users,
+		sender,
+		envelope,
+		channelNotification{
+			ElementType: elementType,
+			ElementID:   elementID,
+			Category:    domain.NotificationCategoryReminders,
+			Title:       subject,
+			Body:        body,
+			LinkURL:     linkURL,
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to send expiry reminder: %w", err)
+	}
+
+	return nil
+}
+
+// channelsInclude reports whether a channel is in a list of channels
+func channelsInclude(channels []feedlib.Channel, channel feedlib.Channel) bool {
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// flattenContacts merges the per-user contacts returned by the profile
+// service into one list
+func flattenContacts(contacts map[string][]string) []string {
+	flat := []string{}
+	for _, userContacts := range contacts {
+		flat = append(flat, userContacts...)
+	}
+	return flat
+}
@@ -0,0 +1,315 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/fcm"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// fcmRetryBaseDelay is the wait before the first retry. It doubles with
+	// every attempt, up to fcmRetryMaxDelay.
+	fcmRetryBaseDelay = time.Minute
+	fcmRetryMaxDelay  = time.Hour
+
+	// fcmMaxAttempts is the number of sends, including the first one, after
+	// which a message is moved to the dead letters
+	fcmMaxAttempts = 6
+
+	// fcmRetryBatchSize is the most FCM retries that are attempted in one run
+	// of the processor. The rest are attempted on the next run.
+	fcmRetryBatchSize = 500
+
+	// defaultFCMDeadLettersLimit is the number of dead letters listed when no
+	// limit is given
+	defaultFCMDeadLettersLimit = 100
+)
+
+var (
+	jitterMu sync.Mutex
+	jitter   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// fcmRetryDelay is how long to wait after a failed attempt before the next
+// one. The exponential backoff is jittered so that messages that failed
+// together are not all retried at the same moment.
+func fcmRetryDelay(attempts int) time.Duration {
+	delay := fcmRetryMaxDelay
+	if attempts > 0 && attempts < 32 {
+		if backoff := fcmRetryBaseDelay << (attempts - 1); backoff < delay {
+			delay = backoff
+		}
+	}
+
+	jitterMu.Lock()
+	delay = delay/2 + time.Duration(jitter.Int63n(int64(delay/2)+1))
+	jitterMu.Unlock()
+	return delay
+}
+
+// failedTokens sorts the tokens that a send failed for into those that may
+// be reached by sending again and those that will not. The failure applies
+// to all the tokens when it is not broken down by token.
+func failedTokens(tokens []string, err error) ([]string, []string) {
+	retryable, permanent := []string{}, []string{}
+	var sendErr *fcm.SendError
+	if errors.As(err, &sendErr) {
+		for _, failure := range sendErr.Failures {
+			if fcm.Retryable(failure.Err) {
+				retryable = append(retryable, failure.Token)
+			} else {
+				permanent = append(permanent, failure.Token)
+			}
+		}
+		return retryable, permanent
+	}
+	if fcm.Retryable(err) {
+		return append(retryable, tokens...), permanent
+	}
+	return retryable, append(permanent, tokens...)
+}
+
+// scheduleFCMRetry records a failed FCM message so that it is sent again to
+// the tokens that it did not reach. The tokens that failed permanently are
+// dead lettered straight away.
+func (n NotificationImpl) scheduleFCMRetry(
+	ctx context.Context,
+	payload []byte,
+	tokens []string,
+	sendErr error,
+) error {
+	now := time.Now()
+	retryable, permanent := failedTokens(tokens, sendErr)
+	if len(permanent) > 0 {
+		err := n.infrastructure.SaveFCMDeadLetter(ctx, domain.FCMDeadLetter{
+			ID:        ksuid.New().String(),
+			Tokens:    permanent,
+			Payload:   payload,
+			Attempts:  1,
+			LastError: sendErr.Error(),
+			CreatedAt: now,
+			DeadAt:    now,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to save FCM dead letter: %w", err)
+		}
+	}
+	if len(retryable) == 0 {
+		return nil
+	}
+
+	retry := domain.FCMRetry{
+		ID:            ksuid.New().String(),
+		Tokens:        retryable,
+		Payload:       payload,
+		Attempts:      1,
+		LastError:     sendErr.Error(),
+		NextAttemptAt: now.Add(fcmRetryDelay(1)),
+		CreatedAt:     now,
+	}
+	err := n.infrastructure.SaveFCMRetry(ctx, retry)
+	if err != nil {
+		return fmt.Errorf("unable to schedule FCM retry: %w", err)
+	}
+	return nil
+}
+
+// ProcessFCMRetries sends the FCM messages whose next attempt is due to the
+// tokens that they have not reached yet.
+//
+// Messages that still fail are rescheduled with a longer wait, until they
+// have been attempted fcmMaxAttempts times and are moved to the dead letters.
+// It is meant to be run periodically e.g every minute by a scheduler calling
+// the inter-service API.
+func (n NotificationImpl) ProcessFCMRetries(
+	ctx context.Context,
+) (*domain.FCMRetryReport, error) {
+	ctx, span := tracer.Start(ctx, "ProcessFCMRetries")
+	defer span.End()
+
+	due, err := n.infrastructure.GetDueFCMRetries(
+		ctx,
+		time.Now(),
+		fcmRetryBatchSize,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get due FCM retries: %w", err)
+	}
+
+	report := &domain.FCMRetryReport{Errors: []string{}}
+	for _, retry := range due {
+		err := n.attemptFCMRetry(ctx, retry, report)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			report.Errors = append(report.Errors, fmt.Sprintf(
+				"FCM retry %s: %s", retry.ID, err))
+		}
+	}
+	return report, nil
+}
+
+func (n NotificationImpl) attemptFCMRetry(
+	ctx context.Context,
+	retry domain.FCMRetry,
+	report *domain.FCMRetryReport,
+) error {
+	payload := &dto.NotificationPayload{}
+	err := json.Unmarshal(retry.Payload, payload)
+	if err != nil {
+		// the payload will not get any better by waiting
+		retry.LastError = fmt.Sprintf("can't unmarshal FCM payload: %s", err)
+		return n.deadLetterFCMRetry(ctx, retry, report)
+	}
+
+	// the users may have opted out or started their quiet hours since the
+	// notification was first sent
+	payload.RegistrationTokens = retry.Tokens
+	payload, err = n.preferences.ScreenPushNotification(ctx, *payload)
+	if err != nil {
+		return fmt.Errorf("unable to screen FCM retry: %w", err)
+	}
+	retry.Tokens = payload.RegistrationTokens
+	if len(retry.Tokens) > 0 {
+		_, err = n.infrastructure.SendNotificationWithOptions(ctx, *payload)
+	}
+	if len(retry.Tokens) == 0 || err == nil || errors.Is(err, fcm.ErrNoValidTokens) {
+		err = n.infrastructure.DeleteFCMRetry(ctx, retry.ID)
+		if err != nil {
+			return fmt.Errorf("unable to remove FCM retry: %w", err)
+		}
+		report.Completed++
+		return nil
+	}
+
+	retry.Attempts++
+	retry.LastError = err.Error()
+	retryable, permanent := failedTokens(retry.Tokens, err)
+	if len(retryable) == 0 || retry.Attempts >= fcmMaxAttempts {
+		retry.Tokens = append(retryable, permanent...)
+		return n.deadLetterFCMRetry(ctx, retry, report)
+	}
+	if len(permanent) > 0 {
+		// the tokens that will not be reached are given up on now and the
+		// rest are retried
+		err = n.infrastructure.SaveFCMDeadLetter(ctx, domain.FCMDeadLetter{
+			ID:        ksuid.New().String(),
+			Tokens:    permanent,
+			Payload:   retry.Payload,
+			Attempts:  retry.Attempts,
+			LastError: retry.LastError,
+			CreatedAt: retry.CreatedAt,
+			DeadAt:    time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to save FCM dead letter: %w", err)
+		}
+		report.DeadLettered++
+	}
+
+	retry.Tokens = retryable
+	retry.NextAttemptAt = time.Now().Add(fcmRetryDelay(retry.Attempts))
+	err = n.infrastructure.SaveFCMRetry(ctx, retry)
+	if err != nil {
+		return fmt.Errorf("unable to reschedule FCM retry: %w", err)
+	}
+	report.Rescheduled++
+	return nil
+}
+
+// deadLetterFCMRetry gives up on an FCM message and keeps it for inspection
+func (n NotificationImpl) deadLetterFCMRetry(
+	ctx context.Context,
+	retry domain.FCMRetry,
+	report *domain.FCMRetryReport,
+) error {
+	err := n.infrastructure.SaveFCMDeadLetter(ctx, domain.FCMDeadLetter{
+		ID:        retry.ID,
+		Tokens:    retry.Tokens,
+		Payload:   retry.Payload,
+		Attempts:  retry.Attempts,
+		LastError: retry.LastError,
+		CreatedAt: retry.CreatedAt,
+		DeadAt:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to save FCM dead letter: %w", err)
+	}
+	err = n.infrastructure.DeleteFCMRetry(ctx, retry.ID)
+	if err != nil {
+		return fmt.Errorf("unable to remove FCM retry: %w", err)
+	}
+	report.DeadLettered++
+	return nil
+}
+
+// FCMDeadLetters lists the FCM messages that were given up on, the latest
+// first
+func (n NotificationImpl) FCMDeadLetters(
+	ctx context.Context,
+	limit int,
+) ([]domain.FCMDeadLetter, error) {
+	ctx, span := tracer.Start(ctx, "FCMDeadLetters")
+	defer span.End()
+	if limit <= 0 {
+		limit = defaultFCMDeadLettersLimit
+	}
+
+	letters, err := n.infrastructure.ListFCMDeadLetters(ctx, limit)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to list FCM dead letters: %w", err)
+	}
+	return letters, nil
+}
+
+// ReplayFCMDeadLetter sends an FCM message that was given up on again, to the
+// tokens that it did not reach. If it fails again, it is retried with a fresh
+// set of attempts.
+func (n NotificationImpl) ReplayFCMDeadLetter(
+	ctx context.Context,
+	id string,
+) (*domain.FCMRetryReport, error) {
+	ctx, span := tracer.Start(ctx, "ReplayFCMDeadLetter")
+	defer span.End()
+
+	letter, err := n.infrastructure.GetFCMDeadLetter(ctx, id)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get FCM dead letter: %w", err)
+	}
+	if letter == nil {
+		return nil, fmt.Errorf("no FCM dead letter with the ID %s", id)
+	}
+
+	report := &domain.FCMRetryReport{Errors: []string{}}
+	retry := domain.FCMRetry{
+		ID:            ksuid.New().String(),
+		Tokens:        letter.Tokens,
+		Payload:       letter.Payload,
+		NextAttemptAt: time.Now(),
+		CreatedAt:     time.Now(),
+	}
+	err = n.attemptFCMRetry(ctx, retry, report)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to replay FCM dead letter: %w", err)
+	}
+
+	err = n.infrastructure.DeleteFCMDeadLetter(ctx, id)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to remove replayed FCM dead letter: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,315 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	firebase "firebase.google.com/go"
+	"firebase.google.com/go/messaging"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/fcm"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
+	onboardingMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding/mock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+)
+
+// errorTransport answers every request with an FCM error
+type errorTransport struct {
+	body string
+}
+
+func (e errorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(e.body)),
+		Request:    req,
+	}, nil
+}
+
+// fcmError gets the error that the FCM client returns for a failed send
+func fcmError(t *testing.T, code string) error {
+	ctx := context.Background()
+	transport := errorTransport{body: fmt.Sprintf(
+		`{"error": {"status": %q, "message": "failed"}}`,
+		code,
+	)}
+	app, err := firebase.NewApp(
+		ctx,
+		&firebase.Config{ProjectID: "test"},
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+	)
+	assert.Nil(t, err)
+	client, err := app.Messaging(ctx)
+	assert.Nil(t, err)
+	_, err = client.Send(ctx, &messaging.Message{Token: "token"})
+	assert.NotNil(t, err)
+	return err
+}
+
+func TestFCMRetryDelay(t *testing.T) {
+	for attempts := 1; attempts <= 10; attempts++ {
+		backoff := fcmRetryBaseDelay << (attempts - 1)
+		if backoff > fcmRetryMaxDelay {
+			backoff = fcmRetryMaxDelay
+		}
+		delay := fcmRetryDelay(attempts)
+		assert.GreaterOrEqual(t, int64(delay), int64(backoff/2), "attempt %d", attempts)
+		assert.LessOrEqual(t, int64(delay), int64(backoff), "attempt %d", attempts)
+	}
+	assert.LessOrEqual(t, int64(fcmRetryDelay(100)), int64(fcmRetryMaxDelay))
+}
+
+func TestFailedTokens(t *testing.T) {
+	tokens := []string{"a", "b", "c"}
+	sendErr := &fcm.SendError{Failures: []fcm.TokenFailure{
+		{Token: "b", Err: fcmError(t, "UNAVAILABLE")},
+		{Token: "c", Err: fcmError(t, "PERMISSION_DENIED")},
+	}}
+	retryable, permanent := failedTokens(tokens, fmt.Errorf("wrapped: %w", sendErr))
+	assert.Equal(t, []string{"b"}, retryable)
+	assert.Equal(t, []string{"c"}, permanent)
+
+	retryable, permanent = failedTokens(tokens, fmt.Errorf("unable to send FCM messages"))
+	assert.Equal(t, tokens, retryable, "errors without an FCM code are retried")
+	assert.Empty(t, permanent)
+
+	retryable, permanent = failedTokens(tokens, fcmError(t, "INVALID_ARGUMENT"))
+	assert.Empty(t, retryable)
+	assert.Equal(t, tokens, permanent)
+}
+
+func TestNotificationImpl_scheduleFCMRetry(t *testing.T) {
+	ctx := context.Background()
+	saved := []domain.FCMRetry{}
+	letters := []domain.FCMDeadLetter{}
+	n := NewNotification(infrastructure.Interactor{
+		Repository: &mock.FakeEngagementRepository{
+			SaveFCMRetryFn: func(ctx context.Context, retry domain.FCMRetry) error {
+				saved = append(saved, retry)
+				return nil
+			},
+			SaveFCMDeadLetterFn: func(ctx context.Context, letter domain.FCMDeadLetter) error {
+				letters = append(letters, letter)
+				return nil
+			},
+		},
+	})
+	sendErr := &fcm.SendError{Failures: []fcm.TokenFailure{
+		{Token: "b", Err: fmt.Errorf("server unavailable")},
+		{Token: "c", Err: fcmError(t, "PERMISSION_DENIED")},
+	}}
+
+	err := n.scheduleFCMRetry(ctx, []byte(`{}`), []string{"a", "b", "c"}, sendErr)
+	assert.Nil(t, err)
+	assert.Len(t, saved, 1)
+	assert.NotEmpty(t, saved[0].ID)
+	assert.Equal(t, []string{"b"}, saved[0].Tokens)
+	assert.Equal(t, 1, saved[0].Attempts)
+	assert.Equal(t, sendErr.Error(), saved[0].LastError)
+	assert.True(t, saved[0].NextAttemptAt.After(time.Now()))
+
+	assert.Len(t, letters, 1, "permanent failures are dead lettered straight away")
+	assert.Equal(t, []string{"c"}, letters[0].Tokens)
+	assert.NotEqual(t, saved[0].ID, letters[0].ID)
+
+	saved, letters = nil, nil
+	err = n.scheduleFCMRetry(ctx, []byte(`{}`), []string{"a"}, fcmError(t, "INVALID_ARGUMENT"))
+	assert.Nil(t, err)
+	assert.Empty(t, saved, "nothing is retried when every failure is permanent")
+	assert.Len(t, letters, 1)
+}
+
+// fakeRetryStore keeps FCM retries and dead letters in memory
+func fakeRetryStore(
+	retries map[string]domain.FCMRetry,
+	letters map[string]domain.FCMDeadLetter,
+) *mock.FakeEngagementRepository {
+	return &mock.FakeEngagementRepository{
+		GetDueFCMRetriesFn: func(
+			ctx context.Context,
+			before time.Time,
+			limit int,
+		) ([]domain.FCMRetry, error) {
+			due := []domain.FCMRetry{}
+			for _, retry := range retries {
+				if !retry.NextAttemptAt.After(before) {
+					due = append(due, retry)
+				}
+			}
+			return due, nil
+		},
+		SaveFCMRetryFn: func(ctx context.Context, retry domain.FCMRetry) error {
+			retries[retry.ID] = retry
+			return nil
+		},
+		DeleteFCMRetryFn: func(ctx context.Context, id string) error {
+			delete(retries, id)
+			return nil
+		},
+		SaveFCMDeadLetterFn: func(ctx context.Context, letter domain.FCMDeadLetter) error {
+			if letter.ID == "unsaveable" {
+				return fmt.Errorf("unable to save")
+			}
+			letters[letter.ID] = letter
+			return nil
+		},
+		ListFCMDeadLettersFn: func(
+			ctx context.Context,
+			limit int,
+		) ([]domain.FCMDeadLetter, error) {
+			list := []domain.FCMDeadLetter{}
+			for _, letter := range letters {
+				list = append(list, letter)
+			}
+			return list, nil
+		},
+		GetFCMDeadLetterFn: func(
+			ctx context.Context,
+			id string,
+		) (*domain.FCMDeadLetter, error) {
+			letter, ok := letters[id]
+			if !ok {
+				return nil, nil
+			}
+			return &letter, nil
+		},
+		DeleteFCMDeadLetterFn: func(ctx context.Context, id string) error {
+			delete(letters, id)
+			return nil
+		},
+	}
+}
+
+func TestNotificationImpl_ProcessFCMRetries(t *testing.T) {
+	ctx := context.Background()
+	retries := map[string]domain.FCMRetry{
+		"corrupt": {
+			ID:            "corrupt",
+			Tokens:        []string{"a"},
+			Payload:       []byte(`not json`),
+			Attempts:      2,
+			NextAttemptAt: time.Now().Add(-time.Minute),
+		},
+		"unsaveable": {
+			ID:            "unsaveable",
+			Payload:       []byte(`not json`),
+			NextAttemptAt: time.Now().Add(-time.Minute),
+		},
+		"later": {
+			ID:            "later",
+			Payload:       []byte(`{}`),
+			NextAttemptAt: time.Now().Add(time.Hour),
+		},
+	}
+	letters := map[string]domain.FCMDeadLetter{}
+	n := NewNotification(infrastructure.Interactor{
+		Repository: fakeRetryStore(retries, letters),
+	})
+
+	report, err := n.ProcessFCMRetries(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.DeadLettered)
+	assert.Len(t, report.Errors, 1, "a retry that can't be dead lettered is kept")
+	assert.Contains(t, retries, "unsaveable")
+	assert.Contains(t, retries, "later")
+	assert.NotContains(t, retries, "corrupt")
+
+	letter := letters["corrupt"]
+	assert.Equal(t, []string{"a"}, letter.Tokens)
+	assert.Equal(t, 2, letter.Attempts)
+	assert.Contains(t, letter.LastError, "can't unmarshal FCM payload")
+	assert.False(t, letter.DeadAt.IsZero())
+
+	n = NewNotification(infrastructure.Interactor{
+		Repository: &mock.FakeEngagementRepository{
+			GetDueFCMRetriesFn: func(
+				ctx context.Context,
+				before time.Time,
+				limit int,
+			) ([]domain.FCMRetry, error) {
+				return nil, fmt.Errorf("firestore unavailable")
+			},
+		},
+	})
+	_, err = n.ProcessFCMRetries(ctx)
+	assert.NotNil(t, err)
+}
+
+func TestNotificationImpl_ProcessFCMRetriesScreening(t *testing.T) {
+	ctx := context.Background()
+	retries := map[string]domain.FCMRetry{
+		"opted-out": {
+			ID:            "opted-out",
+			Tokens:        []string{"a"},
+			Payload:       []byte(`{"uids": ["opted-out"]}`),
+			Attempts:      1,
+			NextAttemptAt: time.Now().Add(-time.Minute),
+		},
+	}
+	repository := fakeRetryStore(retries, map[string]domain.FCMDeadLetter{})
+	repository.GetNotificationPreferencesFn = func(
+		ctx context.Context,
+		uid string,
+	) (*domain.NotificationPreferences, error) {
+		return &domain.NotificationPreferences{UID: uid, OptedOut: true}, nil
+	}
+	n := NewNotification(infrastructure.Interactor{
+		Repository: repository,
+		ProfileService: &onboardingMock.FakeServiceOnboarding{
+			GetDeviceTokensFn: func(
+				ctx context.Context,
+				uids onboarding.UserUIDs,
+			) (map[string][]string, error) {
+				tokens := map[string][]string{}
+				for _, uid := range uids.UIDs {
+					tokens[uid] = []string{"a"}
+				}
+				return tokens, nil
+			},
+		},
+	})
+
+	report, err := n.ProcessFCMRetries(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, 1, report.Completed, "users that opted out are not retried")
+	assert.Empty(t, retries)
+}
+
+func TestNotificationImpl_ReplayFCMDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	retries := map[string]domain.FCMRetry{}
+	letters := map[string]domain.FCMDeadLetter{
+		"dead": {ID: "dead", Tokens: []string{"a"}, Payload: []byte(`not json`), Attempts: 6},
+	}
+	n := NewNotification(infrastructure.Interactor{
+		Repository: fakeRetryStore(retries, letters),
+	})
+
+	listed, err := n.FCMDeadLetters(ctx, 0)
+	assert.Nil(t, err)
+	assert.Len(t, listed, 1)
+
+	_, err = n.ReplayFCMDeadLetter(ctx, "missing")
+	assert.NotNil(t, err)
+
+	report, err := n.ReplayFCMDeadLetter(ctx, "dead")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.DeadLettered, "a replay that fails for good is dead lettered again")
+	assert.NotContains(t, letters, "dead")
+	assert.Len(t, letters, 1)
+	for _, letter := range letters {
+		assert.Equal(t, []string{"a"}, letter.Tokens)
+		assert.Equal(t, 0, letter.Attempts, "a replay starts with fresh attempts")
+	}
+	assert.Empty(t, retries)
+}
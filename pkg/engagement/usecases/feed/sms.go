@@ -0,0 +1,91 @@
+package feed
+
+import "strings"
+
+const (
+	// maxGSM7SMSLength keeps SMS notifications that only use the GSM 7-bit
+	// alphabet within two message segments of 153 characters
+	maxGSM7SMSLength = 306
+
+	// maxUCS2SMSLength keeps SMS notifications with characters outside the
+	// GSM 7-bit alphabet, which are sent as UCS-2, within two message
+	// segments of 67 characters
+	maxUCS2SMSLength = 134
+
+	// smsEllipsis ends shortened SMS notifications. It is in the GSM 7-bit
+	// alphabet, unlike `…`, so that shortening does not switch a message to
+	// UCS-2.
+	smsEllipsis = "..."
+)
+
+// gsm7Basic is the GSM 03.38 basic character set
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extension is the GSM 03.38 extension table. Its characters take two
+// septets, an escape and the character.
+const gsm7Extension = "^{}\\[~]|€\f"
+
+// smsLength returns the length of a text message in the units that its
+// encoding is billed in, septets for GSM 7-bit and UTF-16 code units for
+// UCS-2, and whether it can be sent as GSM 7-bit
+func smsLength(text string) (int, bool) {
+	septets := 0
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			septets++
+		case strings.ContainsRune(gsm7Extension, r):
+			septets += 2
+		default:
+			return utf16Length(text), false
+		}
+	}
+	return septets, true
+}
+
+// utf16Length returns the number of UTF-16 code units in a text
+func utf16Length(text string) int {
+	length := 0
+	for _, r := range text {
+		if r > 0xFFFF {
+			length += 2
+			continue
+		}
+		length++
+	}
+	return length
+}
+
+// smsLimit returns the length that a text message, with the supplied
+// encoding, can have to fit in two segments
+func smsLimit(gsm7 bool) int {
+	if gsm7 {
+		return maxGSM7SMSLength
+	}
+	return maxUCS2SMSLength
+}
+
+// shortenSMS fits a text message, followed by a suffix that is kept whole,
+// in two segments. The text is shortened, and ends with an ellipsis, when it
+// does not fit.
+func shortenSMS(text string, suffix string) string {
+	length, gsm7 := smsLength(text + suffix)
+	limit := smsLimit(gsm7)
+	if length <= limit {
+		return text + suffix
+	}
+
+	runes := []rune(text)
+	if len(runes) > limit {
+		runes = runes[:limit]
+	}
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		shortened := strings.TrimSpace(string(runes)) + smsEllipsis + suffix
+		if length, _ := smsLength(shortened); length <= limit {
+			return shortened
+		}
+	}
+	return strings.TrimSpace(suffix)
+}
@@ -0,0 +1,179 @@
+package feed
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+const (
+	// syncTokenVersion is bumped whenever the format of sync tokens changes
+	syncTokenVersion = "v2"
+
+	// legacySyncTokenVersion tokens carry a time only. They are still
+	// accepted so that clients don't have to sync the whole feed again.
+	legacySyncTokenVersion = "v1"
+
+	// feedChangesLimit is the maximum number of changes read in one sync
+	feedChangesLimit = 500
+)
+
+// encodeSyncToken returns an opaque sync token for the supplied position in
+// a feed's change log. Firestore keeps timestamps to the microsecond, so the
+// token does too.
+func encodeSyncToken(cursor domain.SyncCursor) string {
+	raw := fmt.Sprintf(
+		"%s.%d.%s",
+		syncTokenVersion,
+		cursor.Timestamp.UnixNano()/int64(time.Microsecond),
+		cursor.ChangeID,
+	)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeSyncToken returns the position in a feed's change log that a sync
+// token was issued for
+func decodeSyncToken(token string) (domain.SyncCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return domain.SyncCursor{}, fmt.Errorf("malformed sync token: %w", err)
+	}
+	parts := strings.SplitN(string(raw), ".", 3)
+	switch {
+	case len(parts) == 3 && parts[0] == syncTokenVersion:
+		micros, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return domain.SyncCursor{}, fmt.Errorf("malformed sync token: %w", err)
+		}
+		return domain.SyncCursor{
+			Timestamp: time.Unix(0, micros*int64(time.Microsecond)),
+			ChangeID:  parts[2],
+		}, nil
+	case len(parts) == 2 && parts[0] == legacySyncTokenVersion:
+		nanos, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return domain.SyncCursor{}, fmt.Errorf("malformed sync token: %w", err)
+		}
+		// the change the token was issued for is sent again, which is
+		// harmless
+		return domain.SyncCursor{
+			Timestamp: time.Unix(0, nanos).Truncate(time.Microsecond),
+		}, nil
+	default:
+		return domain.SyncCursor{}, fmt.Errorf("unsupported sync token")
+	}
+}
+
+// FeedChanges returns the elements of a feed that were created, updated or
+// deleted after the supplied sync token.
+//
+// When no sync token is supplied, every element currently in the feed is
+// returned. The result carries a new sync token that should be used for the
+// next sync.
+func (fe UseCaseImpl) FeedChanges(
+	ctx context.Context,
+	uid string,
+	isAnonymous bool,
+	flavour feedlib.Flavour,
+	since *string,
+) (*domain.FeedChanges, error) {
+	ctx, span := tracer.Start(ctx, "FeedChanges")
+	defer span.End()
+
+	var changes *domain.FeedChanges
+	var err error
+	if since == nil || *since == "" {
+		changes, err = fe.feedSnapshot(ctx, uid, flavour)
+	} else {
+		var after domain.SyncCursor
+		after, err = decodeSyncToken(*since)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("invalid sync token: %w", err)
+		}
+		if time.Since(after.Timestamp) > domain.FeedChangeRetention {
+			// the changes since the token may have been deleted
+			return nil, fmt.Errorf(
+				"the sync token has expired, sync the whole feed again")
+		}
+		changes, err = fe.infrastructure.GetFeedChanges(
+			ctx,
+			uid,
+			flavour,
+			after,
+			feedChangesLimit,
+		)
+	}
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get feed changes: %w", err)
+	}
+
+	err = localizeElements(
+		ctx,
+		fe.infrastructure,
+		uid,
+		flavour,
+		isAnonymous,
+		changes.Items,
+		changes.Nudges,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("feed localization error: %w", err)
+	}
+
+	changes.SyncToken = encodeSyncToken(changes.LastChange)
+	return changes, nil
+}
+
+// feedSnapshot reads the whole of a feed for clients that have not synced
+// before
+func (fe UseCaseImpl) feedSnapshot(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+) (*domain.FeedChanges, error) {
+	// changes made while the snapshot is read will be sent again on the
+	// next sync, which is harmless
+	startedAt := time.Now().Truncate(time.Microsecond)
+
+	actions, err := fe.infrastructure.GetActions(ctx, uid, flavour)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get actions: %w", err)
+	}
+	nudges, err := fe.infrastructure.GetNudges(ctx, uid, flavour, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get nudges: %w", err)
+	}
+	items, err := fe.infrastructure.GetItems(
+		ctx,
+		uid,
+		flavour,
+		feedlib.BooleanFilterBoth,
+		nil,
+		nil,
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get items: %w", err)
+	}
+
+	return &domain.FeedChanges{
+		UID:        uid,
+		Flavour:    flavour,
+		Actions:    actions,
+		Nudges:     nudges,
+		Items:      items,
+		Tombstones: []domain.Tombstone{},
+		LastChange: domain.SyncCursor{Timestamp: startedAt},
+	}, nil
+}
@@ -0,0 +1,73 @@
+package feed
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncTokenRoundTrip(t *testing.T) {
+	cursor := domain.SyncCursor{
+		Timestamp: time.Now().Truncate(time.Microsecond),
+		ChangeID:  "a-change",
+	}
+	token := encodeSyncToken(cursor)
+
+	decoded, err := decodeSyncToken(token)
+	assert.Nil(t, err)
+	assert.True(t, cursor.Timestamp.Equal(decoded.Timestamp))
+	assert.Equal(t, cursor.ChangeID, decoded.ChangeID)
+
+	// a change stored in the same microsecond is after a snapshot token
+	decoded, err = decodeSyncToken(encodeSyncToken(domain.SyncCursor{
+		Timestamp: time.Unix(0, 1_500),
+	}))
+	assert.Nil(t, err)
+	assert.True(t, time.Unix(0, 1_000).Equal(decoded.Timestamp))
+	assert.Equal(t, "", decoded.ChangeID)
+}
+
+func TestDecodeLegacySyncToken(t *testing.T) {
+	token := base64.RawURLEncoding.EncodeToString([]byte("v1.1000001500"))
+	decoded, err := decodeSyncToken(token)
+	assert.Nil(t, err)
+	assert.True(t, time.Unix(1, 1_000).Equal(decoded.Timestamp))
+	assert.Equal(t, "", decoded.ChangeID, "legacy tokens start at their time")
+}
+
+func TestDecodeSyncToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{
+			name:  "sad case: not base64",
+			token: "not a token!",
+		},
+		{
+			name:  "sad case: unknown version",
+			token: base64.RawURLEncoding.EncodeToString([]byte("v0.123")),
+		},
+		{
+			name:  "sad case: not a timestamp",
+			token: base64.RawURLEncoding.EncodeToString([]byte("v1.yesterday")),
+		},
+		{
+			name:  "sad case: not a cursor timestamp",
+			token: base64.RawURLEncoding.EncodeToString([]byte("v2.yesterday.a-change")),
+		},
+		{
+			name:  "sad case: no change ID",
+			token: base64.RawURLEncoding.EncodeToString([]byte("v2.123")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeSyncToken(tt.token)
+			assert.NotNil(t, err)
+		})
+	}
+}
@@ -0,0 +1,432 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/savannahghi/converterandformatter"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/feedlib"
+)
+
+// the kinds of change to a message that are published on the
+// `message.update` topic
+const (
+	messageEdited   = "EDITED"
+	messageReacted  = "REACTED"
+	messageAttached = "ATTACHED"
+)
+
+// maxEmojiRunes is long enough for emoji sequences joined with zero width
+// joiners e.g families
+const maxEmojiRunes = 10
+
+// mentionPattern matches `@<uid>` mentions in message text. The mention
+// should start the text or follow whitespace so that email addresses are not
+// taken for mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@([A-Za-z0-9_-]+)`)
+
+// MuteThread mutes or unmutes the conversation on a feed item for one of its
+// participants. Muted participants are not notified of new messages unless
+// they are mentioned.
+func (fe UseCaseImpl) MuteThread(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	participantUID string,
+	muted bool,
+) error {
+	ctx, span := tracer.Start(ctx, "MuteThread")
+	defer span.End()
+
+	if participantUID == "" {
+		return fmt.Errorf("no participant to mute the thread for")
+	}
+
+	item, err := fe.infrastructure.GetFeedItem(ctx, uid, flavour, itemID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to retrieve feed item %s: %w", itemID, err)
+	}
+	if item == nil {
+		return fmt.Errorf("no feed item with ID %s", itemID)
+	}
+
+	err = fe.infrastructure.SetThreadMute(
+		ctx,
+		uid,
+		flavour,
+		itemID,
+		participantUID,
+		muted,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to mute thread: %w", err)
+	}
+	return nil
+}
+
+// parseMentions returns the UIDs mentioned in a message's text, in the order
+// in which they are first mentioned
+func parseMentions(text string) []string {
+	mentions := []string{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		if !converterandformatter.StringSliceContains(mentions, match[1]) {
+			mentions = append(mentions, match[1])
+		}
+	}
+	return mentions
+}
+
+// threadParticipants returns the users taking part in the conversation on an
+// item: the feed's owner, the item's users and everyone that has posted to the
+// thread
+func threadParticipants(owner string, item *feedlib.Item) []string {
+	participants := []string{owner}
+	add := func(uid string) {
+		if uid != "" && !converterandformatter.StringSliceContains(participants, uid) {
+			participants = append(participants, uid)
+		}
+	}
+	for _, uid := range item.Users {
+		add(uid)
+	}
+	for _, message := range item.Conversations {
+		add(message.PostedByUID)
+	}
+	return participants
+}
+
+// excludeUIDs returns the UIDs that are not in the excluded list
+func excludeUIDs(uids []string, excluded ...string) []string {
+	remaining := []string{}
+	for _, uid := range uids {
+		if !converterandformatter.StringSliceContains(excluded, uid) {
+			remaining = append(remaining, uid)
+		}
+	}
+	return remaining
+}
+
+// includeUIDs returns the UIDs that are also in the included list
+func includeUIDs(uids []string, included ...string) []string {
+	remaining := []string{}
+	for _, uid := range uids {
+		if converterandformatter.StringSliceContains(included, uid) {
+			remaining = append(remaining, uid)
+		}
+	}
+	return remaining
+}
+
+// GetThread returns the conversation on an item as a tree. Top level messages
+// and replies are ordered from the oldest to the newest. Replies to messages
+// that have since been deleted are shown at the top level.
+func (fe UseCaseImpl) GetThread(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+) ([]*domain.ThreadMessage, error) {
+	ctx, span := tracer.Start(ctx, "GetThread")
+	defer span.End()
+
+	messages, err := fe.infrastructure.GetThreadMessages(ctx, uid, flavour, itemID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get messages: %w", err)
+	}
+	return threadTree(messages), nil
+}
+
+// EditMessage replaces the text of a message. Only the message's author can
+// edit it and the replaced text is kept in the message's edit history.
+func (fe UseCaseImpl) EditMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	text string,
+	editedByUID string,
+) (*domain.ThreadMessage, error) {
+	ctx, span := tracer.Start(ctx, "EditMessage")
+	defer span.End()
+
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("a message can't be edited to a blank message")
+	}
+	// hidden messages are not found, so they can't be edited
+	message, err := fe.findThreadMessage(ctx, uid, flavour, itemID, messageID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+	if message.PostedByUID != editedByUID {
+		return nil, fmt.Errorf("only the author of a message can edit it")
+	}
+
+	text, err = fe.moderateText(
+		ctx,
+		uid,
+		flavour,
+		itemID,
+		messageID,
+		editedByUID,
+		text,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to edit message: %w", err)
+	}
+	if message.Text == text {
+		return message, nil
+	}
+
+	edited, err := fe.infrastructure.EditMessage(
+		ctx,
+		uid,
+		flavour,
+		itemID,
+		messageID,
+		text,
+		editedByUID,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to edit message: %w", err)
+	}
+
+	err = fe.notifyMessageUpdate(ctx, uid, flavour, itemID, edited, messageEdited)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+	return edited, nil
+}
+
+// ReactToMessage adds or removes a user's emoji reaction to a message
+func (fe UseCaseImpl) ReactToMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	emoji string,
+	reactorUID string,
+	reacted bool,
+) error {
+	ctx, span := tracer.Start(ctx, "ReactToMessage")
+	defer span.End()
+
+	emoji = strings.TrimSpace(emoji)
+	if !isEmoji(emoji) {
+		return fmt.Errorf("`%s` is not an emoji", emoji)
+	}
+	if reactorUID == "" {
+		return fmt.Errorf("no user to react as")
+	}
+
+	err := fe.infrastructure.ReactToMessage(
+		ctx,
+		uid,
+		flavour,
+		itemID,
+		messageID,
+		emoji,
+		reactorUID,
+		reacted,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to react to message: %w", err)
+	}
+
+	message, err := fe.findThreadMessage(ctx, uid, flavour, itemID, messageID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return err
+	}
+	return fe.notifyMessageUpdate(ctx, uid, flavour, itemID, message, messageReacted)
+}
+
+// AttachToMessage attaches uploads, stored through the uploads service, to a
+// message. Only uploads made by the feed's user can be attached.
+func (fe UseCaseImpl) AttachToMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+	uploadIDs []string,
+) (*domain.ThreadMessage, error) {
+	ctx, span := tracer.Start(ctx, "AttachToMessage")
+	defer span.End()
+
+	if len(uploadIDs) == 0 {
+		return nil, fmt.Errorf("no uploads to attach")
+	}
+
+	for _, uploadID := range uploadIDs {
+		owner, err := fe.infrastructure.GetUploadOwner(ctx, uploadID)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to get the owner of upload %s: %w", uploadID, err)
+		}
+		if owner != uid {
+			return nil, fmt.Errorf("upload %s was not made by the feed's user", uploadID)
+		}
+	}
+
+	attachments := []domain.MessageAttachment{}
+	for _, uploadID := range uploadIDs {
+		upload, err := fe.infrastructure.FindUploadByID(ctx, uploadID)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to find upload %s: %w", uploadID, err)
+		}
+		attachments = append(attachments, domain.MessageAttachment{
+			UploadID:    upload.ID,
+			Title:       upload.Title,
+			ContentType: upload.ContentType,
+			URL:         upload.URL,
+			Size:        upload.Size,
+		})
+	}
+
+	err := fe.infrastructure.AttachToMessage(
+		ctx,
+		uid,
+		flavour,
+		itemID,
+		messageID,
+		attachments,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to attach uploads: %w", err)
+	}
+
+	message, err := fe.findThreadMessage(ctx, uid, flavour, itemID, messageID)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+	err = fe.notifyMessageUpdate(ctx, uid, flavour, itemID, message, messageAttached)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+	return message, nil
+}
+
+// findThreadMessage returns one of the messages on an item, with its edit
+// history, reactions and attachments
+func (fe UseCaseImpl) findThreadMessage(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	messageID string,
+) (*domain.ThreadMessage, error) {
+	messages, err := fe.infrastructure.GetThreadMessages(ctx, uid, flavour, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get messages: %w", err)
+	}
+	for i := range messages {
+		if messages[i].ID == messageID {
+			return &messages[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no message with ID %s", messageID)
+}
+
+// notifyMessageUpdate publishes a change to a message so that the clients of
+// the thread's participants can refresh it
+func (fe UseCaseImpl) notifyMessageUpdate(
+	ctx context.Context,
+	uid string,
+	flavour feedlib.Flavour,
+	itemID string,
+	message *domain.ThreadMessage,
+	change string,
+) error {
+	if err := fe.infrastructure.Notify(
+		ctx,
+		helpers.AddPubSubNamespace(common.MessageUpdateTopic),
+		uid,
+		flavour,
+		&feedlib.Message{
+			ID:             message.ID,
+			SequenceNumber: message.SequenceNumber,
+			Text:           message.Text,
+			ReplyTo:        message.ReplyTo,
+			PostedByUID:    message.PostedByUID,
+			PostedByName:   message.PostedByName,
+			Timestamp:      message.Timestamp,
+		},
+		map[string]interface{}{
+			"itemID":    itemID,
+			"messageID": message.ID,
+			"change":    change,
+		},
+	); err != nil {
+		return fmt.Errorf("unable to notify message update to channel: %w", err)
+	}
+	return nil
+}
+
+// threadTree nests replies under the messages that they reply to
+func threadTree(messages []domain.ThreadMessage) []*domain.ThreadMessage {
+	nodes := map[string]*domain.ThreadMessage{}
+	ordered := []*domain.ThreadMessage{}
+	for i := range messages {
+		msg := &messages[i]
+		if msg.Replies == nil {
+			msg.Replies = []*domain.ThreadMessage{}
+		}
+		nodes[msg.ID] = msg
+		ordered = append(ordered, msg)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Timestamp.Equal(ordered[j].Timestamp) {
+			return ordered[i].SequenceNumber < ordered[j].SequenceNumber
+		}
+		return ordered[i].Timestamp.Before(ordered[j].Timestamp)
+	})
+
+	roots := []*domain.ThreadMessage{}
+	for _, msg := range ordered {
+		parent, ok := nodes[msg.ReplyTo]
+		if !ok || parent == msg {
+			roots = append(roots, msg)
+			continue
+		}
+		parent.Replies = append(parent.Replies, msg)
+	}
+	return roots
+}
+
+// isEmoji reports whether a reaction looks like a single emoji: a short
+// sequence of non ASCII characters
+func isEmoji(s string) bool {
+	count := utf8.RuneCountInString(s)
+	if count == 0 || count > maxEmojiRunes {
+		return false
+	}
+	for _, r := range s {
+		if r < utf8.RuneSelf || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,164 @@
+package feed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	"github.com/savannahghi/feedlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "happy case: mentions",
+			text: "@abc123 please check with @def-456 and @abc123",
+			want: []string{"abc123", "def-456"},
+		},
+		{
+			name: "happy case: no mentions",
+			text: "nothing to see here",
+			want: []string{},
+		},
+		{
+			name: "sad case: email addresses are not mentions",
+			text: "write to care@example.com",
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseMentions(tt.text))
+		})
+	}
+}
+
+func TestThreadParticipants(t *testing.T) {
+	item := &feedlib.Item{
+		Users: []string{"clinician", "owner"},
+		Conversations: []feedlib.Message{
+			{PostedByUID: "clinician"},
+			{PostedByUID: "nurse"},
+			{PostedByUID: ""},
+		},
+	}
+
+	participants := threadParticipants("owner", item)
+	assert.Equal(t, []string{"owner", "clinician", "nurse"}, participants)
+	assert.Equal(
+		t,
+		[]string{"owner", "nurse"},
+		excludeUIDs(participants, "clinician"),
+	)
+	assert.Equal(
+		t,
+		[]string{"nurse", "owner"},
+		includeUIDs([]string{"nurse", "stranger", "owner"}, participants...),
+	)
+}
+
+func TestThreadTree(t *testing.T) {
+	now := time.Now()
+	messages := []domain.ThreadMessage{
+		{ID: "reply-2", ReplyTo: "root-1", Timestamp: now.Add(3 * time.Minute)},
+		{ID: "root-2", Timestamp: now.Add(time.Minute)},
+		{ID: "orphan", ReplyTo: "deleted", Timestamp: now.Add(4 * time.Minute)},
+		{ID: "reply-1", ReplyTo: "root-1", Timestamp: now.Add(2 * time.Minute)},
+		{ID: "nested", ReplyTo: "reply-1", Timestamp: now.Add(5 * time.Minute)},
+		{ID: "root-1", Timestamp: now},
+	}
+
+	roots := threadTree(messages)
+	ids := func(msgs []*domain.ThreadMessage) []string {
+		out := []string{}
+		for _, msg := range msgs {
+			out = append(out, msg.ID)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"root-1", "root-2", "orphan"}, ids(roots))
+	assert.Equal(t, []string{"reply-1", "reply-2"}, ids(roots[0].Replies))
+	assert.Equal(t, []string{"nested"}, ids(roots[0].Replies[0].Replies))
+	assert.Equal(t, []string{}, ids(roots[1].Replies))
+}
+
+func TestIsEmoji(t *testing.T) {
+	tests := []struct {
+		name  string
+		emoji string
+		want  bool
+	}{
+		{
+			name:  "happy case: emoji",
+			emoji: "👍",
+			want:  true,
+		},
+		{
+			name:  "happy case: emoji with skin tone",
+			emoji: "👍🏽",
+			want:  true,
+		},
+		{
+			name:  "sad case: blank",
+			emoji: "",
+			want:  false,
+		},
+		{
+			name:  "sad case: text",
+			emoji: "like",
+			want:  false,
+		},
+		{
+			name:  "sad case: non ASCII letters",
+			emoji: "привет",
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isEmoji(tt.emoji))
+		})
+	}
+}
+
+func TestUseCaseImpl_AttachToMessage_OtherUsersUploads(t *testing.T) {
+	ctx := context.Background()
+	owners := map[string]string{"mine": "uid", "theirs": "other"}
+	repository := &mock.FakeEngagementRepository{
+		GetUploadOwnerFn: func(ctx context.Context, uploadID string) (string, error) {
+			return owners[uploadID], nil
+		},
+		AttachToMessageFn: func(
+			ctx context.Context,
+			uid string,
+			flavour feedlib.Flavour,
+			itemID string,
+			messageID string,
+			attachments []domain.MessageAttachment,
+		) error {
+			t.Fatal("uploads were attached")
+			return nil
+		},
+	}
+	fe := NewFeed(infrastructure.Interactor{Repository: repository})
+
+	for _, uploadIDs := range [][]string{{"theirs"}, {"unknown"}, {"mine", "theirs"}} {
+		_, err := fe.AttachToMessage(
+			ctx,
+			"uid",
+			feedlib.FlavourConsumer,
+			"item",
+			"message",
+			uploadIDs,
+		)
+		assert.NotNil(t, err, uploadIDs)
+	}
+}
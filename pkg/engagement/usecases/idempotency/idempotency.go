@@ -0,0 +1,169 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/savannahghi/engagementcore/pkg/engagement/usecases/idempotency")
+
+// WindowEnvVarName is the environment variable that sets how long the
+// response to a request made with an idempotency key is replayed for e.g
+// `48h`. It defaults to 24 hours.
+const WindowEnvVarName = "IDEMPOTENCY_WINDOW"
+
+// DefaultWindow is how long responses are replayed for when no window is
+// configured
+const DefaultWindow = 24 * time.Hour
+
+var (
+	// ErrRequestInProgress is returned when a request is repeated before the
+	// first request with the same idempotency key has completed
+	ErrRequestInProgress = errors.New(
+		"a request with this idempotency key is still in progress")
+
+	// ErrKeyReused is returned when an idempotency key is reused for a
+	// different request
+	ErrKeyReused = errors.New(
+		"this idempotency key was used for a different request")
+)
+
+// RunFunc carries out a request and returns the response that is replayed
+// for repeats of the request
+type RunFunc func(ctx context.Context) (*domain.IdempotentResponse, error)
+
+// UseCaseIdempotency makes requests safe to retry
+type UseCaseIdempotency interface {
+	Idempotent(
+		ctx context.Context,
+		scope string,
+		key string,
+		request []byte,
+		run RunFunc,
+	) (*domain.IdempotentResponse, bool, error)
+
+	ProcessPubSubMessage(
+		ctx context.Context,
+		topic string,
+		messageID string,
+		process ProcessFunc,
+	) (bool, error)
+}
+
+// ImplIdempotency is the idempotency usecase implementation
+type ImplIdempotency struct {
+	infrastructure infrastructure.Interactor
+}
+
+// NewIdempotency initializes an idempotency usecase instance
+func NewIdempotency(infrastructure infrastructure.Interactor) *ImplIdempotency {
+	return &ImplIdempotency{
+		infrastructure: infrastructure,
+	}
+}
+
+// Window returns the configured replay window. An invalid window falls back
+// to the default.
+func Window() time.Duration {
+	return durationFromEnv(WindowEnvVarName, DefaultWindow)
+}
+
+// durationFromEnv reads a positive duration from an environment variable
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	val, ok := os.LookupEnv(name)
+	if !ok || val == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(val)
+	if err != nil || duration <= 0 {
+		log.Printf("invalid %s `%s`, using %s", name, val, fallback)
+		return fallback
+	}
+	return duration
+}
+
+// Fingerprint identifies a request so that a key that is reused for a
+// different request can be detected
+func Fingerprint(request []byte) string {
+	sum := sha256.Sum256(request)
+	return hex.EncodeToString(sum[:])
+}
+
+// Idempotent runs a request at most once per idempotency key within the
+// replay window.
+//
+// The first request with a key is run and its response stored. Repeats of the
+// request get the stored response back, with true, instead of being run again.
+// Requests that fail, or respond with a server error, are not stored so that
+// they can be retried. Requests without a key are always run.
+func (i *ImplIdempotency) Idempotent(
+	ctx context.Context,
+	scope string,
+	key string,
+	request []byte,
+	run RunFunc,
+) (*domain.IdempotentResponse, bool, error) {
+	ctx, span := tracer.Start(ctx, "Idempotent")
+	defer span.End()
+
+	if key == "" {
+		response, err := run(ctx)
+		return response, false, err
+	}
+
+	now := time.Now()
+	fingerprint := Fingerprint(request)
+	record, claimed, err := i.infrastructure.ClaimIdempotencyKey(
+		ctx,
+		domain.IdempotencyRecord{
+			Scope:       scope,
+			Key:         key,
+			Fingerprint: fingerprint,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(Window()),
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, false, fmt.Errorf("unable to check idempotency key: %w", err)
+	}
+	if !claimed {
+		switch {
+		case record.Fingerprint != fingerprint:
+			return nil, false, ErrKeyReused
+		case !record.Completed || record.Response == nil:
+			return nil, false, ErrRequestInProgress
+		default:
+			return record.Response, true, nil
+		}
+	}
+
+	response, err := run(ctx)
+	if err != nil || response == nil || response.StatusCode >= http.StatusInternalServerError {
+		if releaseErr := i.infrastructure.ReleaseIdempotencyKey(ctx, scope, key); releaseErr != nil {
+			helpers.RecordSpanError(span, releaseErr)
+			log.Printf("unable to release idempotency key: %v", releaseErr)
+		}
+		return response, false, err
+	}
+
+	err = i.infrastructure.CompleteIdempotencyKey(ctx, scope, key, *response)
+	if err != nil {
+		// the request has been carried out, so its response is still returned
+		helpers.RecordSpanError(span, err)
+		log.Printf("unable to save idempotent response: %v", err)
+	}
+	return response, false, nil
+}
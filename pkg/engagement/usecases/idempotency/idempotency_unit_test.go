@@ -0,0 +1,351 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/idempotency"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStore keeps idempotency records in memory
+func fakeStore() *mock.FakeEngagementRepository {
+	records := map[string]*domain.IdempotencyRecord{}
+	id := func(scope, key string) string { return scope + "/" + key }
+
+	return &mock.FakeEngagementRepository{
+		ClaimIdempotencyKeyFn: func(
+			ctx context.Context,
+			record domain.IdempotencyRecord,
+		) (*domain.IdempotencyRecord, bool, error) {
+			existing, ok := records[id(record.Scope, record.Key)]
+			if ok && existing.ExpiresAt.After(time.Now()) {
+				return existing, false, nil
+			}
+			records[id(record.Scope, record.Key)] = &record
+			return &record, true, nil
+		},
+		CompleteIdempotencyKeyFn: func(
+			ctx context.Context,
+			scope string,
+			key string,
+			response domain.IdempotentResponse,
+		) error {
+			record, ok := records[id(scope, key)]
+			if !ok {
+				return fmt.Errorf("no idempotency key `%s`", key)
+			}
+			record.Completed = true
+			record.Response = &response
+			return nil
+		},
+		ReleaseIdempotencyKeyFn: func(
+			ctx context.Context,
+			scope string,
+			key string,
+		) error {
+			delete(records, id(scope, key))
+			return nil
+		},
+	}
+}
+
+func TestUnit_Idempotent(t *testing.T) {
+	ctx := context.Background()
+	i := idempotency.NewIdempotency(
+		infrastructure.Interactor{Repository: fakeStore()},
+	)
+
+	runs := 0
+	run := func(status int, err error) idempotency.RunFunc {
+		return func(ctx context.Context) (*domain.IdempotentResponse, error) {
+			runs++
+			if err != nil {
+				return nil, err
+			}
+			return &domain.IdempotentResponse{
+				StatusCode: status,
+				Body:       []byte(fmt.Sprintf("run %d", runs)),
+			}, nil
+		}
+	}
+
+	// requests without a key are always run
+	for n := 1; n <= 2; n++ {
+		_, replayed, err := i.Idempotent(ctx, "scope", "", nil, run(http.StatusOK, nil))
+		assert.Nil(t, err)
+		assert.False(t, replayed)
+	}
+	assert.Equal(t, 2, runs)
+
+	// repeats get the first response back
+	first, replayed, err := i.Idempotent(
+		ctx, "scope", "key", []byte("request"), run(http.StatusOK, nil))
+	assert.Nil(t, err)
+	assert.False(t, replayed)
+
+	repeat, replayed, err := i.Idempotent(
+		ctx, "scope", "key", []byte("request"), run(http.StatusOK, nil))
+	assert.Nil(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first.Body, repeat.Body)
+	assert.Equal(t, 3, runs)
+
+	// the same key in another scope is a different request
+	_, replayed, err = i.Idempotent(
+		ctx, "other scope", "key", []byte("request"), run(http.StatusOK, nil))
+	assert.Nil(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, 4, runs)
+
+	// a key can't be reused for a different request
+	_, _, err = i.Idempotent(
+		ctx, "scope", "key", []byte("another request"), run(http.StatusOK, nil))
+	assert.True(t, errors.Is(err, idempotency.ErrKeyReused))
+	assert.Equal(t, 4, runs)
+
+	// failed requests can be retried with the same key
+	_, _, err = i.Idempotent(
+		ctx, "scope", "retry", nil, run(0, fmt.Errorf("test error")))
+	assert.NotNil(t, err)
+	_, replayed, err = i.Idempotent(
+		ctx, "scope", "retry", nil, run(http.StatusBadGateway, nil))
+	assert.Nil(t, err)
+	assert.False(t, replayed)
+	_, replayed, err = i.Idempotent(
+		ctx, "scope", "retry", nil, run(http.StatusOK, nil))
+	assert.Nil(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, 7, runs)
+}
+
+func TestUnit_Idempotent_InProgress(t *testing.T) {
+	ctx := context.Background()
+	i := idempotency.NewIdempotency(
+		infrastructure.Interactor{Repository: fakeStore()},
+	)
+
+	_, _, err := i.Idempotent(
+		ctx,
+		"scope",
+		"key",
+		nil,
+		func(ctx context.Context) (*domain.IdempotentResponse, error) {
+			_, _, err := i.Idempotent(
+				ctx,
+				"scope",
+				"key",
+				nil,
+				func(ctx context.Context) (*domain.IdempotentResponse, error) {
+					t.Fatal("a request in progress should not be run again")
+					return nil, nil
+				},
+			)
+			assert.True(t, errors.Is(err, idempotency.ErrRequestInProgress))
+			return &domain.IdempotentResponse{StatusCode: http.StatusOK}, nil
+		},
+	)
+	assert.Nil(t, err)
+}
+
+func TestUnit_Window(t *testing.T) {
+	initial := os.Getenv(idempotency.WindowEnvVarName)
+	defer os.Setenv(idempotency.WindowEnvVarName, initial)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "not set", value: "", want: idempotency.DefaultWindow},
+		{name: "configured", value: "48h", want: 48 * time.Hour},
+		{name: "invalid", value: "tomorrow", want: idempotency.DefaultWindow},
+		{name: "negative", value: "-1h", want: idempotency.DefaultWindow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv(idempotency.WindowEnvVarName, tt.value)
+			assert.Equal(t, tt.want, idempotency.Window())
+		})
+	}
+}
+
+// fakeMessageStore keeps processed Pub/Sub messages in memory
+func fakeMessageStore(claimErr error) *mock.FakeEngagementRepository {
+	messages := map[string]*domain.ProcessedMessage{}
+	id := func(topic, messageID string) string { return topic + "/" + messageID }
+
+	return &mock.FakeEngagementRepository{
+		ClaimPubSubMessageFn: func(
+			ctx context.Context,
+			message domain.ProcessedMessage,
+		) (*domain.ProcessedMessage, bool, error) {
+			if claimErr != nil {
+				return nil, false, claimErr
+			}
+			existing, ok := messages[id(message.Topic, message.ID)]
+			if ok && !existing.Claimable(message.ReceivedAt) {
+				return existing, false, nil
+			}
+			if ok {
+				message.Steps = existing.Steps
+			}
+			messages[id(message.Topic, message.ID)] = &message
+			return &message, true, nil
+		},
+		CompletePubSubMessageFn: func(
+			ctx context.Context,
+			topic string,
+			messageID string,
+		) error {
+			message, ok := messages[id(topic, messageID)]
+			if !ok {
+				return fmt.Errorf("no pub sub message `%s`", messageID)
+			}
+			message.Completed = true
+			return nil
+		},
+		CompletePubSubStepFn: func(
+			ctx context.Context,
+			topic string,
+			messageID string,
+			step string,
+		) error {
+			message, ok := messages[id(topic, messageID)]
+			if !ok {
+				return fmt.Errorf("no pub sub message `%s`", messageID)
+			}
+			message.Steps = append(message.Steps, step)
+			return nil
+		},
+		ReleasePubSubMessageFn: func(
+			ctx context.Context,
+			topic string,
+			messageID string,
+		) error {
+			message, ok := messages[id(topic, messageID)]
+			if !ok {
+				return fmt.Errorf("no pub sub message `%s`", messageID)
+			}
+			message.LeaseExpiresAt = time.Now().Add(-time.Second)
+			return nil
+		},
+	}
+}
+
+func TestUnit_ProcessPubSubMessage(t *testing.T) {
+	ctx := context.Background()
+	i := idempotency.NewIdempotency(
+		infrastructure.Interactor{Repository: fakeMessageStore(nil)},
+	)
+
+	runs := 0
+	process := func(err error) idempotency.ProcessFunc {
+		return func(ctx context.Context) error {
+			runs++
+			message, ok := idempotency.PubSubMessageFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "items.publish", message.Topic)
+			return err
+		}
+	}
+
+	duplicate, err := i.ProcessPubSubMessage(ctx, "items.publish", "1", process(nil))
+	assert.Nil(t, err)
+	assert.False(t, duplicate)
+
+	duplicate, err = i.ProcessPubSubMessage(ctx, "items.publish", "1", process(nil))
+	assert.Nil(t, err)
+	assert.True(t, duplicate, "a redelivered message is not processed again")
+	assert.Equal(t, 1, runs)
+
+	// messages that fail are processed again when they are redelivered
+	_, err = i.ProcessPubSubMessage(ctx, "items.publish", "2", process(fmt.Errorf("test error")))
+	assert.NotNil(t, err)
+	duplicate, err = i.ProcessPubSubMessage(ctx, "items.publish", "2", process(nil))
+	assert.Nil(t, err)
+	assert.False(t, duplicate)
+	assert.Equal(t, 3, runs)
+
+	// a redelivery while the message is being processed is turned away
+	_, err = i.ProcessPubSubMessage(ctx, "items.publish", "3", func(ctx context.Context) error {
+		_, err := i.ProcessPubSubMessage(ctx, "items.publish", "3", process(nil))
+		assert.True(t, errors.Is(err, idempotency.ErrMessageInProgress))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, runs)
+
+	// messages are left for Pub/Sub to redeliver when the store is
+	// unavailable
+	i = idempotency.NewIdempotency(infrastructure.Interactor{
+		Repository: fakeMessageStore(fmt.Errorf("firestore unavailable")),
+	})
+	duplicate, err = i.ProcessPubSubMessage(ctx, "items.publish", "1", process(nil))
+	assert.NotNil(t, err)
+	assert.False(t, duplicate)
+	assert.Equal(t, 3, runs)
+}
+
+func TestUnit_Step(t *testing.T) {
+	ctx := context.Background()
+	store := fakeMessageStore(nil)
+	i := idempotency.NewIdempotency(infrastructure.Interactor{Repository: store})
+
+	pushes, writes := 0, 0
+	process := func(writeErr error) idempotency.ProcessFunc {
+		return func(ctx context.Context) error {
+			err := idempotency.Step(ctx, store, "push", func() error {
+				pushes++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			return idempotency.Step(ctx, store, "write", func() error {
+				writes++
+				return writeErr
+			})
+		}
+	}
+
+	_, err := i.ProcessPubSubMessage(ctx, "items.publish", "1", process(fmt.Errorf("test error")))
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, pushes)
+	assert.Equal(t, 1, writes)
+
+	_, err = i.ProcessPubSubMessage(ctx, "items.publish", "1", process(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, pushes, "steps done before a failure are not repeated")
+	assert.Equal(t, 2, writes, "the step that failed is run again")
+
+	err = idempotency.Step(ctx, store, "push", func() error {
+		pushes++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, pushes, "steps outside a pub sub message are run")
+}
+
+func TestProcessedMessage_Claimable(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Minute)
+	earlier := now.Add(-time.Minute)
+
+	assert.False(t, domain.ProcessedMessage{Completed: true, ExpiresAt: later}.Claimable(now))
+	assert.True(t, domain.ProcessedMessage{Completed: true, ExpiresAt: earlier}.Claimable(now))
+	assert.False(t, domain.ProcessedMessage{LeaseExpiresAt: later, ExpiresAt: later}.Claimable(now))
+	assert.True(
+		t,
+		domain.ProcessedMessage{LeaseExpiresAt: earlier, ExpiresAt: later}.Claimable(now),
+		"a message whose processing was abandoned can be claimed",
+	)
+}
@@ -0,0 +1,64 @@
+package idempotency
+
+import (
+	"context"
+	"log"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// the checks that catch duplicate Pub/Sub messages
+const (
+	// the message had already been processed
+	DuplicateMessage = "message"
+
+	// the message's notifications had already been sent
+	DuplicateNotification = "notification"
+
+	// a side effect of the message had already been done
+	DuplicateStep = "step"
+)
+
+// Pub/Sub deduplication measures
+var (
+	PubSubDuplicates = stats.Int64(
+		"pubsub_duplicate_messages",
+		"The number of redelivered Pub/Sub messages that were not processed again",
+		stats.UnitDimensionless,
+	)
+
+	// PubSubTopic is the name of the topic that the message was published to
+	PubSubTopic = tag.MustNewKey("pubsub.topic")
+
+	// DuplicateCheck is the check that caught the duplicate
+	DuplicateCheck = tag.MustNewKey("pubsub.duplicate_check")
+
+	PubSubDuplicatesView = &view.View{
+		Name:        "pubsub_duplicate_message_count",
+		Description: "The number of redelivered Pub/Sub messages that were not processed again",
+		Measure:     PubSubDuplicates,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{PubSubTopic, DuplicateCheck},
+	}
+)
+
+// Views are the views of the deduplication measures. They are registered
+// along with the other service views.
+var Views = []*view.View{PubSubDuplicatesView}
+
+// RecordDuplicate counts a duplicate Pub/Sub message
+func RecordDuplicate(ctx context.Context, topic string, check string) {
+	err := stats.RecordWithTags(
+		ctx,
+		[]tag.Mutator{
+			tag.Upsert(PubSubTopic, topic),
+			tag.Upsert(DuplicateCheck, check),
+		},
+		PubSubDuplicates.M(1),
+	)
+	if err != nil {
+		log.Printf("unable to record duplicate pub sub message: %v", err)
+	}
+}
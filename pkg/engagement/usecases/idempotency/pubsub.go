@@ -0,0 +1,180 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+)
+
+// DeduplicationWindowEnvVarName is the environment variable that sets how long
+// processed Pub/Sub messages are remembered e.g `72h`. It defaults to a week,
+// which is how long the subscriptions retain messages.
+const DeduplicationWindowEnvVarName = "PUBSUB_DEDUPLICATION_WINDOW"
+
+const (
+	// DefaultDeduplicationWindow is how long processed messages are
+	// remembered when no window is configured
+	DefaultDeduplicationWindow = 7 * 24 * time.Hour
+
+	// ProcessingLease is how long a delivery has to process a message before
+	// a redelivery of the message can take over
+	ProcessingLease = 10 * time.Minute
+)
+
+// ErrMessageInProgress is returned when a Pub/Sub message is redelivered
+// while an earlier delivery of it is still being processed
+var ErrMessageInProgress = errors.New(
+	"an earlier delivery of this pub sub message is still being processed")
+
+// ProcessFunc processes a Pub/Sub message
+type ProcessFunc func(ctx context.Context) error
+
+// PubSubMessage identifies the Pub/Sub message that a context is processing
+type PubSubMessage struct {
+	Topic string
+	ID    string
+
+	// the steps that an earlier delivery of the message did before it failed
+	Steps []string
+}
+
+// StepRecorder records the side effects of processing Pub/Sub messages
+type StepRecorder interface {
+	CompletePubSubStep(
+		ctx context.Context,
+		topic string,
+		messageID string,
+		step string,
+	) error
+}
+
+type pubSubMessageContextKey struct{}
+
+// PubSubMessageFromContext returns the Pub/Sub message that is being
+// processed, if any
+func PubSubMessageFromContext(ctx context.Context) (PubSubMessage, bool) {
+	message, ok := ctx.Value(pubSubMessageContextKey{}).(PubSubMessage)
+	return message, ok
+}
+
+// Step runs a side effect of processing a Pub/Sub message e.g a silent push,
+// unless an earlier delivery of the message did it before failing. The step
+// names the side effect within the message. Outside a Pub/Sub message the
+// side effect is always run.
+//
+// A step is recorded once it is done, so a failure to record it means that
+// it may be done again.
+func Step(
+	ctx context.Context,
+	recorder StepRecorder,
+	step string,
+	run func() error,
+) error {
+	message, ok := PubSubMessageFromContext(ctx)
+	if !ok {
+		return run()
+	}
+	for _, done := range message.Steps {
+		if done == step {
+			RecordDuplicate(ctx, message.Topic, DuplicateStep)
+			return nil
+		}
+	}
+	if err := run(); err != nil {
+		return err
+	}
+	err := recorder.CompletePubSubStep(ctx, message.Topic, message.ID, step)
+	if err != nil {
+		log.Printf(
+			"unable to record step %s of pub sub message %s: %v",
+			step,
+			message.ID,
+			err,
+		)
+	}
+	return nil
+}
+
+// DeduplicationWindow returns the configured deduplication window. An invalid
+// window falls back to the default.
+func DeduplicationWindow() time.Duration {
+	return durationFromEnv(
+		DeduplicationWindowEnvVarName,
+		DefaultDeduplicationWindow,
+	)
+}
+
+// ProcessPubSubMessage processes a Pub/Sub message at most once within the
+// deduplication window. Pub/Sub delivers messages at least once, so a message
+// can arrive again after it has been processed.
+//
+// Redeliveries of a message that has been processed are counted as
+// duplicates and return true without processing the message again. A message
+// that fails to process is released so that its redelivery is processed.
+// The message is passed on in the context, with the steps that earlier
+// deliveries did, so that handlers can skip side effects that are done with
+// `Step`. When the processed messages can't be checked, an error is returned
+// so that Pub/Sub redelivers the message later.
+func (i *ImplIdempotency) ProcessPubSubMessage(
+	ctx context.Context,
+	topic string,
+	messageID string,
+	process ProcessFunc,
+) (bool, error) {
+	ctx, span := tracer.Start(ctx, "ProcessPubSubMessage")
+	defer span.End()
+
+	if messageID == "" {
+		return false, process(ctx)
+	}
+
+	now := time.Now()
+	existing, claimed, err := i.infrastructure.ClaimPubSubMessage(
+		ctx,
+		domain.ProcessedMessage{
+			ID:             messageID,
+			Topic:          topic,
+			LeaseExpiresAt: now.Add(ProcessingLease),
+			ReceivedAt:     now,
+			ExpiresAt:      now.Add(DeduplicationWindow()),
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return false, fmt.Errorf("unable to check pub sub message %s: %w", messageID, err)
+	}
+	if !claimed {
+		if !existing.Completed {
+			return false, ErrMessageInProgress
+		}
+		RecordDuplicate(ctx, topic, DuplicateMessage)
+		return true, nil
+	}
+	ctx = context.WithValue(
+		ctx,
+		pubSubMessageContextKey{},
+		PubSubMessage{Topic: topic, ID: messageID, Steps: existing.Steps},
+	)
+
+	if err := process(ctx); err != nil {
+		if releaseErr := i.infrastructure.ReleasePubSubMessage(ctx, topic, messageID); releaseErr != nil {
+			helpers.RecordSpanError(span, releaseErr)
+			log.Printf("unable to release pub sub message: %v", releaseErr)
+		}
+		return false, err
+	}
+
+	err = i.infrastructure.CompletePubSubMessage(ctx, topic, messageID)
+	if err != nil {
+		// the message has been processed, and its lease keeps redeliveries
+		// out for a while
+		helpers.RecordSpanError(span, err)
+		log.Printf("unable to record processed pub sub message %s: %v", messageID, err)
+	}
+	return false, nil
+}
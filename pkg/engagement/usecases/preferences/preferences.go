@@ -0,0 +1,737 @@
+package preferences
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/helpers"
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/i18n"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/firebasetools"
+	"github.com/savannahghi/silcomms"
+	"github.com/segmentio/ksuid"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/savannahghi/engagementcore/pkg/engagement/usecases/preferences")
+
+// the statuses reported for emails that were not sent straight away
+const (
+	emailDeferred   = "deferred"
+	emailSuppressed = "suppressed"
+)
+
+// Deferral is a recipient whose quiet hours a notification falls in
+type Deferral struct {
+	// a UID, or an address when addresses were screened
+	Recipient string
+
+	// the UID of the recipient, if known
+	UID string
+
+	// the end of the recipient's quiet hours
+	Until time.Time
+}
+
+// Screening sorts the recipients of a notification by what their
+// preferences allow
+type Screening struct {
+	// recipients that can be notified straight away
+	Allowed []string
+
+	// recipients that should be notified when their quiet hours end
+	Deferred []Deferral
+
+	// recipients that do not want the notification
+	Suppressed []string
+}
+
+// UsecasePreferences manages users' notification preferences and applies
+// them to outgoing notifications.
+//
+// Transactional notifications e.g one time PINs are not screened.
+type UsecasePreferences interface {
+	NotificationPreferences(
+		ctx context.Context,
+		uid string,
+	) (*domain.NotificationPreferences, error)
+
+	SetNotificationPreferences(
+		ctx context.Context,
+		uid string,
+		preferences domain.NotificationPreferences,
+	) (*domain.NotificationPreferences, error)
+
+	ScreenUsers(
+		ctx context.Context,
+		uids []string,
+		channel feedlib.Channel,
+		category domain.NotificationCategory,
+		transactional bool,
+	) (*Screening, error)
+
+	ScreenAddresses(
+		ctx context.Context,
+		addresses []string,
+		channel feedlib.Channel,
+		category domain.NotificationCategory,
+		transactional bool,
+	) (*Screening, error)
+
+	DeferNotification(
+		ctx context.Context,
+		notification domain.DeferredNotification,
+	) error
+
+	SendSMSWithPreferences(
+		ctx context.Context,
+		to []string,
+		message string,
+		transactional bool,
+	) (*silcomms.BulkSMSResponse, error)
+
+	SendEmailWithPreferences(
+		ctx context.Context,
+		subject string,
+		text string,
+		body *string,
+		transactional bool,
+		to ...string,
+	) (string, error)
+
+	SendFCMByPhoneOrEmailWithPreferences(
+		ctx context.Context,
+		phoneNumber *string,
+		email *string,
+		data map[string]interface{},
+		notification firebasetools.FirebaseSimpleNotificationInput,
+		android *firebasetools.FirebaseAndroidConfigInput,
+		ios *firebasetools.FirebaseAPNSConfigInput,
+		web *firebasetools.FirebaseWebpushConfigInput,
+		transactional bool,
+	) (bool, error)
+
+	ScreenPushNotification(
+		ctx context.Context,
+		payload dto.NotificationPayload,
+	) (*dto.NotificationPayload, error)
+
+	PhoneNumberVerificationCodeWithPreferences(
+		ctx context.Context,
+		to string,
+		code string,
+		marketingMessage string,
+	) (bool, error)
+}
+
+// ImplPreferences is the notification preferences usecase implementation
+type ImplPreferences struct {
+	infrastructure infrastructure.Interactor
+}
+
+// NewPreferences initializes a notification preferences usecase instance
+func NewPreferences(infrastructure infrastructure.Interactor) *ImplPreferences {
+	return &ImplPreferences{
+		infrastructure: infrastructure,
+	}
+}
+
+// NotificationPreferences returns a user's notification preferences. Users
+// that have not set any get everything.
+func (p *ImplPreferences) NotificationPreferences(
+	ctx context.Context,
+	uid string,
+) (*domain.NotificationPreferences, error) {
+	ctx, span := tracer.Start(ctx, "NotificationPreferences")
+	defer span.End()
+
+	preferences, err := p.infrastructure.GetNotificationPreferences(ctx, uid)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get notification preferences: %w", err)
+	}
+	if preferences == nil {
+		preferences = &domain.NotificationPreferences{UID: uid}
+	}
+	if preferences.DisabledChannels == nil {
+		preferences.DisabledChannels = []feedlib.Channel{}
+	}
+	if preferences.DisabledCategories == nil {
+		preferences.DisabledCategories = []domain.NotificationCategory{}
+	}
+	return preferences, nil
+}
+
+// SetNotificationPreferences replaces a user's notification preferences
+func (p *ImplPreferences) SetNotificationPreferences(
+	ctx context.Context,
+	uid string,
+	preferences domain.NotificationPreferences,
+) (*domain.NotificationPreferences, error) {
+	ctx, span := tracer.Start(ctx, "SetNotificationPreferences")
+	defer span.End()
+
+	if uid == "" {
+		return nil, fmt.Errorf("the user is required")
+	}
+	err := preferences.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if preferences.Language != "" {
+		if !i18n.IsSupported(preferences.Language) {
+			return nil, fmt.Errorf("%s is not a supported language", preferences.Language)
+		}
+		preferences.Language = i18n.Resolve(preferences.Language)
+	}
+	preferences.UID = uid
+	preferences.UpdatedAt = time.Now()
+	if preferences.DisabledChannels == nil {
+		preferences.DisabledChannels = []feedlib.Channel{}
+	}
+	if preferences.DisabledCategories == nil {
+		preferences.DisabledCategories = []domain.NotificationCategory{}
+	}
+
+	err = p.infrastructure.SaveNotificationPreferences(ctx, preferences)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to save notification preferences: %w", err)
+	}
+	return &preferences, nil
+}
+
+// ScreenUsers sorts users by whether they accept a notification now, later
+// or not at all
+func (p *ImplPreferences) ScreenUsers(
+	ctx context.Context,
+	uids []string,
+	channel feedlib.Channel,
+	category domain.NotificationCategory,
+	transactional bool,
+) (*Screening, error) {
+	ctx, span := tracer.Start(ctx, "ScreenUsers")
+	defer span.End()
+
+	screening := &Screening{
+		Allowed:    []string{},
+		Deferred:   []Deferral{},
+		Suppressed: []string{},
+	}
+	now := time.Now()
+	seen := map[string]bool{}
+	for _, uid := range uids {
+		if seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		if transactional {
+			screening.Allowed = append(screening.Allowed, uid)
+			continue
+		}
+
+		preferences, err := p.NotificationPreferences(ctx, uid)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, err
+		}
+		screening.add(uid, uid, preferences, channel, category, now)
+	}
+	return screening, nil
+}
+
+// ScreenAddresses sorts phone numbers or email addresses by whether their
+// owners accept a notification now, later or not at all. Phone numbers that
+// opted out on the profile service are suppressed. Addresses that do not
+// belong to a user are allowed.
+func (p *ImplPreferences) ScreenAddresses(
+	ctx context.Context,
+	addresses []string,
+	channel feedlib.Channel,
+	category domain.NotificationCategory,
+	transactional bool,
+) (*Screening, error) {
+	ctx, span := tracer.Start(ctx, "ScreenAddresses")
+	defer span.End()
+
+	screening := &Screening{
+		Allowed:    []string{},
+		Deferred:   []Deferral{},
+		Suppressed: []string{},
+	}
+	if transactional {
+		screening.Allowed = append(screening.Allowed, addresses...)
+		return screening, nil
+	}
+
+	candidates := addresses
+	if channel == feedlib.ChannelSms || channel == feedlib.ChannelWhatsapp {
+		allowed, err := p.infrastructure.PhonesWithoutOptOut(ctx, addresses)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, fmt.Errorf("unable to check opt outs: %w", err)
+		}
+		candidates = allowed
+		screening.Suppressed = excluded(addresses, allowed)
+	}
+
+	// the profile service looks up one address at a time
+	uids := make([]string, len(candidates))
+	preferences := make([]*domain.NotificationPreferences, len(candidates))
+	err := helpers.ForEachConcurrently(
+		len(candidates),
+		helpers.ConcurrentRequests,
+		func(i int) error {
+			var err error
+			uids[i], preferences[i], err = p.addressPreferences(ctx, candidates[i])
+			return err
+		},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+
+	now := time.Now()
+	for i, address := range candidates {
+		if preferences[i] == nil {
+			screening.Allowed = append(screening.Allowed, address)
+			continue
+		}
+		screening.add(address, uids[i], preferences[i], channel, category, now)
+	}
+	return screening, nil
+}
+
+// add sorts one recipient by its preferences
+func (s *Screening) add(
+	recipient string,
+	uid string,
+	preferences *domain.NotificationPreferences,
+	channel feedlib.Channel,
+	category domain.NotificationCategory,
+	now time.Time,
+) {
+	if !preferences.Allows(channel, category) {
+		s.Suppressed = append(s.Suppressed, recipient)
+		return
+	}
+	if until := preferences.DeferUntil(now); until != nil {
+		s.Deferred = append(s.Deferred, Deferral{
+			Recipient: recipient,
+			UID:       uid,
+			Until:     *until,
+		})
+		return
+	}
+	s.Allowed = append(s.Allowed, recipient)
+}
+
+// addressPreferences finds the preferences of the user that a phone number
+// or email address belongs to. They are nil when the address does not
+// belong to a user, or the user has not set any.
+func (p *ImplPreferences) addressPreferences(
+	ctx context.Context,
+	address string,
+) (string, *domain.NotificationPreferences, error) {
+	lookup := &dto.RetrieveUserProfileInput{}
+	if strings.Contains(address, "@") {
+		lookup.EmailAddress = &address
+	} else {
+		lookup.PhoneNumber = &address
+	}
+	profile, err := p.infrastructure.GetUserProfileByPhoneOrEmail(ctx, lookup)
+	if err != nil {
+		// the profile service does not tell "not found" apart from other
+		// failures, and most addresses that are sent to are not users'.
+		// The address is left out of the log as it identifies the person.
+		log.Printf("no profile found for an address: %v", err)
+		return "", nil, nil
+	}
+
+	for _, uid := range profile.VerifiedUIDS {
+		preferences, err := p.infrastructure.GetNotificationPreferences(ctx, uid)
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to get notification preferences: %w", err)
+		}
+		if preferences != nil {
+			return uid, preferences, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// DeferNotification holds a notification back until the recipient's quiet
+// hours end
+func (p *ImplPreferences) DeferNotification(
+	ctx context.Context,
+	notification domain.DeferredNotification,
+) error {
+	ctx, span := tracer.Start(ctx, "DeferNotification")
+	defer span.End()
+
+	notification.ID = ksuid.New().String()
+	notification.CreatedAt = time.Now()
+	err := p.infrastructure.SaveDeferredNotification(ctx, notification)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return fmt.Errorf("unable to defer notification: %w", err)
+	}
+	return nil
+}
+
+// deferToAddresses holds a direct notification back for each of the
+// recipients in their quiet hours
+func (p *ImplPreferences) deferToAddresses(
+	ctx context.Context,
+	deferred []Deferral,
+	notification domain.DeferredNotification,
+) error {
+	for _, deferral := range deferred {
+		notification.RecipientUID = deferral.UID
+		notification.Addresses = []string{deferral.Recipient}
+		notification.DeliverAt = deferral.Until
+		err := p.DeferNotification(ctx, notification)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendSMSWithPreferences sends an SMS to the phone numbers whose owners
+// accept it. Numbers in their owners' quiet hours get it when the hours end.
+func (p *ImplPreferences) SendSMSWithPreferences(
+	ctx context.Context,
+	to []string,
+	message string,
+	transactional bool,
+) (*silcomms.BulkSMSResponse, error) {
+	ctx, span := tracer.Start(ctx, "SendSMSWithPreferences")
+	defer span.End()
+
+	screening, err := p.ScreenAddresses(
+		ctx,
+		to,
+		feedlib.ChannelSms,
+		domain.NotificationCategoryGeneral,
+		transactional,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+	err = p.deferToAddresses(ctx, screening.Deferred, domain.DeferredNotification{
+		Channel:  feedlib.ChannelSms,
+		Category: domain.NotificationCategoryGeneral,
+		Body:     message,
+	})
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+	if len(screening.Allowed) == 0 {
+		return &silcomms.BulkSMSResponse{}, nil
+	}
+	return p.infrastructure.SendToMany(ctx, screening.Allowed, message)
+}
+
+// SendEmailWithPreferences sends an email to the addresses whose owners
+// accept it. Addresses in their owners' quiet hours get it when the hours
+// end.
+func (p *ImplPreferences) SendEmailWithPreferences(
+	ctx context.Context,
+	subject string,
+	text string,
+	body *string,
+	transactional bool,
+	to ...string,
+) (string, error) {
+	ctx, span := tracer.Start(ctx, "SendEmailWithPreferences")
+	defer span.End()
+
+	screening, err := p.ScreenAddresses(
+		ctx,
+		to,
+		feedlib.ChannelEmail,
+		domain.NotificationCategoryGeneral,
+		transactional,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return "", err
+	}
+	deferred := domain.DeferredNotification{
+		Channel:  feedlib.ChannelEmail,
+		Category: domain.NotificationCategoryGeneral,
+		Title:    subject,
+		Body:     text,
+	}
+	if body != nil {
+		deferred.HTML = *body
+	}
+	err = p.deferToAddresses(ctx, screening.Deferred, deferred)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return "", err
+	}
+
+	switch {
+	case len(screening.Allowed) > 0:
+		status, _, err := p.infrastructure.SendEmail(
+			ctx,
+			subject,
+			text,
+			body,
+			screening.Allowed...,
+		)
+		return status, err
+	case len(screening.Deferred) > 0:
+		return emailDeferred, nil
+	default:
+		return emailSuppressed, nil
+	}
+}
+
+// SendFCMByPhoneOrEmailWithPreferences sends a push notification to the
+// user with a phone number or email address if they accept it. It is held
+// back during the user's quiet hours. The platform specific configuration is
+// not kept for held back notifications.
+func (p *ImplPreferences) SendFCMByPhoneOrEmailWithPreferences(
+	ctx context.Context,
+	phoneNumber *string,
+	email *string,
+	data map[string]interface{},
+	notification firebasetools.FirebaseSimpleNotificationInput,
+	android *firebasetools.FirebaseAndroidConfigInput,
+	ios *firebasetools.FirebaseAPNSConfigInput,
+	web *firebasetools.FirebaseWebpushConfigInput,
+	transactional bool,
+) (bool, error) {
+	ctx, span := tracer.Start(ctx, "SendFCMByPhoneOrEmailWithPreferences")
+	defer span.End()
+
+	addresses := []string{}
+	if phoneNumber != nil && *phoneNumber != "" {
+		addresses = append(addresses, *phoneNumber)
+	} else if email != nil && *email != "" {
+		addresses = append(addresses, *email)
+	}
+	screening, err := p.ScreenAddresses(
+		ctx,
+		addresses,
+		feedlib.ChannelFcm,
+		domain.NotificationCategoryGeneral,
+		transactional,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return false, err
+	}
+	if len(addresses) > 0 && len(screening.Allowed) == 0 {
+		err = p.deferToAddresses(ctx, screening.Deferred, domain.DeferredNotification{
+			Channel:  feedlib.ChannelFcm,
+			Category: domain.NotificationCategoryGeneral,
+			Title:    notification.Title,
+			Body:     notification.Body,
+			Data:     data,
+		})
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return false, err
+		}
+		return false, nil
+	}
+
+	return p.infrastructure.SendFCMByPhoneOrEmail(
+		ctx,
+		phoneNumber,
+		email,
+		data,
+		notification,
+		android,
+		ios,
+		web,
+	)
+}
+
+// ScreenPushNotification narrows a push notification's registration tokens
+// down to those of the users that accept it. Users in their quiet hours get
+// it when the hours end. Notifications that are not transactional must say
+// which users the tokens belong to, and tokens that belong to none of them
+// are dropped.
+func (p *ImplPreferences) ScreenPushNotification(
+	ctx context.Context,
+	payload dto.NotificationPayload,
+) (*dto.NotificationPayload, error) {
+	ctx, span := tracer.Start(ctx, "ScreenPushNotification")
+	defer span.End()
+
+	if payload.IsTransactional() {
+		return &payload, nil
+	}
+	if len(payload.UIDs) == 0 {
+		return nil, fmt.Errorf(
+			"the users that the tokens belong to are required for notifications that are not transactional",
+		)
+	}
+
+	screening, err := p.ScreenUsers(
+		ctx,
+		payload.UIDs,
+		feedlib.ChannelFcm,
+		domain.NotificationCategoryGeneral,
+		false,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, err
+	}
+	userTokens, err := p.infrastructure.GetDeviceTokens(
+		ctx,
+		onboarding.UserUIDs{UIDs: screening.Allowed},
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return nil, fmt.Errorf("unable to get push tokens: %w", err)
+	}
+	accepted := map[string]bool{}
+	for _, tokens := range userTokens {
+		for _, token := range tokens {
+			accepted[token] = true
+		}
+	}
+	allowed := []string{}
+	for _, token := range payload.RegistrationTokens {
+		if accepted[token] {
+			allowed = append(allowed, token)
+		}
+	}
+
+	deferred := domain.DeferredNotification{
+		Channel:  feedlib.ChannelFcm,
+		Category: domain.NotificationCategoryGeneral,
+		Data:     stringMapToInterfaces(payload.Data),
+	}
+	if payload.Notification != nil {
+		deferred.Title = payload.Notification.Title
+		deferred.Body = payload.Notification.Body
+		if payload.Notification.ImageURL != nil {
+			deferred.ImageURL = *payload.Notification.ImageURL
+		}
+	}
+	for _, deferral := range screening.Deferred {
+		deferred.RecipientUID = deferral.UID
+		deferred.DeliverAt = deferral.Until
+		err = p.DeferNotification(ctx, deferred)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return nil, err
+		}
+	}
+
+	payload.RegistrationTokens = allowed
+	return &payload, nil
+}
+
+// PhoneNumberVerificationCodeWithPreferences sends a phone number
+// verification code over WhatsApp. The code is always sent, while the
+// marketing message that can come with it is only sent to numbers whose
+// owners accept it.
+func (p *ImplPreferences) PhoneNumberVerificationCodeWithPreferences(
+	ctx context.Context,
+	to string,
+	code string,
+	marketingMessage string,
+) (bool, error) {
+	ctx, span := tracer.Start(ctx, "PhoneNumberVerificationCodeWithPreferences")
+	defer span.End()
+
+	// the code is transactional, so its recipient is never screened out
+	codeScreening, err := p.ScreenAddresses(
+		ctx,
+		[]string{to},
+		feedlib.ChannelWhatsapp,
+		domain.NotificationCategoryGeneral,
+		true,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return false, err
+	}
+	sent := false
+	for _, phone := range codeScreening.Allowed {
+		sent, err = p.infrastructure.PhoneNumberVerificationCode(ctx, phone, code, "")
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return false, err
+		}
+	}
+	if marketingMessage == "" {
+		return sent, nil
+	}
+
+	screening, err := p.ScreenAddresses(
+		ctx,
+		[]string{to},
+		feedlib.ChannelWhatsapp,
+		domain.NotificationCategoryGeneral,
+		false,
+	)
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return false, err
+	}
+	err = p.deferToAddresses(ctx, screening.Deferred, domain.DeferredNotification{
+		Channel:  feedlib.ChannelWhatsapp,
+		Category: domain.NotificationCategoryGeneral,
+		Body:     marketingMessage,
+	})
+	if err != nil {
+		helpers.RecordSpanError(span, err)
+		return false, err
+	}
+	for _, phone := range screening.Allowed {
+		_, err = p.infrastructure.WhatsAppMessage(ctx, phone, marketingMessage)
+		if err != nil {
+			helpers.RecordSpanError(span, err)
+			return false, fmt.Errorf("unable to send marketing message: %w", err)
+		}
+	}
+	return sent, nil
+}
+
+// stringMapToInterfaces converts push notification data to the form that
+// held back notifications keep it in
+func stringMapToInterfaces(data map[string]string) map[string]interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	converted := map[string]interface{}{}
+	for key, value := range data {
+		converted[key] = value
+	}
+	return converted
+}
+
+// excluded returns the entries of `all` that are not in `kept`
+func excluded(all []string, kept []string) []string {
+	keep := map[string]bool{}
+	for _, entry := range kept {
+		keep[entry] = true
+	}
+	dropped := []string{}
+	for _, entry := range all {
+		if !keep[entry] {
+			dropped = append(dropped, entry)
+		}
+	}
+	return dropped
+}
@@ -0,0 +1,346 @@
+package preferences_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/engagementcore/pkg/engagement/application/common/dto"
+	"github.com/savannahghi/engagementcore/pkg/engagement/domain"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/database/mock"
+	"github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding"
+	onboardingMock "github.com/savannahghi/engagementcore/pkg/engagement/infrastructure/services/onboarding/mock"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/preferences"
+	"github.com/savannahghi/feedlib"
+	"github.com/savannahghi/firebasetools"
+	"github.com/savannahghi/profileutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStore keeps notification preferences and deferred notifications in
+// memory
+func fakeStore(
+	saved map[string]*domain.NotificationPreferences,
+	deferred *[]domain.DeferredNotification,
+) *mock.FakeEngagementRepository {
+	return &mock.FakeEngagementRepository{
+		GetNotificationPreferencesFn: func(
+			ctx context.Context,
+			uid string,
+		) (*domain.NotificationPreferences, error) {
+			return saved[uid], nil
+		},
+		SaveNotificationPreferencesFn: func(
+			ctx context.Context,
+			preferences domain.NotificationPreferences,
+		) error {
+			saved[preferences.UID] = &preferences
+			return nil
+		},
+		SaveDeferredNotificationFn: func(
+			ctx context.Context,
+			notification domain.DeferredNotification,
+		) error {
+			*deferred = append(*deferred, notification)
+			return nil
+		},
+	}
+}
+
+// quietNow returns quiet hours that the current time falls in
+func quietNow() *domain.QuietHours {
+	now := time.Now().UTC()
+	return &domain.QuietHours{
+		Start:    now.Add(-time.Hour).Format("15:04"),
+		End:      now.Add(time.Hour).Format("15:04"),
+		Timezone: "UTC",
+	}
+}
+
+func TestUnit_QuietHoursUntil(t *testing.T) {
+	nairobi, err := time.LoadLocation("Africa/Nairobi")
+	assert.Nil(t, err)
+	overnight := domain.QuietHours{Start: "22:00", End: "07:00", Timezone: "Africa/Nairobi"}
+	daytime := domain.QuietHours{Start: "13:00", End: "14:30", Timezone: "Africa/Nairobi"}
+
+	tests := []struct {
+		name  string
+		hours domain.QuietHours
+		at    time.Time
+		quiet bool
+		until time.Time
+	}{
+		{
+			name:  "before midnight",
+			hours: overnight,
+			at:    time.Date(2021, 6, 1, 23, 0, 0, 0, nairobi),
+			quiet: true,
+			until: time.Date(2021, 6, 2, 7, 0, 0, 0, nairobi),
+		},
+		{
+			name:  "after midnight",
+			hours: overnight,
+			at:    time.Date(2021, 6, 2, 3, 0, 0, 0, nairobi),
+			quiet: true,
+			until: time.Date(2021, 6, 2, 7, 0, 0, 0, nairobi),
+		},
+		{
+			name:  "outside an overnight period",
+			hours: overnight,
+			at:    time.Date(2021, 6, 2, 7, 0, 0, 0, nairobi),
+		},
+		{
+			name:  "in a daytime period, given in another time zone",
+			hours: daytime,
+			at:    time.Date(2021, 6, 2, 10, 15, 0, 0, time.UTC),
+			quiet: true,
+			until: time.Date(2021, 6, 2, 14, 30, 0, 0, nairobi),
+		},
+		{
+			name:  "outside a daytime period",
+			hours: daytime,
+			at:    time.Date(2021, 6, 2, 12, 59, 0, 0, nairobi),
+		},
+		{
+			name:  "invalid time zone",
+			hours: domain.QuietHours{Start: "00:00", End: "23:59", Timezone: "Mars/Olympus"},
+			at:    time.Now(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, quiet := tt.hours.Until(tt.at)
+			assert.Equal(t, tt.quiet, quiet)
+			if tt.quiet {
+				assert.True(t, tt.until.Equal(until), "got %s", until)
+			}
+		})
+	}
+}
+
+func TestUnit_SetNotificationPreferences(t *testing.T) {
+	ctx := context.Background()
+	saved := map[string]*domain.NotificationPreferences{}
+	p := preferences.NewPreferences(infrastructure.Interactor{
+		Repository: fakeStore(saved, &[]domain.DeferredNotification{}),
+	})
+
+	defaults, err := p.NotificationPreferences(ctx, "uid")
+	assert.Nil(t, err)
+	assert.False(t, defaults.OptedOut)
+	assert.Empty(t, defaults.DisabledChannels)
+	assert.NotNil(t, defaults.DisabledCategories)
+
+	invalid := []domain.NotificationPreferences{
+		{DisabledChannels: []feedlib.Channel{"PIGEON"}},
+		{DisabledCategories: []domain.NotificationCategory{"GOSSIP"}},
+		{QuietHours: &domain.QuietHours{Start: "25:00", End: "07:00", Timezone: "UTC"}},
+		{QuietHours: &domain.QuietHours{Start: "07:00", End: "07:00", Timezone: "UTC"}},
+		{QuietHours: &domain.QuietHours{Start: "22:00", End: "07:00"}},
+		{Language: "klingon"},
+	}
+	for _, input := range invalid {
+		_, err := p.SetNotificationPreferences(ctx, "uid", input)
+		assert.NotNil(t, err, "%+v should be rejected", input)
+	}
+	assert.Empty(t, saved)
+
+	set, err := p.SetNotificationPreferences(ctx, "uid", domain.NotificationPreferences{
+		UID:              "someone else",
+		DisabledChannels: []feedlib.Channel{feedlib.ChannelSms},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "uid", set.UID)
+	assert.False(t, set.UpdatedAt.IsZero())
+	assert.Equal(t, set, saved["uid"])
+}
+
+func TestUnit_SetNotificationPreferencesLanguage(t *testing.T) {
+	ctx := context.Background()
+	saved := map[string]*domain.NotificationPreferences{}
+	p := preferences.NewPreferences(infrastructure.Interactor{
+		Repository: fakeStore(saved, &[]domain.DeferredNotification{}),
+	})
+
+	set, err := p.SetNotificationPreferences(ctx, "uid", domain.NotificationPreferences{
+		Language: "sw-KE",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "sw", set.Language)
+
+	_, err = p.SetNotificationPreferences(ctx, "uid", domain.NotificationPreferences{
+		Language: "xx",
+	})
+	assert.NotNil(t, err, "unsupported languages are rejected")
+}
+
+func TestUnit_ScreenUsers(t *testing.T) {
+	ctx := context.Background()
+	saved := map[string]*domain.NotificationPreferences{
+		"opted-out": {UID: "opted-out", OptedOut: true},
+		"no-sms":    {UID: "no-sms", DisabledChannels: []feedlib.Channel{feedlib.ChannelSms}},
+		"no-nudges": {
+			UID:                "no-nudges",
+			DisabledCategories: []domain.NotificationCategory{domain.NotificationCategoryNudges},
+		},
+		"asleep": {UID: "asleep", QuietHours: quietNow()},
+	}
+	p := preferences.NewPreferences(infrastructure.Interactor{
+		Repository: fakeStore(saved, &[]domain.DeferredNotification{}),
+	})
+	users := []string{"opted-out", "no-sms", "no-nudges", "asleep", "default", "default"}
+
+	screening, err := p.ScreenUsers(ctx, users, feedlib.ChannelSms, domain.NotificationCategoryNudges, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"default"}, screening.Allowed)
+	assert.Equal(t, []string{"opted-out", "no-sms", "no-nudges"}, screening.Suppressed)
+	assert.Len(t, screening.Deferred, 1)
+	assert.Equal(t, "asleep", screening.Deferred[0].UID)
+	assert.True(t, screening.Deferred[0].Until.After(time.Now()))
+
+	screening, err = p.ScreenUsers(ctx, users, feedlib.ChannelFcm, domain.NotificationCategoryItems, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"no-sms", "no-nudges", "default"}, screening.Allowed)
+	assert.Equal(t, []string{"opted-out"}, screening.Suppressed)
+
+	screening, err = p.ScreenUsers(ctx, users, feedlib.ChannelSms, domain.NotificationCategoryNudges, true)
+	assert.Nil(t, err)
+	assert.Len(t, screening.Allowed, 5, "transactional notifications are not screened")
+	assert.Empty(t, screening.Deferred)
+	assert.Empty(t, screening.Suppressed)
+}
+
+func TestUnit_SendSMSWithPreferences(t *testing.T) {
+	ctx := context.Background()
+	saved := map[string]*domain.NotificationPreferences{
+		"asleep": {UID: "asleep", QuietHours: quietNow()},
+	}
+	deferred := []domain.DeferredNotification{}
+	infra := infrastructure.Interactor{
+		Repository: fakeStore(saved, &deferred),
+		ProfileService: &onboardingMock.FakeServiceOnboarding{
+			PhonesWithoutOptOutFn: func(
+				ctx context.Context,
+				phones []string,
+			) ([]string, error) {
+				allowed := []string{}
+				for _, phone := range phones {
+					if phone != "+254700000001" {
+						allowed = append(allowed, phone)
+					}
+				}
+				return allowed, nil
+			},
+			GetUserProfileByPhoneOrEmailFn: func(
+				ctx context.Context,
+				payload *dto.RetrieveUserProfileInput,
+			) (*profileutils.UserProfile, error) {
+				if *payload.PhoneNumber == "+254700000002" {
+					return &profileutils.UserProfile{VerifiedUIDS: []string{"asleep"}}, nil
+				}
+				return nil, fmt.Errorf("user not found")
+			},
+		},
+	}
+	p := preferences.NewPreferences(infra)
+	to := []string{"+254700000001", "+254700000002", "+254700000003"}
+
+	screening, err := p.ScreenAddresses(ctx, to, feedlib.ChannelSms, domain.NotificationCategoryGeneral, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"+254700000003"}, screening.Allowed)
+	assert.Equal(t, []string{"+254700000001"}, screening.Suppressed)
+	assert.Len(t, screening.Deferred, 1)
+	assert.Equal(t, "+254700000002", screening.Deferred[0].Recipient)
+	assert.Equal(t, "asleep", screening.Deferred[0].UID)
+
+	screening, err = p.ScreenAddresses(ctx, to, feedlib.ChannelSms, domain.NotificationCategoryGeneral, true)
+	assert.Nil(t, err)
+	assert.Equal(t, to, screening.Allowed, "transactional messages are not screened")
+
+	// numbers that are all withheld are not sent to
+	resp, err := p.SendSMSWithPreferences(ctx, to[:2], "Hello", false)
+	assert.Nil(t, err)
+	assert.NotNil(t, resp)
+	assert.Len(t, deferred, 1)
+	assert.Equal(t, []string{"+254700000002"}, deferred[0].Addresses)
+	assert.Equal(t, "asleep", deferred[0].RecipientUID)
+	assert.Equal(t, feedlib.ChannelSms, deferred[0].Channel)
+	assert.Equal(t, "Hello", deferred[0].Body)
+	assert.NotEmpty(t, deferred[0].ID)
+	assert.True(t, deferred[0].DeliverAt.After(time.Now()))
+}
+
+func TestUnit_ScreenPushNotification(t *testing.T) {
+	ctx := context.Background()
+	saved := map[string]*domain.NotificationPreferences{
+		"opted-out": {UID: "opted-out", OptedOut: true},
+		"asleep":    {UID: "asleep", QuietHours: quietNow()},
+	}
+	deferred := []domain.DeferredNotification{}
+	infra := infrastructure.Interactor{
+		Repository: fakeStore(saved, &deferred),
+		ProfileService: &onboardingMock.FakeServiceOnboarding{
+			GetDeviceTokensFn: func(
+				ctx context.Context,
+				uids onboarding.UserUIDs,
+			) (map[string][]string, error) {
+				all := map[string][]string{
+					"default":   {"default-token"},
+					"opted-out": {"opted-out-token"},
+					"asleep":    {"asleep-token"},
+				}
+				tokens := map[string][]string{}
+				for _, uid := range uids.UIDs {
+					tokens[uid] = all[uid]
+				}
+				return tokens, nil
+			},
+		},
+	}
+	p := preferences.NewPreferences(infra)
+
+	transactional := false
+	payload := dto.NotificationPayload{
+		SendNotificationPayload: firebasetools.SendNotificationPayload{
+			RegistrationTokens: []string{
+				"default-token",
+				"opted-out-token",
+				"asleep-token",
+				"unknown-token",
+			},
+			Notification: &firebasetools.FirebaseSimpleNotificationInput{
+				Title: "Hello",
+				Body:  "There is news",
+			},
+		},
+		NotificationOptions: dto.NotificationOptions{
+			UIDs:          []string{"default", "opted-out", "asleep"},
+			Transactional: &transactional,
+		},
+	}
+
+	screened, err := p.ScreenPushNotification(ctx, payload)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"default-token"}, screened.RegistrationTokens)
+	assert.Len(t, deferred, 1)
+	assert.Equal(t, "asleep", deferred[0].RecipientUID)
+	assert.Equal(t, feedlib.ChannelFcm, deferred[0].Channel)
+	assert.Equal(t, "There is news", deferred[0].Body)
+	assert.Empty(t, deferred[0].Addresses, "the user's tokens are looked up when it goes out")
+
+	payload.UIDs = nil
+	_, err = p.ScreenPushNotification(ctx, payload)
+	assert.NotNil(t, err, "the users are required for notifications that are not transactional")
+
+	payload.Transactional = nil
+	_, err = p.ScreenPushNotification(ctx, payload)
+	assert.NotNil(t, err, "notifications are screened by default")
+
+	transactional = true
+	payload.Transactional = &transactional
+	screened, err = p.ScreenPushNotification(ctx, payload)
+	assert.Nil(t, err)
+	assert.Len(t, screened.RegistrationTokens, 4, "transactional notifications are not screened")
+}
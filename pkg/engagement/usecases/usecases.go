@@ -5,11 +5,13 @@ import (
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/fcm"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/feed"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/feedback"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/idempotency"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/library"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/mail"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/messaging"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/onboarding"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/otp"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/preferences"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/sms"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/surveys"
 	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/twilio"
@@ -31,6 +33,8 @@ type Interactor struct {
 	*feedback.ImplFeedback
 	*uploads.ImpUploads
 	*twilio.ImplTwilio
+	*idempotency.ImplIdempotency
+	*preferences.ImplPreferences
 }
 
 // NewUsecasesInteractor initializes a new usecases interactor
@@ -49,6 +53,8 @@ func NewUsecasesInteractor(infrastructure infrastructure.Interactor) Interactor
 	feedback := feedback.NewFeedback(infrastructure)
 	uploads := uploads.NewUploads(infrastructure)
 	twilio := twilio.NewImplTwilio(infrastructure)
+	idempotency := idempotency.NewIdempotency(infrastructure)
+	preferences := preferences.NewPreferences(infrastructure)
 
 	return Interactor{
 		feed,
@@ -64,5 +70,7 @@ func NewUsecasesInteractor(infrastructure infrastructure.Interactor) Interactor
 		feedback,
 		uploads,
 		twilio,
+		idempotency,
+		preferences,
 	}
 }
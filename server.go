@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/savannahghi/engagementcore/pkg/engagement/presentation"
+	"github.com/savannahghi/engagementcore/pkg/engagement/usecases/idempotency"
 	"go.opencensus.io/stats/view"
 
 	"github.com/savannahghi/serverutils"
@@ -23,7 +24,8 @@ func main() {
 		serverutils.LogStartupError(ctx, err)
 	}
 
-	if err := view.Register(serverutils.DefaultServiceViews...); err != nil {
+	views := append(serverutils.DefaultServiceViews, idempotency.Views...)
+	if err := view.Register(views...); err != nil {
 		serverutils.LogStartupError(ctx, err)
 	}
 